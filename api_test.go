@@ -0,0 +1,48 @@
+package schemalex_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/apply"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/model"
+	"github.com/eihigh/schemalex/schemasource"
+)
+
+// The declarations below pin down the stable API that this module adds
+// on top of upstream (see compat_test.go), which tools rely on instead
+// of copying the parser, the statements, or the changes. New features
+// must be added as new functions or options, never by changing the
+// signatures listed here.
+var (
+	_ func(...schemalex.Option) *schemalex.Parser                                                      = schemalex.NewParser
+	_ func(schemalex.Dialect) schemalex.Option                                                         = schemalex.WithDialect
+	_ func(schemalex.MySQLVersion) schemalex.Option                                                    = schemalex.WithMySQLVersion
+	_ func(bool) schemalex.Option                                                                      = schemalex.WithErrorTolerance
+	_ func(string) (schemalex.MySQLVersion, error)                                                     = schemalex.ParseMySQLVersion
+	_ func(schemalex.ParseErrors) string                                                               = schemalex.ParseErrors.Error
+	_ error                                                                                            = schemalex.ParseErrors(nil)
+	_ func(schemalex.TokenType, string) *schemalex.Token                                               = schemalex.NewToken
+	_ func(schemalex.TokenType) string                                                                 = schemalex.TokenType.String
+	_ func(schemasource.Source) schemalex.SchemaSource                                                 = schemalex.FromSource
+	_ func(model.Stmts, func(model.Stmt) bool) model.Stmts                                             = model.Stmts.Filter
+	_ func(model.Stmts, string) (model.Stmt, bool)                                                     = model.Stmts.Lookup
+	_ func(model.Stmts, model.Stmts, ...diff.Option) (string, []diff.Change, error)                    = diff.Stmts
+	_ func(model.Stmts) (string, error)                                                                = model.Stmts.Hash
+	_ func(model.Stmts) *model.DependencyGraph                                                         = model.Stmts.Dependencies
+	_ func(model.Stmts, model.Stmts, ...diff.Option) ([]diff.Change, error)                            = diff.Changes
+	_ func(io.Writer, model.Stmts, model.Stmts, ...diff.Option) error                                  = diff.Text
+	_ func(diff.DestructivePolicy) diff.Option                                                         = diff.WithDestructive
+	_ error                                                                                            = (*diff.DestructiveError)(nil)
+	_ func(context.Context, apply.Execer, []diff.Change, ...apply.Option) ([]apply.Result, error)      = apply.Apply
+	_ func(context.Context, *sql.DB, model.Stmts, model.Stmts, ...apply.Option) ([]diff.Change, error) = apply.Verify
+	_ func(io.Writer, interface{}, ...format.Option) error                                             = format.SQL
+	_ func(context.Context, io.Writer, *sql.DB) error                                                  = schemasource.WriteInformationSchema
+
+	_ = diff.Change{Table: "", Object: "", Name: "", Action: "", SQL: "", Destructive: false, TypeChange: diff.TypeChange("")}
+	_ = schemalex.Token{Type: schemalex.TokenType(0), Value: "", Pos: 0, Line: 0, Col: 0, EOF: false}
+)
@@ -0,0 +1,109 @@
+// Package apply executes the changes generated by the diff against a
+// database, turning a declarative schema into the schema of a running
+// database.
+//
+// Note that MySQL commits DDL statements implicitly, so the changes
+// that were executed before a failure are not rolled back.
+package apply
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+)
+
+// Execer is the interface to execute statements, which is implemented
+// by *sql.DB, *sql.Conn, and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Result is the outcome of executing a single change
+type Result struct {
+	Change diff.Change
+	// Err is the error returned by the database, or nil if the
+	// statement succeeded
+	Err error
+}
+
+// Apply executes the statements of the changes in order, and returns
+// the results of the statements that were executed. If a statement
+// fails, the remaining statements are skipped unless
+// WithContinueOnError is specified. In either case, an error is
+// returned if any statement failed. With WithVersionTable, the apply
+// is recorded in the version table.
+func Apply(ctx context.Context, db Execer, changes []diff.Change, options ...Option) ([]Result, error) {
+	var continueOnError bool
+	var report io.Writer
+	var versionTable string
+	var hash string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyContinueOnError:
+			continueOnError = o.Value().(bool)
+		case optkeyReport:
+			report = o.Value().(io.Writer)
+		case optkeyVersionTable:
+			versionTable = o.Value().(string)
+		case optkeySchemaHash:
+			hash = o.Value().(string)
+		}
+	}
+
+	if versionTable == "" {
+		return apply(ctx, db, changes, continueOnError, report)
+	}
+
+	id, err := startVersion(ctx, db, versionTable, hash, changes)
+	if err != nil {
+		return nil, err
+	}
+	results, err := apply(ctx, db, changes, continueOnError, report)
+	if verr := finishVersion(ctx, db, versionTable, id, err); verr != nil && err == nil {
+		err = verr
+	}
+	return results, err
+}
+
+func apply(ctx context.Context, db Execer, changes []diff.Change, continueOnError bool, report io.Writer) ([]Result, error) {
+	var results []Result
+	var failed int
+	for _, c := range changes {
+		_, err := db.ExecContext(ctx, c.SQL)
+		results = append(results, Result{Change: c, Err: err})
+		if report != nil {
+			writeResult(report, c, err)
+		}
+		if err == nil {
+			continue
+		}
+
+		failed++
+		if !continueOnError {
+			return results, errors.Wrapf(err, `failed to execute %s`, oneLine(c.SQL))
+		}
+	}
+
+	if failed > 0 {
+		return results, errors.Errorf(`%d of %d statements failed`, failed, len(changes))
+	}
+	return results, nil
+}
+
+func writeResult(w io.Writer, c diff.Change, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "FAIL %s: %s\n", oneLine(c.SQL), err)
+		return
+	}
+	fmt.Fprintf(w, "OK   %s\n", oneLine(c.SQL))
+}
+
+// oneLine collapses the statement into a single line for reporting
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
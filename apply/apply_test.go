@@ -0,0 +1,115 @@
+package apply_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/eihigh/schemalex/apply"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+// execer records the statements and their arguments, and fails the
+// ones listed in fail
+type execer struct {
+	executed []string
+	args     [][]interface{}
+	fail     map[string]bool
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.executed = append(e.executed, query)
+	e.args = append(e.args, args)
+	if e.fail[query] {
+		return nil, errors.New("boom")
+	}
+	return result(len(e.executed)), nil
+}
+
+type result int64
+
+func (r result) LastInsertId() (int64, error) { return int64(r), nil }
+func (r result) RowsAffected() (int64, error) { return 1, nil }
+
+func TestApply(t *testing.T) {
+	changes := []diff.Change{
+		{SQL: "DROP TABLE `a`;"},
+		{SQL: "DROP TABLE `b`;"},
+		{SQL: "DROP TABLE `c`;"},
+	}
+
+	e := &execer{fail: map[string]bool{"DROP TABLE `b`;": true}}
+	var report bytes.Buffer
+	results, err := apply.Apply(context.Background(), e, changes, apply.WithReport(&report))
+	if !assert.Error(t, err, "apply should fail") {
+		return
+	}
+	if !assert.Equal(t, []string{"DROP TABLE `a`;", "DROP TABLE `b`;"}, e.executed, "execution should stop at the failure") {
+		return
+	}
+	if !assert.Len(t, results, 2, "there should be a result for each executed statement") {
+		return
+	}
+	if !assert.Equal(t, "OK   DROP TABLE `a`;\nFAIL DROP TABLE `b`;: boom\n", report.String(), "report should match") {
+		return
+	}
+
+	e = &execer{fail: map[string]bool{"DROP TABLE `b`;": true}}
+	results, err = apply.Apply(context.Background(), e, changes, apply.WithContinueOnError(true))
+	if !assert.EqualError(t, err, "1 of 3 statements failed", "apply should fail") {
+		return
+	}
+	if !assert.Len(t, e.executed, 3, "all statements should be executed") {
+		return
+	}
+	if !assert.Error(t, results[1].Err, "the second statement should fail") {
+		return
+	}
+	if !assert.NoError(t, results[2].Err, "the third statement should succeed") {
+		return
+	}
+}
+
+func TestApplyVersionTable(t *testing.T) {
+	changes := []diff.Change{
+		{SQL: "DROP TABLE `a`;"},
+		{SQL: "DROP TABLE `b`;"},
+	}
+
+	e := &execer{fail: map[string]bool{"DROP TABLE `b`;": true}}
+	_, err := apply.Apply(context.Background(), e, changes, apply.WithVersionTable(apply.DefaultVersionTable), apply.WithSchemaHash("abc"))
+	if !assert.Error(t, err, "apply should fail") {
+		return
+	}
+	if !assert.Len(t, e.executed, 5, "the version table should be created, and the apply recorded before and after the changes") {
+		return
+	}
+	if !assert.Contains(t, e.executed[0], "CREATE TABLE IF NOT EXISTS `schemalex_version`", "the version table should be created") {
+		return
+	}
+	if !assert.Equal(t, []interface{}{"abc", "DROP TABLE `a`;\nDROP TABLE `b`;", apply.VersionRunning}, e.args[1], "the changes should be recorded as running") {
+		return
+	}
+	if !assert.Equal(t, []string{"DROP TABLE `a`;", "DROP TABLE `b`;"}, e.executed[2:4], "the changes should be executed") {
+		return
+	}
+	if !assert.Equal(t, apply.VersionFailed, e.args[4][0], "the apply should be recorded as failed") {
+		return
+	}
+	if !assert.Equal(t, int64(2), e.args[4][2], "the row that was inserted should be updated") {
+		return
+	}
+}
+
+func TestVerifyError(t *testing.T) {
+	err := &apply.VerifyError{Changes: []diff.Change{
+		{SQL: "ALTER TABLE `a`\n  DROP COLUMN `b`;"},
+		{SQL: "DROP TABLE `c`;"},
+	}}
+	if !assert.Equal(t, "the database does not match the schema after the changes, which still differ by: ALTER TABLE `a` DROP COLUMN `b`; DROP TABLE `c`;", err.Error(), "message should list the remaining statements") {
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package apply
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+)
+
+// DefaultLargeTable is the number of rows from which the tables are
+// considered large by Estimate, unless WithLargeTable is specified
+const DefaultLargeTable = 1000000
+
+// TableEstimate describes the changes that alter an existing table,
+// along with the number of rows of the table
+type TableEstimate struct {
+	Table string
+	// Rows is the approximate number of rows of the table, as
+	// estimated by the server in information_schema.TABLES
+	Rows int64
+	// Changes are the changes that alter the table
+	Changes []diff.Change
+	// LongRunning is true if the table has at least as many rows as
+	// the threshold, so that altering it may take a long time
+	LongRunning bool
+}
+
+// Estimate looks up the number of rows of the tables that the changes
+// alter in the database, and flags the tables with at least
+// DefaultLargeTable rows, or the number given to WithLargeTable, as
+// long running, so that the changes can be scheduled accordingly. The
+// tables that are created or dropped, and the changes that do not
+// rebuild or scan the table, such as renaming an index, are left out.
+// The estimates are returned in the order of the first change of each
+// table.
+func Estimate(ctx context.Context, db Queryer, changes []diff.Change, options ...Option) ([]TableEstimate, error) {
+	threshold := int64(DefaultLargeTable)
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyLargeTable:
+			threshold = o.Value().(int64)
+		}
+	}
+
+	var estimates []*TableEstimate
+	byTable := make(map[string]*TableEstimate)
+	for _, c := range changes {
+		if !altersTable(c) {
+			continue
+		}
+		e, ok := byTable[c.Table]
+		if !ok {
+			e = &TableEstimate{Table: c.Table}
+			byTable[c.Table] = e
+			estimates = append(estimates, e)
+		}
+		e.Changes = append(e.Changes, c)
+	}
+
+	list := make([]TableEstimate, len(estimates))
+	for i, e := range estimates {
+		var rows sql.NullInt64
+		err := db.QueryRowContext(ctx, "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", e.Table).Scan(&rows)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, errors.Wrapf(err, `failed to estimate the number of rows of table %s`, e.Table)
+		}
+		e.Rows = rows.Int64
+		e.LongRunning = e.Rows >= threshold
+		list[i] = *e
+	}
+	return list, nil
+}
+
+// altersTable returns true if the change alters an existing table in a
+// way that may take time on a large table
+func altersTable(c diff.Change) bool {
+	switch c.Object {
+	case "table", "histogram":
+		return false
+	case "index":
+		return c.Action != "rename"
+	default:
+		return true
+	}
+}
+
+// WriteEstimate writes the tables that are altered by the changes with
+// their number of rows, marking the long running ones, and ends with
+// the number of long running tables
+func WriteEstimate(w io.Writer, estimates []TableEstimate) error {
+	var long int
+	for _, e := range estimates {
+		line := fmt.Sprintf("  `%s`: ~%d rows, %d %s", e.Table, e.Rows, len(e.Changes), plural(len(e.Changes), "change", "changes"))
+		if e.LongRunning {
+			line += " (long running)"
+			long++
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Estimate: %d of %d altered %s may be long running.\n", long, len(estimates), plural(len(estimates), "table", "tables"))
+	return err
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,64 @@
+package apply
+
+import (
+	"io"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyContinueOnError = "continue-on-error"
+	optkeyLargeTable      = "large-table"
+	optkeyReport          = "report"
+	optkeySchemaHash      = "schema-hash"
+	optkeyStatements      = "statements"
+	optkeyVersionTable    = "version-table"
+)
+
+// WithContinueOnError specifies if the remaining statements should be
+// executed after a statement fails. The default is false, which stops
+// at the first failure
+func WithContinueOnError(b bool) Option {
+	return option.New(optkeyContinueOnError, b)
+}
+
+// WithReport specifies the destination of the report, which has one
+// line for each statement as it is executed. If unspecified, no report
+// is written
+func WithReport(w io.Writer) Option {
+	return option.New(optkeyReport, w)
+}
+
+// WithStatements specifies if WritePlan should write the statement of
+// each change. The default is true
+func WithStatements(b bool) Option {
+	return option.New(optkeyStatements, b)
+}
+
+// WithVersionTable specifies the table in which Apply records each
+// apply, such as DefaultVersionTable. The table is created in the
+// target database if it does not exist, and the changes are recorded
+// as running before they are executed, then as applied or failed. The
+// table should be excluded from the diff, such as with
+// diff.WithIgnoreTables, so that it is not dropped. If unspecified,
+// nothing is recorded
+func WithVersionTable(name string) Option {
+	return option.New(optkeyVersionTable, name)
+}
+
+// WithSchemaHash specifies the digest of the schema that the changes
+// apply, as returned by model.Stmts.Hash, which is recorded in the
+// table given to WithVersionTable
+func WithSchemaHash(hash string) Option {
+	return option.New(optkeySchemaHash, hash)
+}
+
+// WithLargeTable specifies the number of rows from which Estimate
+// flags the changes of a table as long running. The default is
+// DefaultLargeTable
+func WithLargeTable(rows int64) Option {
+	return option.New(optkeyLargeTable, rows)
+}
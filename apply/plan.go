@@ -0,0 +1,76 @@
+package apply
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eihigh/schemalex/diff"
+)
+
+// WritePlan writes a human readable plan of the changes, with one
+// entry for each change marked by "+" for objects that are created or
+// added, "-" for objects that are dropped, and "~" for objects that
+// are changed otherwise. Each entry is followed by its statement unless
+// WithStatements(false) is specified, and the plan ends with the number
+// of changes of each kind.
+func WritePlan(w io.Writer, changes []diff.Change, options ...Option) error {
+	statements := true
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyStatements:
+			statements = o.Value().(bool)
+		}
+	}
+
+	var add, change, destroy int
+	for _, c := range changes {
+		marker := planMarker(c)
+		switch marker {
+		case '+':
+			add++
+		case '-':
+			destroy++
+		default:
+			change++
+		}
+
+		target := "`" + c.Table + "`"
+		if c.Name != "" {
+			target += ".`" + c.Name + "`"
+		}
+		object := strings.Replace(c.Object, "_", " ", -1)
+		line := fmt.Sprintf("  %c %s %s", marker, object, target)
+		if c.Destructive {
+			line += " (destructive)"
+		}
+
+		if statements {
+			line += "\n" + indent(c.SQL, "      ") + "\n"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	if !statements && len(changes) > 0 {
+		fmt.Fprintln(w)
+	}
+	_, err := fmt.Fprintf(w, "Plan: %d to add, %d to change, %d to destroy.\n", add, change, destroy)
+	return err
+}
+
+func planMarker(c diff.Change) byte {
+	switch c.Action {
+	case "create", "add":
+		return '+'
+	case "drop":
+		return '-'
+	default:
+		return '~'
+	}
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
+}
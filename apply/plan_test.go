@@ -0,0 +1,68 @@
+package apply_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex/apply"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePlan(t *testing.T) {
+	changes := []diff.Change{
+		{Table: "fuga", Object: "table", Action: "drop", SQL: "DROP TABLE `fuga`;", Destructive: true},
+		{Table: "piyo", Object: "table", Action: "create", SQL: "CREATE TABLE `piyo` (\n  `id` INT (11) DEFAULT NULL\n);"},
+		{Table: "hoge", Object: "column", Name: "a", Action: "modify", SQL: "ALTER TABLE `hoge` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL;"},
+		{Table: "hoge", Object: "foreign_key", Name: "fk", Action: "add", SQL: "ALTER TABLE `hoge` ADD CONSTRAINT `fk` FOREIGN KEY (`a`) REFERENCES `piyo` (`id`);"},
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, apply.WritePlan(&buf, changes), "apply.WritePlan should succeed") {
+		return
+	}
+
+	expect := "  - table `fuga` (destructive)\n" +
+		"      DROP TABLE `fuga`;\n\n" +
+		"  + table `piyo`\n" +
+		"      CREATE TABLE `piyo` (\n        `id` INT (11) DEFAULT NULL\n      );\n\n" +
+		"  ~ column `hoge`.`a`\n" +
+		"      ALTER TABLE `hoge` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL;\n\n" +
+		"  + foreign key `hoge`.`fk`\n" +
+		"      ALTER TABLE `hoge` ADD CONSTRAINT `fk` FOREIGN KEY (`a`) REFERENCES `piyo` (`id`);\n\n" +
+		"Plan: 2 to add, 1 to change, 1 to destroy.\n"
+	if !assert.Equal(t, expect, buf.String(), "plan should match") {
+		return
+	}
+
+	buf.Reset()
+	if !assert.NoError(t, apply.WritePlan(&buf, changes[:2], apply.WithStatements(false)), "apply.WritePlan should succeed") {
+		return
+	}
+
+	expect = "  - table `fuga` (destructive)\n" +
+		"  + table `piyo`\n\n" +
+		"Plan: 1 to add, 0 to change, 1 to destroy.\n"
+	if !assert.Equal(t, expect, buf.String(), "plan should match") {
+		return
+	}
+}
+
+func TestWriteEstimate(t *testing.T) {
+	estimates := []apply.TableEstimate{
+		{Table: "hoge", Rows: 12000000, Changes: make([]diff.Change, 2), LongRunning: true},
+		{Table: "fuga", Rows: 10, Changes: make([]diff.Change, 1)},
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, apply.WriteEstimate(&buf, estimates), "apply.WriteEstimate should succeed") {
+		return
+	}
+
+	expect := "  `hoge`: ~12000000 rows, 2 changes (long running)\n" +
+		"  `fuga`: ~10 rows, 1 change\n" +
+		"Estimate: 1 of 2 altered tables may be long running.\n"
+	if !assert.Equal(t, expect, buf.String(), "estimate should match") {
+		return
+	}
+}
@@ -0,0 +1,147 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// VerifyError is returned by Verify when the schema of the database
+// does not match "to" after the changes are executed
+type VerifyError struct {
+	// Changes are the changes that would still migrate the database
+	// to "to", which the diff failed to generate
+	Changes []diff.Change
+}
+
+func (e *VerifyError) Error() string {
+	statements := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		statements[i] = oneLine(c.SQL)
+	}
+	return "the database does not match the schema after the changes, which still differ by: " + strings.Join(statements, " ")
+}
+
+// Verify checks the changes from "from" to "to" against a real server:
+// the tables of "from" are created in db, which must be an empty
+// scratch database, and the changes generated by the diff are executed.
+// The tables of the database are then read with SHOW CREATE TABLE, and
+// compared with "to". The changes that were executed are returned. A
+// *VerifyError is returned if the tables differ from "to", and other
+// errors if a statement fails, which also tells that the diff is wrong,
+// or that the server does not support it.
+//
+// The options are passed to diff.Changes for both comparisons, and to
+// Apply, so that options such as diff.WithIgnoreDisplayWidth can
+// account for the normalizations of the server. The tables are left in
+// the database.
+func Verify(ctx context.Context, db *sql.DB, from, to model.Stmts, options ...Option) ([]diff.Change, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to connect to database`)
+	}
+	defer conn.Close()
+
+	tables, err := showTables(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) > 0 {
+		return nil, errors.Errorf(`the database must be empty, but it has %d tables`, len(tables))
+	}
+
+	// the statements of the schema may not be in the order of the
+	// foreign keys
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return nil, errors.Wrap(err, `failed to disable foreign key checks`)
+	}
+	for _, stmt := range from {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, table.String()); err != nil {
+			return nil, errors.Wrapf(err, `failed to create "from": failed to execute %s`, oneLine(table.String()))
+		}
+	}
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1"); err != nil {
+		return nil, errors.Wrap(err, `failed to enable foreign key checks`)
+	}
+
+	changes, err := diff.Changes(from, to, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compare "from" and "to"`)
+	}
+	if _, err := Apply(ctx, conn, changes, options...); err != nil {
+		return changes, err
+	}
+
+	dumped, err := dumpTables(ctx, conn)
+	if err != nil {
+		return changes, err
+	}
+	remaining, err := diff.Changes(dumped, to, options...)
+	if err != nil {
+		return changes, errors.Wrap(err, `failed to compare the database and "to"`)
+	}
+	if len(remaining) > 0 {
+		return changes, &VerifyError{Changes: remaining}
+	}
+	return changes, nil
+}
+
+// showTables returns the names of the base tables in the database
+func showTables(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW FULL TABLES")
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to execute 'SHOW FULL TABLES'`)
+	}
+	defer rows.Close()
+
+	var tables []string
+	var name, typ string
+	for rows.Next() {
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, errors.Wrap(err, `failed to scan tables`)
+		}
+		if typ != "VIEW" {
+			tables = append(tables, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, `failed to scan tables`)
+	}
+	return tables, nil
+}
+
+// dumpTables reads the CREATE TABLE statements of the database, and
+// parses them
+func dumpTables(ctx context.Context, conn *sql.Conn) (model.Stmts, error) {
+	tables, err := showTables(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var name, stmt string
+	for _, table := range tables {
+		if err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+util.Backquote(table)).Scan(&name, &stmt); err != nil {
+			return nil, errors.Wrapf(err, `failed to execute 'SHOW CREATE TABLE "%s"'`, table)
+		}
+		buf.WriteString(stmt)
+		buf.WriteString(";\n")
+	}
+
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse the tables of the database`)
+	}
+	return stmts, nil
+}
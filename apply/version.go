@@ -0,0 +1,143 @@
+package apply
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+)
+
+// DefaultVersionTable is the conventional name of the table given to
+// WithVersionTable
+const DefaultVersionTable = "schemalex_version"
+
+// List of possible Version.Status values. VersionRunning is left
+// behind by an apply that was interrupted.
+const (
+	VersionRunning = "running"
+	VersionApplied = "applied"
+	VersionFailed  = "failed"
+)
+
+// Version is a row of the version table, which records a single apply
+type Version struct {
+	ID int64
+	// Hash is the digest of the schema that was applied, as given to
+	// WithSchemaHash
+	Hash string
+	// Statements are the statements of the changes, one per line
+	Statements string
+	Status     string
+	// Error is the error of the statement that failed, if any
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Queryer is the interface to query a single row, which is implemented
+// by *sql.DB, *sql.Conn, and *sql.Tx
+type Queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const versionTimeLayout = "2006-01-02 15:04:05.000000"
+
+func createVersionTable(ctx context.Context, db Execer, table string) error {
+	stmt := "CREATE TABLE IF NOT EXISTS " + util.Backquote(table) + " (" +
+		"`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, " +
+		"`hash` VARCHAR(64) NOT NULL, " +
+		"`statements` LONGTEXT NOT NULL, " +
+		"`status` VARCHAR(16) NOT NULL, " +
+		"`error` TEXT, " +
+		"`started_at` DATETIME(6) NOT NULL, " +
+		"`finished_at` DATETIME(6) DEFAULT NULL, " +
+		"PRIMARY KEY (`id`), " +
+		"KEY `hash` (`hash`))"
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return errors.Wrapf(err, `failed to create version table %s`, table)
+	}
+	return nil
+}
+
+// startVersion records the changes as running, and returns the ID of
+// the row
+func startVersion(ctx context.Context, db Execer, table, hash string, changes []diff.Change) (int64, error) {
+	if err := createVersionTable(ctx, db, table); err != nil {
+		return 0, err
+	}
+
+	statements := make([]string, len(changes))
+	for i, c := range changes {
+		statements[i] = c.SQL
+	}
+	res, err := db.ExecContext(ctx, "INSERT INTO "+util.Backquote(table)+" (`hash`, `statements`, `status`, `started_at`) VALUES (?, ?, ?, UTC_TIMESTAMP(6))",
+		hash, strings.Join(statements, "\n"), VersionRunning)
+	if err != nil {
+		return 0, errors.Wrapf(err, `failed to record apply in %s`, table)
+	}
+	if res == nil {
+		return 0, errors.Errorf(`failed to record apply in %s: no result`, table)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrapf(err, `failed to record apply in %s`, table)
+	}
+	return id, nil
+}
+
+// finishVersion records the outcome of the apply
+func finishVersion(ctx context.Context, db Execer, table string, id int64, applyErr error) error {
+	status := VersionApplied
+	var message interface{}
+	if applyErr != nil {
+		status = VersionFailed
+		message = applyErr.Error()
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE "+util.Backquote(table)+" SET `status` = ?, `error` = ?, `finished_at` = UTC_TIMESTAMP(6) WHERE `id` = ?", status, message, id); err != nil {
+		return errors.Wrapf(err, `failed to record apply in %s`, table)
+	}
+	return nil
+}
+
+// LastApplied returns the latest version recorded in the version table
+// whose changes were all applied, or nil if there is none or if the
+// table does not exist yet. Comparing its hash with the digest of the
+// schema tells whether the schema was already applied, and if so,
+// whether the database has drifted since then when the diff is not
+// empty.
+func LastApplied(ctx context.Context, db Queryer, table string) (*Version, error) {
+	var exists int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", table).Scan(&exists)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to look up version table`)
+	}
+	if exists == 0 {
+		return nil, nil
+	}
+
+	var v Version
+	var errorText sql.NullString
+	var started, finished string
+	err = db.QueryRowContext(ctx, "SELECT `id`, `hash`, `statements`, `status`, `error`, "+
+		"DATE_FORMAT(`started_at`, '%Y-%m-%d %H:%i:%s.%f'), DATE_FORMAT(`finished_at`, '%Y-%m-%d %H:%i:%s.%f') "+
+		"FROM "+util.Backquote(table)+" WHERE `status` = ? ORDER BY `id` DESC LIMIT 1", VersionApplied).
+		Scan(&v.ID, &v.Hash, &v.Statements, &v.Status, &errorText, &started, &finished)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to read version table %s`, table)
+	}
+	v.Error = errorText.String
+	if v.StartedAt, err = time.Parse(versionTimeLayout, started); err != nil {
+		return nil, errors.Wrapf(err, `failed to read version table %s`, table)
+	}
+	if v.FinishedAt, err = time.Parse(versionTimeLayout, finished); err != nil {
+		return nil, errors.Wrapf(err, `failed to read version table %s`, table)
+	}
+	return &v, nil
+}
@@ -4,24 +4,73 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/eihigh/schemalex"
 	"github.com/eihigh/schemalex/diff"
+	_ "github.com/eihigh/schemalex/postgres"
 )
 
 func main() {
-	if err := _main(); err != nil {
+	err := _main()
+	switch {
+	case err == errDifferences:
+		os.Exit(1)
+	case err != nil:
 		log.Printf("%s", err)
+		if exitCode {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
 
+// errDifferences is returned by _main when -exit-code is given and
+// the schemas differ
+var errDifferences = errors.New("schemas differ")
+
+var exitCode bool
+
 func _main() error {
 	var txn bool
+	var startTxn bool
+	var delimiter string
+	var singleLine bool
+	var columnOrder bool
+	var guard bool
+	var allowDrop bool
+	var allowNarrowing bool
+	var skipDestructive bool
+	var histograms bool
+	var disableFKChecks bool
+	var ifNotExists bool
+	var tableOptions bool
+	var explicitRowFormat bool
+	var ignoreComments bool
+	var temporaryTables bool
+	var separateComments bool
+	var trimComments bool
+	var caseInsensitive bool
+	var serverCharset string
+	var serverCollation string
+	var ignoreDisplayWidth bool
+	var displayWidth bool
+	var renameIndexes bool
+	var sortByName bool
+	var dependencyOrder bool
+	var mysqlVersion string
+	var ansiQuotes bool
+	var dialect string
+	var ignoreTables string
+	var ignoreColumns string
+	var ignoreTableOptions string
+	var summary bool
+	var asJSON bool
 	var version bool
 	var outfile string
 
@@ -34,10 +83,109 @@ schemadiff [options...] before after
 -v            Print out the version and exit
 -o file	      Output the result to the specified file (default: stdout)
 -t[=true]     Enable/Disable transaction in the output (default: true)
+-start-transaction
+              Use START TRANSACTION instead of BEGIN to start the
+              transaction. Implies -t (default: false)
+-delimiter str
+              Terminate statements with the given string (default: ";")
+-single-line  Output each statement on a single line (default: false)
+-column-order Move existing columns to match the column order (default: false)
+-guard        Refuse to output destructive statements such as DROP TABLE,
+              DROP COLUMN, type narrowing, or NOT NULL additions (default: false)
+-allow-drop   Allow destructive statements when -guard is specified (default: false)
+-allow-narrowing
+              Do not treat column changes that may truncate values, such as
+              shorter VARCHARs or NOT NULL additions, as destructive with -guard
+              or -skip-destructive. Type changes such as VARCHAR to INT are
+              still destructive (default: false)
+-skip-destructive
+              Omit destructive statements from the output (default: false)
+-histograms   Update histograms declared with ANALYZE TABLE ... UPDATE HISTOGRAM
+              after the columns they describe change (default: false)
+-disable-fk-checks[=true]
+              Surround the output with SET FOREIGN_KEY_CHECKS = 0 and 1
+              (default: same as -t)
+-if-not-exists[=true]
+              Always, or with -if-not-exists=false never, create tables with
+              CREATE TABLE IF NOT EXISTS (default: as in the "after" schema)
+-case-insensitive
+              Compare names of tables, columns, and indexes case insensitively,
+              as done with lower_case_table_names (default: false)
+-server-charset name
+-server-collation name
+              Compare columns as if they declared the character set and
+              collation inherited from the table or the server, using the
+              given server defaults
+-ignore-display-width
+              Ignore display widths of integer columns, such as INT(11),
+              when comparing columns (default: false)
+-display-width[=true]
+              Include display widths of integer columns in the output.
+              Disable for MySQL 8.0.19 or later (default: true)
+-rename-indexes
+              Rename indexes that only changed their names using RENAME INDEX
+              (MySQL 5.7 or later) instead of dropping and adding them
+              (default: false)
+-sort-by-name  Generate statements in the order of the names of the tables,
+              columns, and indexes, instead of their order in the schemas
+              (default: false)
+-dependency-order
+              Process the tables so that each table comes after the tables
+              that its foreign keys reference, instead of their order in
+              the schemas. -sort-by-name takes precedence (default: false)
+-dialect name  Dialect of the schemas, "mysql", "mariadb", "tidb", or
+              "postgres". The MariaDB and TiDB dialects accept their specific
+              table options, column types, and attributes. The postgres
+              dialect (experimental) reads PostgreSQL schemas, such as the
+              output of pg_dump -s, and converts them to MySQL tables
+              (default: mysql)
+-mysql-version version
+              Target the given MySQL version, such as 5.7 or 8.0.16. Syntax
+              that the version does not support is rejected, and statements
+              that it does not support are avoided. The server charset and
+              -ignore-display-width follow the version unless given
+-ansi-quotes  Read strings quoted by double quotes as identifiers, as with
+              the ANSI_QUOTES SQL mode. SET sql_mode statements of the
+              schemas are followed regardless (default: false)
+-table-options
+              Compare table options such as COMMENT or ROW_FORMAT. ENGINE is
+              always compared unless ignored (default: false)
+-explicit-row-format
+              With -table-options, set a row format that changes to DEFAULT
+              to the default of the engine, such as DYNAMIC (default: false)
+-ignore-comments
+              Do not generate changes that only affect the comments of
+              columns and tables (default: false)
+-separate-comments
+              Generate changes that only affect the comments of columns and
+              tables after all the other statements (default: false)
+-trim-comments
+              Compare comments without their leading and trailing whitespace
+              (default: false)
+-temporary-tables[=true]
+              Compare TEMPORARY tables like any other table. Disable to
+              exclude them from both schemas (default: true)
+-ignore-tables patterns
+              Comma separated patterns of tables to exclude from the
+              comparison, such as "tmp_*,%%_archive"
+-ignore-columns patterns
+              Comma separated patterns of columns to exclude from the
+              comparison, such as "updated_at,users.last_login"
+-ignore-table-options patterns
+              Comma separated patterns of table options to exclude from the
+              comparison, such as "AUTO_INCREMENT,COMMENT"
+-summary      Print out the number of changes of each kind instead of
+              the statements (default: false)
+-json         Output the changes as JSON, with the table, kind of object,
+              action, and SQL statement of each change (default: false)
+-exit-code    Exit with 1 if there are differences, and with 2 if an
+              error occurred (default: false)
 
 "before" and "after" may be a file path, or a URI.
-Special URI schemes "mysql" and "local-git" are supported on top of
-"file". If the special path "-" is used, it is treated as stdin
+Special URI schemes "mysql", "local-git", "http", and "https" are
+supported on top of "file". If the special path "-" is used, it is
+treated as stdin. If a directory is given, the *.sql files in it are
+read in lexical order
 
 Examples:
 
@@ -51,6 +199,13 @@ Examples:
 * Compare file in local git repository against local file
   schemadiff "local-git:///path/to/repo?file=foo.sql&commitish=deadbeaf" /path/to/file
 
+* Compare the previous commit of a file in local git repository against
+  the working tree
+  schemadiff local-git:schema.sql@HEAD~1 schema.sql
+
+* Compare schema published at a URL against local file
+  schemadiff https://example.com/schema.sql /path/to/file
+
 * Compare schema from stdin against local file
 	.... | schemadiff - /path/to/file
 
@@ -58,6 +213,40 @@ Examples:
 	}
 	flag.BoolVar(&version, "v", false, "")
 	flag.BoolVar(&txn, "t", true, "")
+	flag.BoolVar(&startTxn, "start-transaction", false, "")
+	flag.StringVar(&delimiter, "delimiter", ";", "")
+	flag.BoolVar(&singleLine, "single-line", false, "")
+	flag.BoolVar(&columnOrder, "column-order", false, "")
+	flag.BoolVar(&guard, "guard", false, "")
+	flag.BoolVar(&allowDrop, "allow-drop", false, "")
+	flag.BoolVar(&allowNarrowing, "allow-narrowing", false, "")
+	flag.BoolVar(&skipDestructive, "skip-destructive", false, "")
+	flag.BoolVar(&histograms, "histograms", false, "")
+	flag.BoolVar(&disableFKChecks, "disable-fk-checks", false, "")
+	flag.BoolVar(&ifNotExists, "if-not-exists", false, "")
+	flag.BoolVar(&tableOptions, "table-options", false, "")
+	flag.BoolVar(&explicitRowFormat, "explicit-row-format", false, "")
+	flag.BoolVar(&ignoreComments, "ignore-comments", false, "")
+	flag.BoolVar(&temporaryTables, "temporary-tables", true, "")
+	flag.BoolVar(&separateComments, "separate-comments", false, "")
+	flag.BoolVar(&trimComments, "trim-comments", false, "")
+	flag.BoolVar(&caseInsensitive, "case-insensitive", false, "")
+	flag.StringVar(&serverCharset, "server-charset", "", "")
+	flag.StringVar(&serverCollation, "server-collation", "", "")
+	flag.BoolVar(&ignoreDisplayWidth, "ignore-display-width", false, "")
+	flag.BoolVar(&displayWidth, "display-width", true, "")
+	flag.BoolVar(&renameIndexes, "rename-indexes", false, "")
+	flag.BoolVar(&sortByName, "sort-by-name", false, "")
+	flag.BoolVar(&dependencyOrder, "dependency-order", false, "")
+	flag.StringVar(&mysqlVersion, "mysql-version", "", "")
+	flag.BoolVar(&ansiQuotes, "ansi-quotes", false, "")
+	flag.StringVar(&dialect, "dialect", "mysql", "")
+	flag.StringVar(&ignoreTables, "ignore-tables", "", "")
+	flag.StringVar(&ignoreColumns, "ignore-columns", "", "")
+	flag.StringVar(&ignoreTableOptions, "ignore-table-options", "", "")
+	flag.BoolVar(&summary, "summary", false, "")
+	flag.BoolVar(&asJSON, "json", false, "")
+	flag.BoolVar(&exitCode, "exit-code", false, "")
 	flag.StringVar(&outfile, "o", "", "")
 	flag.Parse()
 
@@ -77,6 +266,10 @@ Examples:
 		return errors.New("wrong number of arguments")
 	}
 
+	if flag.Arg(0) == "-" && flag.Arg(1) == "-" {
+		return errors.New(`stdin ("-") can only be used for one of "before" and "after"`)
+	}
+
 	var dst io.Writer = os.Stdout
 	if len(outfile) > 0 {
 		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -97,11 +290,113 @@ Examples:
 		return errors.Wrap(err, `failed to create schema source for "to"`)
 	}
 
-	p := schemalex.New()
-	return diff.Sources(
-		dst,
-		fromSource,
-		toSource,
+	policy := diff.DestructiveAllow
+	switch {
+	case skipDestructive:
+		policy = diff.DestructiveSkip
+	case guard && !allowDrop:
+		policy = diff.DestructiveRefuse
+	}
+
+	comments := diff.CommentCompare
+	switch {
+	case ignoreComments:
+		comments = diff.CommentIgnore
+	case separateComments:
+		comments = diff.CommentSeparate
+	}
+
+	var target schemalex.MySQLVersion
+	if mysqlVersion != "" {
+		v, err := schemalex.ParseMySQLVersion(mysqlVersion)
+		if err != nil {
+			return errors.Wrap(err, `invalid -mysql-version`)
+		}
+		target = v
+	}
+
+	d, err := schemalex.ParseDialect(dialect)
+	if err != nil {
+		return errors.Wrap(err, `invalid -dialect`)
+	}
+
+	p := schemalex.NewParser(schemalex.WithDialect(d), schemalex.WithMySQLVersion(target), schemalex.WithANSIQuotes(ansiQuotes))
+	options := []diff.Option{
 		diff.WithTransaction(txn), diff.WithParser(p),
-	)
+		diff.WithStartTransaction(startTxn),
+		diff.WithDelimiter(delimiter),
+		diff.WithSingleLine(singleLine),
+		diff.WithColumnOrder(columnOrder),
+		diff.WithDestructive(policy),
+		diff.WithAllowNarrowing(allowNarrowing),
+		diff.WithHistograms(histograms),
+		diff.WithTableOptions(tableOptions),
+		diff.WithExplicitRowFormat(explicitRowFormat),
+		diff.WithComments(comments),
+		diff.WithTrimComments(trimComments),
+		diff.WithTemporaryTables(temporaryTables),
+		diff.WithCaseInsensitiveNames(caseInsensitive),
+		diff.WithDisplayWidth(displayWidth),
+		diff.WithRenameIndexes(renameIndexes),
+		diff.WithSortByName(sortByName),
+		diff.WithDependencyOrder(dependencyOrder),
+		diff.WithDialect(d),
+		diff.WithMySQLVersion(target),
+		diff.WithIgnoreTables(splitPatterns(ignoreTables)...),
+		diff.WithIgnoreColumns(splitPatterns(ignoreColumns)...),
+		diff.WithIgnoreTableOptions(splitPatterns(ignoreTableOptions)...),
+		diff.WithJSON(asJSON),
+		schemalex.WithWarningHandler(func(w schemalex.Warning) {
+			fmt.Fprintln(os.Stderr, w)
+		}),
+	}
+
+	if serverCharset != "" || serverCollation != "" {
+		options = append(options, diff.WithServerCharset(serverCharset, serverCollation))
+	}
+
+	// only override the defaults, which follow -t, -mysql-version and
+	// the schemas, when given explicitly
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "disable-fk-checks":
+			options = append(options, diff.WithDisableForeignKeyChecks(disableFKChecks))
+		case "if-not-exists":
+			options = append(options, diff.WithIfNotExists(ifNotExists))
+		case "ignore-display-width":
+			options = append(options, diff.WithIgnoreDisplayWidth(ignoreDisplayWidth))
+		}
+	})
+
+	var s diff.Summary
+	options = append(options, diff.WithSummary(&s))
+
+	out := dst
+	if summary {
+		out = ioutil.Discard
+	}
+	if err := diff.Sources(out, fromSource, toSource, options...); err != nil {
+		return err
+	}
+
+	if summary {
+		if _, err := io.WriteString(dst, s.String()); err != nil {
+			return errors.Wrap(err, `failed to write summary`)
+		}
+	}
+
+	if exitCode && !s.IsEmpty() {
+		return errDifferences
+	}
+	return nil
+}
+
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
 }
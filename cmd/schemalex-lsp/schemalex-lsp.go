@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/lsp"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/pkg/errors"
+)
+
+var version string
+
+func main() {
+	if err := _main(); err != nil {
+		log.Printf("%s", err)
+		os.Exit(1)
+	}
+}
+
+func _main() error {
+	var showVersion bool
+	var disable string
+	var severities string
+	var maxVarcharLength int
+	var namePattern string
+	var mysqlVersion string
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `schemalex-lsp version %s
+
+schemalex-lsp -version
+schemalex-lsp [options...]
+
+-v            Print out the version and exit
+-disable rules
+              Comma separated list of lint rules that are not run
+-severity rule=level,...
+              Change the severity of the findings of rules.
+              Levels are "info", "warning", and "error"
+-max-varchar-length number
+              Longest VARCHAR allowed by the "varchar-length" rule
+              (default: 255)
+-name-pattern regexp
+              Pattern that table and column names must match for the
+              "naming" rule (default: ^[a-z][a-z0-9_]*$)
+-mysql-version version
+              Target the given MySQL version, such as 5.7 or 8.0.16
+              (default: the latest version)
+
+schemalex-lsp is a Language Server Protocol server for schema files,
+which editors start and talk to over stdin and stdout. It reports parse
+errors and the findings of the schemalint rules as diagnostics, goes to
+the definitions of the tables and the columns that foreign keys refer
+to, shows the definitions of columns on hover, completes the keywords,
+and formats the CREATE TABLE statements.
+
+`, version)
+	}
+	flag.BoolVar(&showVersion, "v", false, "")
+	flag.StringVar(&disable, "disable", "", "")
+	flag.StringVar(&severities, "severity", "", "")
+	flag.IntVar(&maxVarcharLength, "max-varchar-length", 255, "")
+	flag.StringVar(&namePattern, "name-pattern", `^[a-z][a-z0-9_]*$`, "")
+	flag.StringVar(&mysqlVersion, "mysql-version", "", "")
+	flag.Parse()
+
+	if showVersion {
+		if version == "" {
+			version = "(custom build)"
+		}
+
+		fmt.Printf(
+			"schemalex-lsp version %s, built with %s (%s/%s)\n",
+			version,
+			runtime.Version(),
+			runtime.GOOS,
+			runtime.GOARCH,
+		)
+		return nil
+	}
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	options := []lsp.Option{
+		lint.WithMaxVarcharLength(maxVarcharLength),
+		lint.WithNamePattern(namePattern),
+	}
+	if len(disable) > 0 {
+		options = append(options, lint.WithDisableRules(strings.Split(disable, ",")...))
+	}
+	if len(severities) > 0 {
+		for _, v := range strings.Split(severities, ",") {
+			i := strings.IndexByte(v, '=')
+			if i < 0 {
+				return errors.Errorf(`invalid severity %s: expected rule=level`, v)
+			}
+			s, err := lint.ParseSeverity(v[i+1:])
+			if err != nil {
+				return errors.Wrapf(err, `invalid severity for rule %s`, v[:i])
+			}
+			options = append(options, lint.WithSeverity(v[:i], s))
+		}
+	}
+	if mysqlVersion != "" {
+		v, err := schemalex.ParseMySQLVersion(mysqlVersion)
+		if err != nil {
+			return errors.Wrap(err, `invalid -mysql-version`)
+		}
+		options = append(options, lint.WithMySQLVersion(v))
+	}
+
+	// stdout carries the messages of the protocol, and the log goes to
+	// stderr, which editors show as the output of the server
+	return lsp.New(options...).Serve(context.Background(), os.Stdin, os.Stdout)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/apply"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/schemasource"
+)
+
+// runApply migrates the database given as "before", target, to the
+// schema given as "after", source. The plan is printed first, and unless dryRun is false,
+// nothing is executed. Otherwise, the changes are executed once the
+// user confirms them, or right away if autoApprove is true. If
+// versionTable is not empty, the apply is recorded in that table. If
+// largeTable is positive, the tables that are altered are looked up,
+// and those with at least that many rows are flagged after the plan.
+// Both schemas are parsed with p.
+func runApply(dst io.Writer, target, source string, p *schemalex.Parser, from, to schemalex.SchemaSource, dryRun, autoApprove, continueOnError bool, versionTable string, largeTable int64, options ...diff.Option) error {
+	if !strings.HasPrefix(target, "mysql://") {
+		return errors.New(`-apply requires "before" to be a mysql:// source`)
+	}
+
+	cfg, err := schemasource.ParseDSN(strings.TrimPrefix(target, "mysql://"))
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return errors.Wrap(err, `failed to open connection to database`)
+	}
+	defer db.Close()
+
+	if versionTable != "" {
+		// the version table is not part of the schema
+		options = append(options[:len(options):len(options)], diff.WithIgnoreTables("/^"+regexp.QuoteMeta(versionTable)+"$/"))
+	}
+
+	var buf bytes.Buffer
+	if err := from.WriteSchema(&buf); err != nil {
+		return errors.Wrap(err, `failed to retrieve schema from "from" source`)
+	}
+	fromStmts, err := p.Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, `failed to parse "from" source`)
+	}
+
+	buf.Reset()
+	if err := to.WriteSchema(&buf); err != nil {
+		return errors.Wrap(err, `failed to retrieve schema from "to" source`)
+	}
+	toStmts, err := p.Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, `failed to parse "to" source`)
+	}
+
+	changes, err := diff.Changes(fromStmts, toStmts, options...)
+	if err != nil {
+		return err
+	}
+
+	var hash string
+	if versionTable != "" {
+		if hash, err = toStmts.Hash(); err != nil {
+			return errors.Wrap(err, `failed to compute hash of "to" source`)
+		}
+		last, err := apply.LastApplied(context.Background(), db, versionTable)
+		if err != nil {
+			return err
+		}
+		if last != nil && last.Hash == hash {
+			if len(changes) == 0 {
+				fmt.Fprintf(dst, "no changes, the schema was applied at %s\n", last.FinishedAt.Format(time.RFC3339))
+				return nil
+			}
+			fmt.Fprintf(dst, "The database has drifted since the schema was applied at %s.\n\n", last.FinishedAt.Format(time.RFC3339))
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(dst, "no changes")
+		return nil
+	}
+
+	if err := apply.WritePlan(dst, changes); err != nil {
+		return errors.Wrap(err, `failed to write plan`)
+	}
+
+	if largeTable > 0 {
+		estimates, err := apply.Estimate(context.Background(), db, changes, apply.WithLargeTable(largeTable))
+		if err != nil {
+			return err
+		}
+		if len(estimates) > 0 {
+			fmt.Fprintln(dst)
+			if err := apply.WriteEstimate(dst, estimates); err != nil {
+				return errors.Wrap(err, `failed to write estimate`)
+			}
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintln(dst, "\nThis was a dry run. Use -dry-run=false to execute the changes.")
+		return nil
+	}
+
+	if !autoApprove {
+		// stdin may have been used for the schema
+		if source == "-" {
+			return errors.New(`-auto-approve is required when a schema is read from stdin`)
+		}
+		ok, err := confirm(os.Stdin, dst)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(dst, "Apply cancelled.")
+			return nil
+		}
+	}
+
+	applyOptions := []apply.Option{
+		apply.WithContinueOnError(continueOnError),
+		apply.WithReport(dst),
+	}
+	if versionTable != "" {
+		applyOptions = append(applyOptions, apply.WithVersionTable(versionTable), apply.WithSchemaHash(hash))
+	}
+	_, err = apply.Apply(context.Background(), db, changes, applyOptions...)
+	return err
+}
+
+// confirm asks the user whether the changes should be executed, and
+// returns true only if the answer is "yes"
+func confirm(src io.Reader, dst io.Writer) (bool, error) {
+	fmt.Fprint(dst, "\nDo you want to execute these changes?\nOnly 'yes' will be accepted to approve.\n\nEnter a value: ")
+
+	answer, err := bufio.NewReader(src).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, errors.Wrap(err, `failed to read answer`)
+	}
+	fmt.Fprintln(dst)
+	return strings.TrimSpace(answer) == "yes", nil
+}
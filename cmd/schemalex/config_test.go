@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemalex-config")
+	if !assert.NoError(t, err, "TempDir should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	load := func(content string) *config {
+		fn := filepath.Join(dir, ".schemalex.yml")
+		if !assert.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644), "WriteFile should succeed") {
+			return nil
+		}
+		c, err := loadConfig(fn)
+		if !assert.NoError(t, err, "loadConfig should succeed") {
+			return nil
+		}
+		return c
+	}
+	flags := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("schemalex", flag.ContinueOnError)
+		fs.Bool("guard", false, "")
+		fs.String("ignore-tables", "", "")
+		fs.Bool("apply", false, "")
+		fs.Bool("dry-run", true, "")
+		fs.Bool("auto-approve", false, "")
+		fs.String("o", "", "")
+		fs.String("config", "", "")
+		return fs
+	}
+
+	c := load("guard: true\nignore-tables: [tmp_*, old_*]\nbefore: a.sql\nafter: b.sql\n")
+	if c == nil {
+		return
+	}
+	fs := flags()
+	sources, err := c.apply(fs)
+	if !assert.NoError(t, err, "apply should succeed") {
+		return
+	}
+	if !assert.Equal(t, []string{"a.sql", "b.sql"}, sources, "sources should match") {
+		return
+	}
+	if !assert.Equal(t, "true", fs.Lookup("guard").Value.String(), "guard should be set") {
+		return
+	}
+	if !assert.Equal(t, "tmp_*,old_*", fs.Lookup("ignore-tables").Value.String(), "ignore-tables should be set") {
+		return
+	}
+
+	for _, content := range []string{"apply: true\n", "dry-run: false\n", "auto-approve: true\n", "o: out/\n", "config: other.yml\n"} {
+		c := load(content)
+		if c == nil {
+			return
+		}
+		if _, err := c.apply(flags()); !assert.Error(t, err, "apply should fail for %q", content) {
+			return
+		}
+	}
+}
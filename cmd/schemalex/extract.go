@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+func extractMain(args []string) error {
+	var pf parserFlags
+	var tables patternList
+	var withDependencies bool
+	var outfile string
+
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex version %s
+
+schemalex extract [options...] -table pattern schema
+
+Write the statements of the tables of "schema" that match the patterns,
+such as to create a trimmed schema for integration tests. The histograms
+of the tables are kept, along with the statements that do not belong to
+a table, such as CREATE DATABASE. "schema" is the same as in schemalex.
+
+-table pattern
+              Extract the tables matching the pattern, such as "users" or
+              "user_*". May be given multiple times, or with comma
+              separated patterns (required)
+-with-dependencies
+              Also extract the tables that the extracted tables reference
+              with foreign keys, directly or not. Otherwise, the foreign
+              keys may reference tables that are not extracted
+              (default: false)
+-o file       Output the result to the specified file (default: stdout)
+%s
+
+Examples:
+
+* Extract the orders table and the tables it references
+  schemalex extract -table orders -with-dependencies schema.sql
+
+`, schemalex.Version, parserUsage)
+	}
+	fs.Var(&tables, "table", "")
+	fs.BoolVar(&withDependencies, "with-dependencies", false, "")
+	fs.StringVar(&outfile, "o", "", "")
+	pf.register(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+	if len(tables) == 0 {
+		return errors.New("-table is required")
+	}
+
+	patterns := make([]*regexp.Regexp, len(tables))
+	for i, pattern := range tables {
+		re, err := util.CompilePattern(pattern)
+		if err != nil {
+			return errors.Wrap(err, `invalid -table`)
+		}
+		patterns[i] = re
+	}
+
+	p, err := pf.parser()
+	if err != nil {
+		return err
+	}
+
+	stmts, err := parseSource(p, fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to read "schema"`)
+	}
+
+	var dst io.Writer = os.Stdout
+	if len(outfile) > 0 {
+		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return errors.Wrapf(err, `failed to open file %s for writing`, outfile)
+		}
+		dst = f
+		defer f.Close()
+	}
+
+	if _, err := extractTables(stmts, patterns, withDependencies).WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+// extractTables returns the statements of the tables that match any of
+// the patterns, and of the tables that they depend on if
+// withDependencies is true. Statements that do not belong to a table
+// are kept.
+func extractTables(stmts model.Stmts, patterns []*regexp.Regexp, withDependencies bool) model.Stmts {
+	var names []string
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok && matchPatterns(patterns, table.Name()) {
+			names = append(names, table.Name())
+		}
+	}
+	if withDependencies {
+		names = stmts.Dependencies().Closure(names...)
+	}
+
+	selected := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		selected[name] = struct{}{}
+	}
+	return stmts.Filter(func(stmt model.Stmt) bool {
+		var name string
+		switch stmt := stmt.(type) {
+		case model.Table:
+			name = stmt.Name()
+		case model.Histogram:
+			name = stmt.TableName()
+		case model.DropTable:
+			name = stmt.Name()
+		default:
+			return true
+		}
+		_, ok := selected[name]
+		return ok
+	})
+}
+
+func matchPatterns(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/fixture"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+)
+
+func fixtureMain(args []string) error {
+	var pf parserFlags
+	var tables patternList
+	var rows int
+	var outfile string
+
+	fs := flag.NewFlagSet("fixture", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex version %s
+
+schemalex fixture [options...] schema
+
+Write INSERT statements that fill the tables of "schema" with rows of
+sample values, as scaffolding for test data. The tables are filled after
+the tables that they reference, and the values honor NOT NULL, defaults,
+and ENUM values. "schema" is the same as in schemalex.
+
+-rows n       Number of rows inserted into each table (default: 1)
+-table pattern
+              Only fill the tables matching the pattern, along with the
+              tables that they reference. May be given multiple times, or
+              with comma separated patterns (default: all the tables)
+-o file       Output the result to the specified file (default: stdout)
+%s
+
+Examples:
+
+* Fill the orders table and the tables it references with 3 rows each
+  schemalex fixture -rows 3 -table orders schema.sql
+
+`, schemalex.Version, parserUsage)
+	}
+	fs.Var(&tables, "table", "")
+	fs.IntVar(&rows, "rows", 1, "")
+	fs.StringVar(&outfile, "o", "", "")
+	pf.register(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	p, err := pf.parser()
+	if err != nil {
+		return err
+	}
+
+	stmts, err := parseSource(p, fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to read "schema"`)
+	}
+
+	if len(tables) > 0 {
+		patterns := make([]*regexp.Regexp, len(tables))
+		for i, pattern := range tables {
+			re, err := util.CompilePattern(pattern)
+			if err != nil {
+				return errors.Wrap(err, `invalid -table`)
+			}
+			patterns[i] = re
+		}
+		stmts = extractTables(stmts, patterns, true)
+	}
+
+	var dst io.Writer = os.Stdout
+	if len(outfile) > 0 {
+		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return errors.Wrapf(err, `failed to open file %s for writing`, outfile)
+		}
+		dst = f
+		defer f.Close()
+	}
+
+	return fixture.Write(dst, stmts, fixture.WithRows(rows))
+}
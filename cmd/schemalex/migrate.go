@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/migration"
+	"github.com/eihigh/schemalex/model"
+)
+
+func migrateMain(args []string) error {
+	var pf parserFlags
+	var dir string
+	var layout string
+	var name string
+	var columnOrder bool
+	var renameIndexes bool
+	var tableOptions bool
+
+	layouts := make([]string, len(migration.Layouts))
+	for i, l := range migration.Layouts {
+		layouts[i] = string(l)
+	}
+
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex version %s
+
+schemalex migrate [options...] -name name before after
+
+Write the diff between "before" and "after" into timestamped up and down
+migration files. "before" and "after" are the same as in schemalex.
+
+-name name    Name of the migration, such as add_users (required)
+-dir dir      Directory to write the files to (default: ".")
+-format name  Layout of the files, one of %s
+              (default: golang-migrate)
+-column-order Move existing columns to match the column order (default: false)
+-rename-indexes
+              Rename indexes that only changed their names using RENAME INDEX
+              (MySQL 5.7 or later) instead of dropping and adding them
+              (default: false)
+-table-options
+              Compare table options such as ENGINE or COMMENT (default: false)
+%s
+
+Examples:
+
+* Write the changes to schema.sql since the last commit for goose
+  schemalex migrate -format goose -dir db/migrations -name add_users \
+    local-git:schema.sql@HEAD schema.sql
+
+`, schemalex.Version, strings.Join(layouts, ", "), parserUsage)
+	}
+	fs.StringVar(&dir, "dir", ".", "")
+	fs.StringVar(&layout, "format", string(migration.LayoutGolangMigrate), "")
+	fs.StringVar(&name, "name", "", "")
+	fs.BoolVar(&columnOrder, "column-order", false, "")
+	fs.BoolVar(&renameIndexes, "rename-indexes", false, "")
+	fs.BoolVar(&tableOptions, "table-options", false, "")
+	pf.register(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+	if name == "" {
+		fs.Usage()
+		return errors.New("-name is required")
+	}
+
+	l, err := migration.ParseLayout(layout)
+	if err != nil {
+		return err
+	}
+
+	p, err := pf.parser()
+	if err != nil {
+		return err
+	}
+
+	from, err := parseSource(p, fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to read "before"`)
+	}
+	to, err := parseSource(p, fs.Arg(1))
+	if err != nil {
+		return errors.Wrap(err, `failed to read "after"`)
+	}
+
+	files, err := migration.Generate(from, to, name,
+		migration.WithLayout(l),
+		diff.WithColumnOrder(columnOrder),
+		diff.WithRenameIndexes(renameIndexes),
+		diff.WithTableOptions(tableOptions),
+	)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("no changes")
+		return nil
+	}
+
+	for _, file := range files {
+		fn := filepath.Join(dir, file.Name)
+		// never overwrite an existing migration
+		f, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.Wrapf(err, `failed to open file %s for writing`, fn)
+		}
+		_, err = f.Write(file.Content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return errors.Wrapf(err, `failed to write file %s`, fn)
+		}
+		fmt.Println(fn)
+	}
+	return nil
+}
+
+// parserUsage documents the flags registered by parserFlags
+const parserUsage = `-dialect name Dialect of the schemas, "mysql", "mariadb", "tidb", or
+              "postgres" (default: mysql)
+-mysql-version version
+              Read the schemas as the given MySQL version does, such as 5.7
+              or 8.0.16
+-ansi-quotes  Read strings quoted by double quotes as identifiers, as with
+              the ANSI_QUOTES SQL mode (default: false)`
+
+// parserFlags are the flags of the subcommands that configure how the
+// schemas are parsed, as the flags of the same names do in schemalex
+type parserFlags struct {
+	dialect      string
+	mysqlVersion string
+	ansiQuotes   bool
+}
+
+func (f *parserFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.dialect, "dialect", "mysql", "")
+	fs.StringVar(&f.mysqlVersion, "mysql-version", "", "")
+	fs.BoolVar(&f.ansiQuotes, "ansi-quotes", false, "")
+}
+
+// parser creates the parser configured by the flags
+func (f *parserFlags) parser() (*schemalex.Parser, error) {
+	var target schemalex.MySQLVersion
+	if f.mysqlVersion != "" {
+		v, err := schemalex.ParseMySQLVersion(f.mysqlVersion)
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid -mysql-version`)
+		}
+		target = v
+	}
+
+	d, err := schemalex.ParseDialect(f.dialect)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid -dialect`)
+	}
+	return schemalex.NewParser(schemalex.WithDialect(d), schemalex.WithMySQLVersion(target), schemalex.WithANSIQuotes(f.ansiQuotes)), nil
+}
+
+func parseSource(p *schemalex.Parser, uri string) (model.Stmts, error) {
+	src, err := schemalex.NewSchemaSource(uri)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create schema source`)
+	}
+
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrap(err, `failed to retrieve schema`)
+	}
+	return p.Parse(buf.Bytes())
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+)
+
+// isOutputDir returns true if the -o path is a directory, that is, an
+// existing directory, or a path ending with a slash
+func isOutputDir(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return true
+	}
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// manifestFile is the file of the -o directory that lists the files
+// that writeTables wrote, so that only those are ever removed
+const manifestFile = ".schemalex-files"
+
+// writeTables writes the statements that change each table to a file
+// of its own in dir, named after the table, such as users.sql. Each
+// file is a complete migration, with the transaction and
+// FOREIGN_KEY_CHECKS statements requested by the options. The files of
+// the tables that do not change are left as is, and the files that
+// earlier runs wrote for the tables that are in neither schema are
+// removed. Files that writeTables did not write are never removed. It
+// returns the number of files written.
+func writeTables(dir string, p *schemalex.Parser, from, to schemalex.SchemaSource, options ...diff.Option) (int, error) {
+	fromStmts, err := parseSchema(p, from)
+	if err != nil {
+		return 0, errors.Wrap(err, `failed to read "before"`)
+	}
+	toStmts, err := parseSchema(p, to)
+	if err != nil {
+		return 0, errors.Wrap(err, `failed to read "after"`)
+	}
+
+	changes, err := diff.Changes(fromStmts, toStmts, options...)
+	if err != nil {
+		return 0, err
+	}
+
+	files := tableFileNames(fromStmts, toStmts)
+	written, err := readManifest(dir)
+	if err != nil {
+		return 0, err
+	}
+	if written, err = removeStaleFiles(dir, files, written); err != nil {
+		return 0, err
+	}
+
+	// tables are written in the order of their first change
+	var tables []string
+	seen := make(map[string]struct{})
+	for _, c := range changes {
+		if _, ok := seen[c.Table]; ok {
+			continue
+		}
+		seen[c.Table] = struct{}{}
+		tables = append(tables, c.Table)
+	}
+	if len(tables) == 0 {
+		return 0, writeManifest(dir, written)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, errors.Wrapf(err, `failed to create directory %s`, dir)
+	}
+	for _, table := range tables {
+		var buf bytes.Buffer
+		if err := diff.Statements(&buf, fromStmts, toStmts, append(options[:len(options):len(options)], diff.WithTable(table))...); err != nil {
+			return 0, err
+		}
+		buf.WriteByte('\n')
+
+		fn := filepath.Join(dir, files[table])
+		if err := ioutil.WriteFile(fn, buf.Bytes(), 0644); err != nil {
+			return 0, errors.Wrapf(err, `failed to write file %s`, fn)
+		}
+		written[files[table]] = struct{}{}
+	}
+	return len(tables), writeManifest(dir, written)
+}
+
+// tableFileNames returns the names of the files of the tables of both
+// schemas. Tables whose file names only differ in case, which are the
+// same file on case insensitive file systems, such as Users and users,
+// get a suffix derived from their exact names, such as
+// Users.3f2a5c1e.sql.
+func tableFileNames(schemas ...model.Stmts) map[string]string {
+	byFolded := make(map[string][]string)
+	for _, stmts := range schemas {
+		for _, stmt := range stmts {
+			table, ok := stmt.(model.Table)
+			if !ok {
+				continue
+			}
+			name := tableFileName(table.Name())
+			folded := strings.ToLower(name)
+			var found bool
+			for _, v := range byFolded[folded] {
+				if v == table.Name() {
+					found = true
+					break
+				}
+			}
+			if !found {
+				byFolded[folded] = append(byFolded[folded], table.Name())
+			}
+		}
+	}
+
+	files := make(map[string]string)
+	for _, tables := range byFolded {
+		for _, table := range tables {
+			name := tableFileName(table)
+			if len(tables) > 1 {
+				h := fnv.New32a()
+				h.Write([]byte(table))
+				name = fmt.Sprintf("%s.%08x.sql", strings.TrimSuffix(name, ".sql"), h.Sum32())
+			}
+			files[table] = name
+		}
+	}
+	return files
+}
+
+// removeStaleFiles removes the files of dir that earlier runs wrote,
+// as listed by written, that are not the file of one of the tables, and
+// reports them. It returns the files of written that remain.
+func removeStaleFiles(dir string, files map[string]string, written map[string]struct{}) (map[string]struct{}, error) {
+	current := make(map[string]struct{})
+	for _, name := range files {
+		current[strings.ToLower(name)] = struct{}{}
+	}
+
+	remaining := make(map[string]struct{})
+	for name := range written {
+		if _, ok := current[strings.ToLower(name)]; ok {
+			remaining[name] = struct{}{}
+			continue
+		}
+		fn := filepath.Join(dir, name)
+		if err := os.Remove(fn); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, `failed to remove stale file %s`, fn)
+		}
+		log.Printf("removed %s, whose table is in neither schema", fn)
+	}
+	return remaining, nil
+}
+
+// readManifest returns the files listed by the manifest of dir, which
+// is empty if there is none
+func readManifest(dir string) (map[string]struct{}, error) {
+	written := make(map[string]struct{})
+	fn := filepath.Join(dir, manifestFile)
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return written, nil
+		}
+		return nil, errors.Wrapf(err, `failed to read file %s`, fn)
+	}
+	for _, name := range strings.Split(string(buf), "\n") {
+		// only plain file names are listed, never paths
+		if name == "" || name != filepath.Base(name) || filepath.Ext(name) != ".sql" {
+			continue
+		}
+		written[name] = struct{}{}
+	}
+	return written, nil
+}
+
+// writeManifest lists the files in the manifest of dir. Nothing is
+// written if there are none, and there is no manifest yet.
+func writeManifest(dir string, written map[string]struct{}) error {
+	fn := filepath.Join(dir, manifestFile)
+	if len(written) == 0 {
+		if _, err := os.Stat(fn); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	names := make([]string, 0, len(written))
+	for name := range written {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+	}
+	if err := ioutil.WriteFile(fn, buf.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, `failed to write file %s`, fn)
+	}
+	return nil
+}
+
+// writeText writes the line based diff of the schemas given by -text.
+// It returns errDifferences if they differ and -exit-code or -check is
+// given.
+func writeText(dst io.Writer, p *schemalex.Parser, from, to schemalex.SchemaSource, options ...diff.Option) error {
+	fromStmts, err := parseSchema(p, from)
+	if err != nil {
+		return errors.Wrap(err, `failed to read "before"`)
+	}
+	toStmts, err := parseSchema(p, to)
+	if err != nil {
+		return errors.Wrap(err, `failed to read "after"`)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.Text(&buf, fromStmts, toStmts, options...); err != nil {
+		return err
+	}
+	differ := buf.Len() > 0
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write diff`)
+	}
+	if (exitCode || check) && differ {
+		return errDifferences
+	}
+	return nil
+}
+
+func parseSchema(p *schemalex.Parser, src schemalex.SchemaSource) (model.Stmts, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from %s`, src)
+	}
+	return p.Parse(buf.Bytes())
+}
+
+// tableFileName returns the name of the file that holds the statements
+// of the table, where path separators are replaced by underscores
+func tableFileName(table string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', 0:
+			return '_'
+		}
+		return r
+	}, table)
+	if name == "." || name == ".." {
+		name = strings.Repeat("_", len(name))
+	}
+	return name + ".sql"
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemalex-output")
+	if !assert.NoError(t, err, "TempDir should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// files that are not *.sql are not touched
+	if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("migrations\n"), 0644), "WriteFile should succeed") {
+		return
+	}
+	// neither are the *.sql files that writeTables did not write
+	if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "seed.sql"), []byte("INSERT INTO a VALUES (1);\n"), 0644), "WriteFile should succeed") {
+		return
+	}
+
+	run := func(before, after string) bool {
+		_, err := writeTables(dir, schemalex.New(), schemalex.NewReaderSource(strings.NewReader(before)), schemalex.NewReaderSource(strings.NewReader(after)), diff.WithTransaction(false))
+		return assert.NoError(t, err, "writeTables should succeed")
+	}
+	files := func() []string {
+		list, _ := ioutil.ReadDir(dir)
+		var names []string
+		for _, fi := range list {
+			names = append(names, fi.Name())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	const (
+		v1 = "CREATE TABLE a (id INT); CREATE TABLE old (id INT);"
+		v2 = "CREATE TABLE a (id INT, x INT); CREATE TABLE Users (id INT); CREATE TABLE users (id INT);"
+		v3 = "CREATE TABLE a (id INT, x INT, y INT);"
+	)
+	if !run(v1, v2) {
+		return
+	}
+	// Users and users get files of their own, even on case insensitive
+	// file systems
+	stmts, err := schemalex.New().ParseString(v2)
+	if !assert.NoError(t, err, "ParseString should succeed") {
+		return
+	}
+	names := tableFileNames(stmts)
+	if !assert.Len(t, names, 3, "each table should have a file") || !assert.NotEqual(t, strings.ToLower(names["Users"]), strings.ToLower(names["users"]), "file names should differ in more than case") {
+		return
+	}
+	expected := []string{manifestFile, "README", "a.sql", names["Users"], names["users"], "old.sql", "seed.sql"}
+	sort.Strings(expected)
+	if !assert.Equal(t, expected, files(), "files of the first run should match") {
+		return
+	}
+
+	// the second run removes the file of old, which is in neither
+	// schema anymore
+	if !run(v2, v3) {
+		return
+	}
+	expected = []string{manifestFile, "README", "a.sql", names["Users"], names["users"], "seed.sql"}
+	sort.Strings(expected)
+	if !assert.Equal(t, expected, files(), "files of the second run should match") {
+		return
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "a.sql"))
+	if !assert.NoError(t, err, "ReadFile should succeed") || !assert.Contains(t, string(buf), "ADD COLUMN `y`", "a.sql should be written again") {
+		return
+	}
+	buf, err = ioutil.ReadFile(filepath.Join(dir, names["users"]))
+	if !assert.NoError(t, err, "ReadFile should succeed") || !assert.Equal(t, "DROP TABLE `users`;\n", string(buf), "the file of users should drop it") {
+		return
+	}
+}
@@ -1,42 +1,309 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/apply"
 	"github.com/eihigh/schemalex/diff"
 	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/watch"
+	_ "github.com/eihigh/schemalex/postgres"
 )
 
 func main() {
-	if err := _main(); err != nil {
+	err := _main()
+	switch {
+	case err == errDifferences:
+		os.Exit(1)
+	case err != nil && (exitCode || check):
+		log.Print(err)
+		os.Exit(2)
+	case err != nil:
 		log.Fatal(err)
 	}
 }
 
+// errDifferences is returned by _main when -exit-code or -check is
+// given and the schemas differ
+var errDifferences = errors.New("schemas differ")
+
+var exitCode bool
+var check bool
+
 func _main() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			return migrateMain(os.Args[2:])
+		case "drift":
+			return driftMain(os.Args[2:])
+		case "stats":
+			return statsMain(os.Args[2:])
+		case "extract":
+			return extractMain(os.Args[2:])
+		case "fixture":
+			return fixtureMain(os.Args[2:])
+		}
+	}
+
 	var txn bool
+	var startTxn bool
+	var delimiter string
+	var singleLine bool
+	var columnOrder bool
+	var guard bool
+	var allowDrop bool
+	var allowNarrowing bool
+	var skipDestructive bool
+	var histograms bool
+	var disableFKChecks bool
+	var ifNotExists bool
+	var tableOptions bool
+	var explicitRowFormat bool
+	var ignoreComments bool
+	var temporaryTables bool
+	var separateComments bool
+	var trimComments bool
+	var caseInsensitive bool
+	var serverCharset string
+	var serverCollation string
+	var ignoreDisplayWidth bool
+	var displayWidth bool
+	var renameIndexes bool
+	var ignoreSymbols bool
+	var sortByName bool
+	var dependencyOrder bool
+	var separateFKs bool
+	var mysqlVersion string
+	var ansiQuotes bool
+	var dialect string
+	var ignoreTables string
+	var onlyTables patternList
+	var excludeTables patternList
+	var ignoreColumns string
+	var ignoreTableOptions string
+	var summary bool
+	var asJSON bool
+	var applyChanges bool
+	var verifyServer string
+	var dryRun bool
+	var autoApprove bool
+	var continueOnError bool
+	var versionTable string
+	var largeTable int64
 	var version bool
 	var outfile string
+	var configFile string
+	var watchSources bool
+	var quiet bool
+	var unified bool
+	var textDiff bool
+	var colorMode string
 
 	flag.Usage = func() {
 		fmt.Printf(`schemalex version %s
 
 schemalex -version
 schemalex [options...] before after
+schemalex [options...]
+schemalex migrate [options...] -name name before after
+schemalex drift [options...] schema live
+schemalex stats [options...] schema
+schemalex extract [options...] -table pattern schema
+schemalex fixture [options...] schema
 
 -v            Print out the version and exit
+-config file  Read the default options, and the "before" and "after"
+              sources, from the given YAML file (default: .schemalex.yml
+              or .schemalex.yaml in the current directory, if any).
+              Options that execute or write, such as -apply or -o, can
+              not be set in the file
 -o file	      Output the result to the specified file (default: stdout)
+-o dir/       Output the statements of each table to a file of its own in
+              the directory, such as dir/users.sql, when the path ends
+              with a slash or is an existing directory. The files that
+              earlier runs wrote for the tables that are in neither
+              schema are removed, other files are left as is
 -t[=true]     Enable/Disable transaction in the output (default: true)
+-start-transaction
+              Use START TRANSACTION instead of BEGIN to start the
+              transaction. Implies -t (default: false)
+-delimiter str
+              Terminate statements with the given string (default: ";")
+-single-line  Output each statement on a single line (default: false)
+-column-order Move existing columns to match the column order (default: false)
+-guard        Refuse to output destructive statements such as DROP TABLE,
+              DROP COLUMN, type narrowing, or NOT NULL additions (default: false)
+-allow-drop   Allow destructive statements when -guard is specified (default: false)
+-allow-narrowing
+              Do not treat column changes that may truncate values, such as
+              shorter VARCHARs or NOT NULL additions, as destructive with -guard
+              or -skip-destructive. Type changes such as VARCHAR to INT are
+              still destructive (default: false)
+-skip-destructive
+              Omit destructive statements from the output (default: false)
+-histograms   Update histograms declared with ANALYZE TABLE ... UPDATE HISTOGRAM
+              after the columns they describe change (default: false)
+-disable-fk-checks[=true]
+              Surround the output with SET FOREIGN_KEY_CHECKS = 0 and 1
+              (default: same as -t)
+-if-not-exists[=true]
+              Always, or with -if-not-exists=false never, create tables with
+              CREATE TABLE IF NOT EXISTS (default: as in the "after" schema)
+-case-insensitive
+              Compare names of tables, columns, and indexes case insensitively,
+              as done with lower_case_table_names (default: false)
+-server-charset name
+-server-collation name
+              Compare columns as if they declared the character set and
+              collation inherited from the table or the server, using the
+              given server defaults
+-ignore-display-width
+              Ignore display widths of integer columns, such as INT(11),
+              when comparing columns (default: false)
+-display-width[=true]
+              Include display widths of integer columns in the output.
+              Disable for MySQL 8.0.19 or later (default: true)
+-rename-indexes
+              Rename indexes that only changed their names using RENAME INDEX
+              (MySQL 5.7 or later) instead of dropping and adding them
+              (default: false)
+-ignore-generated-symbols
+              Match foreign keys declared without a symbol with those whose
+              symbols were generated by the server, such as users_ibfk_1,
+              instead of dropping and adding them (default: false)
+-sort-by-name  Generate statements in the order of the names of the tables,
+              columns, and indexes, instead of their order in the schemas
+              (default: false)
+-dependency-order
+              Process the tables so that each table comes after the tables
+              that its foreign keys reference, instead of their order in
+              the schemas. -sort-by-name takes precedence (default: false)
+-separate-foreign-keys
+              Drop and add the foreign keys of altered tables in blocks of
+              their own, before and after all other statements (default: false)
+-dialect name  Dialect of the schemas, "mysql", "mariadb", "tidb", or
+              "postgres". The MariaDB and TiDB dialects accept their specific
+              table options, column types, and attributes. The postgres
+              dialect (experimental) reads PostgreSQL schemas, such as the
+              output of pg_dump -s, and converts them to MySQL tables
+              (default: mysql)
+-mysql-version version
+              Target the given MySQL version, such as 5.7 or 8.0.16. Syntax
+              that the version does not support is rejected, and statements
+              that it does not support are avoided. The server charset and
+              -ignore-display-width follow the version unless given
+-ansi-quotes  Read strings quoted by double quotes as identifiers, as with
+              the ANSI_QUOTES SQL mode. SET sql_mode statements of the
+              schemas are followed regardless (default: false)
+-table-options
+              Compare table options such as COMMENT or ROW_FORMAT. ENGINE is
+              always compared unless ignored (default: false)
+-explicit-row-format
+              With -table-options, set a row format that changes to DEFAULT
+              to the default of the engine, such as DYNAMIC (default: false)
+-ignore-comments
+              Do not generate changes that only affect the comments of
+              columns and tables (default: false)
+-separate-comments
+              Generate changes that only affect the comments of columns and
+              tables after all the other statements (default: false)
+-trim-comments
+              Compare comments without their leading and trailing whitespace
+              (default: false)
+-temporary-tables[=true]
+              Compare TEMPORARY tables like any other table. Disable to
+              exclude them from both schemas (default: true)
+-ignore-tables patterns
+              Comma separated patterns of tables to exclude from the
+              comparison, such as "tmp_*,%%_archive"
+-table pattern
+              Only compare the tables matching the pattern, such as "users"
+              or "user_*". May be given multiple times, or with comma
+              separated patterns
+-exclude-table pattern
+              Exclude the tables matching the pattern from the comparison,
+              as -ignore-tables. May be given multiple times
+-ignore-columns patterns
+              Comma separated patterns of columns to exclude from the
+              comparison, such as "updated_at,users.last_login"
+-ignore-table-options patterns
+              Comma separated patterns of table options to exclude from the
+              comparison, such as "AUTO_INCREMENT,COMMENT"
+-unified      Print out the CREATE TABLE statements of the tables that change
+              as a unified diff, instead of the statements that migrate
+              them, for reviewing the changes (default: false)
+-text         Print out a line based diff of the whole schemas, formatted in
+              the same way, without comparing their tables. Only -color,
+              -sort-by-name, -display-width, and the options to read the
+              schemas apply (default: false)
+-color when   Color the output of -unified and -text, "auto", "always", or "never".
+              With "auto", the output is colored when it is written to a
+              terminal, and NO_COLOR is not set (default: auto)
+-summary      Print out the number of changes of each kind instead of
+              the statements (default: false)
+-json         Output the changes as JSON, with the table, kind of object,
+              action, and SQL statement of each change (default: false)
+-apply        Execute the statements against the database given as "before",
+              which must be a mysql:// source. The plan of the changes is
+              printed, and they are executed after confirmation only if
+              -dry-run=false is given (default: false)
+-dry-run[=true]
+              Print the plan of the changes that -apply would execute
+              without executing them (default: true)
+-auto-approve Execute the changes with -apply -dry-run=false without
+              asking for confirmation (default: false)
+-continue-on-error
+              Execute the remaining statements when a statement fails
+              with -apply (default: false)
+-large-table rows
+              With -apply, look up the number of rows of the tables that
+              are altered after printing out the plan, and flag those with
+              at least the given number of rows as long running. 0
+              disables the estimate (default: 1000000)
+-version-table name
+              Record each -apply in the given table of the database, such
+              as schemalex_version, with the hash of the schema, the
+              statements, their status, and timestamps. The table is
+              created if needed, and is left out of the comparison
+              (default: none)
+-verify server
+              Check the statements against a real server instead of
+              printing them: the tables of "before" are created in a
+              temporary database, the statements are executed, and the
+              result is compared with "after". The server is a mysql://
+              URI, or "docker" or "docker:image" to run a throwaway MySQL
+              container (default image: mysql:8.0, or the version given
+              to -mysql-version)
+-exit-code    Exit with 1 if there are differences, and with 2 if an
+              error occurred (default: false)
+-quiet        Do not print out the result, such as with -exit-code to only
+              tell whether the schemas differ (default: false)
+-check        Print out nothing, and exit with 0 if the schemas match, with
+              1 if there are differences, and with 2 if an error occurred,
+              as -exit-code, for CI pipelines. Errors are still reported to stderr
+              (default: false)
+-watch        Print out the result again each time the local files or
+              directories given as "before" or "after" change, until
+              interrupted. Can not be used with -o, -apply, -exit-code,
+              or -check (default: false)
 
 "before" and "after" may be a file path, or a URI.
-Special URI schemes "mysql" and "local-git" are supported on top of
-"file". If the special path "-" is used, it is treated as stdin
+Special URI schemes "mysql", "local-git", "http", and "https" are
+supported on top of "file". If the special path "-" is used, it is
+treated as stdin. If a directory is given, the *.sql files in it are
+read in lexical order
 
 Examples:
 
@@ -50,6 +317,13 @@ Examples:
 * Compare file in local git repository against local file
   schemalex "local-git:///path/to/repo?file=foo.sql&commitish=deadbeaf" /path/to/file
 
+* Compare the previous commit of a file in local git repository against
+  the working tree
+  schemalex local-git:schema.sql@HEAD~1 schema.sql
+
+* Compare schema published at a URL against local file
+  schemalex https://example.com/schema.sql /path/to/file
+
 * Compare schema from stdin against local file
 	.... | schemalex - /path/to/file
 
@@ -57,9 +331,79 @@ Examples:
 	}
 	flag.BoolVar(&version, "v", false, "")
 	flag.BoolVar(&txn, "t", true, "")
+	flag.BoolVar(&startTxn, "start-transaction", false, "")
+	flag.StringVar(&delimiter, "delimiter", ";", "")
+	flag.BoolVar(&singleLine, "single-line", false, "")
+	flag.BoolVar(&columnOrder, "column-order", false, "")
+	flag.BoolVar(&guard, "guard", false, "")
+	flag.BoolVar(&allowDrop, "allow-drop", false, "")
+	flag.BoolVar(&allowNarrowing, "allow-narrowing", false, "")
+	flag.BoolVar(&skipDestructive, "skip-destructive", false, "")
+	flag.BoolVar(&histograms, "histograms", false, "")
+	flag.BoolVar(&disableFKChecks, "disable-fk-checks", false, "")
+	flag.BoolVar(&ifNotExists, "if-not-exists", false, "")
+	flag.BoolVar(&tableOptions, "table-options", false, "")
+	flag.BoolVar(&explicitRowFormat, "explicit-row-format", false, "")
+	flag.BoolVar(&ignoreComments, "ignore-comments", false, "")
+	flag.BoolVar(&temporaryTables, "temporary-tables", true, "")
+	flag.BoolVar(&separateComments, "separate-comments", false, "")
+	flag.BoolVar(&trimComments, "trim-comments", false, "")
+	flag.BoolVar(&caseInsensitive, "case-insensitive", false, "")
+	flag.StringVar(&serverCharset, "server-charset", "", "")
+	flag.StringVar(&serverCollation, "server-collation", "", "")
+	flag.BoolVar(&ignoreDisplayWidth, "ignore-display-width", false, "")
+	flag.BoolVar(&displayWidth, "display-width", true, "")
+	flag.BoolVar(&renameIndexes, "rename-indexes", false, "")
+	flag.BoolVar(&ignoreSymbols, "ignore-generated-symbols", false, "")
+	flag.BoolVar(&sortByName, "sort-by-name", false, "")
+	flag.BoolVar(&dependencyOrder, "dependency-order", false, "")
+	flag.BoolVar(&separateFKs, "separate-foreign-keys", false, "")
+	flag.StringVar(&mysqlVersion, "mysql-version", "", "")
+	flag.BoolVar(&ansiQuotes, "ansi-quotes", false, "")
+	flag.StringVar(&dialect, "dialect", "mysql", "")
+	flag.StringVar(&ignoreTables, "ignore-tables", "", "")
+	flag.Var(&onlyTables, "table", "")
+	flag.Var(&excludeTables, "exclude-table", "")
+	flag.StringVar(&ignoreColumns, "ignore-columns", "", "")
+	flag.StringVar(&ignoreTableOptions, "ignore-table-options", "", "")
+	flag.BoolVar(&unified, "unified", false, "")
+	flag.BoolVar(&textDiff, "text", false, "")
+	flag.StringVar(&colorMode, "color", "auto", "")
+	flag.BoolVar(&summary, "summary", false, "")
+	flag.BoolVar(&asJSON, "json", false, "")
+	flag.BoolVar(&applyChanges, "apply", false, "")
+	flag.StringVar(&verifyServer, "verify", "", "")
+	flag.BoolVar(&dryRun, "dry-run", true, "")
+	flag.BoolVar(&autoApprove, "auto-approve", false, "")
+	flag.BoolVar(&continueOnError, "continue-on-error", false, "")
+	flag.StringVar(&versionTable, "version-table", "", "")
+	flag.Int64Var(&largeTable, "large-table", apply.DefaultLargeTable, "")
+	flag.BoolVar(&exitCode, "exit-code", false, "")
+	flag.BoolVar(&quiet, "quiet", false, "")
+	flag.BoolVar(&check, "check", false, "")
 	flag.StringVar(&outfile, "o", "", "")
+	flag.StringVar(&configFile, "config", "", "")
+	flag.BoolVar(&watchSources, "watch", false, "")
 	flag.Parse()
 
+	args := flag.Args()
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		sources, err := cfg.apply(flag.CommandLine)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			if applyChanges && sources != nil {
+				return errors.New(`-apply requires "before" and "after" to be given as arguments, not in the config file`)
+			}
+			args = sources
+		}
+	}
+
 	if version {
 		fmt.Printf(
 			"schemalex version %s, built with go %s for %s/%s\n",
@@ -71,13 +415,63 @@ Examples:
 		return nil
 	}
 
-	if flag.NArg() != 2 {
+	if len(args) != 2 {
 		flag.Usage()
 		return errors.New("wrong number of arguments")
 	}
 
+	if args[0] == "-" && args[1] == "-" {
+		return errors.New(`stdin ("-") can only be used for one of "before" and "after"`)
+	}
+
+	var watched []string
+	if watchSources {
+		if len(outfile) > 0 || applyChanges || exitCode || check {
+			return errors.New(`-watch can not be used with -o, -apply, -exit-code, or -check`)
+		}
+		for _, arg := range args {
+			if arg == "-" {
+				return errors.New(`-watch can not be used with stdin ("-")`)
+			}
+			if path, ok := watch.Path(arg); ok {
+				watched = append(watched, path)
+			}
+		}
+		if len(watched) == 0 {
+			return errors.New(`-watch requires "before" or "after" to be a local file or directory`)
+		}
+	}
+
+	if verifyServer != "" && (applyChanges || watchSources || textDiff || unified || summary || asJSON || quiet || check) {
+		return errors.New(`-verify can not be used with -apply, -watch, -text, -unified, -summary, -json, -quiet, or -check`)
+	}
+
+	if textDiff && (unified || summary || asJSON || applyChanges) {
+		return errors.New(`-text can not be used with -unified, -summary, -json, or -apply`)
+	}
+
+	if check {
+		quiet = true
+	}
+	if versionTable != "" && !applyChanges {
+		return errors.New(`-version-table can only be used with -apply`)
+	}
+
+	if quiet && (len(outfile) > 0 || applyChanges) {
+		return errors.New(`-quiet and -check can not be used with -o or -apply`)
+	}
+
 	var dst io.Writer = os.Stdout
-	if len(outfile) > 0 {
+	if quiet {
+		dst = ioutil.Discard
+	}
+	var outdir string
+	if len(outfile) > 0 && isOutputDir(outfile) {
+		if applyChanges || summary || asJSON {
+			return errors.New(`a directory given to -o can not be used with -apply, -summary or -json`)
+		}
+		outdir = outfile
+	} else if len(outfile) > 0 {
 		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 		if err != nil {
 			return errors.Wrapf(err, `failed to open file %s for writing`, outfile)
@@ -86,21 +480,232 @@ Examples:
 		defer f.Close()
 	}
 
-	fromSource, err := schemalex.NewSchemaSource(flag.Arg(0))
+	fromSource, err := schemalex.NewSchemaSource(args[0])
 	if err != nil {
 		return errors.Wrap(err, `failed to create schema source for "from"`)
 	}
 
-	toSource, err := schemalex.NewSchemaSource(flag.Arg(1))
+	toSource, err := schemalex.NewSchemaSource(args[1])
 	if err != nil {
 		return errors.Wrap(err, `failed to create schema source for "to"`)
 	}
 
-	p := schemalex.New()
-	return diff.Sources(
-		dst,
-		fromSource,
-		toSource,
+	policy := diff.DestructiveAllow
+	switch {
+	case skipDestructive:
+		policy = diff.DestructiveSkip
+	case guard && !allowDrop:
+		policy = diff.DestructiveRefuse
+	}
+
+	comments := diff.CommentCompare
+	switch {
+	case ignoreComments:
+		comments = diff.CommentIgnore
+	case separateComments:
+		comments = diff.CommentSeparate
+	}
+
+	var target schemalex.MySQLVersion
+	if mysqlVersion != "" {
+		v, err := schemalex.ParseMySQLVersion(mysqlVersion)
+		if err != nil {
+			return errors.Wrap(err, `invalid -mysql-version`)
+		}
+		target = v
+	}
+
+	d, err := schemalex.ParseDialect(dialect)
+	if err != nil {
+		return errors.Wrap(err, `invalid -dialect`)
+	}
+
+	p := schemalex.NewParser(schemalex.WithDialect(d), schemalex.WithMySQLVersion(target), schemalex.WithANSIQuotes(ansiQuotes))
+	options := []diff.Option{
 		diff.WithTransaction(txn), diff.WithParser(p),
-	)
+		diff.WithStartTransaction(startTxn),
+		diff.WithDelimiter(delimiter),
+		diff.WithSingleLine(singleLine),
+		diff.WithColumnOrder(columnOrder),
+		diff.WithDestructive(policy),
+		diff.WithAllowNarrowing(allowNarrowing),
+		diff.WithHistograms(histograms),
+		diff.WithTableOptions(tableOptions),
+		diff.WithExplicitRowFormat(explicitRowFormat),
+		diff.WithComments(comments),
+		diff.WithTrimComments(trimComments),
+		diff.WithTemporaryTables(temporaryTables),
+		diff.WithCaseInsensitiveNames(caseInsensitive),
+		diff.WithDisplayWidth(displayWidth),
+		diff.WithRenameIndexes(renameIndexes),
+		diff.WithIgnoreGeneratedSymbols(ignoreSymbols),
+		diff.WithSortByName(sortByName),
+		diff.WithDependencyOrder(dependencyOrder),
+		diff.WithSeparateForeignKeys(separateFKs),
+		diff.WithDialect(d),
+		diff.WithMySQLVersion(target),
+		diff.WithIgnoreTables(splitPatterns(ignoreTables)...),
+		diff.WithIgnoreTables(excludeTables...),
+		diff.WithOnlyTables(onlyTables...),
+		diff.WithIgnoreColumns(splitPatterns(ignoreColumns)...),
+		diff.WithIgnoreTableOptions(splitPatterns(ignoreTableOptions)...),
+		diff.WithJSON(asJSON),
+		schemalex.WithWarningHandler(func(w schemalex.Warning) {
+			fmt.Fprintln(os.Stderr, w)
+		}),
+	}
+
+	if unified || textDiff {
+		color, err := useColor(colorMode, dst)
+		if err != nil {
+			return err
+		}
+		options = append(options, diff.WithUnified(unified), diff.WithColor(color))
+	}
+
+	if serverCharset != "" || serverCollation != "" {
+		options = append(options, diff.WithServerCharset(serverCharset, serverCollation))
+	}
+
+	// only override the defaults, which follow -t, -mysql-version and
+	// the schemas, when given explicitly
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "disable-fk-checks":
+			options = append(options, diff.WithDisableForeignKeyChecks(disableFKChecks))
+		case "if-not-exists":
+			options = append(options, diff.WithIfNotExists(ifNotExists))
+		case "ignore-display-width":
+			options = append(options, diff.WithIgnoreDisplayWidth(ignoreDisplayWidth))
+		}
+	})
+
+	if textDiff && outdir != "" {
+		return errors.New(`-text can not be used with a directory given to -o`)
+	}
+
+	if outdir != "" {
+		n, err := writeTables(outdir, p, fromSource, toSource, options...)
+		if err != nil {
+			return err
+		}
+		if exitCode && n > 0 {
+			return errDifferences
+		}
+		return nil
+	}
+
+	if verifyServer != "" {
+		if outdir != "" {
+			return errors.New(`-verify can not be used with a directory given to -o`)
+		}
+		return runVerify(dst, verifyServer, mysqlVersion, p, fromSource, toSource, options...)
+	}
+
+	if applyChanges {
+		return runApply(dst, args[0], args[1], p, fromSource, toSource, dryRun, autoApprove, continueOnError, versionTable, largeTable, options...)
+	}
+
+	run := func() error {
+		if textDiff {
+			return writeText(dst, p, fromSource, toSource, options...)
+		}
+
+		var s diff.Summary
+		out := dst
+		if summary {
+			out = ioutil.Discard
+		}
+		if err := diff.Sources(out, fromSource, toSource, append(options[:len(options):len(options)], diff.WithSummary(&s))...); err != nil {
+			return err
+		}
+
+		if summary {
+			if _, err := io.WriteString(dst, s.String()); err != nil {
+				return errors.Wrap(err, `failed to write summary`)
+			}
+		}
+
+		if (exitCode || check) && !s.IsEmpty() {
+			return errDifferences
+		}
+		return nil
+	}
+
+	if watchSources {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		defer signal.Stop(sigCh)
+		go func() {
+			select {
+			case <-sigCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		watch.Run(ctx, watched, watchInterval, func(changed []string) {
+			if changed != nil {
+				log.Printf("changed: %s", strings.Join(changed, ", "))
+			}
+			if err := run(); err != nil {
+				log.Print(err)
+			}
+			// separate the results, as the statements do not end with
+			// a newline
+			fmt.Fprintln(dst)
+		})
+		return nil
+	}
+	return run()
+}
+
+// useColor returns true if the output written to dst should be colored
+// with -color=mode
+func useColor(mode string, dst io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		f, ok := dst.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, errors.Errorf(`invalid -color %s: expected "auto", "always", or "never"`, mode)
+}
+
+// watchInterval is how often the files are checked with -watch
+const watchInterval = 500 * time.Millisecond
+
+// patternList is a flag that may be given multiple times, each time
+// with one or more comma separated patterns
+type patternList []string
+
+func (l *patternList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *patternList) Set(s string) error {
+	*l = append(*l, splitPatterns(s)...)
+	return nil
+}
+
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/apply"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/schemasource"
+)
+
+// containerTimeout is how long runVerify waits for the server of a
+// container to accept connections
+const containerTimeout = 2 * time.Minute
+
+// runVerify executes the changes from "before" to "after" in a scratch
+// database, and checks that the result matches "after". server is
+// either a mysql:// URI of a server on which a temporary database is
+// created and dropped, or "docker" or "docker:image" to run a
+// throwaway MySQL container. The image defaults to the version given
+// to -mysql-version, or to mysql:8.0.
+func runVerify(dst io.Writer, server, mysqlVersion string, p *schemalex.Parser, from, to schemalex.SchemaSource, options ...diff.Option) error {
+	fromStmts, err := parseSchema(p, from)
+	if err != nil {
+		return errors.Wrap(err, `failed to read "before"`)
+	}
+	toStmts, err := parseSchema(p, to)
+	if err != nil {
+		return errors.Wrap(err, `failed to read "after"`)
+	}
+
+	ctx := context.Background()
+	var dsn string
+	var timeout time.Duration
+	switch {
+	case server == "docker" || strings.HasPrefix(server, "docker:"):
+		image := strings.TrimPrefix(strings.TrimPrefix(server, "docker"), ":")
+		if image == "" {
+			image = "mysql:8.0"
+			if mysqlVersion != "" {
+				image = "mysql:" + mysqlVersion
+			}
+		}
+		addr, stop, err := startContainer(ctx, image)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		dsn = "root@tcp(" + addr + ")/"
+		timeout = containerTimeout
+	case strings.HasPrefix(server, "mysql://"):
+		dsn = strings.TrimPrefix(server, "mysql://")
+	default:
+		return errors.New(`-verify requires a mysql:// server, "docker", or "docker:image"`)
+	}
+
+	cfg, err := schemasource.ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+	cfg.DBName = ""
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return errors.Wrap(err, `failed to open connection to server`)
+	}
+	defer db.Close()
+	if err := waitForServer(ctx, db, timeout); err != nil {
+		return err
+	}
+
+	b := make([]byte, 4)
+	rand.Read(b)
+	name := fmt.Sprintf("schemalex_verify_%x", b)
+	if _, err := db.ExecContext(ctx, "CREATE DATABASE `"+name+"`"); err != nil {
+		return errors.Wrap(err, `failed to create scratch database`)
+	}
+	defer db.ExecContext(ctx, "DROP DATABASE `"+name+"`")
+
+	cfg.DBName = name
+	scratch, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return errors.Wrap(err, `failed to open connection to scratch database`)
+	}
+	defer scratch.Close()
+
+	changes, err := apply.Verify(ctx, scratch, fromStmts, toStmts, append(options[:len(options):len(options)], apply.WithReport(dst))...)
+	if err != nil {
+		return errors.Wrap(err, `verification failed`)
+	}
+	fmt.Fprintf(dst, "Verified %d changes: the result matches \"after\".\n", len(changes))
+	return nil
+}
+
+// startContainer runs a MySQL container from the image, which accepts
+// root without a password on a random local port, and returns the
+// address of the server, and a function that removes the container
+func startContainer(ctx context.Context, image string) (string, func(), error) {
+	out, err := exec.CommandContext(ctx, "docker", "run", "-d", "--rm",
+		"-e", "MYSQL_ALLOW_EMPTY_PASSWORD=yes",
+		"-p", "127.0.0.1::3306",
+		image,
+	).Output()
+	if err != nil {
+		return "", nil, errors.Wrapf(err, `failed to start container from %s`, image)
+	}
+	id := strings.TrimSpace(string(out))
+	stop := func() { exec.Command("docker", "stop", id).Run() }
+
+	out, err = exec.CommandContext(ctx, "docker", "port", id, "3306/tcp").Output()
+	if err != nil {
+		stop()
+		return "", nil, errors.Wrap(err, `failed to find the port of the container`)
+	}
+	// the first line is the address bound to 127.0.0.1
+	addr := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	return addr, stop, nil
+}
+
+// waitForServer waits until the server accepts connections, for up to
+// timeout, as the server of a new container takes a while to start
+func waitForServer(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrap(err, `failed to connect to server`)
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -7,10 +7,15 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/watch"
 	"github.com/eihigh/schemalex/lint"
 )
 
@@ -27,6 +32,16 @@ func _main() error {
 	var showVersion bool
 	var outfile string
 	var indentNum int
+	var disable string
+	var severities string
+	var maxVarcharLength int
+	var namePattern string
+	var failOn string
+	var target string
+	var defaultCharset string
+	var charsetWidths string
+	var mysqlVersion string
+	var watchSource bool
 
 	flag.Usage = func() {
 		fmt.Printf(`schemalint version %s
@@ -37,10 +52,47 @@ schemalint [options...] source
 -v            Print out the version and exit
 -o file	      Output the result to the specified file (default: stdout)
 -i number     Number of spaces to insert as indent (default: 2)
+-disable rules
+              Comma separated list of rules that are not run
+-severity rule=level,...
+              Change the severity of the findings of rules.
+              Levels are "info", "warning", and "error"
+-max-varchar-length number
+              Longest VARCHAR allowed by the "varchar-length" rule
+              (default: 255)
+-name-pattern regexp
+              Pattern that table and column names must match for the
+              "naming" rule (default: ^[a-z][a-z0-9_]*$)
+-default-charset name
+              Character set of the tables that do not declare one, used
+              by the "row-size" and "index-length" rules to compute the
+              size of string columns (default: utf8mb4)
+-charset-widths name=bytes,...
+              Maximum number of bytes per character of character sets
+              that schemalint does not know, or whose width differs
+-mysql-version version
+              Target the given MySQL version, such as 5.7 or 8.0.16. Syntax
+              that the version does not support is rejected, and the
+              "reserved-word" rule reports its reserved words (default:
+              the latest version)
+-fail-on level
+              Exit with status 1 if any finding is at least as severe
+              as level (default: never)
+-target name  Also run the rules specific to the server that the schema
+              is deployed to. "vitess" (or "planetscale") reports foreign
+              keys, AUTO_INCREMENT columns that are not unique across
+              shards, and tables that online schema changes can not copy
+-watch        Lint the source again each time the local file or directory
+              changes, until interrupted. Can not be used with -o
+
+Findings of the rules are reported to stderr. The rules are
+%s.
 
 "source" may be a file path, or a URI.
-Special URI schemes "mysql" and "local-git" are supported on top of
-"file". If the special path "-" is used, it is treated as stdin.
+Special URI schemes "mysql", "local-git", "http", and "https" are
+supported on top of "file". If the special path "-" is used, it is
+treated as stdin. If a directory is given, the *.sql files in it are
+read in lexical order.
 
 Examples:
 
@@ -53,15 +105,26 @@ Examples:
 
 * Lint a file in local git repository 
   schemalint "local-git:///path/to/repo?file=foo.sql&commitish=deadbeaf"
+  schemalint local-git:/path/to/repo/foo.sql@deadbeaf
 
 * Lint schema from stdin against local file
 	.... | schemalint -
 
-`, version)
+`, version, strings.Join(lint.Rules(), ", "))
 	}
 	flag.BoolVar(&showVersion, "v", false, "")
 	flag.StringVar(&outfile, "o", "", "")
 	flag.IntVar(&indentNum, "i", 2, "")
+	flag.StringVar(&disable, "disable", "", "")
+	flag.StringVar(&severities, "severity", "", "")
+	flag.IntVar(&maxVarcharLength, "max-varchar-length", 255, "")
+	flag.StringVar(&namePattern, "name-pattern", `^[a-z][a-z0-9_]*$`, "")
+	flag.StringVar(&failOn, "fail-on", "never", "")
+	flag.StringVar(&target, "target", "", "")
+	flag.StringVar(&defaultCharset, "default-charset", "utf8mb4", "")
+	flag.StringVar(&charsetWidths, "charset-widths", "", "")
+	flag.StringVar(&mysqlVersion, "mysql-version", "", "")
+	flag.BoolVar(&watchSource, "watch", false, "")
 	flag.Parse()
 
 	if showVersion {
@@ -84,6 +147,81 @@ Examples:
 		return errors.New("wrong number of arguments")
 	}
 
+	options := []lint.Option{
+		lint.WithMaxVarcharLength(maxVarcharLength),
+		lint.WithNamePattern(namePattern),
+		lint.WithDefaultCharset(defaultCharset),
+	}
+	if len(disable) > 0 {
+		options = append(options, lint.WithDisableRules(strings.Split(disable, ",")...))
+	}
+	if len(severities) > 0 {
+		for _, v := range strings.Split(severities, ",") {
+			i := strings.IndexByte(v, '=')
+			if i < 0 {
+				return errors.Errorf(`invalid severity %s: expected rule=level`, v)
+			}
+			s, err := lint.ParseSeverity(v[i+1:])
+			if err != nil {
+				return errors.Wrapf(err, `invalid severity for rule %s`, v[:i])
+			}
+			options = append(options, lint.WithSeverity(v[:i], s))
+		}
+	}
+
+	if len(charsetWidths) > 0 {
+		widths := make(map[string]int)
+		for _, v := range strings.Split(charsetWidths, ",") {
+			i := strings.IndexByte(v, '=')
+			if i < 0 {
+				return errors.Errorf(`invalid charset width %s: expected name=bytes`, v)
+			}
+			n, err := strconv.Atoi(v[i+1:])
+			if err != nil || n <= 0 {
+				return errors.Errorf(`invalid width for charset %s: %s`, v[:i], v[i+1:])
+			}
+			widths[v[:i]] = n
+		}
+		options = append(options, lint.WithCharsetWidths(widths))
+	}
+
+	if mysqlVersion != "" {
+		v, err := schemalex.ParseMySQLVersion(mysqlVersion)
+		if err != nil {
+			return errors.Wrap(err, `invalid -mysql-version`)
+		}
+		options = append(options, lint.WithMySQLVersion(v))
+	}
+
+	if len(target) > 0 {
+		t, err := lint.ParseTarget(target)
+		if err != nil {
+			return errors.Wrap(err, `invalid -target`)
+		}
+		options = append(options, lint.WithTarget(t))
+	}
+
+	var threshold lint.Severity
+	if failOn != "never" {
+		s, err := lint.ParseSeverity(failOn)
+		if err != nil {
+			return errors.Wrap(err, `invalid -fail-on level`)
+		}
+		threshold = s
+	}
+
+	var watched []string
+	if watchSource {
+		if len(outfile) > 0 {
+			return errors.New(`-watch can not be used with -o`)
+		}
+		path, ok := watch.Path(flag.Arg(0))
+		if !ok {
+			return errors.New(`-watch requires the source to be a local file or directory`)
+		}
+		watched = append(watched, path)
+	}
+
 	var dst io.Writer = os.Stdout
 	if len(outfile) > 0 {
 		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -104,9 +242,45 @@ Examples:
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := linter.Run(ctx, src, dst, lint.WithIndent(" ", indentNum)); err != nil {
-		return errors.Wrap(err, `failed to lint source`)
+	options = append(options, lint.WithIndent(" ", indentNum), lint.WithWarnings(os.Stderr))
+	run := func() error {
+		var findings []lint.Finding
+		if err := linter.Run(ctx, src, dst, append(options[:len(options):len(options)], lint.WithFindings(&findings))...); err != nil {
+			return errors.Wrap(err, `failed to lint source`)
+		}
+
+		if failOn != "never" {
+			for _, f := range findings {
+				if f.Severity >= threshold {
+					return errors.Errorf(`found %s at %s`, f.Severity, f.Position)
+				}
+			}
+		}
+		return nil
 	}
 
-	return nil
+	if watchSource {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		defer signal.Stop(sigCh)
+		go func() {
+			select {
+			case <-sigCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		watch.Run(ctx, watched, 500*time.Millisecond, func(changed []string) {
+			if changed != nil {
+				log.Printf("changed: %s", strings.Join(changed, ", "))
+			}
+			if err := run(); err != nil && ctx.Err() == nil {
+				log.Printf("%s", err)
+			}
+			fmt.Fprintln(dst)
+		})
+		return nil
+	}
+	return run()
 }
@@ -0,0 +1,18 @@
+package schemalex
+
+import "github.com/eihigh/schemalex/internal/option"
+
+const optkeyUpstreamCompat = "upstream-compat"
+
+// WithUpstreamCompat specifies if the tables should be parsed as
+// schemalex/schemalex parsed them, for use with NewParser. When it is
+// true, indexes declared without a name are left unnamed, rather than
+// named after their first column, and the columns of the primary key
+// are only NOT NULL if they are declared so. See
+// model.Table.NormalizeUpstream.
+//
+// diff.WithUpstreamCompat passes this option to the parser, along with
+// the options that restore the output of the diff of upstream.
+func WithUpstreamCompat(b bool) Option {
+	return option.New(optkeyUpstreamCompat, b)
+}
@@ -0,0 +1,51 @@
+package schemalex_test
+
+import (
+	"io"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/model"
+)
+
+// The declarations below pin down the API of upstream
+// github.com/schemalex/schemalex, so that code written against it
+// keeps compiling with only a change of import paths. New features
+// must be added as new functions or options, never by changing the
+// signatures listed here.
+var (
+	_ func() *schemalex.Parser                                                              = schemalex.New
+	_ func(*schemalex.Parser, []byte) (model.Stmts, error)                                  = (*schemalex.Parser).Parse
+	_ func(*schemalex.Parser, string) (model.Stmts, error)                                  = (*schemalex.Parser).ParseString
+	_ func(*schemalex.Parser, string) (model.Stmts, error)                                  = (*schemalex.Parser).ParseFile
+	_ func(string) (schemalex.SchemaSource, error)                                          = schemalex.NewSchemaSource
+	_ func(io.Reader) schemalex.SchemaSource                                                = schemalex.NewReaderSource
+	_ func(string) schemalex.SchemaSource                                                   = schemalex.NewMySQLSource
+	_ func(string) schemalex.SchemaSource                                                   = schemalex.NewLocalFileSource
+	_ func(string, string, string) schemalex.SchemaSource                                   = schemalex.NewLocalGitSource
+	_ func(schemalex.SchemaSource, io.Writer) error                                         = schemalex.SchemaSource.WriteSchema
+	_ func(schemalex.ParseError) string                                                     = schemalex.ParseError.File
+	_ func(schemalex.ParseError) int                                                        = schemalex.ParseError.Line
+	_ func(schemalex.ParseError) int                                                        = schemalex.ParseError.Col
+	_ func(schemalex.ParseError) string                                                     = schemalex.ParseError.Message
+	_ func(schemalex.ParseError) bool                                                       = schemalex.ParseError.EOF
+	_ func(schemalex.ParseError) schemalex.Token                                            = schemalex.ParseError.Token
+	_ func(schemalex.ParseError) []schemalex.TokenType                                      = schemalex.ParseError.Expected
+	_ func(schemalex.ParseError) string                                                     = schemalex.ParseError.Excerpt
+	_ func([]byte) *schemalex.Tokenizer                                                     = schemalex.Tokenize
+	_ func(*schemalex.Tokenizer) bool                                                       = (*schemalex.Tokenizer).Next
+	_ func(*schemalex.Tokenizer) schemalex.Token                                            = (*schemalex.Tokenizer).Token
+	_ func(*schemalex.Tokenizer) []byte                                                     = (*schemalex.Tokenizer).Text
+	_ string                                                                                = schemalex.Version
+	_ func(io.Writer, model.Stmts, model.Stmts, ...diff.Option) error                       = diff.Statements
+	_ func(io.Writer, string, string, ...diff.Option) error                                 = diff.Strings
+	_ func(io.Writer, string, string, ...diff.Option) error                                 = diff.Files
+	_ func(io.Writer, schemalex.SchemaSource, schemalex.SchemaSource, ...diff.Option) error = diff.Sources
+	_ func(bool) diff.Option                                                                = diff.WithTransaction
+	_ func(*schemalex.Parser) diff.Option                                                   = diff.WithParser
+	_ func(io.Writer, interface{}, ...format.Option) error                                  = format.SQL
+	_ func(string, int) format.Option                                                       = format.WithIndent
+	_ schemalex.Option                                                                      = diff.WithTransaction(true)
+	_ schemalex.Option                                                                      = format.WithIndent(" ", 2)
+)
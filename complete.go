@@ -0,0 +1,123 @@
+package schemalex
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Completion is a token that may be written at the cursor, as returned
+// by Parser.Complete
+type Completion struct {
+	// Type is IDENT for the keywords that are read as identifiers, such
+	// as the names of the spatial types
+	Type TokenType
+	// Text is the keyword or the punctuation to insert, or empty for
+	// the names, strings and numbers, which are up to the user
+	Text string
+}
+
+// punctuation is the text of the tokens that are not keywords
+var punctuation = map[TokenType]string{
+	LPAREN:    "(",
+	RPAREN:    ")",
+	COMMA:     ",",
+	SEMICOLON: ";",
+	DOT:       ".",
+	EQUAL:     "=",
+}
+
+// Complete returns the tokens that the parser accepts at offset in src,
+// such as the column types after the name of a column in a partially
+// written CREATE TABLE statement, to power the autocompletion of
+// editors. The source after offset is not read.
+//
+// If a word ends at offset, it is the beginning of the token being
+// written, which the completions replace: only the keywords that start
+// with it, regardless of case, are returned, along with the names and
+// the other tokens that have no fixed text. Nothing is returned within
+// comments and quoted strings, or where the parser does not know what
+// may follow. The completions are in the order the parser looks for
+// them, which puts the most common ones first.
+//
+// Dialects made available by RegisterDialect do not support completion.
+func (p *Parser) Complete(src []byte, offset int) ([]Completion, error) {
+	if !isBuiltinDialect(p.dialect) {
+		return nil, errors.Errorf(`completion is not supported by dialect %s`, p.dialect)
+	}
+	if offset < 0 || offset > len(src) {
+		return nil, errors.Errorf(`offset %d is out of range`, offset)
+	}
+
+	start := offset
+	for start > 0 && isWordByte(src[start-1]) {
+		start--
+	}
+	word := string(src[start:offset])
+
+	// the word is replaced by a character that the lexer does not
+	// accept, so that the parser fails where the word starts, even if
+	// the statement could end there
+	input := bytes.TrimPrefix(src[:start], byteOrderMark)
+	pos := len(input)
+	input = append(input[:pos:pos], 0x01)
+
+	parser := *p
+	parser.tolerant = true
+	parser.warn = nil
+	err := parser.parse(context.Background(), input, 1, nil, newSession(p.sqlMode), func(model.Stmt) error { return nil })
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		return nil, err
+	}
+
+	list := []Completion{}
+	for _, pe := range errs {
+		t := pe.Token()
+		if t.Type != ILLEGAL || t.Pos != pos {
+			continue
+		}
+		for _, typ := range pe.Expected() {
+			switch typ {
+			case EOF, SPACE, COMMENT_IDENT:
+				continue
+			}
+			c := Completion{Type: typ, Text: tokenText(typ)}
+			if c.Text != "" && !hasPrefixFold(c.Text, word) {
+				continue
+			}
+			list = append(list, c)
+		}
+		if e, ok := pe.(*parseError); ok {
+			for _, w := range e.words {
+				if hasPrefixFold(w, word) {
+					list = append(list, Completion{Type: IDENT, Text: w})
+				}
+			}
+		}
+	}
+	return list, nil
+}
+
+// tokenText returns the text of the tokens of the given type, or an
+// empty string if it varies
+func tokenText(typ TokenType) string {
+	if s, ok := punctuation[typ]; ok {
+		return s
+	}
+	if s := typ.String(); keywordIdentMap[s] == typ {
+		return s
+	}
+	return ""
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || isLetter(rune(c)) || isDigit(rune(c))
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
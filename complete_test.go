@@ -0,0 +1,58 @@
+package schemalex_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{src: "", expected: []string{"CREATE", "ANALYZE", "DROP", "SET", "USE", ";"}},
+		{src: "CREATE TABLE foo (id varc", expected: []string{"VARCHAR"}},
+		{src: "CREATE TABLE foo (id geo", expected: []string{"GEOMETRY", "GEOMETRYCOLLECTION", "GEOMCOLLECTION"}},
+		{src: "CREATE TABLE foo (id INT) eng", expected: []string{"ENGINE"}},
+		{src: "CREATE TABLE foo (id INT, FOREIGN KEY (id) ", expected: []string{"REFERENCES", ")", ","}},
+		{src: "CREATE TABLE foo (id INT, FOREIGN KEY (id) REFERENCES bar (id) ", expected: []string{"MATCH", "ON", ")", ","}},
+		{src: "CREATE TABLE foo (id INT, KEY ", expected: []string{"", "", "USING", "("}},
+		{src: "CREATE TABLE foo (id INT NOT NULL, name VARCHAR(10) NOT NULL CO", expected: []string{"COLLATE", "COMMENT"}},
+		// the errors of the previous statements do not get in the way
+		{src: "CREATE TABLE bar (id FOO);\nCREATE TABLE foo (id INT U", expected: []string{"UNIQUE", "UNSIGNED"}},
+		// within strings and comments
+		{src: "CREATE TABLE foo (id INT COMMENT 'ab", expected: []string{}},
+		{src: "CREATE TABLE foo (id INT /* ab", expected: []string{}},
+	}
+
+	p := schemalex.New()
+	for _, test := range tests {
+		list, err := p.Complete([]byte(test.src), len(test.src))
+		if !assert.NoError(t, err, "Complete should succeed for %q", test.src) {
+			return
+		}
+		texts := []string{}
+		for _, c := range list {
+			texts = append(texts, c.Text)
+		}
+		if !assert.Equal(t, test.expected, texts, "completions for %q should match", test.src) {
+			return
+		}
+	}
+
+	// the source after the offset is not read
+	src := "CREATE TABLE foo (id VARCHAR(10)) ENGINE=InnoDB;"
+	list, err := p.Complete([]byte(src), len("CREATE TABLE foo (id VARC"))
+	if !assert.NoError(t, err, "Complete should succeed") || !assert.Len(t, list, 1, "one completion should be returned") {
+		return
+	}
+	if !assert.Equal(t, schemalex.VARCHAR, list[0].Type, "completion should match") {
+		return
+	}
+
+	if _, err := p.Complete([]byte(src), len(src)+1); !assert.Error(t, err, "offset past the end should fail") {
+		return
+	}
+}
@@ -0,0 +1,201 @@
+package contract
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ChangeType describes how an element of the contract has changed
+type ChangeType int
+
+// List of possible ChangeType values
+const (
+	ChangeTypeAdded ChangeType = iota + 1
+	ChangeTypeRemoved
+	ChangeTypeModified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeTypeAdded:
+		return "added"
+	case ChangeTypeRemoved:
+		return "removed"
+	case ChangeTypeModified:
+		return "modified"
+	}
+	return "(invalid)"
+}
+
+// Change describes a single difference between two contracts.
+// Path is a dot separated path to the element that has changed,
+// such as `tables.users.columns.email.nullable`. Before and After
+// hold the values of the element, and are nil when the element
+// has been added or removed, respectively.
+type Change struct {
+	Type   ChangeType
+	Path   string
+	Before interface{}
+	After  interface{}
+}
+
+// Compare compares two contracts, and returns the list of changes
+// required to go from `from` to `to`. Changes are listed in a stable
+// order: tables and their elements are visited in the order they
+// appear in the contracts.
+func Compare(from, to *Contract) []*Change {
+	var changes []*Change
+	add := func(typ ChangeType, path string, before, after interface{}) {
+		changes = append(changes, &Change{
+			Type:   typ,
+			Path:   path,
+			Before: before,
+			After:  after,
+		})
+	}
+
+	if from.Version != to.Version {
+		add(ChangeTypeModified, "version", from.Version, to.Version)
+	}
+
+	toTables := make(map[string]*Table)
+	for _, table := range to.Tables {
+		toTables[table.Name] = table
+	}
+
+	fromTables := make(map[string]*Table)
+	for _, table := range from.Tables {
+		fromTables[table.Name] = table
+		path := "tables." + table.Name
+		after, ok := toTables[table.Name]
+		if !ok {
+			add(ChangeTypeRemoved, path, table, nil)
+			continue
+		}
+		compareTable(add, path, table, after)
+	}
+
+	for _, table := range to.Tables {
+		if _, ok := fromTables[table.Name]; !ok {
+			add(ChangeTypeAdded, "tables."+table.Name, nil, table)
+		}
+	}
+	return changes
+}
+
+func compareTable(add func(ChangeType, string, interface{}, interface{}), path string, from, to *Table) {
+	if from.Temporary != to.Temporary {
+		add(ChangeTypeModified, path+".temporary", from.Temporary, to.Temporary)
+	}
+
+	toColumns := make(map[string]*Column)
+	for _, col := range to.Columns {
+		toColumns[col.Name] = col
+	}
+
+	var fromOrder []string
+	fromColumns := make(map[string]*Column)
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = col
+		colpath := path + ".columns." + col.Name
+		after, ok := toColumns[col.Name]
+		if !ok {
+			add(ChangeTypeRemoved, colpath, col, nil)
+			continue
+		}
+		fromOrder = append(fromOrder, col.Name)
+		compareFields(add, colpath, col, after)
+	}
+
+	var toOrder []string
+	for _, col := range to.Columns {
+		if _, ok := fromColumns[col.Name]; !ok {
+			add(ChangeTypeAdded, path+".columns."+col.Name, nil, col)
+			continue
+		}
+		toOrder = append(toOrder, col.Name)
+	}
+
+	// Only report a change in order for the columns that exist on
+	// both sides, as added and removed columns are reported already
+	if !reflect.DeepEqual(fromOrder, toOrder) {
+		add(ChangeTypeModified, path+".columns", fromOrder, toOrder)
+	}
+
+	toIndexes := make(map[string]*Index)
+	for _, idx := range to.Indexes {
+		toIndexes[idx.key()] = idx
+	}
+
+	fromIndexes := make(map[string]*Index)
+	for _, idx := range from.Indexes {
+		key := idx.key()
+		fromIndexes[key] = idx
+		idxpath := path + ".indexes." + key
+		after, ok := toIndexes[key]
+		if !ok {
+			add(ChangeTypeRemoved, idxpath, idx, nil)
+			continue
+		}
+		if !reflect.DeepEqual(idx, after) {
+			add(ChangeTypeModified, idxpath, idx, after)
+		}
+	}
+
+	for _, idx := range to.Indexes {
+		if _, ok := fromIndexes[idx.key()]; !ok {
+			add(ChangeTypeAdded, path+".indexes."+idx.key(), nil, idx)
+		}
+	}
+
+	toOptions := make(map[string]string)
+	for _, opt := range to.Options {
+		toOptions[opt.Key] = opt.Value
+	}
+
+	fromOptions := make(map[string]string)
+	for _, opt := range from.Options {
+		fromOptions[opt.Key] = opt.Value
+		optpath := path + ".options." + opt.Key
+		after, ok := toOptions[opt.Key]
+		switch {
+		case !ok:
+			add(ChangeTypeRemoved, optpath, opt.Value, nil)
+		case after != opt.Value:
+			add(ChangeTypeModified, optpath, opt.Value, after)
+		}
+	}
+
+	for _, opt := range to.Options {
+		if _, ok := fromOptions[opt.Key]; !ok {
+			add(ChangeTypeAdded, path+".options."+opt.Key, nil, opt.Value)
+		}
+	}
+
+	switch {
+	case from.Partitioning == nil && to.Partitioning != nil:
+		add(ChangeTypeAdded, path+".partitioning", nil, to.Partitioning)
+	case from.Partitioning != nil && to.Partitioning == nil:
+		add(ChangeTypeRemoved, path+".partitioning", from.Partitioning, nil)
+	case !reflect.DeepEqual(from.Partitioning, to.Partitioning):
+		add(ChangeTypeModified, path+".partitioning", from.Partitioning, to.Partitioning)
+	}
+}
+
+// compareFields compares each field of the given columns, and reports
+// them using the same names as the JSON representation
+func compareFields(add func(ChangeType, string, interface{}, interface{}), path string, from, to *Column) {
+	fv := reflect.ValueOf(from).Elem()
+	tv := reflect.ValueOf(to).Elem()
+	typ := fv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		before := fv.Field(i).Interface()
+		after := tv.Field(i).Interface()
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		add(ChangeTypeModified, path+"."+name, before, after)
+	}
+}
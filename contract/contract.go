@@ -0,0 +1,463 @@
+// Package contract defines the schema contract, a canonical JSON
+// representation of a set of statements.
+//
+// The contract is meant to be the interchange format between schemalex
+// and other tools that need to consume a schema without parsing SQL,
+// such as schema registries. Unlike the SQL produced by the format
+// package, the contract is canonical: tables are normalized, and
+// tables, indexes, and table options are sorted, so two schemas that
+// are semantically identical always produce byte-identical contracts.
+//
+// The structure of the document is described by the JSON Schema in
+// JSONSchema. Every document carries the version of the format it was
+// written in. Fields may be added within a version, but they are never
+// removed or changed in meaning; such changes bump the Version.
+//
+//	{
+//	  "version": 1,
+//	  "tables": [
+//	    {
+//	      "name": "users",
+//	      "columns": [
+//	        { "name": "id", "type": "BIGINT", "length": "20", "unsigned": true, "nullable": false, "auto_increment": true },
+//	        { "name": "email", "type": "VARCHAR", "length": "255", "nullable": false }
+//	      ],
+//	      "indexes": [
+//	        { "name": "PRIMARY", "kind": "PRIMARY KEY", "columns": [ { "name": "id" } ] }
+//	      ],
+//	      "options": [
+//	        { "key": "ENGINE", "value": "InnoDB" }
+//	      ]
+//	    }
+//	  ]
+//	}
+package contract
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Version is the version of the contract format produced by this package
+const Version = 1
+
+// Contract is the root of the schema contract document
+type Contract struct {
+	Version int      `json:"version"`
+	Tables  []*Table `json:"tables"`
+}
+
+// Table describes a table in the contract
+type Table struct {
+	Name         string        `json:"name"`
+	Temporary    bool          `json:"temporary,omitempty"`
+	Columns      []*Column     `json:"columns"`
+	Indexes      []*Index      `json:"indexes,omitempty"`
+	Options      []*Option     `json:"options,omitempty"`
+	Partitioning *Partitioning `json:"partitioning,omitempty"`
+}
+
+// Column describes a column of a table. Columns are listed in the
+// order that they appear in the table.
+type Column struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Length        string   `json:"length,omitempty"`
+	Decimal       string   `json:"decimal,omitempty"`
+	Unsigned      bool     `json:"unsigned,omitempty"`
+	ZeroFill      bool     `json:"zerofill,omitempty"`
+	Binary        bool     `json:"binary,omitempty"`
+	CharacterSet  string   `json:"character_set,omitempty"`
+	Collation     string   `json:"collation,omitempty"`
+	Nullable      bool     `json:"nullable"`
+	Default       *Default `json:"default,omitempty"`
+	AutoIncrement bool     `json:"auto_increment,omitempty"`
+	AutoUpdate    string   `json:"auto_update,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+	EnumValues    []string `json:"enum_values,omitempty"`
+	SetValues     []string `json:"set_values,omitempty"`
+}
+
+// Default describes the default value of a column. Quoted is true
+// when the value is a string literal, and false when it is an
+// expression such as NULL or CURRENT_TIMESTAMP.
+type Default struct {
+	Value  string `json:"value"`
+	Quoted bool   `json:"quoted,omitempty"`
+}
+
+// Index describes an index or a constraint of a table
+type Index struct {
+	Name      string         `json:"name,omitempty"`
+	Symbol    string         `json:"symbol,omitempty"`
+	Kind      string         `json:"kind"`
+	Type      string         `json:"type,omitempty"`
+	Columns   []*IndexColumn `json:"columns"`
+	Reference *Reference     `json:"reference,omitempty"`
+}
+
+// IndexColumn describes a column that is part of an index
+type IndexColumn struct {
+	Name   string `json:"name"`
+	Length string `json:"length,omitempty"`
+	Order  string `json:"order,omitempty"`
+}
+
+// Reference describes the target of a foreign key
+type Reference struct {
+	Table    string         `json:"table"`
+	Columns  []*IndexColumn `json:"columns"`
+	Match    string         `json:"match,omitempty"`
+	OnDelete string         `json:"on_delete,omitempty"`
+	OnUpdate string         `json:"on_update,omitempty"`
+}
+
+// Option describes a table option, such as ENGINE
+type Option struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Partitioning describes the partitioning of a table
+type Partitioning struct {
+	Type        string       `json:"type"`
+	Linear      bool         `json:"linear,omitempty"`
+	Expression  string       `json:"expression,omitempty"`
+	Columns     []string     `json:"columns,omitempty"`
+	Count       string       `json:"count,omitempty"`
+	Definitions []*Partition `json:"definitions,omitempty"`
+}
+
+// Partition describes a single partition definition
+type Partition struct {
+	Name     string `json:"name"`
+	LessThan string `json:"less_than,omitempty"`
+	MaxValue bool   `json:"max_value,omitempty"`
+	ValuesIn string `json:"values_in,omitempty"`
+	Engine   string `json:"engine,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Names of the index kinds used in the contract
+const (
+	KindPrimaryKey = "PRIMARY KEY"
+	KindUnique     = "UNIQUE"
+	KindNormal     = "INDEX"
+	KindFullText   = "FULLTEXT"
+	KindSpatial    = "SPATIAL"
+	KindForeignKey = "FOREIGN KEY"
+)
+
+// New creates a contract from the given statements. Statements other
+// than tables are not part of the contract, and are ignored.
+func New(stmts model.Stmts) (*Contract, error) {
+	c := &Contract{
+		Version: Version,
+		Tables:  []*Table{},
+	}
+
+	for _, stmt := range stmts {
+		t, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		if t.HasLikeTable() {
+			return nil, errors.Errorf(`table %s: CREATE TABLE ... LIKE can not be represented in a contract`, t.Name())
+		}
+
+		table, err := newTable(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to convert table %s`, t.Name())
+		}
+		c.Tables = append(c.Tables, table)
+	}
+
+	sort.Slice(c.Tables, func(i, j int) bool {
+		return c.Tables[i].Name < c.Tables[j].Name
+	})
+	return c, nil
+}
+
+func newTable(t model.Table) (*Table, error) {
+	t, _ = t.Normalize()
+
+	table := &Table{
+		Name:      t.Name(),
+		Temporary: t.IsTemporary(),
+		Columns:   []*Column{},
+	}
+
+	for col := range t.Columns() {
+		table.Columns = append(table.Columns, newColumn(col))
+	}
+
+	for idx := range t.Indexes() {
+		index, err := newIndex(idx)
+		if err != nil {
+			return nil, err
+		}
+		table.Indexes = append(table.Indexes, index)
+	}
+	sort.SliceStable(table.Indexes, func(i, j int) bool {
+		return table.Indexes[i].key() < table.Indexes[j].key()
+	})
+
+	for opt := range t.Options() {
+		table.Options = append(table.Options, &Option{
+			Key:   opt.Key(),
+			Value: opt.Value(),
+		})
+	}
+	sort.SliceStable(table.Options, func(i, j int) bool {
+		return table.Options[i].Key < table.Options[j].Key
+	})
+
+	if t.HasPartitioning() {
+		partitioning, err := newPartitioning(t.Partitioning())
+		if err != nil {
+			return nil, err
+		}
+		table.Partitioning = partitioning
+	}
+	return table, nil
+}
+
+func newColumn(col model.TableColumn) *Column {
+	column := &Column{
+		Name:          col.Name(),
+		Type:          col.Type().String(),
+		Unsigned:      col.IsUnsigned(),
+		ZeroFill:      col.IsZeroFill(),
+		Binary:        col.IsBinary(),
+		Nullable:      col.NullState() != model.NullStateNotNull,
+		AutoIncrement: col.IsAutoIncrement(),
+	}
+
+	if col.HasLength() {
+		l := col.Length()
+		column.Length = l.Length()
+		if l.HasDecimal() {
+			column.Decimal = l.Decimal()
+		}
+	}
+	if col.HasCharacterSet() {
+		column.CharacterSet = col.CharacterSet()
+	}
+	if col.HasCollation() {
+		column.Collation = col.Collation()
+	}
+	if col.HasDefault() {
+		column.Default = &Default{
+			Value:  col.Default(),
+			Quoted: col.IsQuotedDefault(),
+		}
+	}
+	if col.HasAutoUpdate() {
+		column.AutoUpdate = col.AutoUpdate()
+	}
+	if col.HasComment() {
+		column.Comment = col.Comment()
+	}
+	for v := range col.EnumValues() {
+		column.EnumValues = append(column.EnumValues, v)
+	}
+	for v := range col.SetValues() {
+		column.SetValues = append(column.SetValues, v)
+	}
+	return column
+}
+
+func newIndex(idx model.Index) (*Index, error) {
+	index := &Index{}
+	switch {
+	case idx.IsPrimaryKey():
+		index.Kind = KindPrimaryKey
+		index.Name = "PRIMARY"
+	case idx.IsUnique():
+		index.Kind = KindUnique
+	case idx.IsNormal():
+		index.Kind = KindNormal
+	case idx.IsFullText():
+		index.Kind = KindFullText
+	case idx.IsSpatial():
+		index.Kind = KindSpatial
+	case idx.IsForeignKey():
+		index.Kind = KindForeignKey
+	default:
+		return nil, errors.Errorf(`invalid index kind for index %s`, idx.ID())
+	}
+
+	if idx.HasName() && !idx.IsPrimaryKey() {
+		index.Name = idx.Name()
+	}
+	if idx.HasSymbol() {
+		index.Symbol = idx.Symbol()
+	}
+
+	switch {
+	case idx.IsBtree():
+		index.Type = "BTREE"
+	case idx.IsHash():
+		index.Type = "HASH"
+	}
+
+	index.Columns = newIndexColumns(idx.Columns())
+
+	if idx.IsForeignKey() {
+		r := idx.Reference()
+		if r == nil {
+			return nil, errors.Errorf(`foreign key %s does not have a reference`, idx.ID())
+		}
+		index.Reference = &Reference{
+			Table:    r.TableName(),
+			Columns:  newIndexColumns(r.Columns()),
+			OnDelete: referenceOption(r.OnDelete()),
+			OnUpdate: referenceOption(r.OnUpdate()),
+		}
+		switch {
+		case r.MatchFull():
+			index.Reference.Match = "FULL"
+		case r.MatchPartial():
+			index.Reference.Match = "PARTIAL"
+		case r.MatchSimple():
+			index.Reference.Match = "SIMPLE"
+		}
+	}
+	return index, nil
+}
+
+func newIndexColumns(ch chan model.IndexColumn) []*IndexColumn {
+	list := []*IndexColumn{}
+	for col := range ch {
+		c := &IndexColumn{Name: col.Name()}
+		if col.HasLength() {
+			c.Length = col.Length()
+		}
+		switch {
+		case col.IsAscending():
+			c.Order = "ASC"
+		case col.IsDescending():
+			c.Order = "DESC"
+		}
+		list = append(list, c)
+	}
+	return list
+}
+
+func referenceOption(opt model.ReferenceOption) string {
+	switch opt {
+	case model.ReferenceOptionRestrict:
+		return "RESTRICT"
+	case model.ReferenceOptionCascade:
+		return "CASCADE"
+	case model.ReferenceOptionSetNull:
+		return "SET NULL"
+	case model.ReferenceOptionNoAction:
+		return "NO ACTION"
+	}
+	return ""
+}
+
+func newPartitioning(p model.Partitioning) (*Partitioning, error) {
+	partitioning := &Partitioning{
+		Linear:     p.IsLinear(),
+		Expression: p.Expression(),
+	}
+
+	switch p.Type() {
+	case model.PartitionTypeRange:
+		partitioning.Type = "RANGE"
+	case model.PartitionTypeList:
+		partitioning.Type = "LIST"
+	case model.PartitionTypeHash:
+		partitioning.Type = "HASH"
+	case model.PartitionTypeKey:
+		partitioning.Type = "KEY"
+	default:
+		return nil, errors.New(`invalid partition type`)
+	}
+
+	for col := range p.Columns() {
+		partitioning.Columns = append(partitioning.Columns, col)
+	}
+	if p.HasPartitionCount() {
+		partitioning.Count = p.PartitionCount()
+	}
+
+	for def := range p.Definitions() {
+		partition := &Partition{
+			Name:     def.Name(),
+			MaxValue: def.IsMaxValue(),
+		}
+		if def.HasLessThan() {
+			partition.LessThan = def.LessThan()
+		}
+		if def.HasValuesIn() {
+			partition.ValuesIn = def.ValuesIn()
+		}
+		if def.HasEngine() {
+			partition.Engine = def.Engine()
+		}
+		if def.HasComment() {
+			partition.Comment = def.Comment()
+		}
+		partitioning.Definitions = append(partitioning.Definitions, partition)
+	}
+	return partitioning, nil
+}
+
+// key returns the key used to identify the index within a table.
+// Named indexes are identified by their name, and unnamed ones by
+// their kind and columns. Foreign keys have their own namespace,
+// so they are always prefixed by their kind.
+func (idx *Index) key() string {
+	var prefix string
+	if idx.Kind == KindForeignKey {
+		prefix = KindForeignKey + " "
+	}
+	if idx.Symbol != "" && idx.Kind == KindForeignKey {
+		return prefix + idx.Symbol
+	}
+	if idx.Name != "" {
+		return prefix + idx.Name
+	}
+
+	key := idx.Kind + " ("
+	for i, col := range idx.Columns {
+		if i > 0 {
+			key += ", "
+		}
+		key += col.Name
+		if col.Length != "" {
+			key += "(" + col.Length + ")"
+		}
+	}
+	return key + ")"
+}
+
+// Encode writes the contract as indented JSON to dst
+func Encode(dst io.Writer, c *Contract) error {
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return errors.Wrap(err, `failed to encode contract`)
+	}
+	return nil
+}
+
+// Decode reads a contract from src, and validates it
+func Decode(src io.Reader) (*Contract, error) {
+	var c Contract
+	if err := json.NewDecoder(src).Decode(&c); err != nil {
+		return nil, errors.Wrap(err, `failed to decode contract`)
+	}
+
+	if err := Validate(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,210 @@
+package contract_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/contract"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContract(t *testing.T, src string) *contract.Contract {
+	t.Helper()
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		t.FailNow()
+	}
+
+	c, err := contract.New(stmts)
+	if !assert.NoError(t, err, "contract.New should succeed") {
+		t.FailNow()
+	}
+	return c
+}
+
+func TestEncode(t *testing.T) {
+	c := newContract(t, "CREATE TABLE `users` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) COMMENT 'full name', PRIMARY KEY (`id`) ) ENGINE=InnoDB; ANALYZE TABLE `users` UPDATE HISTOGRAM ON `name`;")
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, contract.Encode(&buf, c), "contract.Encode should succeed") {
+		return
+	}
+
+	expected := `{
+  "version": 1,
+  "tables": [
+    {
+      "name": "users",
+      "columns": [
+        {
+          "name": "id",
+          "type": "INT",
+          "length": "11",
+          "nullable": false
+        },
+        {
+          "name": "name",
+          "type": "VARCHAR",
+          "length": "20",
+          "nullable": true,
+          "default": {
+            "value": "NULL"
+          },
+          "comment": "full name"
+        }
+      ],
+      "indexes": [
+        {
+          "name": "PRIMARY",
+          "kind": "PRIMARY KEY",
+          "columns": [
+            {
+              "name": "id"
+            }
+          ]
+        }
+      ],
+      "options": [
+        {
+          "key": "ENGINE",
+          "value": "InnoDB"
+        }
+      ]
+    }
+  ]
+}
+`
+	if !assert.Equal(t, expected, buf.String(), "encoded contract should match") {
+		return
+	}
+
+	decoded, err := contract.Decode(&buf)
+	if !assert.NoError(t, err, "contract.Decode should succeed") {
+		return
+	}
+	if !assert.Equal(t, c, decoded, "decoded contract should match") {
+		return
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	// Semantically identical schemas produce identical contracts
+	a := newContract(t, "CREATE TABLE `b` ( `id` INT NOT NULL PRIMARY KEY, `x` INTEGER, KEY `k2` (`x`), KEY `k1` (`id`) ); CREATE TABLE `a` ( `id` BOOL );")
+	b := newContract(t, "CREATE TABLE `a` ( `id` TINYINT(1) NULL ); CREATE TABLE `b` ( `id` INT(11) NOT NULL, `x` INT DEFAULT NULL, PRIMARY KEY (`id`), KEY `k1` (`id`), KEY `k2` (`x`) );")
+
+	var bufA, bufB bytes.Buffer
+	if !assert.NoError(t, contract.Encode(&bufA, a), "contract.Encode should succeed") {
+		return
+	}
+	if !assert.NoError(t, contract.Encode(&bufB, b), "contract.Encode should succeed") {
+		return
+	}
+	if !assert.Equal(t, bufA.String(), bufB.String(), "contracts should be identical") {
+		return
+	}
+	if !assert.Empty(t, contract.Compare(a, b), "there should be no changes") {
+		return
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type Spec struct {
+		Input    string
+		Problems []string
+	}
+
+	specs := []Spec{
+		{
+			Input: `{"version": 1, "tables": [{"name": "a", "columns": [{"name": "id", "type": "INT", "nullable": false}]}]}`,
+		},
+		{
+			Input:    `{"version": 2, "tables": []}`,
+			Problems: []string{"version: unsupported version 2"},
+		},
+		{
+			Input: `{"version": 1, "tables": [
+				{"name": "a", "columns": [{"name": "id", "type": "INT"}]},
+				{"name": "a", "columns": [{"name": "id", "type": "INTEGRAL"}, {"name": "id", "type": "INT"}]}
+			]}`,
+			Problems: []string{
+				"tables.a: duplicate table",
+				`tables.a.columns.id: unknown type "INTEGRAL"`,
+				"tables.a.columns.id: duplicate column",
+			},
+		},
+		{
+			Input: `{"version": 1, "tables": [{"name": "a", "columns": [{"name": "id", "type": "INT"}], "indexes": [
+				{"kind": "PRIMARY KEY", "name": "PRIMARY", "columns": [{"name": "id"}]},
+				{"kind": "KEY", "columns": [{"name": "id"}]},
+				{"kind": "INDEX", "name": "k", "columns": [{"name": "missing", "order": "UP"}]},
+				{"kind": "FOREIGN KEY", "symbol": "fk", "columns": [{"name": "id"}]}
+			]}]}`,
+			Problems: []string{
+				`tables.a.indexes[1]: unknown kind "KEY"`,
+				`tables.a.indexes[2]: unknown column "missing"`,
+				`tables.a.indexes[2]: unknown order "UP" for column "missing"`,
+				"tables.a.indexes[3]: foreign key requires reference",
+			},
+		},
+	}
+
+	for _, spec := range specs {
+		_, err := contract.Decode(strings.NewReader(spec.Input))
+		if spec.Problems == nil {
+			if !assert.NoError(t, err, "contract.Decode should succeed") {
+				return
+			}
+			continue
+		}
+
+		verr, ok := err.(*contract.ValidationError)
+		if !assert.True(t, ok, "error should be a *contract.ValidationError (got %v)", err) {
+			return
+		}
+		if !assert.Equal(t, spec.Problems, verr.Problems, "problems should match") {
+			return
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := newContract(t, "CREATE TABLE `a` ( `id` INT NOT NULL, `x` INT, `y` INT, KEY `k` (`x`) ); CREATE TABLE `b` ( `id` INT NOT NULL ) ENGINE=InnoDB;")
+	to := newContract(t, "CREATE TABLE `a` ( `x` VARCHAR(10) NOT NULL, `id` INT NOT NULL, `z` INT, KEY `k` (`x`, `id`) ); CREATE TABLE `c` ( `id` INT NOT NULL );")
+
+	var summary []string
+	for _, change := range contract.Compare(from, to) {
+		summary = append(summary, change.Type.String()+" "+change.Path)
+	}
+
+	expected := []string{
+		"modified tables.a.columns.x.type",
+		"modified tables.a.columns.x.length",
+		"modified tables.a.columns.x.nullable",
+		"modified tables.a.columns.x.default",
+		"removed tables.a.columns.y",
+		"added tables.a.columns.z",
+		"modified tables.a.columns",
+		"modified tables.a.indexes.k",
+		"removed tables.b",
+		"added tables.c",
+	}
+	if !assert.Equal(t, expected, summary, "changes should match") {
+		return
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal([]byte(contract.JSONSchema), &schema), "JSONSchema should be valid JSON") {
+		return
+	}
+
+	version := schema["properties"].(map[string]interface{})["version"].(map[string]interface{})["const"]
+	if !assert.Equal(t, float64(contract.Version), version, "JSONSchema should describe the current version") {
+		return
+	}
+}
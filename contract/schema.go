@@ -0,0 +1,149 @@
+package contract
+
+// JSONSchema is the JSON Schema (draft-07) describing version 1 of the
+// contract format. It is published so that consumers written in other
+// languages can validate contracts without depending on this package.
+const JSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/eihigh/schemalex/contract/v1.json",
+  "title": "schemalex schema contract",
+  "type": "object",
+  "required": ["version", "tables"],
+  "properties": {
+    "version": { "const": 1 },
+    "tables": {
+      "type": "array",
+      "items": { "$ref": "#/definitions/table" }
+    }
+  },
+  "definitions": {
+    "table": {
+      "type": "object",
+      "required": ["name", "columns"],
+      "properties": {
+        "name": { "type": "string", "minLength": 1 },
+        "temporary": { "type": "boolean" },
+        "columns": {
+          "type": "array",
+          "minItems": 1,
+          "items": { "$ref": "#/definitions/column" }
+        },
+        "indexes": {
+          "type": "array",
+          "items": { "$ref": "#/definitions/index" }
+        },
+        "options": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["key", "value"],
+            "properties": {
+              "key": { "type": "string", "minLength": 1 },
+              "value": { "type": "string" }
+            }
+          }
+        },
+        "partitioning": { "$ref": "#/definitions/partitioning" }
+      }
+    },
+    "column": {
+      "type": "object",
+      "required": ["name", "type", "nullable"],
+      "properties": {
+        "name": { "type": "string", "minLength": 1 },
+        "type": { "type": "string" },
+        "length": { "type": "string" },
+        "decimal": { "type": "string" },
+        "unsigned": { "type": "boolean" },
+        "zerofill": { "type": "boolean" },
+        "binary": { "type": "boolean" },
+        "character_set": { "type": "string" },
+        "collation": { "type": "string" },
+        "nullable": { "type": "boolean" },
+        "default": {
+          "type": "object",
+          "required": ["value"],
+          "properties": {
+            "value": { "type": "string" },
+            "quoted": { "type": "boolean" }
+          }
+        },
+        "auto_increment": { "type": "boolean" },
+        "auto_update": { "type": "string" },
+        "comment": { "type": "string" },
+        "enum_values": { "type": "array", "items": { "type": "string" } },
+        "set_values": { "type": "array", "items": { "type": "string" } }
+      }
+    },
+    "index": {
+      "type": "object",
+      "required": ["kind", "columns"],
+      "properties": {
+        "name": { "type": "string" },
+        "symbol": { "type": "string" },
+        "kind": {
+          "enum": ["PRIMARY KEY", "UNIQUE", "INDEX", "FULLTEXT", "SPATIAL", "FOREIGN KEY"]
+        },
+        "type": { "enum": ["BTREE", "HASH"] },
+        "columns": {
+          "type": "array",
+          "minItems": 1,
+          "items": { "$ref": "#/definitions/index_column" }
+        },
+        "reference": {
+          "type": "object",
+          "required": ["table", "columns"],
+          "properties": {
+            "table": { "type": "string", "minLength": 1 },
+            "columns": {
+              "type": "array",
+              "items": { "$ref": "#/definitions/index_column" }
+            },
+            "match": { "enum": ["FULL", "PARTIAL", "SIMPLE"] },
+            "on_delete": { "$ref": "#/definitions/reference_option" },
+            "on_update": { "$ref": "#/definitions/reference_option" }
+          }
+        }
+      }
+    },
+    "index_column": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": { "type": "string", "minLength": 1 },
+        "length": { "type": "string" },
+        "order": { "enum": ["ASC", "DESC"] }
+      }
+    },
+    "reference_option": {
+      "enum": ["RESTRICT", "CASCADE", "SET NULL", "NO ACTION"]
+    },
+    "partitioning": {
+      "type": "object",
+      "required": ["type"],
+      "properties": {
+        "type": { "enum": ["RANGE", "LIST", "HASH", "KEY"] },
+        "linear": { "type": "boolean" },
+        "expression": { "type": "string" },
+        "columns": { "type": "array", "items": { "type": "string" } },
+        "count": { "type": "string" },
+        "definitions": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["name"],
+            "properties": {
+              "name": { "type": "string", "minLength": 1 },
+              "less_than": { "type": "string" },
+              "max_value": { "type": "boolean" },
+              "values_in": { "type": "string" },
+              "engine": { "type": "string" },
+              "comment": { "type": "string" }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
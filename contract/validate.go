@@ -0,0 +1,190 @@
+package contract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// ValidationError is returned by Validate, and lists all the problems
+// found in the contract
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid contract: " + strings.Join(e.Problems, ", ")
+}
+
+var columnTypes = func() map[string]struct{} {
+	m := make(map[string]struct{})
+	for typ := model.ColumnTypeInvalid + 1; typ < model.ColumnTypeMax; typ++ {
+		m[typ.String()] = struct{}{}
+	}
+	return m
+}()
+
+var indexKinds = map[string]struct{}{
+	KindPrimaryKey: {},
+	KindUnique:     {},
+	KindNormal:     {},
+	KindFullText:   {},
+	KindSpatial:    {},
+	KindForeignKey: {},
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// Validate checks that the contract is well formed: the version must
+// be supported, names must be present and unique, types and kinds must
+// be known, and indexes may only refer to columns of their own table.
+// If any problems are found, a *ValidationError is returned.
+func Validate(c *Contract) error {
+	var v validator
+	if c.Version < 1 || c.Version > Version {
+		v.errorf("version", "unsupported version %d", c.Version)
+	}
+
+	tables := make(map[string]struct{})
+	for i, table := range c.Tables {
+		path := "tables[" + itoa(i) + "]"
+		if table == nil {
+			v.errorf(path, "table is null")
+			continue
+		}
+		if table.Name == "" {
+			v.errorf(path, "name is required")
+		} else {
+			path = "tables." + table.Name
+			if _, ok := tables[table.Name]; ok {
+				v.errorf(path, "duplicate table")
+			}
+			tables[table.Name] = struct{}{}
+		}
+		v.validateTable(path, table)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (v *validator) validateTable(path string, table *Table) {
+	if len(table.Columns) == 0 {
+		v.errorf(path, "at least one column is required")
+	}
+
+	columns := make(map[string]struct{})
+	for i, col := range table.Columns {
+		colpath := path + ".columns[" + itoa(i) + "]"
+		if col == nil {
+			v.errorf(colpath, "column is null")
+			continue
+		}
+		if col.Name == "" {
+			v.errorf(colpath, "name is required")
+		} else {
+			colpath = path + ".columns." + col.Name
+			if _, ok := columns[col.Name]; ok {
+				v.errorf(colpath, "duplicate column")
+			}
+			columns[col.Name] = struct{}{}
+		}
+		if _, ok := columnTypes[col.Type]; !ok {
+			v.errorf(colpath, "unknown type %q", col.Type)
+		}
+		if col.Decimal != "" && col.Length == "" {
+			v.errorf(colpath, "decimal requires length")
+		}
+	}
+
+	var primary int
+	indexes := make(map[string]struct{})
+	for i, idx := range table.Indexes {
+		idxpath := path + ".indexes[" + itoa(i) + "]"
+		if idx == nil {
+			v.errorf(idxpath, "index is null")
+			continue
+		}
+		if _, ok := indexKinds[idx.Kind]; !ok {
+			v.errorf(idxpath, "unknown kind %q", idx.Kind)
+		}
+		if idx.Kind == KindPrimaryKey {
+			primary++
+		}
+		if key := idx.key(); key != "" {
+			if _, ok := indexes[key]; ok {
+				v.errorf(idxpath, "duplicate index %s", key)
+			}
+			indexes[key] = struct{}{}
+		}
+		switch idx.Type {
+		case "", "BTREE", "HASH":
+		default:
+			v.errorf(idxpath, "unknown type %q", idx.Type)
+		}
+
+		if len(idx.Columns) == 0 {
+			v.errorf(idxpath, "at least one column is required")
+		}
+		for _, col := range idx.Columns {
+			if col == nil {
+				v.errorf(idxpath, "column is null")
+				continue
+			}
+			if _, ok := columns[col.Name]; !ok {
+				v.errorf(idxpath, "unknown column %q", col.Name)
+			}
+			switch col.Order {
+			case "", "ASC", "DESC":
+			default:
+				v.errorf(idxpath, "unknown order %q for column %q", col.Order, col.Name)
+			}
+		}
+
+		if idx.Kind == KindForeignKey && idx.Reference == nil {
+			v.errorf(idxpath, "foreign key requires reference")
+		}
+		if r := idx.Reference; r != nil {
+			if idx.Kind != KindForeignKey {
+				v.errorf(idxpath, "reference is only allowed for foreign keys")
+			}
+			if r.Table == "" {
+				v.errorf(idxpath+".reference", "table is required")
+			}
+			if len(r.Columns) != len(idx.Columns) {
+				v.errorf(idxpath+".reference", "number of columns does not match the foreign key")
+			}
+		}
+	}
+	if primary > 1 {
+		v.errorf(path, "multiple primary keys")
+	}
+
+	for i, opt := range table.Options {
+		if opt == nil || opt.Key == "" {
+			v.errorf(path+".options["+itoa(i)+"]", "key is required")
+		}
+	}
+
+	if p := table.Partitioning; p != nil {
+		switch p.Type {
+		case "RANGE", "LIST", "HASH", "KEY":
+		default:
+			v.errorf(path+".partitioning", "unknown type %q", p.Type)
+		}
+	}
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
@@ -0,0 +1,273 @@
+// Package corpus contains a corpus-driven description of the semantics
+// of the diff package.
+//
+// A corpus is a directory that contains one sub-directory per case.
+// Each case directory holds a `before.sql`, an `after.sql`, and an
+// `expected.sql` file, and optionally a `description.txt` file that
+// explains what the case demonstrates. The statements in `expected.sql`
+// are what the diff package is expected to produce when migrating from
+// `before.sql` to `after.sql`.
+//
+// The same corpus is used as a test suite, run by the corpustest
+// package, as runnable examples, and as the source for the generated
+// documentation in README.md. Organizations can register their own
+// corpora to lock in the expected migration behavior for the patterns
+// they use.
+package corpus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+)
+
+// Names of the files that make up a case
+const (
+	BeforeFile      = "before.sql"
+	AfterFile       = "after.sql"
+	ExpectedFile    = "expected.sql"
+	DescriptionFile = "description.txt"
+)
+
+// Case is a single before/after/expected triple
+type Case struct {
+	Name        string
+	Description string
+	Before      string
+	After       string
+	Expected    string
+}
+
+// Corpus is a named collection of cases
+type Corpus struct {
+	name  string
+	cases []*Case
+}
+
+// MismatchError is returned from Case.Check when the diff output does
+// not match the expected statements
+type MismatchError struct {
+	Case     string
+	Expected string
+	Actual   string
+}
+
+var registry = struct {
+	mu      sync.RWMutex
+	corpora map[string]*Corpus
+}{corpora: make(map[string]*Corpus)}
+
+// New creates a new corpus with the given name and cases
+func New(name string, cases ...*Case) *Corpus {
+	return &Corpus{
+		name:  name,
+		cases: cases,
+	}
+}
+
+// Load reads all cases under the given directory and creates a corpus.
+// Sub-directories that do not contain an `expected.sql` file are skipped.
+// Cases are sorted by name.
+func Load(name, dir string) (*Corpus, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to read corpus directory %s`, dir)
+	}
+
+	c := New(name)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		casedir := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(casedir, ExpectedFile)); err != nil {
+			continue
+		}
+
+		tc, err := LoadCase(casedir)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to load case %s`, entry.Name())
+		}
+		c.cases = append(c.cases, tc)
+	}
+
+	sort.Slice(c.cases, func(i, j int) bool {
+		return c.cases[i].Name < c.cases[j].Name
+	})
+	return c, nil
+}
+
+// LoadCase reads a single case from the given directory. The name of
+// the case is the base name of the directory.
+func LoadCase(dir string) (*Case, error) {
+	tc := &Case{Name: filepath.Base(dir)}
+	for _, f := range []struct {
+		name     string
+		dst      *string
+		optional bool
+	}{
+		{name: BeforeFile, dst: &tc.Before},
+		{name: AfterFile, dst: &tc.After},
+		{name: ExpectedFile, dst: &tc.Expected},
+		{name: DescriptionFile, dst: &tc.Description, optional: true},
+	} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			if f.optional && os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, `failed to read %s`, f.name)
+		}
+		*f.dst = strings.TrimSpace(string(content))
+	}
+	return tc, nil
+}
+
+// Register registers the corpus so that it can be looked up by name.
+// Registering a corpus with a name that is already in use is an error.
+func Register(c *Corpus) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.corpora[c.name]; ok {
+		return errors.Errorf(`corpus %s is already registered`, c.name)
+	}
+	registry.corpora[c.name] = c
+	return nil
+}
+
+// MustRegister is like Register, but panics on error
+func MustRegister(c *Corpus) {
+	if err := Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the registered corpus with the given name
+func Lookup(name string) (*Corpus, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	c, ok := registry.corpora[name]
+	return c, ok
+}
+
+// Corpora returns all registered corpora, sorted by name
+func Corpora() []*Corpus {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	list := make([]*Corpus, 0, len(registry.corpora))
+	for _, c := range registry.corpora {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+// Name returns the name of the corpus
+func (c *Corpus) Name() string {
+	return c.name
+}
+
+// Cases returns the list of cases in the corpus
+func (c *Corpus) Cases() []*Case {
+	return c.cases
+}
+
+// Check runs all cases in the corpus, and returns the errors for
+// those cases that did not produce the expected statements
+func (c *Corpus) Check(options ...diff.Option) []error {
+	var errs []error
+	for _, tc := range c.cases {
+		if err := tc.Check(options...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// WriteMarkdown writes the documentation for the corpus in markdown
+// format. Each case is rendered with its description, the schemas
+// before and after, and the generated statements.
+func (c *Corpus) WriteMarkdown(dst io.Writer) error {
+	var buf bytes.Buffer
+	for i, tc := range c.cases {
+		if i > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString("## ")
+		buf.WriteString(tc.Name)
+		buf.WriteString("\n\n")
+		if tc.Description != "" {
+			buf.WriteString(tc.Description)
+			buf.WriteString("\n\n")
+		}
+		writeSQLBlock(&buf, "Before", tc.Before)
+		buf.WriteString("\n\n")
+		writeSQLBlock(&buf, "After", tc.After)
+		buf.WriteString("\n\n")
+		if tc.Expected == "" {
+			buf.WriteString("Generated statements: (none)")
+		} else {
+			writeSQLBlock(&buf, "Generated statements", tc.Expected)
+		}
+	}
+	buf.WriteByte('\n')
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write markdown`)
+	}
+	return nil
+}
+
+func writeSQLBlock(buf *bytes.Buffer, title, sql string) {
+	buf.WriteString(title)
+	buf.WriteString(":\n\n```sql\n")
+	buf.WriteString(sql)
+	buf.WriteString("\n```")
+}
+
+// Diff generates the statements to migrate from Before to After
+func (tc *Case) Diff(options ...diff.Option) (string, error) {
+	var buf bytes.Buffer
+	if err := diff.Strings(&buf, tc.Before, tc.After, options...); err != nil {
+		return "", errors.Wrapf(err, `failed to generate diff for case %s`, tc.Name)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Check generates the diff for the case, and compares it against
+// the expected statements. If they do not match, a *MismatchError
+// is returned
+func (tc *Case) Check(options ...diff.Option) error {
+	actual, err := tc.Diff(options...)
+	if err != nil {
+		return err
+	}
+
+	if actual != tc.Expected {
+		return &MismatchError{
+			Case:     tc.Name,
+			Expected: tc.Expected,
+			Actual:   actual,
+		}
+	}
+	return nil
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("case %s: generated statements do not match\nexpected:\n%s\nactual:\n%s", e.Case, e.Expected, e.Actual)
+}
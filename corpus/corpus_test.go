@@ -0,0 +1,81 @@
+package corpus_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/eihigh/schemalex/corpus"
+	"github.com/eihigh/schemalex/corpus/corpustest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorpus(t *testing.T) {
+	c, err := corpus.Load("schemalex", "testdata")
+	if !assert.NoError(t, err, "corpus.Load should succeed") {
+		return
+	}
+	if !assert.NotEmpty(t, c.Cases(), "corpus should contain cases") {
+		return
+	}
+
+	corpustest.Run(t, c)
+}
+
+func TestCaseCheck(t *testing.T) {
+	tc := &corpus.Case{
+		Name:     "mismatch",
+		Before:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+		After:    "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+		Expected: "ALTER TABLE `fuga` DROP COLUMN `a`;",
+	}
+
+	err := tc.Check()
+	if !assert.Error(t, err, "Check should fail") {
+		return
+	}
+	merr, ok := err.(*corpus.MismatchError)
+	if !assert.True(t, ok, "error should be a *corpus.MismatchError, got %T", err) {
+		return
+	}
+	assert.Equal(t, "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;", merr.Actual, "actual statements should match")
+
+	tc.Expected = merr.Actual
+	assert.NoError(t, tc.Check(), "Check should succeed")
+}
+
+func TestRegister(t *testing.T) {
+	c := corpus.New("test-register", &corpus.Case{Name: "empty"})
+	if !assert.NoError(t, corpus.Register(c), "Register should succeed") {
+		return
+	}
+	assert.Error(t, corpus.Register(c), "registering the same name twice should fail")
+
+	found, ok := corpus.Lookup("test-register")
+	if !assert.True(t, ok, "Lookup should succeed") {
+		return
+	}
+	assert.Equal(t, c, found, "Lookup should return the registered corpus")
+
+	_, ok = corpus.Lookup("test-not-registered")
+	assert.False(t, ok, "Lookup should fail for unknown corpus")
+}
+
+func TestREADME(t *testing.T) {
+	c, err := corpus.Load("schemalex", "testdata")
+	if !assert.NoError(t, err, "corpus.Load should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, c.WriteMarkdown(&buf), "WriteMarkdown should succeed") {
+		return
+	}
+
+	readme, err := ioutil.ReadFile("README.md")
+	if !assert.NoError(t, err, "reading README.md should succeed") {
+		return
+	}
+	assert.True(t, strings.HasSuffix(string(readme), buf.String()), "README.md should be up to date (run go generate)")
+}
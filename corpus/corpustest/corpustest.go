@@ -0,0 +1,24 @@
+// Package corpustest runs the cases of a corpus as tests. It is kept
+// apart from the corpus package, so that the programs that use corpora
+// do not import the testing package.
+package corpustest
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex/corpus"
+	"github.com/eihigh/schemalex/diff"
+)
+
+// Run runs each case in the corpus as a sub test of t
+func Run(t *testing.T, c *corpus.Corpus, options ...diff.Option) {
+	t.Helper()
+	for _, tc := range c.Cases() {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			if err := tc.Check(options...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package corpus_test
+
+import (
+	"fmt"
+
+	"github.com/eihigh/schemalex/corpus"
+)
+
+func ExampleCase_Diff() {
+	tc := &corpus.Case{
+		Name:   "add_column_first",
+		Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+		After:  "CREATE TABLE `fuga` ( `a` INTEGER NOT NULL, `id` INTEGER NOT NULL );",
+	}
+
+	stmts, err := tc.Diff()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(stmts)
+
+	// OUTPUT:
+	// ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL FIRST;
+}
+
+func ExampleLoad() {
+	c, err := corpus.Load("schemalex", "testdata")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, err := range c.Check() {
+		fmt.Println(err)
+	}
+	fmt.Printf("%d cases checked", len(c.Cases()))
+
+	// OUTPUT:
+	// 10 cases checked
+}
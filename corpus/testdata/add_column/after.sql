@@ -0,0 +1,5 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL,
+  `a` INTEGER NOT NULL,
+  `b` INTEGER NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;
+ALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `a`;
@@ -0,0 +1,4 @@
+CREATE TABLE `fuga` (
+  `a` INTEGER NOT NULL,
+  `id` INTEGER NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL FIRST;
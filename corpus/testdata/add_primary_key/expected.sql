@@ -0,0 +1 @@
+ALTER TABLE `fuga` ADD PRIMARY KEY (`id`);
@@ -0,0 +1,3 @@
+CREATE TABLE `fuga` (
+  `id` BIGINT NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE `fuga` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL;
@@ -0,0 +1,5 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL AUTO_INCREMENT,
+  `fid` INTEGER NOT NULL,
+  CONSTRAINT `fsym` FOREIGN KEY (fid) REFERENCES f (id)
+);
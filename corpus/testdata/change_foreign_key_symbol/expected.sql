@@ -0,0 +1,4 @@
+ALTER TABLE `fuga` DROP FOREIGN KEY `fsym`;
+ALTER TABLE `fuga` DROP INDEX `fsym`;
+ALTER TABLE `fuga` ADD INDEX `ksym` (`fid`);
+ALTER TABLE `fuga` ADD CONSTRAINT `ksym` FOREIGN KEY (`fid`) REFERENCES `f` (`id`);
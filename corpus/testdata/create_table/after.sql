@@ -0,0 +1,6 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL
+);
+CREATE TABLE `hoge` (
+  `id` INTEGER NOT NULL
+) ENGINE=InnoDB DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,3 @@
+CREATE TABLE `hoge` (
+`id` INT (11) NOT NULL
+) ENGINE = InnoDB, DEFAULT CHARACTER SET = utf8mb4;
@@ -0,0 +1,4 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL,
+  `c` VARCHAR (20) NOT NULL DEFAULT 'xxx'
+);
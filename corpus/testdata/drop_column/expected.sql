@@ -0,0 +1 @@
+ALTER TABLE `fuga` DROP COLUMN `c`;
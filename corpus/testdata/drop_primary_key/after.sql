@@ -0,0 +1,3 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL AUTO_INCREMENT
+);
@@ -0,0 +1,4 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL AUTO_INCREMENT,
+  PRIMARY KEY (`id`)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE `hoge` (
+  `id` INTEGER NOT NULL
+);
+CREATE TABLE `fuga` (
+  `id` INTEGER NOT NULL
+);
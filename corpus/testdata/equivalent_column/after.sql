@@ -0,0 +1,4 @@
+CREATE TABLE `fuga` (
+  `id` INT (11) DEFAULT NULL,
+  `n` INT (10) UNSIGNED DEFAULT 0
+);
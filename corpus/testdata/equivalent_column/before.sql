@@ -0,0 +1,4 @@
+CREATE TABLE `fuga` (
+  `id` INTEGER NULL,
+  `n` INT UNSIGNED DEFAULT '0'
+);
@@ -0,0 +1,121 @@
+package schemalex
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/option"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Dialect is the flavor of SQL that the parser accepts
+type Dialect string
+
+// List of possible Dialect values
+const (
+	DialectMySQL   Dialect = "mysql"
+	DialectMariaDB Dialect = "mariadb"
+	DialectTiDB    Dialect = "tidb"
+)
+
+// DialectParser parses the statements of a dialect that is not built
+// into Parser, such as the one provided by the postgres package
+type DialectParser interface {
+	Parse(src []byte) (model.Stmts, error)
+}
+
+// DialectFunc creates a DialectParser, given the options passed to
+// NewParser
+type DialectFunc func(options ...Option) DialectParser
+
+var dialects = struct {
+	sync.RWMutex
+	parsers map[Dialect]DialectFunc
+}{
+	parsers: make(map[Dialect]DialectFunc),
+}
+
+// RegisterDialect makes a dialect available to NewParser and
+// ParseDialect. Dialect names are case insensitive. RegisterDialect
+// panics if the dialect is built in or already registered.
+func RegisterDialect(d Dialect, fn DialectFunc) {
+	d = Dialect(strings.ToLower(string(d)))
+
+	dialects.Lock()
+	defer dialects.Unlock()
+
+	if fn == nil {
+		panic("schemalex: RegisterDialect func is nil")
+	}
+	if isBuiltinDialect(d) {
+		panic("schemalex: RegisterDialect called for built-in dialect " + string(d))
+	}
+	if _, ok := dialects.parsers[d]; ok {
+		panic("schemalex: RegisterDialect called twice for dialect " + string(d))
+	}
+	dialects.parsers[d] = fn
+}
+
+func lookupDialect(d Dialect) (DialectFunc, bool) {
+	dialects.RLock()
+	defer dialects.RUnlock()
+
+	fn, ok := dialects.parsers[d]
+	return fn, ok
+}
+
+func isBuiltinDialect(d Dialect) bool {
+	return d == "" || d == DialectMySQL || d == DialectMariaDB || d == DialectTiDB
+}
+
+// Dialects returns the built-in dialects, followed by the registered
+// ones in lexical order
+func Dialects() []Dialect {
+	dialects.RLock()
+	defer dialects.RUnlock()
+
+	var registered []Dialect
+	for d := range dialects.parsers {
+		registered = append(registered, d)
+	}
+	sort.Slice(registered, func(i, j int) bool {
+		return registered[i] < registered[j]
+	})
+	return append([]Dialect{DialectMySQL, DialectMariaDB, DialectTiDB}, registered...)
+}
+
+// ParseDialect returns the Dialect with the given name, such as
+// "mariadb". Names are case insensitive.
+func ParseDialect(s string) (Dialect, error) {
+	for _, d := range Dialects() {
+		if string(d) == strings.ToLower(s) {
+			return d, nil
+		}
+	}
+	return "", errors.Errorf(`unknown dialect %s`, s)
+}
+
+const optkeyDialect = "dialect"
+
+// WithDialect specifies the dialect of the statements, for use with
+// NewParser. By default, the MySQL dialect is used. The MariaDB dialect
+// additionally accepts MariaDB specific table options, such as
+// PAGE_CHECKSUM and TRANSACTIONAL, and the INET4, INET6, and UUID types.
+// As MariaDB dumps JSON columns as LONGTEXT columns with a
+// `CHECK (json_valid(...))` constraint, such columns are read as JSON
+// columns, so that they compare equal to the declared ones.
+//
+// The TiDB dialect accepts AUTO_RANDOM columns, CLUSTERED and
+// NONCLUSTERED primary keys, and the SHARD_ROW_ID_BITS,
+// PRE_SPLIT_REGIONS, AUTO_RANDOM_BASE, and AUTO_ID_CACHE table options.
+// TiDB specific comments written by SHOW CREATE TABLE, such as
+// `/*T![clustered_index] CLUSTERED */`, are read as part of the
+// statement.
+//
+// WithMySQLVersion is only applied to the MySQL dialect. Other
+// dialects may be made available using RegisterDialect.
+func WithDialect(d Dialect) Option {
+	return option.New(optkeyDialect, d)
+}
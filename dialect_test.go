@@ -0,0 +1,116 @@
+package schemalex_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/format"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMariaDBDialect(t *testing.T) {
+	const src = "CREATE TABLE `t` (\n" +
+		"  `id` uuid NOT NULL,\n" +
+		"  `addr` inet6 DEFAULT NULL,\n" +
+		"  `doc` longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL CHECK (json_valid(`doc`)),\n" +
+		"  `created_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=Aria DEFAULT CHARSET=utf8mb4 PAGE_CHECKSUM=1 TRANSACTIONAL=1 ROW_FORMAT=page;"
+
+	_, err := schemalex.New().ParseString(src)
+	if !assert.Error(t, err, "parse should fail without the MariaDB dialect") {
+		return
+	}
+
+	p := schemalex.NewParser(schemalex.WithDialect(schemalex.DialectMariaDB))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, stmts); !assert.NoError(t, err, "format should succeed") {
+		return
+	}
+	expected := "CREATE TABLE `t` (\n" +
+		"`id` UUID NOT NULL,\n" +
+		"`addr` INET6 DEFAULT NULL,\n" +
+		"`doc` JSON DEFAULT NULL,\n" +
+		"`created_at` TIMESTAMP ON UPDATE CURRENT_TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"PRIMARY KEY (`id`)\n" +
+		") ENGINE = Aria, DEFAULT CHARACTER SET = utf8mb4, PAGE_CHECKSUM = 1, TRANSACTIONAL = 1, ROW_FORMAT = PAGE"
+	if !assert.Equal(t, expected, buf.String(), "formatted statements should match") {
+		return
+	}
+
+	_, err = p.ParseString("CREATE TABLE `t` (`a` INT CHECK (`a` > 0));")
+	if !assert.Error(t, err, "parse should fail for other CHECK constraints") {
+		return
+	}
+}
+
+func TestTiDBDialect(t *testing.T) {
+	const src = "CREATE TABLE `t` (\n" +
+		"  `id` bigint(20) NOT NULL /*T![auto_rand] AUTO_RANDOM(5) */,\n" +
+		"  `name` varchar(64) DEFAULT '/*T! not a comment */',\n" +
+		"  PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 /*T![auto_rand_base] AUTO_RANDOM_BASE=30001 */;\n" +
+		"CREATE TABLE `u` (\n" +
+		"  `id` bigint NOT NULL AUTO_RANDOM(4,  54),\n" +
+		"  PRIMARY KEY (`id`) NONCLUSTERED\n" +
+		") SHARD_ROW_ID_BITS=4 PRE_SPLIT_REGIONS=2;"
+
+	_, err := schemalex.New().ParseString(src)
+	if !assert.Error(t, err, "parse should fail without the TiDB dialect") {
+		return
+	}
+
+	p := schemalex.NewParser(schemalex.WithDialect(schemalex.DialectTiDB))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 2, "there should be 2 statements") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, stmts[0]); !assert.NoError(t, err, "format should succeed") {
+		return
+	}
+	expected := "CREATE TABLE `t` (\n" +
+		"`id` BIGINT (20) NOT NULL AUTO_RANDOM(5),\n" +
+		"`name` VARCHAR (64) DEFAULT '/*T! not a comment */',\n" +
+		"PRIMARY KEY (`id`) CLUSTERED\n" +
+		") ENGINE = InnoDB, DEFAULT CHARACTER SET = utf8mb4, AUTO_RANDOM_BASE = 30001"
+	if !assert.Equal(t, expected, buf.String(), "formatted statements should match") {
+		return
+	}
+
+	buf.Reset()
+	if err := format.SQL(&buf, stmts[1]); !assert.NoError(t, err, "format should succeed") {
+		return
+	}
+	expected = "CREATE TABLE `u` (\n" +
+		"`id` BIGINT (20) NOT NULL AUTO_RANDOM(4, 54),\n" +
+		"PRIMARY KEY (`id`) NONCLUSTERED\n" +
+		") SHARD_ROW_ID_BITS = 4, PRE_SPLIT_REGIONS = 2"
+	if !assert.Equal(t, expected, buf.String(), "formatted statements should match") {
+		return
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	d, err := schemalex.ParseDialect("MariaDB")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, schemalex.DialectMariaDB, d) {
+		return
+	}
+	_, err = schemalex.ParseDialect("oracle")
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+}
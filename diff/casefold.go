@@ -0,0 +1,201 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// nameSet maps the lower cased names of objects to their spelling
+type nameSet map[string]string
+
+func (s nameSet) add(name string) {
+	if _, ok := s[strings.ToLower(name)]; !ok {
+		s[strings.ToLower(name)] = name
+	}
+}
+
+// spell returns the spelling of name in the set, or name itself if
+// the set does not contain a name that only differs in case
+func (s nameSet) spell(name string) string {
+	if v, ok := s[strings.ToLower(name)]; ok {
+		return v
+	}
+	return name
+}
+
+// nameFolder holds the spelling of the names used in a schema
+type nameFolder struct {
+	tables  nameSet
+	columns map[string]nameSet // keyed by lower cased table name
+	indexes map[string]nameSet // keyed by lower cased table name
+}
+
+func newNameFolder(stmts model.Stmts) *nameFolder {
+	f := &nameFolder{
+		tables:  make(nameSet),
+		columns: make(map[string]nameSet),
+		indexes: make(map[string]nameSet),
+	}
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		f.tables.add(table.Name())
+
+		columns := make(nameSet)
+		for col := range table.Columns() {
+			columns.add(col.Name())
+		}
+		f.columns[strings.ToLower(table.Name())] = columns
+
+		indexes := make(nameSet)
+		for idx := range table.Indexes() {
+			if idx.HasName() {
+				indexes.add(idx.Name())
+			}
+			if idx.HasSymbol() {
+				indexes.add(idx.Symbol())
+			}
+		}
+		f.indexes[strings.ToLower(table.Name())] = indexes
+	}
+	return f
+}
+
+// foldNames returns a copy of `from` in which the names of tables,
+// columns, and indexes that only differ in case from the ones in `to`
+// are spelled as in `to`, so that they compare as equal. This matches
+// the behavior of MySQL servers with lower_case_table_names enabled,
+// where column and index names are case insensitive as well.
+func foldNames(from, to model.Stmts) model.Stmts {
+	f := newNameFolder(to)
+
+	var stmts model.Stmts
+	for _, stmt := range from {
+		switch v := stmt.(type) {
+		case model.Table:
+			stmts = append(stmts, f.table(v))
+		case model.Histogram:
+			table := f.tables.spell(v.TableName())
+			h := model.NewHistogram(table, f.columnsOf(table).spell(v.ColumnName()))
+			if v.HasBuckets() {
+				h.SetBuckets(v.Buckets())
+			}
+			stmts = append(stmts, h)
+		default:
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+func (f *nameFolder) columnsOf(table string) nameSet {
+	return f.columns[strings.ToLower(table)]
+}
+
+func (f *nameFolder) table(table model.Table) model.Table {
+	name := f.tables.spell(table.Name())
+	columns := f.columnsOf(name)
+	indexes := f.indexes[strings.ToLower(name)]
+
+	t := model.NewTable(name)
+	t.SetTemporary(table.IsTemporary())
+	t.SetIfNotExists(table.IsIfNotExists())
+	if table.HasLikeTable() {
+		t.SetLikeTable(f.tables.spell(table.LikeTable()))
+	}
+	for col := range table.Columns() {
+		t.AddColumn(col.Clone().SetName(columns.spell(col.Name())))
+	}
+	for idx := range table.Indexes() {
+		t.AddIndex(f.index(idx, t.ID(), columns, indexes))
+	}
+	for opt := range table.Options() {
+		t.AddOption(opt)
+	}
+	if table.HasPartitioning() {
+		t.SetPartitioning(table.Partitioning())
+	}
+	return t
+}
+
+func (f *nameFolder) index(idx model.Index, tableID string, columns, indexes nameSet) model.Index {
+	var kind model.IndexKind
+	switch {
+	case idx.IsPrimaryKey():
+		kind = model.IndexKindPrimaryKey
+	case idx.IsUnique():
+		kind = model.IndexKindUnique
+	case idx.IsFullText():
+		kind = model.IndexKindFullText
+	case idx.IsSpatial():
+		kind = model.IndexKindSpatial
+	case idx.IsForeignKey():
+		kind = model.IndexKindForeignKey
+	default:
+		kind = model.IndexKindNormal
+	}
+
+	i := model.NewIndex(kind, tableID)
+	if idx.HasName() {
+		i.SetName(indexes.spell(idx.Name()))
+	}
+	if idx.HasSymbol() {
+		i.SetSymbol(indexes.spell(idx.Symbol()))
+	}
+	switch {
+	case idx.IsBtree():
+		i.SetType(model.IndexTypeBtree)
+	case idx.IsHash():
+		i.SetType(model.IndexTypeHash)
+	}
+	switch {
+	case idx.IsClustered():
+		i.SetClustering(model.IndexClusteringClustered)
+	case idx.IsNonClustered():
+		i.SetClustering(model.IndexClusteringNonClustered)
+	}
+	if idx.HasParser() {
+		i.SetParser(idx.Parser())
+	}
+	for col := range idx.Columns() {
+		i.AddColumns(foldIndexColumn(col, columns))
+	}
+
+	if r := idx.Reference(); r != nil {
+		table := f.tables.spell(r.TableName())
+		ref := model.NewReference()
+		ref.SetTableName(table)
+		switch {
+		case r.MatchFull():
+			ref.SetMatch(model.ReferenceMatchFull)
+		case r.MatchPartial():
+			ref.SetMatch(model.ReferenceMatchPartial)
+		case r.MatchSimple():
+			ref.SetMatch(model.ReferenceMatchSimple)
+		}
+		ref.SetOnDelete(r.OnDelete())
+		ref.SetOnUpdate(r.OnUpdate())
+		for col := range r.Columns() {
+			ref.AddColumns(foldIndexColumn(col, f.columnsOf(table)))
+		}
+		i.SetReference(ref)
+	}
+	return i
+}
+
+func foldIndexColumn(col model.IndexColumn, columns nameSet) model.IndexColumn {
+	c := model.NewIndexColumn(columns.spell(col.Name()))
+	if col.HasLength() {
+		c.SetLength(col.Length())
+	}
+	switch {
+	case col.IsAscending():
+		c.SetSortDirection(model.SortDirectionAscending)
+	case col.IsDescending():
+		c.SetSortDirection(model.SortDirectionDescending)
+	}
+	return c
+}
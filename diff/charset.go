@@ -0,0 +1,175 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// defaultCollations lists the default collation of the character sets
+// that are commonly used, as of MySQL 5.7. The default collation of
+// utf8mb4 was changed to utf8mb4_0900_ai_ci in MySQL 8.0, which is
+// handled by specifying it as the server default.
+var defaultCollations = map[string]string{
+	"armscii8": "armscii8_general_ci",
+	"ascii":    "ascii_general_ci",
+	"big5":     "big5_chinese_ci",
+	"binary":   "binary",
+	"cp1250":   "cp1250_general_ci",
+	"cp1251":   "cp1251_general_ci",
+	"cp1256":   "cp1256_general_ci",
+	"cp1257":   "cp1257_general_ci",
+	"cp932":    "cp932_japanese_ci",
+	"eucjpms":  "eucjpms_japanese_ci",
+	"euckr":    "euckr_korean_ci",
+	"gb18030":  "gb18030_chinese_ci",
+	"gb2312":   "gb2312_chinese_ci",
+	"gbk":      "gbk_chinese_ci",
+	"greek":    "greek_general_ci",
+	"hebrew":   "hebrew_general_ci",
+	"koi8r":    "koi8r_general_ci",
+	"latin1":   "latin1_swedish_ci",
+	"latin2":   "latin2_general_ci",
+	"sjis":     "sjis_japanese_ci",
+	"tis620":   "tis620_thai_ci",
+	"ucs2":     "ucs2_general_ci",
+	"ujis":     "ujis_japanese_ci",
+	"utf16":    "utf16_general_ci",
+	"utf32":    "utf32_general_ci",
+	"utf8":     "utf8_general_ci",
+	"utf8mb4":  "utf8mb4_general_ci",
+}
+
+// DefaultServerCharset returns the default character set and collation
+// of the given MySQL server version, such as "5.7" or "8.0.21", so that
+// they can be passed to WithServerCharset. Empty strings are returned
+// for unknown versions.
+func DefaultServerCharset(version string) (charset, collation string) {
+	switch {
+	case version == "8" || strings.HasPrefix(version, "8."):
+		return "utf8mb4", "utf8mb4_0900_ai_ci"
+	case version == "5" || strings.HasPrefix(version, "5."):
+		return "latin1", "latin1_swedish_ci"
+	}
+	return "", ""
+}
+
+// charsetDefaults expands the character set and collation that columns
+// inherit from their table, which in turn inherits them from the server
+type charsetDefaults struct {
+	charset   string
+	collation string
+}
+
+func newCharsetDefaults(charset, collation string) *charsetDefaults {
+	charset = normalizeCharset(charset)
+	collation = normalizeCollation(collation)
+	if charset == "" {
+		charset = charsetOf(collation)
+	}
+	if collation == "" {
+		collation = defaultCollations[charset]
+	}
+	return &charsetDefaults{
+		charset:   charset,
+		collation: collation,
+	}
+}
+
+// utf8mb3 is an alias of utf8, and is used by MySQL 8.0.30 and later
+// when dumping schemas
+func normalizeCharset(s string) string {
+	s = strings.ToLower(s)
+	if s == "utf8mb3" {
+		return "utf8"
+	}
+	return s
+}
+
+func normalizeCollation(s string) string {
+	s = strings.ToLower(s)
+	if strings.HasPrefix(s, "utf8mb3_") {
+		return "utf8_" + strings.TrimPrefix(s, "utf8mb3_")
+	}
+	return s
+}
+
+// charsetOf returns the character set of the given collation
+func charsetOf(collation string) string {
+	if i := strings.IndexByte(collation, '_'); i > 0 {
+		return collation[:i]
+	}
+	return collation
+}
+
+// collationOf returns the default collation of the given character set
+func (d *charsetDefaults) collationOf(charset string) string {
+	if charset == d.charset {
+		return d.collation
+	}
+	return defaultCollations[charset]
+}
+
+// table returns the character set and collation of the table
+func (d *charsetDefaults) table(table model.Table) (charset, collation string) {
+	for opt := range table.Options() {
+		switch opt.Key() {
+		case "DEFAULT CHARACTER SET":
+			charset = normalizeCharset(opt.Value())
+		case "DEFAULT COLLATE":
+			collation = normalizeCollation(opt.Value())
+		}
+	}
+
+	switch {
+	case charset == "" && collation == "":
+		return d.charset, d.collation
+	case charset == "":
+		charset = charsetOf(collation)
+	case collation == "":
+		collation = d.collationOf(charset)
+	}
+	return charset, collation
+}
+
+// expand returns a copy of the column with the character set and
+// collation that it inherits set explicitly. Columns that do not hold
+// characters are returned as is.
+func (d *charsetDefaults) expand(table model.Table, col model.TableColumn) model.TableColumn {
+	if d == nil || !isCharacterType(col.Type()) {
+		return col
+	}
+
+	charset := normalizeCharset(col.CharacterSet())
+	collation := normalizeCollation(col.Collation())
+	switch {
+	case charset == "" && collation == "":
+		charset, collation = d.table(table)
+	case charset == "":
+		charset = charsetOf(collation)
+	case collation == "":
+		if tableCharset, tableCollation := d.table(table); charset == tableCharset {
+			collation = tableCollation
+		} else {
+			collation = d.collationOf(charset)
+		}
+	}
+
+	col = col.Clone()
+	col.SetCharacterSet(charset)
+	if collation != "" {
+		col.SetCollation(collation)
+	}
+	return col
+}
+
+func isCharacterType(typ model.ColumnType) bool {
+	switch typ {
+	case model.ColumnTypeChar, model.ColumnTypeVarChar,
+		model.ColumnTypeTinyText, model.ColumnTypeText,
+		model.ColumnTypeMediumText, model.ColumnTypeLongText,
+		model.ColumnTypeEnum, model.ColumnTypeSet:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// columnComparer compares columns, ignoring the differences that do
+// not matter under the options given to the diff
+type columnComparer struct {
+	charsets           *charsetDefaults
+	ignoreDisplayWidth bool
+	currentTimestamp   bool
+	comments           CommentPolicy
+	trimComments       bool
+}
+
+// normalize returns the column as it is compared
+func (c *columnComparer) normalize(table model.Table, col model.TableColumn) model.TableColumn {
+	col = c.charsets.expand(table, col)
+
+	// where the column was declared does not matter
+	col = col.Clone()
+	col.SetPosition(model.Position{})
+
+	// the display width only matters along with ZEROFILL
+	if c.ignoreDisplayWidth && isIntegerType(col.Type()) && col.HasLength() && !col.IsZeroFill() {
+		col.SetLength(nil)
+	}
+
+	if c.currentTimestamp {
+		if col.HasDefault() && !col.IsQuotedDefault() {
+			col.SetDefault(normalizeCurrentTimestamp(col.Default()), false)
+		}
+		if col.HasAutoUpdate() {
+			col.SetAutoUpdate(normalizeCurrentTimestamp(col.AutoUpdate()))
+		}
+	}
+
+	switch {
+	case c.comments == CommentIgnore:
+		col.SetComment("")
+	case c.trimComments:
+		// an empty comment is the same as no comment
+		col.SetComment(strings.TrimSpace(col.Comment()))
+	}
+	return col
+}
+
+// normalizeCurrentTimestamp returns CURRENT_TIMESTAMP, with the same
+// fractional seconds precision, for the synonyms of CURRENT_TIMESTAMP,
+// and v unchanged otherwise
+func normalizeCurrentTimestamp(v string) string {
+	name, precision := v, ""
+	if i := strings.IndexByte(v, '('); i >= 0 && strings.HasSuffix(v, ")") {
+		name, precision = v[:i], strings.TrimSpace(v[i+1:len(v)-1])
+	}
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP":
+	case "NOW":
+		// unlike the others, NOW requires parentheses
+		if name == v {
+			return v
+		}
+	default:
+		return v
+	}
+	// precision 0 is the default
+	if precision == "" || precision == "0" {
+		return "CURRENT_TIMESTAMP"
+	}
+	return "CURRENT_TIMESTAMP(" + precision + ")"
+}
+
+func (c *columnComparer) equal(fromTable model.Table, from model.TableColumn, toTable model.Table, to model.TableColumn) bool {
+	return reflect.DeepEqual(c.normalize(fromTable, from), c.normalize(toTable, to))
+}
+
+func isIntegerType(typ model.ColumnType) bool {
+	switch typ {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt,
+		model.ColumnTypeMediumInt, model.ColumnTypeInt,
+		model.ColumnTypeInteger, model.ColumnTypeBigInt,
+		model.ColumnTypeBool, model.ColumnTypeBoolean:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// CommentPolicy specifies what to do with the changes that only affect
+// the comments of columns and tables
+type CommentPolicy int
+
+// List of possible CommentPolicy values. CommentCompare generates
+// comment changes like any other change. CommentIgnore does not
+// generate changes that only affect comments, although statements
+// generated for other reasons still set the new comments.
+// CommentSeparate generates them after all the other statements, so
+// that they can be applied, or left out, on their own.
+const (
+	CommentCompare CommentPolicy = iota
+	CommentIgnore
+	CommentSeparate
+)
+
+// comment returns the comment as it is compared
+func (c *columnComparer) comment(v string) string {
+	if c.trimComments {
+		return strings.TrimSpace(v)
+	}
+	return v
+}
+
+// commentOnly returns true if the columns only differ by their comments
+func (c *columnComparer) commentOnly(fromTable model.Table, from model.TableColumn, toTable model.Table, to model.TableColumn) bool {
+	ignore := *c
+	ignore.comments = CommentIgnore
+	return ignore.equal(fromTable, from, toTable, to)
+}
+
+// tableComment returns the COMMENT option of the table, or nil if the
+// table does not declare one
+func tableComment(table model.Table) model.TableOption {
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			return opt
+		}
+	}
+	return nil
+}
+
+// alteredComment returns the COMMENT option that changes the comment of
+// the table, or nil if the comment is the same in both schemas or if
+// it is not compared
+func alteredComment(ctx *alterCtx) model.TableOption {
+	if !ctx.tableOptions || ctx.columns.comments == CommentIgnore || ctx.ignore.option("COMMENT") {
+		return nil
+	}
+
+	opt := tableComment(ctx.to)
+	if opt == nil {
+		// removed options are left as is
+		return nil
+	}
+	if prev := tableComment(ctx.from); prev != nil && ctx.columns.comment(prev.Value()) == ctx.columns.comment(opt.Value()) {
+		return nil
+	}
+	return opt
+}
+
+// alterComments generates the changes that only affect comments, when
+// they are kept apart from the others with CommentSeparate
+func alterComments(ctx *diffCtx) (changes, error) {
+	if ctx.columns.comments != CommentSeparate {
+		return nil, nil
+	}
+	return eachAlteredTable(ctx, alterColumnComments, alterTableComment)
+}
+
+func alterColumnComments(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, columnName := range ctx.columnIDs(ctx.to, ctx.toColumns.Intersect(ctx.fromColumns), nil) {
+		before, ok := ctx.from.LookupColumn(columnName)
+		if !ok {
+			return nil, errors.Errorf(`column %s not found in old schema`, columnName)
+		}
+
+		after, ok := ctx.to.LookupColumn(columnName)
+		if !ok {
+			return nil, errors.Errorf(`column %s not found in new schema`, columnName)
+		}
+
+		if ctx.columns.equal(ctx.from, before, ctx.to, after) || !ctx.columns.commentOnly(ctx.from, before, ctx.to, after) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("ALTER TABLE ")
+		buf.WriteString(util.Backquote(ctx.from.Name()))
+		buf.WriteString(" CHANGE COLUMN ")
+		buf.WriteString(util.Backquote(after.Name()))
+		buf.WriteString(" ")
+		if err := format.SQL(&buf, after, ctx.fmtOptions...); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(';')
+		list.addColumnChange(changeColumnModify, ctx.from.Name(), after.Name(), buf.String(), TypeChangeWidening)
+	}
+	return list, nil
+}
+
+func alterTableComment(ctx *alterCtx) (changes, error) {
+	opt := alteredComment(ctx)
+	if opt == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("ALTER TABLE ")
+	buf.WriteString(util.Backquote(ctx.from.Name()))
+	buf.WriteString(" ")
+	if err := format.SQL(&buf, opt); err != nil {
+		return nil, err
+	}
+	buf.WriteByte(';')
+
+	var list changes
+	list.add(changeTableOptions, ctx.from.Name(), "", buf.String(), false)
+	return list, nil
+}
@@ -0,0 +1,88 @@
+package diff_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUpstreamCompat pins the output of schemalex/schemalex for the
+// inputs whose default output differs in this fork, as listed in the
+// README under MIGRATING FROM schemalex/schemalex. Expect is the output
+// of this fork, and Upstream is the output of upstream, which is
+// restored by WithUpstreamCompat.
+func TestUpstreamCompat(t *testing.T) {
+	type Spec struct {
+		Before   string
+		After    string
+		Upstream string
+		Expect   string
+	}
+
+	specs := []Spec{
+		// unnamed indexes are named after their first column
+		{
+			Before:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:    "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, INDEX (`id`) );",
+			Upstream: "ALTER TABLE `fuga` ADD INDEX (`id`);",
+			Expect:   "ALTER TABLE `fuga` ADD INDEX `id` (`id`);",
+		},
+		{
+			Before:   "",
+			After:    "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `c` VARCHAR (20) NOT NULL, INDEX (`id`), INDEX (`c`) );",
+			Upstream: "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL,\n`c` VARCHAR (20) NOT NULL,\nINDEX (`id`),\nINDEX (`c`)\n);",
+			Expect:   "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL,\n`c` VARCHAR (20) NOT NULL,\nINDEX `id` (`id`),\nINDEX `c` (`c`)\n);",
+		},
+		// the columns of primary keys are NOT NULL
+		{
+			Before:   "",
+			After:    "CREATE TABLE `fuga` ( `id` INTEGER PRIMARY KEY AUTO_INCREMENT );",
+			Upstream: "CREATE TABLE `fuga` (\n`id` INT (11) DEFAULT NULL AUTO_INCREMENT,\nPRIMARY KEY (`id`)\n);",
+			Expect:   "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL AUTO_INCREMENT,\nPRIMARY KEY (`id`)\n);",
+		},
+		{
+			Before:   "CREATE TABLE `fuga` ( `id` INTEGER );",
+			After:    "CREATE TABLE `fuga` ( `id` INTEGER PRIMARY KEY );",
+			Upstream: "ALTER TABLE `fuga` ADD PRIMARY KEY (`id`);",
+			Expect:   "ALTER TABLE `fuga` CHANGE COLUMN `id` `id` INT (11) NOT NULL;\nALTER TABLE `fuga` ADD PRIMARY KEY (`id`);",
+		},
+		{
+			Before:   "CREATE TABLE `fuga` ( `id` INTEGER AUTO_INCREMENT PRIMARY KEY );",
+			After:    "CREATE TABLE `fuga` ( `id` BIGINT AUTO_INCREMENT PRIMARY KEY );",
+			Upstream: "ALTER TABLE `fuga` CHANGE COLUMN `id` `id` BIGINT (20) DEFAULT NULL AUTO_INCREMENT;",
+			Expect:   "ALTER TABLE `fuga` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL AUTO_INCREMENT;",
+		},
+		// storage engines are compared
+		{
+			Before:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE=InnoDB;",
+			After:    "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE=MyISAM;",
+			Upstream: "",
+			Expect:   "ALTER TABLE `fuga` ENGINE = MyISAM;",
+		},
+	}
+
+	var buf bytes.Buffer
+	for _, spec := range specs {
+		buf.Reset()
+		if !assert.NoError(t, diff.Strings(&buf, spec.Before, spec.After), "diff.Strings should succeed") {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, buf.String(), "result SQL should match") {
+			t.Logf("before = %s", spec.Before)
+			t.Logf("after = %s", spec.After)
+			return
+		}
+
+		buf.Reset()
+		if !assert.NoError(t, diff.Strings(&buf, spec.Before, spec.After, diff.WithUpstreamCompat(true)), "diff.Strings should succeed") {
+			return
+		}
+		if !assert.Equal(t, spec.Upstream, buf.String(), "result SQL should match the output of upstream") {
+			t.Logf("before = %s", spec.Before)
+			t.Logf("after = %s", spec.After)
+			return
+		}
+	}
+}
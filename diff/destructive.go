@@ -0,0 +1,251 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// DestructivePolicy specifies what to do with statements that may
+// lose data when applied, such as DROP TABLE or DROP COLUMN
+type DestructivePolicy int
+
+// List of possible DestructivePolicy values. DestructiveAllow emits
+// destructive statements along with the others. DestructiveSkip omits
+// them from the output. DestructiveRefuse refuses to generate the diff
+// at all, and returns a *DestructiveError instead
+const (
+	DestructiveAllow DestructivePolicy = iota
+	DestructiveSkip
+	DestructiveRefuse
+)
+
+// DestructiveError is returned when the diff contains destructive
+// statements, and the DestructiveRefuse policy is in effect
+type DestructiveError struct {
+	Statements []string
+}
+
+func (e *DestructiveError) Error() string {
+	return fmt.Sprintf("refusing to generate %d destructive statement(s):\n%s", len(e.Statements), strings.Join(e.Statements, "\n"))
+}
+
+// TypeChange classifies how the modification of a column changes the
+// values that it can hold
+type TypeChange string
+
+// List of possible TypeChange values. TypeChangeWidening keeps every
+// value, such as INT to BIGINT, or a change that only affects the
+// comment or the default. TypeChangeNarrowing may truncate values or
+// fail on existing rows, such as VARCHAR (20) to VARCHAR (10), the
+// addition of a NOT NULL constraint, a change of character set or
+// collation, DATETIME (6) to DATETIME, or the addition of an SRID.
+// TypeChangeIncompatible converts values to a different kind of type,
+// such as VARCHAR to INT.
+const (
+	TypeChangeWidening     TypeChange = "widening"
+	TypeChangeNarrowing    TypeChange = "narrowing"
+	TypeChangeIncompatible TypeChange = "incompatible"
+)
+
+type typeFamily int
+
+const (
+	familyOther typeFamily = iota
+	familyInteger
+	familyFloat
+	familyDecimal
+	familyString
+	familyBinary
+	familyText
+	familyBlob
+	familyEnum
+	familySet
+)
+
+// textFamilies maps the families of the types that hold the same kind
+// of values to the same family
+var textFamilies = map[typeFamily]typeFamily{
+	familyString: familyString,
+	familyText:   familyString,
+	familyBinary: familyBinary,
+	familyBlob:   familyBinary,
+}
+
+// typeRanks orders the types within the same family from the
+// narrowest to the widest
+var typeRanks = map[model.ColumnType]struct {
+	family typeFamily
+	rank   int
+}{
+	model.ColumnTypeTinyInt:    {familyInteger, 1},
+	model.ColumnTypeSmallInt:   {familyInteger, 2},
+	model.ColumnTypeMediumInt:  {familyInteger, 3},
+	model.ColumnTypeInt:        {familyInteger, 4},
+	model.ColumnTypeBigInt:     {familyInteger, 5},
+	model.ColumnTypeFloat:      {familyFloat, 1},
+	model.ColumnTypeDouble:     {familyFloat, 2},
+	model.ColumnTypeDecimal:    {familyDecimal, 1},
+	model.ColumnTypeChar:       {familyString, 1},
+	model.ColumnTypeVarChar:    {familyString, 1},
+	model.ColumnTypeBinary:     {familyBinary, 1},
+	model.ColumnTypeVarBinary:  {familyBinary, 1},
+	model.ColumnTypeTinyText:   {familyText, 1},
+	model.ColumnTypeText:       {familyText, 2},
+	model.ColumnTypeMediumText: {familyText, 3},
+	model.ColumnTypeLongText:   {familyText, 4},
+	model.ColumnTypeTinyBlob:   {familyBlob, 1},
+	model.ColumnTypeBlob:       {familyBlob, 2},
+	model.ColumnTypeMediumBlob: {familyBlob, 3},
+	model.ColumnTypeLongBlob:   {familyBlob, 4},
+	model.ColumnTypeEnum:       {familyEnum, 1},
+	model.ColumnTypeSet:        {familySet, 1},
+}
+
+// classifyColumnChange classifies the change of the definition of a
+// column from `from` to `to`. Only widening changes are safe: the
+// others may lose data, or fail on existing rows.
+func classifyColumnChange(from, to model.TableColumn) TypeChange {
+	fromType := from.Type().SynonymType()
+	toType := to.Type().SynonymType()
+	fromRank, fromOK := typeRanks[fromType]
+	toRank, toOK := typeRanks[toType]
+	if !fromOK || !toOK {
+		// We know nothing about these types, so any change
+		// between them may be lossy
+		if fromType != toType {
+			return TypeChangeIncompatible
+		}
+	} else if fromRank.family != toRank.family {
+		// Strings keep their characters when they are converted to
+		// TEXT, and the other way around, but may not fit
+		if family, ok := textFamilies[fromRank.family]; ok && family == textFamilies[toRank.family] {
+			return TypeChangeNarrowing
+		}
+		return TypeChangeIncompatible
+	}
+
+	if from.NullState() != model.NullStateNotNull && to.NullState() == model.NullStateNotNull {
+		return TypeChangeNarrowing
+	}
+	if fromOK && toOK && isNarrowing(fromRank.family, fromRank.rank > toRank.rank, from, to) {
+		return TypeChangeNarrowing
+	}
+	if isTemporalType(fromType) && fromType == toType && fractionalSeconds(to) < fractionalSeconds(from) {
+		return TypeChangeNarrowing
+	}
+	if isCharacterType(toType) && isCharsetNarrowing(from, to) {
+		return TypeChangeNarrowing
+	}
+	if to.HasSRID() && from.SRID() != to.SRID() {
+		// the values of other spatial reference systems are rejected
+		return TypeChangeNarrowing
+	}
+	return TypeChangeWidening
+}
+
+// isCharsetNarrowing returns true if the characters of `from` may not
+// be kept by `to`, as its character set can not represent them, or may
+// now compare equal, as its collation differs, which fails on unique
+// indexes. Converting utf8 to utf8mb4, with the same collation rules,
+// keeps every character. Columns that inherit their character set or
+// collation are only compared when WithServerCharset expands them.
+func isCharsetNarrowing(from, to model.TableColumn) bool {
+	fromCharset := normalizeCharset(from.CharacterSet())
+	toCharset := normalizeCharset(to.CharacterSet())
+	if fromCharset != "" && toCharset != "" && fromCharset != toCharset && !(fromCharset == "utf8" && toCharset == "utf8mb4") {
+		return true
+	}
+
+	fromCollation := normalizeCollation(from.Collation())
+	toCollation := normalizeCollation(to.Collation())
+	if fromCollation == "" || toCollation == "" {
+		return false
+	}
+	// the rules of the collation are what follows the character set,
+	// such as general_ci for utf8mb4_general_ci
+	return strings.TrimPrefix(fromCollation, charsetOf(fromCollation)) != strings.TrimPrefix(toCollation, charsetOf(toCollation))
+}
+
+func isTemporalType(typ model.ColumnType) bool {
+	switch typ {
+	case model.ColumnTypeTime, model.ColumnTypeTimestamp, model.ColumnTypeDateTime:
+		return true
+	}
+	return false
+}
+
+// fractionalSeconds returns the fractional seconds precision of a TIME,
+// TIMESTAMP or DATETIME column, which defaults to 0
+func fractionalSeconds(col model.TableColumn) int {
+	if !col.HasLength() {
+		return 0
+	}
+	n, _ := strconv.Atoi(col.Length().Length())
+	return n
+}
+
+// isNarrowing returns true if the values of `from`, whose type is of
+// the same family as the type of `to`, may not fit in `to`
+func isNarrowing(family typeFamily, smaller bool, from, to model.TableColumn) bool {
+	if smaller {
+		return true
+	}
+
+	switch family {
+	case familyInteger, familyFloat:
+		return from.IsUnsigned() != to.IsUnsigned()
+	case familyDecimal:
+		if from.IsUnsigned() != to.IsUnsigned() {
+			return true
+		}
+		fromPrecision, fromScale := decimalSize(from)
+		toPrecision, toScale := decimalSize(to)
+		return toScale < fromScale || toPrecision-toScale < fromPrecision-fromScale
+	case familyString, familyBinary:
+		return stringSize(to) < stringSize(from)
+	case familyEnum:
+		return !containsAll(to.EnumValues(), from.EnumValues())
+	case familySet:
+		return !containsAll(to.SetValues(), from.SetValues())
+	}
+	return false
+}
+
+func decimalSize(col model.TableColumn) (int, int) {
+	l := col.NativeLength()
+	if col.HasLength() {
+		l = col.Length()
+	}
+
+	precision, _ := strconv.Atoi(l.Length())
+	var scale int
+	if l.HasDecimal() {
+		scale, _ = strconv.Atoi(l.Decimal())
+	}
+	return precision, scale
+}
+
+func stringSize(col model.TableColumn) int {
+	if !col.HasLength() {
+		// CHAR and BINARY default to a length of 1
+		return 1
+	}
+	n, _ := strconv.Atoi(col.Length().Length())
+	return n
+}
+
+func containsAll(values, required chan string) bool {
+	set := make(map[string]struct{})
+	for v := range values {
+		set[v] = struct{}{}
+	}
+	for v := range required {
+		if _, ok := set[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -7,31 +7,82 @@ import (
 	"io"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/deckarep/golang-set"
 	"github.com/eihigh/schemalex"
 	"github.com/eihigh/schemalex/format"
 	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
 	"github.com/eihigh/schemalex/model"
 )
 
+// change is a single statement generated by the diff, along with
+// what it changes and whether or not applying it may lose data.
+// name is the name of the changed column, index, foreign key, or
+// histogram, and is empty for changes to the table itself. typeChange
+// classifies the changes to the definition of existing columns
+type change struct {
+	kind        changeKind
+	table       string
+	name        string
+	sql         string
+	destructive bool
+	typeChange  TypeChange
+	reordered   bool
+	note        string
+	// delimited is true for the statements that are written within a
+	// DELIMITER block, as they may contain semicolons
+	delimited bool
+}
+
+type changes []*change
+
+func (l *changes) add(kind changeKind, table, name, sql string, destructive bool) {
+	*l = append(*l, &change{kind: kind, table: table, name: name, sql: sql, destructive: destructive})
+}
+
+func (l *changes) addColumnChange(kind changeKind, table, name, sql string, typeChange TypeChange) {
+	*l = append(*l, &change{kind: kind, table: table, name: name, sql: sql, destructive: typeChange != TypeChangeWidening, typeChange: typeChange})
+}
+
 type diffCtx struct {
-	fromSet mapset.Set
-	toSet   mapset.Set
-	from    model.Stmts
-	to      model.Stmts
+	fromSet        mapset.Set
+	toSet          mapset.Set
+	from           model.Stmts
+	to             model.Stmts
+	columnOrder    bool
+	histograms     bool
+	tableOptions   bool
+	renameIndexes  bool
+	ignoreSymbols  bool
+	sortByName     bool
+	separateFKs    bool
+	engines        engineSupport
+	explicitRows   bool
+	ifNotExists    bool
+	ifNotExistsSet bool
+	ignore         *ignoreRules
+	columns        *columnComparer
+	fmtOptions     []format.Option
+
+	// filled by sortTables
+	dropOrder      []model.Table
+	createOrder    []model.Table
+	deferredDrop   []*foreignKey
+	deferredCreate []*foreignKey
 }
 
-func newDiffCtx(from, to model.Stmts) *diffCtx {
+func newDiffCtx(from, to model.Stmts, ignore *ignoreRules) *diffCtx {
 	fromSet := mapset.NewSet()
 	for _, stmt := range from {
-		if cs, ok := stmt.(model.Table); ok {
+		if cs, ok := stmt.(model.Table); ok && !ignore.tableStmt(cs) {
 			fromSet.Add(cs.ID())
 		}
 	}
 	toSet := mapset.NewSet()
 	for _, stmt := range to {
-		if cs, ok := stmt.(model.Table); ok {
+		if cs, ok := stmt.(model.Table); ok && !ignore.tableStmt(cs) {
 			toSet.Add(cs.ID())
 		}
 	}
@@ -41,6 +92,7 @@ func newDiffCtx(from, to model.Stmts) *diffCtx {
 		toSet:   toSet,
 		from:    from,
 		to:      to,
+		ignore:  ignore,
 	}
 }
 
@@ -49,39 +101,343 @@ func newDiffCtx(from, to model.Stmts) *diffCtx {
 // writing the result to `dst`
 func Statements(dst io.Writer, from, to model.Stmts, options ...Option) error {
 	var txn bool
+	var begin = "BEGIN"
+	var delimiter = ";"
+	var singleLine bool
+	var displayWidth = true
+	var fkChecks, fkChecksSet bool
+	var ifNotExists, ifNotExistsSet bool
+	var columnOrder bool
+	var histograms bool
+	var tableOptions bool
+	var explicitRows bool
+	var caseInsensitive bool
+	var renameIndexes bool
+	var ignoreSymbols bool
+	var sortByName bool
+	var dependencyOrder bool
+	var separateFKs bool
+	var dialect schemalex.Dialect
+	var version schemalex.MySQLVersion
+	var ignoreDisplayWidthSet bool
+	var columns columnComparer
+	var ignore ignoreRules
+	var policy DestructivePolicy
+	var allowNarrowing bool
+	var summary *Summary
+	var asJSON bool
+	var unified bool
+	var color bool
+	var exported *[]Change
+	var onlyTable string
+	var warn func(schemalex.Warning)
 	for _, o := range options {
 		switch o.Name() {
 		case optkeyTransaction:
 			txn = o.Value().(bool)
+		case optkeyStartTransaction:
+			if o.Value().(bool) {
+				txn = true
+				begin = "START TRANSACTION"
+			} else {
+				begin = "BEGIN"
+			}
+		case optkeyDelimiter:
+			delimiter = o.Value().(string)
+		case optkeySingleLine:
+			singleLine = o.Value().(bool)
+		case optkeyForeignKeyChecks:
+			fkChecks = o.Value().(bool)
+			fkChecksSet = true
+		case optkeyIfNotExists:
+			ifNotExists = o.Value().(bool)
+			ifNotExistsSet = true
+		case optkeyColumnOrder:
+			columnOrder = o.Value().(bool)
+		case optkeyHistograms:
+			histograms = o.Value().(bool)
+		case optkeyTableOptions:
+			tableOptions = o.Value().(bool)
+		case optkeyExplicitRowFormat:
+			explicitRows = o.Value().(bool)
+		case optkeyRenameIndexes:
+			renameIndexes = o.Value().(bool)
+		case optkeyIgnoreFKSymbols:
+			ignoreSymbols = o.Value().(bool)
+		case optkeySortByName:
+			sortByName = o.Value().(bool)
+		case optkeyDependencyOrder:
+			dependencyOrder = o.Value().(bool)
+		case optkeySeparateForeignKeys:
+			separateFKs = o.Value().(bool)
+		case optkeyCaseInsensitive:
+			caseInsensitive = o.Value().(bool)
+		case optkeyServerCharset:
+			v := o.Value().([2]string)
+			columns.charsets = newCharsetDefaults(v[0], v[1])
+		case optkeyIgnoreDisplayWidth:
+			columns.ignoreDisplayWidth = o.Value().(bool)
+			ignoreDisplayWidthSet = true
+		case optkeyNormalizeTimestamp:
+			columns.currentTimestamp = o.Value().(bool)
+		case optkeyComments:
+			columns.comments = o.Value().(CommentPolicy)
+		case optkeyTrimComments:
+			columns.trimComments = o.Value().(bool)
+		case optkeyDialect:
+			dialect = o.Value().(schemalex.Dialect)
+		case optkeyMySQLVersion:
+			version = o.Value().(schemalex.MySQLVersion)
+		case optkeyDisplayWidth:
+			displayWidth = o.Value().(bool)
+		case optkeyIgnoreTables:
+			if err := ignore.addTables(o.Value().([]string)); err != nil {
+				return errors.Wrap(err, `failed to produce diff`)
+			}
+		case optkeyOnlyTables:
+			if err := ignore.addOnlyTables(o.Value().([]string)); err != nil {
+				return errors.Wrap(err, `failed to produce diff`)
+			}
+		case optkeyIgnoreColumns:
+			if err := ignore.addColumns(o.Value().([]string)); err != nil {
+				return errors.Wrap(err, `failed to produce diff`)
+			}
+		case optkeyTemporaryTables:
+			ignore.temporary = !o.Value().(bool)
+		case optkeyIgnoreTableOptions:
+			if err := ignore.addOptions(o.Value().([]string)); err != nil {
+				return errors.Wrap(err, `failed to produce diff`)
+			}
+		case optkeyUpstreamCompat:
+			if o.Value().(bool) {
+				if err := ignore.addOptions([]string{"ENGINE"}); err != nil {
+					return errors.Wrap(err, `failed to produce diff`)
+				}
+			}
+		case optkeyDestructive:
+			policy = o.Value().(DestructivePolicy)
+		case optkeyAllowNarrowing:
+			allowNarrowing = o.Value().(bool)
+		case optkeySummary:
+			summary = o.Value().(*Summary)
+		case optkeyJSON:
+			asJSON = o.Value().(bool)
+		case optkeyUnified:
+			unified = o.Value().(bool)
+		case optkeyColor:
+			color = o.Value().(bool)
+		case optkeyChanges:
+			exported = o.Value().(*[]Change)
+		case optkeyTable:
+			onlyTable = o.Value().(string)
+		case optkeyWarningHandler:
+			warn = o.Value().(func(schemalex.Warning))
 		}
 	}
 
-	ctx := newDiffCtx(from, to)
+	if !fkChecksSet {
+		fkChecks = txn
+	}
+
+	if dialect == schemalex.DialectMariaDB || dialect == schemalex.DialectTiDB {
+		// MariaDB and TiDB do not support ANALYZE TABLE ... UPDATE
+		// HISTOGRAM, and their versions are not comparable to the MySQL
+		// ones
+		histograms = false
+		version = schemalex.MySQLVersion{}
+	}
+
+	if !version.IsZero() {
+		// avoid statements that the target server does not support
+		if !version.AtLeast(5, 7, 0) {
+			renameIndexes = false
+		}
+		if !version.AtLeast(8, 0, 0) {
+			histograms = false
+		}
+		if columns.charsets == nil {
+			if charset, collation := DefaultServerCharset(version.String()); charset != "" {
+				columns.charsets = newCharsetDefaults(charset, collation)
+			}
+		}
+		if !ignoreDisplayWidthSet && version.AtLeast(8, 0, 19) {
+			columns.ignoreDisplayWidth = true
+		}
+	}
+
+	if caseInsensitive {
+		from = foldNames(from, to)
+	}
+
+	if dependencyOrder {
+		from = from.InDependencyOrder()
+		to = to.InDependencyOrder()
+	}
+
+	ctx := newDiffCtx(from, to, &ignore)
+	ctx.columnOrder = columnOrder
+	ctx.histograms = histograms
+	ctx.tableOptions = tableOptions
+	ctx.explicitRows = explicitRows
+	ctx.ifNotExists = ifNotExists
+	ctx.ifNotExistsSet = ifNotExistsSet
+	ctx.columns = &columns
+	ctx.renameIndexes = renameIndexes
+	ctx.ignoreSymbols = ignoreSymbols
+	ctx.sortByName = sortByName
+	ctx.separateFKs = separateFKs
+	ctx.engines = engineSupport{defaultEngine: defaultEngine(dialect), version: version}
+	if singleLine {
+		ctx.fmtOptions = append(ctx.fmtOptions, format.WithSingleLine(true))
+	}
+	if !displayWidth {
+		ctx.fmtOptions = append(ctx.fmtOptions, format.WithDisplayWidth(false))
+	}
+
+	if err := ctx.sortTables(); err != nil {
+		return errors.Wrap(err, `failed to produce diff`)
+	}
+
+	if warn != nil {
+		for _, msg := range ctx.engineWarnings(onlyTable) {
+			warn(schemalex.Warning{Message: msg})
+		}
+	}
 
-	var procs = []func(*diffCtx, io.Writer) (int64, error){
+	var procs = []func(*diffCtx) (changes, error){
+		dropRoutines,
+		dropViews,
+		dropForeignKeys,
 		dropTables,
 		createTables,
 		alterTables,
+		addForeignKeys,
+		replaceViews,
+		createRoutines,
+		updateHistograms,
+		alterComments,
 	}
 
-	var buf bytes.Buffer
-	if txn {
-		buf.WriteString("\nBEGIN;\n\nSET FOREIGN_KEY_CHECKS = 0;")
-	}
-
+	var groups []changes
+	var destructive []string
 	for _, p := range procs {
-		var pbuf bytes.Buffer
-		n, err := p(ctx, &pbuf)
+		list, err := p(ctx)
 		if err != nil {
 			return errors.Wrap(err, `failed to produce diff`)
 		}
-		if txn && n > 0 || !txn && buf.Len() > 0 && n > 0 {
-			buf.WriteString("\n\n")
+
+		var filtered changes
+		for _, c := range list {
+			if onlyTable != "" && c.table != onlyTable {
+				continue
+			}
+			if c.destructive && !(allowNarrowing && c.typeChange == TypeChangeNarrowing) {
+				destructive = append(destructive, c.sql)
+				if policy == DestructiveSkip {
+					continue
+				}
+			}
+			filtered = append(filtered, c)
+		}
+		groups = append(groups, filtered)
+	}
+
+	if policy == DestructiveRefuse && len(destructive) > 0 {
+		return &DestructiveError{Statements: destructive}
+	}
+
+	if summary != nil {
+		*summary = Summary{}
+		for _, list := range groups {
+			for _, c := range list {
+				summary.count(c)
+			}
+		}
+	}
+
+	if exported != nil {
+		*exported = exportChanges(groups)
+	}
+
+	if asJSON {
+		return writeJSON(dst, groups)
+	}
+
+	if unified {
+		// statements are written on multiple lines regardless of
+		// WithSingleLine, which would make a single line of each
+		fmtOptions := []format.Option{format.WithIndent(" ", 2)}
+		if !displayWidth {
+			fmtOptions = append(fmtOptions, format.WithDisplayWidth(false))
+		}
+		return writeUnified(dst, ctx, groups, color, fmtOptions)
+	}
+
+	// statements are written with a trailing semicolon, which is
+	// replaced by the requested delimiter
+	terminate := func(sql string) string {
+		return strings.TrimSuffix(sql, ";") + delimiter
+	}
+
+	var blocks []string
+	if txn {
+		blocks = append(blocks, terminate(begin))
+	}
+	if fkChecks {
+		blocks = append(blocks, terminate("SET FOREIGN_KEY_CHECKS = 0"))
+	}
+	for _, list := range groups {
+		if len(list) == 0 {
+			continue
+		}
+		// the statements of a group with triggers or stored routines are
+		// written within a DELIMITER block, unless another delimiter is
+		// requested, which the runner already expects
+		end := terminate
+		var block string
+		if delimiter == ";" {
+			for _, c := range list {
+				if c.delimited {
+					sqls := make([]string, len(list))
+					for i, c := range list {
+						sqls[i] = c.sql
+					}
+					block = blockDelimiter(sqls)
+					end = func(sql string) string {
+						return strings.TrimSuffix(sql, ";") + block
+					}
+					break
+				}
+			}
 		}
-		pbuf.WriteTo(&buf)
+
+		stmts := make([]string, len(list))
+		for i, c := range list {
+			stmts[i] = end(c.sql)
+			if c.note != "" {
+				stmts[i] = "-- " + c.note + "\n" + stmts[i]
+			}
+		}
+		if block != "" {
+			stmts = append(append([]string{"DELIMITER " + block}, stmts...), "DELIMITER ;")
+		}
+		blocks = append(blocks, strings.Join(stmts, "\n"))
+	}
+	if fkChecks {
+		blocks = append(blocks, terminate("SET FOREIGN_KEY_CHECKS = 1"))
 	}
 	if txn {
-		buf.WriteString("\n\nSET FOREIGN_KEY_CHECKS = 1;\n\nCOMMIT;")
+		blocks = append(blocks, terminate("COMMIT"))
+	}
+
+	var buf bytes.Buffer
+	if singleLine {
+		buf.WriteString(strings.Join(blocks, "\n"))
+	} else {
+		if txn {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Join(blocks, "\n\n"))
 	}
 
 	if _, err := buf.WriteTo(dst); err != nil {
@@ -102,7 +458,9 @@ func Strings(dst io.Writer, from, to string, options ...Option) error {
 		}
 	}
 	if p == nil {
-		p = schemalex.New()
+		// the parser picks up WithDialect, WithMySQLVersion, and
+		// WithUpstreamCompat
+		p = schemalex.NewParser(options...)
 	}
 
 	stmts1, err := p.ParseString(from)
@@ -142,72 +500,65 @@ func Sources(dst io.Writer, from, to schemalex.SchemaSource, options ...Option)
 	return Strings(dst, fromStr, buf.String(), options...)
 }
 
-func dropTables(ctx *diffCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	ids := ctx.fromSet.Difference(ctx.toSet)
-	for i, id := range ids.ToSlice() {
-		if i > 0 {
-			buf.WriteByte('\n')
-		}
-
-		stmt, ok := ctx.from.Lookup(id.(string))
-		if !ok {
-			return 0, errors.Errorf(`failed to lookup table %s`, id)
-		}
-
-		table, ok := stmt.(model.Table)
-		if !ok {
-			return 0, errors.Errorf(`lookup failed: %s is not a model.Table`, id)
-		}
-		buf.WriteString("DROP TABLE `")
-		buf.WriteString(table.Name())
-		buf.WriteString("`;")
+func dropTables(ctx *diffCtx) (changes, error) {
+	var list changes
+	for _, table := range ctx.dropOrder {
+		list.add(changeTableDrop, table.Name(), "", "DROP TABLE "+util.Backquote(table.Name())+";", true)
 	}
-
-	return buf.WriteTo(dst)
+	return list, nil
 }
 
-func createTables(ctx *diffCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-
-	ids := ctx.toSet.Difference(ctx.fromSet)
-	for _, id := range ids.ToSlice() {
-		// Lookup the corresponding statement, and add its SQL
-		stmt, ok := ctx.to.Lookup(id.(string))
-		if !ok {
-			return 0, errors.Errorf(`failed to lookup table %s`, id)
+func createTables(ctx *diffCtx) (changes, error) {
+	var list changes
+	for _, table := range ctx.createOrder {
+		stmt := withoutForeignKeys(table, ctx.deferredCreate)
+		if ctx.ifNotExistsSet && stmt.IsIfNotExists() != ctx.ifNotExists {
+			stmt = copyTable(stmt, nil)
+			stmt.SetIfNotExists(ctx.ifNotExists)
 		}
 
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
-		}
-
-		if err := format.SQL(&buf, stmt); err != nil {
-			return 0, err
+		var buf bytes.Buffer
+		if err := format.SQL(&buf, stmt, ctx.fmtOptions...); err != nil {
+			return nil, err
 		}
 		buf.WriteByte(';')
+		list.add(changeTableCreate, table.Name(), "", buf.String(), false)
 	}
-	return buf.WriteTo(dst)
+	return list, nil
 }
 
 type alterCtx struct {
-	fromColumns mapset.Set
-	toColumns   mapset.Set
-	fromIndexes mapset.Set
-	toIndexes   mapset.Set
-	from        model.Table
-	to          model.Table
+	fromColumns  mapset.Set
+	toColumns    mapset.Set
+	fromIndexes  mapset.Set
+	toIndexes    mapset.Set
+	from         model.Table
+	to           model.Table
+	columnOrder  bool
+	tableOptions bool
+	sortByName   bool
+	engines      engineSupport
+	explicitRows bool
+	ignore       *ignoreRules
+	columns      *columnComparer
+	fmtOptions   []format.Option
+	renames      []*indexRename
+	reorders     map[string]*indexReorder
 }
 
-func newAlterCtx(from, to model.Table) *alterCtx {
+func newAlterCtx(from, to model.Table, ignore *ignoreRules) *alterCtx {
 	fromColumns := mapset.NewSet()
 	for col := range from.Columns() {
-		fromColumns.Add(col.ID())
+		if !ignore.column(from.Name(), col.Name()) {
+			fromColumns.Add(col.ID())
+		}
 	}
 
 	toColumns := mapset.NewSet()
 	for col := range to.Columns() {
-		toColumns.Add(col.ID())
+		if !ignore.column(to.Name(), col.Name()) {
+			toColumns.Add(col.ID())
+		}
 	}
 
 	fromIndexes := mapset.NewSet()
@@ -227,79 +578,120 @@ func newAlterCtx(from, to model.Table) *alterCtx {
 		toIndexes:   toIndexes,
 		from:        from,
 		to:          to,
+		ignore:      ignore,
 	}
 }
 
-func alterTables(ctx *diffCtx, dst io.Writer) (int64, error) {
-	procs := []func(*alterCtx, io.Writer) (int64, error){
+func alterTables(ctx *diffCtx) (changes, error) {
+	if ctx.separateFKs {
+		return eachAlteredTable(ctx,
+			dropTableIndexes,
+			renameTableIndexes,
+			dropTableColumns,
+			addTableColumns,
+			alterTableColumns,
+			reorderTableColumns,
+			addTableIndexes,
+			alterTableOptions,
+			alterTablePartitions,
+		)
+	}
+
+	// foreign keys are dropped and added along with the other
+	// statements of their table
+	return eachAlteredTable(ctx,
+		dropTableForeignKeys,
 		dropTableIndexes,
+		renameTableIndexes,
 		dropTableColumns,
 		addTableColumns,
 		alterTableColumns,
+		reorderTableColumns,
 		addTableIndexes,
-	}
+		addTableForeignKeys,
+		alterTableOptions,
+		alterTablePartitions,
+	)
+}
 
-	ids := ctx.toSet.Intersect(ctx.fromSet)
-	var buf bytes.Buffer
-	for _, id := range ids.ToSlice() {
+// eachAlteredTable applies procs to each table that exists in both
+// schemas, in the order of the new schema
+func eachAlteredTable(ctx *diffCtx, procs ...func(*alterCtx) (changes, error)) (changes, error) {
+	var list changes
+	for _, id := range ctx.tableIDs(ctx.to, ctx.fromSet, nil) {
 		var stmt model.Stmt
 		var ok bool
 
-		stmt, ok = ctx.from.Lookup(id.(string))
+		stmt, ok = ctx.from.Lookup(id)
 		if !ok {
-			return 0, errors.Errorf(`table '%s' not found in old schema (alter table)`, id)
+			return nil, errors.Errorf(`table '%s' not found in old schema (alter table)`, id)
 		}
 		beforeStmt := stmt.(model.Table)
 
-		stmt, ok = ctx.to.Lookup(id.(string))
+		stmt, ok = ctx.to.Lookup(id)
 		if !ok {
-			return 0, errors.Errorf(`table '%s' not found in new schema (alter table)`, id)
+			return nil, errors.Errorf(`table '%s' not found in new schema (alter table)`, id)
 		}
 		afterStmt := stmt.(model.Table)
 
-		var pbuf bytes.Buffer
-		alterCtx := newAlterCtx(beforeStmt, afterStmt)
+		alterCtx := newAlterCtx(beforeStmt, afterStmt, ctx.ignore)
+		alterCtx.columnOrder = ctx.columnOrder
+		alterCtx.tableOptions = ctx.tableOptions
+		alterCtx.sortByName = ctx.sortByName
+		alterCtx.engines = ctx.engines
+		alterCtx.explicitRows = ctx.explicitRows
+		alterCtx.columns = ctx.columns
+		alterCtx.fmtOptions = ctx.fmtOptions
+		if ctx.ignoreSymbols {
+			for _, pair := range matchGeneratedSymbols(alterCtx) {
+				alterCtx.fromIndexes.Remove(pair[0].ID())
+				alterCtx.toIndexes.Remove(pair[1].ID())
+			}
+			// the implicit indexes of the foreign keys come and go
+			// with their symbols
+			for _, id := range implicitIndexes(beforeStmt) {
+				alterCtx.fromIndexes.Remove(id)
+			}
+			for _, id := range implicitIndexes(afterStmt) {
+				alterCtx.toIndexes.Remove(id)
+			}
+		}
+		if ctx.renameIndexes {
+			// renamed indexes are neither dropped nor added
+			alterCtx.renames = findIndexRenames(alterCtx)
+			for _, r := range alterCtx.renames {
+				alterCtx.fromIndexes.Remove(r.from.ID())
+				alterCtx.toIndexes.Remove(r.to.ID())
+			}
+		}
+		alterCtx.reorders = findIndexReorders(alterCtx)
 		for _, p := range procs {
-			n, err := p(alterCtx, &pbuf)
+			l, err := p(alterCtx)
 			if err != nil {
-				return 0, errors.Wrap(err, `failed to generate alter table`)
-			}
-
-			if buf.Len() > 0 && n > 0 {
-				buf.WriteByte('\n')
+				return nil, errors.Wrap(err, `failed to generate alter table`)
 			}
-			pbuf.WriteTo(&buf)
+			list = append(list, l...)
 		}
 	}
 
-	return buf.WriteTo(dst)
+	return list, nil
 }
 
-func dropTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
-	columnNames := ctx.fromColumns.Difference(ctx.toColumns)
-
-	var buf bytes.Buffer
-	for _, columnName := range columnNames.ToSlice() {
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
-		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` DROP COLUMN `")
-		col, ok := ctx.from.LookupColumn(columnName.(string))
+func dropTableColumns(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, columnName := range ctx.columnIDs(ctx.from, ctx.fromColumns, ctx.toColumns) {
+		col, ok := ctx.from.LookupColumn(columnName)
 		if !ok {
-			return 0, errors.Errorf(`failed to lookup column %s`, columnName)
+			return nil, errors.Errorf(`failed to lookup column %s`, columnName)
 		}
-
-		buf.WriteString(col.Name())
-		buf.WriteString("`;")
+		list.add(changeColumnDrop, ctx.from.Name(), col.Name(), "ALTER TABLE "+util.Backquote(ctx.from.Name())+" DROP COLUMN "+util.Backquote(col.Name())+";", true)
 	}
 
-	return buf.WriteTo(dst)
+	return list, nil
 }
 
-func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
+func addTableColumns(ctx *alterCtx) (changes, error) {
+	var list changes
 
 	beforeToNext := make(map[string]string) // lookup next column
 	nextToBefore := make(map[string]string) // lookup before column
@@ -308,12 +700,11 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 	// we always start adding with a column that has a either no before
 	// columns, or one that already exists in the database
 	var firstColumn model.TableColumn
-	for _, v := range ctx.toColumns.Difference(ctx.fromColumns).ToSlice() {
-		columnName := v.(string)
+	for _, columnName := range ctx.columnIDs(ctx.to, ctx.toColumns, ctx.fromColumns) {
 		// find the before-column for each.
 		col, ok := ctx.to.LookupColumn(columnName)
 		if !ok {
-			return 0, errors.Errorf(`failed to lookup column %s`, columnName)
+			return nil, errors.Errorf(`failed to lookup column %s`, columnName)
 		}
 
 		beforeCol, hasBeforeCol := ctx.to.LookupColumnBefore(col.ID())
@@ -331,14 +722,15 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 
 	// First column is always safe to add
 	if firstColumn != nil {
-		writeAddColumn(ctx, &buf, firstColumn.ID())
+		if err := addColumns(ctx, &list, firstColumn.ID()); err != nil {
+			return nil, err
+		}
 	}
 
 	var columnNames []string
 	// Find columns that have before columns which existed in both
 	// from and to tables
-	for _, v := range ctx.toColumns.Intersect(ctx.fromColumns).ToSlice() {
-		columnName := v.(string)
+	for _, columnName := range ctx.columnIDs(ctx.to, ctx.toColumns.Intersect(ctx.fromColumns), nil) {
 		if nextColumnName, ok := beforeToNext[columnName]; ok {
 			delete(beforeToNext, columnName)
 			delete(nextToBefore, nextColumnName)
@@ -348,7 +740,9 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 
 	if len(columnNames) > 0 {
 		sort.Strings(columnNames)
-		writeAddColumn(ctx, &buf, columnNames...)
+		if err := addColumns(ctx, &list, columnNames...); err != nil {
+			return nil, err
+		}
 	}
 
 	// Finally, we process the remaining columns.
@@ -366,12 +760,14 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 			jcol, _ := ctx.to.LookupColumnOrder(columnNames[j])
 			return icol < jcol
 		})
-		writeAddColumn(ctx, &buf, columnNames...)
+		if err := addColumns(ctx, &list, columnNames...); err != nil {
+			return nil, err
+		}
 	}
-	return buf.WriteTo(dst)
+	return list, nil
 }
 
-func writeAddColumn(ctx *alterCtx, buf *bytes.Buffer, columnNames ...string) error {
+func addColumns(ctx *alterCtx, list *changes, columnNames ...string) error {
 	for _, columnName := range columnNames {
 		stmt, ok := ctx.to.LookupColumn(columnName)
 		if !ok {
@@ -379,165 +775,338 @@ func writeAddColumn(ctx *alterCtx, buf *bytes.Buffer, columnNames ...string) err
 		}
 
 		beforeCol, hasBeforeCol := ctx.to.LookupColumnBefore(stmt.ID())
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
-		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` ADD COLUMN ")
-		if err := format.SQL(buf, stmt); err != nil {
+		var buf bytes.Buffer
+		buf.WriteString("ALTER TABLE ")
+		buf.WriteString(util.Backquote(ctx.from.Name()))
+		buf.WriteString(" ADD COLUMN ")
+		if err := format.SQL(&buf, stmt, ctx.fmtOptions...); err != nil {
 			return err
 		}
 		if hasBeforeCol {
-			buf.WriteString(" AFTER `")
-			buf.WriteString(beforeCol.Name())
-			buf.WriteString("`")
+			buf.WriteString(" AFTER ")
+			buf.WriteString(util.Backquote(beforeCol.Name()))
 		} else {
 			buf.WriteString(" FIRST")
 		}
 
 		buf.WriteByte(';')
+		list.add(changeColumnAdd, ctx.from.Name(), stmt.Name(), buf.String(), false)
 	}
 	return nil
 }
 
-func alterTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	columnNames := ctx.toColumns.Intersect(ctx.fromColumns)
-	for _, columnName := range columnNames.ToSlice() {
-		beforeColumnStmt, ok := ctx.from.LookupColumn(columnName.(string))
+func alterTableColumns(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, columnName := range ctx.columnIDs(ctx.to, ctx.toColumns.Intersect(ctx.fromColumns), nil) {
+		beforeColumnStmt, ok := ctx.from.LookupColumn(columnName)
 		if !ok {
-			return 0, errors.Errorf(`column %s not found in old schema`, columnName)
+			return nil, errors.Errorf(`column %s not found in old schema`, columnName)
 		}
 
-		afterColumnStmt, ok := ctx.to.LookupColumn(columnName.(string))
+		afterColumnStmt, ok := ctx.to.LookupColumn(columnName)
 		if !ok {
-			return 0, errors.Errorf(`column %s not found in new schema`, columnName)
+			return nil, errors.Errorf(`column %s not found in new schema`, columnName)
 		}
 
-		if reflect.DeepEqual(beforeColumnStmt, afterColumnStmt) {
+		if ctx.columns.equal(ctx.from, beforeColumnStmt, ctx.to, afterColumnStmt) {
 			continue
 		}
-
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		if ctx.columns.comments == CommentSeparate && ctx.columns.commentOnly(ctx.from, beforeColumnStmt, ctx.to, afterColumnStmt) {
+			// generated by alterComments
+			continue
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` CHANGE COLUMN `")
-		buf.WriteString(afterColumnStmt.Name())
-		buf.WriteString("` ")
-		if err := format.SQL(&buf, afterColumnStmt); err != nil {
-			return 0, err
+
+		var buf bytes.Buffer
+		buf.WriteString("ALTER TABLE ")
+		buf.WriteString(util.Backquote(ctx.from.Name()))
+		buf.WriteString(" CHANGE COLUMN ")
+		buf.WriteString(util.Backquote(afterColumnStmt.Name()))
+		buf.WriteString(" ")
+		if err := format.SQL(&buf, afterColumnStmt, ctx.fmtOptions...); err != nil {
+			return nil, err
 		}
 		buf.WriteByte(';')
+		list.addColumnChange(changeColumnModify, ctx.from.Name(), afterColumnStmt.Name(), buf.String(), classifyColumnChange(ctx.columns.normalize(ctx.from, beforeColumnStmt), ctx.columns.normalize(ctx.to, afterColumnStmt)))
 	}
 
-	return buf.WriteTo(dst)
+	return list, nil
 }
 
-func dropTableIndexes(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	indexes := ctx.fromIndexes.Difference(ctx.toIndexes)
-	// drop index after drop constraint.
-	// because cannot drop index if needed in a foreign key constraint
-	lazy := make([]model.Index, 0, indexes.Cardinality())
-	for _, index := range indexes.ToSlice() {
-		indexStmt, ok := ctx.from.LookupIndex(index.(string))
-		if !ok {
-			return 0, errors.Errorf(`index '%s' not found in old schema (drop index)`, index)
+// alterTableOptions sets the storage engine when it has changed and,
+// with WithTableOptions, the other table options that have been added
+// or changed. Options that have been removed are left as is, as there
+// is no general way to reset an option to its default value.
+func alterTableOptions(ctx *alterCtx) (changes, error) {
+	var options []model.TableOption
+	engine := alteredEngine(ctx)
+	if engine != nil {
+		options = append(options, engine)
+	}
+
+	if ctx.tableOptions {
+		if opt := alteredRowFormat(ctx); opt != nil {
+			options = append(options, opt)
 		}
 
-		if indexStmt.IsPrimaryKey() {
-			if buf.Len() > 0 {
-				buf.WriteByte('\n')
+		fromOptions := make(map[string]model.TableOption)
+		for opt := range ctx.from.Options() {
+			fromOptions[opt.Key()] = opt
+		}
+
+		for opt := range ctx.to.Options() {
+			if ctx.ignore.option(opt.Key()) {
+				continue
+			}
+			if ctx.engines.defaultEngine != "" && strings.EqualFold(opt.Key(), "ENGINE") {
+				// compared by alteredEngine
+				continue
+			}
+			if strings.EqualFold(opt.Key(), "ROW_FORMAT") {
+				// compared by alteredRowFormat
+				continue
+			}
+			if strings.EqualFold(opt.Key(), "COMMENT") {
+				if ctx.columns.comments != CommentSeparate && alteredComment(ctx) != nil {
+					options = append(options, opt)
+				}
+				continue
+			}
+			if prev, ok := fromOptions[opt.Key()]; ok && reflect.DeepEqual(prev, opt) {
+				continue
 			}
-			buf.WriteString("ALTER TABLE `")
-			buf.WriteString(ctx.from.Name())
-			buf.WriteString("` DROP PRIMARY KEY;")
+			options = append(options, opt)
+		}
+	}
+
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("ALTER TABLE ")
+	buf.WriteString(util.Backquote(ctx.from.Name()))
+	buf.WriteString(" ")
+	for i, opt := range options {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if err := format.SQL(&buf, opt); err != nil {
+			return nil, err
+		}
+	}
+
+	var list changes
+	buf.WriteByte(';')
+	list.add(changeTableOptions, ctx.from.Name(), "", buf.String(), false)
+	return list, nil
+}
+
+// reorderTableColumns moves the columns that are not in the same
+// position as in the new schema. The columns are compared against the
+// order that the table will have after the columns have been dropped
+// and added, and only the columns that are not part of the longest
+// run of columns that are already in the correct relative order are moved.
+func reorderTableColumns(ctx *alterCtx) (changes, error) {
+	if !ctx.columnOrder {
+		return nil, nil
+	}
+
+	// Compute the order of the columns after DROP COLUMN / ADD COLUMN.
+	// Added columns are always placed after the column preceding them
+	// in the new schema, or at the beginning of the table.
+	var current []string
+	for col := range ctx.from.Columns() {
+		if ctx.toColumns.Contains(col.ID()) {
+			current = append(current, col.ID())
+		}
+	}
+
+	var desired []string
+	for col := range ctx.to.Columns() {
+		if !ctx.toColumns.Contains(col.ID()) {
+			continue
+		}
+		desired = append(desired, col.ID())
+		if ctx.fromColumns.Contains(col.ID()) {
 			continue
 		}
 
-		if !indexStmt.HasName() && !indexStmt.HasSymbol() {
-			return 0, errors.Errorf("can not drop index without name: %s", indexStmt.ID())
+		pos := 0
+		if beforeCol, ok := ctx.to.LookupColumnBefore(col.ID()); ok {
+			for i, id := range current {
+				if id == beforeCol.ID() {
+					pos = i + 1
+					break
+				}
+			}
 		}
-		if !indexStmt.IsForeignKey() {
-			lazy = append(lazy, indexStmt)
+		current = append(current, "")
+		copy(current[pos+1:], current[pos:])
+		current[pos] = col.ID()
+	}
+
+	stable := stableColumns(current, desired)
+
+	var list changes
+	for i, columnName := range desired {
+		if _, ok := stable[columnName]; ok {
 			continue
 		}
 
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		col, ok := ctx.to.LookupColumn(columnName)
+		if !ok {
+			return nil, errors.Errorf(`failed to lookup column %s`, columnName)
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("ALTER TABLE ")
+		buf.WriteString(util.Backquote(ctx.from.Name()))
+		buf.WriteString(" MODIFY COLUMN ")
+		if err := format.SQL(&buf, col, ctx.fmtOptions...); err != nil {
+			return nil, err
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` DROP FOREIGN KEY `")
-		if indexStmt.HasSymbol() {
-			buf.WriteString(indexStmt.Symbol())
+		if i > 0 {
+			beforeCol, ok := ctx.to.LookupColumn(desired[i-1])
+			if !ok {
+				return nil, errors.Errorf(`failed to lookup column %s`, desired[i-1])
+			}
+			buf.WriteString(" AFTER ")
+			buf.WriteString(util.Backquote(beforeCol.Name()))
 		} else {
-			buf.WriteString(indexStmt.Name())
+			buf.WriteString(" FIRST")
+		}
+		buf.WriteByte(';')
+
+		// MODIFY COLUMN also applies the new definition, so it is as
+		// destructive as the CHANGE COLUMN for the same column
+		typeChange := TypeChangeWidening
+		if fromCol, ok := ctx.from.LookupColumn(columnName); ok {
+			typeChange = classifyColumnChange(ctx.columns.normalize(ctx.from, fromCol), ctx.columns.normalize(ctx.to, col))
 		}
-		buf.WriteString("`;")
+		list.addColumnChange(changeColumnMove, ctx.from.Name(), col.Name(), buf.String(), typeChange)
 	}
-	// drop index after drop CONSTRAINT
-	for _, indexStmt := range lazy {
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+
+	return list, nil
+}
+
+// stableColumns returns the set of columns that do not need to be
+// moved to turn `current` into `desired`, which is the longest
+// increasing subsequence of the current positions in desired order
+func stableColumns(current, desired []string) map[string]struct{} {
+	positions := make(map[string]int, len(current))
+	for i, id := range current {
+		positions[id] = i
+	}
+
+	// tails[k] holds the index (in desired) of the smallest tail of
+	// all increasing subsequences of length k+1
+	var tails []int
+	prev := make([]int, len(desired))
+	for i, id := range desired {
+		pos := positions[id]
+		k := sort.Search(len(tails), func(j int) bool {
+			return positions[desired[tails[j]]] >= pos
+		})
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` DROP INDEX `")
-		if !indexStmt.HasName() {
-			buf.WriteString(indexStmt.Symbol())
+		if k == len(tails) {
+			tails = append(tails, i)
 		} else {
-			buf.WriteString(indexStmt.Name())
+			tails[k] = i
 		}
-
-		buf.WriteString("`;")
 	}
 
-	return buf.WriteTo(dst)
+	stable := make(map[string]struct{}, len(tails))
+	if len(tails) == 0 {
+		return stable
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		stable[desired[i]] = struct{}{}
+	}
+	return stable
 }
 
-func addTableIndexes(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	indexes := ctx.toIndexes.Difference(ctx.fromIndexes)
-	// add index before add foreign key.
-	// because cannot add index if create implicitly index by foreign key.
-	lazy := make([]model.Index, 0, indexes.Cardinality())
-	for _, index := range indexes.ToSlice() {
-		indexStmt, ok := ctx.to.LookupIndex(index.(string))
+func dropTableIndexes(ctx *alterCtx) (changes, error) {
+	var list changes
+	// the primary key is dropped first, followed by the other indexes
+	var lazy []model.Index
+	for _, index := range ctx.indexIDs(ctx.from, ctx.fromIndexes, ctx.toIndexes) {
+		indexStmt, ok := ctx.from.LookupIndex(index)
 		if !ok {
-			return 0, errors.Errorf(`index '%s' not found in old schema (add index)`, index)
+			return nil, errors.Errorf(`index '%s' not found in old schema (drop index)`, index)
 		}
-		if indexStmt.IsForeignKey() {
-			lazy = append(lazy, indexStmt)
+
+		if indexStmt.IsPrimaryKey() {
+			list.add(changeIndexDrop, ctx.from.Name(), "PRIMARY", "ALTER TABLE "+util.Backquote(ctx.from.Name())+" DROP PRIMARY KEY;", false)
+			ctx.markReordered(list[len(list)-1], indexStmt, true)
 			continue
 		}
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+
+		// foreign keys are dropped by dropTableForeignKeys
+		if indexStmt.IsForeignKey() {
+			continue
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` ADD ")
-		if err := format.SQL(&buf, indexStmt); err != nil {
-			return 0, err
+
+		if !indexStmt.HasName() && !indexStmt.HasSymbol() {
+			return nil, errors.Errorf("can not drop index without name: %s", indexStmt.ID())
 		}
-		buf.WriteByte(';')
+		lazy = append(lazy, indexStmt)
 	}
-
+	// drop index after drop CONSTRAINT
 	for _, indexStmt := range lazy {
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		name := indexName(indexStmt)
+		list.add(changeIndexDrop, ctx.from.Name(), name, "ALTER TABLE "+util.Backquote(ctx.from.Name())+" DROP INDEX "+util.Backquote(name)+";", false)
+		ctx.markReordered(list[len(list)-1], indexStmt, true)
+	}
+
+	return list, nil
+}
+
+func addTableIndexes(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, index := range ctx.indexIDs(ctx.to, ctx.toIndexes, ctx.fromIndexes) {
+		indexStmt, ok := ctx.to.LookupIndex(index)
+		if !ok {
+			return nil, errors.Errorf(`index '%s' not found in old schema (add index)`, index)
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` ADD ")
-		if err := format.SQL(&buf, indexStmt); err != nil {
-			return 0, err
+		// foreign keys are added by addTableForeignKeys, after the
+		// indexes they may depend on have been created
+		if indexStmt.IsForeignKey() {
+			continue
+		}
+		if err := addIndex(ctx, &list, indexStmt); err != nil {
+			return nil, err
 		}
-		buf.WriteByte(';')
 	}
 
-	return buf.WriteTo(dst)
+	return list, nil
+}
+
+func addIndex(ctx *alterCtx, list *changes, indexStmt model.Index) error {
+	var buf bytes.Buffer
+	buf.WriteString("ALTER TABLE ")
+	buf.WriteString(util.Backquote(ctx.from.Name()))
+	buf.WriteString(" ADD ")
+	if err := format.SQL(&buf, indexStmt); err != nil {
+		return err
+	}
+	buf.WriteByte(';')
+	list.add(changeIndexAdd, ctx.from.Name(), indexName(indexStmt), buf.String(), false)
+	ctx.markReordered((*list)[len(*list)-1], indexStmt, false)
+	return nil
+}
+
+// indexName returns the name used to refer to the index in statements
+func indexName(idx model.Index) string {
+	switch {
+	case idx.IsPrimaryKey():
+		return "PRIMARY"
+	case idx.HasName():
+		return idx.Name()
+	default:
+		return idx.Symbol()
+	}
 }
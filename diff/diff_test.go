@@ -4,15 +4,17 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/eihigh/schemalex"
 	"github.com/eihigh/schemalex/diff"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestDiff(t *testing.T) {
 	type Spec struct {
-		Before string
-		After  string
-		Expect string
+		Before  string
+		After   string
+		Expect  string
+		Options []diff.Option
 	}
 
 	specs := []Spec{
@@ -40,6 +42,452 @@ func TestDiff(t *testing.T) {
 			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, `c` INTEGER NOT NULL );",
 			Expect: "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\nALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `a`;\nALTER TABLE `fuga` ADD COLUMN `c` INT (11) NOT NULL AFTER `b`;",
 		},
+		// column order is not preserved by default
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Expect: "",
+		},
+		// move column (after)
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, `c` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, `c` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` MODIFY COLUMN `a` INT (11) NOT NULL AFTER `c`;",
+			Options: []diff.Option{diff.WithColumnOrder(true)},
+		},
+		// move column (first)
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `b` INTEGER NOT NULL, `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` MODIFY COLUMN `b` INT (11) NOT NULL FIRST;",
+			Options: []diff.Option{diff.WithColumnOrder(true)},
+		},
+		// move column along with added and dropped columns
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, `c` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, `d` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` DROP COLUMN `b`;\nALTER TABLE `fuga` ADD COLUMN `d` INT (11) NOT NULL AFTER `c`;\nALTER TABLE `fuga` MODIFY COLUMN `a` INT (11) NOT NULL AFTER `d`;",
+			Options: []diff.Option{diff.WithColumnOrder(true)},
+		},
+		// moving a column with a changed definition
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, `a` BIGINT NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) NOT NULL;\nALTER TABLE `fuga` MODIFY COLUMN `b` INT (11) NOT NULL AFTER `id`;",
+			Options: []diff.Option{diff.WithColumnOrder(true)},
+		},
+		// destructive statements are skipped
+		{
+			Before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` VARCHAR (20) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` VARCHAR (10), `c` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `c` INT (11) NOT NULL AFTER `b`;",
+			Options: []diff.Option{diff.WithDestructive(diff.DestructiveSkip)},
+		},
+		// widening changes are not destructive
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` VARCHAR (40) );",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `b` `b` VARCHAR (40) DEFAULT NULL;",
+			Options: []diff.Option{diff.WithDestructive(diff.DestructiveRefuse)},
+		},
+		// histograms are ignored by default
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `id`;",
+			Expect: "",
+		},
+		// new histogram
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `id` WITH 16 BUCKETS;",
+			Expect:  "ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `id` WITH 16 BUCKETS;",
+			Options: []diff.Option{diff.WithHistograms(true)},
+		},
+		// histogram is updated after its column changes
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `id`, `a`;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BIGINT ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `id`, `a`;",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL;\n\nANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `a`;",
+			Options: []diff.Option{diff.WithHistograms(true)},
+		},
+		// histograms for new tables and columns
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `a`; ANALYZE TABLE `hoge` UPDATE HISTOGRAM ON `id`;",
+			Expect:  "CREATE TABLE `hoge` (\n`id` INT (11) NOT NULL\n);\n\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;\n\nANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `a`;\nANALYZE TABLE `hoge` UPDATE HISTOGRAM ON `id`;",
+			Options: []diff.Option{diff.WithHistograms(true)},
+		},
+		// histogram is dropped, unless its column is dropped
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `a`, `b` WITH 8 BUCKETS;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER );",
+			Expect:  "ALTER TABLE `fuga` DROP COLUMN `b`;\n\nANALYZE TABLE `fuga` DROP HISTOGRAM ON `a`;",
+			Options: []diff.Option{diff.WithHistograms(true)},
+		},
+		// referenced tables are created first, and dropped last
+		{
+			Before: "",
+			After:  "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) ); CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			Expect: "CREATE TABLE `a` (\n`id` INT (11) NOT NULL\n);\nCREATE TABLE `b` (\n`id` INT (11) NOT NULL,\n`aid` INT (11) NOT NULL,\nINDEX `b_fk` (`aid`),\nCONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`)\n);",
+		},
+		{
+			Before: "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) ); CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			After:  "",
+			Expect: "DROP TABLE `b`;\nDROP TABLE `a`;",
+		},
+		// foreign keys that form a cycle are added after the tables are created
+		{
+			Before: "",
+			After:  "CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) );",
+			Expect: "CREATE TABLE `a` (\n`id` INT (11) NOT NULL,\n`bid` INT (11) NOT NULL,\nINDEX `a_fk` (`bid`)\n);\nCREATE TABLE `b` (\n`id` INT (11) NOT NULL,\n`aid` INT (11) NOT NULL,\nINDEX `b_fk` (`aid`),\nCONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`)\n);\n\nALTER TABLE `a` ADD CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`);",
+		},
+		// ... and dropped before the tables are dropped
+		{
+			Before: "CREATE TABLE `a` ( `id` INTEGER NOT NULL, `bid` INTEGER NOT NULL, CONSTRAINT `a_fk` FOREIGN KEY (`bid`) REFERENCES `b` (`id`) ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `aid` INTEGER NOT NULL, CONSTRAINT `b_fk` FOREIGN KEY (`aid`) REFERENCES `a` (`id`) );",
+			After:  "",
+			Expect: "ALTER TABLE `a` DROP FOREIGN KEY `a_fk`;\n\nDROP TABLE `b`;\nDROP TABLE `a`;",
+		},
+		// foreign keys referencing new columns are added after the columns
+		{
+			Before: "CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `a` ( `id` INTEGER NOT NULL, `code` INTEGER NOT NULL ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `acode` INTEGER NOT NULL, CONSTRAINT `b_fk` FOREIGN KEY (`acode`) REFERENCES `a` (`code`) );",
+			Expect: "CREATE TABLE `b` (\n`id` INT (11) NOT NULL,\n`acode` INT (11) NOT NULL,\nINDEX `b_fk` (`acode`)\n);\n\nALTER TABLE `a` ADD COLUMN `code` INT (11) NOT NULL AFTER `id`;\n\nALTER TABLE `b` ADD CONSTRAINT `b_fk` FOREIGN KEY (`acode`) REFERENCES `a` (`code`);",
+		},
+		// foreign key checks
+		{
+			Before:  "",
+			After:   "CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			Expect:  "SET FOREIGN_KEY_CHECKS = 0;\n\nCREATE TABLE `a` (\n`id` INT (11) NOT NULL\n);\n\nSET FOREIGN_KEY_CHECKS = 1;",
+			Options: []diff.Option{diff.WithDisableForeignKeyChecks(true)},
+		},
+		{
+			Before:  "",
+			After:   "CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			Expect:  "\nBEGIN;\n\nCREATE TABLE `a` (\n`id` INT (11) NOT NULL\n);\n\nCOMMIT;",
+			Options: []diff.Option{diff.WithTransaction(true), diff.WithDisableForeignKeyChecks(false)},
+		},
+		// START TRANSACTION, delimiter, and single line output
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER );",
+			Expect:  "START TRANSACTION;\nSET FOREIGN_KEY_CHECKS = 0;\nDROP TABLE `fuga`;\nCREATE TABLE `hoge` ( `id` INT (11) NOT NULL, `a` INT (11) DEFAULT NULL );\nSET FOREIGN_KEY_CHECKS = 1;\nCOMMIT;",
+			Options: []diff.Option{diff.WithStartTransaction(true), diff.WithSingleLine(true)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			Expect:  "\nBEGIN\nGO\n\nSET FOREIGN_KEY_CHECKS = 0\nGO\n\nCREATE TABLE `hoge` (\n`id` INT (11) NOT NULL\n)\nGO\n\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`\nGO\n\nSET FOREIGN_KEY_CHECKS = 1\nGO\n\nCOMMIT\nGO",
+			Options: []diff.Option{diff.WithTransaction(true), diff.WithDelimiter("\nGO")},
+		},
+		// ignored tables
+		{
+			Before:  "CREATE TABLE `tmp_1` ( `id` INTEGER NOT NULL ); CREATE TABLE `log_archive` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `tmp_2` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Expect:  "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);",
+			Options: []diff.Option{diff.WithIgnoreTables("tmp_*", "%_archive")},
+		},
+		{
+			Before:  "CREATE TABLE `tmp_1` ( `id` INTEGER NOT NULL ); CREATE TABLE `tmp_x` ( `id` INTEGER NOT NULL );",
+			After:   "",
+			Expect:  "DROP TABLE `tmp_x`;",
+			Options: []diff.Option{diff.WithIgnoreTables("/^TMP_[0-9]+$/")},
+		},
+		// only some tables
+		{
+			Before:  "CREATE TABLE `users` ( `id` INTEGER NOT NULL ); CREATE TABLE `user_logs` ( `id` INTEGER NOT NULL ); CREATE TABLE `posts` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `users` ( `id` INTEGER NOT NULL, `name` TEXT ); CREATE TABLE `user_tmp` ( `id` INTEGER NOT NULL ); CREATE TABLE `items` ( `id` INTEGER NOT NULL );",
+			Expect:  "DROP TABLE `user_logs`;\n\nALTER TABLE `users` ADD COLUMN `name` TEXT AFTER `id`;",
+			Options: []diff.Option{diff.WithOnlyTables("user*"), diff.WithIgnoreTables("*_tmp")},
+		},
+		// ignored columns
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `updated_at` DATETIME, `a` INTEGER ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `updated_at` TIMESTAMP ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` DROP COLUMN `a`;",
+			Options: []diff.Option{diff.WithIgnoreColumns("updated_at", "hoge.a")},
+		},
+		// table options
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB, AUTO_INCREMENT = 10;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB, AUTO_INCREMENT = 20, COMMENT = 'fuga';",
+			Expect:  "ALTER TABLE `fuga` AUTO_INCREMENT = 20, COMMENT = 'fuga';",
+			Options: []diff.Option{diff.WithTableOptions(true)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB, AUTO_INCREMENT = 10;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM, AUTO_INCREMENT = 20, COMMENT = 'fuga';",
+			Expect:  "ALTER TABLE `fuga` ENGINE = MyISAM;",
+			Options: []diff.Option{diff.WithTableOptions(true), diff.WithIgnoreTableOptions("auto_increment", "COMMENT")},
+		},
+		// TEMPORARY tables
+		{
+			Before: "CREATE TEMPORARY TABLE `tmp` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TEMPORARY TABLE `tmp` ( `id` INTEGER NOT NULL, `a` INTEGER ); CREATE TEMPORARY TABLE `tmp2` ( `id` INTEGER NOT NULL );",
+			Expect: "CREATE TEMPORARY TABLE `tmp2` (\n`id` INT (11) NOT NULL\n);\n\nALTER TABLE `tmp` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;",
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TEMPORARY TABLE `tmp` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER ); CREATE TEMPORARY TABLE `tmp2` ( `id` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;",
+			Options: []diff.Option{diff.WithTemporaryTables(false)},
+		},
+		// IF NOT EXISTS
+		{
+			Before: "",
+			After:  "CREATE TABLE IF NOT EXISTS `fuga` ( `id` INTEGER NOT NULL );",
+			Expect: "CREATE TABLE IF NOT EXISTS `fuga` (\n`id` INT (11) NOT NULL\n);",
+		},
+		{
+			Before:  "",
+			After:   "CREATE TABLE IF NOT EXISTS `fuga` ( `id` INTEGER NOT NULL );",
+			Expect:  "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);",
+			Options: []diff.Option{diff.WithIfNotExists(false)},
+		},
+		{
+			Before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );",
+			After:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, CONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `hoge` (`id`) ); CREATE TABLE `piyo` LIKE `hoge`;",
+			Expect:  "CREATE TABLE IF NOT EXISTS `fuga` (\n`id` INT (11) NOT NULL,\nINDEX `fk` (`id`),\nCONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `hoge` (`id`)\n);\nCREATE TABLE IF NOT EXISTS `piyo` LIKE `hoge`;",
+			Options: []diff.Option{diff.WithIfNotExists(true)},
+		},
+		// comments
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'id', `a` INTEGER COMMENT 'a' ) COMMENT = 'fuga';",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'the id', `a` BIGINT COMMENT 'the a' ) COMMENT = 'the fuga';",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL COMMENT 'the a';",
+			Options: []diff.Option{diff.WithTableOptions(true), diff.WithComments(diff.CommentIgnore)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'id', `a` INTEGER COMMENT 'a' ) COMMENT = 'fuga';",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'the id', `a` BIGINT COMMENT 'the a', `b` INTEGER ) COMMENT = 'the fuga';",
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `b` INT (11) DEFAULT NULL AFTER `a`;\nALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL COMMENT 'the a';\n\nALTER TABLE `fuga` CHANGE COLUMN `id` `id` INT (11) NOT NULL COMMENT 'the id';\nALTER TABLE `fuga` COMMENT = 'the fuga';",
+			Options: []diff.Option{diff.WithTableOptions(true), diff.WithComments(diff.CommentSeparate)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'id ', `a` INTEGER COMMENT '' ) COMMENT = ' fuga';",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'id', `a` INTEGER ) COMMENT = 'fuga';",
+			Expect:  "",
+			Options: []diff.Option{diff.WithTableOptions(true), diff.WithTrimComments(true)},
+		},
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'id ' );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'id' );",
+			Expect: "ALTER TABLE `fuga` CHANGE COLUMN `id` `id` INT (11) NOT NULL COMMENT 'id';",
+		},
+		// DEFAULT is the default row format of the engine
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DEFAULT;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = dynamic;",
+			Expect:  "",
+			Options: []diff.Option{diff.WithTableOptions(true)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DYNAMIC;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Expect:  "",
+			Options: []diff.Option{diff.WithTableOptions(true)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DEFAULT;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DYNAMIC;",
+			Expect:  "ALTER TABLE `fuga` ROW_FORMAT = DYNAMIC;",
+			Options: []diff.Option{diff.WithTableOptions(true), diff.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 6, Patch: 51})},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = COMPRESSED;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Expect:  "ALTER TABLE `fuga` ROW_FORMAT = DEFAULT;",
+			Options: []diff.Option{diff.WithTableOptions(true)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = COMPRESSED;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DEFAULT;",
+			Expect:  "ALTER TABLE `fuga` ROW_FORMAT = DYNAMIC;",
+			Options: []diff.Option{diff.WithTableOptions(true), diff.WithExplicitRowFormat(true)},
+		},
+		// the storage engine is compared even without WithTableOptions
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM;",
+			Expect: "ALTER TABLE `fuga` ENGINE = MyISAM;",
+		},
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Expect: "ALTER TABLE `fuga` ENGINE = InnoDB;",
+		},
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = innodb;",
+			Expect: "",
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM;",
+			Expect:  "",
+			Options: []diff.Option{diff.WithIgnoreTableOptions("engine")},
+		},
+		// quoted and unquoted identifiers are equal
+		{
+			Before: "CREATE TABLE fuga ( id INTEGER NOT NULL, KEY idx (id), CONSTRAINT fk FOREIGN KEY (id) REFERENCES hoge (id) );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, KEY `idx` (`id`), CONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `hoge` (`id`) );",
+			Expect: "",
+		},
+		// case insensitive names
+		{
+			Before:  "CREATE TABLE `Fuga` ( `Id` INTEGER NOT NULL, `A` INTEGER, KEY `Idx` (`Id`), CONSTRAINT `Fk` FOREIGN KEY (`A`) REFERENCES `Hoge` (`ID`) ); CREATE TABLE `Hoge` ( `ID` INTEGER NOT NULL ); ANALYZE TABLE `Fuga` UPDATE HISTOGRAM ON `A`;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BIGINT, KEY `idx` (`id`), CONSTRAINT `fk` FOREIGN KEY (`a`) REFERENCES `hoge` (`id`) ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); ANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `a`;",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL;\n\nANALYZE TABLE `fuga` UPDATE HISTOGRAM ON `a`;",
+			Options: []diff.Option{diff.WithCaseInsensitiveNames(true), diff.WithHistograms(true)},
+		},
+		// inherited character sets and collations
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` VARCHAR (10) ) DEFAULT CHARSET = utf8mb4;",
+			After:   "CREATE TABLE `fuga` ( `a` VARCHAR (10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci ) DEFAULT CHARSET = utf8mb4;",
+			Expect:  "",
+			Options: []diff.Option{diff.WithServerCharset("latin1", "")},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` VARCHAR (10) );",
+			After:   "CREATE TABLE `fuga` ( `a` VARCHAR (10) COLLATE utf8mb4_0900_ai_ci );",
+			Expect:  "",
+			Options: []diff.Option{diff.WithServerCharset(diff.DefaultServerCharset("8.0"))},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` VARCHAR (10) );",
+			After:   "CREATE TABLE `fuga` ( `a` VARCHAR (10) CHARACTER SET utf8mb4 );",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` VARCHAR (10) CHARACTER SET `utf8mb4` DEFAULT NULL;",
+			Options: []diff.Option{diff.WithServerCharset("latin1", "")},
+		},
+		// display widths
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` INT (10), `b` INT (5) ZEROFILL );",
+			After:   "CREATE TABLE `fuga` ( `a` INT, `b` INT ZEROFILL );",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `b` `b` INT (11) ZEROFILL DEFAULT NULL;",
+			Options: []diff.Option{diff.WithIgnoreDisplayWidth(true)},
+		},
+		// synonyms of CURRENT_TIMESTAMP
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` DATETIME NOT NULL DEFAULT NOW() ON UPDATE LOCALTIMESTAMP, `b` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP(0), `c` DATETIME DEFAULT NOW() );",
+			After:   "CREATE TABLE `fuga` ( `a` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, `b` DATETIME NOT NULL DEFAULT NOW(), `c` DATETIME DEFAULT '2000-01-01 00:00:00' );",
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `c` `c` DATETIME DEFAULT '2000-01-01 00:00:00';",
+			Options: []diff.Option{diff.WithNormalizeCurrentTimestamp(true)},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INT NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INT NOT NULL, `a` BIGINT UNSIGNED, `b` TINYINT (1), `c` DECIMAL (10,2) );",
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `a` BIGINT UNSIGNED DEFAULT NULL AFTER `id`;\nALTER TABLE `fuga` ADD COLUMN `b` TINYINT (1) DEFAULT NULL AFTER `a`;\nALTER TABLE `fuga` ADD COLUMN `c` DECIMAL (10,2) DEFAULT NULL AFTER `b`;",
+			Options: []diff.Option{diff.WithDisplayWidth(false)},
+		},
+		// renamed indexes
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, KEY `old_a` (`a`), UNIQUE KEY `old_b` (`b`), KEY `c` (`a`, `b`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, KEY `new_a` (`a`), UNIQUE KEY `new_b` (`b`), KEY `c` (`b`, `a`) );",
+			Expect:  "-- index `c` of table `fuga` is rebuilt because the order of its columns changed from (`a`, `b`) to (`b`, `a`)\nALTER TABLE `fuga` DROP INDEX `c`;\nALTER TABLE `fuga` RENAME INDEX `old_a` TO `new_a`;\nALTER TABLE `fuga` RENAME INDEX `old_b` TO `new_b`;\nALTER TABLE `fuga` ADD INDEX `c` (`b`, `a`);",
+			Options: []diff.Option{diff.WithRenameIndexes(true)},
+		},
+		// reordered index columns
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, PRIMARY KEY (`id`, `a`), UNIQUE KEY `ab` (`a`, `b` DESC) );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, PRIMARY KEY (`a`, `id`), UNIQUE KEY `ab` (`b` DESC, `a`) );",
+			Expect: "-- index `PRIMARY` of table `fuga` is rebuilt because the order of its columns changed from (`id`, `a`) to (`a`, `id`)\nALTER TABLE `fuga` DROP PRIMARY KEY;\n-- index `ab` of table `fuga` is rebuilt because the order of its columns changed from (`a`, `b`) to (`b`, `a`)\nALTER TABLE `fuga` DROP INDEX `ab`;\nALTER TABLE `fuga` ADD PRIMARY KEY (`a`, `id`);\nALTER TABLE `fuga` ADD UNIQUE INDEX `ab` (`b` DESC, `a`);",
+		},
+		// same name with other columns
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, KEY `ab` (`a`, `b`) );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, `b` INTEGER, UNIQUE KEY `ab` (`b`, `a`) );",
+			Expect: "ALTER TABLE `fuga` DROP INDEX `ab`;\nALTER TABLE `fuga` ADD UNIQUE INDEX `ab` (`b`, `a`);",
+		},
+		// partitions added after the last one
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY RANGE (TO_DAYS(`created`)) ( PARTITION p1 VALUES LESS THAN (10), PARTITION p2 VALUES LESS THAN (20) );",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY RANGE (TO_DAYS(`created`)) ( PARTITION p1 VALUES LESS THAN (10), PARTITION p2 VALUES LESS THAN (20), PARTITION p3 VALUES LESS THAN (30) );",
+			Expect: "ALTER TABLE `log` ADD PARTITION (PARTITION `p3` VALUES LESS THAN (30));",
+		},
+		// partitions added before MAXVALUE and dropped
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY RANGE (TO_DAYS(`created`)) ( PARTITION p1 VALUES LESS THAN (10), PARTITION p2 VALUES LESS THAN (20), PARTITION pmax VALUES LESS THAN MAXVALUE );",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY RANGE (TO_DAYS(`created`)) ( PARTITION p2 VALUES LESS THAN (20), PARTITION p3 VALUES LESS THAN (30), PARTITION pmax VALUES LESS THAN MAXVALUE );",
+			Expect: "ALTER TABLE `log` DROP PARTITION `p1`;\nALTER TABLE `log` REORGANIZE PARTITION `pmax` INTO (PARTITION `p3` VALUES LESS THAN (30), PARTITION `pmax` VALUES LESS THAN MAXVALUE);",
+		},
+		// partitions changed
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY LIST (`id`) ( PARTITION p1 VALUES IN (1, 2), PARTITION p2 VALUES IN (3), PARTITION p3 VALUES IN (4) );",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY LIST (`id`) ( PARTITION p1 VALUES IN (1, 2), PARTITION p2 VALUES IN (3, 4), PARTITION p5 VALUES IN (5) );",
+			Expect: "ALTER TABLE `log` REORGANIZE PARTITION `p2`, `p3` INTO (PARTITION `p2` VALUES IN (3, 4), PARTITION `p5` VALUES IN (5));",
+		},
+		// partitioning changed
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 4;",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY KEY (`id`) PARTITIONS 4;",
+			Expect: "ALTER TABLE `log` PARTITION BY KEY (`id`) PARTITIONS 4;",
+		},
+		// number of HASH partitions changed
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 4;",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 6;",
+			Expect: "ALTER TABLE `log` ADD PARTITION PARTITIONS 2;",
+		},
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 4;",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`);",
+			Expect: "ALTER TABLE `log` COALESCE PARTITION 3;",
+		},
+		// partitioning added and removed
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL );",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 4;",
+			Expect: "ALTER TABLE `log` PARTITION BY HASH (`id`) PARTITIONS 4;",
+		},
+		{
+			Before: "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 4;",
+			After:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL );",
+			Expect: "ALTER TABLE `log` REMOVE PARTITIONING;",
+		},
+		// foreign keys with generated symbols
+		{
+			Before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `a` (`a`), CONSTRAINT `fuga_ibfk_1` FOREIGN KEY (`a`) REFERENCES `hoge` (`id`) );",
+			After:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `a` (`a`), FOREIGN KEY (`a`) REFERENCES `hoge` (`id`) );",
+			Expect:  "",
+			Options: []diff.Option{diff.WithIgnoreGeneratedSymbols(true)},
+		},
+		{
+			Before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `a` (`a`), CONSTRAINT `fuga_ibfk_1` FOREIGN KEY (`a`) REFERENCES `hoge` (`id`) );",
+			After:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `a` (`a`), CONSTRAINT `fk_hoge` FOREIGN KEY (`a`) REFERENCES `hoge` (`id`) ON DELETE CASCADE );",
+			Expect:  "ALTER TABLE `fuga` DROP FOREIGN KEY `fuga_ibfk_1`;\nALTER TABLE `fuga` ADD INDEX `fk_hoge` (`a`);\nALTER TABLE `fuga` ADD CONSTRAINT `fk_hoge` FOREIGN KEY (`a`) REFERENCES `hoge` (`id`) ON DELETE CASCADE;",
+			Options: []diff.Option{diff.WithIgnoreGeneratedSymbols(true)},
+		},
+		// MariaDB dumps
+		{
+			Before:  "CREATE TABLE `t` ( `id` uuid NOT NULL, `doc` longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL CHECK (json_valid(`doc`)), PRIMARY KEY (`id`) ) ENGINE=Aria PAGE_CHECKSUM=1;",
+			After:   "CREATE TABLE `t` ( `id` UUID NOT NULL, `doc` JSON, `addr` INET6, PRIMARY KEY (`id`) ) ENGINE=Aria PAGE_CHECKSUM=1;",
+			Expect:  "ALTER TABLE `t` ADD COLUMN `addr` INET6 DEFAULT NULL AFTER `doc`;",
+			Options: []diff.Option{diff.WithDialect(schemalex.DialectMariaDB)},
+		},
+		// TiDB dumps
+		{
+			Before:  "CREATE TABLE `t` ( `id` bigint(20) NOT NULL /*T![auto_rand] AUTO_RANDOM(5) */, PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */ );",
+			After:   "CREATE TABLE `t` ( `id` BIGINT (20) NOT NULL AUTO_RANDOM(5), `a` INT, PRIMARY KEY (`id`) );",
+			Expect:  "ALTER TABLE `t` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;",
+			Options: []diff.Option{diff.WithDialect(schemalex.DialectTiDB)},
+		},
+		// target versions
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `old_a` (`a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `new_a` (`a`) );",
+			Expect:  "ALTER TABLE `fuga` DROP INDEX `old_a`;\nALTER TABLE `fuga` ADD INDEX `new_a` (`a`);",
+			Options: []diff.Option{diff.WithRenameIndexes(true), diff.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 6})},
+		},
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` INT (10), `b` VARCHAR (10) CHARACTER SET utf8mb4 );",
+			After:   "CREATE TABLE `fuga` ( `a` INT, `b` VARCHAR (10) );",
+			Expect:  "",
+			Options: []diff.Option{diff.WithMySQLVersion(schemalex.MySQLVersion{Major: 8, Minor: 0, Patch: 19})},
+		},
+		{
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `old_a` (`a`) );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `new_a` (`a`) );",
+			Expect: "ALTER TABLE `fuga` DROP INDEX `old_a`;\nALTER TABLE `fuga` ADD INDEX `new_a` (`a`);",
+		},
 		// add column (first)
 		{
 			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
@@ -111,6 +559,13 @@ func TestDiff(t *testing.T) {
 			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `fid` INTEGER NOT NULL, INDEX fid (fid) );",
 			Expect: "ALTER TABLE `fuga` DROP FOREIGN KEY `fk`;\nALTER TABLE `fuga` ADD INDEX `fid` (`fid`);",
 		},
+		// foreign keys in blocks of their own
+		{
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `fid` INTEGER NOT NULL, CONSTRAINT `fsym` FOREIGN KEY (fid) REFERENCES f (id) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `fid` INTEGER NOT NULL, CONSTRAINT `ksym` FOREIGN KEY (fid) REFERENCES f (id) );",
+			Expect:  "ALTER TABLE `fuga` DROP FOREIGN KEY `fsym`;\n\nALTER TABLE `fuga` DROP INDEX `fsym`;\nALTER TABLE `fuga` ADD INDEX `ksym` (`fid`);\n\nALTER TABLE `fuga` ADD CONSTRAINT `ksym` FOREIGN KEY (`fid`) REFERENCES `f` (`id`);",
+			Options: []diff.Option{diff.WithSeparateForeignKeys(true)},
+		},
 		// multi modify
 		{
 			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `aid` INTEGER NOT NULL, `bid` INTEGER NOT NULL, INDEX `ab` (`aid`, `bid`) );",
@@ -192,12 +647,133 @@ CREATE TABLE foo (
 			`,
 			Expect: "",
 		},
+		// statements follow the order of the schemas
+		{
+			Before: "CREATE TABLE `b` (`id` INT, `z` INT, `y` INT, KEY `kz` (`z`), KEY `ky` (`y`)); CREATE TABLE `a` (`id` INT);",
+			After:  "CREATE TABLE `b` (`id` INT, `z` BIGINT, `y` BIGINT); CREATE TABLE `d` (`id` INT); CREATE TABLE `c` (`id` INT); CREATE TABLE `a` (`id` INT, `x` INT);",
+			Expect: "CREATE TABLE `d` (\n`id` INT (11) DEFAULT NULL\n);\nCREATE TABLE `c` (\n`id` INT (11) DEFAULT NULL\n);\n\nALTER TABLE `b` DROP INDEX `kz`;\nALTER TABLE `b` DROP INDEX `ky`;\nALTER TABLE `b` CHANGE COLUMN `z` `z` BIGINT (20) DEFAULT NULL;\nALTER TABLE `b` CHANGE COLUMN `y` `y` BIGINT (20) DEFAULT NULL;\nALTER TABLE `a` ADD COLUMN `x` INT (11) DEFAULT NULL AFTER `id`;",
+		},
+		// or the order of the names
+		{
+			Before:  "CREATE TABLE `b` (`id` INT, `z` INT, `y` INT, KEY `kz` (`z`), KEY `ky` (`y`)); CREATE TABLE `a` (`id` INT);",
+			After:   "CREATE TABLE `b` (`id` INT, `z` BIGINT, `y` BIGINT); CREATE TABLE `d` (`id` INT); CREATE TABLE `c` (`id` INT); CREATE TABLE `a` (`id` INT, `x` INT);",
+			Expect:  "CREATE TABLE `c` (\n`id` INT (11) DEFAULT NULL\n);\nCREATE TABLE `d` (\n`id` INT (11) DEFAULT NULL\n);\n\nALTER TABLE `a` ADD COLUMN `x` INT (11) DEFAULT NULL AFTER `id`;\nALTER TABLE `b` DROP INDEX `ky`;\nALTER TABLE `b` DROP INDEX `kz`;\nALTER TABLE `b` CHANGE COLUMN `y` `y` BIGINT (20) DEFAULT NULL;\nALTER TABLE `b` CHANGE COLUMN `z` `z` BIGINT (20) DEFAULT NULL;",
+			Options: []diff.Option{diff.WithSortByName(true)},
+		},
+		// synonyms of CURRENT_TIMESTAMP with a precision
+		{
+			Before:  "CREATE TABLE `fuga` ( `a` DATETIME(3) NOT NULL DEFAULT NOW(3) ON UPDATE LOCALTIME(3), `b` DATETIME DEFAULT LOCALTIMESTAMP );",
+			After:   "CREATE TABLE `fuga` ( `a` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3), `b` DATETIME DEFAULT current_timestamp() );",
+			Expect:  "",
+			Options: []diff.Option{diff.WithNormalizeCurrentTimestamp(true)},
+		},
+		// the parser of a full-text index can not be altered
+		{
+			Before: "CREATE TABLE `a` (`id` INT, `body` TEXT, FULLTEXT KEY `ft` (`body`));",
+			After:  "CREATE TABLE `a` (`id` INT, `body` TEXT, FULLTEXT KEY `ft` (`body`) WITH PARSER ngram);",
+			Expect: "ALTER TABLE `a` DROP INDEX `ft`;\nALTER TABLE `a` ADD FULLTEXT INDEX `ft` (`body`) WITH PARSER ngram;",
+		},
+		// a primary key declared by a column is the same as one
+		// declared by the table, whose columns are NOT NULL, and whose
+		// name is always PRIMARY
+		{
+			Before: "CREATE TABLE `a` (`id` INT PRIMARY KEY, `b` INT, KEY `kb` (`b`));",
+			After:  "CREATE TABLE `a` (`id` INT NOT NULL, `b` INT, KEY `kb` (`b`), CONSTRAINT `pk` PRIMARY KEY (`id`));",
+			Expect: "",
+		},
+		{
+			Before: "CREATE TABLE `a` (`id` INT KEY, `b` INT);",
+			After:  "CREATE TABLE `a` (`id` INT, `b` INT, PRIMARY KEY (`id`));",
+			Expect: "",
+		},
+		{
+			Before: "CREATE TABLE `a` (`id` INT PRIMARY KEY, `b` INT);",
+			After:  "CREATE TABLE `a` (`id` INT, `b` INT, PRIMARY KEY (`id`, `b`));",
+			Expect: "ALTER TABLE `a` DROP PRIMARY KEY;\nALTER TABLE `a` CHANGE COLUMN `b` `b` INT (11) NOT NULL;\nALTER TABLE `a` ADD PRIMARY KEY (`id`, `b`);",
+		},
+		// a unique column is the same as a unique index, which MySQL
+		// names after its first column, or after its symbol
+		{
+			Before: "CREATE TABLE `a` (`id` INT, `email` VARCHAR(255) UNIQUE, `name` VARCHAR(255) UNIQUE);",
+			After:  "CREATE TABLE `a` (`id` INT, `email` VARCHAR(255), `name` VARCHAR(255), UNIQUE KEY (`email`), CONSTRAINT `name` UNIQUE (`name`));",
+			Expect: "",
+		},
+		{
+			Before: "CREATE TABLE `a` (`id` INT, `email` VARCHAR(255) UNIQUE, KEY (`email`, `id`));",
+			After:  "CREATE TABLE `a` (`id` INT, `email` VARCHAR(255), UNIQUE KEY (`email`), KEY `email_2` (`email`, `id`));",
+			Expect: "",
+		},
+		{
+			Before: "CREATE TABLE `a` (`id` INT, `email` VARCHAR(255) UNIQUE);",
+			After:  "CREATE TABLE `a` (`id` INT, `email` VARCHAR(255), UNIQUE KEY (`id`), UNIQUE KEY (`email`));",
+			Expect: "ALTER TABLE `a` ADD UNIQUE INDEX `id` (`id`);",
+		},
+		// backticks within identifiers are escaped by doubling them
+		{
+			Before: "CREATE TABLE `we``ird` (`id` INT, `a``b` INT, `c\\` INT, KEY `k``1` (`id`)); CREATE TABLE `drop``me` (`id` INT);",
+			After:  "CREATE TABLE `we``ird` (`id` INT, `x``y` INT, `c\\` BIGINT);",
+			Expect: "DROP TABLE `drop``me`;\n\nALTER TABLE `we``ird` DROP INDEX `k``1`;\nALTER TABLE `we``ird` DROP COLUMN `a``b`;\nALTER TABLE `we``ird` ADD COLUMN `x``y` INT (11) DEFAULT NULL AFTER `id`;\nALTER TABLE `we``ird` CHANGE COLUMN `c\\` `c\\` BIGINT (20) DEFAULT NULL;",
+		},
+		// spatial reference systems
+		{
+			Before: "CREATE TABLE `geo` (`id` INT, `g` POINT NOT NULL);",
+			After:  "CREATE TABLE `geo` (`id` INT, `g` POINT NOT NULL SRID 4326);",
+			Expect: "ALTER TABLE `geo` CHANGE COLUMN `g` `g` POINT NOT NULL SRID 4326;",
+		},
+		{
+			Before: "CREATE TABLE `geo` (`id` INT, `g` POINT NOT NULL SRID 4326);",
+			After:  "CREATE TABLE `geo` (`id` INT, `g` POINT NOT NULL SRID 4326);",
+			Expect: "",
+		},
+		// views
+		{
+			Before: "CREATE TABLE `t` ( `id` INT ); CREATE VIEW `v` AS SELECT id FROM t;",
+			After:  "CREATE TABLE `t` ( `id` INT ); create view v as select `ID`   from `T` /* same */;",
+			Expect: "",
+		},
+		{
+			Before: "CREATE TABLE `t` ( `id` INT ); CREATE VIEW `v` AS SELECT id FROM t;",
+			After:  "CREATE TABLE `t` ( `id` INT, `a` INT ); CREATE VIEW `v` AS SELECT id, a FROM t;",
+			Expect: "ALTER TABLE `t` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;\n\nCREATE OR REPLACE VIEW `v` AS SELECT id, a FROM t;",
+		},
+		{
+			Before: "CREATE TABLE `t` ( `id` INT ); CREATE VIEW `w` AS SELECT id FROM v; CREATE VIEW `v` AS SELECT id FROM t;",
+			After:  "",
+			Expect: "DROP VIEW `w`;\nDROP VIEW `v`;\n\nDROP TABLE `t`;",
+		},
+		{
+			Before: "",
+			After:  "CREATE TABLE `t` ( `id` INT ); CREATE VIEW `w` AS SELECT id FROM v; CREATE VIEW `v` AS SELECT id FROM t WITH CHECK OPTION;",
+			Expect: "CREATE TABLE `t` (\n`id` INT (11) DEFAULT NULL\n);\n\nCREATE OR REPLACE VIEW `v` AS SELECT id FROM t WITH CASCADED CHECK OPTION;\nCREATE OR REPLACE VIEW `w` AS SELECT id FROM v;",
+		},
+		// triggers and stored routines
+		{
+			Before: "CREATE TABLE `t` ( `id` INT ); CREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW SET NEW.id = 1; CREATE FUNCTION f () RETURNS INT RETURN 1;",
+			After:  "CREATE TABLE `t` ( `id` INT ); create trigger `trg` before insert on `T` for each row set new.ID = 1 /* same */; CREATE FUNCTION f () RETURNS INT RETURN 1;",
+			Expect: "",
+		},
+		{
+			Before: "CREATE TABLE `t` ( `id` INT ); CREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW SET NEW.id = 1; CREATE PROCEDURE p () SELECT 1;",
+			After:  "CREATE TABLE `t` ( `id` INT ); DELIMITER $$\nCREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.id = 2; END$$\nDELIMITER ;\nCREATE FUNCTION f () RETURNS INT RETURN 1;",
+			Expect: "DROP PROCEDURE `p`;\n\nDELIMITER $$\nCREATE FUNCTION `f` () RETURNS INT RETURN 1$$\nDROP TRIGGER `trg`$$\nCREATE TRIGGER `trg` BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.id = 2; END$$\nDELIMITER ;",
+		},
+		{
+			Before:  "CREATE TABLE `t` ( `id` INT );",
+			After:   "CREATE TABLE `t` ( `id` INT ); CREATE PROCEDURE p () SELECT '$$';",
+			Expect:  "CREATE PROCEDURE `p` () SELECT '$$'\nGO",
+			Options: []diff.Option{diff.WithDelimiter("\nGO")},
+		},
+		{
+			Before: "CREATE TABLE `t` ( `id` INT ); CREATE TABLE `u` ( `id` INT ); CREATE TRIGGER trg AFTER DELETE ON u FOR EACH ROW DELETE FROM t WHERE id = OLD.id;",
+			After:  "CREATE TABLE `t` ( `id` INT );",
+			Expect: "DROP TRIGGER `trg`;\n\nDROP TABLE `u`;",
+		},
 	}
 
 	var buf bytes.Buffer
 	for _, spec := range specs {
 		buf.Reset()
-		if !assert.NoError(t, diff.Strings(&buf, spec.Before, spec.After), "diff.String should succeed") {
+		if !assert.NoError(t, diff.Strings(&buf, spec.Before, spec.After, spec.Options...), "diff.String should succeed") {
 			return
 		}
 
@@ -208,3 +784,529 @@ CREATE TABLE foo (
 		}
 	}
 }
+
+func TestDestructive(t *testing.T) {
+	type Spec struct {
+		Before      string
+		After       string
+		Destructive bool
+	}
+
+	specs := []Spec{
+		{Before: "`a` INTEGER", After: "`a` BIGINT", Destructive: false},
+		{Before: "`a` BIGINT", After: "`a` INTEGER", Destructive: true},
+		{Before: "`a` INTEGER", After: "`a` INTEGER UNSIGNED", Destructive: true},
+		{Before: "`a` INTEGER", After: "`a` INTEGER NOT NULL", Destructive: true},
+		{Before: "`a` INTEGER NOT NULL", After: "`a` INTEGER", Destructive: false},
+		{Before: "`a` CHAR (10)", After: "`a` VARCHAR (10)", Destructive: false},
+		{Before: "`a` VARCHAR (10)", After: "`a` VARCHAR (5)", Destructive: true},
+		{Before: "`a` DECIMAL (10,2)", After: "`a` DECIMAL (12,2)", Destructive: false},
+		{Before: "`a` DECIMAL (10,2)", After: "`a` DECIMAL (10,4)", Destructive: true},
+		{Before: "`a` TEXT", After: "`a` TINYTEXT", Destructive: true},
+		{Before: "`a` TEXT", After: "`a` LONGTEXT", Destructive: false},
+		{Before: "`a` VARCHAR (255)", After: "`a` TEXT", Destructive: true},
+		{Before: "`a` ENUM ('x', 'y')", After: "`a` ENUM ('x', 'y', 'z')", Destructive: false},
+		{Before: "`a` ENUM ('x', 'y')", After: "`a` ENUM ('x')", Destructive: true},
+		{Before: "`a` INTEGER COMMENT 'foo'", After: "`a` INTEGER COMMENT 'bar'", Destructive: false},
+	}
+
+	for _, spec := range specs {
+		before := "CREATE TABLE `fuga` ( " + spec.Before + " );"
+		after := "CREATE TABLE `fuga` ( " + spec.After + " );"
+
+		var buf bytes.Buffer
+		err := diff.Strings(&buf, before, after, diff.WithDestructive(diff.DestructiveRefuse))
+		if !spec.Destructive {
+			if !assert.NoError(t, err, "%s -> %s should not be destructive", spec.Before, spec.After) {
+				return
+			}
+			continue
+		}
+
+		derr, ok := err.(*diff.DestructiveError)
+		if !assert.True(t, ok, "%s -> %s should be destructive (got %v)", spec.Before, spec.After, err) {
+			return
+		}
+		if !assert.Len(t, derr.Statements, 1, "there should be one destructive statement") {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	err := diff.Strings(&buf, "CREATE TABLE `fuga` ( `a` INTEGER, `b` INTEGER );", "CREATE TABLE `fuga` ( `a` INTEGER );", diff.WithDropGuard(true))
+	if !assert.IsType(t, &diff.DestructiveError{}, err, "WithDropGuard should refuse to drop columns") {
+		return
+	}
+}
+
+func TestTypeChange(t *testing.T) {
+	type Spec struct {
+		Before string
+		After  string
+		Expect diff.TypeChange
+	}
+
+	specs := []Spec{
+		{Before: "`a` INTEGER", After: "`a` BIGINT", Expect: diff.TypeChangeWidening},
+		{Before: "`a` INTEGER NOT NULL", After: "`a` INTEGER", Expect: diff.TypeChangeWidening},
+		{Before: "`a` INTEGER COMMENT 'foo'", After: "`a` INTEGER COMMENT 'bar'", Expect: diff.TypeChangeWidening},
+		{Before: "`a` FLOAT", After: "`a` DOUBLE", Expect: diff.TypeChangeWidening},
+		{Before: "`a` BIGINT", After: "`a` INTEGER", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` INTEGER", After: "`a` INTEGER UNSIGNED", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` INTEGER", After: "`a` INTEGER NOT NULL", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` VARCHAR (20)", After: "`a` VARCHAR (10)", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` ENUM ('x', 'y')", After: "`a` ENUM ('x')", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` VARCHAR (255)", After: "`a` TEXT", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` VARCHAR (20)", After: "`a` INTEGER", Expect: diff.TypeChangeIncompatible},
+		{Before: "`a` INTEGER", After: "`a` DATETIME", Expect: diff.TypeChangeIncompatible},
+		{Before: "`a` VARCHAR (20)", After: "`a` VARBINARY (20)", Expect: diff.TypeChangeIncompatible},
+		{Before: "`a` VARCHAR (20) CHARACTER SET utf8mb4", After: "`a` VARCHAR (20) CHARACTER SET latin1", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` VARCHAR (20) CHARACTER SET utf8", After: "`a` VARCHAR (20) CHARACTER SET utf8mb4", Expect: diff.TypeChangeWidening},
+		{Before: "`a` VARCHAR (20) CHARACTER SET utf8 COLLATE utf8_general_ci", After: "`a` VARCHAR (20) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci", Expect: diff.TypeChangeWidening},
+		{Before: "`a` VARCHAR (20) COLLATE utf8mb4_bin", After: "`a` VARCHAR (20) COLLATE utf8mb4_general_ci", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` TEXT CHARACTER SET utf8mb4", After: "`a` TEXT CHARACTER SET ascii", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` DATETIME (6)", After: "`a` DATETIME", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` TIMESTAMP (6) NULL", After: "`a` TIMESTAMP (3) NULL", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` TIME", After: "`a` TIME (3)", Expect: diff.TypeChangeWidening},
+		{Before: "`a` POINT NOT NULL", After: "`a` POINT NOT NULL SRID 4326", Expect: diff.TypeChangeNarrowing},
+		{Before: "`a` POINT NOT NULL SRID 4326", After: "`a` POINT NOT NULL", Expect: diff.TypeChangeWidening},
+	}
+
+	p := schemalex.New()
+	for _, spec := range specs {
+		from, err := p.ParseString("CREATE TABLE `fuga` ( " + spec.Before + " );")
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+		to, err := p.ParseString("CREATE TABLE `fuga` ( " + spec.After + " );")
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+
+		changes, err := diff.Changes(from, to)
+		if !assert.NoError(t, err, "diff.Changes should succeed") {
+			return
+		}
+		if !assert.Len(t, changes, 1, "there should be one change") {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, changes[0].TypeChange, "%s -> %s should be classified as %s", spec.Before, spec.After, spec.Expect) {
+			return
+		}
+		if !assert.Equal(t, spec.Expect != diff.TypeChangeWidening, changes[0].Destructive, "%s -> %s destructiveness should match", spec.Before, spec.After) {
+			return
+		}
+	}
+}
+
+func TestAllowNarrowing(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `a` VARCHAR (20), `b` VARCHAR (20) );"
+
+	var buf bytes.Buffer
+	err := diff.Strings(&buf, before, "CREATE TABLE `fuga` ( `a` VARCHAR (10), `b` VARCHAR (20) );",
+		diff.WithDestructive(diff.DestructiveRefuse),
+		diff.WithAllowNarrowing(true),
+	)
+	if !assert.NoError(t, err, "narrowing changes should be allowed") {
+		return
+	}
+	if !assert.Equal(t, "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` VARCHAR (10) DEFAULT NULL;", buf.String(), "diff should match") {
+		return
+	}
+
+	buf.Reset()
+	err = diff.Strings(&buf, before, "CREATE TABLE `fuga` ( `a` VARCHAR (10), `b` INTEGER );",
+		diff.WithDestructive(diff.DestructiveRefuse),
+		diff.WithAllowNarrowing(true),
+	)
+	derr, ok := err.(*diff.DestructiveError)
+	if !assert.True(t, ok, "incompatible changes should be refused (got %v)", err) {
+		return
+	}
+	if !assert.Equal(t, []string{"ALTER TABLE `fuga` CHANGE COLUMN `b` `b` INT (11) DEFAULT NULL;"}, derr.Statements, "destructive statements should match") {
+		return
+	}
+}
+
+func TestDestructiveError(t *testing.T) {
+	var buf bytes.Buffer
+	err := diff.Strings(&buf,
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+		"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+		diff.WithDestructive(diff.DestructiveRefuse),
+	)
+	derr, ok := err.(*diff.DestructiveError)
+	if !assert.True(t, ok, "error should be a *diff.DestructiveError") {
+		return
+	}
+	if !assert.Equal(t, []string{"DROP TABLE `hoge`;"}, derr.Statements, "destructive statements should match") {
+		return
+	}
+	if !assert.Equal(t, 0, buf.Len(), "nothing should be written") {
+		return
+	}
+}
+
+func TestSummary(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER, INDEX `a_idx` (`a`) );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	to, err := p.ParseString("CREATE TABLE `fuga` ( `id` BIGINT NOT NULL, `b` INTEGER, `c` INTEGER ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	s, err := diff.Summarize(from, to)
+	if !assert.NoError(t, err, "diff.Summarize should succeed") {
+		return
+	}
+	expect := diff.Summary{
+		TablesCreated:   1,
+		TablesDropped:   1,
+		ColumnsAdded:    2,
+		ColumnsDropped:  1,
+		ColumnsModified: 1,
+		IndexesDropped:  1,
+		Destructive:     2,
+	}
+	if !assert.Equal(t, expect, *s, "summary should match") {
+		return
+	}
+	if !assert.Equal(t, "1 table created\n1 table dropped\n2 columns added\n1 column dropped\n1 column modified\n1 index dropped\n2 statements may lose data\n", s.String(), "summary string should match") {
+		return
+	}
+
+	s, err = diff.Summarize(to, to)
+	if !assert.NoError(t, err, "diff.Summarize should succeed") {
+		return
+	}
+	if !assert.True(t, s.IsEmpty(), "summary should be empty") {
+		return
+	}
+	if !assert.Equal(t, "no changes\n", s.String(), "summary string should match") {
+		return
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := diff.Strings(&buf,
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER, INDEX `a_idx` (`a`) );",
+		"CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` BIGINT );",
+		diff.WithJSON(true),
+	)
+	if !assert.NoError(t, err, "diff.Strings should succeed") {
+		return
+	}
+
+	expect := `{
+  "changes": [
+    {
+      "table": "hoge",
+      "object": "index",
+      "name": "a_idx",
+      "action": "drop",
+      "sql": "ALTER TABLE ` + "`hoge` DROP INDEX `a_idx`" + `;",
+      "destructive": false
+    },
+    {
+      "table": "hoge",
+      "object": "column",
+      "name": "a",
+      "action": "modify",
+      "sql": "ALTER TABLE ` + "`hoge` CHANGE COLUMN `a` `a` BIGINT (20) DEFAULT NULL" + `;",
+      "destructive": false,
+      "type_change": "widening"
+    }
+  ]
+}
+`
+	if !assert.Equal(t, expect, buf.String(), "JSON output should match") {
+		return
+	}
+
+	buf.Reset()
+	err = diff.Strings(&buf, "CREATE TABLE `hoge` ( `id` INTEGER );", "CREATE TABLE `hoge` ( `id` INTEGER );", diff.WithJSON(true))
+	if !assert.NoError(t, err, "diff.Strings should succeed") {
+		return
+	}
+	if !assert.Equal(t, "{\n  \"changes\": []\n}\n", buf.String(), "JSON output should match") {
+		return
+	}
+}
+
+func TestChanges(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	changes, err := diff.Changes(from, to, diff.WithTransaction(true))
+	if !assert.NoError(t, err, "diff.Changes should succeed") {
+		return
+	}
+	expect := []diff.Change{
+		{
+			Table:  "hoge",
+			Object: "column",
+			Name:   "a",
+			Action: "add",
+			SQL:    "ALTER TABLE `hoge` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;",
+		},
+	}
+	if !assert.Equal(t, expect, changes, "changes should match") {
+		return
+	}
+
+	from, err = p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `ia` (`id`, `a`) );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	to, err = p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER, KEY `ia` (`a`, `id`) );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	changes, err = diff.Changes(from, to)
+	if !assert.NoError(t, err, "diff.Changes should succeed") {
+		return
+	}
+	expect = []diff.Change{
+		{
+			Table:                 "hoge",
+			Object:                "index",
+			Name:                  "ia",
+			Action:                "drop",
+			SQL:                   "ALTER TABLE `hoge` DROP INDEX `ia`;",
+			ReorderedIndexColumns: true,
+		},
+		{
+			Table:                 "hoge",
+			Object:                "index",
+			Name:                  "ia",
+			Action:                "add",
+			SQL:                   "ALTER TABLE `hoge` ADD INDEX `ia` (`a`, `id`);",
+			ReorderedIndexColumns: true,
+		},
+	}
+	if !assert.Equal(t, expect, changes, "reordered index columns should be marked") {
+		return
+	}
+}
+
+func TestStmts(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `b` INTEGER );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	sql, changes, err := diff.Stmts(from, to, diff.WithTransaction(false))
+	if !assert.NoError(t, err, "Stmts should succeed") {
+		return
+	}
+	if !assert.Equal(t, "ALTER TABLE `hoge` DROP COLUMN `b`;\nALTER TABLE `hoge` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;", sql, "SQL should match") {
+		return
+	}
+	expect := []diff.Change{
+		{
+			Table:       "hoge",
+			Object:      "column",
+			Name:        "b",
+			Action:      "drop",
+			SQL:         "ALTER TABLE `hoge` DROP COLUMN `b`;",
+			Destructive: true,
+		},
+		{
+			Table:  "hoge",
+			Object: "column",
+			Name:   "a",
+			Action: "add",
+			SQL:    "ALTER TABLE `hoge` ADD COLUMN `a` INT (11) DEFAULT NULL AFTER `id`;",
+		},
+	}
+	if !assert.Equal(t, expect, changes, "changes should match") {
+		return
+	}
+
+	if _, _, err := diff.Stmts(from, to, diff.WithDestructive(diff.DestructiveRefuse)); !assert.Error(t, err, "Stmts should fail with DestructiveRefuse") {
+		return
+	}
+}
+
+func TestWithTable(t *testing.T) {
+	const before = "CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY); CREATE TABLE `b` (`id` INT, `x` INT);"
+	const after = "CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY, `y` INT); CREATE TABLE `b` (`id` INT, CONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `a` (`id`)); CREATE TABLE `c` (`id` INT);"
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithTable("b"), diff.WithTransaction(false)), "diff.Strings should succeed") {
+		return
+	}
+	expect := "ALTER TABLE `b` DROP COLUMN `x`;\nALTER TABLE `b` ADD INDEX `fk` (`id`);\nALTER TABLE `b` ADD CONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `a` (`id`);"
+	if !assert.Equal(t, expect, buf.String(), "only the statements of the table should be written") {
+		return
+	}
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithTable("d")), "diff.Strings should succeed") {
+		return
+	}
+	if !assert.Empty(t, buf.String(), "no statement should be written for other tables") {
+		return
+	}
+}
+
+func TestEngineWarnings(t *testing.T) {
+	type Spec struct {
+		Before  string
+		After   string
+		Version string
+		Expect  []string
+	}
+
+	specs := []Spec{
+		{
+			Before: "CREATE TABLE `a` ( `id` INT NOT NULL PRIMARY KEY );",
+			After:  "CREATE TABLE `a` ( `id` INT NOT NULL PRIMARY KEY ); CREATE TABLE `b` ( `a_id` INT, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ) ENGINE = MyISAM;",
+			Expect: []string{"warning: foreign key `fk_a` of table `b` is ignored by the MyISAM engine"},
+		},
+		{
+			Before: "CREATE TABLE `a` ( `id` INT NOT NULL PRIMARY KEY ); CREATE TABLE `b` ( `a_id` INT, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "CREATE TABLE `a` ( `id` INT NOT NULL PRIMARY KEY ); CREATE TABLE `b` ( `a_id` INT, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ) ENGINE = MyISAM;",
+			Expect: []string{"warning: foreign key `fk_a` of table `b` is ignored by the MyISAM engine"},
+		},
+		{
+			// the foreign key is left as is
+			Before: "CREATE TABLE `a` ( `id` INT NOT NULL PRIMARY KEY ); CREATE TABLE `b` ( `a_id` INT, `x` INT, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ) ENGINE = MyISAM;",
+			After:  "CREATE TABLE `a` ( `id` INT NOT NULL PRIMARY KEY ); CREATE TABLE `b` ( `a_id` INT, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ) ENGINE = MyISAM;",
+		},
+		{
+			Before:  "CREATE TABLE `t` ( `body` TEXT );",
+			After:   "CREATE TABLE `t` ( `body` TEXT, FULLTEXT INDEX `ft` (`body`) );",
+			Version: "5.5.62",
+			Expect:  []string{"warning: FULLTEXT index `ft` of table `t` requires MySQL 5.6.0 or later with the InnoDB engine"},
+		},
+		{
+			Before:  "CREATE TABLE `t` ( `body` TEXT );",
+			After:   "CREATE TABLE `t` ( `body` TEXT, FULLTEXT INDEX `ft` (`body`) ) ENGINE = MyISAM;",
+			Version: "5.5.62",
+		},
+		{
+			Before:  "CREATE TABLE `t` ( `body` TEXT );",
+			After:   "CREATE TABLE `t` ( `body` TEXT, FULLTEXT INDEX `ft` (`body`) );",
+			Version: "5.6.51",
+		},
+		{
+			Before:  "CREATE TABLE `t` ( `p` POINT NOT NULL );",
+			After:   "CREATE TABLE `t` ( `p` POINT NOT NULL, SPATIAL INDEX `sp` (`p`) );",
+			Version: "5.7.4",
+			Expect:  []string{"warning: SPATIAL index `sp` of table `t` requires MySQL 5.7.5 or later with the InnoDB engine"},
+		},
+	}
+
+	for _, spec := range specs {
+		var warnings []string
+		options := []diff.Option{schemalex.WithWarningHandler(func(w schemalex.Warning) {
+			warnings = append(warnings, w.String())
+		})}
+		if spec.Version != "" {
+			v, err := schemalex.ParseMySQLVersion(spec.Version)
+			if !assert.NoError(t, err, "schemalex.ParseMySQLVersion should succeed") {
+				return
+			}
+			options = append(options, diff.WithMySQLVersion(v))
+		}
+
+		var buf bytes.Buffer
+		if !assert.NoError(t, diff.Strings(&buf, spec.Before, spec.After, options...), "diff.Strings should succeed") {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, warnings, "warnings should match for %s", spec.After) {
+			return
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	const before = "CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY, `x` INT, `z` INT); CREATE TABLE `b` (`id` INT);"
+	const after = "CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY, `x` BIGINT, `z` INT, `y` INT); CREATE TABLE `c` (`id` INT);"
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithUnified(true)), "diff.Strings should succeed") {
+		return
+	}
+	expect := "--- a/a\n+++ b/a\n@@ -1,6 +1,7 @@\n CREATE TABLE `a` (\n   `id` INT (11) NOT NULL,\n-  `x` INT (11) DEFAULT NULL,\n+  `x` BIGINT (20) DEFAULT NULL,\n   `z` INT (11) DEFAULT NULL,\n+  `y` INT (11) DEFAULT NULL,\n   PRIMARY KEY (`id`)\n );\n" +
+		"--- /dev/null\n+++ b/c\n@@ -0,0 +1,3 @@\n+CREATE TABLE `c` (\n+  `id` INT (11) DEFAULT NULL\n+);\n" +
+		"--- a/b\n+++ /dev/null\n@@ -1,3 +0,0 @@\n-CREATE TABLE `b` (\n-  `id` INT (11) DEFAULT NULL\n-);\n"
+	if !assert.Equal(t, expect, buf.String(), "the tables should be written as a unified diff") {
+		return
+	}
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithUnified(true), diff.WithColor(true), diff.WithTable("a")), "diff.Strings should succeed") {
+		return
+	}
+	expect = "\x1b[1m--- a/a\x1b[0m\n\x1b[1m+++ b/a\x1b[0m\n\x1b[36m@@ -1,6 +1,7 @@\x1b[0m\n CREATE TABLE \x1b[33m`a`\x1b[0m (\n   \x1b[33m`id`\x1b[0m INT (11) NOT NULL,\n" +
+		"\x1b[31m-  `x` INT (11) DEFAULT NULL,\x1b[0m\n\x1b[32m+  `x` BIGINT (20) DEFAULT NULL,\x1b[0m\n   \x1b[33m`z`\x1b[0m INT (11) DEFAULT NULL,\n" +
+		"\x1b[32m+  `y` INT (11) DEFAULT NULL,\x1b[0m\n   PRIMARY KEY (\x1b[33m`id`\x1b[0m)\n );\n"
+	if !assert.Equal(t, expect, buf.String(), "the lines should be colored") {
+		return
+	}
+}
+
+func TestText(t *testing.T) {
+	p := schemalex.New()
+	before, err := p.ParseString("create table b (id int); CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY);")
+	if !assert.NoError(t, err, "parsing before should succeed") {
+		return
+	}
+	after, err := p.ParseString("CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY, `x` INT); CREATE TABLE `b` (`id` INT);")
+	if !assert.NoError(t, err, "parsing after should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Text(&buf, before, after, diff.WithSortByName(true)), "diff.Text should succeed") {
+		return
+	}
+	expect := "--- before\n+++ after\n@@ -1,5 +1,6 @@\n CREATE TABLE `a` (\n   `id` INT (11) NOT NULL,\n+  `x` INT (11) DEFAULT NULL,\n   PRIMARY KEY (`id`)\n );\n \n"
+	if !assert.Equal(t, expect, buf.String(), "the formatted schemas should be compared") {
+		return
+	}
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Text(&buf, before, before), "diff.Text should succeed") {
+		return
+	}
+	if !assert.Empty(t, buf.String(), "nothing should be written for the same schemas") {
+		return
+	}
+
+	child, err := p.ParseString("CREATE TABLE `c` (`id` INT, FOREIGN KEY (`id`) REFERENCES `p` (`id`)); CREATE TABLE `p` (`id` INT NOT NULL PRIMARY KEY);")
+	if !assert.NoError(t, err, "parsing child should succeed") {
+		return
+	}
+	parent, err := p.ParseString("CREATE TABLE `p` (`id` INT NOT NULL PRIMARY KEY); CREATE TABLE `c` (`id` INT, FOREIGN KEY (`id`) REFERENCES `p` (`id`));")
+	if !assert.NoError(t, err, "parsing parent should succeed") {
+		return
+	}
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Text(&buf, child, parent, diff.WithDependencyOrder(true)), "diff.Text should succeed") {
+		return
+	}
+	if !assert.Empty(t, buf.String(), "the tables should be written after the tables they reference") {
+		return
+	}
+}
@@ -0,0 +1,231 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	schemalex "github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// defaultEngine returns the storage engine of the tables that do not
+// declare one, or an empty string if the dialect has no storage engines
+func defaultEngine(dialect schemalex.Dialect) string {
+	switch dialect {
+	case "", schemalex.DialectMySQL, schemalex.DialectMariaDB:
+		return "InnoDB"
+	default:
+		return ""
+	}
+}
+
+// tableEngine returns the ENGINE option of the table, or nil if the
+// table does not declare one
+func tableEngine(table model.Table) model.TableOption {
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "ENGINE") {
+			return opt
+		}
+	}
+	return nil
+}
+
+// engineName returns the storage engine of the table, or fallback if
+// the table does not declare one
+func engineName(table model.Table, fallback string) string {
+	if opt := tableEngine(table); opt != nil {
+		return opt.Value()
+	}
+	return fallback
+}
+
+// alteredEngine returns the ENGINE option that changes the storage
+// engine of the table, or nil if the engine is the same in both
+// schemas. A table that does not declare its engine uses the default
+// engine, so that dropping `ENGINE=MyISAM` converts the table back to
+// InnoDB.
+func alteredEngine(ctx *alterCtx) model.TableOption {
+	if ctx.engines.defaultEngine == "" || ctx.ignore.option("ENGINE") {
+		return nil
+	}
+
+	from := engineName(ctx.from, ctx.engines.defaultEngine)
+	to := engineName(ctx.to, ctx.engines.defaultEngine)
+	if strings.EqualFold(from, to) {
+		return nil
+	}
+	if opt := tableEngine(ctx.to); opt != nil {
+		return opt
+	}
+	return model.NewTableOption("ENGINE", to, false)
+}
+
+// tableRowFormat returns the ROW_FORMAT option of the table, or nil if
+// the table does not declare one
+func tableRowFormat(table model.Table) model.TableOption {
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "ROW_FORMAT") {
+			return opt
+		}
+	}
+	return nil
+}
+
+// defaultRowFormat returns the row format that the engine uses for
+// ROW_FORMAT=DEFAULT, or an empty string if it is not known. InnoDB uses
+// DYNAMIC from MySQL 5.7.9 on, and COMPACT before.
+func (s engineSupport) defaultRowFormat(engine string) string {
+	if !strings.EqualFold(engine, "InnoDB") {
+		return ""
+	}
+	if !s.version.IsZero() && !s.version.AtLeast(5, 7, 9) {
+		return "COMPACT"
+	}
+	return "DYNAMIC"
+}
+
+// rowFormat returns the row format of the table in upper case, where a
+// missing ROW_FORMAT is DEFAULT, and DEFAULT is the default row format
+// of the engine when it is known
+func (s engineSupport) rowFormat(table model.Table) string {
+	format := "DEFAULT"
+	if opt := tableRowFormat(table); opt != nil {
+		format = strings.ToUpper(opt.Value())
+	}
+	if format == "DEFAULT" {
+		if v := s.defaultRowFormat(engineName(table, s.defaultEngine)); v != "" {
+			return v
+		}
+	}
+	return format
+}
+
+// alteredRowFormat returns the ROW_FORMAT option that changes the row
+// format of the table, or nil if the row format is the same in both
+// schemas. A table that no longer declares its row format is set to
+// DEFAULT, or to the default row format of its engine with
+// WithExplicitRowFormat.
+func alteredRowFormat(ctx *alterCtx) model.TableOption {
+	if ctx.ignore.option("ROW_FORMAT") {
+		return nil
+	}
+
+	opt := tableRowFormat(ctx.to)
+	if opt == nil && tableRowFormat(ctx.from) == nil {
+		return nil
+	}
+	if ctx.engines.rowFormat(ctx.from) == ctx.engines.rowFormat(ctx.to) {
+		return nil
+	}
+
+	value := "DEFAULT"
+	if opt != nil {
+		value = strings.ToUpper(opt.Value())
+	}
+	if value == "DEFAULT" && ctx.explicitRows {
+		if v := ctx.engines.defaultRowFormat(engineName(ctx.to, ctx.engines.defaultEngine)); v != "" {
+			value = v
+		}
+	}
+	return model.NewTableOption("ROW_FORMAT", value, false)
+}
+
+// engineSupport describes the storage engines of the target server: the
+// default engine, the default row formats, and the indexes that the
+// engines can not create
+type engineSupport struct {
+	defaultEngine string
+	version       schemalex.MySQLVersion
+}
+
+// check returns the warnings about the indexes of the table that the
+// storage engine can not create, or silently ignores. When the table
+// is altered rather than created, only the indexes in added are
+// checked, unless the engine itself is changed.
+func (s engineSupport) check(table model.Table, added func(model.Index) bool) []string {
+	if s.defaultEngine == "" {
+		return nil
+	}
+
+	engine := engineName(table, s.defaultEngine)
+	innodb := strings.EqualFold(engine, "InnoDB")
+
+	var warnings []string
+	for idx := range table.Indexes() {
+		if added != nil && !added(idx) {
+			continue
+		}
+		switch {
+		case idx.IsForeignKey():
+			if !supportsForeignKeys(engine) {
+				warnings = append(warnings, fmt.Sprintf("%s of table %s is ignored by the %s engine", describeForeignKey(idx), util.Backquote(table.Name()), engine))
+			}
+		case idx.IsFullText():
+			if innodb && !s.version.IsZero() && !s.version.AtLeast(5, 6, 0) {
+				warnings = append(warnings, fmt.Sprintf("FULLTEXT index %s of table %s requires MySQL 5.6.0 or later with the InnoDB engine", util.Backquote(indexName(idx)), util.Backquote(table.Name())))
+			}
+		case idx.IsSpatial():
+			if innodb && !s.version.IsZero() && !s.version.AtLeast(5, 7, 5) {
+				warnings = append(warnings, fmt.Sprintf("SPATIAL index %s of table %s requires MySQL 5.7.5 or later with the InnoDB engine", util.Backquote(indexName(idx)), util.Backquote(table.Name())))
+			}
+		}
+	}
+	return warnings
+}
+
+// supportsForeignKeys returns true if the storage engine enforces
+// foreign keys. The other engines parse and ignore them.
+func supportsForeignKeys(engine string) bool {
+	switch strings.ToLower(engine) {
+	case "innodb", "ndb", "ndbcluster":
+		return true
+	default:
+		return false
+	}
+}
+
+func describeForeignKey(idx model.Index) string {
+	if idx.HasSymbol() {
+		return "foreign key " + util.Backquote(idx.Symbol())
+	}
+	var names []string
+	for col := range idx.Columns() {
+		names = append(names, util.Backquote(col.Name()))
+	}
+	return "foreign key (" + strings.Join(names, ", ") + ")"
+}
+
+// engineWarnings returns the warnings about the tables that are created
+// or altered with indexes that their storage engines do not support
+func (ctx *diffCtx) engineWarnings(onlyTable string) []string {
+	var warnings []string
+	for _, id := range ctx.tableIDs(ctx.to, ctx.toSet, nil) {
+		stmt, ok := ctx.to.Lookup(id)
+		if !ok {
+			continue
+		}
+		table := stmt.(model.Table)
+		if onlyTable != "" && table.Name() != onlyTable {
+			continue
+		}
+
+		var added func(model.Index) bool
+		if stmt, ok := ctx.from.Lookup(id); ok && ctx.fromSet.Contains(id) {
+			before := stmt.(model.Table)
+			fallback := ctx.engines.defaultEngine
+			if strings.EqualFold(engineName(before, fallback), engineName(table, fallback)) {
+				existing := make(map[string]struct{})
+				for idx := range before.Indexes() {
+					existing[idx.ID()] = struct{}{}
+				}
+				added = func(idx model.Index) bool {
+					_, ok := existing[idx.ID()]
+					return !ok
+				}
+			}
+		}
+		warnings = append(warnings, ctx.engines.check(table, added)...)
+	}
+	return warnings
+}
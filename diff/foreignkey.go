@@ -0,0 +1,324 @@
+package diff
+
+import (
+	"bytes"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// foreignKey is a foreign key that is dropped or added separately
+// from the table that it belongs to
+type foreignKey struct {
+	table model.Table
+	index model.Index
+}
+
+// sortTables computes the order in which tables are dropped and
+// created. Tables are created after the tables that they reference,
+// and dropped before them. When tables reference each other, the
+// foreign keys that close the cycle are deferred: they are added after
+// all tables have been created, or dropped before any table is dropped.
+//
+// Foreign keys of new tables that reference columns that are added to
+// existing tables are deferred as well, as those columns do not exist
+// until the existing tables are altered.
+func (ctx *diffCtx) sortTables() error {
+	dropped, err := lookupTables(ctx.from, ctx.tableIDs(ctx.from, ctx.fromSet, ctx.toSet))
+	if err != nil {
+		return err
+	}
+	created, err := lookupTables(ctx.to, ctx.tableIDs(ctx.to, ctx.toSet, ctx.fromSet))
+	if err != nil {
+		return err
+	}
+
+	var order []model.Table
+	order, ctx.deferredDrop = topologicalSort(dropped)
+	for i := len(order) - 1; i >= 0; i-- {
+		ctx.dropOrder = append(ctx.dropOrder, order[i])
+	}
+
+	ctx.createOrder, ctx.deferredCreate = topologicalSort(created)
+	for _, table := range ctx.createOrder {
+		for idx := range table.Indexes() {
+			if !idx.IsForeignKey() || isDeferred(ctx.deferredCreate, idx) {
+				continue
+			}
+			if ctx.referencesNewColumn(idx.Reference()) {
+				ctx.deferredCreate = append(ctx.deferredCreate, &foreignKey{table: table, index: idx})
+			}
+		}
+	}
+	return nil
+}
+
+// referencesNewColumn returns true if the reference points to an
+// existing table, but to a column that is only added by the diff
+func (ctx *diffCtx) referencesNewColumn(r model.Reference) bool {
+	if r == nil {
+		return false
+	}
+
+	id := model.NewTable(r.TableName()).ID()
+	if !ctx.fromSet.Contains(id) || !ctx.toSet.Contains(id) {
+		return false
+	}
+
+	stmt, ok := ctx.from.Lookup(id)
+	if !ok {
+		return false
+	}
+	table := stmt.(model.Table)
+	for col := range r.Columns() {
+		if _, ok := table.LookupColumn(model.NewTableColumn(col.Name()).ID()); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupTables(stmts model.Stmts, ids []string) ([]model.Table, error) {
+	var tables []model.Table
+	for _, id := range ids {
+		stmt, ok := stmts.Lookup(id)
+		if !ok {
+			return nil, errors.Errorf(`failed to lookup table %s`, id)
+		}
+
+		table, ok := stmt.(model.Table)
+		if !ok {
+			return nil, errors.Errorf(`lookup failed: %s is not a model.Table`, id)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// topologicalSort sorts the given tables so that referenced tables come
+// before the tables that reference them. Only references between the
+// given tables are considered. Ties are broken by the given order. If
+// the references form a cycle, the first remaining table is picked, and
+// its foreign keys to the remaining tables are returned as deferred.
+func topologicalSort(tables []model.Table) ([]model.Table, []*foreignKey) {
+	pending := make(map[string]struct{})
+	for _, table := range tables {
+		pending[table.Name()] = struct{}{}
+	}
+
+	var sorted []model.Table
+	var deferred []*foreignKey
+	for len(sorted) < len(tables) {
+		var next model.Table
+		for _, table := range tables {
+			if _, ok := pending[table.Name()]; !ok {
+				continue
+			}
+			if len(pendingReferences(table, pending)) == 0 {
+				next = table
+				break
+			}
+		}
+
+		if next == nil {
+			// every remaining table depends on another one, so break
+			// the cycle at the first one
+			for _, table := range tables {
+				if _, ok := pending[table.Name()]; ok {
+					next = table
+					break
+				}
+			}
+			for _, idx := range pendingReferences(next, pending) {
+				deferred = append(deferred, &foreignKey{table: next, index: idx})
+			}
+		}
+
+		delete(pending, next.Name())
+		sorted = append(sorted, next)
+	}
+	return sorted, deferred
+}
+
+// pendingReferences returns the foreign keys of table that reference
+// one of the pending tables, other than the table itself
+func pendingReferences(table model.Table, pending map[string]struct{}) []model.Index {
+	var list []model.Index
+	for idx := range table.Indexes() {
+		if !idx.IsForeignKey() || idx.Reference() == nil {
+			continue
+		}
+		name := idx.Reference().TableName()
+		if name == table.Name() {
+			continue
+		}
+		if _, ok := pending[name]; ok {
+			list = append(list, idx)
+		}
+	}
+	return list
+}
+
+func isDeferred(list []*foreignKey, idx model.Index) bool {
+	for _, fk := range list {
+		if fk.index == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutForeignKeys returns a copy of table that does not include the
+// deferred foreign keys. If none of the foreign keys of the table are
+// deferred, the table is returned as is.
+func withoutForeignKeys(table model.Table, deferred []*foreignKey) model.Table {
+	var found bool
+	for _, fk := range deferred {
+		if fk.table == table {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return table
+	}
+
+	return copyTable(table, func(idx model.Index) bool {
+		return !isDeferred(deferred, idx)
+	})
+}
+
+// copyTable returns a copy of table that shares its columns, options
+// and partitioning, and only includes the indexes for which keep
+// returns true, or all of them if keep is nil
+func copyTable(table model.Table, keep func(model.Index) bool) model.Table {
+	t := model.NewTable(table.Name())
+	t.SetTemporary(table.IsTemporary())
+	t.SetIfNotExists(table.IsIfNotExists())
+	if table.HasLikeTable() {
+		t.SetLikeTable(table.LikeTable())
+	}
+	for col := range table.Columns() {
+		t.AddColumn(col)
+	}
+	for idx := range table.Indexes() {
+		if keep == nil || keep(idx) {
+			t.AddIndex(idx)
+		}
+	}
+	for opt := range table.Options() {
+		t.AddOption(opt)
+	}
+	if table.HasPartitioning() {
+		t.SetPartitioning(table.Partitioning())
+	}
+	return t
+}
+
+func foreignKeyName(idx model.Index) string {
+	if idx.HasSymbol() {
+		return idx.Symbol()
+	}
+	return idx.Name()
+}
+
+func dropForeignKey(list *changes, table model.Table, idx model.Index) error {
+	if !idx.HasName() && !idx.HasSymbol() {
+		return errors.Errorf("can not drop foreign key without name: %s", idx.ID())
+	}
+	list.add(changeForeignKeyDrop, table.Name(), foreignKeyName(idx), "ALTER TABLE "+util.Backquote(table.Name())+" DROP FOREIGN KEY "+util.Backquote(foreignKeyName(idx))+";", false)
+	return nil
+}
+
+func addForeignKey(list *changes, table model.Table, idx model.Index) error {
+	var buf bytes.Buffer
+	buf.WriteString("ALTER TABLE ")
+	buf.WriteString(util.Backquote(table.Name()))
+	buf.WriteString(" ADD ")
+	if err := format.SQL(&buf, idx); err != nil {
+		return err
+	}
+	buf.WriteByte(';')
+	list.add(changeForeignKeyAdd, table.Name(), foreignKeyName(idx), buf.String(), false)
+	return nil
+}
+
+// dropForeignKeys drops the foreign keys that are no longer needed
+// before any table or index is dropped, as those may be referenced
+// by the foreign keys. Unless WithSeparateForeignKeys is enabled, only
+// the deferred foreign keys are dropped here.
+func dropForeignKeys(ctx *diffCtx) (changes, error) {
+	var list changes
+	for _, fk := range ctx.deferredDrop {
+		if err := dropForeignKey(&list, fk.table, fk.index); err != nil {
+			return nil, err
+		}
+	}
+	if !ctx.separateFKs {
+		return list, nil
+	}
+
+	l, err := eachAlteredTable(ctx, dropTableForeignKeys)
+	if err != nil {
+		return nil, err
+	}
+	return append(list, l...), nil
+}
+
+// addForeignKeys adds foreign keys after all tables, columns, and
+// indexes have been created, so that everything that they reference
+// already exists. Unless WithSeparateForeignKeys is enabled, only the
+// deferred foreign keys are added here.
+func addForeignKeys(ctx *diffCtx) (changes, error) {
+	var list changes
+	for _, fk := range ctx.deferredCreate {
+		if err := addForeignKey(&list, fk.table, fk.index); err != nil {
+			return nil, err
+		}
+	}
+	if !ctx.separateFKs {
+		return list, nil
+	}
+
+	l, err := eachAlteredTable(ctx, addTableForeignKeys)
+	if err != nil {
+		return nil, err
+	}
+	return append(list, l...), nil
+}
+
+func dropTableForeignKeys(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, index := range ctx.indexIDs(ctx.from, ctx.fromIndexes, ctx.toIndexes) {
+		indexStmt, ok := ctx.from.LookupIndex(index)
+		if !ok {
+			return nil, errors.Errorf(`index '%s' not found in old schema (drop foreign key)`, index)
+		}
+		if !indexStmt.IsForeignKey() {
+			continue
+		}
+		if err := dropForeignKey(&list, ctx.from, indexStmt); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func addTableForeignKeys(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, index := range ctx.indexIDs(ctx.to, ctx.toIndexes, ctx.fromIndexes) {
+		indexStmt, ok := ctx.to.LookupIndex(index)
+		if !ok {
+			return nil, errors.Errorf(`index '%s' not found in new schema (add foreign key)`, index)
+		}
+		if !indexStmt.IsForeignKey() {
+			continue
+		}
+		if err := addForeignKey(&list, ctx.from, indexStmt); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
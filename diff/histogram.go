@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"bytes"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// updateHistograms keeps the histograms in sync with the new schema.
+// A histogram is (re)created when it is new, when its number of buckets
+// has changed, or when the column it describes has been added or changed,
+// as the existing statistics no longer reflect the column. Histograms
+// that are no longer declared are dropped, unless the column itself is
+// dropped, in which case the histogram is removed along with it.
+func updateHistograms(ctx *diffCtx) (changes, error) {
+	if !ctx.histograms {
+		return nil, nil
+	}
+
+	fromHistograms := histograms(ctx.from)
+	toHistograms := histograms(ctx.to)
+
+	var list changes
+	for _, id := range ctx.histogramIDs(ctx.to) {
+		h := toHistograms[id]
+		if ctx.ignoreHistogram(h) {
+			continue
+		}
+		table, ok := lookupTable(ctx.to, h.TableName())
+		if !ok {
+			continue
+		}
+		after, ok := table.LookupColumn(columnID(h.ColumnName()))
+		if !ok {
+			continue
+		}
+
+		if prev, ok := fromHistograms[id]; ok && prev.Buckets() == h.Buckets() {
+			if fromTable, ok := lookupTable(ctx.from, h.TableName()); ok {
+				before, ok := fromTable.LookupColumn(after.ID())
+				if ok && ctx.columns.equal(fromTable, before, table, after) {
+					continue
+				}
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := format.SQL(&buf, h); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(';')
+		list.add(changeHistogramUpdate, h.TableName(), h.ColumnName(), buf.String(), false)
+	}
+
+	for _, id := range ctx.histogramIDs(ctx.from) {
+		if _, ok := toHistograms[id]; ok {
+			continue
+		}
+
+		h := fromHistograms[id]
+		if ctx.ignoreHistogram(h) {
+			continue
+		}
+		table, ok := lookupTable(ctx.to, h.TableName())
+		if !ok {
+			continue
+		}
+		if _, ok := table.LookupColumn(columnID(h.ColumnName())); !ok {
+			continue
+		}
+		list.add(changeHistogramDrop, h.TableName(), h.ColumnName(), "ANALYZE TABLE "+util.Backquote(h.TableName())+" DROP HISTOGRAM ON "+util.Backquote(h.ColumnName())+";", false)
+	}
+
+	return list, nil
+}
+
+// ignoreHistogram returns true if the table or the column that the
+// histogram describes is excluded from the comparison
+func (ctx *diffCtx) ignoreHistogram(h model.Histogram) bool {
+	return ctx.ignore.table(h.TableName()) || ctx.ignore.column(h.TableName(), h.ColumnName())
+}
+
+func histograms(stmts model.Stmts) map[string]model.Histogram {
+	m := make(map[string]model.Histogram)
+	for _, stmt := range stmts {
+		if h, ok := stmt.(model.Histogram); ok {
+			m[h.ID()] = h
+		}
+	}
+	return m
+}
+
+func lookupTable(stmts model.Stmts, name string) (model.Table, bool) {
+	stmt, ok := stmts.Lookup(model.NewTable(name).ID())
+	if !ok {
+		return nil, false
+	}
+	table, ok := stmt.(model.Table)
+	return table, ok
+}
+
+func columnID(name string) string {
+	return model.NewTableColumn(name).ID()
+}
@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// ignoreRules holds the patterns of the tables, columns, and table
+// options that are excluded from the comparison. If only is not empty,
+// the tables that do not match it are excluded too, and so are the
+// TEMPORARY tables if temporary is true.
+type ignoreRules struct {
+	tables    []*regexp.Regexp
+	only      []*regexp.Regexp
+	columns   []*regexp.Regexp
+	options   []*regexp.Regexp
+	temporary bool
+}
+
+func (r *ignoreRules) addTables(patterns []string) error {
+	for _, pattern := range patterns {
+		re, err := util.CompilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		r.tables = append(r.tables, re)
+	}
+	return nil
+}
+
+func (r *ignoreRules) addOnlyTables(patterns []string) error {
+	for _, pattern := range patterns {
+		re, err := util.CompilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		r.only = append(r.only, re)
+	}
+	return nil
+}
+
+// addColumns adds patterns that are matched against `table.column`.
+// Glob patterns that do not specify the table apply to all tables.
+func (r *ignoreRules) addColumns(patterns []string) error {
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "/") && !strings.Contains(pattern, ".") {
+			pattern = "*." + pattern
+		}
+		re, err := util.CompilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		r.columns = append(r.columns, re)
+	}
+	return nil
+}
+
+func (r *ignoreRules) addOptions(patterns []string) error {
+	for _, pattern := range patterns {
+		re, err := util.CompilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		r.options = append(r.options, re)
+	}
+	return nil
+}
+
+func (r *ignoreRules) table(name string) bool {
+	if len(r.only) > 0 && !matchAny(r.only, name) {
+		return true
+	}
+	return matchAny(r.tables, name)
+}
+
+// tableStmt is like table, and also excludes the TEMPORARY tables when
+// they are not compared
+func (r *ignoreRules) tableStmt(table model.Table) bool {
+	if r.temporary && table.IsTemporary() {
+		return true
+	}
+	return r.table(table.Name())
+}
+
+func (r *ignoreRules) column(table, column string) bool {
+	return matchAny(r.columns, table+"."+column)
+}
+
+func (r *ignoreRules) option(key string) bool {
+	return matchAny(r.options, key)
+}
+
+func matchAny(list []*regexp.Regexp, s string) bool {
+	for _, re := range list {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// indexReorder is an index that only changed the order of its columns,
+// and that has to be dropped and added again
+type indexReorder struct {
+	from model.Index
+	to   model.Index
+}
+
+// note returns the comment written before the statement that drops
+// the index
+func (r *indexReorder) note(table string) string {
+	return fmt.Sprintf("index %s of table %s is rebuilt because the order of its columns changed from %s to %s",
+		util.Backquote(indexName(r.to)), util.Backquote(table), describeIndexColumns(r.from), describeIndexColumns(r.to))
+}
+
+func describeIndexColumns(idx model.Index) string {
+	var names []string
+	for col := range idx.Columns() {
+		names = append(names, util.Backquote(col.Name()))
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+// sortedIndexContent returns the same key as indexContent, with the
+// columns sorted, so that it does not depend on their order
+func sortedIndexContent(idx model.Index) string {
+	keys := indexColumnKeys(idx)
+	sort.Strings(keys)
+	var buf strings.Builder
+	buf.WriteString(indexKind(idx))
+	for _, key := range keys {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+	}
+	return buf.String()
+}
+
+// findIndexReorders pairs the indexes that are dropped with the indexes
+// that are added with the same name and the same columns in a
+// different order, keyed by the name of the index. Foreign keys are
+// left out, as they are dropped and added on their own.
+func findIndexReorders(ctx *alterCtx) map[string]*indexReorder {
+	dropped := make(map[string]model.Index)
+	for _, id := range ctx.fromIndexes.Difference(ctx.toIndexes).ToSlice() {
+		if idx, ok := ctx.from.LookupIndex(id.(string)); ok && !idx.IsForeignKey() && (idx.IsPrimaryKey() || idx.HasName()) {
+			dropped[indexName(idx)] = idx
+		}
+	}
+
+	reorders := make(map[string]*indexReorder)
+	for _, id := range ctx.toIndexes.Difference(ctx.fromIndexes).ToSlice() {
+		idx, ok := ctx.to.LookupIndex(id.(string))
+		if !ok || idx.IsForeignKey() {
+			continue
+		}
+		from, ok := dropped[indexName(idx)]
+		if !ok || from.IsPrimaryKey() != idx.IsPrimaryKey() {
+			continue
+		}
+		if indexContent(from) != indexContent(idx) && sortedIndexContent(from) == sortedIndexContent(idx) {
+			reorders[indexName(idx)] = &indexReorder{from: from, to: idx}
+		}
+	}
+	return reorders
+}
+
+// markReordered marks the change that drops or adds the index as part
+// of the rebuild of an index whose columns were reordered. The drop,
+// which comes first, also explains why.
+func (ctx *alterCtx) markReordered(c *change, idx model.Index, drop bool) {
+	r, ok := ctx.reorders[indexName(idx)]
+	if !ok || (drop && r.from != idx) || (!drop && r.to != idx) {
+		return
+	}
+	c.reordered = true
+	if drop {
+		c.note = r.note(ctx.from.Name())
+	}
+}
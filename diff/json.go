@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/option"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Change describes a single statement generated by the diff, as
+// written by WithJSON
+type Change struct {
+	// Table is the name of the table or view that is changed, which is
+	// the table of a changed trigger, or the name of a changed procedure
+	// or function
+	Table string `json:"table"`
+	// Object is the kind of object that is changed: "table",
+	// "table_options", "column", "index", "foreign_key", "histogram",
+	// "partitioning", "partition", "view", "trigger", "procedure", or
+	// "function"
+	Object string `json:"object"`
+	// Name is the name of the changed object. It is empty when the
+	// object is the table itself or its partitioning, or a view, a
+	// procedure or a function, whose name is Table, and lists the names
+	// separated by commas when several partitions are changed
+	Name string `json:"name,omitempty"`
+	// Action is what is done to the object: "create", "drop", "add",
+	// "modify", "move", "rename", "update", "reorganize", "coalesce",
+	// or "replace"
+	Action string `json:"action"`
+	// SQL is the statement that applies the change
+	SQL string `json:"sql"`
+	// Destructive is true if applying the statement may lose data
+	Destructive bool `json:"destructive"`
+	// TypeChange classifies the change of the definition of a column,
+	// for the "modify" and "move" actions on columns. It is empty for
+	// other changes
+	TypeChange TypeChange `json:"type_change,omitempty"`
+	// ReorderedIndexColumns is true for the statements that drop and
+	// add an index again only because the order of its columns changed
+	ReorderedIndexColumns bool `json:"reordered_index_columns,omitempty"`
+}
+
+var changeKinds = map[changeKind][2]string{
+	changeTableCreate:     {"table", "create"},
+	changeTableDrop:       {"table", "drop"},
+	changeTableOptions:    {"table_options", "modify"},
+	changeColumnAdd:       {"column", "add"},
+	changeColumnDrop:      {"column", "drop"},
+	changeColumnModify:    {"column", "modify"},
+	changeColumnMove:      {"column", "move"},
+	changeIndexAdd:        {"index", "add"},
+	changeIndexDrop:       {"index", "drop"},
+	changeIndexRename:     {"index", "rename"},
+	changeForeignKeyAdd:   {"foreign_key", "add"},
+	changeForeignKeyDrop:  {"foreign_key", "drop"},
+	changeHistogramUpdate: {"histogram", "update"},
+	changeHistogramDrop:   {"histogram", "drop"},
+
+	changePartitioningModify:  {"partitioning", "modify"},
+	changePartitioningDrop:    {"partitioning", "drop"},
+	changePartitionAdd:        {"partition", "add"},
+	changePartitionDrop:       {"partition", "drop"},
+	changePartitionReorganize: {"partition", "reorganize"},
+	changePartitionCoalesce:   {"partition", "coalesce"},
+
+	changeViewCreate:  {"view", "create"},
+	changeViewReplace: {"view", "replace"},
+	changeViewDrop:    {"view", "drop"},
+
+	changeTriggerCreate:   {"trigger", "create"},
+	changeTriggerDrop:     {"trigger", "drop"},
+	changeProcedureCreate: {"procedure", "create"},
+	changeProcedureDrop:   {"procedure", "drop"},
+	changeFunctionCreate:  {"function", "create"},
+	changeFunctionDrop:    {"function", "drop"},
+}
+
+func (c *change) export() Change {
+	kind := changeKinds[c.kind]
+	return Change{
+		Table:       c.table,
+		Object:      kind[0],
+		Name:        c.name,
+		Action:      kind[1],
+		SQL:         c.sql,
+		Destructive: c.destructive,
+		TypeChange:  c.typeChange,
+
+		ReorderedIndexColumns: c.reordered,
+	}
+}
+
+// Changes compares two model.Stmts and returns the list of changes
+// required to migrate from the old one to the new one, in the order in
+// which the statements would be written by Statements. The options are
+// the same as for Statements. Transaction and FOREIGN_KEY_CHECKS
+// statements are not included.
+func Changes(from, to model.Stmts, options ...Option) ([]Change, error) {
+	var list []Change
+	options = append(options[:len(options):len(options)], option.New(optkeyChanges, &list))
+	if err := Statements(ioutil.Discard, from, to, options...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Stmts compares two model.Stmts, and returns the statements that
+// migrate from the old one to the new one, as written by Statements,
+// along with the list of changes that they apply, as returned by
+// Changes:
+//
+//	sql, changes, err := diff.Stmts(before, after, diff.WithTransaction(false))
+func Stmts(from, to model.Stmts, options ...Option) (string, []Change, error) {
+	var buf bytes.Buffer
+	var list []Change
+	options = append(options[:len(options):len(options)], option.New(optkeyChanges, &list))
+	if err := Statements(&buf, from, to, options...); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), list, nil
+}
+
+func exportChanges(groups []changes) []Change {
+	list := []Change{}
+	for _, changes := range groups {
+		for _, c := range changes {
+			list = append(list, c.export())
+		}
+	}
+	return list
+}
+
+// writeJSON writes the changes as a JSON object, in the order in
+// which the statements would have been written
+func writeJSON(dst io.Writer, groups []changes) error {
+	doc := struct {
+		Changes []Change `json:"changes"`
+	}{
+		Changes: exportChanges(groups),
+	}
+
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, `failed to encode diff`)
+	}
+	buf = append(buf, '\n')
+	if _, err := dst.Write(buf); err != nil {
+		return errors.Wrap(err, `failed to write diff`)
+	}
+	return nil
+}
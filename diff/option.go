@@ -8,8 +8,46 @@ import (
 type Option = schemalex.Option
 
 const (
-	optkeyParser      = "parser"
-	optkeyTransaction = "transaction"
+	optkeyAllowNarrowing      = "allow-narrowing"
+	optkeyCaseInsensitive     = "case-insensitive"
+	optkeyColumnOrder         = "column-order"
+	optkeyComments            = "comments"
+	optkeyDelimiter           = "delimiter"
+	optkeyDependencyOrder     = "dependency-order"
+	optkeyDestructive         = "destructive"
+	optkeyDialect             = "dialect"
+	optkeyExplicitRowFormat   = "explicit-row-format"
+	optkeyForeignKeyChecks    = "foreign-key-checks"
+	optkeyHistograms          = "histograms"
+	optkeyIfNotExists         = "if-not-exists"
+	optkeyIgnoreColumns       = "ignore-columns"
+	optkeyIgnoreFKSymbols     = "ignore-generated-symbols"
+	optkeyIgnoreTableOptions  = "ignore-table-options"
+	optkeyIgnoreTables        = "ignore-tables"
+	optkeySummary             = "summary"
+	optkeyJSON                = "json"
+	optkeyOnlyTables          = "only-tables"
+	optkeyMySQLVersion        = "mysql-version"
+	optkeyNormalizeTimestamp  = "normalize-current-timestamp"
+	optkeyChanges             = "changes"
+	optkeyColor               = "color"
+	optkeyServerCharset       = "server-charset"
+	optkeyDisplayWidth        = "display-width"
+	optkeyIgnoreDisplayWidth  = "ignore-display-width"
+	optkeyRenameIndexes       = "rename-indexes"
+	optkeySeparateForeignKeys = "separate-foreign-keys"
+	optkeyParser              = "parser"
+	optkeySingleLine          = "single-line"
+	optkeySortByName          = "sort-by-name"
+	optkeyStartTransaction    = "start-transaction"
+	optkeyTable               = "table"
+	optkeyTableOptions        = "table-options"
+	optkeyTemporaryTables     = "temporary-tables"
+	optkeyTransaction         = "transaction"
+	optkeyTrimComments        = "trim-comments"
+	optkeyUnified             = "unified"
+	optkeyUpstreamCompat      = "upstream-compat"
+	optkeyWarningHandler      = "warning-handler"
 )
 
 // WithParser specifies the parser instance to use when parsing
@@ -19,8 +57,345 @@ func WithParser(p *schemalex.Parser) Option {
 	return option.New(optkeyParser, p)
 }
 
+// WithDialect specifies the dialect of the statements, which are parsed
+// with schemalex.WithDialect unless WithParser is given. Histograms are
+// not managed for MariaDB and TiDB, and WithMySQLVersion is ignored.
+func WithDialect(d schemalex.Dialect) Option {
+	return schemalex.WithDialect(d)
+}
+
+// WithMySQLVersion specifies the version of the MySQL server that the
+// diff is applied to. Statements are parsed with
+// schemalex.WithMySQLVersion, unless WithParser is given, and
+// statements that the server does not support are avoided: indexes are
+// not renamed before 5.7, and histograms are not managed before 8.0.
+// Unless specified otherwise, columns are compared using the default
+// character set of the server, and display widths are ignored from
+// 8.0.19 on.
+func WithMySQLVersion(v schemalex.MySQLVersion) Option {
+	return schemalex.WithMySQLVersion(v)
+}
+
+// WithUpstreamCompat specifies if the output should be that of
+// schemalex/schemalex, for code that uses this package as a drop-in
+// replacement. Statements are parsed with schemalex.WithUpstreamCompat,
+// unless WithParser is given, so that indexes declared without a name
+// are written without one, and the columns of the primary key are only
+// NOT NULL if they are declared so. Storage engines are not compared,
+// as with WithIgnoreTableOptions("ENGINE").
+//
+// The options that are specific to this package remain available, and
+// only differ from upstream when they are given.
+func WithUpstreamCompat(b bool) Option {
+	return option.New(optkeyUpstreamCompat, b)
+}
+
 // WithTransaction specifies if statements to control transactions
 // should be included in the diff.
 func WithTransaction(b bool) Option {
 	return option.New(optkeyTransaction, b)
 }
+
+// WithStartTransaction specifies if statements should be wrapped in
+// `START TRANSACTION` and `COMMIT`, instead of the `BEGIN` and `COMMIT`
+// used by WithTransaction. Enabling it implies WithTransaction(true).
+//
+// Note that MySQL implicitly commits most DDL statements, so the
+// transaction only protects the statements from being applied
+// partially on databases that support transactional DDL.
+func WithStartTransaction(b bool) Option {
+	return option.New(optkeyStartTransaction, b)
+}
+
+// WithDelimiter specifies the string that terminates each statement.
+// If unspecified, statements are terminated by a semicolon. No
+// `DELIMITER` statement is generated, so the runner that the output
+// is given to must already expect the delimiter.
+func WithDelimiter(s string) Option {
+	return option.New(optkeyDelimiter, s)
+}
+
+// WithSingleLine specifies if each statement should be written on
+// a single line. When enabled, statements such as CREATE TABLE are
+// not pretty-printed, and statements are separated by a single line
+// break instead of being grouped by blank lines.
+func WithSingleLine(b bool) Option {
+	return option.New(optkeySingleLine, b)
+}
+
+// WithDisableForeignKeyChecks specifies if the statements should be
+// surrounded by `SET FOREIGN_KEY_CHECKS = 0` and `SET FOREIGN_KEY_CHECKS = 1`.
+// Statements are always ordered so that referenced tables are created
+// before, and dropped after, the tables that reference them, but this
+// allows the diff to be applied to databases whose data does not satisfy
+// the constraints yet. If unspecified, foreign key checks are disabled
+// only when WithTransaction is enabled.
+func WithDisableForeignKeyChecks(b bool) Option {
+	return option.New(optkeyForeignKeyChecks, b)
+}
+
+// WithIfNotExists specifies if the generated CREATE TABLE statements
+// should always (true) or never (false) include IF NOT EXISTS. By
+// default, they include it when the tables of the new schema do.
+func WithIfNotExists(b bool) Option {
+	return option.New(optkeyIfNotExists, b)
+}
+
+// WithColumnOrder specifies if the physical order of existing columns
+// should be preserved. When enabled, columns whose position differs
+// from the new schema are moved using `MODIFY COLUMN ... AFTER`
+// (or `FIRST`) statements.
+func WithColumnOrder(b bool) Option {
+	return option.New(optkeyColumnOrder, b)
+}
+
+// WithDestructive specifies what to do with destructive statements,
+// i.e. those that may lose data when applied: DROP TABLE, DROP COLUMN,
+// column changes that narrow the type, and column changes that add
+// a NOT NULL constraint. By default they are generated like any other
+// statement.
+func WithDestructive(p DestructivePolicy) Option {
+	return option.New(optkeyDestructive, p)
+}
+
+// WithDropGuard specifies if destructive statements should be refused,
+// which is the same as WithDestructive(DestructiveRefuse) when true, and
+// WithDestructive(DestructiveAllow) when false.
+func WithDropGuard(b bool) Option {
+	if b {
+		return WithDestructive(DestructiveRefuse)
+	}
+	return WithDestructive(DestructiveAllow)
+}
+
+// WithAllowNarrowing specifies if the column changes classified as
+// TypeChangeNarrowing, such as shortening a VARCHAR or adding a NOT NULL
+// constraint, should be exempt from the policy given by
+// WithDestructive. They are still reported as destructive by Changes
+// and Summarize. Incompatible type changes, such as VARCHAR to INT, are
+// never exempt.
+func WithAllowNarrowing(b bool) Option {
+	return option.New(optkeyAllowNarrowing, b)
+}
+
+// WithHistograms specifies if the histograms declared with
+// `ANALYZE TABLE ... UPDATE HISTOGRAM` statements should be managed.
+// When enabled, histograms are updated after the columns they describe
+// are added or changed, and dropped when they are no longer declared.
+func WithHistograms(b bool) Option {
+	return option.New(optkeyHistograms, b)
+}
+
+// WithCaseInsensitiveNames specifies if the names of tables, columns,
+// and indexes should be compared case insensitively, as done by MySQL
+// servers with lower_case_table_names set to 1 or 2. This avoids
+// spurious differences between hand-written schemas and the ones
+// dumped from such servers. Objects that only differ in case are
+// treated as the same, and statements use the names of the new schema.
+//
+// Quoted and unquoted identifiers are always treated as equal.
+func WithCaseInsensitiveNames(b bool) Option {
+	return option.New(optkeyCaseInsensitive, b)
+}
+
+// WithServerCharset specifies the default character set and collation
+// of the server that the schema is applied to. When specified, columns
+// that do not declare a character set or collation are compared as if
+// they declared the ones inherited from their table, or from the server
+// if the table does not declare them either, so that they compare equal
+// to columns that declare them explicitly. Either value may be empty,
+// in which case it is derived from the other. DefaultServerCharset
+// returns the defaults of a given MySQL version.
+func WithServerCharset(charset, collation string) Option {
+	return option.New(optkeyServerCharset, [2]string{charset, collation})
+}
+
+// WithIgnoreDisplayWidth specifies if the display widths of integer
+// columns, such as the 11 in INT(11), should be ignored when comparing
+// columns. MySQL 8.0.19 and later do not report display widths, as
+// they do not affect the values that can be stored. Display widths of
+// ZEROFILL columns are still compared.
+func WithIgnoreDisplayWidth(b bool) Option {
+	return option.New(optkeyIgnoreDisplayWidth, b)
+}
+
+// WithNormalizeCurrentTimestamp specifies if the synonyms of
+// CURRENT_TIMESTAMP, such as NOW() and LOCALTIMESTAMP, should be
+// treated as CURRENT_TIMESTAMP when comparing the DEFAULT and ON UPDATE
+// clauses of the columns, as MySQL does. The default is false.
+func WithNormalizeCurrentTimestamp(b bool) Option {
+	return option.New(optkeyNormalizeTimestamp, b)
+}
+
+// WithDisplayWidth specifies if the display widths of integer columns
+// should be included in the generated statements. By default they are
+// included. See format.WithDisplayWidth for details.
+func WithDisplayWidth(b bool) Option {
+	return option.New(optkeyDisplayWidth, b)
+}
+
+// WithRenameIndexes specifies if indexes that only changed their names
+// should be renamed using `RENAME INDEX`, which requires MySQL 5.7 or
+// later. Indexes are matched by their kind, type, and columns. When
+// disabled, such indexes are dropped and added again.
+func WithRenameIndexes(b bool) Option {
+	return option.New(optkeyRenameIndexes, b)
+}
+
+// WithIgnoreGeneratedSymbols specifies if foreign keys declared without
+// a symbol should match the foreign keys whose symbols were generated by
+// the server, such as users_ibfk_1, when their columns and references
+// are the same. This avoids dropping and adding again the foreign keys
+// of hand-written schemas that are compared with dumps. Foreign keys
+// whose symbols are given explicitly are still compared by symbol.
+func WithIgnoreGeneratedSymbols(b bool) Option {
+	return option.New(optkeyIgnoreFKSymbols, b)
+}
+
+// WithSortByName specifies if the statements should be generated in the
+// order of the names of the tables, columns and indexes, rather than in
+// the order in which they appear in the schemas. Either way, the same
+// inputs always produce the same output. Tables are still created after
+// the tables that they reference, and columns are still added after the
+// columns that they follow.
+func WithSortByName(b bool) Option {
+	return option.New(optkeySortByName, b)
+}
+
+// WithDependencyOrder specifies if the tables should be processed so
+// that each table comes after the tables that its foreign keys
+// reference, rather than in the order in which they appear in the
+// schemas, as by model.Stmts.InDependencyOrder. This applies to the
+// statements that alter tables, to the tables written by WithUnified,
+// and to the schemas written by Text. WithSortByName takes precedence.
+func WithDependencyOrder(b bool) Option {
+	return option.New(optkeyDependencyOrder, b)
+}
+
+// WithSeparateForeignKeys specifies if the foreign keys of altered
+// tables should be dropped before, and added after, all other
+// statements, in blocks of their own, rather than along with the other
+// statements of their table. This allows a foreign key to reference a
+// table or column that is created later in the diff. Foreign keys that
+// close a cycle between tables, or that reference columns that are
+// added by the diff, are always deferred this way.
+func WithSeparateForeignKeys(b bool) Option {
+	return option.New(optkeySeparateForeignKeys, b)
+}
+
+// WithTableOptions specifies if table options, such as ENGINE or
+// COMMENT, should be compared. When enabled, options that have been
+// added or changed are set using `ALTER TABLE ... KEY = value`.
+func WithTableOptions(b bool) Option {
+	return option.New(optkeyTableOptions, b)
+}
+
+// WithExplicitRowFormat specifies if a row format that is set to
+// DEFAULT, or no longer declared, should be set to the row format that
+// the engine uses by default, such as `ROW_FORMAT = DYNAMIC` for InnoDB,
+// rather than `ROW_FORMAT = DEFAULT`, for use with WithTableOptions.
+// Either way, DEFAULT is equal to the default row format of the engine.
+func WithExplicitRowFormat(b bool) Option {
+	return option.New(optkeyExplicitRowFormat, b)
+}
+
+// WithComments specifies what to do with the changes that only affect
+// the comments of columns, or the COMMENT of tables with
+// WithTableOptions. By default they are generated like any other
+// change.
+func WithComments(p CommentPolicy) Option {
+	return option.New(optkeyComments, p)
+}
+
+// WithTrimComments specifies if the comments of columns and tables
+// should be compared without their leading and trailing whitespace,
+// where an empty comment is the same as no comment.
+func WithTrimComments(b bool) Option {
+	return option.New(optkeyTrimComments, b)
+}
+
+// WithTemporaryTables specifies if TEMPORARY tables should be compared.
+// By default they are, like any other table. When disabled, the
+// TEMPORARY tables of both schemas are excluded from the comparison,
+// as they do not outlive the session that creates them.
+func WithTemporaryTables(b bool) Option {
+	return option.New(optkeyTemporaryTables, b)
+}
+
+// WithIgnoreTables specifies patterns of table names to exclude from
+// the comparison. Patterns are globs, where `*` and `%` match any
+// sequence of characters and `?` matches a single character, such as
+// `tmp_*` or `%_archive`. Patterns surrounded by slashes, such as
+// `/^tmp_[0-9]+$/`, are treated as regular expressions. Matching is
+// case insensitive. This option may be specified multiple times.
+func WithIgnoreTables(patterns ...string) Option {
+	return option.New(optkeyIgnoreTables, patterns)
+}
+
+// WithOnlyTables restricts the comparison to the tables whose names
+// match any of the patterns, which speeds up working on a few tables of
+// a large schema. Tables that also match WithIgnoreTables are excluded.
+// See WithIgnoreTables for the syntax of the patterns. This option may
+// be specified multiple times.
+func WithOnlyTables(patterns ...string) Option {
+	return option.New(optkeyOnlyTables, patterns)
+}
+
+// WithTable restricts the output to the statements that change the
+// table of the given name, such as its foreign keys, as listed by
+// Changes. The other tables are still compared, so that the statements
+// are the same as without the option. This allows to write the changes
+// of each table separately.
+func WithTable(name string) Option {
+	return option.New(optkeyTable, name)
+}
+
+// WithIgnoreColumns specifies patterns of columns to exclude from the
+// comparison of existing tables. Patterns are matched against
+// `table.column`, and glob patterns without a dot, such as
+// `updated_at`, match the column in all tables. See WithIgnoreTables
+// for the syntax of the patterns. Columns of new tables are always
+// created.
+func WithIgnoreColumns(patterns ...string) Option {
+	return option.New(optkeyIgnoreColumns, patterns)
+}
+
+// WithIgnoreTableOptions specifies patterns of table option names,
+// such as `AUTO_INCREMENT` or `COMMENT`, to exclude from the comparison
+// enabled by WithTableOptions. See WithIgnoreTables for the syntax of
+// the patterns.
+func WithIgnoreTableOptions(patterns ...string) Option {
+	return option.New(optkeyIgnoreTableOptions, patterns)
+}
+
+// WithSummary specifies a Summary to be filled with the number of
+// changes of each kind that the generated statements make. Statements
+// that are omitted by WithDestructive(DestructiveSkip) are not counted.
+func WithSummary(s *Summary) Option {
+	return option.New(optkeySummary, s)
+}
+
+// WithJSON specifies if the diff should be written as a JSON object
+// holding the list of changes, instead of SQL statements. Each change
+// is described by a Change. Transaction and FOREIGN_KEY_CHECKS
+// statements are not included.
+func WithJSON(b bool) Option {
+	return option.New(optkeyJSON, b)
+}
+
+// WithUnified specifies if the diff should be written as a unified diff
+// of the CREATE TABLE statements of the tables that change, instead of
+// the statements that migrate them, which is easier to review. Only the
+// tables that have changes are written, but their statements are
+// written whole, including the columns and table options that are
+// ignored by the comparison.
+func WithUnified(b bool) Option {
+	return option.New(optkeyUnified, b)
+}
+
+// WithColor specifies if the output of WithUnified should be colored
+// with ANSI escape sequences, for terminals. Removed lines are red, and
+// added lines are green.
+func WithColor(b bool) Option {
+	return option.New(optkeyColor, b)
+}
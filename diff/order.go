@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"sort"
+
+	"github.com/deckarep/golang-set"
+	"github.com/eihigh/schemalex/model"
+)
+
+// The statements of a diff are generated in the order in which the
+// tables, columns and indexes appear in the schemas, so that the same
+// inputs always produce the same output. With WithSortByName, they are
+// generated in the order of their names instead. Columns that are added
+// are always ordered by their position, as they may follow each other.
+
+// tableIDs returns the IDs of the tables of stmts that are in set, but
+// not in exclude
+func (ctx *diffCtx) tableIDs(stmts model.Stmts, set, exclude mapset.Set) []string {
+	var tables []model.Table
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok || !set.Contains(table.ID()) || (exclude != nil && exclude.Contains(table.ID())) {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	if ctx.sortByName {
+		sort.SliceStable(tables, func(i, j int) bool {
+			return tables[i].Name() < tables[j].Name()
+		})
+	}
+
+	ids := make([]string, len(tables))
+	for i, table := range tables {
+		ids[i] = table.ID()
+	}
+	return ids
+}
+
+// histogramIDs returns the IDs of the histograms of stmts
+func (ctx *diffCtx) histogramIDs(stmts model.Stmts) []string {
+	var list []model.Histogram
+	seen := make(map[string]struct{})
+	for _, stmt := range stmts {
+		h, ok := stmt.(model.Histogram)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[h.ID()]; ok {
+			continue
+		}
+		seen[h.ID()] = struct{}{}
+		list = append(list, h)
+	}
+	if ctx.sortByName {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].ID() < list[j].ID()
+		})
+	}
+
+	ids := make([]string, len(list))
+	for i, h := range list {
+		ids[i] = h.ID()
+	}
+	return ids
+}
+
+// columnIDs returns the IDs of the columns of table that are in set,
+// but not in exclude
+func (ctx *alterCtx) columnIDs(table model.Table, set, exclude mapset.Set) []string {
+	var columns []model.TableColumn
+	for col := range table.Columns() {
+		if !set.Contains(col.ID()) || (exclude != nil && exclude.Contains(col.ID())) {
+			continue
+		}
+		columns = append(columns, col)
+	}
+	if ctx.sortByName {
+		sort.SliceStable(columns, func(i, j int) bool {
+			return columns[i].Name() < columns[j].Name()
+		})
+	}
+
+	ids := make([]string, len(columns))
+	for i, col := range columns {
+		ids[i] = col.ID()
+	}
+	return ids
+}
+
+// indexIDs returns the IDs of the indexes of table that are in set,
+// but not in exclude
+func (ctx *alterCtx) indexIDs(table model.Table, set, exclude mapset.Set) []string {
+	var indexes []model.Index
+	for idx := range table.Indexes() {
+		if !set.Contains(idx.ID()) || (exclude != nil && exclude.Contains(idx.ID())) {
+			continue
+		}
+		indexes = append(indexes, idx)
+	}
+	if ctx.sortByName {
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return indexName(indexes[i]) < indexName(indexes[j])
+		})
+	}
+
+	ids := make([]string, len(indexes))
+	for i, idx := range indexes {
+		ids[i] = idx.ID()
+	}
+	return ids
+}
@@ -0,0 +1,250 @@
+package diff
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// alterTablePartitions changes the partitions of the table. RANGE and
+// LIST partitions are matched by name, so that only the partitions
+// that are added, dropped, or changed are touched, and HASH and KEY
+// partitions are added or coalesced when only their number changes.
+// Other changes of the partitioning repartition the whole table.
+func alterTablePartitions(ctx *alterCtx) (changes, error) {
+	var list changes
+	from, to := ctx.from, ctx.to
+	switch {
+	case !from.HasPartitioning() && !to.HasPartitioning():
+		return nil, nil
+	case !to.HasPartitioning():
+		list.add(changePartitioningDrop, from.Name(), "", "ALTER TABLE "+util.Backquote(from.Name())+" REMOVE PARTITIONING;", false)
+		return list, nil
+	case !from.HasPartitioning():
+		return repartition(ctx)
+	}
+
+	before, after := from.Partitioning(), to.Partitioning()
+	if !samePartitionScheme(before, after) {
+		return repartition(ctx)
+	}
+
+	fromDefs := partitionDefinitions(before)
+	toDefs := partitionDefinitions(after)
+	switch after.Type() {
+	case model.PartitionTypeHash, model.PartitionTypeKey:
+		if len(fromDefs) > 0 || len(toDefs) > 0 {
+			if samePartitionDefinitions(fromDefs, toDefs) && before.PartitionCount() == after.PartitionCount() {
+				return nil, nil
+			}
+			return repartition(ctx)
+		}
+
+		n, err1 := partitionCount(before)
+		m, err2 := partitionCount(after)
+		switch {
+		case err1 != nil || err2 != nil:
+			if before.PartitionCount() == after.PartitionCount() {
+				return nil, nil
+			}
+			return repartition(ctx)
+		case n < m:
+			list.add(changePartitionAdd, from.Name(), "", "ALTER TABLE "+util.Backquote(from.Name())+" ADD PARTITION PARTITIONS "+strconv.Itoa(m-n)+";", false)
+		case n > m:
+			list.add(changePartitionCoalesce, from.Name(), "", "ALTER TABLE "+util.Backquote(from.Name())+" COALESCE PARTITION "+strconv.Itoa(n-m)+";", false)
+		}
+		return list, nil
+	}
+
+	if before.PartitionCount() != after.PartitionCount() {
+		return repartition(ctx)
+	}
+
+	// the partitions that are in both schemas with the same definition
+	// are kept, and split the others into segments, each of which is
+	// replaced on its own. If the kept partitions are not in the same
+	// order, the table is repartitioned instead.
+	kept := make(map[string]struct{})
+	for _, def := range toDefs {
+		if prev, ok := before.LookupDefinition(def.Name()); ok && samePartitionDefinition(prev, def) {
+			kept[def.Name()] = struct{}{}
+		}
+	}
+	fromSegments, fromKept := partitionSegments(fromDefs, kept)
+	toSegments, toKept := partitionSegments(toDefs, kept)
+	if strings.Join(fromKept, ",") != strings.Join(toKept, ",") {
+		return repartition(ctx)
+	}
+
+	isRange := after.Type() == model.PartitionTypeRange
+	for i := range fromSegments {
+		dropped, added := fromSegments[i], toSegments[i]
+		var next model.PartitionDefinition
+		if i < len(toKept) {
+			next, _ = after.LookupDefinition(toKept[i])
+		}
+
+		switch {
+		case len(dropped) == 0 && len(added) == 0:
+			continue
+		case len(added) == 0:
+			list.add(changePartitionDrop, from.Name(), partitionNames(dropped), "ALTER TABLE "+util.Backquote(from.Name())+" DROP PARTITION "+backquotePartitions(dropped)+";", true)
+		case len(dropped) == 0 && (!isRange || next == nil):
+			stmt, err := partitionStatement(from.Name(), "ADD PARTITION", added)
+			if err != nil {
+				return nil, err
+			}
+			list.add(changePartitionAdd, from.Name(), partitionNames(added), stmt, false)
+		case len(dropped) == 0:
+			// RANGE partitions can only be added at the end, so the
+			// following partition is split instead
+			stmt, err := partitionStatement(from.Name(), "REORGANIZE PARTITION "+util.Backquote(next.Name())+" INTO", append(added[:len(added):len(added)], next))
+			if err != nil {
+				return nil, err
+			}
+			list.add(changePartitionReorganize, from.Name(), next.Name(), stmt, false)
+		default:
+			stmt, err := partitionStatement(from.Name(), "REORGANIZE PARTITION "+backquotePartitions(dropped)+" INTO", added)
+			if err != nil {
+				return nil, err
+			}
+			list.add(changePartitionReorganize, from.Name(), partitionNames(dropped), stmt, false)
+		}
+	}
+	return list, nil
+}
+
+// repartition partitions the table again as in the new schema
+func repartition(ctx *alterCtx) (changes, error) {
+	var buf bytes.Buffer
+	buf.WriteString("ALTER TABLE ")
+	buf.WriteString(util.Backquote(ctx.from.Name()))
+	buf.WriteByte(' ')
+	if err := format.SQL(&buf, ctx.to.Partitioning(), format.WithSingleLine(true)); err != nil {
+		return nil, err
+	}
+	buf.WriteByte(';')
+
+	var list changes
+	list.add(changePartitioningModify, ctx.from.Name(), "", buf.String(), false)
+	return list, nil
+}
+
+// samePartitionScheme returns true if both partitionings use the same
+// method on the same expression or columns, regardless of their
+// partitions
+func samePartitionScheme(a, b model.Partitioning) bool {
+	if a.Type() != b.Type() || a.IsLinear() != b.IsLinear() || a.HasColumns() != b.HasColumns() {
+		return false
+	}
+	if strings.Join(partitionColumns(a), ",") != strings.Join(partitionColumns(b), ",") {
+		return false
+	}
+	return strings.Join(strings.Fields(a.Expression()), " ") == strings.Join(strings.Fields(b.Expression()), " ")
+}
+
+func partitionColumns(p model.Partitioning) []string {
+	var columns []string
+	for col := range p.Columns() {
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+func partitionDefinitions(p model.Partitioning) []model.PartitionDefinition {
+	var defs []model.PartitionDefinition
+	for def := range p.Definitions() {
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// partitionCount returns the number of HASH or KEY partitions, which
+// is 1 if it is not specified
+func partitionCount(p model.Partitioning) (int, error) {
+	if !p.HasPartitionCount() {
+		return 1, nil
+	}
+	return strconv.Atoi(p.PartitionCount())
+}
+
+// samePartitionDefinition compares the partitions as they are written
+func samePartitionDefinition(a, b model.PartitionDefinition) bool {
+	var bufA, bufB bytes.Buffer
+	if err := format.SQL(&bufA, a); err != nil {
+		return false
+	}
+	if err := format.SQL(&bufB, b); err != nil {
+		return false
+	}
+	return bufA.String() == bufB.String()
+}
+
+func samePartitionDefinitions(a, b []model.PartitionDefinition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !samePartitionDefinition(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// partitionSegments splits the partitions around the kept ones. It
+// returns the partitions before each kept partition and after the last
+// one, along with the names of the kept partitions in order.
+func partitionSegments(defs []model.PartitionDefinition, kept map[string]struct{}) ([][]model.PartitionDefinition, []string) {
+	segments := [][]model.PartitionDefinition{nil}
+	var names []string
+	for _, def := range defs {
+		if _, ok := kept[def.Name()]; ok {
+			names = append(names, def.Name())
+			segments = append(segments, nil)
+			continue
+		}
+		segments[len(segments)-1] = append(segments[len(segments)-1], def)
+	}
+	return segments, names
+}
+
+func partitionNames(defs []model.PartitionDefinition) string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func backquotePartitions(defs []model.PartitionDefinition) string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = util.Backquote(def.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// partitionStatement writes `ALTER TABLE table clause (definitions)`
+func partitionStatement(table, clause string, defs []model.PartitionDefinition) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("ALTER TABLE ")
+	buf.WriteString(util.Backquote(table))
+	buf.WriteByte(' ')
+	buf.WriteString(clause)
+	buf.WriteString(" (")
+	for i, def := range defs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if err := format.SQL(&buf, def); err != nil {
+			return "", err
+		}
+	}
+	buf.WriteString(");")
+	return buf.String(), nil
+}
@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// indexRename is an index that only changed its name
+type indexRename struct {
+	from model.Index
+	to   model.Index
+}
+
+// indexContent returns a key that describes what the index does,
+// regardless of its name
+func indexContent(idx model.Index) string {
+	var buf bytes.Buffer
+	buf.WriteString(indexKind(idx))
+	for _, key := range indexColumnKeys(idx) {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+	}
+	return buf.String()
+}
+
+// indexKind returns the kind and the type of the index
+func indexKind(idx model.Index) string {
+	var kind string
+	switch {
+	case idx.IsUnique():
+		kind = "UNIQUE"
+	case idx.IsFullText():
+		kind = "FULLTEXT"
+	case idx.IsSpatial():
+		kind = "SPATIAL"
+	default:
+		kind = "INDEX"
+	}
+	switch {
+	case idx.IsBtree():
+		kind += " BTREE"
+	case idx.IsHash():
+		kind += " HASH"
+	}
+	return kind
+}
+
+// indexColumnKeys returns a key for each column of the index, in the
+// order of the index
+func indexColumnKeys(idx model.Index) []string {
+	var keys []string
+	for col := range idx.Columns() {
+		key := col.ID()
+		switch {
+		case col.IsAscending():
+			key += " ASC"
+		case col.IsDescending():
+			key += " DESC"
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// isRenameable returns true if the index can be renamed using
+// `RENAME INDEX`. Primary keys can not be renamed, and foreign keys
+// have to be dropped and added again to change their names.
+func isRenameable(idx model.Index) bool {
+	return idx.HasName() && !idx.IsPrimaryKey() && !idx.IsForeignKey()
+}
+
+// findIndexRenames pairs the indexes that are dropped with the indexes
+// that are added with the same content. If several indexes have the
+// same content, they are paired in the order of their names.
+func findIndexRenames(ctx *alterCtx) []*indexRename {
+	dropped := make(map[string][]model.Index)
+	for _, id := range ctx.fromIndexes.Difference(ctx.toIndexes).ToSlice() {
+		if idx, ok := ctx.from.LookupIndex(id.(string)); ok && isRenameable(idx) {
+			key := indexContent(idx)
+			dropped[key] = append(dropped[key], idx)
+		}
+	}
+
+	added := make(map[string][]model.Index)
+	for _, id := range ctx.toIndexes.Difference(ctx.fromIndexes).ToSlice() {
+		if idx, ok := ctx.to.LookupIndex(id.(string)); ok && isRenameable(idx) {
+			key := indexContent(idx)
+			added[key] = append(added[key], idx)
+		}
+	}
+
+	var renames []*indexRename
+	for key, from := range dropped {
+		to := added[key]
+		sortIndexesByName(from)
+		sortIndexesByName(to)
+		for i := 0; i < len(from) && i < len(to); i++ {
+			renames = append(renames, &indexRename{from: from[i], to: to[i]})
+		}
+	}
+	sort.Slice(renames, func(i, j int) bool {
+		return renames[i].from.Name() < renames[j].from.Name()
+	})
+	return renames
+}
+
+func sortIndexesByName(list []model.Index) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+}
+
+// renameTableIndexes renames the indexes found by findIndexRenames,
+// instead of dropping and adding them again
+func renameTableIndexes(ctx *alterCtx) (changes, error) {
+	var list changes
+	for _, r := range ctx.renames {
+		list.add(changeIndexRename, ctx.from.Name(), r.to.Name(), "ALTER TABLE "+util.Backquote(ctx.from.Name())+" RENAME INDEX "+util.Backquote(r.from.Name())+" TO "+util.Backquote(r.to.Name())+";", false)
+	}
+	return list, nil
+}
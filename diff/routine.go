@@ -0,0 +1,174 @@
+package diff
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// routineKinds lists the kinds of routines in the order that they are
+// created, as procedures may call functions, and triggers may call both
+var routineKinds = []model.RoutineKind{
+	model.RoutineKindFunction,
+	model.RoutineKindProcedure,
+	model.RoutineKindTrigger,
+}
+
+// dropRoutines drops the triggers, procedures and functions that are no
+// longer declared, before anything else, as triggers can not be dropped
+// once their tables are
+func dropRoutines(ctx *diffCtx) (changes, error) {
+	toRoutines := routines(ctx.to)
+
+	var list changes
+	order := ctx.routineOrder(ctx.from)
+	for i := len(order) - 1; i >= 0; i-- {
+		r := order[i]
+		if _, ok := toRoutines[r.ID()]; ok || ctx.ignore.table(routineTable(r)) {
+			continue
+		}
+		list.addRoutineDrop(r)
+	}
+	return list, nil
+}
+
+// createRoutines creates the triggers, procedures and functions that are
+// new once the tables and views have been created and altered, and drops
+// and creates again those whose definitions have changed, as they can
+// not be replaced
+func createRoutines(ctx *diffCtx) (changes, error) {
+	fromRoutines := routines(ctx.from)
+
+	var list changes
+	for _, r := range ctx.routineOrder(ctx.to) {
+		if ctx.ignore.table(routineTable(r)) {
+			continue
+		}
+		if prev, ok := fromRoutines[r.ID()]; ok {
+			if routinesEqual(prev, r) {
+				continue
+			}
+			list.addRoutineDrop(prev)
+		}
+
+		var buf bytes.Buffer
+		if err := format.SQL(&buf, r); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(';')
+		list.add(routineChangeKind(r.Kind(), false), routineTable(r), routineName(r), buf.String(), false)
+		// the body may contain semicolons
+		list[len(list)-1].delimited = true
+	}
+	return list, nil
+}
+
+func (l *changes) addRoutineDrop(r model.Routine) {
+	sql := "DROP " + r.Kind().String() + " " + util.Backquote(r.Name()) + ";"
+	l.add(routineChangeKind(r.Kind(), true), routineTable(r), routineName(r), sql, false)
+}
+
+// routinesEqual returns true if the routines have the same definition,
+// regardless of spaces, comments, and the case and quotes of keywords
+// and identifiers. As with views, DEFINER is only compared when both
+// routines specify it.
+func routinesEqual(a, b model.Routine) bool {
+	if a.Definer() != "" && b.Definer() != "" && normalizeSQL(a.Definer()) != normalizeSQL(b.Definer()) {
+		return false
+	}
+	return normalizeSQL(a.Definition()) == normalizeSQL(b.Definition())
+}
+
+// routineTable returns the name that the changes of the routine are
+// listed under, which is the table of a trigger, or the name of a
+// procedure or a function, as with views
+func routineTable(r model.Routine) string {
+	if r.Kind() == model.RoutineKindTrigger {
+		return r.Table()
+	}
+	return r.Name()
+}
+
+// routineName returns the name of the changed object within the table
+// that the changes of the routine are listed under
+func routineName(r model.Routine) string {
+	if r.Kind() == model.RoutineKindTrigger {
+		return r.Name()
+	}
+	return ""
+}
+
+func routineChangeKind(kind model.RoutineKind, drop bool) changeKind {
+	switch kind {
+	case model.RoutineKindTrigger:
+		if drop {
+			return changeTriggerDrop
+		}
+		return changeTriggerCreate
+	case model.RoutineKindProcedure:
+		if drop {
+			return changeProcedureDrop
+		}
+		return changeProcedureCreate
+	default:
+		if drop {
+			return changeFunctionDrop
+		}
+		return changeFunctionCreate
+	}
+}
+
+func routines(stmts model.Stmts) map[string]model.Routine {
+	m := make(map[string]model.Routine)
+	for _, stmt := range stmts {
+		if r, ok := stmt.(model.Routine); ok {
+			m[r.ID()] = r
+		}
+	}
+	return m
+}
+
+// routineOrder returns the routines of stmts by kind, as listed by
+// routineKinds, and in the order of the schema, or of their names with
+// WithSortByName. The last declaration of a routine wins.
+func (ctx *diffCtx) routineOrder(stmts model.Stmts) []model.Routine {
+	latest := routines(stmts)
+	var list []model.Routine
+	seen := make(map[string]bool)
+	for _, stmt := range stmts {
+		r, ok := stmt.(model.Routine)
+		if !ok || seen[r.ID()] {
+			continue
+		}
+		seen[r.ID()] = true
+		list = append(list, latest[r.ID()])
+	}
+
+	rank := make(map[model.RoutineKind]int, len(routineKinds))
+	for i, kind := range routineKinds {
+		rank[kind] = i
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Kind() != list[j].Kind() {
+			return rank[list[i].Kind()] < rank[list[j].Kind()]
+		}
+		return ctx.sortByName && list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+// blockDelimiter returns the delimiter of the DELIMITER block that the
+// statements are written in, which is $$ unless one of them contains it
+func blockDelimiter(stmts []string) string {
+	d := "$$"
+	for _, sql := range stmts {
+		for strings.Contains(sql, d) {
+			d += "$"
+		}
+	}
+	return d
+}
@@ -0,0 +1,214 @@
+package diff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+type changeKind int
+
+const (
+	changeTableCreate changeKind = iota
+	changeTableDrop
+	changeTableOptions
+	changeColumnAdd
+	changeColumnDrop
+	changeColumnModify
+	changeColumnMove
+	changeIndexAdd
+	changeIndexDrop
+	changeIndexRename
+	changeForeignKeyAdd
+	changeForeignKeyDrop
+	changeHistogramUpdate
+	changeHistogramDrop
+	changePartitioningModify
+	changePartitioningDrop
+	changePartitionAdd
+	changePartitionDrop
+	changePartitionReorganize
+	changePartitionCoalesce
+	changeViewCreate
+	changeViewReplace
+	changeViewDrop
+	changeTriggerCreate
+	changeTriggerDrop
+	changeProcedureCreate
+	changeProcedureDrop
+	changeFunctionCreate
+	changeFunctionDrop
+)
+
+// Summary holds the number of changes of each kind made by a diff
+type Summary struct {
+	TablesCreated       int
+	TablesDropped       int
+	TableOptionsChanged int
+	ColumnsAdded        int
+	ColumnsDropped      int
+	ColumnsModified     int
+	ColumnsMoved        int
+	IndexesAdded        int
+	IndexesDropped      int
+	IndexesRenamed      int
+	ForeignKeysAdded    int
+	ForeignKeysDropped  int
+	HistogramsUpdated   int
+	HistogramsDropped   int
+	PartitionsChanged   int
+	ViewsCreated        int
+	ViewsReplaced       int
+	ViewsDropped        int
+	TriggersCreated     int
+	TriggersDropped     int
+	ProceduresCreated   int
+	ProceduresDropped   int
+	FunctionsCreated    int
+	FunctionsDropped    int
+
+	// Destructive is the number of statements that may lose data.
+	// These are also counted by kind.
+	Destructive int
+}
+
+func (s *Summary) count(c *change) {
+	if c.destructive {
+		s.Destructive++
+	}
+
+	switch c.kind {
+	case changeTableCreate:
+		s.TablesCreated++
+	case changeTableDrop:
+		s.TablesDropped++
+	case changeTableOptions:
+		s.TableOptionsChanged++
+	case changeColumnAdd:
+		s.ColumnsAdded++
+	case changeColumnDrop:
+		s.ColumnsDropped++
+	case changeColumnModify:
+		s.ColumnsModified++
+	case changeColumnMove:
+		s.ColumnsMoved++
+	case changeIndexAdd:
+		s.IndexesAdded++
+	case changeIndexDrop:
+		s.IndexesDropped++
+	case changeIndexRename:
+		s.IndexesRenamed++
+	case changeForeignKeyAdd:
+		s.ForeignKeysAdded++
+	case changeForeignKeyDrop:
+		s.ForeignKeysDropped++
+	case changeHistogramUpdate:
+		s.HistogramsUpdated++
+	case changeHistogramDrop:
+		s.HistogramsDropped++
+	case changePartitioningModify, changePartitioningDrop, changePartitionAdd, changePartitionDrop, changePartitionReorganize, changePartitionCoalesce:
+		s.PartitionsChanged++
+	case changeViewCreate:
+		s.ViewsCreated++
+	case changeViewReplace:
+		s.ViewsReplaced++
+	case changeViewDrop:
+		s.ViewsDropped++
+	case changeTriggerCreate:
+		s.TriggersCreated++
+	case changeTriggerDrop:
+		s.TriggersDropped++
+	case changeProcedureCreate:
+		s.ProceduresCreated++
+	case changeProcedureDrop:
+		s.ProceduresDropped++
+	case changeFunctionCreate:
+		s.FunctionsCreated++
+	case changeFunctionDrop:
+		s.FunctionsDropped++
+	}
+}
+
+// Total returns the total number of changes
+func (s *Summary) Total() int {
+	return s.TablesCreated + s.TablesDropped + s.TableOptionsChanged +
+		s.ColumnsAdded + s.ColumnsDropped + s.ColumnsModified + s.ColumnsMoved +
+		s.IndexesAdded + s.IndexesDropped + s.IndexesRenamed +
+		s.ForeignKeysAdded + s.ForeignKeysDropped +
+		s.HistogramsUpdated + s.HistogramsDropped +
+		s.PartitionsChanged +
+		s.ViewsCreated + s.ViewsReplaced + s.ViewsDropped +
+		s.TriggersCreated + s.TriggersDropped +
+		s.ProceduresCreated + s.ProceduresDropped +
+		s.FunctionsCreated + s.FunctionsDropped
+}
+
+// IsEmpty returns true if there are no changes
+func (s *Summary) IsEmpty() bool {
+	return s.Total() == 0
+}
+
+// String returns a human readable summary, with one line for each
+// kind of change that was made, such as "2 tables created"
+func (s *Summary) String() string {
+	if s.IsEmpty() {
+		return "no changes\n"
+	}
+
+	var buf bytes.Buffer
+	line := func(n int, singular, plural, what string) {
+		if n == 0 {
+			return
+		}
+		buf.WriteString(strconv.Itoa(n))
+		buf.WriteByte(' ')
+		if n == 1 {
+			buf.WriteString(singular)
+		} else {
+			buf.WriteString(plural)
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(what)
+		buf.WriteByte('\n')
+	}
+	line(s.TablesCreated, "table", "tables", "created")
+	line(s.TablesDropped, "table", "tables", "dropped")
+	line(s.TableOptionsChanged, "table's options", "tables' options", "changed")
+	line(s.ColumnsAdded, "column", "columns", "added")
+	line(s.ColumnsDropped, "column", "columns", "dropped")
+	line(s.ColumnsModified, "column", "columns", "modified")
+	line(s.ColumnsMoved, "column", "columns", "moved")
+	line(s.IndexesAdded, "index", "indexes", "added")
+	line(s.IndexesDropped, "index", "indexes", "dropped")
+	line(s.IndexesRenamed, "index", "indexes", "renamed")
+	line(s.ForeignKeysAdded, "foreign key", "foreign keys", "added")
+	line(s.ForeignKeysDropped, "foreign key", "foreign keys", "dropped")
+	line(s.HistogramsUpdated, "histogram", "histograms", "updated")
+	line(s.HistogramsDropped, "histogram", "histograms", "dropped")
+	line(s.PartitionsChanged, "table's partitions", "tables' partitions", "changed")
+	line(s.ViewsCreated, "view", "views", "created")
+	line(s.ViewsReplaced, "view", "views", "replaced")
+	line(s.ViewsDropped, "view", "views", "dropped")
+	line(s.TriggersCreated, "trigger", "triggers", "created")
+	line(s.TriggersDropped, "trigger", "triggers", "dropped")
+	line(s.ProceduresCreated, "procedure", "procedures", "created")
+	line(s.ProceduresDropped, "procedure", "procedures", "dropped")
+	line(s.FunctionsCreated, "function", "functions", "created")
+	line(s.FunctionsDropped, "function", "functions", "dropped")
+	line(s.Destructive, "statement", "statements", "may lose data")
+	return buf.String()
+}
+
+// Summarize compares two model.Stmts and returns the summary of the
+// changes required to migrate from the old one to the new one. The
+// options are the same as for Statements.
+func Summarize(from, to model.Stmts, options ...Option) (*Summary, error) {
+	var s Summary
+	options = append(options[:len(options):len(options)], WithSummary(&s))
+	if err := Statements(ioutil.Discard, from, to, options...); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
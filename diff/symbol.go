@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// hasGeneratedSymbol returns true if the foreign key has no symbol, or
+// a symbol that the server generates for foreign keys declared without
+// one, such as users_ibfk_1 for the table users
+func hasGeneratedSymbol(table string, idx model.Index) bool {
+	if !idx.HasSymbol() {
+		return true
+	}
+	prefix := strings.ToLower(table) + "_ibfk_"
+	sym := strings.ToLower(idx.Symbol())
+	if !strings.HasPrefix(sym, prefix) {
+		return false
+	}
+	_, err := strconv.Atoi(sym[len(prefix):])
+	return err == nil
+}
+
+// foreignKeyContent returns a key that describes what the foreign key
+// does, regardless of its symbol and name
+func foreignKeyContent(idx model.Index) string {
+	var buf bytes.Buffer
+	for col := range idx.Columns() {
+		buf.WriteString(col.ID())
+		buf.WriteByte(' ')
+	}
+	if ref := idx.Reference(); ref != nil {
+		buf.WriteString(ref.ID())
+	}
+	return buf.String()
+}
+
+// implicitIndexes returns the IDs of the indexes that the model adds
+// for the foreign keys of the table whose symbols were generated, which
+// are named after the symbols
+func implicitIndexes(table model.Table) []string {
+	symbols := make(map[string]struct{})
+	for idx := range table.Indexes() {
+		if idx.IsForeignKey() && idx.HasSymbol() && hasGeneratedSymbol(table.Name(), idx) {
+			symbols[idx.Symbol()] = struct{}{}
+		}
+	}
+
+	var ids []string
+	for idx := range table.Indexes() {
+		if !idx.IsNormal() || !idx.HasName() {
+			continue
+		}
+		if _, ok := symbols[idx.Name()]; ok {
+			ids = append(ids, idx.ID())
+		}
+	}
+	return ids
+}
+
+// matchGeneratedSymbols pairs the foreign keys that are dropped with
+// the foreign keys that are added with the same content, when both
+// have no symbol or a generated one, and returns the pairs as they are
+// neither dropped nor added
+func matchGeneratedSymbols(ctx *alterCtx) [][2]model.Index {
+	candidates := func(table model.Table, ids []interface{}) map[string][]model.Index {
+		m := make(map[string][]model.Index)
+		for _, id := range ids {
+			idx, ok := table.LookupIndex(id.(string))
+			if !ok || !idx.IsForeignKey() || !hasGeneratedSymbol(table.Name(), idx) {
+				continue
+			}
+			key := foreignKeyContent(idx)
+			m[key] = append(m[key], idx)
+		}
+		for _, list := range m {
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].Symbol() < list[j].Symbol()
+			})
+		}
+		return m
+	}
+	dropped := candidates(ctx.from, ctx.fromIndexes.Difference(ctx.toIndexes).ToSlice())
+	added := candidates(ctx.to, ctx.toIndexes.Difference(ctx.fromIndexes).ToSlice())
+
+	var pairs [][2]model.Index
+	for key, from := range dropped {
+		to := added[key]
+		for i := 0; i < len(from) && i < len(to); i++ {
+			pairs = append(pairs, [2]model.Index{from[i], to[i]})
+		}
+	}
+	return pairs
+}
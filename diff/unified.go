@@ -0,0 +1,388 @@
+package diff
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ANSI escape sequences used by WithColor
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorCyan   = "\x1b[36m"
+	colorYellow = "\x1b[33m"
+)
+
+// writeUnified writes the CREATE TABLE statements of the tables that
+// change as a unified diff, in the order of the new schema, followed
+// by the dropped tables in the order of the old schema, and by the
+// CREATE VIEW statements of the views that change, and by the CREATE
+// statements of the triggers and stored routines that change. Tables
+// whose statements are the same, such as those whose histograms only
+// change, are skipped.
+func writeUnified(dst io.Writer, ctx *diffCtx, groups []changes, color bool, fmtOptions []format.Option) error {
+	changed := make(map[string]struct{})
+	for _, list := range groups {
+		for _, c := range list {
+			changed[c.table] = struct{}{}
+		}
+	}
+
+	var tables []string
+	add := func(stmts model.Stmts, ids []string) {
+		for _, id := range ids {
+			stmt, ok := stmts.Lookup(id)
+			if !ok {
+				continue
+			}
+			name := stmt.(model.Table).Name()
+			if _, ok := changed[name]; ok {
+				tables = append(tables, name)
+			}
+		}
+	}
+	add(ctx.to, ctx.tableIDs(ctx.to, ctx.toSet, nil))
+	add(ctx.from, ctx.tableIDs(ctx.from, ctx.fromSet, ctx.toSet))
+
+	// views are written after the tables, in the order of the changes
+	seen := make(map[string]struct{}, len(tables))
+	for _, name := range tables {
+		seen[name] = struct{}{}
+	}
+	for _, list := range groups {
+		for _, c := range list {
+			switch c.kind {
+			case changeViewCreate, changeViewReplace, changeViewDrop:
+			default:
+				continue
+			}
+			if _, ok := seen[c.table]; !ok {
+				seen[c.table] = struct{}{}
+				tables = append(tables, c.table)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	write := func(name, from, to string) error {
+		if from == to {
+			return nil
+		}
+
+		ud := difflib.UnifiedDiff{
+			A:        splitLines(from),
+			B:        splitLines(to),
+			FromFile: "a/" + name,
+			ToFile:   "b/" + name,
+			Context:  3,
+		}
+		if from == "" {
+			ud.FromFile = "/dev/null"
+		}
+		if to == "" {
+			ud.ToFile = "/dev/null"
+		}
+
+		text, err := difflib.GetUnifiedDiffString(ud)
+		if err != nil {
+			return errors.Wrap(err, `failed to produce diff`)
+		}
+		if color {
+			text = colorize(text)
+		}
+		buf.WriteString(text)
+		return nil
+	}
+
+	for _, name := range tables {
+		from, err := formatTableNamed(ctx.from, name, fmtOptions)
+		if err != nil {
+			return errors.Wrap(err, `failed to produce diff`)
+		}
+		to, err := formatTableNamed(ctx.to, name, fmtOptions)
+		if err != nil {
+			return errors.Wrap(err, `failed to produce diff`)
+		}
+		if err := write(name, from, to); err != nil {
+			return err
+		}
+	}
+
+	// triggers and stored routines are written last, in the order of
+	// the changes
+	fromRoutines, toRoutines := routines(ctx.from), routines(ctx.to)
+	written := make(map[string]struct{})
+	for _, list := range groups {
+		for _, c := range list {
+			var kind model.RoutineKind
+			switch c.kind {
+			case changeTriggerCreate, changeTriggerDrop:
+				kind = model.RoutineKindTrigger
+			case changeProcedureCreate, changeProcedureDrop:
+				kind = model.RoutineKindProcedure
+			case changeFunctionCreate, changeFunctionDrop:
+				kind = model.RoutineKindFunction
+			default:
+				continue
+			}
+			name := c.name
+			if kind != model.RoutineKindTrigger {
+				name = c.table
+			}
+			id := model.NewRoutine(kind, name, "").ID()
+			if _, ok := written[id]; ok {
+				continue
+			}
+			written[id] = struct{}{}
+
+			from, err := formatRoutine(fromRoutines[id], fmtOptions)
+			if err != nil {
+				return errors.Wrap(err, `failed to produce diff`)
+			}
+			to, err := formatRoutine(toRoutines[id], fmtOptions)
+			if err != nil {
+				return errors.Wrap(err, `failed to produce diff`)
+			}
+			if err := write(name, from, to); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write diff`)
+	}
+	return nil
+}
+
+// Text writes a line based unified diff of the two schemas, as
+// formatted by the format package, so that differences in whitespace,
+// case of keywords, or quotes are not shown. Unlike Statements, the
+// tables are not compared, and the schemas are written whole, which
+// shows what changed textually. Statements are written in the order of
+// the schemas, or with WithSortByName, in the order of the names of
+// the tables, or with WithDependencyOrder, after the tables that they
+// reference. WithColor and WithDisplayWidth are honored, and other
+// options are ignored. Nothing is written if the schemas are the same.
+func Text(dst io.Writer, from, to model.Stmts, options ...Option) error {
+	var color bool
+	var sortByName bool
+	var dependencyOrder bool
+	fmtOptions := []format.Option{format.WithIndent(" ", 2)}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyColor:
+			color = o.Value().(bool)
+		case optkeySortByName:
+			sortByName = o.Value().(bool)
+		case optkeyDependencyOrder:
+			dependencyOrder = o.Value().(bool)
+		case optkeyDisplayWidth:
+			fmtOptions = append(fmtOptions, format.WithDisplayWidth(o.Value().(bool)))
+		}
+	}
+
+	if dependencyOrder {
+		from = from.InDependencyOrder()
+		to = to.InDependencyOrder()
+	}
+
+	fromText, err := formatSchema(from, sortByName, fmtOptions)
+	if err != nil {
+		return errors.Wrap(err, `failed to format "from"`)
+	}
+	toText, err := formatSchema(to, sortByName, fmtOptions)
+	if err != nil {
+		return errors.Wrap(err, `failed to format "to"`)
+	}
+	if fromText == toText {
+		return nil
+	}
+
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(fromText),
+		B:        splitLines(toText),
+		FromFile: "before",
+		ToFile:   "after",
+		Context:  3,
+	})
+	if err != nil {
+		return errors.Wrap(err, `failed to produce diff`)
+	}
+	if color {
+		text = colorize(text)
+	}
+	if _, err := io.WriteString(dst, text); err != nil {
+		return errors.Wrap(err, `failed to write diff`)
+	}
+	return nil
+}
+
+// formatSchema formats the statements, separated by blank lines. With
+// sortByName, the tables are sorted by name, after the other
+// statements, which keep their order.
+func formatSchema(stmts model.Stmts, sortByName bool, options []format.Option) (string, error) {
+	if sortByName {
+		sorted := make(model.Stmts, len(stmts))
+		copy(sorted, stmts)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			ti, iok := sorted[i].(model.Table)
+			tj, jok := sorted[j].(model.Table)
+			if iok && jok {
+				return ti.Name() < tj.Name()
+			}
+			return !iok && jok
+		})
+		stmts = sorted
+	}
+
+	var buf bytes.Buffer
+	for i, stmt := range stmts {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if err := format.SQL(&buf, stmt, options...); err != nil {
+			return "", err
+		}
+		buf.WriteString(";\n")
+	}
+	return buf.String(), nil
+}
+
+// formatTableNamed formats the CREATE TABLE statement of the table
+// named name, or the CREATE VIEW statement of the view named name if
+// there is no such table, or returns an empty string if there is
+// neither
+func formatTableNamed(stmts model.Stmts, name string, options []format.Option) (string, error) {
+	var found model.Stmt
+	for _, stmt := range stmts {
+		switch v := stmt.(type) {
+		case model.Table:
+			if v.Name() == name {
+				found = v
+			}
+		case model.View:
+			if v.Name() == name && found == nil {
+				found = v
+			}
+		}
+		if _, ok := found.(model.Table); ok {
+			break
+		}
+	}
+	if found == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, found, options...); err != nil {
+		return "", err
+	}
+	buf.WriteString(";\n")
+	return buf.String(), nil
+}
+
+// formatRoutine formats the CREATE statement of the routine, or returns
+// an empty string if it is nil
+func formatRoutine(r model.Routine, options []format.Option) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, r, options...); err != nil {
+		return "", err
+	}
+	buf.WriteString(";\n")
+	return buf.String(), nil
+}
+
+// splitLines splits s into lines that end with a newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	return lines[:len(lines)-1]
+}
+
+// colorize colors the lines of a unified diff: the file headers in
+// bold, the hunk headers in cyan, and the removed and added lines in
+// red and green. In unchanged lines, quoted identifiers are shown in
+// yellow, so that the names of the columns and indexes stand out.
+func colorize(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var buf strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		body := strings.TrimSuffix(line, "\n")
+		var color string
+		switch {
+		case strings.HasPrefix(body, "---"), strings.HasPrefix(body, "+++"):
+			color = colorBold
+		case strings.HasPrefix(body, "@@"):
+			color = colorCyan
+		case strings.HasPrefix(body, "-"):
+			color = colorRed
+		case strings.HasPrefix(body, "+"):
+			color = colorGreen
+		}
+		if color == "" {
+			buf.WriteString(highlightIdents(line))
+			continue
+		}
+		buf.WriteString(color)
+		buf.WriteString(body)
+		buf.WriteString(colorReset)
+		buf.WriteString(line[len(body):])
+	}
+	return buf.String()
+}
+
+// highlightIdents colors the identifiers quoted by backquotes. Doubled
+// backquotes within identifiers are kept as part of them.
+func highlightIdents(line string) string {
+	if strings.IndexByte(line, '`') < 0 {
+		return line
+	}
+
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(line, '`')
+		if start < 0 {
+			break
+		}
+		end := start + 1
+		for end < len(line) {
+			if line[end] == '`' {
+				if end+1 < len(line) && line[end+1] == '`' {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		if end >= len(line) {
+			break
+		}
+		buf.WriteString(line[:start])
+		buf.WriteString(colorYellow)
+		buf.WriteString(line[start : end+1])
+		buf.WriteString(colorReset)
+		line = line[end+1:]
+	}
+	buf.WriteString(line)
+	return buf.String()
+}
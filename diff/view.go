@@ -0,0 +1,190 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// dropViews drops the views that are no longer declared, before the
+// tables that they select from are dropped. Views are dropped before
+// the views that they select from.
+func dropViews(ctx *diffCtx) (changes, error) {
+	toViews := views(ctx.to)
+
+	var list changes
+	order := ctx.viewOrder(ctx.from)
+	for i := len(order) - 1; i >= 0; i-- {
+		v := order[i]
+		if _, ok := toViews[v.Name()]; ok || ctx.ignore.table(v.Name()) {
+			continue
+		}
+		list.add(changeViewDrop, v.Name(), "", "DROP VIEW "+util.Backquote(v.Name())+";", false)
+	}
+	return list, nil
+}
+
+// replaceViews creates the views that are new, and replaces the views
+// whose definitions have changed with CREATE OR REPLACE VIEW, once the
+// tables that they select from have been created and altered. Views
+// are created after the views that they select from.
+func replaceViews(ctx *diffCtx) (changes, error) {
+	fromViews := views(ctx.from)
+
+	var list changes
+	for _, v := range ctx.viewOrder(ctx.to) {
+		if ctx.ignore.table(v.Name()) {
+			continue
+		}
+		kind := changeViewCreate
+		if prev, ok := fromViews[v.Name()]; ok {
+			if viewsEqual(prev, v) {
+				continue
+			}
+			kind = changeViewReplace
+		}
+
+		var buf bytes.Buffer
+		if err := format.SQL(&buf, v); err != nil {
+			return nil, err
+		}
+		list.add(kind, v.Name(), "", "CREATE OR REPLACE"+strings.TrimPrefix(buf.String(), "CREATE")+";", false)
+	}
+	return list, nil
+}
+
+// viewsEqual returns true if the views have the same definition. The
+// SELECT statements are compared regardless of spaces, comments, and
+// the case and quotes of keywords and identifiers. ALGORITHM and SQL
+// SECURITY that are not specified are the same as their defaults, and
+// DEFINER is only compared when both views specify it, as it defaults
+// to the user that creates the view.
+func viewsEqual(a, b model.View) bool {
+	if !reflect.DeepEqual(a.Columns(), b.Columns()) {
+		return false
+	}
+	if orDefault(a.Algorithm(), "UNDEFINED") != orDefault(b.Algorithm(), "UNDEFINED") {
+		return false
+	}
+	if orDefault(a.SQLSecurity(), "DEFINER") != orDefault(b.SQLSecurity(), "DEFINER") {
+		return false
+	}
+	if a.Definer() != "" && b.Definer() != "" && normalizeSQL(a.Definer()) != normalizeSQL(b.Definer()) {
+		return false
+	}
+	return a.CheckOption() == b.CheckOption() && normalizeSQL(a.Definition()) == normalizeSQL(b.Definition())
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// normalizeSQL returns the words of s separated by single spaces, with
+// identifiers and keywords in upper case and identifiers quoted, so
+// that `Foo` and foo are the same. Strings are kept as written.
+func normalizeSQL(s string) string {
+	var buf bytes.Buffer
+	tz := schemalex.Tokenize([]byte(s))
+	for tz.Next() {
+		t := tz.Token()
+		switch t.Type {
+		case schemalex.SPACE, schemalex.COMMENT_IDENT:
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		switch t.Type {
+		case schemalex.IDENT, schemalex.BACKTICK_IDENT:
+			buf.WriteString(util.Backquote(strings.ToUpper(t.Value)))
+		case schemalex.SINGLE_QUOTE_IDENT, schemalex.DOUBLE_QUOTE_IDENT:
+			buf.Write(tz.Text())
+		default:
+			buf.WriteString(strings.ToUpper(string(tz.Text())))
+		}
+	}
+	return buf.String()
+}
+
+func views(stmts model.Stmts) map[string]model.View {
+	m := make(map[string]model.View)
+	for _, stmt := range stmts {
+		if v, ok := stmt.(model.View); ok {
+			m[v.Name()] = v
+		}
+	}
+	return m
+}
+
+// viewOrder returns the views of stmts in the order of the schema, or
+// of their names with WithSortByName, where each view comes after the
+// views that it selects from
+func (ctx *diffCtx) viewOrder(stmts model.Stmts) []model.View {
+	var list []model.View
+	byName := make(map[string]model.View)
+	for _, stmt := range stmts {
+		v, ok := stmt.(model.View)
+		if !ok {
+			continue
+		}
+		if _, ok := byName[v.Name()]; ok {
+			// as with views(), the last declaration wins
+			for i := range list {
+				if list[i].Name() == v.Name() {
+					list[i] = v
+				}
+			}
+		} else {
+			list = append(list, v)
+		}
+		byName[v.Name()] = v
+	}
+	if ctx.sortByName {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Name() < list[j].Name()
+		})
+	}
+
+	var order []model.View
+	visited := make(map[string]bool)
+	var visit func(v model.View)
+	visit = func(v model.View) {
+		if visited[v.Name()] {
+			return
+		}
+		visited[v.Name()] = true
+		for _, name := range viewReferences(v) {
+			if dep, ok := byName[name]; ok {
+				visit(dep)
+			}
+		}
+		order = append(order, v)
+	}
+	for _, v := range list {
+		visit(v)
+	}
+	return order
+}
+
+// viewReferences returns the identifiers of the definition of the view,
+// which include the names of the tables and views that it selects from
+func viewReferences(v model.View) []string {
+	var names []string
+	tz := schemalex.Tokenize([]byte(v.Definition()))
+	for tz.Next() {
+		switch t := tz.Token(); t.Type {
+		case schemalex.IDENT, schemalex.BACKTICK_IDENT:
+			names = append(names, t.Value)
+		}
+	}
+	return names
+}
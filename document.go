@@ -0,0 +1,411 @@
+package schemalex
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+	"github.com/eihigh/schemalex/schemasource"
+)
+
+// Document is a source that is parsed again each time it is edited,
+// such as a file open in an editor. The source is split into segments
+// of whole lines, at the ends of the statements, and an edit only parses
+// the segments from the one it changes, up to where the rest of the
+// source is known to parse as before. The statements, errors and
+// warnings of a document are the same as those of parsing the whole
+// source with WithErrorTolerance.
+//
+// The statements that follow an edit are kept, and their positions are
+// updated in place, so that the statements returned before an edit may
+// change. A Document is not safe for concurrent use.
+type Document struct {
+	parser   *Parser
+	src      []byte
+	segments []*segment
+}
+
+// segment is a run of whole lines of the source of a document, which
+// starts where the previous statement ended
+type segment struct {
+	start, end int // offsets in the source, without the byte order mark
+	line       int // line where the segment starts
+	lines      int // number of new lines in the segment
+	// closed is true if the segment is followed by another one, which
+	// is parsed on its own
+	closed bool
+
+	// before is the state of the session before the segment, and
+	// changes, sqlMode and vars make the state after it
+	before  sessionState
+	changes []defChange
+	sqlMode string
+	vars    map[string]string
+	// duplicated is true if the segment reports duplicates, whose
+	// messages refer to the positions of other segments
+	duplicated bool
+
+	stmts    []model.Stmt
+	errs     ParseErrors
+	warnings []Warning
+}
+
+// sessionState identifies the state of a session, on which the parse
+// of the statements that follow depends
+type sessionState struct {
+	sqlMode string
+	vars    string
+	defs    uint64
+}
+
+func (sess *session) state() sessionState {
+	vars := make([]string, 0, len(sess.vars))
+	for name, mode := range sess.vars {
+		vars = append(vars, name+"="+mode)
+	}
+	sort.Strings(vars)
+	return sessionState{
+		sqlMode: sess.sqlMode,
+		vars:    strings.Join(vars, "\n"),
+		defs:    sess.defs.sum,
+	}
+}
+
+// ParseDocument parses src as a Document, which can then be edited. The
+// parse errors do not fail, and are returned by Document.Err instead.
+// Dialects made available by RegisterDialect do not support documents.
+func (p *Parser) ParseDocument(ctx context.Context, src []byte) (*Document, error) {
+	if !isBuiltinDialect(p.dialect) {
+		return nil, errors.Errorf(`dialect %s does not support documents`, p.dialect)
+	}
+
+	d := &Document{parser: p}
+	src = append([]byte(nil), src...)
+	segments, err := d.parse(ctx, src, 0, 0, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	d.src = src
+	d.segments = segments
+	return d, nil
+}
+
+// Edit replaces the bytes of the source from start to end by text, and
+// parses the statements that it changes again. If it fails, such as
+// when the context is canceled, the document is left as it was.
+func (d *Document) Edit(ctx context.Context, start, end int, text []byte) error {
+	if start < 0 || start > end || end > len(d.src) {
+		return errors.Errorf(`invalid edit from %d to %d of a source of %d bytes`, start, end, len(d.src))
+	}
+
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+
+	// the offsets of the segments do not count the byte order mark
+	bom := len(d.src) - len(bytes.TrimPrefix(d.src, byteOrderMark))
+	full := start < bom || len(src)-len(bytes.TrimPrefix(src, byteOrderMark)) != bom
+	segments, err := d.parse(ctx, src, start-bom, end-bom, len(text), full)
+	if err != nil {
+		return err
+	}
+	d.src = src
+	d.segments = segments
+	return nil
+}
+
+// Source returns the source of the document, which must not be
+// modified
+func (d *Document) Source() []byte {
+	return d.src
+}
+
+// Stmts returns the statements of the document, as Parse would
+func (d *Document) Stmts() model.Stmts {
+	var stmts model.Stmts
+	for _, seg := range d.segments {
+		for _, stmt := range seg.stmts {
+			stmts = stmts.Apply(stmt)
+		}
+	}
+	return stmts
+}
+
+// Err returns the parse errors of the document as ParseErrors, or nil
+// if there are none
+func (d *Document) Err() error {
+	var errs ParseErrors
+	for _, seg := range d.segments {
+		errs = append(errs, seg.errs...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Warnings returns the warnings of the document, in order. They are
+// also reported to the handler given by WithWarningHandler as the
+// statements are parsed.
+func (d *Document) Warnings() []Warning {
+	var list []Warning
+	for _, seg := range d.segments {
+		list = append(list, seg.warnings...)
+	}
+	return list
+}
+
+// segmentMove is a segment that is kept after an edit, which is moved
+// once the edit succeeds
+type segmentMove struct {
+	seg   *segment
+	shift int // number of bytes the segment moves by
+	lines int // number of lines the segment moves by
+}
+
+// parse returns the segments of src, which is the source of the
+// document where the bytes from start to end were replaced by n bytes.
+// The segments before the edit are kept, and so are the segments after
+// it once parsing reaches one of them with the same session as before,
+// unless full is true. Sources concatenated from several files are
+// parsed in full, as the positions within the files do not all move
+// along with an edit, and so are the sources of TiDB, whose comments
+// are unwrapped beforehand.
+func (d *Document) parse(ctx context.Context, src []byte, start, end, n int, full bool) ([]*segment, error) {
+	src = bytes.TrimPrefix(src, byteOrderMark)
+	var markers []fileMarker
+	marker := []byte(schemasource.FileMarker)
+	if bytes.Contains(src, marker) || bytes.Contains(d.src, marker) {
+		markers = findFileMarkers(src)
+		full = true
+	}
+	if d.parser.dialect == DialectTiDB {
+		full = true
+	}
+
+	var list []*segment
+	old := d.segments
+	if full {
+		old = nil
+	}
+	for len(old) > 0 && old[0].closed && old[0].end <= start {
+		list = append(list, old[0])
+		old = old[1:]
+	}
+
+	shift := n - (end - start)
+	after := make(map[int]*segment)
+	for _, seg := range old {
+		if seg.start >= end {
+			after[seg.start+shift] = seg
+		}
+	}
+
+	p := *d.parser
+	p.tolerant = true
+	var cur *segment
+	p.warn = func(w Warning) {
+		cur.warnings = append(cur.warnings, w)
+		if d.parser.warn != nil {
+			d.parser.warn(w)
+		}
+	}
+
+	sess := newSession(p.sqlMode)
+	pos, line := 0, 1
+	for _, seg := range list {
+		seg.replay(sess, 0)
+		pos, line = seg.end, seg.line+seg.lines
+	}
+
+	// reusable returns the segment that follows the edit at pos, if it
+	// parses as before at the given line. The columns of the first
+	// line are counted from 1, unlike those of the other lines, so the
+	// segments do not move to or from the first line.
+	reusable := func(pos, line int) (*segment, bool) {
+		seg, ok := after[pos]
+		if !ok || seg.duplicated || seg.before != sess.state() || (seg.line == 1) != (line == 1) {
+			return nil, false
+		}
+		return seg, true
+	}
+	begin := func(pos, line int) {
+		cur = &segment{start: pos, line: line, before: sess.state()}
+		sess.defs.changes = &cur.changes
+		sess.duplicated = false
+	}
+	finish := func(end int, closed bool) {
+		cur.end = end
+		cur.lines = bytes.Count(src[cur.start:end], []byte{'\n'})
+		cur.closed = closed
+		cur.sqlMode = sess.sqlMode
+		cur.vars = copyVars(sess.vars)
+		cur.duplicated = sess.duplicated
+		sess.defs.changes = nil
+		list = append(list, cur)
+	}
+
+	var moves []segmentMove
+	for {
+		seg, ok := reusable(pos, line)
+		for ok {
+			lines := line - seg.line
+			seg.replay(sess, lines)
+			moves = append(moves, segmentMove{seg: seg, shift: shift, lines: lines})
+			list = append(list, seg)
+			if !seg.closed {
+				break
+			}
+			pos, line = seg.end+shift, line+seg.lines
+			seg, ok = reusable(pos, line)
+		}
+		if (seg != nil && !seg.closed) || (pos >= len(src) && len(list) > 0) {
+			break
+		}
+
+		first := len(list)
+		begin(pos, line)
+		var stopped bool
+		err := p.parseFrom(ctx, src, pos, line, markers, sess, func(stmt model.Stmt) error {
+			cur.stmts = append(cur.stmts, stmt)
+			return nil
+		}, func(next, nextLine int) bool {
+			finish(next, true)
+			pos, line = next, nextLine
+			if _, ok := reusable(next, nextLine); ok {
+				stopped = true
+				return false
+			}
+			begin(next, nextLine)
+			return true
+		})
+		if !stopped {
+			finish(len(src), false)
+		}
+		if err != nil {
+			errs, ok := err.(ParseErrors)
+			if !ok {
+				sess.defs.changes = nil
+				return nil, err
+			}
+			assignErrors(list[first:], errs)
+		}
+		if !stopped {
+			break
+		}
+	}
+
+	for _, m := range moves {
+		m.seg.move(src, m.shift, m.lines)
+	}
+	return list, nil
+}
+
+// assignErrors gives the errors to the segments where they were found
+func assignErrors(segments []*segment, errs ParseErrors) {
+	i := 0
+	for _, err := range errs {
+		pe, ok := err.(*parseError)
+		if ok {
+			for i < len(segments)-1 && pe.token.Pos >= segments[i].end {
+				i++
+			}
+		}
+		segments[i].errs = append(segments[i].errs, err)
+	}
+}
+
+// nextLine returns the offset and the line of the beginning of the line
+// that follows, if the parser is between statements and the next token
+// is a space that spans it. The statements from there on are parsed the
+// same on their own, as the parser only read up to the space.
+func (pctx *parseCtx) nextLine() (int, int, bool) {
+	t := pctx.peek()
+	if t.Type != SPACE {
+		return 0, 0, false
+	}
+	i := strings.IndexByte(t.value(pctx.input), '\n')
+	if i < 0 || t.Pos+i+1 >= len(pctx.input) {
+		return 0, 0, false
+	}
+	return t.Pos + i + 1, t.Line + 1, true
+}
+
+// replay applies the segment to the session, as if it was parsed lines
+// below where it was
+func (seg *segment) replay(sess *session, lines int) {
+	for _, c := range seg.changes {
+		c.pos = movePosition(c.pos, lines)
+		sess.defs.apply(c)
+	}
+	sess.sqlMode = seg.sqlMode
+	sess.vars = copyVars(seg.vars)
+	if sess.vars == nil {
+		sess.vars = make(map[string]string)
+	}
+}
+
+// move moves the segment within src, along with the positions of its
+// statements, errors and warnings
+func (seg *segment) move(src []byte, shift, lines int) {
+	seg.start += shift
+	seg.end += shift
+	for _, err := range seg.errs {
+		if pe, ok := err.(*parseError); ok {
+			pe.input = src
+			pe.token.Pos += shift
+			pe.line += lines
+			pe.token.Line += lines
+		}
+	}
+	if lines == 0 {
+		return
+	}
+
+	seg.line += lines
+	for i := range seg.changes {
+		seg.changes[i].pos = movePosition(seg.changes[i].pos, lines)
+	}
+	for _, stmt := range seg.stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		table.SetPosition(movePosition(table.Position(), lines))
+		for col := range table.Columns() {
+			col.SetPosition(movePosition(col.Position(), lines))
+		}
+		for idx := range table.Indexes() {
+			idx.SetPosition(movePosition(idx.Position(), lines))
+		}
+	}
+	for i := range seg.warnings {
+		if seg.warnings[i].Line > 0 {
+			seg.warnings[i].Line += lines
+		}
+	}
+}
+
+func movePosition(pos model.Position, lines int) model.Position {
+	if pos.IsValid() {
+		pos.Line += lines
+	}
+	return pos
+}
+
+// copyVars copies the user variables of a session, or returns nil if
+// there are none
+func copyVars(vars map[string]string) map[string]string {
+	if len(vars) == 0 {
+		return nil
+	}
+	list := make(map[string]string, len(vars))
+	for name, mode := range vars {
+		list[name] = mode
+	}
+	return list
+}
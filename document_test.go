@@ -0,0 +1,213 @@
+package schemalex_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `CREATE TABLE a (
+  id INT NOT NULL PRIMARY KEY,
+  name VARCHAR(20) DEFAULT 'x;y'
+);
+CREATE TABLE b (id INT NOT NULL, CONSTRAINT fk FOREIGN KEY (id) REFERENCES a (id));
+
+SET sql_mode = 'ANSI_QUOTES';
+CREATE TABLE "c" (id INT);
+SET sql_mode = '';
+/* a comment */ CREATE TABLE d (id INT, id INT);
+USE foo;
+DROP TABLE b;
+CREATE TABLE e (id INT) ENGINE = InnoDB
+`
+
+// testDocument checks that the document parses as its source does
+func testDocument(t *testing.T, p *schemalex.Parser, warnings *[]schemalex.Warning, d *schemalex.Document, msg string) bool {
+	*warnings = nil
+	stmts, err := p.Parse(d.Source())
+	if !assert.Equal(t, stmts, d.Stmts(), "statements should match (%s)", msg) {
+		return false
+	}
+	if !assert.Equal(t, fmt.Sprint(err), fmt.Sprint(d.Err()), "errors should match (%s)", msg) {
+		return false
+	}
+	if errs, ok := err.(schemalex.ParseErrors); ok {
+		for i, pe := range d.Err().(schemalex.ParseErrors) {
+			if !assert.Equal(t, errs[i].Excerpt(), pe.Excerpt(), "excerpts should match (%s)", msg) {
+				return false
+			}
+		}
+	}
+	if !assert.Equal(t, *warnings, d.Warnings(), "warnings should match (%s)", msg) {
+		return false
+	}
+	return true
+}
+
+func TestDocument(t *testing.T) {
+	var warnings []schemalex.Warning
+	p := schemalex.NewParser(schemalex.WithErrorTolerance(true), schemalex.WithWarningHandler(func(w schemalex.Warning) {
+		warnings = append(warnings, w)
+	}))
+
+	d, err := schemalex.New().ParseDocument(context.Background(), []byte(document))
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !testDocument(t, p, &warnings, d, "initial") {
+		return
+	}
+
+	edits := []struct {
+		name    string
+		find    string // the text to edit, at its first occurrence
+		replace string
+		insert  bool // replace is inserted before find instead
+	}{
+		{name: "insert a line at the top", find: "CREATE TABLE a", replace: "-- header\n", insert: true},
+		{name: "add a column", find: "  name VARCHAR", replace: "  age INT,\n", insert: true},
+		{name: "break a statement", find: "FOREIGN KEY", replace: "FOREIGN KEYS"},
+		{name: "fix the statement", find: "FOREIGN KEYS", replace: "FOREIGN KEY"},
+		{name: "leave a statement open", find: ");\nCREATE TABLE b", replace: ",\nCREATE TABLE b"},
+		{name: "close the statement", find: ",\nCREATE TABLE b", replace: ");\nCREATE TABLE b"},
+		{name: "remove ANSI_QUOTES", find: "SET sql_mode = 'ANSI_QUOTES';\n", replace: ""},
+		{name: "restore ANSI_QUOTES", find: "CREATE TABLE \"c\"", replace: "SET sql_mode = 'ANSI_QUOTES';\n", insert: true},
+		{name: "fix the duplicate column", find: "id INT, id INT", replace: "id INT, id2 INT"},
+		{name: "duplicate a table", find: "CREATE TABLE e", replace: "CREATE TABLE a (id INT);\n", insert: true},
+		{name: "rename the duplicate", find: "CREATE TABLE a (id INT);", replace: "CREATE TABLE f (id INT);"},
+		{name: "append at the end", find: "ENGINE = InnoDB\n", replace: "ENGINE = InnoDB;\nCREATE TABLE g (id INT);\n"},
+		{name: "remove the drop", find: "DROP TABLE b;\n", replace: ""},
+		{name: "insert lines in the middle", find: "SET sql_mode = '';", replace: "\n\n\n", insert: true},
+	}
+	for _, e := range edits {
+		start := bytes.Index(d.Source(), []byte(e.find))
+		if !assert.True(t, start >= 0, "%q should be in the source (%s)", e.find, e.name) {
+			return
+		}
+		end := start + len(e.find)
+		if e.insert {
+			end = start
+		}
+		if !assert.NoError(t, d.Edit(context.Background(), start, end, []byte(e.replace)), "edit should succeed (%s)", e.name) {
+			return
+		}
+		if !testDocument(t, p, &warnings, d, e.name) {
+			return
+		}
+	}
+}
+
+func TestDocumentRandomEdits(t *testing.T) {
+	var warnings []schemalex.Warning
+	p := schemalex.NewParser(schemalex.WithErrorTolerance(true), schemalex.WithWarningHandler(func(w schemalex.Warning) {
+		warnings = append(warnings, w)
+	}))
+
+	texts := []string{"", "\n", ";", ";\n", "'", "/*", "*/", "-- x\n", "CREATE TABLE z (id INT);\n", "SET sql_mode = 'ANSI';\n", "DROP TABLE a;\n", " id INT,", ")"}
+	for _, src := range []string{document, "\xEF\xBB\xBF" + document} {
+		d, err := schemalex.New().ParseDocument(context.Background(), []byte(src))
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 500; i++ {
+			src := d.Source()
+			start := r.Intn(len(src) + 1)
+			end := start + r.Intn(len(src)-start+1)/8
+			text := texts[r.Intn(len(texts))]
+			if !assert.NoError(t, d.Edit(context.Background(), start, end, []byte(text)), "edit should succeed") {
+				return
+			}
+			if !testDocument(t, p, &warnings, d, fmt.Sprintf("edit %d: %q from %d to %d", i, text, start, end)) {
+				return
+			}
+		}
+	}
+}
+
+// TestDocumentRandomEditErrors edits sources with many errors, whose
+// statements are reused at other offsets, and compares the messages of
+// the errors with those of parsing the source again
+func TestDocumentRandomEditErrors(t *testing.T) {
+	p := schemalex.NewParser(schemalex.WithErrorTolerance(true))
+
+	srcs := []string{
+		"\nCREATE TABLE a (id FOO);\n\nCREATE TABLE b (id INT,,);\nx;\n  CREATE TABLE c (id INT NOT NULL BAR);\n",
+		"CREATE TABLE a (id INT); CREATE TABLE b (id FOO);\nCREATE TABLE c (x INT) ENGINE=;\n\n\nCREATE TABLE d (id INT, KEY (id) FOO);\n",
+	}
+	texts := []string{"", "\n", "\n\n", " ", ";", ";\n", "-- x\n", "y;\n", "CREATE TABLE z (id BAZ);\n", "'", "/*", "*/", "CREATE TABLE aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa (", ")"}
+	for seed := int64(0); seed < 50; seed++ {
+		for _, src := range srcs {
+			d, err := schemalex.New().ParseDocument(context.Background(), []byte(src))
+			if !assert.NoError(t, err, "parse should succeed") {
+				return
+			}
+
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 200; i++ {
+				src := d.Source()
+				start := r.Intn(len(src) + 1)
+				end := start + r.Intn(len(src)-start+1)/8
+				text := texts[r.Intn(len(texts))]
+				if !assert.NoError(t, d.Edit(context.Background(), start, end, []byte(text)), "edit should succeed") {
+					return
+				}
+
+				_, err := p.Parse(d.Source())
+				if !assert.Equal(t, fmt.Sprint(err), fmt.Sprint(d.Err()), "errors should match (seed %d, edit %d: %q from %d to %d)", seed, i, text, start, end) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestDocumentEditError(t *testing.T) {
+	d, err := schemalex.New().ParseDocument(context.Background(), []byte(document))
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	stmts := d.Stmts()
+
+	if !assert.Error(t, d.Edit(context.Background(), 10, 5, nil), "edit out of order should fail") {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if !assert.Equal(t, context.Canceled, d.Edit(ctx, 0, 0, []byte("\n")), "edit should be canceled") {
+		return
+	}
+	if !assert.Equal(t, document, string(d.Source()), "the source should not change") {
+		return
+	}
+	if !assert.Equal(t, stmts, d.Stmts(), "the statements should not change") {
+		return
+	}
+}
+
+func BenchmarkDocumentEdit(b *testing.B) {
+	src := largeSchema(1000)
+	d, err := schemalex.New().ParseDocument(context.Background(), src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// add and remove a blank line in the middle, which moves the
+	// positions of the tables that follow
+	mid := bytes.Index(src[len(src)/2:], []byte("CREATE TABLE")) + len(src)/2
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.Edit(context.Background(), mid, mid, []byte("\n")); err != nil {
+			b.Fatal(err)
+		}
+		if err := d.Edit(context.Background(), mid, mid+1, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
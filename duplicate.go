@@ -0,0 +1,241 @@
+package schemalex
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/option"
+	"github.com/eihigh/schemalex/model"
+)
+
+const optkeyDuplicateErrors = "duplicate-errors"
+
+// WithDuplicateErrors specifies that tables, columns, indexes and
+// foreign key symbols that are defined twice are reported as parse
+// errors, for use with NewParser. By default, they are reported to the
+// handler given by WithWarningHandler, and the statements are parsed
+// as usual. Tables created with IF NOT EXISTS are not reported.
+func WithDuplicateErrors(v bool) Option {
+	return option.New(optkeyDuplicateErrors, v)
+}
+
+// definitions records where the tables and the foreign key symbols,
+// whose names must be unique in the schema, were first defined
+type definitions struct {
+	tables  map[string]model.Position
+	symbols map[string]symbolDefinition
+	// sum is a hash of the names that are defined, regardless of
+	// their positions and of the order they were defined in
+	sum uint64
+	// changes records the changes of the definitions, if it is not
+	// nil, so that a Document can replay them
+	changes *[]defChange
+}
+
+type symbolDefinition struct {
+	table    string
+	position model.Position
+}
+
+// defChange is a change of the definitions, see definitions.apply
+type defChange struct {
+	kind   defChangeKind
+	table  string
+	symbol string
+	pos    model.Position
+}
+
+type defChangeKind int
+
+const (
+	defineTable defChangeKind = iota
+	defineSymbol
+	undefineTable
+	undefineDatabase
+)
+
+func newDefinitions() *definitions {
+	return &definitions{
+		tables:  make(map[string]model.Position),
+		symbols: make(map[string]symbolDefinition),
+	}
+}
+
+// dropTable forgets the table and its foreign key symbols, which can
+// be defined again once the table is dropped
+func (defs *definitions) dropTable(name string) {
+	defs.change(defChange{kind: undefineTable, table: name})
+}
+
+// dropDatabase forgets all the definitions
+func (defs *definitions) dropDatabase() {
+	defs.change(defChange{kind: undefineDatabase})
+}
+
+// change records and applies the change
+func (defs *definitions) change(c defChange) {
+	if defs.changes != nil {
+		*defs.changes = append(*defs.changes, c)
+	}
+	defs.apply(c)
+}
+
+func (defs *definitions) apply(c defChange) {
+	switch c.kind {
+	case defineTable:
+		defs.tables[c.table] = c.pos
+		defs.sum += nameHash("table", c.table)
+	case defineSymbol:
+		defs.symbols[c.symbol] = symbolDefinition{table: c.table, position: c.pos}
+		defs.sum += nameHash("symbol", c.symbol, c.table)
+	case undefineTable:
+		if _, ok := defs.tables[c.table]; ok {
+			delete(defs.tables, c.table)
+			defs.sum -= nameHash("table", c.table)
+		}
+		for sym, def := range defs.symbols {
+			if def.table == c.table {
+				delete(defs.symbols, sym)
+				defs.sum -= nameHash("symbol", sym, def.table)
+			}
+		}
+	case undefineDatabase:
+		defs.tables = make(map[string]model.Position)
+		defs.symbols = make(map[string]symbolDefinition)
+		defs.sum = 0
+	}
+}
+
+func nameHash(names ...string) uint64 {
+	h := fnv.New64a()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// duplicatef reports that the object named by t was first defined at
+// prev, as a parse error or as a warning
+func (pctx *parseCtx) duplicatef(t *Token, prev model.Position, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...) + " (first defined"
+	if prev.File != "" {
+		msg += " in file " + prev.File
+	}
+	msg += " at line " + strconv.Itoa(prev.Line) + " column " + strconv.Itoa(prev.Col) + ")"
+
+	pctx.session.duplicated = true
+	if pctx.dupErrs {
+		return newParseError(pctx, t, "%s", msg)
+	}
+	pctx.warnf(t, "%s", msg)
+	return nil
+}
+
+// checkTable reports a table that was already defined in the schema,
+// and records its definition otherwise
+func (pctx *parseCtx) checkTable(t *Token, table model.Table) error {
+	if table.IsIfNotExists() {
+		return nil
+	}
+	if prev, ok := pctx.defs.tables[table.Name()]; ok {
+		return pctx.duplicatef(t, prev, "duplicate table `%s`", table.Name())
+	}
+	pctx.defs.change(defChange{kind: defineTable, table: table.Name(), pos: pctx.position(t)})
+	return nil
+}
+
+// checkColumn reports a column that is already defined in the table,
+// and a column declared as the primary key of a table that already has
+// one. Column names are case insensitive.
+func (pctx *parseCtx) checkColumn(t *Token, table model.Table, col model.TableColumn) error {
+	for prev := range table.Columns() {
+		if strings.EqualFold(prev.Name(), col.Name()) {
+			return pctx.duplicatef(t, prev.Position(), "duplicate column `%s` in table `%s`", col.Name(), table.Name())
+		}
+	}
+	if isPrimaryColumn(col) {
+		if prev, ok := primaryKeyPosition(table); ok {
+			return pctx.duplicatef(t, prev, "duplicate primary key in table `%s`", table.Name())
+		}
+	}
+	return nil
+}
+
+// isPrimaryColumn returns true if the column is declared as the primary
+// key, with PRIMARY KEY or KEY
+func isPrimaryColumn(col model.TableColumn) bool {
+	return col.IsPrimary() || col.IsKey()
+}
+
+// primaryKeyPosition returns where the primary key of the table was
+// declared, by a column or by the table, if it has one
+func primaryKeyPosition(table model.Table) (model.Position, bool) {
+	for col := range table.Columns() {
+		if isPrimaryColumn(col) {
+			return col.Position(), true
+		}
+	}
+	for idx := range table.Indexes() {
+		if idx.IsPrimaryKey() {
+			return idx.Position(), true
+		}
+	}
+	return model.Position{}, false
+}
+
+// checkIndex reports an index whose name is already used by another
+// index of the table, or a foreign key whose symbol is already used in
+// the schema. Index names and symbols are case insensitive.
+func (pctx *parseCtx) checkIndex(t *Token, table model.Table, index model.Index) error {
+	if index.IsForeignKey() {
+		if !index.HasSymbol() {
+			return nil
+		}
+		sym := strings.ToLower(index.Symbol())
+		if prev, ok := pctx.defs.symbols[sym]; ok {
+			return pctx.duplicatef(t, prev.position, "duplicate foreign key constraint `%s`", index.Symbol())
+		}
+		pctx.defs.change(defChange{kind: defineSymbol, table: table.Name(), symbol: sym, pos: index.Position()})
+		return nil
+	}
+
+	if index.IsPrimaryKey() {
+		for col := range table.Columns() {
+			if isPrimaryColumn(col) {
+				return pctx.duplicatef(t, col.Position(), "duplicate primary key in table `%s`", table.Name())
+			}
+		}
+	}
+
+	name := indexName(index)
+	if name == "" {
+		return nil
+	}
+	for prev := range table.Indexes() {
+		if prev == index || prev.IsForeignKey() || !strings.EqualFold(indexName(prev), name) {
+			continue
+		}
+		if index.IsPrimaryKey() {
+			return pctx.duplicatef(t, prev.Position(), "duplicate primary key in table `%s`", table.Name())
+		}
+		return pctx.duplicatef(t, prev.Position(), "duplicate index `%s` in table `%s`", name, table.Name())
+	}
+	return nil
+}
+
+// indexName returns the name of the index in the table, which is
+// the symbol of the constraint if the index is not named
+func indexName(index model.Index) string {
+	switch {
+	case index.IsPrimaryKey():
+		return "PRIMARY"
+	case index.HasName():
+		return index.Name()
+	case index.HasSymbol():
+		return index.Symbol()
+	}
+	return ""
+}
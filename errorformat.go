@@ -0,0 +1,206 @@
+package schemalex
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+const (
+	optkeyErrorContext = "error-context"
+	optkeyErrorColor   = "error-color"
+)
+
+// WithErrorContext specifies the number of lines that FormatError shows
+// before and after the line of each parse error (default: 2)
+func WithErrorContext(n int) Option {
+	return option.New(optkeyErrorContext, n)
+}
+
+// WithErrorColor specifies if the output of FormatError is colored with
+// ANSI escape sequences, for terminals
+func WithErrorColor(b bool) Option {
+	return option.New(optkeyErrorColor, b)
+}
+
+// ANSI escape sequences used by WithErrorColor
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[1;31m"
+	colorCyan  = "\x1b[36m"
+)
+
+// FormatError renders the parse errors of err like the diagnostics of a
+// compiler: the message and the position of each error, followed by the
+// lines around it, with the offending token underlined:
+//
+//	parse error: unexpected column option IDENT at line 3 column 21
+//	1 | CREATE TABLE foo (
+//	2 |   id INT,
+//	3 |   name TEXT NOT NULL BAR
+//	  |                      ^^^
+//	4 | );
+//
+// err may be a ParseError or ParseErrors, or wrap one of them, in which
+// case the messages of the wrapping errors are left out. Other errors
+// are rendered as their Error method does. The parse errors of dialects
+// made available by RegisterDialect are rendered with their Excerpt.
+func FormatError(err error, options ...Option) string {
+	n := 2
+	var color bool
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyErrorContext:
+			n = o.Value().(int)
+		case optkeyErrorColor:
+			color = o.Value().(bool)
+		}
+	}
+
+	for cause := err; cause != nil; {
+		switch v := cause.(type) {
+		case ParseErrors:
+			var b strings.Builder
+			for i, pe := range v {
+				if i > 0 {
+					b.WriteString("\n\n")
+				}
+				b.WriteString(formatParseError(pe, n, color))
+			}
+			return b.String()
+		case ParseError:
+			return formatParseError(v, n, color)
+		}
+		c, ok := cause.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		cause = c.Cause()
+	}
+	return err.Error()
+}
+
+func formatParseError(err ParseError, n int, color bool) string {
+	paint := func(s, c string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+
+	e, ok := err.(*parseError)
+	if !ok || e.token == nil {
+		s := err.Error()
+		if excerpt := err.Excerpt(); excerpt != "" {
+			s += "\n" + excerpt
+		}
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(paint("parse error:", colorRed))
+	header := " " + e.message
+	if e.file != "" {
+		header += " in file " + e.file
+	}
+	header += " at line " + strconv.Itoa(e.line) + " column " + strconv.Itoa(e.col)
+	if e.eof {
+		header += " (at EOF)"
+	}
+	b.WriteString(paint(header, colorBold))
+
+	src, pos := e.input, e.token.Pos
+	if pos > len(src) {
+		pos = len(src)
+	}
+
+	// the lines of the input around the error, which is on the line
+	// at index errIndex
+	begin := lineStart(src, pos)
+	for i := 0; i < n && begin > 0 && e.line-i > 1; i++ {
+		begin = lineStart(src, begin-1)
+	}
+	end := pos
+	for i := 0; ; i++ {
+		j := bytes.IndexByte(src[end:], '\n')
+		if j < 0 {
+			end = len(src)
+			break
+		}
+		end += j
+		if i == n {
+			break
+		}
+		end++
+	}
+	errIndex := bytes.Count(src[begin:pos], []byte{'\n'})
+	lines := strings.Split(string(src[begin:end]), "\n")
+	if len(lines)-1 > errIndex && lines[len(lines)-1] == "" {
+		// the new line that ends the input
+		lines = lines[:len(lines)-1]
+	}
+
+	first := e.line - errIndex
+	width := len(strconv.Itoa(first + len(lines) - 1))
+	gutter := func(num int) string {
+		s := ""
+		if num > 0 {
+			s = strconv.Itoa(num)
+		}
+		return paint(strings.Repeat(" ", width-len(s))+s+" |", colorCyan)
+	}
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		b.WriteByte('\n')
+		b.WriteString(gutter(first + i))
+		if line != "" {
+			b.WriteByte(' ')
+			b.WriteString(line)
+		}
+		if i != errIndex {
+			continue
+		}
+
+		b.WriteByte('\n')
+		b.WriteString(gutter(0))
+		b.WriteByte(' ')
+		// keep the tabs, so that the underline lines up with the text
+		for _, r := range string(src[lineStart(src, pos):pos]) {
+			if r == '\t' {
+				b.WriteByte('\t')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(paint(strings.Repeat("^", tokenWidth(src, pos)), colorRed))
+	}
+	return b.String()
+}
+
+// lineStart returns the offset of the beginning of the line that
+// contains the byte at offset pos
+func lineStart(src []byte, pos int) int {
+	return bytes.LastIndexByte(src[:pos], '\n') + 1
+}
+
+// tokenWidth returns the number of characters of the token at offset
+// pos, up to the end of its line, and at least 1
+func tokenWidth(src []byte, pos int) int {
+	tz := Tokenize(src[pos:])
+	if !tz.Next() {
+		return 1
+	}
+	text := tz.Text()
+	if i := bytes.IndexAny(text, "\r\n"); i >= 0 {
+		text = text[:i]
+	}
+	if w := utf8.RuneCount(text); w > 0 {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,67 @@
+package schemalex_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatError(t *testing.T) {
+	src := "CREATE TABLE foo (id INT);\n\n\n\n\n\n\n\nCREATE TABLE bar (\n\tid INT,\n\tname TEXT NOT NULL BAR\n);\n"
+	_, err := schemalex.New().Parse([]byte(src))
+	if !assert.Error(t, err, "Parse should fail") {
+		return
+	}
+
+	tests := []struct {
+		options  []schemalex.Option
+		expected string
+	}{
+		{
+			expected: "parse error: unexpected column option IDENT at line 11 column 20\n" +
+				" 9 | CREATE TABLE bar (\n" +
+				"10 | \tid INT,\n" +
+				"11 | \tname TEXT NOT NULL BAR\n" +
+				"   | \t                   ^^^\n" +
+				"12 | );",
+		},
+		{
+			options: []schemalex.Option{schemalex.WithErrorContext(0), schemalex.WithErrorColor(true)},
+			expected: "\x1b[1;31mparse error:\x1b[0m\x1b[1m unexpected column option IDENT at line 11 column 20\x1b[0m\n" +
+				"\x1b[36m11 |\x1b[0m \tname TEXT NOT NULL BAR\n" +
+				"\x1b[36m   |\x1b[0m \t                   \x1b[1;31m^^^\x1b[0m",
+		},
+	}
+	for _, test := range tests {
+		if !assert.Equal(t, test.expected, schemalex.FormatError(err, test.options...), "output should match") {
+			return
+		}
+	}
+
+	// the errors that wrap parse errors are rendered as the parse errors
+	if !assert.Equal(t, schemalex.FormatError(err), schemalex.FormatError(errors.Wrap(err, "failed to parse")), "wrapped error should be rendered the same") {
+		return
+	}
+
+	// the context does not go past the ends of the input
+	_, err = schemalex.NewParser(schemalex.WithErrorTolerance(true)).Parse([]byte("CREATE TABLE a (id FOO);\nCREATE TABLE b (id INT"))
+	expected := "parse error: unsupported type in column specification at line 1 column 20\n" +
+		"1 | CREATE TABLE a (id FOO);\n" +
+		"  |                    ^^^\n" +
+		"2 | CREATE TABLE b (id INT\n" +
+		"\n" +
+		"parse error: unexpected column option EOF at line 2 column 22 (at EOF)\n" +
+		"1 | CREATE TABLE a (id FOO);\n" +
+		"2 | CREATE TABLE b (id INT\n" +
+		"  |                       ^"
+	if !assert.Equal(t, expected, schemalex.FormatError(err), "output should match") {
+		return
+	}
+
+	other := errors.New("not a parse error")
+	if !assert.Equal(t, other.Error(), schemalex.FormatError(other), "other errors should be rendered as is") {
+		return
+	}
+}
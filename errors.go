@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eihigh/schemalex/internal/option"
+	"github.com/eihigh/schemalex/internal/util"
 )
 
 // ParseError is returned from the various `Parse` methods when an
 // invalid or unsupported SQL is found. When stringified, the result
 // will look something like this:
 //
-//    parse error: expected RPAREN at line 3 column 14
-//	      "CREATE TABLE foo " <---- AROUND HERE
+//	   parse error: expected RPAREN at line 3 column 14
+//		      "CREATE TABLE foo " <---- AROUND HERE
+//
+// Tools that present diagnostics can use Token, Expected and Excerpt
+// instead of parsing this string.
 type ParseError interface {
 	error
 	File() string
@@ -19,15 +27,23 @@ type ParseError interface {
 	Col() int
 	Message() string
 	EOF() bool
+	Token() Token
+	Expected() []TokenType
+	Excerpt() string
 }
 
 type parseError struct {
-	file    string
-	context string
-	line    int
-	col     int
-	message string
-	eof     bool
+	file     string
+	line     int
+	col      int
+	message  string
+	eof      bool
+	token    *Token
+	expected []TokenType
+	// words are the identifiers that the parser accepts as keywords in
+	// place of the token, such as the names of the spatial types
+	words []string
+	input []byte
 }
 
 // File returns the file name (if applicable) where the error was encountered
@@ -45,6 +61,35 @@ func (e parseError) EOF() bool { return e.eof }
 // Message returns the actual error message
 func (e parseError) Message() string { return e.message }
 
+// Token returns the offending token. Its position is relative to the
+// parsed input, unlike Line and Col which are relative to File.
+func (e parseError) Token() Token {
+	if e.token == nil {
+		return Token{}
+	}
+	e.token.value(e.input)
+	return *e.token
+}
+
+// Expected returns the types of the tokens that would have been valid
+// in place of the offending token, or nil if they are not known. They
+// include the optional tokens that the parser looked for in its place,
+// which the message may not mention, such as REFERENCES after the
+// columns of a foreign key.
+func (e parseError) Expected() []TokenType { return e.expected }
+
+// Excerpt returns the line where the error was encountered, with a
+// caret pointing at the offending token:
+//
+//	3 |   id INT,,
+//	  |          ^
+func (e parseError) Excerpt() string {
+	if e.token == nil {
+		return ""
+	}
+	return util.Excerpt(e.input, e.token.Pos, e.line)
+}
+
 // Error returns the formatted string representation of this parse error.
 func (e parseError) Error() string {
 	var buf bytes.Buffer
@@ -62,35 +107,156 @@ func (e parseError) Error() string {
 		buf.WriteString(" (at EOF)")
 	}
 	buf.WriteString("\n    ")
-	buf.WriteString(e.context)
+	buf.WriteString(e.context())
 	return buf.String()
 }
 
-func newParseError(ctx *parseCtx, t *Token, msg string, args ...interface{}) error {
-	if len(args) > 0 {
-		msg = fmt.Sprintf(msg, args...)
+// context returns the text that precedes the offending token on its
+// line, up to 40 characters. It is read from the input when the error
+// is reported, as the errors of a Document move along with the edits.
+func (e parseError) context() string {
+	if e.token == nil {
+		return ""
 	}
+	input, pos := e.input, e.token.Pos
 
-	// find the closest newline before t.Pos
+	// find the closest newline before pos
 	var ctxbegin int
-	if i := bytes.LastIndexByte(ctx.input[:t.Pos], '\n'); i > 0 {
-		if len(ctx.input)-1 > i {
+	if i := bytes.LastIndexByte(input[:pos], '\n'); i > 0 {
+		if len(input)-1 > i {
 			ctxbegin = i + 1
 		}
 	}
 
-	// if this is more than 40 chars from t.Pos, truncate it
-	if t.Pos-ctxbegin > 40 {
-		ctxbegin = t.Pos - 40
+	// if this is more than 40 chars from pos, truncate it, without
+	// splitting multi-byte characters
+	begin := pos
+	for n := 0; n < 40 && begin > ctxbegin; n++ {
+		_, w := utf8.DecodeLastRune(input[ctxbegin:begin])
+		begin -= w
+	}
+
+	return fmt.Sprintf(`"%s" <---- AROUND HERE`, input[begin:pos])
+}
+
+// ParseErrors is returned along with the statements that could be
+// parsed, when WithErrorTolerance is specified and some statements
+// could not be parsed
+type ParseErrors []ParseError
+
+// Error returns the errors, separated by new lines
+func (e ParseErrors) Error() string {
+	var buf bytes.Buffer
+	for i, pe := range e {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(pe.Error())
+	}
+	return buf.String()
+}
+
+const optkeyErrorTolerance = "error-tolerance"
+
+// WithErrorTolerance specifies that the parse methods do not stop at
+// the first error, for use with NewParser. The statements that can not
+// be parsed are skipped up to the next semicolon, and their errors are
+// returned as ParseErrors along with the other statements. Dialects
+// made available by RegisterDialect may not support it.
+func WithErrorTolerance(v bool) Option {
+	return option.New(optkeyErrorTolerance, v)
+}
+
+func newParseError(ctx *parseCtx, t *Token, msg string, args ...interface{}) error {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	if t.Type == ILLEGAL {
+		// what the lexer could not read is more helpful to report
+		// than what was expected in its place
+		msg = illegalMessage(t.value(ctx.input))
 	}
 
-	// We're going to append a marker here
+	// if the input was concatenated from several files, report the
+	// position within the file
+	file, line := locate(ctx.markers, t.Pos, t.Line)
 
 	return &parseError{
-		context: fmt.Sprintf(`"%s" <---- AROUND HERE`, ctx.input[ctxbegin:t.Pos]),
-		line:    t.Line,
+		file:    file,
+		line:    line,
 		col:     t.Col,
 		eof:     t.EOF,
 		message: msg,
+		token:   t,
+		input:   ctx.input,
+	}
+}
+
+func illegalMessage(v string) string {
+	switch {
+	case strings.HasPrefix(v, "/*"):
+		return "unterminated comment"
+	case strings.HasPrefix(v, "'"), strings.HasPrefix(v, `"`), strings.HasPrefix(v, "`"):
+		return "unterminated quoted string"
+	default:
+		return fmt.Sprintf("unexpected character %q", v)
+	}
+}
+
+// newExpectedError creates a parse error for a token that is not one
+// of the expected types
+func newExpectedError(ctx *parseCtx, t *Token, expected ...TokenType) error {
+	var buf bytes.Buffer
+	buf.WriteString("expected ")
+	for i, typ := range expected {
+		switch {
+		case i == 0:
+		case i == len(expected)-1:
+			buf.WriteString(" or ")
+		default:
+			buf.WriteString(", ")
+		}
+		buf.WriteString(typ.String())
+	}
+	return withExpected(ctx, newParseError(ctx, t, buf.String()), expected...)
+}
+
+// withExpected sets the types of the tokens that would have been valid
+// in place of the offending token of err, for the errors whose message
+// does not list them, along with the optional ones that the parser
+// looked for there
+// withWords sets the identifiers that the parser accepts as keywords
+// in place of the offending token of err, for Parser.Complete
+func withWords(err error, words ...string) error {
+	if pe, ok := err.(*parseError); ok {
+		pe.words = words
+	}
+	return err
+}
+
+func withExpected(ctx *parseCtx, err error, expected ...TokenType) error {
+	pe, ok := err.(*parseError)
+	if !ok {
+		return err
+	}
+	if ctx.optionalPos != pe.token.Pos || len(ctx.optional) == 0 {
+		pe.expected = expected
+		return err
+	}
+
+	list := append([]TokenType(nil), ctx.optional...)
+	for _, typ := range expected {
+		var found bool
+		for _, v := range list {
+			if v == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, typ)
+		}
 	}
+	pe.expected = list
+	return err
 }
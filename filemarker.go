@@ -0,0 +1,61 @@
+package schemalex
+
+import (
+	"bytes"
+
+	"github.com/eihigh/schemalex/model"
+	"github.com/eihigh/schemalex/schemasource"
+)
+
+// fileMarker records where the contents of a file start in an input
+// that was concatenated from several files, as marked by
+// schemasource.FileMarker
+type fileMarker struct {
+	pos  int    // offset of the marker line in the input
+	line int    // line number of the marker line in the input
+	file string // name of the file that follows the marker
+}
+
+// findFileMarkers returns the file markers in the input, in order
+func findFileMarkers(input []byte) []fileMarker {
+	prefix := []byte(schemasource.FileMarker)
+	if !bytes.Contains(input, prefix) {
+		return nil
+	}
+
+	var markers []fileMarker
+	var pos int
+	for line := 1; pos < len(input); line++ {
+		end := bytes.IndexByte(input[pos:], '\n')
+		if end < 0 {
+			end = len(input) - pos
+		}
+		if l := input[pos : pos+end]; bytes.HasPrefix(l, prefix) {
+			markers = append(markers, fileMarker{
+				pos:  pos,
+				line: line,
+				file: string(bytes.TrimSpace(l[len(prefix):])),
+			})
+		}
+		pos += end + 1
+	}
+	return markers
+}
+
+// locate translates the line number of the token at pos in the
+// concatenated input into the file and the line number within it
+func locate(markers []fileMarker, pos, line int) (string, int) {
+	for i := len(markers) - 1; i >= 0; i-- {
+		if m := markers[i]; m.pos <= pos {
+			return m.file, line - m.line
+		}
+	}
+	return "", line
+}
+
+// position returns the position of the token in the source, within
+// the original file if the input was concatenated from several files
+func (pctx *parseCtx) position(t *Token) model.Position {
+	file, line := locate(pctx.markers, t.Pos, t.Line)
+	return model.Position{File: file, Line: line, Col: t.Col}
+}
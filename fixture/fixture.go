@@ -0,0 +1,338 @@
+// Package fixture generates test data from a schema: INSERT statements
+// that fill each table with rows of sample values, in an order that
+// satisfies the foreign keys. The values honor NOT NULL constraints,
+// defaults, the values of ENUM and SET columns, and the lengths of
+// string columns, and the foreign keys reference the rows inserted into
+// the referenced tables. The statements are meant as scaffolding that
+// tests edit to fit their needs.
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Insert is an INSERT statement that fills a table
+type Insert struct {
+	Table   string
+	Columns []string
+	// Rows holds the values of the columns of each row, as SQL
+	// literals, NULL, or DEFAULT
+	Rows [][]string
+}
+
+// String returns the INSERT statement, without a terminating semicolon
+func (ins Insert) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("INSERT INTO ")
+	buf.WriteString(util.Backquote(ins.Table))
+	buf.WriteString(" (")
+	for i, name := range ins.Columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(util.Backquote(name))
+	}
+	buf.WriteString(") VALUES")
+	for i, row := range ins.Rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString("\n(")
+		buf.WriteString(strings.Join(row, ", "))
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+// Generate returns an INSERT statement for each table declared by the
+// statements, so that each table comes after the tables that it
+// references. If some tables reference each other, they are in schema
+// order instead, and the foreign keys must be disabled to insert them.
+//
+// Each column is given a sample value, unless it has a default, which
+// is used with DEFAULT, or is nullable, which is set to NULL. Columns of
+// the primary keys, and columns referenced by foreign keys, are always
+// given sample values. The columns of the foreign keys are given the values of the referenced
+// rows: the n-th row of a table references the n-th row of the
+// referenced table.
+func Generate(stmts model.Stmts, options ...Option) ([]Insert, error) {
+	rows := 1
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyRows:
+			rows = o.Value().(int)
+		}
+	}
+	if rows < 1 {
+		return nil, errors.Errorf(`invalid number of rows %d`, rows)
+	}
+
+	graph := stmts.Dependencies()
+	tables, err := graph.Sort()
+	if err != nil {
+		tables = graph.Tables()
+	}
+
+	// the referenced columns are given values even if they are
+	// nullable, so that the rows referencing them are complete
+	keys := make(map[reference]bool)
+	for _, table := range tables {
+		for _, ref := range referencedColumns(table) {
+			keys[ref] = true
+		}
+	}
+
+	// values of the rows of the tables inserted so far, by table and
+	// column, for the foreign keys
+	values := make(map[string]map[string][]string)
+	inserts := make([]Insert, 0, len(tables))
+	for _, table := range tables {
+		ins := Insert{Table: table.Name(), Rows: make([][]string, rows)}
+		primary := primaryKeyColumns(table)
+		references := referencedColumns(table)
+
+		columnValues := make(map[string][]string)
+		for col := range table.Columns() {
+			ins.Columns = append(ins.Columns, col.Name())
+			list := make([]string, rows)
+			ref, isReference := references[col.Name()]
+			notNull := col.NullState() == model.NullStateNotNull || col.IsPrimary() || primary[col.Name()] || keys[reference{table: table.Name(), column: col.Name()}]
+			// nullable columns are given DEFAULT NULL by the parser
+			nullDefault := col.HasDefault() && !col.IsQuotedDefault() && strings.EqualFold(col.Default(), "NULL")
+			for i := range list {
+				switch {
+				case isReference:
+					list[i] = referencedValue(values, table.Name(), ref, i, columnValues, col, notNull)
+				case notNull && (!col.HasDefault() || nullDefault || col.IsAutoIncrement()):
+					list[i] = sampleValue(col, i)
+				case col.HasDefault() && !nullDefault:
+					list[i] = "DEFAULT"
+				default:
+					list[i] = "NULL"
+				}
+			}
+			columnValues[col.Name()] = list
+			for i, v := range list {
+				ins.Rows[i] = append(ins.Rows[i], v)
+			}
+		}
+		values[table.Name()] = columnValues
+		inserts = append(inserts, ins)
+	}
+	return inserts, nil
+}
+
+// Write writes the statements returned by Generate, terminated by
+// semicolons and separated by empty lines. If some tables reference
+// each other, the statements are surrounded by SET FOREIGN_KEY_CHECKS =
+// 0 and 1.
+func Write(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	inserts, err := Generate(stmts, options...)
+	if err != nil {
+		return errors.Wrap(err, `failed to generate fixtures`)
+	}
+
+	var blocks []string
+	cyclic := stmts.Dependencies().Cycles() != nil
+	if cyclic {
+		blocks = append(blocks, "SET FOREIGN_KEY_CHECKS = 0;")
+	}
+	for _, ins := range inserts {
+		blocks = append(blocks, ins.String()+";")
+	}
+	if cyclic {
+		blocks = append(blocks, "SET FOREIGN_KEY_CHECKS = 1;")
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	if _, err := io.WriteString(dst, strings.Join(blocks, "\n\n")+"\n"); err != nil {
+		return errors.Wrap(err, `failed to write fixtures`)
+	}
+	return nil
+}
+
+func primaryKeyColumns(table model.Table) map[string]bool {
+	columns := make(map[string]bool)
+	for idx := range table.Indexes() {
+		if !idx.IsPrimaryKey() {
+			continue
+		}
+		for col := range idx.Columns() {
+			columns[col.Name()] = true
+		}
+	}
+	return columns
+}
+
+// reference is a column referenced by a foreign key
+type reference struct {
+	table  string
+	column string
+}
+
+// referencedColumns returns the columns referenced by the foreign keys
+// of the table, by the names of the referencing columns. When a column
+// is part of several foreign keys, the first one is used.
+func referencedColumns(table model.Table) map[string]reference {
+	references := make(map[string]reference)
+	for idx := range table.Indexes() {
+		if !idx.IsForeignKey() || idx.Reference() == nil {
+			continue
+		}
+		var columns, referenced []string
+		for col := range idx.Columns() {
+			columns = append(columns, col.Name())
+		}
+		for col := range idx.Reference().Columns() {
+			referenced = append(referenced, col.Name())
+		}
+		for i, name := range columns {
+			if _, ok := references[name]; ok || i >= len(referenced) {
+				continue
+			}
+			references[name] = reference{table: idx.Reference().TableName(), column: referenced[i]}
+		}
+	}
+	return references
+}
+
+// referencedValue returns the value of the column of the i-th row that
+// the foreign key references. References to the rows of tables that are
+// not inserted yet, such as those that form cycles, are set to NULL if
+// the column is nullable, or to a sample value otherwise.
+func referencedValue(values map[string]map[string][]string, table string, ref reference, i int, current map[string][]string, col model.TableColumn, notNull bool) string {
+	if ref.table == table {
+		// the rows of the table reference themselves
+		if list, ok := current[ref.column]; ok {
+			return list[i]
+		}
+	} else if list, ok := values[ref.table][ref.column]; ok {
+		return list[i]
+	}
+	if !notNull {
+		return "NULL"
+	}
+	return sampleValue(col, i)
+}
+
+// baseTime is the time of the first row of temporal columns. The other
+// rows are one day later each.
+var baseTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// sampleValue returns the sample value of the column for the i-th row.
+// The values of the rows differ, so that they do not conflict with
+// unique indexes.
+func sampleValue(col model.TableColumn, i int) string {
+	n := i + 1
+	switch col.Type() {
+	case model.ColumnTypeBit:
+		return "b'" + strconv.FormatInt(int64(n%2), 2) + "'"
+	case model.ColumnTypeBoolean, model.ColumnTypeBool:
+		return strconv.Itoa(n % 2)
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt,
+		model.ColumnTypeInt, model.ColumnTypeInteger, model.ColumnTypeBigInt,
+		model.ColumnTypeReal, model.ColumnTypeDouble, model.ColumnTypeFloat,
+		model.ColumnTypeDecimal, model.ColumnTypeNumeric:
+		return strconv.Itoa(n)
+	case model.ColumnTypeDate:
+		return util.Singlequote(baseTime.AddDate(0, 0, i).Format("2006-01-02"))
+	case model.ColumnTypeDateTime, model.ColumnTypeTimestamp:
+		return util.Singlequote(baseTime.AddDate(0, 0, i).Format("2006-01-02 15:04:05"))
+	case model.ColumnTypeTime:
+		return util.Singlequote(fmt.Sprintf("%02d:00:00", i%24))
+	case model.ColumnTypeYear:
+		return strconv.Itoa(baseTime.Year() + i)
+	case model.ColumnTypeEnum:
+		return util.Singlequote(pick(col.EnumValues(), i))
+	case model.ColumnTypeSet:
+		return util.Singlequote(pick(col.SetValues(), i))
+	case model.ColumnTypeJSON:
+		return util.Singlequote("{}")
+	case model.ColumnTypeInet4:
+		return util.Singlequote("192.0.2." + strconv.Itoa(n%256))
+	case model.ColumnTypeInet6:
+		return util.Singlequote("2001:db8::" + strconv.FormatInt(int64(n), 16))
+	case model.ColumnTypeUUID:
+		return util.Singlequote("00000000-0000-0000-0000-" + leftPad(strconv.FormatInt(int64(n), 16), 12))
+	case model.ColumnTypeGeometry, model.ColumnTypePoint, model.ColumnTypeLineString,
+		model.ColumnTypePolygon, model.ColumnTypeMultiPoint, model.ColumnTypeMultiLineString,
+		model.ColumnTypeMultiPolygon, model.ColumnTypeGeometryCollection, model.ColumnTypeGeomCollection:
+		return "ST_GeomFromText(" + util.Singlequote(sampleGeometry(col.Type(), n)) + ")"
+	}
+
+	// strings and binary strings are named after the column, and
+	// truncated to fit its length
+	s := col.Name() + "_" + strconv.Itoa(n)
+	if col.HasLength() {
+		if max, err := strconv.Atoi(col.Length().Length()); err == nil && max > 0 {
+			s = truncate(s, max, strconv.Itoa(n))
+		}
+	}
+	return util.Singlequote(s)
+}
+
+// sampleGeometry returns the WKT of a sample value of the spatial
+// type, whose coordinates depend on n
+func sampleGeometry(typ model.ColumnType, n int) string {
+	c := strconv.Itoa(n)
+	point := c + " " + c
+	ring := "(0 0, " + c + " 0, " + point + ", 0 0)"
+	switch typ {
+	case model.ColumnTypeLineString:
+		return "LINESTRING(0 0, " + point + ")"
+	case model.ColumnTypePolygon:
+		return "POLYGON(" + ring + ")"
+	case model.ColumnTypeMultiPoint:
+		return "MULTIPOINT(" + point + ")"
+	case model.ColumnTypeMultiLineString:
+		return "MULTILINESTRING((0 0, " + point + "))"
+	case model.ColumnTypeMultiPolygon:
+		return "MULTIPOLYGON((" + ring + "))"
+	case model.ColumnTypeGeometryCollection, model.ColumnTypeGeomCollection:
+		return "GEOMETRYCOLLECTION(POINT(" + point + "))"
+	}
+	return "POINT(" + point + ")"
+}
+
+// pick returns the i-th value of the channel, cycling through them
+func pick(ch chan string, i int) string {
+	var list []string
+	for v := range ch {
+		list = append(list, v)
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return list[i%len(list)]
+}
+
+// truncate shortens s to max characters, keeping suffix at its end so
+// that the values of the rows still differ
+func truncate(s string, max int, suffix string) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	if len(suffix) >= max {
+		return string([]rune(suffix)[len(suffix)-max:])
+	}
+	return string(runes[:max-len(suffix)]) + suffix
+}
+
+func leftPad(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
@@ -0,0 +1,67 @@
+package fixture_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	stmts, err := schemalex.New().ParseString(`
+CREATE TABLE posts (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  author VARCHAR(8) NOT NULL,
+  status ENUM('draft', 'published') NOT NULL,
+  body TEXT,
+  views INT NOT NULL DEFAULT 0,
+  created_at DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (author) REFERENCES users (name)
+);
+CREATE TABLE users (
+  id INT NOT NULL,
+  name VARCHAR(32) NOT NULL,
+  born DATE,
+  PRIMARY KEY (id),
+  UNIQUE KEY (name)
+);`)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, fixture.Write(&buf, stmts, fixture.WithRows(2)), "fixture.Write should succeed") {
+		return
+	}
+	expect := "INSERT INTO `users` (`id`, `name`, `born`) VALUES\n(1, 'name_1', NULL),\n(2, 'name_2', NULL);\n\n" +
+		"INSERT INTO `posts` (`id`, `author`, `status`, `body`, `views`, `created_at`) VALUES\n" +
+		"(1, 'name_1', 'draft', NULL, DEFAULT, '2000-01-01 00:00:00'),\n" +
+		"(2, 'name_2', 'published', NULL, DEFAULT, '2000-01-02 00:00:00');\n"
+	if !assert.Equal(t, expect, buf.String(), "referenced tables should be filled first") {
+		return
+	}
+}
+
+func TestWriteCycle(t *testing.T) {
+	stmts, err := schemalex.New().ParseString(`
+CREATE TABLE a (id INT NOT NULL, b_id INT, code CHAR(3) NOT NULL, PRIMARY KEY (id), FOREIGN KEY (b_id) REFERENCES b (id));
+CREATE TABLE b (id INT NOT NULL, a_id INT NOT NULL, PRIMARY KEY (id), FOREIGN KEY (a_id) REFERENCES a (id));`)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, fixture.Write(&buf, stmts), "fixture.Write should succeed") {
+		return
+	}
+	expect := "SET FOREIGN_KEY_CHECKS = 0;\n\n" +
+		"INSERT INTO `a` (`id`, `b_id`, `code`) VALUES\n(1, NULL, 'co1');\n\n" +
+		"INSERT INTO `b` (`id`, `a_id`) VALUES\n(1, 1);\n\n" +
+		"SET FOREIGN_KEY_CHECKS = 1;\n"
+	if !assert.Equal(t, expect, buf.String(), "tables that reference each other should be in schema order") {
+		return
+	}
+}
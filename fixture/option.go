@@ -0,0 +1,18 @@
+package fixture
+
+import (
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyRows = "rows"
+)
+
+// WithRows specifies the number of rows inserted into each table. The
+// default is 1
+func WithRows(n int) Option {
+	return option.New(optkeyRows, n)
+}
@@ -2,8 +2,11 @@ package format_test
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/eihigh/schemalex"
 	"github.com/eihigh/schemalex/format"
 	"github.com/eihigh/schemalex/model"
 	"github.com/stretchr/testify/assert"
@@ -33,3 +36,165 @@ func TestFormat(t *testing.T) {
 
 	t.Logf("%s", dst.String())
 }
+
+func TestFormatSingleLine(t *testing.T) {
+	table := model.NewTable("hoge")
+
+	for _, name := range []string{"id", "fuga"} {
+		col := model.NewTableColumn(name)
+		col.SetType(model.ColumnTypeInt)
+		col.SetNullState(model.NullStateNotNull)
+		table.AddColumn(col)
+	}
+
+	index := model.NewIndex(model.IndexKindPrimaryKey, table.ID())
+	index.AddColumns(model.NewIndexColumn("id"))
+	table.AddIndex(index)
+
+	var dst bytes.Buffer
+	if !assert.NoError(t, format.SQL(&dst, table, format.WithIndent(" ", 2), format.WithSingleLine(true)), "format.SQL should succeed") {
+		return
+	}
+
+	if !assert.Equal(t, "CREATE TABLE `hoge` ( `id` INT NOT NULL, `fuga` INT NOT NULL, PRIMARY KEY (`id`) )", dst.String()) {
+		return
+	}
+}
+
+func TestFormatQuoteIdentifiers(t *testing.T) {
+	const src = "CREATE TABLE `rank` (`id` INT NOT NULL, `comment` TEXT, `a b` INT, `x``y` INT, `9lives` INT, PRIMARY KEY (`id`));"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var dst bytes.Buffer
+	if !assert.NoError(t, format.SQL(&dst, stmts[0], format.WithSingleLine(true)), "format.SQL should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TABLE `rank` ( `id` INT (11) NOT NULL, `comment` TEXT, `a b` INT (11) DEFAULT NULL, `x``y` INT (11) DEFAULT NULL, `9lives` INT (11) DEFAULT NULL, PRIMARY KEY (`id`) )", dst.String(), "all identifiers should be quoted by default") {
+		return
+	}
+
+	dst.Reset()
+	if !assert.NoError(t, format.SQL(&dst, stmts[0], format.WithSingleLine(true), format.WithQuoteIdentifiers(false)), "format.SQL should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TABLE `rank` ( id INT (11) NOT NULL, `comment` TEXT, `a b` INT (11) DEFAULT NULL, `x``y` INT (11) DEFAULT NULL, `9lives` INT (11) DEFAULT NULL, PRIMARY KEY (id) )", dst.String(), "only identifiers that need quotes should be quoted") {
+		return
+	}
+
+	dst.Reset()
+	options := []format.Option{format.WithSingleLine(true), format.WithQuoteIdentifiers(false), schemalex.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 7})}
+	if !assert.NoError(t, format.SQL(&dst, stmts[0], options...), "format.SQL should succeed") {
+		return
+	}
+	if !assert.True(t, strings.HasPrefix(dst.String(), "CREATE TABLE rank ("), "RANK is not reserved by MySQL 5.7: %s", dst.String()) {
+		return
+	}
+}
+
+func TestFormatTemporaryTables(t *testing.T) {
+	const src = "CREATE TEMPORARY TABLE `tmp` (`id` INT); CREATE TABLE `t` (`id` INT);"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var dst bytes.Buffer
+	if !assert.NoError(t, format.SQL(&dst, stmts, format.WithSingleLine(true), format.WithTemporaryTables(false)), "format.SQL should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TABLE `t` ( `id` INT (11) DEFAULT NULL )", dst.String(), "TEMPORARY tables should be left out") {
+		return
+	}
+
+	dst.Reset()
+	if !assert.NoError(t, format.SQL(&dst, stmts[0], format.WithSingleLine(true), format.WithTemporaryTables(false)), "format.SQL should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TEMPORARY TABLE `tmp` ( `id` INT (11) DEFAULT NULL )", dst.String(), "a TEMPORARY table given on its own should be written") {
+		return
+	}
+}
+
+func TestFormatDependencyOrder(t *testing.T) {
+	const src = "CREATE TABLE `c` (`p_id` INT, FOREIGN KEY (`p_id`) REFERENCES `p` (`id`)); CREATE TABLE `p` (`id` INT);"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var dst bytes.Buffer
+	if !assert.NoError(t, format.SQL(&dst, stmts, format.WithSingleLine(true), format.WithDependencyOrder(true)), "format.SQL should succeed") {
+		return
+	}
+	if !assert.True(t, strings.Index(dst.String(), "`p` (") < strings.Index(dst.String(), "`c` ("), "referenced tables should be written first: %s", dst.String()) {
+		return
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	const src = "CREATE TABLE foo (id INT NOT NULL, name VARCHAR(20), PRIMARY KEY (id), KEY name (name));\n" +
+		"CREATE TABLE bar (id INT NOT NULL);\n" +
+		"ANALYZE TABLE foo UPDATE HISTOGRAM ON name WITH 8 BUCKETS;"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	for _, stmt := range stmts {
+		var dst bytes.Buffer
+		if !assert.NoError(t, format.SQL(&dst, stmt), "format.SQL should succeed") {
+			return
+		}
+		s, ok := stmt.(fmt.Stringer)
+		if !assert.True(t, ok, "%s should be a Stringer", stmt.ID()) {
+			return
+		}
+		if !assert.Equal(t, dst.String(), s.String(), "String should match format.SQL") {
+			return
+		}
+	}
+
+	table := stmts[0].(model.Table)
+	for col := range table.Columns() {
+		var dst bytes.Buffer
+		n, err := col.WriteTo(&dst)
+		if !assert.NoError(t, err, "WriteTo should succeed") {
+			return
+		}
+		if !assert.Equal(t, int64(dst.Len()), n, "WriteTo should count the bytes") {
+			return
+		}
+		if !assert.Equal(t, dst.String(), col.String(), "String should match WriteTo") {
+			return
+		}
+	}
+
+	expected := "CREATE TABLE `foo` (\n" +
+		"`id` INT (11) NOT NULL,\n" +
+		"`name` VARCHAR (20) DEFAULT NULL,\n" +
+		"PRIMARY KEY (`id`),\n" +
+		"INDEX `name` (`name`)\n" +
+		");\n\n" +
+		"CREATE TABLE `bar` (\n" +
+		"`id` INT (11) NOT NULL\n" +
+		");\n\n" +
+		"ANALYZE TABLE `foo` UPDATE HISTOGRAM ON `name` WITH 8 BUCKETS;\n"
+	if !assert.Equal(t, expected, stmts.String(), "Stmts.String should write a schema file") {
+		return
+	}
+
+	reparsed, err := schemalex.New().ParseString(stmts.String())
+	if !assert.NoError(t, err, "the schema file should be parsed again") {
+		return
+	}
+	if !assert.Equal(t, stmts.String(), reparsed.String(), "the schema should not change") {
+		return
+	}
+}
@@ -9,7 +9,15 @@ import (
 
 type Option = schemalex.Option
 
-const optkeyIndent = "indent"
+const (
+	optkeyDependencyOrder  = "dependency-order"
+	optkeyDisplayWidth     = "display-width"
+	optkeyIndent           = "indent"
+	optkeyMySQLVersion     = "mysql-version"
+	optkeyQuoteIdentifiers = "quote-identifiers"
+	optkeySingleLine       = "single-line"
+	optkeyTemporaryTables  = "temporary-tables"
+)
 
 // WithIndent specifies the indent string to use, and the length.
 // For example, if you specify WithIndent(" " /* single space */, 2), the
@@ -23,3 +31,48 @@ func WithIndent(s string, n int) Option {
 	}
 	return option.New(optkeyIndent, strings.Repeat(s, n))
 }
+
+// WithSingleLine specifies if statements should be formatted on a
+// single line. When enabled, the line breaks that would separate the
+// elements of a multi-line statement, such as the columns of a
+// CREATE TABLE statement, are replaced by spaces, and indents are
+// not used.
+func WithSingleLine(b bool) Option {
+	return option.New(optkeySingleLine, b)
+}
+
+// WithDisplayWidth specifies if the display widths of integer columns,
+// such as the 11 in INT(11), should be written. By default they are.
+// When disabled, the display widths are omitted in the same way as
+// MySQL 8.0.19 and later do: they are kept only for ZEROFILL columns
+// and for TINYINT(1), which is commonly used for boolean values.
+func WithDisplayWidth(b bool) Option {
+	return option.New(optkeyDisplayWidth, b)
+}
+
+// WithQuoteIdentifiers specifies if all identifiers, such as the names
+// of tables and columns, should be quoted with backticks. By default
+// they are, so that the statements never break on reserved words. When
+// disabled, only the identifiers that need quotes are quoted, such as
+// the reserved words of the MySQL version given by
+// schemalex.WithMySQLVersion, or of the latest version if none is
+// given (see schemalex.NeedsQuotes).
+func WithQuoteIdentifiers(b bool) Option {
+	return option.New(optkeyQuoteIdentifiers, b)
+}
+
+// WithTemporaryTables specifies if TEMPORARY tables should be written
+// when formatting a list of statements. By default they are. When
+// disabled, they are left out of the list, although a TEMPORARY table
+// given on its own is still written.
+func WithTemporaryTables(b bool) Option {
+	return option.New(optkeyTemporaryTables, b)
+}
+
+// WithDependencyOrder specifies if the tables of a list of statements
+// should be written so that each table comes after the tables that its
+// foreign keys reference, as by model.Stmts.InDependencyOrder. By
+// default, statements are written in the order of the list.
+func WithDependencyOrder(b bool) Option {
+	return option.New(optkeyDependencyOrder, b)
+}
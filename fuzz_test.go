@@ -0,0 +1,66 @@
+//go:build go1.18
+// +build go1.18
+
+package schemalex
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+var fuzzSeeds = []string{
+	"CREATE TABLE foo (id INT NOT NULL AUTO_INCREMENT, name VARCHAR(255) DEFAULT 'x', PRIMARY KEY (id)) ENGINE=InnoDB;",
+	"CREATE TABLE `foo` (\n  `id` bigint(20) unsigned NOT NULL COMMENT 'the \\'id\\''\n) /*!50100 PARTITION BY HASH (id) */;",
+	"CREATE DATABASE IF NOT EXISTS foo; USE foo; SET NAMES utf8mb4;",
+	"ANALYZE TABLE foo UPDATE HISTOGRAM ON a, b WITH 16 BUCKETS;",
+	"INSERT INTO foo VALUES ('a;b', \"c\");\nCREATE TABLE bar (id INT);",
+	"CREATE TABLE foo (id INT /*T![auto_rand] AUTO_RANDOM(5) */ PRIMARY KEY /*T![clustered_index] CLUSTERED */);",
+	"-- comment\n# comment\n/* comment */ CREATE TABLE foo (a ENUM('x','y'), b SET('z'));",
+	"CREATE TABLE foo (a TEXT DEFAULT 'unterminated",
+	"CREATE TABLE `foo",
+	"CREATE TABLE foo (a INT DEFAULT \"x",
+	"/* unterminated",
+	"CREATE TABLE foo (\x00\x01\x7f\xff)",
+	"CREATE TABLE foo (a DECIMAL(10,",
+}
+
+func FuzzLex(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		l := newLexer(input)
+		// every token consumes at least one byte, except EOF
+		for i := 0; i <= len(input)+1; i++ {
+			tok := l.nextToken()
+			if tok.Pos < 0 || tok.Pos > len(input) {
+				t.Fatalf("token position %d is out of range", tok.Pos)
+			}
+			if tok.Type == EOF {
+				return
+			}
+		}
+		t.Fatalf("lexer did not reach EOF")
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	// the parser must return an error rather than panic, whatever the
+	// input is
+	f.Fuzz(func(t *testing.T, input []byte) {
+		for _, dialect := range []Dialect{DialectMySQL, DialectMariaDB, DialectTiDB} {
+			p := NewParser(WithDialect(dialect))
+			p.Parse(input)
+
+			p = NewParser(WithDialect(dialect), WithErrorTolerance(true))
+			p.Parse(input)
+			p.ParseReader(context.Background(), bytes.NewReader(input), func(model.Stmt) error { return nil })
+		}
+	})
+}
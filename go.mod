@@ -8,4 +8,5 @@ require (
 	github.com/pkg/errors v0.8.1-0.20170910134614-2b3a18b5f0fb
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.4.0
+	gopkg.in/yaml.v2 v2.2.2
 )
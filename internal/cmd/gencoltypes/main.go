@@ -34,6 +34,8 @@ func _main() error {
 		"Real":    "Double",
 		"Bool":    "TinyInt",
 		"Boolean": "TinyInt",
+
+		"GeomCollection": "GeometryCollection",
 	}
 
 	types := []string{
@@ -72,6 +74,18 @@ func _main() error {
 		"Boolean",
 		"Bool",
 		"JSON",
+		"Inet4",
+		"Inet6",
+		"UUID",
+		"Geometry",
+		"Point",
+		"LineString",
+		"Polygon",
+		"MultiPoint",
+		"MultiLineString",
+		"MultiPolygon",
+		"GeometryCollection",
+		"GeomCollection",
 	}
 
 	buf.WriteString(`// generated by internal/cmd/gencoltypes/main.go. DO NOT EDIT`)
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/eihigh/schemalex/corpus"
+)
+
+var (
+	dirName  = flag.String("dir", "corpus/testdata", "name of corpus directory")
+	fileName = flag.String("file", "corpus/README.md", "name of file")
+)
+
+func main() {
+	flag.Parse()
+	if err := _main(); err != nil {
+		log.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+func _main() error {
+	c, err := corpus.Load("schemalex", *dirName)
+	if err != nil {
+		return err
+	}
+
+	// Never document behavior that the diff package does not actually have
+	if errs := c.Check(); len(errs) > 0 {
+		return errs[0]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<!-- generated by internal/cmd/gencorpusdoc/main.go. DO NOT EDIT -->\n\n")
+	buf.WriteString("# Diff semantics\n\n")
+	buf.WriteString("This document describes the statements that schemadiff generates for\n")
+	buf.WriteString("common schema changes. Each section is generated from a case under\n")
+	buf.WriteString("`corpus/testdata`, and every case is verified by `go test`.\n\n")
+	if err := c.WriteMarkdown(&buf); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(*fileName, buf.Bytes(), 0644)
+}
@@ -38,6 +38,11 @@ func _main() error {
 	buf.WriteString("\nLine int")
 	buf.WriteString("\nCol int")
 	buf.WriteString("\nEOF bool")
+	buf.WriteString("\n\n// size is the length of the source text of the token, from")
+	buf.WriteString("\n// which the lexer reads Value on first use unless it is 0. It")
+	buf.WriteString("\n// fits in the padding after EOF, so that tokens are no larger")
+	buf.WriteString("\n// than their exported fields.")
+	buf.WriteString("\nsize int32")
 	buf.WriteString("\n}")
 
 	buf.WriteString("\n\n// NewToken creates a new token of type `t`, with value `v`")
@@ -185,6 +190,22 @@ func _main() error {
 		{Ident: "ASC"},
 		{Ident: "DESC"},
 		{Ident: "NOW"},
+		{Ident: "PARTITION"},
+		{Ident: "PARTITIONS"},
+		{Ident: "BY"},
+		{Ident: "RANGE"},
+		{Ident: "LIST"},
+		{Ident: "LINEAR"},
+		{Ident: "COLUMNS"},
+		{Ident: "VALUES"},
+		{Ident: "LESS"},
+		{Ident: "THAN"},
+		{Ident: "MAXVALUE"},
+		{Ident: "IN"},
+		{Ident: "ANALYZE"},
+		{Ident: "HISTOGRAM"},
+		{Ident: "BUCKETS"},
+		{Ident: "WITH"},
 	}
 
 	for _, tok := range tokens {
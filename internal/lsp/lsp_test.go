@@ -0,0 +1,332 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const schema = `CREATE TABLE users (
+  id INT NOT NULL PRIMARY KEY,
+  name VARCHAR(20)
+);
+
+CREATE TABLE posts (
+  id INT NOT NULL PRIMARY KEY,
+  user_id INT NOT NULL,
+  CONSTRAINT fk FOREIGN KEY (user_id) REFERENCES users (id)
+);
+`
+
+// message is a message that the server sends
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+	Params json.RawMessage `json:"params"`
+}
+
+// serve sends the messages to a server, followed by shutdown and exit,
+// and returns what the server sends back
+func serve(t *testing.T, msgs ...interface{}) ([]message, bool) {
+	var in bytes.Buffer
+	msgs = append(msgs,
+		map[string]interface{}{"jsonrpc": "2.0", "id": 1000, "method": "shutdown"},
+		map[string]interface{}{"jsonrpc": "2.0", "method": "exit"},
+	)
+	for _, msg := range msgs {
+		if !assert.NoError(t, writeMessage(&in, msg), "writeMessage should succeed") {
+			return nil, false
+		}
+	}
+
+	var out bytes.Buffer
+	if !assert.NoError(t, New().Serve(context.Background(), &in, &out), "Serve should succeed") {
+		return nil, false
+	}
+
+	var list []message
+	r := bufio.NewReader(&out)
+	for {
+		buf, err := readMessage(r)
+		if err == io.EOF {
+			return list, true
+		}
+		if !assert.NoError(t, err, "readMessage should succeed") {
+			return nil, false
+		}
+		var msg message
+		if !assert.NoError(t, json.Unmarshal(buf, &msg), "json.Unmarshal should succeed") {
+			return nil, false
+		}
+		list = append(list, msg)
+	}
+}
+
+func call(id int, method string, params interface{}) interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params interface{}) interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func at(line, character int) interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///schema.sql"},
+		"position":     position{Line: line, Character: character},
+	}
+}
+
+func open(src string) interface{} {
+	return notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///schema.sql", "version": 1, "text": src},
+	})
+}
+
+func responseTo(msgs []message, id int) (message, bool) {
+	for _, msg := range msgs {
+		if msg.ID != nil && *msg.ID == id {
+			return msg, true
+		}
+	}
+	return message{}, false
+}
+
+func diagnostics(msgs []message) [][]diagnostic {
+	var list [][]diagnostic
+	for _, msg := range msgs {
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var params publishDiagnosticsParams
+			json.Unmarshal(msg.Params, &params)
+			list = append(list, params.Diagnostics)
+		}
+	}
+	return list
+}
+
+func TestServer(t *testing.T) {
+	msgs, ok := serve(t,
+		call(1, "initialize", map[string]interface{}{}),
+		notify("initialized", map[string]interface{}{}),
+		open(schema),
+		call(2, "textDocument/definition", at(8, 51)), // users
+		call(3, "textDocument/definition", at(8, 58)), // id
+		call(4, "textDocument/hover", at(8, 33)),      // user_id
+		call(5, "textDocument/hover", at(8, 2)),       // CONSTRAINT
+		notify("textDocument/didChange", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": "file:///schema.sql", "version": 2},
+			"contentChanges": []interface{}{
+				map[string]interface{}{"range": textRange{Start: position{Line: 2, Character: 7}, End: position{Line: 2, Character: 14}}, "text": "VARCHA"},
+			},
+		}),
+		call(6, "textDocument/formatting", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": "file:///schema.sql"},
+			"options":      formattingOptions{TabSize: 2, InsertSpaces: true},
+		}),
+		call(7, "textDocument/unknown", map[string]interface{}{}),
+	)
+	if !ok {
+		return
+	}
+
+	res, ok := responseTo(msgs, 1)
+	if !assert.True(t, ok, "initialize should respond") {
+		return
+	}
+	if !assert.Contains(t, string(res.Result), `"hoverProvider":true`, "hover should be provided") {
+		return
+	}
+
+	tests := []struct {
+		id     int
+		result string
+	}{
+		{id: 2, result: `{"uri":"file:///schema.sql","range":{"start":{"line":0,"character":13},"end":{"line":0,"character":18}}}`},
+		{id: 3, result: `{"uri":"file:///schema.sql","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":4}}}`},
+		{id: 4, result: `{"contents":{"kind":"markdown","value":"` + "```sql\\n`user_id` INT (11) NOT NULL\\n```" + `"},"range":{"start":{"line":8,"character":29},"end":{"line":8,"character":36}}}`},
+		{id: 5, result: `null`},
+		{id: 6, result: `[]`},
+		{id: 1000, result: `null`},
+	}
+	for _, test := range tests {
+		res, ok := responseTo(msgs, test.id)
+		if !assert.True(t, ok, "request %d should respond", test.id) {
+			return
+		}
+		if !assert.Nil(t, res.Error, "request %d should succeed", test.id) {
+			return
+		}
+		if !assert.Equal(t, test.result, string(res.Result), "result of request %d should match", test.id) {
+			return
+		}
+	}
+
+	res, _ = responseTo(msgs, 7)
+	if !assert.NotNil(t, res.Error, "unknown method should fail") || !assert.Equal(t, codeMethodNotFound, res.Error.Code, "error code should match") {
+		return
+	}
+
+	diags := diagnostics(msgs)
+	if !assert.Len(t, diags, 2, "diagnostics should be published on open and change") {
+		return
+	}
+	for _, d := range diags[0] {
+		if !assert.NotEqual(t, severityError, d.Severity, "the schema should have no errors (%s)", d.Message) {
+			return
+		}
+	}
+	var found bool
+	for _, d := range diags[1] {
+		if d.Source == "schemalex" && d.Severity == severityError {
+			found = assert.Equal(t, textRange{Start: position{Line: 2, Character: 7}, End: position{Line: 2, Character: 13}}, d.Range, "the error should be reported at the edit")
+		}
+	}
+	if !assert.True(t, found, "the parse error should be reported") {
+		return
+	}
+}
+
+func TestServerLint(t *testing.T) {
+	msgs, ok := serve(t, open("CREATE TABLE t (\n  `Id` INT\n);\n"))
+	if !ok {
+		return
+	}
+
+	diags := diagnostics(msgs)
+	if !assert.Len(t, diags, 1, "diagnostics should be published") {
+		return
+	}
+	for _, d := range diags[0] {
+		if d.Code == "naming" {
+			if !assert.Equal(t, "schemalint", d.Source, "source should match") {
+				return
+			}
+			if !assert.Equal(t, textRange{Start: position{Line: 1, Character: 2}, End: position{Line: 1, Character: 6}}, d.Range, "the finding should be reported at the column") {
+				return
+			}
+			return
+		}
+	}
+	t.Errorf("naming finding should be reported: %v", diags[0])
+}
+
+func TestServerFormatting(t *testing.T) {
+	src := "-- users\ncreate table users (id int not null, primary key (id));\ncreate table posts (id int /* comment */);\n"
+	msgs, ok := serve(t,
+		open(src),
+		call(1, "textDocument/formatting", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": "file:///schema.sql"},
+			"options":      formattingOptions{TabSize: 4, InsertSpaces: true},
+		}),
+	)
+	if !ok {
+		return
+	}
+
+	res, ok := responseTo(msgs, 1)
+	if !assert.True(t, ok, "formatting should respond") {
+		return
+	}
+	var edits []textEdit
+	if !assert.NoError(t, json.Unmarshal(res.Result, &edits), "edits should decode") {
+		return
+	}
+	// the statement with a comment is left as it is
+	if !assert.Len(t, edits, 1, "one statement should be formatted") {
+		return
+	}
+	if !assert.Equal(t, textRange{Start: position{Line: 1, Character: 0}, End: position{Line: 1, Character: 54}}, edits[0].Range, "the statement should be replaced") {
+		return
+	}
+	if !assert.True(t, strings.HasPrefix(edits[0].NewText, "CREATE TABLE `users` (\n    `id` INT"), "the statement should be formatted: %s", edits[0].NewText) {
+		return
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	msgs, ok := serve(t,
+		open("CREATE TABLE t (\n  id INT NOT NULL,\n  name VAR\n"),
+		call(1, "textDocument/completion", at(2, 10)),
+		call(2, "textDocument/completion", at(1, 15)),
+	)
+	if !ok {
+		return
+	}
+
+	tests := []struct {
+		id     int
+		result string
+	}{
+		{id: 1, result: `[{"label":"VARCHAR","kind":14},{"label":"VARBINARY","kind":14}]`},
+		{id: 2, result: `[{"label":"NULL","kind":14}]`},
+	}
+	for _, test := range tests {
+		res, ok := responseTo(msgs, test.id)
+		if !assert.True(t, ok, "request %d should respond", test.id) {
+			return
+		}
+		if !assert.Nil(t, res.Error, "request %d should succeed", test.id) {
+			return
+		}
+		if !assert.Equal(t, test.result, string(res.Result), "result of request %d should match", test.id) {
+			return
+		}
+	}
+}
+
+func TestServerExitBeforeShutdown(t *testing.T) {
+	var in, out bytes.Buffer
+	writeMessage(&in, map[string]interface{}{"jsonrpc": "2.0", "method": "exit"})
+	if !assert.Error(t, New().Serve(context.Background(), &in, &out), "exit before shutdown should fail") {
+		return
+	}
+}
+
+func TestText(t *testing.T) {
+	// the byte order mark is a character of the first line for the
+	// protocol, while the parser counts the columns after it
+	src := "\xEF\xBB\xBFab\r\n\U0001F600é x\n"
+	txt := newText([]byte(src))
+
+	tests := []struct {
+		off       int
+		pos       position
+		line, col int // as reported by the parser
+	}{
+		{off: 3, pos: position{Line: 0, Character: 1}, line: 1, col: 1},
+		{off: 4, pos: position{Line: 0, Character: 2}, line: 1, col: 2},
+		{off: 7, pos: position{Line: 1, Character: 0}, line: 2, col: 0},
+		{off: 11, pos: position{Line: 1, Character: 2}, line: 2, col: 1},
+		{off: 14, pos: position{Line: 1, Character: 4}, line: 2, col: 3},
+	}
+	for _, test := range tests {
+		if !assert.Equal(t, test.pos, txt.position(test.off), "position of %d should match", test.off) {
+			return
+		}
+		off, ok := txt.offset(test.pos)
+		if !assert.True(t, ok, "offset of %v should be found", test.pos) || !assert.Equal(t, test.off, off, "offset of %v should match", test.pos) {
+			return
+		}
+		off, ok = txt.sourceOffset(test.line, test.col)
+		if !assert.True(t, ok, "offset of %d:%d should be found", test.line, test.col) || !assert.Equal(t, test.off, off, "offset of %d:%d should match", test.line, test.col) {
+			return
+		}
+	}
+
+	// characters past the end of a line stand for its end
+	off, ok := txt.offset(position{Line: 0, Character: 10})
+	if !assert.True(t, ok, "offset should be found") || !assert.Equal(t, 5, off, "offset should be the end of the line") {
+		return
+	}
+	if _, ok := txt.offset(position{Line: 3, Character: 0}); !assert.False(t, ok, "offset past the last line should not be found") {
+		return
+	}
+}
@@ -0,0 +1,663 @@
+// Package lsp implements a Language Server Protocol server for schema
+// files, for the schemalex-lsp command. The documents that the editor
+// opens are kept as schemalex.Document, so that each change only parses
+// the statements that it affects.
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/eihigh/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+// Server reports the parse errors, the warnings and the lint findings
+// of the documents, finds the definitions of the tables and the columns
+// that foreign keys refer to, shows the definitions of columns on hover,
+// completes the keywords, and formats the CREATE TABLE statements.
+type Server struct {
+	parser   *schemalex.Parser
+	options  []Option
+	docs     map[string]*document
+	out      io.Writer
+	shutdown bool
+}
+
+type document struct {
+	*schemalex.Document
+	version int
+}
+
+// New creates a Server. The options are given to the parser, to
+// lint.Check and to format.SQL.
+func New(options ...Option) *Server {
+	return &Server{
+		parser:  schemalex.NewParser(options...),
+		options: options,
+		docs:    make(map[string]*document),
+	}
+}
+
+// Serve reads the messages of the client from r, and writes the
+// responses and the notifications to w, until the client sends the exit
+// notification. It returns an error if the client exits without
+// shutting the server down first, or if r is closed before.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.out = w
+	br := bufio.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		buf, err := readMessage(br)
+		if err == io.EOF {
+			return errors.New(`connection closed before exit`)
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(buf, &req); err != nil {
+			if err := s.reply(json.RawMessage("null"), nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New(`exit before shutdown`)
+			}
+			return nil
+		}
+
+		result, err := s.handle(ctx, &req)
+		if req.isNotification() {
+			if err != nil {
+				if err := s.logf("%s: %s", req.Method, err); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := s.reply(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req *request) (interface{}, error) {
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: `the server is shut down`}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize(), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.didOpen(ctx, &params)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.didChange(ctx, &params)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.didClose(&params)
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(&params)
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(&params)
+	case "textDocument/formatting":
+		var params documentFormattingParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.formatting(&params)
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := decode(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(&params)
+	}
+
+	// notifications that the server does not know, such as
+	// $/cancelRequest, are ignored
+	if req.isNotification() {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: `method ` + req.Method + ` is not supported`}
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: `invalid params: ` + err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) reply(id json.RawMessage, result interface{}, err error) error {
+	res := response{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		re, ok := err.(*responseError)
+		if !ok {
+			re = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		res.Error = re
+	} else {
+		buf, err := json.Marshal(result)
+		if err != nil {
+			res.Error = &responseError{Code: codeInternalError, Message: err.Error()}
+		} else {
+			res.Result = buf
+		}
+	}
+	return writeMessage(s.out, res)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// logf reports an error to the client, which usually shows it in the
+// output of the server
+func (s *Server) logf(f string, args ...interface{}) error {
+	return s.notify("window/logMessage", logMessageParams{Type: 1, Message: fmt.Sprintf(f, args...)})
+}
+
+func (s *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync": map[string]interface{}{
+				"openClose": true,
+				"change":    2, // incremental
+			},
+			"definitionProvider":         true,
+			"hoverProvider":              true,
+			"documentFormattingProvider": true,
+			"completionProvider":         map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "schemalex-lsp",
+			"version": schemalex.Version,
+		},
+	}
+}
+
+func (s *Server) didOpen(ctx context.Context, params *didOpenParams) error {
+	doc, err := s.parser.ParseDocument(ctx, []byte(params.TextDocument.Text))
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse %s`, params.TextDocument.URI)
+	}
+	d := &document{Document: doc, version: params.TextDocument.Version}
+	s.docs[params.TextDocument.URI] = d
+	return s.publish(params.TextDocument.URI, d)
+}
+
+func (s *Server) didChange(ctx context.Context, params *didChangeParams) error {
+	uri := params.TextDocument.URI
+	d, ok := s.docs[uri]
+	if !ok {
+		return errors.Errorf(`document %s is not open`, uri)
+	}
+
+	for _, c := range params.ContentChanges {
+		if c.Range == nil {
+			doc, err := s.parser.ParseDocument(ctx, []byte(c.Text))
+			if err != nil {
+				delete(s.docs, uri)
+				return errors.Wrapf(err, `failed to parse %s`, uri)
+			}
+			d.Document = doc
+			continue
+		}
+
+		t := newText(d.Source())
+		start, ok := t.offset(c.Range.Start)
+		end, ok2 := t.offset(c.Range.End)
+		if !ok || !ok2 || start > end {
+			// the document is out of sync, and is dropped until it is
+			// opened again
+			delete(s.docs, uri)
+			return errors.Errorf(`invalid range of change to %s`, uri)
+		}
+		if err := d.Edit(ctx, start, end, []byte(c.Text)); err != nil {
+			delete(s.docs, uri)
+			return errors.Wrapf(err, `failed to edit %s`, uri)
+		}
+	}
+	d.version = params.TextDocument.Version
+	return s.publish(uri, d)
+}
+
+func (s *Server) didClose(params *didCloseParams) error {
+	delete(s.docs, params.TextDocument.URI)
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         params.TextDocument.URI,
+		Diagnostics: []diagnostic{},
+	})
+}
+
+// publish sends the diagnostics of the document to the client
+func (s *Server) publish(uri string, d *document) error {
+	diags, err := s.diagnostics(d)
+	if err := s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Version:     &d.version,
+		Diagnostics: diags,
+	}); err != nil {
+		return err
+	}
+	return err
+}
+
+// diagnostics returns the parse errors, the warnings and the lint
+// findings of the document. The warnings and the findings whose position
+// is not known are reported at the beginning of the document.
+func (s *Server) diagnostics(d *document) ([]diagnostic, error) {
+	t := newText(d.Source())
+	tokens := t.tokens()
+	diags := []diagnostic{}
+
+	if errs, ok := d.Err().(schemalex.ParseErrors); ok {
+		for _, err := range errs {
+			tok := err.Token()
+			diags = append(diags, diagnostic{
+				Range:    tokenRange(t, tokens, t.bom+tok.Pos),
+				Severity: severityError,
+				Source:   "schemalex",
+				Message:  err.Message(),
+			})
+		}
+	}
+
+	for _, w := range d.Warnings() {
+		var off int
+		if w.File == "" {
+			off, _ = t.sourceOffset(w.Line, w.Col)
+		}
+		diags = append(diags, diagnostic{
+			Range:    tokenRange(t, tokens, off),
+			Severity: severityWarning,
+			Source:   "schemalex",
+			Message:  w.Message,
+		})
+	}
+
+	findings, err := lint.Check(d.Stmts(), s.options...)
+	if err != nil {
+		return diags, errors.Wrap(err, `failed to check source`)
+	}
+	for _, f := range findings {
+		off, _ := positionOffset(t, f.Position)
+		severity := severityInformation
+		switch f.Severity {
+		case lint.SeverityWarning:
+			severity = severityWarning
+		case lint.SeverityError:
+			severity = severityError
+		}
+		diags = append(diags, diagnostic{
+			Range:    tokenRange(t, tokens, off),
+			Severity: severity,
+			Code:     f.Rule,
+			Source:   "schemalint",
+			Message:  f.Message,
+		})
+	}
+	return diags, nil
+}
+
+// tokenRange returns the range of the token that starts at offset off,
+// or an empty range at off if there is none
+func tokenRange(t *text, tokens []span, off int) textRange {
+	if i := tokenIndex(tokens, off); i < len(tokens) && tokens[i].start == off && tokens[i].Type != schemalex.SPACE {
+		return t.textRange(tokens[i].start, tokens[i].end)
+	}
+	return t.textRange(off, off)
+}
+
+// positionOffset returns the offset of a position of the model, or false
+// if it is not known, or is within another file than the document
+func positionOffset(t *text, pos model.Position) (int, bool) {
+	if !pos.IsValid() || pos.File != "" {
+		return 0, false
+	}
+	return t.sourceOffset(pos.Line, pos.Col)
+}
+
+// target is the table or the column that an identifier refers to
+type target struct {
+	table  model.Table
+	column model.TableColumn // nil if the identifier names the table
+}
+
+// resolve returns what the identifier at offset off refers to, along
+// with the token of the identifier. Tables are named by the REFERENCES
+// clauses of foreign keys, and columns by the column lists that follow
+// them, or else belong to the table that the statement creates.
+func resolve(stmts model.Stmts, t *text, sig []span, off int) (target, span, bool) {
+	i, ok := identAt(sig, off)
+	if !ok {
+		return target{}, span{}, false
+	}
+	name := sig[i].Value
+
+	// REFERENCES [db.]table
+	if j := qualifiedStart(sig, i); j > 0 && sig[j-1].Type == schemalex.REFERENCES {
+		table, ok := lookupTable(stmts, name)
+		return target{table: table}, sig[i], ok
+	}
+
+	// REFERENCES table (column, ...)
+	j := i - 1
+	for j >= 0 && (isName(sig[j]) || sig[j].Type == schemalex.COMMA) {
+		j--
+	}
+	if j > 0 && sig[j].Type == schemalex.LPAREN && isName(sig[j-1]) {
+		if k := qualifiedStart(sig, j-1); k > 0 && sig[k-1].Type == schemalex.REFERENCES {
+			table, ok := lookupTable(stmts, sig[j-1].Value)
+			if !ok {
+				return target{}, span{}, false
+			}
+			col, ok := lookupColumn(table, name)
+			return target{table: table, column: col}, sig[i], ok
+		}
+	}
+
+	// a column of the table that the statement creates
+	start := i
+	for start > 0 && sig[start-1].Type != schemalex.SEMICOLON {
+		start--
+	}
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		if off, ok := positionOffset(t, table.Position()); ok && off == sig[start].start {
+			col, ok := lookupColumn(table, name)
+			return target{table: table, column: col}, sig[i], ok
+		}
+	}
+	return target{}, span{}, false
+}
+
+// isName returns true if the token may name a table or a column, which
+// includes the keywords that MySQL does not reserve
+func isName(s span) bool {
+	if isIdent(s.Type) {
+		return true
+	}
+	if s.Value == "" {
+		return false
+	}
+	for i, r := range s.Value {
+		if !unicode.IsLetter(r) && r != '_' && r != '$' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// qualifiedStart returns the index of the first token of the name that
+// ends at index i, which may be qualified by the name of the database
+func qualifiedStart(sig []span, i int) int {
+	if i >= 2 && sig[i-1].Type == schemalex.DOT && isName(sig[i-2]) {
+		return i - 2
+	}
+	return i
+}
+
+func lookupTable(stmts model.Stmts, name string) (model.Table, bool) {
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok && table.Name() == name {
+			return table, true
+		}
+	}
+	return nil, false
+}
+
+// lookupColumn looks up a column by name, which is case insensitive
+func lookupColumn(table model.Table, name string) (model.TableColumn, bool) {
+	var found model.TableColumn
+	for col := range table.Columns() {
+		if found == nil && equalFold(col.Name(), name) {
+			found = col
+		}
+	}
+	return found, found != nil
+}
+
+func equalFold(a, b string) bool {
+	return bytes.EqualFold([]byte(a), []byte(b))
+}
+
+// nameSpan returns the token of the name of the target, or false if its
+// position is not known
+func nameSpan(t *text, sig []span, tgt target) (span, bool) {
+	if tgt.column != nil {
+		off, ok := positionOffset(t, tgt.column.Position())
+		if !ok {
+			return span{}, false
+		}
+		i, ok := identAt(sig, off)
+		if !ok {
+			return span{}, false
+		}
+		return sig[i], true
+	}
+
+	off, ok := positionOffset(t, tgt.table.Position())
+	if !ok {
+		return span{}, false
+	}
+	// CREATE [TEMPORARY] TABLE [IF NOT EXISTS] [db.]name
+	i := tokenIndex(sig, off)
+	for i < len(sig) && sig[i].Type != schemalex.TABLE {
+		i++
+	}
+	for i++; i < len(sig); i++ {
+		switch sig[i].Type {
+		case schemalex.IF, schemalex.NOT, schemalex.EXISTS:
+			continue
+		}
+		if i+2 < len(sig) && sig[i+1].Type == schemalex.DOT {
+			i += 2
+		}
+		return sig[i], true
+	}
+	return span{}, false
+}
+
+// lookup returns the document, its text and its tokens, and the offset
+// of the position
+func (s *Server) lookup(params *textDocumentPositionParams) (*document, *text, []span, int, error) {
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil, nil, 0, errors.Errorf(`document %s is not open`, params.TextDocument.URI)
+	}
+	t := newText(d.Source())
+	off, ok := t.offset(params.Position)
+	if !ok {
+		return nil, nil, nil, 0, &responseError{Code: codeInvalidParams, Message: `position out of the document`}
+	}
+	return d, t, significant(t.tokens()), off, nil
+}
+
+func (s *Server) definition(params *textDocumentPositionParams) (interface{}, error) {
+	d, t, sig, off, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+	tgt, _, ok := resolve(d.Stmts(), t, sig, off)
+	if !ok {
+		return nil, nil
+	}
+	name, ok := nameSpan(t, sig, tgt)
+	if !ok {
+		return nil, nil
+	}
+	return location{URI: params.TextDocument.URI, Range: t.textRange(name.start, name.end)}, nil
+}
+
+func (s *Server) hover(params *textDocumentPositionParams) (interface{}, error) {
+	d, t, sig, off, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+	tgt, ident, ok := resolve(d.Stmts(), t, sig, off)
+	if !ok {
+		return nil, nil
+	}
+
+	var v interface{} = tgt.table
+	if tgt.column != nil {
+		v = tgt.column
+	}
+	var buf bytes.Buffer
+	buf.WriteString("```sql\n")
+	if err := format.SQL(&buf, v, s.options...); err != nil {
+		return nil, errors.Wrap(err, `failed to format definition`)
+	}
+	buf.WriteString("\n```")
+
+	r := t.textRange(ident.start, ident.end)
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: buf.String()},
+		Range:    &r,
+	}, nil
+}
+
+// completion returns the keywords and the punctuation that the parser
+// accepts at the position
+func (s *Server) completion(params *textDocumentPositionParams) (interface{}, error) {
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, errors.Errorf(`document %s is not open`, params.TextDocument.URI)
+	}
+	t := newText(d.Source())
+	off, ok := t.offset(params.Position)
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: `position out of the document`}
+	}
+	list, err := s.parser.Complete(t.src, off)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to complete`)
+	}
+
+	items := []completionItem{}
+	for _, c := range list {
+		switch {
+		case c.Text == "":
+			// names and values are up to the user
+		case isKeyword(c.Text):
+			items = append(items, completionItem{Label: c.Text, Kind: completionKeyword})
+		default:
+			items = append(items, completionItem{Label: c.Text, Kind: completionOperator})
+		}
+	}
+	return items, nil
+}
+
+// isKeyword returns true if the text of a completion is a keyword,
+// rather than punctuation
+func isKeyword(s string) bool {
+	return unicode.IsLetter(rune(s[0]))
+}
+
+// formatting formats the CREATE TABLE statements of the document, in
+// place. The other statements are left as they are, and so are the
+// statements that contain comments, which the formatter does not keep.
+// Documents with parse errors are not formatted.
+func (s *Server) formatting(params *documentFormattingParams) (interface{}, error) {
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, errors.Errorf(`document %s is not open`, params.TextDocument.URI)
+	}
+	edits := []textEdit{}
+	if d.Err() != nil {
+		return edits, nil
+	}
+
+	indent := format.WithIndent("\t", 1)
+	if params.Options.InsertSpaces {
+		indent = format.WithIndent(" ", params.Options.TabSize)
+	}
+	options := append(s.options[:len(s.options):len(s.options)], indent)
+
+	t := newText(d.Source())
+	tokens := t.tokens()
+	for _, stmt := range d.Stmts() {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		start, ok := positionOffset(t, table.Position())
+		if !ok {
+			continue
+		}
+
+		// the statement ends before its semicolon, or at its last token
+		end := start
+		var comment bool
+		for _, tok := range tokens[tokenIndex(tokens, start):] {
+			if tok.Type == schemalex.SEMICOLON {
+				break
+			}
+			if tok.Type == schemalex.COMMENT_IDENT {
+				comment = true
+			}
+			if tok.Type != schemalex.SPACE {
+				end = tok.end
+			}
+		}
+		if comment {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := format.SQL(&buf, table, options...); err != nil {
+			return nil, errors.Wrapf(err, `failed to format table %s`, table.Name())
+		}
+		if bytes.Equal(buf.Bytes(), t.src[start:end]) {
+			continue
+		}
+		edits = append(edits, textEdit{Range: t.textRange(start, end), NewText: buf.String()})
+	}
+	return edits, nil
+}
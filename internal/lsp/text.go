@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"bytes"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/eihigh/schemalex"
+)
+
+var byteOrderMark = []byte{0xEF, 0xBB, 0xBF}
+
+// text maps the byte offsets of a source to the positions of the
+// protocol, whose characters are counted in UTF-16 code units, and to
+// the lines and columns reported by the parser, which count runes
+type text struct {
+	src   []byte
+	bom   int   // length of the byte order mark, which the parser skips
+	lines []int // offsets where the lines start
+}
+
+func newText(src []byte) *text {
+	t := &text{
+		src:   src,
+		bom:   len(src) - len(bytes.TrimPrefix(src, byteOrderMark)),
+		lines: []int{0},
+	}
+	for i, c := range src {
+		if c == '\n' {
+			t.lines = append(t.lines, i+1)
+		}
+	}
+	return t
+}
+
+// lineEnd returns the offset of the end of the line, before its new
+// line characters
+func (t *text) lineEnd(line int) int {
+	end := len(t.src)
+	if line+1 < len(t.lines) {
+		end = t.lines[line+1] - 1
+	}
+	if end > t.lines[line] && t.src[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// position returns the position of the byte at offset off
+func (t *text) position(off int) position {
+	line := sort.Search(len(t.lines), func(i int) bool { return t.lines[i] > off }) - 1
+	var n int
+	for _, r := range string(t.src[t.lines[line]:off]) {
+		n += utf16Width(r)
+	}
+	return position{Line: line, Character: n}
+}
+
+// textRange returns the range of the bytes from start to end
+func (t *text) textRange(start, end int) textRange {
+	return textRange{Start: t.position(start), End: t.position(end)}
+}
+
+// offset returns the offset of the byte at position p, or false if the
+// line is not in the source. The characters past the end of a line
+// stand for the end of the line.
+func (t *text) offset(p position) (int, bool) {
+	if p.Line < 0 || p.Line >= len(t.lines) || p.Character < 0 {
+		return 0, false
+	}
+	off, end := t.lines[p.Line], t.lineEnd(p.Line)
+	for n := 0; n < p.Character && off < end; {
+		r, size := utf8.DecodeRune(t.src[off:end])
+		n += utf16Width(r)
+		off += size
+	}
+	return off, true
+}
+
+// sourceOffset returns the offset of the line and the column reported
+// by the parser, or false if they are not known. The columns of the
+// first line are counted from 1, after the byte order mark, and those of
+// the other lines from 0.
+func (t *text) sourceOffset(line, col int) (int, bool) {
+	if line < 1 || line > len(t.lines) {
+		return 0, false
+	}
+	off, end := t.lines[line-1], t.lineEnd(line-1)
+	if line == 1 {
+		off += t.bom
+		col--
+	}
+	for ; col > 0 && off < end; col-- {
+		_, size := utf8.DecodeRune(t.src[off:end])
+		off += size
+	}
+	return off, true
+}
+
+func utf16Width(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// span is a token of the source, which spans the bytes from start to
+// end
+type span struct {
+	schemalex.Token
+	start, end int
+}
+
+// tokens returns the tokens of the source, including spaces and
+// comments
+func (t *text) tokens() []span {
+	var list []span
+	tz := schemalex.Tokenize(t.src[t.bom:])
+	for tz.Next() {
+		tok := tz.Token()
+		start := t.bom + tok.Pos
+		list = append(list, span{Token: tok, start: start, end: start + len(tz.Text())})
+	}
+	return list
+}
+
+// significant returns the tokens of the list that are neither spaces
+// nor comments
+func significant(list []span) []span {
+	var sig []span
+	for _, s := range list {
+		switch s.Type {
+		case schemalex.SPACE, schemalex.COMMENT_IDENT:
+		default:
+			sig = append(sig, s)
+		}
+	}
+	return sig
+}
+
+// tokenIndex returns the index of the first token of the list that
+// starts at or after offset off
+func tokenIndex(list []span, off int) int {
+	return sort.Search(len(list), func(i int) bool { return list[i].start >= off })
+}
+
+// identAt returns the index of the name of the list that contains
+// the byte at offset off, or that ends there
+func identAt(list []span, off int) (int, bool) {
+	i := sort.Search(len(list), func(i int) bool { return list[i].end > off })
+	if i < len(list) && list[i].start <= off && isName(list[i]) {
+		return i, true
+	}
+	if i > 0 && list[i-1].end == off && isName(list[i-1]) {
+		return i - 1, true
+	}
+	return 0, false
+}
+
+func isIdent(typ schemalex.TokenType) bool {
+	switch typ {
+	case schemalex.IDENT, schemalex.BACKTICK_IDENT, schemalex.DOUBLE_QUOTE_IDENT:
+		return true
+	}
+	return false
+}
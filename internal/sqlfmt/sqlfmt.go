@@ -0,0 +1,24 @@
+// Package sqlfmt connects the format package to the SQL formatting of
+// the model package, which can not export it without making it part of
+// its API.
+package sqlfmt
+
+import "io"
+
+// Options are the options of the format package, as understood by the
+// model package
+type Options struct {
+	Indent       string
+	SingleLine   bool
+	DisplayWidth bool
+	// Quote returns the identifier as written, quoted or not. If it is
+	// nil, every identifier is quoted with backticks.
+	Quote           func(ident string) string
+	SkipTemporary   bool
+	DependencyOrder bool
+}
+
+// Write writes the SQL representation of v, one of the objects of the
+// model package, with the options. It is set by the model package,
+// which every package that passes model objects to it imports.
+var Write func(dst io.Writer, v interface{}, o Options) error
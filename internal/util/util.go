@@ -1,13 +1,19 @@
 package util
 
 import (
+	"bytes"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/eihigh/schemalex/internal/errors"
 )
 
-// Backquote surrounds the given string in backquotes
+// Backquote surrounds the given string in backquotes. Backquotes in
+// the string are escaped by doubling them.
 func Backquote(s string) string {
-	// XXX Does this require escaping
-	return "`" + s + "`"
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
 }
 
 // Singlequote surrounds the given string in singlequotes
@@ -30,3 +36,68 @@ func Singlequote(s string) string {
 	b.WriteRune('\'')
 	return b.String()
 }
+
+// Excerpt renders the line of src that contains the byte offset pos,
+// prefixed by its line number, followed by a caret pointing at pos
+func Excerpt(src []byte, pos, line int) string {
+	if pos > len(src) {
+		pos = len(src)
+	}
+	begin := bytes.LastIndexByte(src[:pos], '\n') + 1
+	end := len(src)
+	if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+		end = pos + i
+	}
+	text := strings.TrimRight(string(src[begin:end]), "\r")
+
+	num := strconv.Itoa(line)
+	b := strings.Builder{}
+	b.WriteString(num)
+	b.WriteString(" | ")
+	b.WriteString(text)
+	b.WriteByte('\n')
+	b.WriteString(strings.Repeat(" ", len(num)))
+	b.WriteString(" | ")
+	// keep the tabs, so that the caret lines up with the text
+	for prefix := src[begin:pos]; len(prefix) > 0; {
+		r, size := utf8.DecodeRune(prefix)
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+		prefix = prefix[size:]
+	}
+	b.WriteByte('^')
+	return b.String()
+}
+
+// CompilePattern compiles a pattern of names, such as the names of
+// the tables to ignore. Patterns surrounded by slashes, such as
+// `/^tmp_[0-9]+$/`, are regular expressions. Other patterns are globs, where `*` and `%` match any sequence of
+// characters, and `?` matches a single character. Matching is case
+// insensitive.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return nil, errors.Wrapf(err, `invalid pattern %s`, pattern)
+		}
+		return re, nil
+	}
+
+	var buf strings.Builder
+	buf.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*', '%':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteByte('.')
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buf.WriteByte('$')
+	return regexp.MustCompile(buf.String()), nil
+}
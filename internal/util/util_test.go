@@ -22,3 +22,51 @@ func TestSinglequote(t *testing.T) {
 		}
 	}
 }
+
+func TestBackquote(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{
+			input: "hoge",
+			want:  "`hoge`",
+		},
+		{
+			input: "ho`ge",
+			want:  "`ho``ge`",
+		},
+	}
+	for _, tt := range tests {
+		got := Backquote(tt.input)
+		if got != tt.want {
+			t.Errorf("want %q; got %q", tt.want, got)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		src       string
+		pos, line int
+		want      string
+	}{
+		{
+			src:  "CREATE TABLE foo (\n\tid INT,,\n)",
+			pos:  27,
+			line: 2,
+			want: "2 | \tid INT,,\n  | \t       ^",
+		},
+		{
+			src:  "CREATE TABLE",
+			pos:  12,
+			line: 10,
+			want: "10 | CREATE TABLE\n   |             ^",
+		},
+	}
+	for _, tt := range tests {
+		got := Excerpt([]byte(tt.src), tt.pos, tt.line)
+		if got != tt.want {
+			t.Errorf("want %q; got %q", tt.want, got)
+		}
+	}
+}
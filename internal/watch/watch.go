@@ -0,0 +1,103 @@
+// Package watch calls a function each time local files change, for the
+// -watch options of the commands. The files are polled, which does not
+// depend on the file notification APIs of each OS.
+package watch
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Path returns the local file or directory that is read by the source
+// given as uri, as accepted by schemalex.NewSchemaSource. It returns
+// false for sources that can not be watched, such as stdin ("-"), or
+// mysql:// and local-git sources.
+func Path(uri string) (string, bool) {
+	if uri == "-" || strings.HasPrefix(uri, "mysql://") {
+		return "", false
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "file", "":
+		if u.Host != "" && u.Host != "localhost" {
+			return "", false
+		}
+		return u.Path, u.Path != ""
+	}
+	return "", false
+}
+
+type state struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot records the states of the files in paths, and of the files
+// in the directories among them. Paths that do not exist are skipped,
+// as editors may remove files while saving them.
+func snapshot(paths []string) map[string]state {
+	files := make(map[string]state)
+	for _, path := range paths {
+		filepath.Walk(path, func(fn string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return nil
+			}
+			files[fn] = state{modTime: fi.ModTime(), size: fi.Size()}
+			return nil
+		})
+	}
+	return files
+}
+
+// changes returns the files that were added, removed, or modified
+// between the two snapshots, in lexical order
+func changes(before, after map[string]state) []string {
+	var changed []string
+	for fn, s := range after {
+		if prev, ok := before[fn]; !ok || !prev.modTime.Equal(s.modTime) || prev.size != s.size {
+			changed = append(changed, fn)
+		}
+	}
+	for fn := range before {
+		if _, ok := after[fn]; !ok {
+			changed = append(changed, fn)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Run calls fn, and calls it again each time the files in paths
+// change, until ctx is canceled. The files are checked every interval.
+// fn is given the files that changed, which are nil for the first
+// call.
+func Run(ctx context.Context, paths []string, interval time.Duration, fn func(changed []string)) {
+	files := snapshot(paths)
+	fn(nil)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := snapshot(paths)
+		changed := changes(files, current)
+		if len(changed) == 0 {
+			continue
+		}
+		files = current
+		fn(changed)
+	}
+}
@@ -2,14 +2,16 @@ package schemalex
 
 import (
 	"bytes"
-	"context"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/eihigh/schemalex/internal/errors"
 )
 
-const eof = rune(0)
+// eof is returned by peek at the end of the input. It can not be
+// decoded from the input, unlike NUL.
+const eof = rune(-1)
 
 type lrune struct {
 	r rune
@@ -23,7 +25,6 @@ type position struct {
 }
 
 type lexer struct {
-	out       chan *Token
 	input     []byte
 	peekCount int
 	peekRunes [3]lrune
@@ -31,29 +32,92 @@ type lexer struct {
 	start position // position where we last emitted
 	cur   position // current position including read-ahead
 	width int
-}
 
-func lex(ctx context.Context, input []byte) chan *Token {
-	ch := make(chan *Token, 3)
-	l := newLexer(ch, input)
-	go l.Run(ctx)
-	return ch
+	token *Token // the token emitted by the last step
+	done  bool   // EOF, an unterminated quote or comment has been found
+
+	// ansiQuotes is true if strings quoted by double quotes are
+	// identifiers, as with the ANSI_QUOTES SQL mode
+	ansiQuotes bool
+
+	// delimiter is the statement delimiter set by the DELIMITER command
+	// of the mysql client, such as $$, which is read as a SEMICOLON, or
+	// an empty string if it is the semicolon
+	delimiter string
+
+	// tokens are emitted from chunks allocated at once, and tokens is
+	// what remains of the current chunk
+	chunk  []Token
+	tokens []Token
 }
 
-func newLexer(out chan *Token, input []byte) *lexer {
+// Chunks start with minTokenChunk tokens, as some inputs are as short
+// as a single statement, and double up to maxTokenChunk tokens
+const (
+	minTokenChunk = 16
+	maxTokenChunk = 256
+)
+
+// lexAt creates a lexer for the input as if it started at the beginning
+// of the given line, so that the tokens are numbered as in the whole
+// input
+func lexAt(input []byte, line int) *lexer {
 	var l lexer
-	l.out = out
+	l.reset(input, 0, line)
+	return &l
+}
+
+func newLexer(input []byte) *lexer {
+	return lexAt(input, 1)
+}
+
+// reset prepares the lexer to read the input from the offset pos, which
+// is the beginning of the given line, as lexAt does. The current chunk
+// of tokens is reused, so the tokens emitted before must not be referred
+// to anymore.
+func (l *lexer) reset(input []byte, pos, line int) {
+	*l = lexer{chunk: l.chunk, tokens: l.chunk}
 	l.input = input
+	l.start.pos = pos
 	l.start.line = 1
 	l.start.col = 1
-	l.cur.line = 1
-	l.cur.col = 1
+	if line > 1 {
+		// columns are counted from 0 after a new line
+		l.start.line, l.start.col = line, 0
+	}
+	l.cur = l.start
 	l.peekCount = -1
-	return &l
 }
 
-func (l *lexer) emit(ctx context.Context, typ TokenType) {
-	var t Token
+// skipTo moves the lexer to the offset pos of the input, which is the
+// beginning of the given line, as reset does, but the tokens emitted
+// before are kept, and so are the quotes and the delimiter
+func (l *lexer) skipTo(pos, line int) {
+	tokens, ansiQuotes, delimiter := l.tokens, l.ansiQuotes, l.delimiter
+	l.reset(l.input, pos, line)
+	l.tokens, l.ansiQuotes, l.delimiter = tokens, ansiQuotes, delimiter
+}
+
+// newToken returns a zero token from the current chunk
+func (l *lexer) newToken() *Token {
+	if len(l.tokens) == 0 {
+		n := 2 * len(l.chunk)
+		if n < minTokenChunk {
+			n = minTokenChunk
+		} else if n > maxTokenChunk {
+			n = maxTokenChunk
+		}
+		l.chunk = make([]Token, n)
+		l.tokens = l.chunk
+	}
+	t := &l.tokens[0]
+	l.tokens = l.tokens[1:]
+	*t = Token{}
+	return t
+}
+
+func (l *lexer) emit(typ TokenType) {
+	t := l.newToken()
 	t.Line = l.start.line
 	t.Col = l.start.col
 	t.Type = typ
@@ -63,155 +127,227 @@ func (l *lexer) emit(ctx context.Context, typ TokenType) {
 		t.EOF = true
 		t.Pos = len(l.input)
 	} else {
-		t.Value = l.str()
-		switch typ {
-		case SINGLE_QUOTE_IDENT:
-			t.Value = unescapeQuotes(t.Value, '\'')
-		case DOUBLE_QUOTE_IDENT:
-			t.Value = unescapeQuotes(t.Value, '"')
-		case BACKTICK_IDENT:
-			t.Value = unescapeQuotes(t.Value, '`')
-		}
+		// the value is only read from the input if the parser needs
+		// it, as most tokens, such as spaces and keywords, are only
+		// told apart by their types
+		t.size = int32(len(l.bytes()))
 	}
 
-	select {
-	case <-ctx.Done():
-	case l.out <- &t:
+	l.token = t
+	if typ == EOF {
+		l.done = true
 	}
 
 	// when we emit, we must copy the value of cur to start
 	// but we also must adjust the position by the read-ahead offset
 	l.start = l.cur
-	l.start.pos = l.start.pos - (l.peekCount + 1)
+	l.start.pos = l.start.pos - l.readAhead()
+}
+
+// readAhead returns the number of bytes peeked but not consumed yet
+func (l *lexer) readAhead() int {
+	var n int
+	for i := 0; i <= l.peekCount; i++ {
+		n += l.peekRunes[i].w
+	}
+	return n
 }
 
-func (l *lexer) str() string {
-	endpos := l.cur.pos - (l.peekCount + 1)
+// bytes returns the source text of the token being read, which refers
+// to the input
+func (l *lexer) bytes() []byte {
+	endpos := l.cur.pos - l.readAhead()
 	w := len(l.input[l.start.pos:])
 	if endpos-l.start.pos > w {
 		endpos = l.start.pos + w
 	}
-	return string(l.input[l.start.pos:endpos])
+	return l.input[l.start.pos:endpos:endpos]
 }
 
-func (l *lexer) Run(ctx context.Context) {
-	defer close(l.out)
+// value returns the value of the token, which is read from its source
+// text in the input that it was read from on first use: quoted
+// identifiers and strings are unquoted, and the other tokens are their
+// source text
+func (t *Token) value(input []byte) string {
+	if t.size == 0 {
+		return t.Value
+	}
+	v := string(input[t.Pos : t.Pos+int(t.size)])
+	switch t.Type {
+	case SINGLE_QUOTE_IDENT:
+		v = unescapeQuotes(v, '\'')
+	case DOUBLE_QUOTE_IDENT:
+		v = unescapeQuotes(v, '"')
+	case BACKTICK_IDENT:
+		if strings.HasPrefix(v, `"`) {
+			v = unescapeQuotes(v, '"')
+		} else {
+			v = unescapeQuotes(v, '`')
+		}
+	}
+	t.Value, t.size = v, 0
+	return v
+}
 
-OUTER:
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
+// lookupKeyword returns the type of the keyword, if the identifier is
+// one. Keywords are ASCII, so the identifier is upper cased into a
+// buffer on the stack, and the lookup does not allocate.
+func lookupKeyword(ident []byte) (TokenType, bool) {
+	var buf [32]byte
+	if len(ident) > len(buf) {
+		return ILLEGAL, false
+	}
+	for i, c := range ident {
+		switch {
+		case c >= utf8.RuneSelf:
+			return ILLEGAL, false
+		case 'a' <= c && c <= 'z':
+			c -= 'a' - 'A'
 		}
+		buf[i] = c
+	}
+	typ, ok := keywordIdentMap[string(buf[:len(ident)])]
+	return typ, ok
+}
 
-		r := l.peek()
+// nextToken returns the next token of the input. Once the input is
+// exhausted, or an unterminated quote or comment is found, EOF is
+// returned
+func (l *lexer) nextToken() *Token {
+	if l.done {
+		return &Token{Type: EOF, EOF: true, Pos: len(l.input), Line: l.start.line, Col: l.start.col}
+	}
+	l.token = nil
+	l.step()
+	return l.token
+}
 
-		// These require peek, and then consume
-		switch {
-		case isSpace(r):
-			// read until space end
-			l.runSpace()
-			l.emit(ctx, SPACE)
-			continue OUTER
-		case isLetter(r):
-			t := l.runIdent()
-			s := l.str()
-			if typ, ok := keywordIdentMap[strings.ToUpper(s)]; ok {
-				t = typ
-			}
-			l.emit(ctx, t)
-			continue OUTER
-		case isDigit(r):
-			l.runNumber()
-			l.emit(ctx, NUMBER)
-			continue OUTER
+// step scans the input and emits a single token
+func (l *lexer) step() {
+	if l.delimiter != "" && bytes.HasPrefix(l.input[l.start.pos:], []byte(l.delimiter)) {
+		for range l.delimiter {
+			l.next()
+		}
+		l.emit(SEMICOLON)
+		return
+	}
+
+	r := l.peek()
+
+	// These require peek, and then consume
+	switch {
+	case isBlank(r):
+		// read until space end
+		l.runSpace()
+		l.emit(SPACE)
+		return
+	case isLetter(r) || isExtended(r):
+		t := l.runIdent()
+		if typ, ok := lookupKeyword(l.bytes()); ok {
+			t = typ
 		}
+		l.emit(t)
+		return
+	case isDigit(r):
+		l.runNumber()
+		l.emit(NUMBER)
+		return
+	}
 
-		// once we got here, we can consume
-		l.advance()
-		switch r {
-		case eof:
-			l.emit(ctx, EOF)
+	// once we got here, we can consume
+	l.advance()
+	switch r {
+	case eof:
+		l.emit(EOF)
+		return
+	case '`':
+		if err := l.runQuote('`'); err != nil {
+			l.emit(ILLEGAL)
+			l.done = true
 			return
-		case '`':
-			if err := l.runQuote('`'); err != nil {
-				l.emit(ctx, ILLEGAL)
-				return
-			}
+		}
 
-			l.emit(ctx, BACKTICK_IDENT)
-		case '"':
-			if err := l.runQuote('"'); err != nil {
-				l.emit(ctx, ILLEGAL)
-				return
-			}
+		l.emit(BACKTICK_IDENT)
+	case '"':
+		if err := l.runQuote('"'); err != nil {
+			l.emit(ILLEGAL)
+			l.done = true
+			return
+		}
 
-			l.emit(ctx, DOUBLE_QUOTE_IDENT)
-		case '\'':
-			if err := l.runQuote('\''); err != nil {
-				l.emit(ctx, ILLEGAL)
-				return
-			}
+		if l.ansiQuotes {
+			l.emit(BACKTICK_IDENT)
+		} else {
+			l.emit(DOUBLE_QUOTE_IDENT)
+		}
+	case '\'':
+		if err := l.runQuote('\''); err != nil {
+			l.emit(ILLEGAL)
+			l.done = true
+			return
+		}
 
-			l.emit(ctx, SINGLE_QUOTE_IDENT)
-		case '/':
-			switch c := l.peek(); c {
-			case '*':
-				l.runCComment()
-				l.emit(ctx, COMMENT_IDENT)
-			default:
-				l.emit(ctx, SLASH)
+		l.emit(SINGLE_QUOTE_IDENT)
+	case '/':
+		switch c := l.peek(); c {
+		case '*':
+			if !l.runCComment() {
+				l.emit(ILLEGAL)
+				l.done = true
+				return
 			}
-		case '-':
-			switch r1 := l.peek(); {
-			case r1 == '-':
-				l.advance()
-				// TODO: https://dev.mysql.com/doc/refman/5.6/en/comments.html
-				// TODO: not only space. control character
-				if !isSpace(l.peek()) {
-					l.emit(ctx, DASH)
-					continue OUTER
-				}
-				l.runToEOL()
-				l.emit(ctx, COMMENT_IDENT)
-			case isDigit(r1):
-				l.runNumber()
-				l.emit(ctx, NUMBER)
-			default:
-				l.emit(ctx, DASH)
+			l.emit(COMMENT_IDENT)
+		default:
+			l.emit(SLASH)
+		}
+	case '-':
+		switch r1 := l.peek(); {
+		case r1 == '-':
+			l.advance()
+			// TODO: https://dev.mysql.com/doc/refman/5.6/en/comments.html
+			// TODO: not only space. control character
+			if !isBlank(l.peek()) {
+				l.emit(DASH)
+				return
 			}
-		case '#':
-			// https://dev.mysql.com/doc/refman/5.6/en/comments.html
 			l.runToEOL()
-			l.emit(ctx, COMMENT_IDENT)
-		case '(':
-			l.emit(ctx, LPAREN)
-		case ')':
-			l.emit(ctx, RPAREN)
-		case ';':
-			l.emit(ctx, SEMICOLON)
-		case ',':
-			l.emit(ctx, COMMA)
-		case '.':
-			if isDigit(l.peek()) {
-				l.runNumber()
-				l.emit(ctx, NUMBER)
-			} else {
-				l.emit(ctx, DOT)
-			}
-		case '+':
-			if isDigit(l.peek()) {
-				l.runNumber()
-				l.emit(ctx, NUMBER)
-			} else {
-				l.emit(ctx, PLUS)
-			}
-		case '=':
-			l.emit(ctx, EQUAL)
+			l.emit(COMMENT_IDENT)
+		case isDigit(r1):
+			l.runNumber()
+			l.emit(NUMBER)
 		default:
-			l.emit(ctx, ILLEGAL)
+			l.emit(DASH)
 		}
+	case '#':
+		// https://dev.mysql.com/doc/refman/5.6/en/comments.html
+		l.runToEOL()
+		l.emit(COMMENT_IDENT)
+	case '(':
+		l.emit(LPAREN)
+	case ')':
+		l.emit(RPAREN)
+	case ';':
+		l.emit(SEMICOLON)
+	case ',':
+		l.emit(COMMA)
+	case '.':
+		if isDigit(l.peek()) {
+			l.runNumber()
+			l.emit(NUMBER)
+		} else {
+			l.emit(DOT)
+		}
+	case '+':
+		if isDigit(l.peek()) {
+			l.runNumber()
+			l.emit(NUMBER)
+		} else {
+			l.emit(PLUS)
+		}
+	case '=':
+		l.emit(EQUAL)
+	default:
+		l.emit(ILLEGAL)
 	}
 }
 
@@ -248,6 +384,8 @@ func (l *lexer) advance() {
 		l.cur.line++
 		l.cur.col = 0
 	case eof:
+		// nothing was read
+		return
 	default:
 		l.cur.col++
 	}
@@ -256,7 +394,7 @@ func (l *lexer) advance() {
 }
 
 func (l *lexer) runSpace() {
-	for isSpace(l.peek()) {
+	for isBlank(l.peek()) {
 		l.advance()
 	}
 }
@@ -266,10 +404,7 @@ OUTER:
 	for {
 		r := l.peek()
 		switch {
-		case r == eof:
-			l.advance()
-			break OUTER
-		case isCharacter(r):
+		case isCharacter(r) || isExtended(r):
 			l.advance()
 		default:
 			break OUTER
@@ -291,7 +426,19 @@ func unescapeQuotes(s string, quot rune) string {
 		}
 
 		switch r {
+		case '\r':
+			// CRLF line endings within the quotes read as LF
+			if r2, _, _ := rdr.ReadRune(); r2 == '\n' {
+				i++
+				r = r2
+			} else {
+				rdr.UnreadRune()
+			}
 		case '\\', quot: // possible escape sequence
+			if r == '\\' && quot == '`' {
+				// backslashes are not escapes within backticks
+				break
+			}
 			if r2, _, _ := rdr.ReadRune(); r2 == quot {
 				i++
 				r = quot
@@ -309,7 +456,7 @@ func (l *lexer) runQuote(pair rune) error {
 		r := l.next()
 		if r == eof {
 			return errors.New(`unexpected eof`)
-		} else if r == '\\' {
+		} else if r == '\\' && pair != '`' {
 			if l.peek() == pair {
 				r = l.next()
 			}
@@ -324,17 +471,19 @@ func (l *lexer) runQuote(pair rune) error {
 	}
 }
 
+// runCComment reads a comment, and returns false if it is not
+// terminated
 // https://dev.mysql.com/doc/refman/5.6/en/comments.html
-func (l *lexer) runCComment() {
+func (l *lexer) runCComment() bool {
 	for {
 		r := l.next()
 		switch r {
 		case eof:
-			return
+			return false
 		case '*':
 			if l.peek() == '/' {
 				l.advance()
-				return
+				return true
 			}
 		}
 	}
@@ -381,6 +530,17 @@ func isSpace(r rune) bool {
 	return r == ' ' || r == '\n' || r == '\t' || r == '\r'
 }
 
+// byteOrderMark is the UTF-8 byte order mark that editors on Windows
+// write at the beginning of files
+var byteOrderMark = []byte("\uFEFF")
+
+// isBlank is like isSpace, but also accepts the other white spaces of
+// Unicode, such as the non-breaking spaces written by GUI tools, and
+// the byte order marks of the files concatenated in the input
+func isBlank(r rune) bool {
+	return isSpace(r) || unicode.IsSpace(r) || r == '\uFEFF'
+}
+
 func isLetter(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
 }
@@ -389,6 +549,75 @@ func isCharacter(r rune) bool {
 	return isDigit(r) || isLetter(r) || r == '_'
 }
 
+// isExtended returns true if r is one of the characters beyond ASCII
+// that MySQL accepts in unquoted identifiers, U+0080 to U+FFFF, except
+// white spaces
+// https://dev.mysql.com/doc/refman/8.0/en/identifiers.html
+func isExtended(r rune) bool {
+	return r >= 0x80 && r <= 0xFFFF && r != utf8.RuneError && !isBlank(r)
+}
+
 func isDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
+
+// unwrapTiDBComments returns a copy of src where the TiDB specific
+// comments, such as `/*T![clustered_index] CLUSTERED */`, are replaced
+// by their contents. The comment markers are overwritten with spaces,
+// so that the positions of the tokens do not change.
+func unwrapTiDBComments(src []byte) []byte {
+	dst := make([]byte, len(src))
+	copy(dst, src)
+
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			dst[i] = ' '
+		}
+	}
+
+	var quote byte
+	var inComment bool
+	for i := 0; i < len(dst); i++ {
+		c := dst[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '#' || (c == '-' && i+2 < len(dst) && dst[i+1] == '-' && isSpace(rune(dst[i+2]))):
+			for i < len(dst) && dst[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(dst) && dst[i+1] == '*':
+			if !bytes.HasPrefix(dst[i+2:], []byte("T!")) {
+				end := bytes.Index(dst[i+2:], []byte("*/"))
+				if end < 0 {
+					return dst
+				}
+				i += end + 3
+				continue
+			}
+			start := i
+			i += 4
+			if i < len(dst) && dst[i] == '[' {
+				end := bytes.IndexByte(dst[i:], ']')
+				if end < 0 {
+					return dst
+				}
+				i += end + 1
+			}
+			blank(start, i)
+			i--
+			inComment = true
+		case c == '*' && inComment && i+1 < len(dst) && dst[i+1] == '/':
+			blank(i, i+2)
+			i++
+			inComment = false
+		}
+	}
+	return dst
+}
@@ -1,9 +1,8 @@
 package schemalex
 
 import (
-	"context"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -83,30 +82,115 @@ func TestLexToken(t *testing.T) {
 			input: "`ho``ge`",
 			token: Token{Value: "ho`ge", Type: BACKTICK_IDENT},
 		},
+		{
+			input: "```hoge```",
+			token: Token{Value: "`hoge`", Type: BACKTICK_IDENT},
+		},
+		{
+			input: "`ho\\ge\\` ",
+			token: Token{Value: "ho\\ge\\", Type: BACKTICK_IDENT},
+		},
 		// ESCAPED STRING BY BACKSLASH
 		{
 			input: `'ho\'ge'`,
 			token: Token{Value: `ho'ge`, Type: SINGLE_QUOTE_IDENT},
 		},
+		// multi-byte characters
+		{
+			input: "`ユーザー`",
+			token: Token{Value: "ユーザー", Type: BACKTICK_IDENT},
+		},
+		{
+			input: "ユーザー名 ",
+			token: Token{Value: "ユーザー名", Type: IDENT},
+		},
+		{
+			input: "'🍣 ''é''' ",
+			token: Token{Value: "🍣 'é'", Type: SINGLE_QUOTE_IDENT},
+		},
+		{
+			input: "/* 日本語 🍣 */",
+			token: Token{Value: "/* 日本語 🍣 */", Type: COMMENT_IDENT},
+		},
 	}
 
 	for _, spec := range specs {
 		t.Logf("Lexing %s", spec.input)
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		input := []byte(spec.input)
+		tok := newLexer(input).nextToken()
+		spec.token.Line = 1
+		spec.token.Col = 1
+		tok.value(input)
+		if !assert.Equal(t, spec.token, *tok, "tok matches") {
+			return
+		}
+	}
+}
+
+func TestLexMultiByte(t *testing.T) {
+	input := []byte("`ü` 'é🍣'\u00A0/* 🍣 */ ß\n  é")
+	l := newLexer(input)
+	var got []Token
+	for {
+		tok := l.nextToken()
+		if tok.Type == EOF {
+			break
+		}
+		if tok.Type != SPACE {
+			got = append(got, Token{Type: tok.Type, Value: tok.value(input), Pos: tok.Pos, Line: tok.Line, Col: tok.Col})
+		}
+	}
+	// columns are counted in characters, positions in bytes
+	expect := []Token{
+		{Type: BACKTICK_IDENT, Value: "ü", Pos: 0, Line: 1, Col: 1},
+		{Type: SINGLE_QUOTE_IDENT, Value: "é🍣", Pos: 5, Line: 1, Col: 5},
+		{Type: COMMENT_IDENT, Value: "/* 🍣 */", Pos: 15, Line: 1, Col: 10},
+		{Type: IDENT, Value: "ß", Pos: 26, Line: 1, Col: 18},
+		{Type: IDENT, Value: "é", Pos: 31, Line: 2, Col: 2},
+	}
+	if !assert.Equal(t, expect, got, "tokens should match") {
+		return
+	}
+}
 
-		ch := lex(ctx, []byte(spec.input))
-		select {
-		case <-ctx.Done():
-			t.Logf("%s", ctx.Err())
-			t.Fail()
+func TestLookupKeyword(t *testing.T) {
+	for _, spec := range []struct {
+		ident string
+		typ   TokenType
+		ok    bool
+	}{
+		{ident: "create", typ: CREATE, ok: true},
+		{ident: "Create", typ: CREATE, ok: true},
+		{ident: "CREATE", typ: CREATE, ok: true},
+		{ident: "creates"},
+		{ident: "créate"},
+		{ident: strings.Repeat("x", 64)},
+	} {
+		typ, ok := lookupKeyword([]byte(spec.ident))
+		if !assert.Equal(t, spec.ok, ok, "%s is a keyword", spec.ident) {
+			return
+		}
+		if ok && !assert.Equal(t, spec.typ, typ, "%s has the keyword type", spec.ident) {
 			return
-		case tok := <-ch:
-			spec.token.Line = 1
-			spec.token.Col = 1
-			if !assert.Equal(t, spec.token, *tok, "tok matches") {
-				return
-			}
+		}
+	}
+}
+
+func BenchmarkLex(b *testing.B) {
+	const table = "CREATE TABLE `t` (\n" +
+		"  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `name` varchar(255) NOT NULL DEFAULT '' COMMENT 'the name',\n" +
+		"  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_name` (`name`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4; -- comment\n"
+	src := []byte(strings.Repeat(table, 1000))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := newLexer(src)
+		for l.nextToken().Type != EOF {
 		}
 	}
 }
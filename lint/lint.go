@@ -3,11 +3,13 @@ package lint
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/pkg/errors"
 	"github.com/eihigh/schemalex"
 	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/option"
 )
 
 type Linter struct{}
@@ -17,6 +19,24 @@ func WithIndent(s string, n int) Option {
 	return format.WithIndent(s, n)
 }
 
+const (
+	optkeyFindings = "findings"
+	optkeyWarnings = "warnings"
+)
+
+// WithFindings specifies a slice that Run stores the findings of the
+// lint rules to, so that callers can act on them
+func WithFindings(findings *[]Finding) Option {
+	return option.New(optkeyFindings, findings)
+}
+
+// WithWarnings specifies the destination of the findings reported by
+// the lint rules, such as redundant indexes. If unspecified, no findings
+// are reported.
+func WithWarnings(w io.Writer) Option {
+	return option.New(optkeyWarnings, w)
+}
+
 func New(options ...Option) *Linter {
 	return &Linter{}
 }
@@ -27,12 +47,36 @@ func (l *Linter) Run(ctx context.Context, src schemalex.SchemaSource, dst io.Wri
 		return errors.Wrap(err, `failed to read from source`)
 	}
 
-	p := schemalex.New()
-	stmts, err := p.Parse(buf.Bytes())
+	// the parser picks up schemalex.WithDialect and WithMySQLVersion
+	p := schemalex.NewParser(options...)
+	stmts, err := p.ParseContext(ctx, buf.Bytes())
 	if err != nil {
 		return errors.Wrap(err, `failed to parse source`)
 	}
 
+	var findings []Finding
+	var checked bool
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyWarnings, optkeyFindings:
+			if !checked {
+				findings, err = Check(stmts, options...)
+				if err != nil {
+					return errors.Wrap(err, `failed to check source`)
+				}
+				checked = true
+			}
+			switch v := o.Value().(type) {
+			case io.Writer:
+				for _, f := range findings {
+					fmt.Fprintln(v, f)
+				}
+			case *[]Finding:
+				*v = findings
+			}
+		}
+	}
+
 	for i, stmt := range stmts {
 		if i != 0 {
 			dst.Write([]byte{'\n', '\n'})
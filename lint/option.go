@@ -0,0 +1,77 @@
+package lint
+
+import (
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+const (
+	optkeyCharsetWidths    = "charset-widths"
+	optkeyDefaultCharset   = "default-charset"
+	optkeyDisableRules     = "disable-rules"
+	optkeyMaxVarcharLength = "max-varchar-length"
+	optkeyMySQLVersion     = "mysql-version"
+	optkeyNamePattern      = "name-pattern"
+	optkeySeverity         = "severity"
+	optkeyTarget           = "target"
+)
+
+type ruleSeverity struct {
+	rule     string
+	severity Severity
+}
+
+// WithDisableRules specifies the rules that are not run by Check
+func WithDisableRules(names ...string) Option {
+	return option.New(optkeyDisableRules, names)
+}
+
+// WithSeverity overrides the severity of the findings of a rule
+func WithSeverity(rule string, s Severity) Option {
+	return option.New(optkeySeverity, ruleSeverity{rule: rule, severity: s})
+}
+
+// WithMaxVarcharLength specifies the maximum length of VARCHAR columns
+// allowed by the "varchar-length" rule. The default is 255
+func WithMaxVarcharLength(n int) Option {
+	return option.New(optkeyMaxVarcharLength, n)
+}
+
+// WithNamePattern specifies the regular expression that the names of
+// tables and columns must match for the "naming" rule. The default is
+// `^[a-z][a-z0-9_]*$`
+func WithNamePattern(pattern string) Option {
+	return option.New(optkeyNamePattern, pattern)
+}
+
+// WithTarget enables the rules that are specific to the server that the
+// schema is deployed to, such as the "vitess-foreign-key" rule for
+// TargetVitess
+func WithTarget(t Target) Option {
+	return option.New(optkeyTarget, t)
+}
+
+// WithDefaultCharset specifies the character set of the columns whose
+// tables do not declare one, which the "row-size" and "index-length"
+// rules use to compute the size of string columns. The default is
+// utf8mb4
+func WithDefaultCharset(name string) Option {
+	return option.New(optkeyDefaultCharset, name)
+}
+
+// WithCharsetWidths specifies the maximum number of bytes per character
+// of character sets, for the "row-size" and "index-length" rules. They
+// take precedence over the widths of the character sets known to
+// schemalex, such as 4 for utf8mb4. Unknown character sets are assumed
+// to use 4 bytes per character
+func WithCharsetWidths(widths map[string]int) Option {
+	return option.New(optkeyCharsetWidths, widths)
+}
+
+// WithMySQLVersion specifies the version of the MySQL server that the
+// schema targets. The "reserved-word" rule reports the names that are
+// reserved words of that version, or of the latest version if none is
+// given. Run also parses the schema with schemalex.WithMySQLVersion.
+func WithMySQLVersion(v schemalex.MySQLVersion) Option {
+	return schemalex.WithMySQLVersion(v)
+}
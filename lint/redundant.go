@@ -0,0 +1,142 @@
+package lint
+
+import (
+	"github.com/eihigh/schemalex/model"
+)
+
+// RedundantIndex describes an index that is not needed, because
+// another index of the same table already covers it
+type RedundantIndex struct {
+	Table     string
+	Index     string
+	CoveredBy string
+
+	// Duplicate is true if both indexes have the same columns, and
+	// false if the columns of the index are a prefix of the other one
+	Duplicate bool
+
+	// Position is where the redundant index was declared
+	Position model.Position
+}
+
+func (r *RedundantIndex) String() string {
+	if r.Duplicate {
+		return "index `" + r.Index + "` on table `" + r.Table + "` is a duplicate of `" + r.CoveredBy + "`"
+	}
+	return "index `" + r.Index + "` on table `" + r.Table + "` is a prefix of `" + r.CoveredBy + "`"
+}
+
+type indexInfo struct {
+	name    string
+	family  string
+	unique  bool
+	primary bool
+	columns []string
+	pos     model.Position
+}
+
+func newIndexInfo(idx model.Index) *indexInfo {
+	info := &indexInfo{
+		name:    idx.Name(),
+		family:  "BTREE",
+		unique:  idx.IsUnique() || idx.IsPrimaryKey(),
+		primary: idx.IsPrimaryKey(),
+		pos:     idx.Position(),
+	}
+	switch {
+	case idx.IsPrimaryKey():
+		info.name = "PRIMARY"
+	case !idx.HasName():
+		info.name = idx.Symbol()
+	}
+	switch {
+	case idx.IsFullText():
+		info.family = "FULLTEXT"
+	case idx.IsSpatial():
+		info.family = "SPATIAL"
+	case idx.IsHash():
+		info.family = "HASH"
+	}
+
+	for col := range idx.Columns() {
+		key := col.ID()
+		if col.IsDescending() {
+			key += " DESC"
+		}
+		info.columns = append(info.columns, key)
+	}
+	return info
+}
+
+// covers returns true if the columns of other are a prefix of the
+// columns of info
+func (info *indexInfo) covers(other *indexInfo) bool {
+	if info.family != other.family || len(other.columns) > len(info.columns) {
+		return false
+	}
+	// only B-tree indexes can be used by their prefixes
+	if info.family != "BTREE" && len(other.columns) != len(info.columns) {
+		return false
+	}
+	for i, col := range other.columns {
+		if info.columns[i] != col {
+			return false
+		}
+	}
+	return true
+}
+
+// RedundantIndexes returns the indexes that are redundant, either
+// because another index has the same columns, or because their columns
+// are a prefix of the columns of another index. Unique indexes are only
+// reported when another unique index has the same columns, as they also
+// enforce a constraint, and primary keys are never reported. Of two
+// duplicate indexes, the one that is declared last is reported.
+// Foreign keys are not considered.
+func RedundantIndexes(stmts model.Stmts) []*RedundantIndex {
+	var list []*RedundantIndex
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+
+		var indexes []*indexInfo
+		for idx := range table.Indexes() {
+			if !idx.IsForeignKey() {
+				indexes = append(indexes, newIndexInfo(idx))
+			}
+		}
+
+		for i, idx := range indexes {
+			if idx.primary {
+				continue
+			}
+
+			for j, other := range indexes {
+				if i == j || !other.covers(idx) {
+					continue
+				}
+
+				duplicate := len(idx.columns) == len(other.columns)
+				if idx.unique && (!duplicate || !other.unique) {
+					continue
+				}
+				// of two identical indexes, only report the latter
+				if duplicate && idx.unique == other.unique && !other.primary && j > i {
+					continue
+				}
+
+				list = append(list, &RedundantIndex{
+					Table:     table.Name(),
+					Index:     idx.name,
+					CoveredBy: other.name,
+					Duplicate: duplicate,
+					Position:  idx.pos,
+				})
+				break
+			}
+		}
+	}
+	return list
+}
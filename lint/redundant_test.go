@@ -0,0 +1,45 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedundantIndexes(t *testing.T) {
+	const src = `CREATE TABLE fuga (
+  id INTEGER NOT NULL,
+  a INTEGER,
+  b INTEGER,
+  PRIMARY KEY (id),
+  KEY by_id (id),
+  KEY by_a (a),
+  KEY by_a_b (a, b),
+  KEY by_b (b),
+  KEY by_b_2 (b),
+  UNIQUE KEY u_a (a),
+  UNIQUE KEY u_a_b (a, b)
+);`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var got []string
+	for _, r := range lint.RedundantIndexes(stmts) {
+		got = append(got, r.String())
+	}
+
+	expected := []string{
+		"index `by_id` on table `fuga` is a duplicate of `PRIMARY`",
+		"index `by_a` on table `fuga` is a prefix of `by_a_b`",
+		"index `by_a_b` on table `fuga` is a duplicate of `u_a_b`",
+		"index `by_b_2` on table `fuga` is a duplicate of `by_b`",
+	}
+	if !assert.Equal(t, expected, got) {
+		return
+	}
+}
@@ -0,0 +1,53 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedWord(t *testing.T) {
+	const src = "CREATE TABLE `order` (\n" +
+		"  id INT NOT NULL,\n" +
+		"  `rank` INT,\n" +
+		"  PRIMARY KEY (id),\n" +
+		"  KEY `window` (`rank`)\n" +
+		");"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	check := func(options ...lint.Option) []string {
+		findings, err := lint.Check(stmts, options...)
+		if !assert.NoError(t, err, "check should succeed") {
+			return nil
+		}
+		var got []string
+		for _, f := range findings {
+			if f.Rule == "reserved-word" {
+				got = append(got, f.String())
+			}
+		}
+		return got
+	}
+
+	expected := []string{
+		"1:1: warning: table name `order` is a reserved word in MySQL: rename it, or always quote it (reserved-word)",
+		"3:2: warning: column name `order`.`rank` is a reserved word in MySQL: rename it, or always quote it (reserved-word)",
+		"5:2: warning: index name `window` is a reserved word in MySQL: rename it, or always quote it (reserved-word)",
+	}
+	if !assert.Equal(t, expected, check()) {
+		return
+	}
+
+	expected = []string{
+		"1:1: warning: table name `order` is a reserved word in MySQL 5.7.0: rename it, or always quote it (reserved-word)",
+	}
+	if !assert.Equal(t, expected, check(lint.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 7}))) {
+		return
+	}
+}
@@ -0,0 +1,483 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/model"
+	"github.com/pkg/errors"
+)
+
+// Severity describes how serious a finding is
+type Severity int
+
+// List of possible Severity values
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "Severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// ParseSeverity returns the Severity with the given name, such as "warning"
+func ParseSeverity(s string) (Severity, error) {
+	for _, v := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if v.String() == strings.ToLower(s) {
+			return v, nil
+		}
+	}
+	return 0, errors.Errorf(`unknown severity %s`, s)
+}
+
+// Finding is a problem found by a rule
+type Finding struct {
+	Rule     string
+	Severity Severity
+	// Position is where the offending table, column, or index was
+	// declared, if known
+	Position model.Position
+	Message  string
+}
+
+// String returns the finding in the form
+// "file:line:col: severity: message (rule)"
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Position, f.Severity, f.Message, f.Rule)
+}
+
+// Rule is the interface for lint rules. Rules made available using
+// Register are run by Check, and therefore by the command line tool,
+// along with the built-in rules.
+type Rule interface {
+	// Name returns the name of the rule, such as "primary-key", which
+	// is used to disable the rule or to change its severity
+	Name() string
+	// Check returns the problems found in the statements. The Rule
+	// field of the findings does not need to be set.
+	Check(stmts model.Stmts) []Finding
+}
+
+type ruleFunc struct {
+	name  string
+	check func(model.Stmts) []Finding
+}
+
+// NewRule creates a Rule from a function
+func NewRule(name string, check func(model.Stmts) []Finding) Rule {
+	return &ruleFunc{name: name, check: check}
+}
+
+func (r *ruleFunc) Name() string {
+	return r.name
+}
+
+func (r *ruleFunc) Check(stmts model.Stmts) []Finding {
+	return r.check(stmts)
+}
+
+// config holds the settings of the built-in rules
+type config struct {
+	maxVarcharLength int
+	namePattern      *regexp.Regexp
+	target           Target
+	defaultCharset   string
+	charsetWidths    map[string]int
+	version          schemalex.MySQLVersion
+}
+
+func defaultConfig() config {
+	return config{
+		maxVarcharLength: 255,
+		namePattern:      regexp.MustCompile(`^[a-z][a-z0-9_]*$`),
+		defaultCharset:   "utf8mb4",
+	}
+}
+
+func (cfg *config) setCharsetWidths(widths map[string]int) {
+	cfg.charsetWidths = make(map[string]int)
+	for name, n := range widths {
+		cfg.charsetWidths[strings.ToLower(name)] = n
+	}
+}
+
+// builtinRule is a rule that checks each table, using the settings
+// given to Check. Rules with a target only run for that target.
+type builtinRule struct {
+	name     string
+	severity Severity
+	target   Target
+	check    func(cfg *config, table model.Table) []Finding
+}
+
+func (r *builtinRule) Name() string {
+	return r.name
+}
+
+func (r *builtinRule) Check(stmts model.Stmts) []Finding {
+	cfg := defaultConfig()
+	return r.checkConfig(&cfg, stmts)
+}
+
+func (r *builtinRule) checkConfig(cfg *config, stmts model.Stmts) []Finding {
+	var findings []Finding
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok || table.HasLikeTable() {
+			continue
+		}
+		for _, f := range r.check(cfg, table) {
+			f.Severity = r.severity
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// registry holds the rules in the order that they were registered,
+// starting with the built-in rules
+var registry = struct {
+	sync.RWMutex
+	rules []Rule
+	names map[string]Rule
+}{
+	names: make(map[string]Rule),
+}
+
+func init() {
+	Register(&builtinRule{name: "primary-key", severity: SeverityError, check: checkPrimaryKey})
+	Register(&builtinRule{name: "foreign-key-index", severity: SeverityWarning, check: checkForeignKeyIndex})
+	Register(&builtinRule{name: "redundant-index", severity: SeverityWarning, check: checkRedundantIndex})
+	Register(&builtinRule{name: "varchar-length", severity: SeverityWarning, check: checkVarcharLength})
+	Register(&builtinRule{name: "naming", severity: SeverityWarning, check: checkNaming})
+	Register(&builtinRule{name: "reserved-word", severity: SeverityWarning, check: checkReservedWord})
+	Register(&builtinRule{name: "row-size", severity: SeverityError, check: checkRowSize})
+	Register(&builtinRule{name: "index-length", severity: SeverityError, check: checkIndexLength})
+	Register(&builtinRule{name: "vitess-foreign-key", severity: SeverityError, target: TargetVitess, check: checkVitessForeignKey})
+	Register(&builtinRule{name: "vitess-auto-increment", severity: SeverityWarning, target: TargetVitess, check: checkVitessAutoIncrement})
+	Register(&builtinRule{name: "vitess-unique-key", severity: SeverityError, target: TargetVitess, check: checkVitessUniqueKey})
+}
+
+// Register makes a rule available to Check. All registered rules are
+// enabled by default. Register panics if a rule with the same name is
+// already registered.
+func Register(r Rule) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if r == nil {
+		panic("lint: Register rule is nil")
+	}
+	if _, ok := registry.names[r.Name()]; ok {
+		panic("lint: Register called twice for rule " + r.Name())
+	}
+	registry.rules = append(registry.rules, r)
+	registry.names[r.Name()] = r
+}
+
+// Lookup returns the registered rule with the given name
+func Lookup(name string) (Rule, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	r, ok := registry.names[name]
+	return r, ok
+}
+
+// Rules returns the names of the registered rules, in the order that
+// they were registered
+func Rules() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, len(registry.rules))
+	for i, r := range registry.rules {
+		names[i] = r.Name()
+	}
+	return names
+}
+
+// Check runs the registered rules over the statements, and returns the
+// findings sorted by their positions. Rules can be disabled using
+// WithDisableRules, and their severities changed using WithSeverity.
+// The rules specific to a target only run if it is given by WithTarget.
+func Check(stmts model.Stmts, options ...Option) ([]Finding, error) {
+	cfg := defaultConfig()
+	disabled := make(map[string]bool)
+	severities := make(map[string]Severity)
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyDisableRules:
+			for _, name := range o.Value().([]string) {
+				disabled[name] = true
+			}
+		case optkeySeverity:
+			v := o.Value().(ruleSeverity)
+			severities[v.rule] = v.severity
+		case optkeyMaxVarcharLength:
+			cfg.maxVarcharLength = o.Value().(int)
+		case optkeyNamePattern:
+			re, err := regexp.Compile(o.Value().(string))
+			if err != nil {
+				return nil, errors.Wrap(err, `invalid name pattern`)
+			}
+			cfg.namePattern = re
+		case optkeyTarget:
+			cfg.target = o.Value().(Target)
+		case optkeyMySQLVersion:
+			cfg.version = o.Value().(schemalex.MySQLVersion)
+		case optkeyDefaultCharset:
+			cfg.defaultCharset = o.Value().(string)
+		case optkeyCharsetWidths:
+			cfg.setCharsetWidths(o.Value().(map[string]int))
+		}
+	}
+
+	for name := range disabled {
+		if _, ok := Lookup(name); !ok {
+			return nil, errors.Errorf(`unknown rule %s`, name)
+		}
+	}
+	for name := range severities {
+		if _, ok := Lookup(name); !ok {
+			return nil, errors.Errorf(`unknown rule %s`, name)
+		}
+	}
+
+	registry.RLock()
+	rules := append([]Rule(nil), registry.rules...)
+	registry.RUnlock()
+
+	var findings []Finding
+	for _, r := range rules {
+		if disabled[r.Name()] {
+			continue
+		}
+
+		var list []Finding
+		if br, ok := r.(*builtinRule); ok {
+			if br.target != cfg.target && br.target != "" {
+				continue
+			}
+			list = br.checkConfig(&cfg, stmts)
+		} else {
+			list = r.Check(stmts)
+		}
+		for _, f := range list {
+			f.Rule = r.Name()
+			if severity, ok := severities[r.Name()]; ok {
+				f.Severity = severity
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Position, findings[j].Position
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return findings, nil
+}
+
+// checkPrimaryKey reports tables without a primary key
+func checkPrimaryKey(cfg *config, table model.Table) []Finding {
+	for idx := range table.Indexes() {
+		if idx.IsPrimaryKey() {
+			return nil
+		}
+	}
+	return []Finding{{
+		Position: table.Position(),
+		Message:  "table `" + table.Name() + "` has no primary key",
+	}}
+}
+
+// checkForeignKeyIndex reports foreign keys whose columns are not the
+// leading columns of an index declared in the table. The index that
+// is created implicitly for the foreign key does not count.
+func checkForeignKeyIndex(cfg *config, table model.Table) []Finding {
+	var fks, indexes []model.Index
+	for idx := range table.Indexes() {
+		if idx.IsForeignKey() {
+			fks = append(fks, idx)
+		} else {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	var findings []Finding
+	for _, fk := range fks {
+		columns := indexColumnNames(fk)
+		var indexed bool
+		for _, idx := range indexes {
+			// implicit indexes share the position of the foreign key
+			if fk.Position().IsValid() && idx.Position() == fk.Position() {
+				continue
+			}
+			if hasPrefix(indexColumnNames(idx), columns) {
+				indexed = true
+				break
+			}
+		}
+		if !indexed {
+			findings = append(findings, Finding{
+				Position: fk.Position(),
+				Message:  "columns (" + strings.Join(columns, ", ") + ") of foreign key `" + foreignKeyName(table, fk) + "` on table `" + table.Name() + "` are not indexed",
+			})
+		}
+	}
+	return findings
+}
+
+// checkRedundantIndex reports the indexes found by RedundantIndexes
+func checkRedundantIndex(cfg *config, table model.Table) []Finding {
+	var findings []Finding
+	for _, r := range RedundantIndexes(model.Stmts{table}) {
+		findings = append(findings, Finding{
+			Position: r.Position,
+			Message:  r.String(),
+		})
+	}
+	return findings
+}
+
+// checkVarcharLength reports VARCHAR columns that are longer than the
+// maximum length
+func checkVarcharLength(cfg *config, table model.Table) []Finding {
+	var findings []Finding
+	for col := range table.Columns() {
+		if col.Type() != model.ColumnTypeVarChar || !col.HasLength() {
+			continue
+		}
+		n, err := strconv.Atoi(col.Length().Length())
+		if err != nil || n <= cfg.maxVarcharLength {
+			continue
+		}
+		findings = append(findings, Finding{
+			Position: col.Position(),
+			Message:  fmt.Sprintf("column `%s`.`%s` is VARCHAR (%d), which is longer than %d", table.Name(), col.Name(), n, cfg.maxVarcharLength),
+		})
+	}
+	return findings
+}
+
+// checkNaming reports tables and columns whose names do not match the
+// naming pattern
+func checkNaming(cfg *config, table model.Table) []Finding {
+	var findings []Finding
+	if !cfg.namePattern.MatchString(table.Name()) {
+		findings = append(findings, Finding{
+			Position: table.Position(),
+			Message:  "table name `" + table.Name() + "` does not match " + cfg.namePattern.String(),
+		})
+	}
+	for col := range table.Columns() {
+		if !cfg.namePattern.MatchString(col.Name()) {
+			findings = append(findings, Finding{
+				Position: col.Position(),
+				Message:  "column name `" + table.Name() + "`.`" + col.Name() + "` does not match " + cfg.namePattern.String(),
+			})
+		}
+	}
+	return findings
+}
+
+// checkReservedWord reports tables, columns, indexes and constraints
+// whose names are reserved words of the target MySQL version, which
+// break statements that do not quote them
+func checkReservedWord(cfg *config, table model.Table) []Finding {
+	version := "MySQL"
+	if !cfg.version.IsZero() {
+		version += " " + cfg.version.String()
+	}
+
+	var findings []Finding
+	report := func(pos model.Position, kind, name, display string) {
+		if schemalex.IsReservedWord(name, cfg.version) {
+			findings = append(findings, Finding{
+				Position: pos,
+				Message:  kind + " name " + display + " is a reserved word in " + version + ": rename it, or always quote it",
+			})
+		}
+	}
+
+	report(table.Position(), "table", table.Name(), "`"+table.Name()+"`")
+	for col := range table.Columns() {
+		report(col.Position(), "column", col.Name(), "`"+table.Name()+"`.`"+col.Name()+"`")
+	}
+	for idx := range table.Indexes() {
+		if idx.HasName() {
+			report(idx.Position(), "index", idx.Name(), "`"+idx.Name()+"`")
+		}
+		if idx.HasSymbol() {
+			report(idx.Position(), "constraint", idx.Symbol(), "`"+idx.Symbol()+"`")
+		}
+	}
+	return findings
+}
+
+func indexColumnNames(idx model.Index) []string {
+	var names []string
+	for col := range idx.Columns() {
+		names = append(names, col.Name())
+	}
+	return names
+}
+
+func hasPrefix(columns, prefix []string) bool {
+	if len(prefix) > len(columns) {
+		return false
+	}
+	for i, name := range prefix {
+		if columns[i] != name {
+			return false
+		}
+	}
+	return true
+}
+
+// foreignKeyName returns the symbol or the name of the foreign key, or
+// for foreign keys declared with neither, the symbol that the server
+// generates for it, such as users_ibfk_1 for the first of them in the
+// table users
+func foreignKeyName(table model.Table, idx model.Index) string {
+	if idx.HasSymbol() {
+		return idx.Symbol()
+	}
+	if idx.HasName() {
+		return idx.Name()
+	}
+
+	var n int
+	for fk := range table.Indexes() {
+		if !fk.IsForeignKey() || fk.HasSymbol() || fk.HasName() {
+			continue
+		}
+		n++
+		if fk == idx {
+			break
+		}
+	}
+	return table.Name() + "_ibfk_" + strconv.Itoa(n)
+}
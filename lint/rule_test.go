@@ -0,0 +1,176 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/eihigh/schemalex/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	const src = `CREATE TABLE parent (
+  id INTEGER NOT NULL,
+  PRIMARY KEY (id)
+);
+CREATE TABLE Child (
+  id INTEGER NOT NULL,
+  parent_id INTEGER NOT NULL,
+  title VARCHAR (1024),
+  CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES parent (id)
+);
+CREATE TABLE indexed (
+  id INTEGER NOT NULL,
+  parent_id INTEGER NOT NULL,
+  PRIMARY KEY (id),
+  KEY by_parent (parent_id),
+  CONSTRAINT fk_indexed FOREIGN KEY (parent_id) REFERENCES parent (id)
+);`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		findings, err := lint.Check(stmts)
+		if !assert.NoError(t, err, "check should succeed") {
+			return
+		}
+
+		var got []string
+		for _, f := range findings {
+			got = append(got, f.String())
+		}
+		expected := []string{
+			"5:0: error: table `Child` has no primary key (primary-key)",
+			"5:0: warning: table name `Child` does not match ^[a-z][a-z0-9_]*$ (naming)",
+			"8:2: warning: column `Child`.`title` is VARCHAR (1024), which is longer than 255 (varchar-length)",
+			"9:2: warning: columns (parent_id) of foreign key `fk_parent` on table `Child` are not indexed (foreign-key-index)",
+			"16:2: warning: index `fk_indexed` on table `indexed` is a duplicate of `by_parent` (redundant-index)",
+		}
+		if !assert.Equal(t, expected, got) {
+			return
+		}
+	})
+	t.Run("Options", func(t *testing.T) {
+		findings, err := lint.Check(stmts,
+			lint.WithDisableRules("naming", "foreign-key-index", "redundant-index"),
+			lint.WithSeverity("primary-key", lint.SeverityInfo),
+			lint.WithMaxVarcharLength(2048),
+		)
+		if !assert.NoError(t, err, "check should succeed") {
+			return
+		}
+		if !assert.Len(t, findings, 1) {
+			return
+		}
+		if !assert.Equal(t, "primary-key", findings[0].Rule) {
+			return
+		}
+		if !assert.Equal(t, lint.SeverityInfo, findings[0].Severity) {
+			return
+		}
+	})
+	t.Run("UnnamedForeignKey", func(t *testing.T) {
+		stmts, err := schemalex.New().ParseString(`CREATE TABLE orders (
+  id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  FOREIGN KEY (item_id) REFERENCES items (id)
+);`)
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+		findings, err := lint.Check(stmts)
+		if !assert.NoError(t, err, "check should succeed") {
+			return
+		}
+
+		var got []string
+		for _, f := range findings {
+			got = append(got, f.String())
+		}
+		expected := []string{
+			"6:2: warning: columns (user_id) of foreign key `orders_ibfk_1` on table `orders` are not indexed (foreign-key-index)",
+			"7:2: warning: columns (item_id) of foreign key `orders_ibfk_2` on table `orders` are not indexed (foreign-key-index)",
+		}
+		if !assert.Equal(t, expected, got) {
+			return
+		}
+	})
+	t.Run("UnknownRule", func(t *testing.T) {
+		_, err := lint.Check(stmts, lint.WithDisableRules("no-such-rule"))
+		if !assert.Error(t, err, "check should fail") {
+			return
+		}
+	})
+}
+
+func TestRegister(t *testing.T) {
+	lint.Register(lint.NewRule("test-timestamps", func(stmts model.Stmts) []lint.Finding {
+		var findings []lint.Finding
+		for _, stmt := range stmts {
+			table, ok := stmt.(model.Table)
+			if !ok {
+				continue
+			}
+			if _, ok := table.LookupColumn(model.NewTableColumn("created_at").ID()); !ok {
+				findings = append(findings, lint.Finding{
+					Severity: lint.SeverityError,
+					Position: table.Position(),
+					Message:  "table `" + table.Name() + "` has no created_at column",
+				})
+			}
+		}
+		return findings
+	}))
+
+	if !assert.Panics(t, func() {
+		lint.Register(lint.NewRule("test-timestamps", nil))
+	}, "registering the same name twice should panic") {
+		return
+	}
+	if !assert.Contains(t, lint.Rules(), "test-timestamps") {
+		return
+	}
+
+	stmts, err := schemalex.New().ParseString(`CREATE TABLE foo (
+  id INTEGER NOT NULL,
+  PRIMARY KEY (id)
+);`)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	findings, err := lint.Check(stmts, lint.WithSeverity("test-timestamps", lint.SeverityWarning))
+	if !assert.NoError(t, err, "check should succeed") {
+		return
+	}
+	expected := []lint.Finding{{
+		Rule:     "test-timestamps",
+		Severity: lint.SeverityWarning,
+		Position: model.Position{Line: 1, Col: 1},
+		Message:  "table `foo` has no created_at column",
+	}}
+	if !assert.Equal(t, expected, findings) {
+		return
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := lint.ParseSeverity("Warning")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, lint.SeverityWarning, s) {
+		return
+	}
+	_, err = lint.ParseSeverity("fatal")
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+}
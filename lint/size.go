@@ -0,0 +1,368 @@
+package lint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+// Limits enforced by MySQL, in bytes
+const (
+	maxRowSize            = 65535
+	maxIndexKeyLength     = 3072
+	maxCompactIndexPrefix = 767
+)
+
+// defaultCharsetWidths lists the maximum number of bytes per character
+// of the common character sets
+var defaultCharsetWidths = map[string]int{
+	"armscii8": 1,
+	"ascii":    1,
+	"big5":     2,
+	"binary":   1,
+	"cp1250":   1,
+	"cp1251":   1,
+	"cp1256":   1,
+	"cp1257":   1,
+	"cp850":    1,
+	"cp852":    1,
+	"cp866":    1,
+	"cp932":    2,
+	"dec8":     1,
+	"eucjpms":  3,
+	"euckr":    2,
+	"gb18030":  4,
+	"gb2312":   2,
+	"gbk":      2,
+	"geostd8":  1,
+	"greek":    1,
+	"hebrew":   1,
+	"hp8":      1,
+	"keybcs2":  1,
+	"koi8r":    1,
+	"koi8u":    1,
+	"latin1":   1,
+	"latin2":   1,
+	"latin5":   1,
+	"latin7":   1,
+	"macce":    1,
+	"macroman": 1,
+	"sjis":     2,
+	"swe7":     1,
+	"tis620":   1,
+	"ucs2":     2,
+	"ujis":     3,
+	"utf16":    4,
+	"utf16le":  4,
+	"utf32":    4,
+	"utf8":     3,
+	"utf8mb3":  3,
+	"utf8mb4":  4,
+}
+
+// RowSize returns the maximum number of bytes that a row of the table
+// counts for in the row size limit of MySQL, as computed by the
+// "row-size" rule. WithDefaultCharset and WithCharsetWidths specify the
+// character sets used to compute the size of string columns.
+func RowSize(table model.Table, options ...Option) int {
+	cfg := defaultConfig()
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyDefaultCharset:
+			cfg.defaultCharset = o.Value().(string)
+		case optkeyCharsetWidths:
+			cfg.setCharsetWidths(o.Value().(map[string]int))
+		}
+	}
+	return rowSize(&cfg, table)
+}
+
+func rowSize(cfg *config, table model.Table) int {
+	var size, nullable int
+	for col := range table.Columns() {
+		size += columnRowSize(cfg, table, col)
+		if col.NullState() != model.NullStateNotNull && !col.IsPrimary() {
+			nullable++
+		}
+	}
+	return size + (nullable+7)/8
+}
+
+// checkRowSize reports tables whose rows may be longer than the 65535
+// bytes that MySQL allows. TEXT, BLOB and JSON columns only count for
+// the size of their length and of the pointer to their data.
+func checkRowSize(cfg *config, table model.Table) []Finding {
+	size := rowSize(cfg, table)
+	if size <= maxRowSize {
+		return nil
+	}
+	return []Finding{{
+		Position: table.Position(),
+		Message:  fmt.Sprintf("rows of table `%s` may be %d bytes long, which is longer than %d: change some columns to TEXT or BLOB", table.Name(), size, maxRowSize),
+	}}
+}
+
+// checkIndexLength reports the indexes of InnoDB tables whose keys may
+// be longer than 3072 bytes, or whose columns may be longer than 767
+// bytes with the COMPACT and REDUNDANT row formats
+func checkIndexLength(cfg *config, table model.Table) []Finding {
+	if engine, ok := tableOption(table, "ENGINE"); ok && !strings.EqualFold(engine, "InnoDB") {
+		return nil
+	}
+	maxPrefix := maxIndexKeyLength
+	if format, ok := tableOption(table, "ROW_FORMAT"); ok {
+		switch strings.ToUpper(format) {
+		case "COMPACT", "REDUNDANT":
+			maxPrefix = maxCompactIndexPrefix
+		}
+	}
+
+	var findings []Finding
+	for idx := range table.Indexes() {
+		if idx.IsFullText() || idx.IsSpatial() {
+			continue
+		}
+
+		var total int
+		for ic := range idx.Columns() {
+			col, ok := table.LookupColumn(model.NewTableColumn(ic.Name()).ID())
+			if !ok {
+				continue
+			}
+			n, ok := indexColumnLength(cfg, table, col, ic)
+			if !ok {
+				continue
+			}
+			if n > maxPrefix {
+				findings = append(findings, Finding{
+					Position: idx.Position(),
+					Message:  fmt.Sprintf("column `%s` of index %s on table `%s` may be %d bytes long, which is longer than %d: use a prefix length", col.Name(), describeIndex(idx), table.Name(), n, maxPrefix),
+				})
+			}
+			total += n
+		}
+		if total > maxIndexKeyLength {
+			findings = append(findings, Finding{
+				Position: idx.Position(),
+				Message:  fmt.Sprintf("keys of index %s on table `%s` may be %d bytes long, which is longer than %d", describeIndex(idx), table.Name(), total, maxIndexKeyLength),
+			})
+		}
+	}
+	return findings
+}
+
+func describeIndex(idx model.Index) string {
+	switch {
+	case idx.IsPrimaryKey():
+		return "PRIMARY"
+	case idx.HasName():
+		return "`" + idx.Name() + "`"
+	case idx.HasSymbol():
+		return "`" + idx.Symbol() + "`"
+	}
+	var names []string
+	for col := range idx.Columns() {
+		names = append(names, col.Name())
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func tableOption(table model.Table, key string) (string, bool) {
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), key) {
+			return opt.Value(), true
+		}
+	}
+	return "", false
+}
+
+// charsetWidth returns the maximum number of bytes per character of
+// the column, using the character set of the table if the column does
+// not declare one, and the default character set if neither does
+func charsetWidth(cfg *config, table model.Table, col model.TableColumn) int {
+	charset := cfg.defaultCharset
+	switch {
+	case col.HasCharacterSet():
+		charset = col.CharacterSet()
+	case col.HasCollation():
+		charset = collationCharset(col.Collation())
+	default:
+		if v, ok := tableOption(table, "DEFAULT CHARACTER SET"); ok {
+			charset = v
+		} else if v, ok := tableOption(table, "DEFAULT COLLATE"); ok {
+			charset = collationCharset(v)
+		}
+	}
+
+	charset = strings.ToLower(charset)
+	if n, ok := cfg.charsetWidths[charset]; ok {
+		return n
+	}
+	if n, ok := defaultCharsetWidths[charset]; ok {
+		return n
+	}
+	// unknown character sets are assumed to be as wide as utf8mb4
+	return 4
+}
+
+// collationCharset returns the character set of a collation, such as
+// utf8mb4 for utf8mb4_general_ci
+func collationCharset(collation string) string {
+	if i := strings.IndexByte(collation, '_'); i > 0 {
+		return collation[:i]
+	}
+	return collation
+}
+
+func lengthOf(col model.TableColumn, def int) int {
+	if !col.HasLength() {
+		return def
+	}
+	n, err := strconv.Atoi(col.Length().Length())
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// lengthBytes returns the number of bytes used to store the length of
+// a variable length value of at most n bytes
+func lengthBytes(n int) int {
+	if n > 255 {
+		return 2
+	}
+	return 1
+}
+
+// columnDataSize returns the maximum number of bytes used to store the
+// value of a column whose size is bounded, without the length of
+// variable length values. It returns false for TEXT, BLOB and JSON
+// columns.
+func columnDataSize(cfg *config, table model.Table, col model.TableColumn) (int, bool) {
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt, model.ColumnTypeYear:
+		return 1, true
+	case model.ColumnTypeSmallInt:
+		return 2, true
+	case model.ColumnTypeMediumInt, model.ColumnTypeDate:
+		return 3, true
+	case model.ColumnTypeInt, model.ColumnTypeInet4:
+		return 4, true
+	case model.ColumnTypeBigInt, model.ColumnTypeDouble:
+		return 8, true
+	case model.ColumnTypeFloat:
+		if col.HasLength() && !col.Length().HasDecimal() && lengthOf(col, 0) > 24 {
+			return 8, true
+		}
+		return 4, true
+	case model.ColumnTypeDecimal:
+		return decimalSize(col), true
+	case model.ColumnTypeBit:
+		return (lengthOf(col, 1) + 7) / 8, true
+	case model.ColumnTypeTime:
+		return 3 + fractionalSize(col), true
+	case model.ColumnTypeTimestamp:
+		return 4 + fractionalSize(col), true
+	case model.ColumnTypeDateTime:
+		return 5 + fractionalSize(col), true
+	case model.ColumnTypeInet6, model.ColumnTypeUUID:
+		return 16, true
+	case model.ColumnTypeChar, model.ColumnTypeVarChar:
+		return lengthOf(col, 1) * charsetWidth(cfg, table, col), true
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary:
+		return lengthOf(col, 1), true
+	case model.ColumnTypeEnum:
+		var n int
+		for range col.EnumValues() {
+			n++
+		}
+		if n > 255 {
+			return 2, true
+		}
+		return 1, true
+	case model.ColumnTypeSet:
+		var n int
+		for range col.SetValues() {
+			n++
+		}
+		if size := (n + 7) / 8; size <= 4 {
+			return size, true
+		}
+		return 8, true
+	}
+	return 0, false
+}
+
+// columnRowSize returns the number of bytes that the column counts for
+// in the row size limit
+func columnRowSize(cfg *config, table model.Table, col model.TableColumn) int {
+	if n, ok := columnDataSize(cfg, table, col); ok {
+		switch col.Type() {
+		case model.ColumnTypeVarChar, model.ColumnTypeVarBinary:
+			return n + lengthBytes(n)
+		}
+		return n
+	}
+
+	// the length of the value, and a pointer to it
+	switch col.Type() {
+	case model.ColumnTypeTinyBlob, model.ColumnTypeTinyText:
+		return 1 + 8
+	case model.ColumnTypeBlob, model.ColumnTypeText:
+		return 2 + 8
+	case model.ColumnTypeMediumBlob, model.ColumnTypeMediumText:
+		return 3 + 8
+	}
+	return 4 + 8
+}
+
+// indexColumnLength returns the number of bytes that the column counts
+// for in the index key length limit. It returns false for TEXT and BLOB
+// columns without a prefix length.
+func indexColumnLength(cfg *config, table model.Table, col model.TableColumn, ic model.IndexColumn) (int, bool) {
+	if ic.HasLength() {
+		if n, err := strconv.Atoi(ic.Length()); err == nil {
+			switch col.Type() {
+			case model.ColumnTypeChar, model.ColumnTypeVarChar,
+				model.ColumnTypeTinyText, model.ColumnTypeText, model.ColumnTypeMediumText, model.ColumnTypeLongText:
+				return n * charsetWidth(cfg, table, col), true
+			}
+			return n, true
+		}
+	}
+	return columnDataSize(cfg, table, col)
+}
+
+// decimalSize returns the number of bytes used to store a DECIMAL
+// value: each group of 9 digits takes 4 bytes, and the remaining
+// digits of the integer and fractional parts take up to 4 bytes
+func decimalSize(col model.TableColumn) int {
+	precision, scale := 10, 0
+	if col.HasLength() {
+		l := col.Length()
+		if n, err := strconv.Atoi(l.Length()); err == nil {
+			precision = n
+		}
+		if l.HasDecimal() {
+			if n, err := strconv.Atoi(l.Decimal()); err == nil {
+				scale = n
+			}
+		}
+	}
+	if precision < 0 || scale < 0 || scale > precision {
+		return 0
+	}
+
+	digits := func(n int) int {
+		return n/9*4 + [9]int{0, 1, 1, 2, 2, 3, 3, 4, 4}[n%9]
+	}
+	return digits(precision-scale) + digits(scale)
+}
+
+// fractionalSize returns the number of bytes used to store the
+// fractional seconds of TIME, DATETIME and TIMESTAMP values
+func fractionalSize(col model.TableColumn) int {
+	return (lengthOf(col, 0) + 1) / 2
+}
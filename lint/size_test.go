@@ -0,0 +1,88 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeLimits(t *testing.T) {
+	const src = `CREATE TABLE wide (
+  id INT NOT NULL,
+  a VARCHAR (10000),
+  b VARCHAR (10000),
+  c TEXT,
+  PRIMARY KEY (id)
+) DEFAULT CHARACTER SET utf8mb4;
+CREATE TABLE narrow (
+  id INT NOT NULL,
+  a VARCHAR (20000),
+  b VARCHAR (20000),
+  PRIMARY KEY (id)
+) DEFAULT CHARACTER SET latin1;
+CREATE TABLE keys (
+  id INT NOT NULL,
+  email VARCHAR (255),
+  url VARCHAR (1000),
+  body TEXT,
+  PRIMARY KEY (id),
+  KEY by_email (email),
+  KEY by_url (url),
+  KEY by_url_prefix (url (500)),
+  KEY by_both (email, url (600)),
+  KEY by_body (body (100))
+);
+CREATE TABLE legacy (
+  id INT NOT NULL,
+  email VARCHAR (256) CHARACTER SET utf8,
+  name VARCHAR (255) COLLATE ascii_bin,
+  PRIMARY KEY (id),
+  KEY by_email (email),
+  KEY by_name (name)
+) ROW_FORMAT = COMPACT;
+CREATE TABLE myisam (
+  id INT NOT NULL,
+  url VARCHAR (1000),
+  PRIMARY KEY (id),
+  KEY by_url (url)
+) ENGINE = MyISAM;`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	check := func(options ...lint.Option) []string {
+		findings, err := lint.Check(stmts, options...)
+		if !assert.NoError(t, err, "check should succeed") {
+			return nil
+		}
+		var got []string
+		for _, f := range findings {
+			if f.Rule == "row-size" || f.Rule == "index-length" {
+				got = append(got, f.String())
+			}
+		}
+		return got
+	}
+
+	expected := []string{
+		"1:1: error: rows of table `wide` may be 80019 bytes long, which is longer than 65535: change some columns to TEXT or BLOB (row-size)",
+		"21:2: error: column `url` of index `by_url` on table `keys` may be 4000 bytes long, which is longer than 3072: use a prefix length (index-length)",
+		"21:2: error: keys of index `by_url` on table `keys` may be 4000 bytes long, which is longer than 3072 (index-length)",
+		"23:2: error: keys of index `by_both` on table `keys` may be 3420 bytes long, which is longer than 3072 (index-length)",
+		"31:2: error: column `email` of index `by_email` on table `legacy` may be 768 bytes long, which is longer than 767: use a prefix length (index-length)",
+	}
+	if !assert.Equal(t, expected, check()) {
+		return
+	}
+
+	expected = []string{
+		"31:2: error: column `email` of index `by_email` on table `legacy` may be 768 bytes long, which is longer than 767: use a prefix length (index-length)",
+	}
+	if !assert.Equal(t, expected, check(lint.WithDefaultCharset("latin1"), lint.WithCharsetWidths(map[string]int{"UTF8MB4": 2}))) {
+		return
+	}
+}
@@ -0,0 +1,113 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+	"github.com/pkg/errors"
+)
+
+// Target is the kind of server that the schema is deployed to. Rules
+// that are specific to a target only run when it is given to Check
+// using WithTarget.
+type Target string
+
+// List of possible Target values
+const (
+	TargetVitess Target = "vitess"
+)
+
+// ParseTarget returns the Target with the given name, such as "vitess".
+// PlanetScale is accepted as an alias of Vitess.
+func ParseTarget(s string) (Target, error) {
+	switch strings.ToLower(s) {
+	case "vitess", "planetscale":
+		return TargetVitess, nil
+	}
+	return "", errors.Errorf(`unknown target %s`, s)
+}
+
+// checkVitessForeignKey reports foreign keys, which Vitess does not
+// enforce across shards, and which block its online schema changes
+func checkVitessForeignKey(cfg *config, table model.Table) []Finding {
+	var findings []Finding
+	for idx := range table.Indexes() {
+		if !idx.IsForeignKey() {
+			continue
+		}
+		findings = append(findings, Finding{
+			Position: idx.Position(),
+			Message:  "foreign key `" + foreignKeyName(table, idx) + "` on table `" + table.Name() + "` is not supported by Vitess: drop the constraint and enforce the relation in the application",
+		})
+	}
+	return findings
+}
+
+// checkVitessAutoIncrement reports AUTO_INCREMENT columns, whose values
+// are not unique across the shards of a keyspace
+func checkVitessAutoIncrement(cfg *config, table model.Table) []Finding {
+	var findings []Finding
+	for col := range table.Columns() {
+		if !col.IsAutoIncrement() {
+			continue
+		}
+		msg := "AUTO_INCREMENT column `" + table.Name() + "`.`" + col.Name() + "` is not unique across shards: "
+		if isPrimaryKeyColumn(table, col) {
+			msg += "use a Vitess sequence for it in sharded keyspaces"
+		} else {
+			msg += "make it the primary key and use a Vitess sequence for it, or drop AUTO_INCREMENT"
+		}
+		findings = append(findings, Finding{
+			Position: col.Position(),
+			Message:  msg,
+		})
+	}
+	return findings
+}
+
+// checkVitessUniqueKey reports tables without a primary key or a unique
+// key on NOT NULL columns, which Vitess requires to copy the rows of a
+// table during online schema changes
+func checkVitessUniqueKey(cfg *config, table model.Table) []Finding {
+	for idx := range table.Indexes() {
+		if idx.IsPrimaryKey() {
+			return nil
+		}
+		if idx.IsUnique() && isNotNullIndex(table, idx) {
+			return nil
+		}
+	}
+	for col := range table.Columns() {
+		if col.IsPrimary() || (col.IsUnique() && col.NullState() == model.NullStateNotNull) {
+			return nil
+		}
+	}
+	return []Finding{{
+		Position: table.Position(),
+		Message:  "table `" + table.Name() + "` has no primary key or unique key on NOT NULL columns, which Vitess requires for online schema changes: add a primary key",
+	}}
+}
+
+func isPrimaryKeyColumn(table model.Table, col model.TableColumn) bool {
+	if col.IsPrimary() {
+		return true
+	}
+	for idx := range table.Indexes() {
+		if !idx.IsPrimaryKey() {
+			continue
+		}
+		columns := indexColumnNames(idx)
+		return len(columns) == 1 && columns[0] == col.Name()
+	}
+	return false
+}
+
+func isNotNullIndex(table model.Table, idx model.Index) bool {
+	for name := range idx.Columns() {
+		col, ok := table.LookupColumn(model.NewTableColumn(name.Name()).ID())
+		if !ok || col.NullState() != model.NullStateNotNull {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVitess(t *testing.T) {
+	const src = `CREATE TABLE parent (
+  id INTEGER NOT NULL AUTO_INCREMENT,
+  PRIMARY KEY (id)
+);
+CREATE TABLE child (
+  id INTEGER NOT NULL,
+  seq INTEGER NOT NULL AUTO_INCREMENT,
+  parent_id INTEGER NOT NULL,
+  PRIMARY KEY (id),
+  KEY by_seq (seq),
+  KEY by_parent (parent_id),
+  CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES parent (id)
+);
+CREATE TABLE keyed (
+  code VARCHAR (10) NOT NULL,
+  UNIQUE KEY by_code (code)
+);
+CREATE TABLE nullable (
+  code VARCHAR (10),
+  UNIQUE KEY by_code (code)
+);`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	options := []lint.Option{
+		lint.WithTarget(lint.TargetVitess),
+		lint.WithDisableRules("primary-key", "redundant-index"),
+	}
+	findings, err := lint.Check(stmts, options...)
+	if !assert.NoError(t, err, "check should succeed") {
+		return
+	}
+
+	// rules registered by other tests are ignored
+	var got []string
+	for _, f := range findings {
+		if strings.HasPrefix(f.Rule, "vitess-") {
+			got = append(got, f.String())
+		}
+	}
+	expected := []string{
+		"2:2: warning: AUTO_INCREMENT column `parent`.`id` is not unique across shards: use a Vitess sequence for it in sharded keyspaces (vitess-auto-increment)",
+		"7:2: warning: AUTO_INCREMENT column `child`.`seq` is not unique across shards: make it the primary key and use a Vitess sequence for it, or drop AUTO_INCREMENT (vitess-auto-increment)",
+		"12:2: error: foreign key `fk_parent` on table `child` is not supported by Vitess: drop the constraint and enforce the relation in the application (vitess-foreign-key)",
+		"18:0: error: table `nullable` has no primary key or unique key on NOT NULL columns, which Vitess requires for online schema changes: add a primary key (vitess-unique-key)",
+	}
+	if !assert.Equal(t, expected, got) {
+		return
+	}
+
+	findings, err = lint.Check(stmts, options[1:]...)
+	if !assert.NoError(t, err, "check should succeed") {
+		return
+	}
+	for _, f := range findings {
+		if !assert.False(t, strings.HasPrefix(f.Rule, "vitess-"), "vitess rules should only run for the target") {
+			return
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := lint.ParseTarget("PlanetScale")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, lint.TargetVitess, target) {
+		return
+	}
+	_, err = lint.ParseTarget("spanner")
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+}
@@ -0,0 +1,215 @@
+// Package mask generates views that expose masked versions of the
+// columns that contain personally identifiable information (PII),
+// along with the GRANT statements to allow access to them.
+//
+// Columns are annotated in their comments using a `pii` tag, which
+// may specify the masking method:
+//
+//	email VARCHAR(255) NOT NULL COMMENT 'contact address pii:hash',
+//	phone VARCHAR(32) COMMENT 'pii:mask',
+//	address TEXT COMMENT 'pii:omit',
+//
+// A bare `pii` tag uses the default method. Annotations can also be
+// given (or overridden) without touching the schema, using WithRule.
+package mask
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Method describes how a sensitive column is exposed in the view
+type Method string
+
+// List of possible masking methods. MethodNone exposes the column
+// as-is. MethodHash replaces the value with its SHA-256 hash.
+// MethodMask replaces all but the last four characters with `*`.
+// MethodNull replaces the value with NULL, and MethodOmit removes the
+// column from the view altogether
+const (
+	MethodNone Method = "none"
+	MethodHash Method = "hash"
+	MethodMask Method = "mask"
+	MethodNull Method = "null"
+	MethodOmit Method = "omit"
+)
+
+var annotationRx = regexp.MustCompile(`(?i)(?:^|[^\w])pii(?::(\w+))?(?:$|[^\w:])`)
+
+type maskCtx struct {
+	database       string
+	defaultMethod  Method
+	grantee        string
+	rules          map[string]Method
+	sourceDatabase string
+	viewName       string
+}
+
+func newMaskCtx() *maskCtx {
+	return &maskCtx{
+		defaultMethod: MethodHash,
+		grantee:       `'analyst'@'%'`,
+		rules:         make(map[string]Method),
+		viewName:      "%s_masked",
+	}
+}
+
+func ruleKey(table, column string) string {
+	return table + "." + column
+}
+
+// Annotation returns the masking method specified in the comment of
+// the column. The second return value is false if the column is not
+// annotated. A bare `pii` tag returns an empty Method, which means
+// that the default method should be used
+func Annotation(col model.TableColumn) (Method, bool, error) {
+	if !col.HasComment() {
+		return "", false, nil
+	}
+
+	m := annotationRx.FindStringSubmatch(col.Comment())
+	if m == nil {
+		return "", false, nil
+	}
+
+	method := Method(m[1])
+	switch method {
+	case "", MethodNone, MethodHash, MethodMask, MethodNull, MethodOmit:
+		return method, true, nil
+	default:
+		return "", false, errors.Errorf(`unknown masking method %s for column %s`, method, col.Name())
+	}
+}
+
+// Views generates a CREATE OR REPLACE VIEW statement for each table
+// in `stmts`, masking the annotated columns, followed by a GRANT
+// statement for the view. The result is written to `dst`
+func Views(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	ctx := newMaskCtx()
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyDatabase:
+			ctx.database = o.Value().(string)
+		case optkeyDefaultMethod:
+			ctx.defaultMethod = o.Value().(Method)
+		case optkeyGrantee:
+			ctx.grantee = o.Value().(string)
+		case optkeyRule:
+			r := o.Value().(rule)
+			ctx.rules[ruleKey(r.table, r.column)] = r.method
+		case optkeySourceDatabase:
+			ctx.sourceDatabase = o.Value().(string)
+		case optkeyViewName:
+			ctx.viewName = o.Value().(string)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n\n")
+		}
+		if err := writeView(ctx, &buf, table); err != nil {
+			return errors.Wrapf(err, `failed to generate view for table %s`, table.Name())
+		}
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write views`)
+	}
+	return nil
+}
+
+// Strings parses the given schema, and generates the views for it
+func Strings(dst io.Writer, src string, options ...Option) error {
+	stmts, err := schemalex.New().ParseString(src)
+	if err != nil {
+		return errors.Wrap(err, `failed to parse schema`)
+	}
+	return Views(dst, stmts, options...)
+}
+
+func (ctx *maskCtx) method(table model.Table, col model.TableColumn) (Method, error) {
+	if m, ok := ctx.rules[ruleKey(table.Name(), col.Name())]; ok {
+		return m, nil
+	}
+
+	m, ok, err := Annotation(col)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return MethodNone, nil
+	}
+	if m == "" {
+		return ctx.defaultMethod, nil
+	}
+	return m, nil
+}
+
+func qualify(database, name string) string {
+	if database == "" {
+		return util.Backquote(name)
+	}
+	return util.Backquote(database) + "." + util.Backquote(name)
+}
+
+func writeView(ctx *maskCtx, buf *bytes.Buffer, table model.Table) error {
+	var exprs []string
+	for col := range table.Columns() {
+		m, err := ctx.method(table, col)
+		if err != nil {
+			return err
+		}
+
+		name := util.Backquote(col.Name())
+		switch m {
+		case MethodNone:
+			exprs = append(exprs, name)
+		case MethodHash:
+			exprs = append(exprs, "SHA2("+name+", 256) AS "+name)
+		case MethodMask:
+			exprs = append(exprs, "CONCAT(REPEAT('*', GREATEST(CHAR_LENGTH("+name+") - 4, 0)), RIGHT("+name+", 4)) AS "+name)
+		case MethodNull:
+			exprs = append(exprs, "NULL AS "+name)
+		case MethodOmit:
+		default:
+			return errors.Errorf(`unknown masking method %s for column %s`, m, col.Name())
+		}
+	}
+
+	if len(exprs) == 0 {
+		return errors.New(`all columns are omitted`)
+	}
+
+	view := qualify(ctx.database, fmt.Sprintf(ctx.viewName, table.Name()))
+	buf.WriteString("CREATE OR REPLACE VIEW ")
+	buf.WriteString(view)
+	buf.WriteString(" AS SELECT ")
+	for i, expr := range exprs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(expr)
+	}
+	buf.WriteString(" FROM ")
+	buf.WriteString(qualify(ctx.sourceDatabase, table.Name()))
+	buf.WriteString(";\nGRANT SELECT ON ")
+	buf.WriteString(view)
+	buf.WriteString(" TO ")
+	buf.WriteString(ctx.grantee)
+	buf.WriteByte(';')
+	return nil
+}
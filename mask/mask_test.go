@@ -0,0 +1,79 @@
+package mask_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex/mask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViews(t *testing.T) {
+	type Spec struct {
+		Input   string
+		Options []mask.Option
+		Expect  string
+		Error   bool
+	}
+
+	specs := []Spec{
+		// no annotations
+		{
+			Input:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) );",
+			Expect: "CREATE OR REPLACE VIEW `hoge_masked` AS SELECT `id`, `name` FROM `hoge`;\nGRANT SELECT ON `hoge_masked` TO 'analyst'@'%';",
+		},
+		// annotated columns
+		{
+			Input: "CREATE TABLE `users` ( `id` INTEGER NOT NULL, `email` VARCHAR (255) COMMENT 'contact address pii:hash', `phone` VARCHAR (32) COMMENT 'pii:mask', `birthday` DATE COMMENT 'PII:null', `address` TEXT COMMENT 'pii:omit', `name` VARCHAR (64) COMMENT 'pii' );",
+			Expect: "CREATE OR REPLACE VIEW `users_masked` AS SELECT `id`, SHA2(`email`, 256) AS `email`, " +
+				"CONCAT(REPEAT('*', GREATEST(CHAR_LENGTH(`phone`) - 4, 0)), RIGHT(`phone`, 4)) AS `phone`, " +
+				"NULL AS `birthday`, SHA2(`name`, 256) AS `name` FROM `users`;\nGRANT SELECT ON `users_masked` TO 'analyst'@'%';",
+		},
+		// words containing pii are not annotations
+		{
+			Input:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL COMMENT 'piing' );",
+			Expect: "CREATE OR REPLACE VIEW `hoge_masked` AS SELECT `id` FROM `hoge`;\nGRANT SELECT ON `hoge_masked` TO 'analyst'@'%';",
+		},
+		// rules, naming and multiple tables
+		{
+			Input: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `email` VARCHAR (255) COMMENT 'pii' ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `token` VARCHAR (255) COMMENT 'pii:hash' );",
+			Options: []mask.Option{
+				mask.WithRule("hoge", "id", mask.MethodHash),
+				mask.WithRule("fuga", "token", mask.MethodNone),
+				mask.WithDefaultMethod(mask.MethodNull),
+				mask.WithViewName("v_%s"),
+				mask.WithDatabase("analytics"),
+				mask.WithSourceDatabase("app"),
+				mask.WithGrantee("'bi'@'10.0.0.%'"),
+			},
+			Expect: "CREATE OR REPLACE VIEW `analytics`.`v_hoge` AS SELECT SHA2(`id`, 256) AS `id`, NULL AS `email` FROM `app`.`hoge`;\nGRANT SELECT ON `analytics`.`v_hoge` TO 'bi'@'10.0.0.%';\n\n" +
+				"CREATE OR REPLACE VIEW `analytics`.`v_fuga` AS SELECT `id`, `token` FROM `app`.`fuga`;\nGRANT SELECT ON `analytics`.`v_fuga` TO 'bi'@'10.0.0.%';",
+		},
+		// unknown method
+		{
+			Input: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL COMMENT 'pii:encrypt' );",
+			Error: true,
+		},
+		// all columns omitted
+		{
+			Input: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL COMMENT 'pii:omit' );",
+			Error: true,
+		},
+	}
+
+	var buf bytes.Buffer
+	for _, spec := range specs {
+		buf.Reset()
+		err := mask.Strings(&buf, spec.Input, spec.Options...)
+		if spec.Error {
+			assert.Error(t, err, "mask.Strings should fail for %s", spec.Input)
+			continue
+		}
+		if !assert.NoError(t, err, "mask.Strings should succeed") {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, buf.String(), "result SQL should match") {
+			return
+		}
+	}
+}
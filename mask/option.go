@@ -0,0 +1,62 @@
+package mask
+
+import (
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyDatabase       = "database"
+	optkeyDefaultMethod  = "default-method"
+	optkeyGrantee        = "grantee"
+	optkeyRule           = "rule"
+	optkeySourceDatabase = "source-database"
+	optkeyViewName       = "view-name"
+)
+
+type rule struct {
+	table  string
+	column string
+	method Method
+}
+
+// WithRule specifies the masking method for a column, regardless of
+// the annotation in the column comment. This can be used to annotate
+// columns without modifying the canonical schema.
+func WithRule(table, column string, m Method) Option {
+	return option.New(optkeyRule, rule{table: table, column: column, method: m})
+}
+
+// WithDefaultMethod specifies the method used for columns annotated
+// with a bare `pii` tag. The default is MethodHash
+func WithDefaultMethod(m Method) Option {
+	return option.New(optkeyDefaultMethod, m)
+}
+
+// WithViewName specifies the format string used to derive the view
+// name from the table name. The default is "%s_masked"
+func WithViewName(s string) Option {
+	return option.New(optkeyViewName, s)
+}
+
+// WithDatabase specifies the database in which the views are created.
+// If unspecified, the view names are not qualified
+func WithDatabase(s string) Option {
+	return option.New(optkeyDatabase, s)
+}
+
+// WithSourceDatabase specifies the database that contains the tables
+// that the views select from. If unspecified, the table names are
+// not qualified
+func WithSourceDatabase(s string) Option {
+	return option.New(optkeySourceDatabase, s)
+}
+
+// WithGrantee specifies the account that is granted SELECT on the
+// generated views, such as `'analyst'@'%'`. The value is used as-is.
+// The default is `'analyst'@'%'`
+func WithGrantee(s string) Option {
+	return option.New(optkeyGrantee, s)
+}
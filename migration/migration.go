@@ -0,0 +1,138 @@
+// Package migration writes the diff between two schemas as a pair of
+// up and down migrations, in the file layout used by migration tools
+// such as goose, golang-migrate, or sql-migrate.
+//
+// The up migration migrates from the old schema to the new one, and
+// the down migration reverts it. Both are versioned by a timestamp,
+// such as 20060102150405.
+package migration
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Layout describes how the migrations are laid out in files
+type Layout string
+
+// List of possible Layout values
+const (
+	// LayoutGolangMigrate writes VERSION_NAME.up.sql and
+	// VERSION_NAME.down.sql, as read by golang-migrate
+	LayoutGolangMigrate Layout = "golang-migrate"
+	// LayoutGoose writes VERSION_NAME.sql, with the migrations
+	// annotated by "-- +goose Up" and "-- +goose Down"
+	LayoutGoose Layout = "goose"
+	// LayoutSQLMigrate writes VERSION-NAME.sql, with the migrations
+	// annotated by "-- +migrate Up" and "-- +migrate Down"
+	LayoutSQLMigrate Layout = "sql-migrate"
+)
+
+// Layouts lists the supported layouts
+var Layouts = []Layout{LayoutGolangMigrate, LayoutGoose, LayoutSQLMigrate}
+
+const versionFormat = "20060102150405"
+
+// File is a generated migration file
+type File struct {
+	// Name is the file name, without the directory
+	Name    string
+	Content []byte
+}
+
+// Generate creates the migration files that migrate from one schema
+// to the other. The name describes the migration, such as "add_users",
+// and is normalized to lower case letters, digits, and underscores.
+//
+// Options other than the ones in this package are passed to
+// diff.Statements, such as diff.WithColumnOrder. If the schemas are
+// the same, no files are generated.
+func Generate(from, to model.Stmts, name string, options ...Option) ([]File, error) {
+	layout := LayoutGolangMigrate
+	now := time.Now()
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyLayout:
+			layout = o.Value().(Layout)
+		case optkeyTime:
+			now = o.Value().(time.Time)
+		}
+	}
+
+	name = normalizeName(name)
+	if name == "" {
+		return nil, errors.New(`migration name is required`)
+	}
+
+	var up, down bytes.Buffer
+	if err := diff.Statements(&up, from, to, options...); err != nil {
+		return nil, errors.Wrap(err, `failed to generate up migration`)
+	}
+	if up.Len() == 0 {
+		return nil, nil
+	}
+	if err := diff.Statements(&down, to, from, options...); err != nil {
+		return nil, errors.Wrap(err, `failed to generate down migration`)
+	}
+
+	version := now.UTC().Format(versionFormat)
+	switch layout {
+	case LayoutGolangMigrate:
+		return []File{
+			{Name: version + "_" + name + ".up.sql", Content: terminate(up.Bytes())},
+			{Name: version + "_" + name + ".down.sql", Content: terminate(down.Bytes())},
+		}, nil
+	case LayoutGoose:
+		return []File{
+			{Name: version + "_" + name + ".sql", Content: annotate("-- +goose", up.Bytes(), down.Bytes())},
+		}, nil
+	case LayoutSQLMigrate:
+		return []File{
+			{Name: version + "-" + name + ".sql", Content: annotate("-- +migrate", up.Bytes(), down.Bytes())},
+		}, nil
+	}
+	return nil, errors.Errorf(`unknown layout %s`, layout)
+}
+
+// ParseLayout returns the Layout with the given name
+func ParseLayout(s string) (Layout, error) {
+	for _, l := range Layouts {
+		if string(l) == strings.ToLower(s) {
+			return l, nil
+		}
+	}
+	return "", errors.Errorf(`unknown layout %s`, s)
+}
+
+func annotate(prefix string, up, down []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(prefix)
+	buf.WriteString(" Up\n")
+	buf.Write(terminate(up))
+	buf.WriteByte('\n')
+	buf.WriteString(prefix)
+	buf.WriteString(" Down\n")
+	buf.Write(terminate(down))
+	return buf.Bytes()
+}
+
+// terminate makes sure that the statements end with a newline
+func terminate(b []byte) []byte {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return b
+	}
+	return append(b, '\n')
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalizeName(s string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
@@ -0,0 +1,69 @@
+package migration_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/migration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	to, err := p.ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	const up = "ALTER TABLE `hoge` ADD COLUMN `name` VARCHAR (20) DEFAULT NULL AFTER `id`;\n"
+	const down = "ALTER TABLE `hoge` DROP COLUMN `name`;\n"
+
+	specs := []struct {
+		Layout migration.Layout
+		Expect []migration.File
+	}{
+		{
+			Layout: migration.LayoutGolangMigrate,
+			Expect: []migration.File{
+				{Name: "20200102030405_add_name.up.sql", Content: []byte(up)},
+				{Name: "20200102030405_add_name.down.sql", Content: []byte(down)},
+			},
+		},
+		{
+			Layout: migration.LayoutGoose,
+			Expect: []migration.File{
+				{Name: "20200102030405_add_name.sql", Content: []byte("-- +goose Up\n" + up + "\n-- +goose Down\n" + down)},
+			},
+		},
+		{
+			Layout: migration.LayoutSQLMigrate,
+			Expect: []migration.File{
+				{Name: "20200102030405-add_name.sql", Content: []byte("-- +migrate Up\n" + up + "\n-- +migrate Down\n" + down)},
+			},
+		},
+	}
+
+	for _, spec := range specs {
+		files, err := migration.Generate(from, to, "Add name", migration.WithLayout(spec.Layout), migration.WithTime(now))
+		if !assert.NoError(t, err, "migration.Generate should succeed for %s", spec.Layout) {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, files, "files should match for %s", spec.Layout) {
+			return
+		}
+	}
+
+	files, err := migration.Generate(to, to, "noop")
+	if !assert.NoError(t, err, "migration.Generate should succeed") {
+		return
+	}
+	if !assert.Empty(t, files, "there should be no files without changes") {
+		return
+	}
+}
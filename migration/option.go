@@ -0,0 +1,27 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyLayout = "layout"
+	optkeyTime   = "time"
+)
+
+// WithLayout specifies the layout of the generated files.
+// The default is LayoutGolangMigrate
+func WithLayout(l Layout) Option {
+	return option.New(optkeyLayout, l)
+}
+
+// WithTime specifies the time used for the version of the migration.
+// The default is the current time
+func WithTime(t time.Time) Option {
+	return option.New(optkeyTime, t)
+}
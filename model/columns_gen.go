@@ -42,6 +42,18 @@ const (
 	ColumnTypeBoolean
 	ColumnTypeBool
 	ColumnTypeJSON
+	ColumnTypeInet4
+	ColumnTypeInet6
+	ColumnTypeUUID
+	ColumnTypeGeometry
+	ColumnTypePoint
+	ColumnTypeLineString
+	ColumnTypePolygon
+	ColumnTypeMultiPoint
+	ColumnTypeMultiLineString
+	ColumnTypeMultiPolygon
+	ColumnTypeGeometryCollection
+	ColumnTypeGeomCollection
 
 	ColumnTypeMax
 )
@@ -116,6 +128,30 @@ func (c ColumnType) String() string {
 		return "BOOL"
 	case ColumnTypeJSON:
 		return "JSON"
+	case ColumnTypeInet4:
+		return "INET4"
+	case ColumnTypeInet6:
+		return "INET6"
+	case ColumnTypeUUID:
+		return "UUID"
+	case ColumnTypeGeometry:
+		return "GEOMETRY"
+	case ColumnTypePoint:
+		return "POINT"
+	case ColumnTypeLineString:
+		return "LINESTRING"
+	case ColumnTypePolygon:
+		return "POLYGON"
+	case ColumnTypeMultiPoint:
+		return "MULTIPOINT"
+	case ColumnTypeMultiLineString:
+		return "MULTILINESTRING"
+	case ColumnTypeMultiPolygon:
+		return "MULTIPOLYGON"
+	case ColumnTypeGeometryCollection:
+		return "GEOMETRYCOLLECTION"
+	case ColumnTypeGeomCollection:
+		return "GEOMCOLLECTION"
 	default:
 		return "(invalid)"
 	}
@@ -129,6 +165,8 @@ func (c ColumnType) SynonymType() ColumnType {
 		return ColumnTypeTinyInt
 	case ColumnTypeBoolean:
 		return ColumnTypeTinyInt
+	case ColumnTypeGeomCollection:
+		return ColumnTypeGeometryCollection
 	case ColumnTypeInteger:
 		return ColumnTypeInt
 	case ColumnTypeNumeric:
@@ -0,0 +1,311 @@
+package model
+
+import (
+	"strings"
+)
+
+// DependencyGraph is a directed graph of the tables of a schema, where
+// each table depends on the tables that its foreign keys reference.
+// Only references between the tables of the schema are part of the
+// graph, and a table that references itself does not depend on itself.
+type DependencyGraph struct {
+	tables     []Table
+	names      map[string]int      // table name to its index in tables
+	dependsOn  map[string][]string // table name to the tables it references
+	dependents map[string][]string // table name to the tables that reference it
+}
+
+// CycleError is returned when the tables of a schema can not be sorted
+// because their foreign keys reference each other
+type CycleError struct {
+	// Tables lists the names of the tables that form the cycle, in
+	// schema order
+	Tables []string
+}
+
+func (e *CycleError) Error() string {
+	return "tables reference each other: " + strings.Join(e.Tables, ", ")
+}
+
+// Dependencies builds the dependency graph of the tables declared by
+// the statements. Statements other than tables are ignored.
+func (s Stmts) Dependencies() *DependencyGraph {
+	g := &DependencyGraph{
+		names:      make(map[string]int),
+		dependsOn:  make(map[string][]string),
+		dependents: make(map[string][]string),
+	}
+	for _, stmt := range s {
+		table, ok := stmt.(Table)
+		if !ok {
+			continue
+		}
+		if _, ok := g.names[table.Name()]; ok {
+			continue
+		}
+		g.names[table.Name()] = len(g.tables)
+		g.tables = append(g.tables, table)
+	}
+
+	for _, table := range g.tables {
+		seen := make(map[string]struct{})
+		for idx := range table.Indexes() {
+			if !idx.IsForeignKey() || idx.Reference() == nil {
+				continue
+			}
+			name := idx.Reference().TableName()
+			if name == table.Name() {
+				continue
+			}
+			if _, ok := g.names[name]; !ok {
+				continue
+			}
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			g.dependsOn[table.Name()] = append(g.dependsOn[table.Name()], name)
+			g.dependents[name] = append(g.dependents[name], table.Name())
+		}
+	}
+	return g
+}
+
+// Tables returns the tables of the graph, in schema order
+func (g *DependencyGraph) Tables() []Table {
+	return append([]Table(nil), g.tables...)
+}
+
+// Lookup returns the table with the given name
+func (g *DependencyGraph) Lookup(name string) (Table, bool) {
+	i, ok := g.names[name]
+	if !ok {
+		return nil, false
+	}
+	return g.tables[i], true
+}
+
+// DependsOn returns the names of the tables that the given table
+// references directly, in the order of its foreign keys
+func (g *DependencyGraph) DependsOn(name string) []string {
+	return append([]string(nil), g.dependsOn[name]...)
+}
+
+// Dependents returns the names of the tables that reference the given
+// table directly, in schema order
+func (g *DependencyGraph) Dependents(name string) []string {
+	return append([]string(nil), g.dependents[name]...)
+}
+
+// Closure returns the names of the given tables, along with the names
+// of the tables that they depend on, directly or not, in schema order.
+// Names of tables that are not part of the graph are ignored.
+func (g *DependencyGraph) Closure(names ...string) []string {
+	required := make(map[string]struct{})
+	var visit func(name string)
+	visit = func(name string) {
+		if _, ok := required[name]; ok {
+			return
+		}
+		required[name] = struct{}{}
+		for _, dep := range g.dependsOn[name] {
+			visit(dep)
+		}
+	}
+	for _, name := range names {
+		if _, ok := g.names[name]; ok {
+			visit(name)
+		}
+	}
+
+	var closure []string
+	for _, table := range g.tables {
+		if _, ok := required[table.Name()]; ok {
+			closure = append(closure, table.Name())
+		}
+	}
+	return closure
+}
+
+// Sort returns the tables so that each table comes after the tables
+// that it depends on, which is the order in which tables can be
+// created or loaded. Reverse it to get the order in which tables can
+// be dropped or truncated. Ties are broken by schema order.
+//
+// If some tables reference each other, Sort returns a *CycleError that
+// describes the first cycle, in schema order.
+func (g *DependencyGraph) Sort() ([]Table, error) {
+	sorted, ok := g.sort(false)
+	if !ok {
+		return nil, &CycleError{Tables: g.Cycles()[0]}
+	}
+	return sorted, nil
+}
+
+// Order returns the tables in the same order as Sort, except that
+// tables that reference each other do not make it fail: when every
+// remaining table depends on another one, the first of them in schema
+// order comes next.
+func (g *DependencyGraph) Order() []Table {
+	sorted, _ := g.sort(true)
+	return sorted
+}
+
+// sort sorts the tables, and returns false if it gives up on a cycle.
+// With lenient, cycles are broken by schema order instead.
+func (g *DependencyGraph) sort(lenient bool) ([]Table, bool) {
+	pending := make(map[string]int) // number of dependencies not sorted yet
+	for _, table := range g.tables {
+		pending[table.Name()] = len(g.dependsOn[table.Name()])
+	}
+
+	sorted := make([]Table, 0, len(g.tables))
+	for len(sorted) < len(g.tables) {
+		var next Table
+		for _, table := range g.tables {
+			if n, ok := pending[table.Name()]; ok && n == 0 {
+				next = table
+				break
+			}
+		}
+		if next == nil {
+			if !lenient {
+				return nil, false
+			}
+			for _, table := range g.tables {
+				if _, ok := pending[table.Name()]; ok {
+					next = table
+					break
+				}
+			}
+		}
+
+		delete(pending, next.Name())
+		for _, name := range g.dependents[next.Name()] {
+			// a dependent may already be sorted when a cycle was broken
+			if _, ok := pending[name]; ok {
+				pending[name]--
+			}
+		}
+		sorted = append(sorted, next)
+	}
+	return sorted, true
+}
+
+// InDependencyOrder returns the statements with the tables reordered
+// as by DependencyGraph.Order, so that each table comes after the
+// tables that it references. The other statements, such as ANALYZE
+// TABLE, follow the table that precedes them in the original order, or
+// come first if no table precedes them.
+func (s Stmts) InDependencyOrder() Stmts {
+	g := s.Dependencies()
+
+	var head Stmts
+	following := make(map[Table]Stmts)
+	var last Table
+	for _, stmt := range s {
+		// a table declared twice only takes part in the graph once,
+		// and its later declarations are kept like other statements
+		if table, ok := stmt.(Table); ok {
+			if first, _ := g.Lookup(table.Name()); first == table {
+				last = table
+				continue
+			}
+		}
+		if last == nil {
+			head = append(head, stmt)
+		} else {
+			following[last] = append(following[last], stmt)
+		}
+	}
+
+	result := make(Stmts, 0, len(s))
+	result = append(result, head...)
+	for _, table := range g.Order() {
+		result = append(result, table)
+		result = append(result, following[table]...)
+	}
+	return result
+}
+
+// Cycles returns the groups of tables that reference each other,
+// directly or not. Each group lists the names of its tables in schema
+// order, and the groups are ordered by their first table. Cycles
+// returns nil if the tables can be sorted.
+func (g *DependencyGraph) Cycles() [][]string {
+	// Tarjan's algorithm, which finds the strongly connected components
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, dep := range g.dependsOn[name] {
+			if _, ok := index[dep]; !ok {
+				visit(dep)
+				if lowlink[dep] < lowlink[name] {
+					lowlink[name] = lowlink[dep]
+				}
+			} else if onStack[dep] && index[dep] < lowlink[name] {
+				lowlink[name] = index[dep]
+			}
+		}
+
+		if lowlink[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+		if len(component) > 1 {
+			components = append(components, component)
+		}
+	}
+
+	for _, table := range g.tables {
+		if _, ok := index[table.Name()]; !ok {
+			visit(table.Name())
+		}
+	}
+	if len(components) == 0 {
+		return nil
+	}
+
+	// list the tables in schema order, and the cycles by their first table
+	inCycle := make(map[string]int)
+	for i, component := range components {
+		for _, name := range component {
+			inCycle[name] = i
+		}
+	}
+	cycles := make([][]string, 0, len(components))
+	position := make(map[int]int) // component to its index in cycles
+	for _, table := range g.tables {
+		i, ok := inCycle[table.Name()]
+		if !ok {
+			continue
+		}
+		j, ok := position[i]
+		if !ok {
+			j = len(cycles)
+			position[i] = j
+			cycles = append(cycles, nil)
+		}
+		cycles[j] = append(cycles[j], table.Name())
+	}
+	return cycles
+}
@@ -0,0 +1,124 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func tableNames(tables []model.Table) []string {
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name())
+	}
+	return names
+}
+
+func TestDependencies(t *testing.T) {
+	stmts, err := schemalex.New().ParseString(`
+CREATE TABLE comments (id INT, post_id INT, user_id INT, parent_id INT,
+  FOREIGN KEY (post_id) REFERENCES posts (id),
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  FOREIGN KEY (parent_id) REFERENCES comments (id),
+  FOREIGN KEY (user_id) REFERENCES external (id));
+CREATE TABLE posts (id INT, user_id INT, FOREIGN KEY (user_id) REFERENCES users (id));
+CREATE TABLE users (id INT);
+CREATE TABLE tags (id INT);`)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	g := stmts.Dependencies()
+	if !assert.Equal(t, []string{"comments", "posts", "users", "tags"}, tableNames(g.Tables()), "tables should be in schema order") {
+		return
+	}
+	if !assert.Equal(t, []string{"posts", "users"}, g.DependsOn("comments"), "references to itself and to unknown tables should be ignored") {
+		return
+	}
+	if !assert.Equal(t, []string{"comments", "posts"}, g.Dependents("users"), "dependents should be in schema order") {
+		return
+	}
+	if !assert.Nil(t, g.Cycles(), "there should be no cycles") {
+		return
+	}
+
+	sorted, err := g.Sort()
+	if !assert.NoError(t, err, "sort should succeed") {
+		return
+	}
+	if !assert.Equal(t, []string{"users", "posts", "comments", "tags"}, tableNames(sorted), "referenced tables should come first") {
+		return
+	}
+
+	if !assert.Equal(t, []string{"comments", "posts", "users"}, g.Closure("comments", "external"), "closure should include indirect dependencies in schema order") {
+		return
+	}
+	if !assert.Equal(t, []string{"tags"}, g.Closure("tags"), "closure of a table without dependencies should be itself") {
+		return
+	}
+
+	filtered := stmts.Filter(func(stmt model.Stmt) bool {
+		table, ok := stmt.(model.Table)
+		return ok && table.Name() != "posts"
+	})
+	if !assert.Len(t, filtered, 3, "filter should keep the matching statements") {
+		return
+	}
+}
+
+func TestDependenciesCycle(t *testing.T) {
+	stmts, err := schemalex.New().ParseString(`
+CREATE TABLE a (id INT, c_id INT, FOREIGN KEY (c_id) REFERENCES c (id));
+CREATE TABLE b (id INT);
+CREATE TABLE c (id INT, d_id INT, FOREIGN KEY (d_id) REFERENCES d (id));
+CREATE TABLE d (id INT, a_id INT, FOREIGN KEY (a_id) REFERENCES a (id));
+CREATE TABLE e (id INT, f_id INT, FOREIGN KEY (f_id) REFERENCES f (id));
+CREATE TABLE f (id INT, e_id INT, FOREIGN KEY (e_id) REFERENCES e (id));`)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	g := stmts.Dependencies()
+	if !assert.Equal(t, [][]string{{"a", "c", "d"}, {"e", "f"}}, g.Cycles(), "cycles should be found") {
+		return
+	}
+
+	_, err = g.Sort()
+	cerr, ok := err.(*model.CycleError)
+	if !assert.True(t, ok, "sort should return a *model.CycleError") {
+		return
+	}
+	if !assert.Equal(t, []string{"a", "c", "d"}, cerr.Tables, "the first cycle should be reported") {
+		return
+	}
+	if !assert.Equal(t, "tables reference each other: a, c, d", err.Error(), "error message should match") {
+		return
+	}
+
+	if !assert.Equal(t, []string{"b", "a", "d", "c", "e", "f"}, tableNames(g.Order()), "cycles should be broken in schema order") {
+		return
+	}
+}
+
+func TestInDependencyOrder(t *testing.T) {
+	stmts, err := schemalex.New().ParseString(`
+ANALYZE TABLE posts UPDATE HISTOGRAM ON user_id;
+CREATE TABLE posts (id INT, user_id INT, FOREIGN KEY (user_id) REFERENCES users (id));
+ANALYZE TABLE posts UPDATE HISTOGRAM ON id;
+CREATE TABLE users (id INT);
+CREATE TABLE tags (id INT);`)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	ordered := stmts.InDependencyOrder()
+	if !assert.Len(t, ordered, len(stmts), "all the statements should be kept") {
+		return
+	}
+	expect := []model.Stmt{stmts[0], stmts[3], stmts[1], stmts[2], stmts[4]}
+	if !assert.Equal(t, expect, []model.Stmt(ordered), "tables should come after the tables they reference") {
+		return
+	}
+}
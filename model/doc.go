@@ -0,0 +1,9 @@
+// Package model describes the statements of a schema, such as tables,
+// columns, and indexes, as built by the parser, and as compared by the
+// diff package.
+//
+// The package does not depend on the parser, so that tools that only
+// build or inspect statements can import it alone. The statements write
+// themselves as SQL with WriteTo and String, as the format package does
+// with its default options.
+package model
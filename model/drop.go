@@ -0,0 +1,137 @@
+package model
+
+// NewDropTable creates a new DROP TABLE statement for the given table
+func NewDropTable(name string) DropTable {
+	return &dropTable{
+		name: name,
+	}
+}
+
+func (d *dropTable) isDropTable() bool {
+	return true
+}
+
+func (d *dropTable) ID() string {
+	return "droptable#" + d.name
+}
+
+func (d *dropTable) Name() string {
+	return d.name
+}
+
+func (d *dropTable) IsTemporary() bool {
+	return d.temporary
+}
+
+func (d *dropTable) SetTemporary(v bool) DropTable {
+	d.temporary = v
+	return d
+}
+
+func (d *dropTable) IsIfExists() bool {
+	return d.ifexists
+}
+
+func (d *dropTable) SetIfExists(v bool) DropTable {
+	d.ifexists = v
+	return d
+}
+
+// NewDropDatabase creates a new DROP DATABASE statement for the given
+// database
+func NewDropDatabase(name string) DropDatabase {
+	return &dropDatabase{
+		name: name,
+	}
+}
+
+func (d *dropDatabase) isDropDatabase() bool {
+	return true
+}
+
+func (d *dropDatabase) ID() string {
+	return "dropdatabase#" + d.name
+}
+
+func (d *dropDatabase) Name() string {
+	return d.name
+}
+
+func (d *dropDatabase) IsIfExists() bool {
+	return d.ifexists
+}
+
+func (d *dropDatabase) SetIfExists(v bool) DropDatabase {
+	d.ifexists = v
+	return d
+}
+
+// Apply returns the statements after stmt is applied to them, as the
+// parser does for the statements of a schema file. A DropTable removes
+// the tables with its name, or only the temporary ones for DROP
+// TEMPORARY TABLE, along with their histograms and triggers. A DropView
+// removes the view with its name, and a View replaces the view with its
+// name, as CREATE OR REPLACE VIEW does. DropRoutine and Routine do the
+// same for the triggers, procedures and functions of their kind. A
+// DropDatabase removes all the statements, as a schema file describes a
+// single database. Other statements are appended.
+func (s Stmts) Apply(stmt Stmt) Stmts {
+	switch stmt := stmt.(type) {
+	case DropTable:
+		var dropped bool
+		var list Stmts
+		for _, prev := range s {
+			switch prev := prev.(type) {
+			case Table:
+				if prev.Name() == stmt.Name() && (prev.IsTemporary() || !stmt.IsTemporary()) {
+					dropped = true
+					continue
+				}
+			case Histogram:
+				if prev.TableName() == stmt.Name() && !stmt.IsTemporary() {
+					continue
+				}
+			case Routine:
+				if prev.Table() == stmt.Name() && !stmt.IsTemporary() {
+					continue
+				}
+			}
+			list = append(list, prev)
+		}
+		if !dropped {
+			return s
+		}
+		return list
+	case DropView:
+		for i, prev := range s {
+			if prev, ok := prev.(View); ok && prev.Name() == stmt.Name() {
+				return append(s[:i:i], s[i+1:]...)
+			}
+		}
+		return s
+	case View:
+		for i, prev := range s {
+			if prev, ok := prev.(View); ok && prev.Name() == stmt.Name() {
+				list := append(s[:i:i], s[i+1:]...)
+				return append(list, stmt)
+			}
+		}
+	case DropRoutine:
+		for i, prev := range s {
+			if prev, ok := prev.(Routine); ok && prev.Kind() == stmt.Kind() && prev.Name() == stmt.Name() {
+				return append(s[:i:i], s[i+1:]...)
+			}
+		}
+		return s
+	case Routine:
+		for i, prev := range s {
+			if prev, ok := prev.(Routine); ok && prev.Kind() == stmt.Kind() && prev.Name() == stmt.Name() {
+				list := append(s[:i:i], s[i+1:]...)
+				return append(list, stmt)
+			}
+		}
+	case DropDatabase:
+		return nil
+	}
+	return append(s, stmt)
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/eihigh/schemalex/internal/sqlfmt"
+)
+
+type countWriter struct {
+	dst io.Writer
+	n   int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.dst.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// writeTo writes the SQL representation of v as the format package
+// does by default
+func writeTo(dst io.Writer, v interface{}) (int64, error) {
+	w := &countWriter{dst: dst}
+	err := writeSQL(w, v, sqlfmt.Options{DisplayWidth: true})
+	return w.n, err
+}
+
+func stringOf(v io.WriterTo) string {
+	var buf bytes.Buffer
+	if _, err := v.WriteTo(&buf); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// WriteTo writes the statements as a schema file, where each statement
+// is terminated by a semicolon, and separated from the next one by an
+// empty line. The result can be parsed again.
+func (s Stmts) WriteTo(dst io.Writer) (int64, error) {
+	var total int64
+	for i, stmt := range s {
+		if i > 0 {
+			n, err := io.WriteString(dst, "\n")
+			total += int64(n)
+			if err != nil {
+				return total, err
+			}
+		}
+		n, err := writeTo(dst, stmt)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		m, err := io.WriteString(dst, ";\n")
+		total += int64(m)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// String returns the statements as written by WriteTo
+func (s Stmts) String() string { return stringOf(s) }
+
+// WriteTo writes the CREATE TABLE statement for the table
+func (t *table) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, t) }
+
+// String returns the CREATE TABLE statement for the table
+func (t *table) String() string { return stringOf(t) }
+
+// WriteTo writes the definition of the column
+func (t *tablecol) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, t) }
+
+// String returns the definition of the column
+func (t *tablecol) String() string { return stringOf(t) }
+
+// WriteTo writes the definition of the index
+func (stmt *index) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, stmt) }
+
+// String returns the definition of the index
+func (stmt *index) String() string { return stringOf(stmt) }
+
+// WriteTo writes the CREATE DATABASE statement for the database
+func (d *database) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, d) }
+
+// String returns the CREATE DATABASE statement for the database
+func (d *database) String() string { return stringOf(d) }
+
+// WriteTo writes the ANALYZE TABLE statement for the histogram
+func (h *histogram) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, h) }
+
+// String returns the ANALYZE TABLE statement for the histogram
+func (h *histogram) String() string { return stringOf(h) }
+
+// WriteTo writes the DROP TABLE statement
+func (d *dropTable) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, d) }
+
+// String returns the DROP TABLE statement
+func (d *dropTable) String() string { return stringOf(d) }
+
+// WriteTo writes the DROP DATABASE statement
+func (d *dropDatabase) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, d) }
+
+// String returns the DROP DATABASE statement
+func (d *dropDatabase) String() string { return stringOf(d) }
+
+// WriteTo writes the CREATE VIEW statement for the view
+func (v *view) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, v) }
+
+// String returns the CREATE VIEW statement for the view
+func (v *view) String() string { return stringOf(v) }
+
+// WriteTo writes the DROP VIEW statement
+func (d *dropView) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, d) }
+
+// String returns the DROP VIEW statement
+func (d *dropView) String() string { return stringOf(d) }
+
+// WriteTo writes the CREATE statement for the routine
+func (r *routine) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, r) }
+
+// String returns the CREATE statement for the routine
+func (r *routine) String() string { return stringOf(r) }
+
+// WriteTo writes the DROP statement for the routine
+func (d *dropRoutine) WriteTo(dst io.Writer) (int64, error) { return writeTo(dst, d) }
+
+// String returns the DROP statement for the routine
+func (d *dropRoutine) String() string { return stringOf(d) }
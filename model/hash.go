@@ -0,0 +1,103 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Hash returns a digest of the schema, as a hex encoded SHA-256, so
+// that two schemas can be compared without diffing them. The digest
+// only depends on what the statements declare: tables are normalized,
+// the order of the statements, of the indexes and of the table options
+// does not matter, and the AUTO_INCREMENT table option, which is the
+// next value of the counter, is ignored. The order of the columns
+// matters, as it is part of the schema.
+//
+// The digest is computed from the statements as WriteTo writes them,
+// with the tables in the canonical form above, so it does not depend on
+// the packages that are imported.
+func (s Stmts) Hash() (string, error) {
+	var parts []string
+	for _, stmt := range s {
+		var buf bytes.Buffer
+		if table, ok := stmt.(Table); ok {
+			if err := writeTableDigest(&buf, table); err != nil {
+				return "", err
+			}
+		} else if _, err := writeTo(&buf, stmt); err != nil {
+			return "", err
+		}
+		parts = append(parts, buf.String())
+	}
+	sort.Strings(parts)
+
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeTableDigest writes the canonical form of the table that Hash
+// computes the digest of, one line per element
+func writeTableDigest(buf *bytes.Buffer, table Table) error {
+	table, _ = table.Normalize()
+
+	buf.WriteString("TABLE ")
+	buf.WriteString(table.Name())
+	if table.IsTemporary() {
+		buf.WriteString(" TEMPORARY")
+	}
+	if table.HasLikeTable() {
+		buf.WriteString(" LIKE ")
+		buf.WriteString(table.LikeTable())
+	}
+	buf.WriteByte('\n')
+
+	for col := range table.Columns() {
+		buf.WriteString("COLUMN ")
+		if _, err := writeTo(buf, col); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+
+	var lines []string
+	for idx := range table.Indexes() {
+		var line bytes.Buffer
+		line.WriteString("INDEX ")
+		if _, err := writeTo(&line, idx); err != nil {
+			return err
+		}
+		lines = append(lines, line.String())
+	}
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "AUTO_INCREMENT") {
+			continue
+		}
+		var line bytes.Buffer
+		line.WriteString("OPTION ")
+		if _, err := writeTo(&line, opt); err != nil {
+			return err
+		}
+		lines = append(lines, line.String())
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	if table.HasPartitioning() {
+		buf.WriteString("PARTITION ")
+		if _, err := writeTo(buf, table.Partitioning()); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmtsHash(t *testing.T) {
+	hash := func(src string) string {
+		stmts, err := schemalex.New().ParseString(src)
+		if !assert.NoError(t, err, "parse should succeed") {
+			return ""
+		}
+		h, err := stmts.Hash()
+		if !assert.NoError(t, err, "hash should succeed") {
+			return ""
+		}
+		return h
+	}
+
+	base := hash("CREATE TABLE foo (id INT NOT NULL AUTO_INCREMENT, name VARCHAR(10), PRIMARY KEY (id), KEY (name)) ENGINE=InnoDB AUTO_INCREMENT=10;\n" +
+		"CREATE TABLE bar (id INTEGER);")
+	if !assert.Len(t, base, 64, "hash should be a hex encoded SHA-256") {
+		return
+	}
+
+	same := []string{
+		"-- bar comes first\nCREATE TABLE bar (id INT);\n" +
+			"CREATE   TABLE foo (\n  id INT NOT NULL AUTO_INCREMENT,\n  name VARCHAR(10),\n  KEY (name),\n  PRIMARY KEY (id)\n) AUTO_INCREMENT=42 ENGINE=InnoDB;",
+		"CREATE TABLE foo (id INT NOT NULL AUTO_INCREMENT, name VARCHAR(10), PRIMARY KEY (id), KEY (name)) ENGINE=InnoDB;\n" +
+			"/* comment */ CREATE TABLE bar (id INT);",
+	}
+	for _, src := range same {
+		assert.Equal(t, base, hash(src), "hash should not change for %q", src)
+	}
+
+	different := []string{
+		"CREATE TABLE foo (id INT NOT NULL AUTO_INCREMENT, name VARCHAR(20), PRIMARY KEY (id), KEY (name)) ENGINE=InnoDB;\n" +
+			"CREATE TABLE bar (id INT);",
+		"CREATE TABLE foo (name VARCHAR(10), id INT NOT NULL AUTO_INCREMENT, PRIMARY KEY (id), KEY (name)) ENGINE=InnoDB;\n" +
+			"CREATE TABLE bar (id INT);",
+		"CREATE TABLE foo (id INT NOT NULL AUTO_INCREMENT, name VARCHAR(10), PRIMARY KEY (id), KEY (name)) ENGINE=MyISAM;\n" +
+			"CREATE TABLE bar (id INT);",
+	}
+	for _, src := range different {
+		assert.NotEqual(t, base, hash(src), "hash should change for %q", src)
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+// NewHistogram creates a new histogram for the given table and column
+func NewHistogram(table, column string) Histogram {
+	return &histogram{
+		table:  table,
+		column: column,
+	}
+}
+
+func (h *histogram) ID() string {
+	return "histogram#" + h.table + "." + h.column
+}
+
+func (h *histogram) TableName() string {
+	return h.table
+}
+
+func (h *histogram) ColumnName() string {
+	return h.column
+}
+
+func (h *histogram) HasBuckets() bool {
+	return h.buckets > 0
+}
+
+func (h *histogram) Buckets() int {
+	return h.buckets
+}
+
+func (h *histogram) SetBuckets(n int) Histogram {
+	h.buckets = n
+	return h
+}
@@ -0,0 +1,42 @@
+package model_test
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestImports makes sure that the model package can be imported
+// without the parser, or any other package of the module that is not
+// internal
+func TestImports(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if !assert.NoError(t, err, "listing files should succeed") {
+		return
+	}
+
+	fset := token.NewFileSet()
+	for _, fn := range files {
+		if strings.HasSuffix(fn, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, fn, nil, parser.ImportsOnly)
+		if !assert.NoError(t, err, "parsing %s should succeed", fn) {
+			return
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if !strings.HasPrefix(path, "github.com/eihigh/schemalex") {
+				continue
+			}
+			if !assert.True(t, strings.HasPrefix(path, "github.com/eihigh/schemalex/internal/"), "%s should not import %s", fn, path) {
+				return
+			}
+		}
+	}
+}
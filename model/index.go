@@ -20,14 +20,18 @@ func (stmt *index) ID() string {
 	//
 	// In case we don't have a name, we need to know the table, the kind,
 	// the type, // the column(s), and the reference(s).
+	//
+	// The primary key is always named PRIMARY by MySQL, so its name is
+	// not part of the ID. Only foreign keys keep their symbol, which
+	// merely names the other indexes.
 	name := "index"
-	if stmt.HasName() {
+	if stmt.HasName() && !stmt.IsPrimaryKey() {
 		name = name + "#" + stmt.Name()
 	}
 	h := sha256.New()
 
 	sym := "none"
-	if stmt.HasSymbol() {
+	if stmt.HasSymbol() && stmt.IsForeignKey() {
 		sym = stmt.Symbol()
 	}
 
@@ -42,6 +46,9 @@ func (stmt *index) ID() string {
 		fmt.Fprintf(h, ".")
 		fmt.Fprintf(h, "%s", col.ID())
 	}
+	if stmt.HasParser() {
+		fmt.Fprintf(h, ".parser#%s", stmt.Parser())
+	}
 	if stmt.reference != nil {
 		fmt.Fprintf(h, ".")
 		fmt.Fprintf(h, stmt.reference.ID())
@@ -140,6 +147,46 @@ func (stmt *index) IsForeignKey() bool {
 	return stmt.kind == IndexKindForeignKey
 }
 
+func (stmt *index) HasClustering() bool {
+	return stmt.cluster != IndexClusteringNone
+}
+
+func (stmt *index) IsClustered() bool {
+	return stmt.cluster == IndexClusteringClustered
+}
+
+func (stmt *index) IsNonClustered() bool {
+	return stmt.cluster == IndexClusteringNonClustered
+}
+
+func (stmt *index) SetClustering(v IndexClustering) Index {
+	stmt.cluster = v
+	return stmt
+}
+
+func (stmt *index) HasParser() bool {
+	return stmt.parser.Valid
+}
+
+func (stmt *index) Parser() string {
+	return stmt.parser.Value
+}
+
+func (stmt *index) SetParser(s string) Index {
+	stmt.parser.Valid = true
+	stmt.parser.Value = s
+	return stmt
+}
+
+func (stmt *index) Position() Position {
+	return stmt.pos
+}
+
+func (stmt *index) SetPosition(v Position) Index {
+	stmt.pos = v
+	return stmt
+}
+
 func (stmt *index) Normalize() (Index, bool) {
 	return stmt, false
 }
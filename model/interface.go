@@ -5,7 +5,11 @@
 
 package model
 
-import "sync"
+import (
+	"fmt"
+	"io"
+	"sync"
+)
 
 // Stmt is the interface to define a statement
 type Stmt interface {
@@ -51,6 +55,8 @@ type IndexColumn interface {
 // Index describes an index on a table.
 type Index interface {
 	Stmt
+	io.WriterTo
+	fmt.Stringer
 	ColumnContainer
 
 	HasType() bool
@@ -72,6 +78,25 @@ type Index interface {
 	IsSpatial() bool
 	IsForeignKey() bool
 
+	// HasClustering returns true if the primary key is declared as
+	// CLUSTERED or NONCLUSTERED, which is only accepted by TiDB. The
+	// clustering is not part of the ID, as TiDB can not change it once
+	// the table is created
+	HasClustering() bool
+	IsClustered() bool
+	IsNonClustered() bool
+	SetClustering(IndexClustering) Index
+
+	// HasParser returns true if the full-text index is declared with
+	// WITH PARSER, such as the ngram parser
+	HasParser() bool
+	Parser() string
+	SetParser(string) Index
+
+	// Position returns where the index was declared
+	Position() Position
+	SetPosition(Position) Index
+
 	// Normalize returns normalized index. If a normalization was performed
 	// and the index is modified, returns a new instance of the Table object
 	// along with a true value as the second return value.
@@ -107,6 +132,17 @@ const (
 	IndexTypeHash
 )
 
+// IndexClustering describes whether the rows of a table are stored
+// along with its primary key, as declared by TiDB
+type IndexClustering int
+
+// List of possible IndexClustering values
+const (
+	IndexClusteringNone IndexClustering = iota
+	IndexClusteringClustered
+	IndexClusteringNonClustered
+)
+
 // and index column specification may be
 // name or name(length)
 type indexColumn struct {
@@ -120,10 +156,13 @@ type index struct {
 	kind    IndexKind
 	name    maybeString
 	typ     IndexType
+	cluster IndexClustering
+	parser  maybeString
 	table   string
 	columns []IndexColumn
 	// TODO Options.
 	reference Reference
+	pos       Position
 }
 
 // Reference describes a possible reference from one table to another
@@ -179,6 +218,8 @@ const (
 // Table describes a table model
 type Table interface {
 	Stmt
+	io.WriterTo
+	fmt.Stringer
 
 	Name() string
 	IsTemporary() bool
@@ -206,12 +247,26 @@ type Table interface {
 
 	LookupIndex(string) (Index, bool)
 
+	HasPartitioning() bool
+	Partitioning() Partitioning
+	SetPartitioning(Partitioning) Table
+
+	// Position returns where the table was declared
+	Position() Position
+	SetPosition(Position) Table
+
 	// Normalize returns normalized table. If a normalization was performed
 	// and the table is modified, returns a new instance of the Table object
 	// along with a true value as the second return value.
 	// Otherwise, Normalize() returns the receiver unchanged, with a false
 	// as the second return value.
 	Normalize() (Table, bool)
+
+	// NormalizeUpstream is like Normalize, but leaves the indexes that
+	// are declared without a name unnamed, and the columns of the
+	// primary key nullable unless declared NOT NULL, as
+	// schemalex/schemalex did.
+	NormalizeUpstream() (Table, bool)
 }
 
 // TableOption describes a possible table option, such as `ENGINE=InnoDB`
@@ -232,6 +287,8 @@ type table struct {
 	columnNameToIndex map[string]int
 	indexes           []Index
 	options           []TableOption
+	partitioning      Partitioning
+	pos               Position
 }
 
 type tableopt struct {
@@ -240,6 +297,89 @@ type tableopt struct {
 	needQuotes bool
 }
 
+// PartitionType describes the method used to partition a table
+type PartitionType int
+
+// List of possible PartitionType values
+const (
+	PartitionTypeNone PartitionType = iota
+	PartitionTypeRange
+	PartitionTypeList
+	PartitionTypeHash
+	PartitionTypeKey
+)
+
+// Partitioning describes the `PARTITION BY` clause of a table.
+//
+// For RANGE, LIST, and HASH partitioning, the partitioning expression
+// is kept as-is in Expression(). If `RANGE COLUMNS` or `LIST COLUMNS`
+// is used, or the columns of KEY partitioning are given, they are
+// available from Columns() instead.
+type Partitioning interface {
+	Type() PartitionType
+	IsLinear() bool
+	SetLinear(bool) Partitioning
+	Expression() string
+	SetExpression(string) Partitioning
+	HasColumns() bool
+	Columns() chan string
+	SetColumns([]string) Partitioning
+	HasPartitionCount() bool
+	PartitionCount() string
+	SetPartitionCount(string) Partitioning
+
+	AddDefinition(PartitionDefinition) Partitioning
+	Definitions() chan PartitionDefinition
+	LookupDefinition(string) (PartitionDefinition, bool)
+
+	// Clone returns the cloned partitioning
+	Clone() Partitioning
+}
+
+// PartitionDefinition describes a single partition, such as
+// `PARTITION p0 VALUES LESS THAN (2020)`
+type PartitionDefinition interface {
+	Name() string
+
+	// LessThan returns the value list of `VALUES LESS THAN (...)`
+	// as-is, without the surrounding parentheses.
+	HasLessThan() bool
+	LessThan() string
+	SetLessThan(string) PartitionDefinition
+	// IsMaxValue returns true for `VALUES LESS THAN MAXVALUE`
+	IsMaxValue() bool
+	SetMaxValue(bool) PartitionDefinition
+	// ValuesIn returns the value list of `VALUES IN (...)` as-is,
+	// without the surrounding parentheses.
+	HasValuesIn() bool
+	ValuesIn() string
+	SetValuesIn(string) PartitionDefinition
+	HasEngine() bool
+	Engine() string
+	SetEngine(string) PartitionDefinition
+	HasComment() bool
+	Comment() string
+	SetComment(string) PartitionDefinition
+}
+
+type partitioning struct {
+	typ         PartitionType
+	linear      bool
+	expression  string
+	columns     []string
+	count       maybeString
+	definitions []PartitionDefinition
+}
+
+type partitionDefinition struct {
+	name     string
+	lessThan maybeString
+	maxValue bool
+	valuesIn maybeString
+	engine   maybeString
+	comment  maybeString
+}
+
 // NullState describes the possible NULL constraint of a column
 type NullState int
 
@@ -269,11 +409,14 @@ type length struct {
 // definition of a table
 type TableColumn interface {
 	Stmt
+	io.WriterTo
+	fmt.Stringer
 
 	TableID() string
 	SetTableID(string) TableColumn
 
 	Name() string
+	SetName(string) TableColumn
 	Type() ColumnType
 	SetType(ColumnType) TableColumn
 
@@ -296,6 +439,17 @@ type TableColumn interface {
 	HasAutoUpdate() bool
 	AutoUpdate() string
 	SetAutoUpdate(string) TableColumn
+	// HasAutoRandom returns true for TiDB AUTO_RANDOM columns. AutoRandom
+	// returns the arguments of AUTO_RANDOM without the parentheses,
+	// such as "5" or "5, 54", or an empty string if none were given
+	HasAutoRandom() bool
+	AutoRandom() string
+	SetAutoRandom(string) TableColumn
+	// HasSRID returns true for spatial columns that are restricted to
+	// the spatial reference system SRID, such as "4326"
+	HasSRID() bool
+	SRID() string
+	SetSRID(string) TableColumn
 	HasEnumValues() bool
 	SetEnumValues([]string) TableColumn
 	EnumValues() chan string
@@ -325,6 +479,10 @@ type TableColumn interface {
 	// Currently only supports numeric types, but may change later.
 	NativeLength() Length
 
+	// Position returns where the column was declared
+	Position() Position
+	SetPosition(Position) TableColumn
+
 	// Normalize returns normalized column. If a normalization was performed
 	// and the column is modified, returns a new instance of the Table object
 	// along with a true value as the second return value.
@@ -356,6 +514,8 @@ type tablecol struct {
 	defaultValue defaultValue
 	comment      maybeString
 	autoUpdate   maybeString
+	autoRandom   maybeString
+	srid         maybeString
 	enumValues   []string
 	setValues    []string
 	autoincr     bool
@@ -365,6 +525,7 @@ type tablecol struct {
 	unique       bool
 	unsigned     bool
 	zerofill     bool
+	pos          Position
 }
 
 // Database represents a database definition
@@ -378,6 +539,8 @@ type Database interface {
 	isDatabase() bool
 
 	Stmt
+	io.WriterTo
+	fmt.Stringer
 
 	Name() string
 	IsIfNotExists() bool
@@ -388,3 +551,211 @@ type database struct {
 	name        string
 	ifnotexists bool
 }
+
+// DropTable describes a `DROP TABLE` statement for a single table. A
+// statement that names more than one table creates one DropTable per
+// table.
+type DropTable interface {
+	// This is a dummy method to differentiate between DropTable and
+	// DropDatabase, as with Database
+	isDropTable() bool
+
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	Name() string
+	IsTemporary() bool
+	SetTemporary(bool) DropTable
+	IsIfExists() bool
+	SetIfExists(bool) DropTable
+}
+
+type dropTable struct {
+	name      string
+	temporary bool
+	ifexists  bool
+}
+
+// DropDatabase describes a `DROP DATABASE` statement
+type DropDatabase interface {
+	isDropDatabase() bool
+
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	Name() string
+	IsIfExists() bool
+	SetIfExists(bool) DropDatabase
+}
+
+type dropDatabase struct {
+	name     string
+	ifexists bool
+}
+
+// Histogram describes the optimizer statistics for a single column,
+// as created by `ANALYZE TABLE ... UPDATE HISTOGRAM ON ...`.
+// A statement that names more than one column creates one Histogram
+// per column.
+type Histogram interface {
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	TableName() string
+	ColumnName() string
+	HasBuckets() bool
+	Buckets() int
+	SetBuckets(int) Histogram
+}
+
+type histogram struct {
+	table   string
+	column  string
+	buckets int
+}
+
+// View describes a `CREATE VIEW` statement. The SELECT statement that
+// defines the view is kept as written, as the parser does not read
+// its contents.
+type View interface {
+	isView() bool
+
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	Name() string
+	// Columns returns the names given to the columns of the view, or
+	// nil if the names are those of the SELECT statement
+	Columns() []string
+	SetColumns([]string) View
+	// Algorithm returns the ALGORITHM of the view, such as MERGE, or
+	// an empty string if it is not specified
+	Algorithm() string
+	SetAlgorithm(string) View
+	// Definer returns the DEFINER of the view as written, such as
+	// `root`@`localhost`, or an empty string if it is not specified
+	Definer() string
+	SetDefiner(string) View
+	// SQLSecurity returns the SQL SECURITY of the view, DEFINER or
+	// INVOKER, or an empty string if it is not specified
+	SQLSecurity() string
+	SetSQLSecurity(string) View
+	// Definition returns the SELECT statement of the view
+	Definition() string
+	SetDefinition(string) View
+	// CheckOption returns the level of WITH CHECK OPTION, CASCADED or
+	// LOCAL, or an empty string if the view has no check option
+	CheckOption() string
+	SetCheckOption(string) View
+
+	// Position returns where the view was declared
+	Position() Position
+	SetPosition(Position) View
+}
+
+type view struct {
+	name        string
+	columns     []string
+	algorithm   string
+	definer     string
+	security    string
+	definition  string
+	checkOption string
+	pos         Position
+}
+
+// DropView describes a `DROP VIEW` statement for a single view. A
+// statement that names more than one view creates one DropView per
+// view.
+type DropView interface {
+	isDropView() bool
+
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	Name() string
+	IsIfExists() bool
+	SetIfExists(bool) DropView
+}
+
+type dropView struct {
+	name     string
+	ifexists bool
+}
+
+// RoutineKind describes the kind of a Routine
+type RoutineKind int
+
+// List of possible RoutineKind.
+const (
+	RoutineKindInvalid RoutineKind = iota
+	RoutineKindTrigger
+	RoutineKindProcedure
+	RoutineKindFunction
+)
+
+// Routine describes a `CREATE TRIGGER`, `CREATE PROCEDURE` or `CREATE
+// FUNCTION` statement. What follows the name, such as the parameters
+// and the body, is kept as written, as the parser does not read the
+// statements of the body.
+type Routine interface {
+	isRoutine() bool
+
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	Kind() RoutineKind
+	Name() string
+	// Table returns the name of the table of a trigger, or an empty
+	// string for procedures and functions
+	Table() string
+	SetTable(string) Routine
+	// Definer returns the DEFINER of the routine as written, such as
+	// `root`@`localhost`, or an empty string if it is not specified
+	Definer() string
+	SetDefiner(string) Routine
+	// Definition returns what follows the name of the routine, such as
+	// BEFORE INSERT ON t FOR EACH ROW SET NEW.a = 1 for a trigger
+	Definition() string
+	SetDefinition(string) Routine
+
+	// Position returns where the routine was declared
+	Position() Position
+	SetPosition(Position) Routine
+}
+
+type routine struct {
+	kind       RoutineKind
+	name       string
+	table      string
+	definer    string
+	definition string
+	pos        Position
+}
+
+// DropRoutine describes a `DROP TRIGGER`, `DROP PROCEDURE` or `DROP
+// FUNCTION` statement
+type DropRoutine interface {
+	isDropRoutine() bool
+
+	Stmt
+	io.WriterTo
+	fmt.Stringer
+
+	Kind() RoutineKind
+	Name() string
+	IsIfExists() bool
+	SetIfExists(bool) DropRoutine
+}
+
+type dropRoutine struct {
+	kind     RoutineKind
+	name     string
+	ifexists bool
+}
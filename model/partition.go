@@ -0,0 +1,174 @@
+package model
+
+// NewPartitioning creates a new partitioning of the given type
+func NewPartitioning(typ PartitionType) Partitioning {
+	return &partitioning{
+		typ: typ,
+	}
+}
+
+func (p *partitioning) Type() PartitionType {
+	return p.typ
+}
+
+func (p *partitioning) IsLinear() bool {
+	return p.linear
+}
+
+func (p *partitioning) SetLinear(v bool) Partitioning {
+	p.linear = v
+	return p
+}
+
+func (p *partitioning) Expression() string {
+	return p.expression
+}
+
+func (p *partitioning) SetExpression(s string) Partitioning {
+	p.expression = s
+	return p
+}
+
+func (p *partitioning) HasColumns() bool {
+	return len(p.columns) > 0
+}
+
+func (p *partitioning) Columns() chan string {
+	ch := make(chan string, len(p.columns))
+	for _, col := range p.columns {
+		ch <- col
+	}
+	close(ch)
+	return ch
+}
+
+func (p *partitioning) SetColumns(l []string) Partitioning {
+	p.columns = l
+	return p
+}
+
+func (p *partitioning) HasPartitionCount() bool {
+	return p.count.Valid
+}
+
+func (p *partitioning) PartitionCount() string {
+	return p.count.Value
+}
+
+func (p *partitioning) SetPartitionCount(s string) Partitioning {
+	p.count.Valid = true
+	p.count.Value = s
+	return p
+}
+
+func (p *partitioning) AddDefinition(v PartitionDefinition) Partitioning {
+	p.definitions = append(p.definitions, v)
+	return p
+}
+
+func (p *partitioning) Definitions() chan PartitionDefinition {
+	ch := make(chan PartitionDefinition, len(p.definitions))
+	for _, def := range p.definitions {
+		ch <- def
+	}
+	close(ch)
+	return ch
+}
+
+func (p *partitioning) LookupDefinition(name string) (PartitionDefinition, bool) {
+	for _, def := range p.definitions {
+		if def.Name() == name {
+			return def, true
+		}
+	}
+	return nil, false
+}
+
+func (p *partitioning) Clone() Partitioning {
+	newp := &partitioning{}
+	*newp = *p
+	newp.columns = append([]string(nil), p.columns...)
+	newp.definitions = make([]PartitionDefinition, 0, len(p.definitions))
+	for _, def := range p.definitions {
+		newdef := &partitionDefinition{}
+		*newdef = *(def.(*partitionDefinition))
+		newp.definitions = append(newp.definitions, newdef)
+	}
+	return newp
+}
+
+// NewPartitionDefinition creates a new partition definition with the given name
+func NewPartitionDefinition(name string) PartitionDefinition {
+	return &partitionDefinition{
+		name: name,
+	}
+}
+
+func (d *partitionDefinition) Name() string {
+	return d.name
+}
+
+func (d *partitionDefinition) HasLessThan() bool {
+	return d.lessThan.Valid
+}
+
+func (d *partitionDefinition) LessThan() string {
+	return d.lessThan.Value
+}
+
+func (d *partitionDefinition) SetLessThan(s string) PartitionDefinition {
+	d.lessThan.Valid = true
+	d.lessThan.Value = s
+	return d
+}
+
+func (d *partitionDefinition) IsMaxValue() bool {
+	return d.maxValue
+}
+
+func (d *partitionDefinition) SetMaxValue(v bool) PartitionDefinition {
+	d.maxValue = v
+	return d
+}
+
+func (d *partitionDefinition) HasValuesIn() bool {
+	return d.valuesIn.Valid
+}
+
+func (d *partitionDefinition) ValuesIn() string {
+	return d.valuesIn.Value
+}
+
+func (d *partitionDefinition) SetValuesIn(s string) PartitionDefinition {
+	d.valuesIn.Valid = true
+	d.valuesIn.Value = s
+	return d
+}
+
+func (d *partitionDefinition) HasEngine() bool {
+	return d.engine.Valid
+}
+
+func (d *partitionDefinition) Engine() string {
+	return d.engine.Value
+}
+
+func (d *partitionDefinition) SetEngine(s string) PartitionDefinition {
+	d.engine.Valid = true
+	d.engine.Value = s
+	return d
+}
+
+func (d *partitionDefinition) HasComment() bool {
+	return d.comment.Valid
+}
+
+func (d *partitionDefinition) Comment() string {
+	return d.comment.Value
+}
+
+func (d *partitionDefinition) SetComment(s string) PartitionDefinition {
+	d.comment.Valid = true
+	d.comment.Value = s
+	return d
+}
@@ -0,0 +1,32 @@
+package model
+
+import "strconv"
+
+// Position describes where a statement or a part of it was declared
+// in the source. The zero value means that the position is unknown,
+// such as for objects that are not created by the parser.
+type Position struct {
+	// File is the name of the file, which is empty if the source
+	// was not read from a file
+	File string
+	Line int
+	Col  int
+}
+
+// IsValid returns true if the position is known
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form "file:line:col", or
+// "line:col" if the file is unknown
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	s := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Col)
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
+}
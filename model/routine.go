@@ -0,0 +1,112 @@
+package model
+
+import "strings"
+
+// String returns the keyword of the kind, such as TRIGGER
+func (k RoutineKind) String() string {
+	switch k {
+	case RoutineKindTrigger:
+		return "TRIGGER"
+	case RoutineKindProcedure:
+		return "PROCEDURE"
+	case RoutineKindFunction:
+		return "FUNCTION"
+	}
+	return "INVALID"
+}
+
+// NewRoutine creates a new trigger, procedure or function with the given
+// name, defined by what follows the name
+func NewRoutine(kind RoutineKind, name, definition string) Routine {
+	return &routine{
+		kind:       kind,
+		name:       name,
+		definition: definition,
+	}
+}
+
+func (r *routine) isRoutine() bool {
+	return true
+}
+
+func (r *routine) ID() string {
+	return strings.ToLower(r.kind.String()) + "#" + r.name
+}
+
+func (r *routine) Kind() RoutineKind {
+	return r.kind
+}
+
+func (r *routine) Name() string {
+	return r.name
+}
+
+func (r *routine) Table() string {
+	return r.table
+}
+
+func (r *routine) SetTable(s string) Routine {
+	r.table = s
+	return r
+}
+
+func (r *routine) Definer() string {
+	return r.definer
+}
+
+func (r *routine) SetDefiner(s string) Routine {
+	r.definer = s
+	return r
+}
+
+func (r *routine) Definition() string {
+	return r.definition
+}
+
+func (r *routine) SetDefinition(s string) Routine {
+	r.definition = s
+	return r
+}
+
+func (r *routine) Position() Position {
+	return r.pos
+}
+
+func (r *routine) SetPosition(pos Position) Routine {
+	r.pos = pos
+	return r
+}
+
+// NewDropRoutine creates a new DROP statement for the given trigger,
+// procedure or function
+func NewDropRoutine(kind RoutineKind, name string) DropRoutine {
+	return &dropRoutine{
+		kind: kind,
+		name: name,
+	}
+}
+
+func (d *dropRoutine) isDropRoutine() bool {
+	return true
+}
+
+func (d *dropRoutine) ID() string {
+	return "drop" + strings.ToLower(d.kind.String()) + "#" + d.name
+}
+
+func (d *dropRoutine) Kind() RoutineKind {
+	return d.kind
+}
+
+func (d *dropRoutine) Name() string {
+	return d.name
+}
+
+func (d *dropRoutine) IsIfExists() bool {
+	return d.ifexists
+}
+
+func (d *dropRoutine) SetIfExists(v bool) DropRoutine {
+	d.ifexists = v
+	return d
+}
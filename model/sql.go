@@ -0,0 +1,809 @@
+package model
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/sqlfmt"
+	"github.com/eihigh/schemalex/internal/util"
+)
+
+// fmtCtx holds the state of the SQL formatting of the statements,
+// which WriteTo, String, and the format package use
+type fmtCtx struct {
+	curIndent    string
+	dst          io.Writer
+	indent       string
+	singleLine   bool
+	displayWidth bool
+	quoteFunc    func(string) string
+	skipTemp     bool
+	depOrder     bool
+}
+
+func init() {
+	sqlfmt.Write = writeSQL
+}
+
+// writeSQL writes the SQL representation of v to dst with the options
+// of the format package
+func writeSQL(dst io.Writer, v interface{}, o sqlfmt.Options) error {
+	ctx := &fmtCtx{
+		dst:          dst,
+		indent:       o.Indent,
+		singleLine:   o.SingleLine,
+		displayWidth: o.DisplayWidth,
+		quoteFunc:    o.Quote,
+		skipTemp:     o.SkipTemporary,
+		depOrder:     o.DependencyOrder,
+	}
+	if ctx.singleLine {
+		ctx.indent = ""
+	}
+	return format(ctx, v)
+}
+
+func (ctx *fmtCtx) clone() *fmtCtx {
+	c := *ctx
+	return &c
+}
+
+// quote returns the identifier surrounded by backticks, unless
+// identifiers are only quoted when needed and it does not need them
+func (ctx *fmtCtx) quote(ident string) string {
+	if ctx.quoteFunc != nil {
+		return ctx.quoteFunc(ident)
+	}
+	return util.Backquote(ident)
+}
+
+// newline writes a line break to buf, or a space if statements
+// are formatted on a single line
+func (ctx *fmtCtx) newline(buf *bytes.Buffer) {
+	if ctx.singleLine {
+		buf.WriteByte(' ')
+		return
+	}
+	buf.WriteByte('\n')
+}
+
+func format(ctx *fmtCtx, v interface{}) error {
+	switch v.(type) {
+	case ColumnType:
+		return formatColumnType(ctx, v.(ColumnType))
+	case Database:
+		return formatDatabase(ctx, v.(Database))
+	case Stmts:
+		stmts := v.(Stmts)
+		if ctx.depOrder {
+			stmts = stmts.InDependencyOrder()
+		}
+		for _, s := range stmts {
+			if table, ok := s.(Table); ok && ctx.skipTemp && table.IsTemporary() {
+				continue
+			}
+			if err := format(ctx, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Table:
+		return formatTable(ctx, v.(Table))
+	case TableColumn:
+		return formatTableColumn(ctx, v.(TableColumn))
+	case TableOption:
+		return formatTableOption(ctx, v.(TableOption))
+	case Index:
+		return formatIndex(ctx, v.(Index))
+	case Reference:
+		return formatReference(ctx, v.(Reference))
+	case Partitioning:
+		return formatPartitioning(ctx, v.(Partitioning))
+	case PartitionDefinition:
+		return formatPartitionDefinition(ctx, v.(PartitionDefinition))
+	case Histogram:
+		return formatHistogram(ctx, v.(Histogram))
+	case DropTable:
+		return formatDropTable(ctx, v.(DropTable))
+	case DropDatabase:
+		return formatDropDatabase(ctx, v.(DropDatabase))
+	case View:
+		return formatView(ctx, v.(View))
+	case DropView:
+		return formatDropView(ctx, v.(DropView))
+	case Routine:
+		return formatRoutine(ctx, v.(Routine))
+	case DropRoutine:
+		return formatDropRoutine(ctx, v.(DropRoutine))
+	default:
+		return errors.New("unsupported model type")
+	}
+}
+
+func formatDatabase(ctx *fmtCtx, d Database) error {
+	var buf bytes.Buffer
+	buf.WriteString("CREATE DATABASE")
+	if d.IsIfNotExists() {
+		buf.WriteString(" IF NOT EXISTS")
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(ctx.quote(d.Name()))
+	buf.WriteByte(';')
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatHistogram(ctx *fmtCtx, h Histogram) error {
+	var buf bytes.Buffer
+	buf.WriteString("ANALYZE TABLE ")
+	buf.WriteString(ctx.quote(h.TableName()))
+	buf.WriteString(" UPDATE HISTOGRAM ON ")
+	buf.WriteString(ctx.quote(h.ColumnName()))
+	if h.HasBuckets() {
+		buf.WriteString(" WITH ")
+		buf.WriteString(strconv.Itoa(h.Buckets()))
+		buf.WriteString(" BUCKETS")
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatDropTable(ctx *fmtCtx, d DropTable) error {
+	var buf bytes.Buffer
+	buf.WriteString("DROP ")
+	if d.IsTemporary() {
+		buf.WriteString("TEMPORARY ")
+	}
+	buf.WriteString("TABLE ")
+	if d.IsIfExists() {
+		buf.WriteString("IF EXISTS ")
+	}
+	buf.WriteString(ctx.quote(d.Name()))
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+// formatView writes the CREATE VIEW statement of the view. The SELECT
+// statement is written as it was declared.
+func formatView(ctx *fmtCtx, v View) error {
+	var buf bytes.Buffer
+	buf.WriteString("CREATE ")
+	if s := v.Algorithm(); s != "" {
+		buf.WriteString("ALGORITHM = ")
+		buf.WriteString(s)
+		buf.WriteByte(' ')
+	}
+	if s := v.Definer(); s != "" {
+		buf.WriteString("DEFINER = ")
+		buf.WriteString(s)
+		buf.WriteByte(' ')
+	}
+	if s := v.SQLSecurity(); s != "" {
+		buf.WriteString("SQL SECURITY ")
+		buf.WriteString(s)
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("VIEW ")
+	buf.WriteString(ctx.quote(v.Name()))
+	if columns := v.Columns(); len(columns) > 0 {
+		buf.WriteString(" (")
+		for i, col := range columns {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(ctx.quote(col))
+		}
+		buf.WriteByte(')')
+	}
+	buf.WriteString(" AS ")
+	buf.WriteString(v.Definition())
+	if s := v.CheckOption(); s != "" {
+		buf.WriteString(" WITH ")
+		buf.WriteString(s)
+		buf.WriteString(" CHECK OPTION")
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatDropView(ctx *fmtCtx, d DropView) error {
+	var buf bytes.Buffer
+	buf.WriteString("DROP VIEW ")
+	if d.IsIfExists() {
+		buf.WriteString("IF EXISTS ")
+	}
+	buf.WriteString(ctx.quote(d.Name()))
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatDropDatabase(ctx *fmtCtx, d DropDatabase) error {
+	var buf bytes.Buffer
+	buf.WriteString("DROP DATABASE ")
+	if d.IsIfExists() {
+		buf.WriteString("IF EXISTS ")
+	}
+	buf.WriteString(ctx.quote(d.Name()))
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatTableOption(ctx *fmtCtx, option TableOption) error {
+	var buf bytes.Buffer
+	buf.WriteString(option.Key())
+	buf.WriteString(" = ")
+	if option.NeedQuotes() {
+		buf.WriteString(util.Singlequote(option.Value()))
+	} else {
+		buf.WriteString(option.Value())
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatTable(ctx *fmtCtx, table Table) error {
+	var buf bytes.Buffer
+
+	buf.WriteString("CREATE")
+	if table.IsTemporary() {
+		buf.WriteString(" TEMPORARY")
+	}
+
+	buf.WriteString(" TABLE")
+	if table.IsIfNotExists() {
+		buf.WriteString(" IF NOT EXISTS")
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(ctx.quote(table.Name()))
+
+	if table.HasLikeTable() {
+		buf.WriteString(" LIKE ")
+		buf.WriteString(ctx.quote(table.LikeTable()))
+	} else {
+
+		newctx := ctx.clone()
+		newctx.curIndent = newctx.indent + newctx.curIndent
+		newctx.dst = &buf
+
+		buf.WriteString(" (")
+
+		colch := table.Columns()
+		idxch := table.Indexes()
+		colchmax := len(colch)
+		idxchmax := len(idxch)
+
+		var i int
+		for col := range colch {
+			ctx.newline(&buf)
+			if err := formatTableColumn(newctx, col); err != nil {
+				return err
+			}
+			if i < colchmax-1 || idxchmax > 0 {
+				buf.WriteByte(',')
+			}
+			i++
+		}
+
+		i = 0
+		for idx := range idxch {
+			ctx.newline(&buf)
+			if err := formatIndex(newctx, idx); err != nil {
+				return err
+			}
+			if i < idxchmax-1 {
+				buf.WriteByte(',')
+			}
+			i++
+		}
+
+		ctx.newline(&buf)
+		buf.WriteByte(')')
+
+		optch := table.Options()
+		if l := len(optch); l > 0 {
+			buf.WriteByte(' ')
+			var i int
+			for option := range optch {
+				if err := formatTableOption(newctx, option); err != nil {
+					return err
+				}
+
+				if i < l-1 {
+					buf.WriteString(", ")
+				}
+				i++
+			}
+		}
+
+		if table.HasPartitioning() {
+			partctx := ctx.clone()
+			partctx.dst = &buf
+			ctx.newline(&buf)
+			if err := formatPartitioning(partctx, table.Partitioning()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatPartitioning(ctx *fmtCtx, partitioning Partitioning) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	buf.WriteString("PARTITION BY ")
+	if partitioning.IsLinear() {
+		buf.WriteString("LINEAR ")
+	}
+
+	switch partitioning.Type() {
+	case PartitionTypeRange:
+		buf.WriteString("RANGE")
+	case PartitionTypeList:
+		buf.WriteString("LIST")
+	case PartitionTypeHash:
+		buf.WriteString("HASH")
+	case PartitionTypeKey:
+		buf.WriteString("KEY")
+	default:
+		return errors.New(`invalid partition type`)
+	}
+
+	switch {
+	case partitioning.Type() == PartitionTypeKey:
+		buf.WriteString(" (")
+		writeIdentList(ctx, &buf, partitioning.Columns())
+		buf.WriteByte(')')
+	case partitioning.HasColumns():
+		buf.WriteString(" COLUMNS (")
+		writeIdentList(ctx, &buf, partitioning.Columns())
+		buf.WriteByte(')')
+	default:
+		buf.WriteString(" (")
+		buf.WriteString(partitioning.Expression())
+		buf.WriteByte(')')
+	}
+
+	if partitioning.HasPartitionCount() {
+		buf.WriteString(" PARTITIONS ")
+		buf.WriteString(partitioning.PartitionCount())
+	}
+
+	defch := partitioning.Definitions()
+	if l := len(defch); l > 0 {
+		newctx := ctx.clone()
+		newctx.curIndent = newctx.indent + newctx.curIndent
+		newctx.dst = &buf
+
+		buf.WriteString(" (")
+		var i int
+		for def := range defch {
+			ctx.newline(&buf)
+			if err := formatPartitionDefinition(newctx, def); err != nil {
+				return err
+			}
+			if i < l-1 {
+				buf.WriteByte(',')
+			}
+			i++
+		}
+		ctx.newline(&buf)
+		buf.WriteString(ctx.curIndent)
+		buf.WriteByte(')')
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatPartitionDefinition(ctx *fmtCtx, def PartitionDefinition) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	buf.WriteString("PARTITION ")
+	buf.WriteString(ctx.quote(def.Name()))
+
+	switch {
+	case def.IsMaxValue():
+		buf.WriteString(" VALUES LESS THAN MAXVALUE")
+	case def.HasLessThan():
+		buf.WriteString(" VALUES LESS THAN (")
+		buf.WriteString(def.LessThan())
+		buf.WriteByte(')')
+	case def.HasValuesIn():
+		buf.WriteString(" VALUES IN (")
+		buf.WriteString(def.ValuesIn())
+		buf.WriteByte(')')
+	}
+
+	if def.HasEngine() {
+		buf.WriteString(" ENGINE = ")
+		buf.WriteString(def.Engine())
+	}
+
+	if def.HasComment() {
+		buf.WriteString(" COMMENT = ")
+		buf.WriteString(util.Singlequote(def.Comment()))
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func writeIdentList(ctx *fmtCtx, buf *bytes.Buffer, ch chan string) {
+	var i int
+	for name := range ch {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(ctx.quote(name))
+		i++
+	}
+}
+
+func formatColumnType(ctx *fmtCtx, col ColumnType) error {
+	if col <= ColumnTypeInvalid || col >= ColumnTypeMax {
+		return errors.New(`invalid column type`)
+	}
+
+	if _, err := io.WriteString(ctx.dst, col.String()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func formatTableColumn(ctx *fmtCtx, col TableColumn) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	buf.WriteString(ctx.quote(col.Name()))
+	buf.WriteByte(' ')
+
+	newctx := ctx.clone()
+	newctx.curIndent = ""
+	newctx.dst = &buf
+	if err := formatColumnType(newctx, col.Type()); err != nil {
+		return err
+	}
+
+	switch col.Type() {
+	case ColumnTypeEnum:
+		buf.WriteString(" (")
+		for enumValue := range col.EnumValues() {
+			buf.WriteString(util.Singlequote(enumValue))
+			buf.WriteByte(',')
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(')')
+	case ColumnTypeSet:
+		buf.WriteString(" (")
+		for setValue := range col.SetValues() {
+			buf.WriteString(util.Singlequote(setValue))
+			buf.WriteByte(',')
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(')')
+	default:
+		if col.HasLength() && (ctx.displayWidth || !isDisplayWidth(col)) {
+			l := col.Length()
+			buf.WriteString(" (")
+			buf.WriteString(l.Length())
+			if l.HasDecimal() {
+				buf.WriteByte(',')
+				buf.WriteString(l.Decimal())
+			}
+			buf.WriteByte(')')
+		}
+	}
+
+	if col.IsUnsigned() {
+		buf.WriteString(" UNSIGNED")
+	}
+
+	if col.IsZeroFill() {
+		buf.WriteString(" ZEROFILL")
+	}
+
+	if col.IsBinary() {
+		buf.WriteString(" BINARY")
+	}
+
+	if col.HasCharacterSet() {
+		buf.WriteString(" CHARACTER SET ")
+		buf.WriteString(ctx.quote(col.CharacterSet()))
+	}
+
+	if col.HasCollation() {
+		buf.WriteString(" COLLATE ")
+		buf.WriteString(ctx.quote(col.Collation()))
+	}
+
+	if col.HasAutoUpdate() {
+		buf.WriteString(" ON UPDATE ")
+		buf.WriteString(col.AutoUpdate())
+	}
+
+	if n := col.NullState(); n != NullStateNone {
+		buf.WriteByte(' ')
+		switch n {
+		case NullStateNull:
+			buf.WriteString("NULL")
+		case NullStateNotNull:
+			buf.WriteString("NOT NULL")
+		}
+	}
+
+	if col.HasSRID() {
+		buf.WriteString(" SRID ")
+		buf.WriteString(col.SRID())
+	}
+
+	if col.HasDefault() {
+		buf.WriteString(" DEFAULT ")
+		if col.IsQuotedDefault() {
+			buf.WriteString(util.Singlequote(col.Default()))
+		} else {
+			buf.WriteString(col.Default())
+		}
+	}
+
+	if col.IsAutoIncrement() {
+		buf.WriteString(" AUTO_INCREMENT")
+	}
+
+	if col.HasAutoRandom() {
+		buf.WriteString(" AUTO_RANDOM")
+		if args := col.AutoRandom(); args != "" {
+			buf.WriteByte('(')
+			buf.WriteString(args)
+			buf.WriteByte(')')
+		}
+	}
+
+	if col.IsUnique() {
+		buf.WriteString(" UNIQUE KEY")
+	}
+
+	if col.IsPrimary() {
+		buf.WriteString(" PRIMARY KEY")
+	} else if col.IsKey() {
+		buf.WriteString(" KEY")
+	}
+
+	if col.HasComment() {
+		buf.WriteString(" COMMENT ")
+		buf.WriteString(util.Singlequote(col.Comment()))
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatIndex(ctx *fmtCtx, index Index) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	if index.HasSymbol() {
+		buf.WriteString("CONSTRAINT ")
+		buf.WriteString(ctx.quote(index.Symbol()))
+		buf.WriteByte(' ')
+	}
+
+	switch {
+	case index.IsPrimaryKey():
+		buf.WriteString("PRIMARY KEY")
+	case index.IsNormal():
+		buf.WriteString("INDEX")
+	case index.IsUnique():
+		buf.WriteString("UNIQUE INDEX")
+	case index.IsFullText():
+		buf.WriteString("FULLTEXT INDEX")
+	case index.IsSpatial():
+		buf.WriteString("SPATIAL INDEX")
+	case index.IsForeignKey():
+		buf.WriteString("FOREIGN KEY")
+	}
+
+	if index.HasName() {
+		buf.WriteByte(' ')
+		buf.WriteString(ctx.quote(index.Name()))
+	}
+
+	switch {
+	case index.IsBtree():
+		buf.WriteString(" USING BTREE")
+	case index.IsHash():
+		buf.WriteString(" USING HASH")
+	}
+
+	buf.WriteString(" (")
+	ch := index.Columns()
+	lch := len(ch)
+	if lch == 0 {
+		return errors.New(`no columns in index`)
+	}
+
+	var i int
+	for col := range ch {
+		buf.WriteString(ctx.quote(col.Name()))
+		if col.HasLength() {
+			buf.WriteByte('(')
+			buf.WriteString(col.Length())
+			buf.WriteByte(')')
+		}
+		if col.HasSortDirection() {
+			if col.IsAscending() {
+				buf.WriteString(" ASC")
+			} else {
+				buf.WriteString(" DESC")
+			}
+		}
+
+		if i < lch-1 {
+			buf.WriteString(", ")
+		}
+		i++
+	}
+	buf.WriteByte(')')
+
+	switch {
+	case index.IsClustered():
+		buf.WriteString(" CLUSTERED")
+	case index.IsNonClustered():
+		buf.WriteString(" NONCLUSTERED")
+	}
+
+	if index.HasParser() {
+		buf.WriteString(" WITH PARSER ")
+		buf.WriteString(index.Parser())
+	}
+
+	if ref := index.Reference(); ref != nil {
+		newctx := ctx.clone()
+		newctx.dst = &buf
+
+		buf.WriteByte(' ')
+		if err := formatReference(newctx, ref); err != nil {
+			return err
+		}
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatReference(ctx *fmtCtx, r Reference) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	buf.WriteString("REFERENCES ")
+	buf.WriteString(ctx.quote(r.TableName()))
+	buf.WriteString(" (")
+
+	ch := r.Columns()
+	lch := len(ch)
+	var i int
+	for col := range ch {
+		buf.WriteString(ctx.quote(col.Name()))
+		if col.HasLength() {
+			buf.WriteByte('(')
+			buf.WriteString(col.Length())
+			buf.WriteByte(')')
+		}
+		if i < lch-1 {
+			buf.WriteString(", ")
+		}
+		i++
+	}
+	buf.WriteByte(')')
+
+	switch {
+	case r.MatchFull():
+		buf.WriteString(" MATCH FULL")
+	case r.MatchPartial():
+		buf.WriteString(" MATCH PARTIAL")
+	case r.MatchSimple():
+		buf.WriteString(" MATCH SIMPLE")
+	}
+
+	// we should really check for errors...
+	writeReferenceOption(&buf, "ON DELETE", r.OnDelete())
+	writeReferenceOption(&buf, "ON UPDATE", r.OnUpdate())
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isDisplayWidth returns true if the length of the column is a display
+// width that MySQL 8.0.19 and later omit
+func isDisplayWidth(col TableColumn) bool {
+	if col.IsZeroFill() {
+		return false
+	}
+
+	switch col.Type() {
+	case ColumnTypeTinyInt, ColumnTypeBool, ColumnTypeBoolean:
+		return col.Length().Length() != "1"
+	case ColumnTypeSmallInt, ColumnTypeMediumInt,
+		ColumnTypeInt, ColumnTypeInteger, ColumnTypeBigInt:
+		return true
+	}
+	return false
+}
+
+// formatRoutine writes the CREATE statement of the trigger, procedure
+// or function. What follows the name is written as it was declared.
+func formatRoutine(ctx *fmtCtx, r Routine) error {
+	var buf bytes.Buffer
+	buf.WriteString("CREATE ")
+	if s := r.Definer(); s != "" {
+		buf.WriteString("DEFINER = ")
+		buf.WriteString(s)
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(r.Kind().String())
+	buf.WriteByte(' ')
+	buf.WriteString(ctx.quote(r.Name()))
+	buf.WriteByte(' ')
+	buf.WriteString(r.Definition())
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatDropRoutine(ctx *fmtCtx, d DropRoutine) error {
+	var buf bytes.Buffer
+	buf.WriteString("DROP ")
+	buf.WriteString(d.Kind().String())
+	buf.WriteByte(' ')
+	if d.IsIfExists() {
+		buf.WriteString("IF EXISTS ")
+	}
+	buf.WriteString(ctx.quote(d.Name()))
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
@@ -9,3 +9,16 @@ func (s Stmts) Lookup(id string) (Stmt, bool) {
 	}
 	return nil, false
 }
+
+// Filter returns the statements for which fn returns true, in order.
+// Combined with DependencyGraph.Closure, it extracts a subset of a
+// schema, such as the tables that some tests need.
+func (s Stmts) Filter(fn func(Stmt) bool) Stmts {
+	var filtered Stmts
+	for _, stmt := range s {
+		if fn(stmt) {
+			filtered = append(filtered, stmt)
+		}
+	}
+	return filtered
+}
@@ -1,5 +1,10 @@
 package model
 
+import (
+	"strconv"
+	"strings"
+)
+
 // NewTable create a new table with the given name
 func NewTable(name string) Table {
 	return &table{
@@ -118,6 +123,28 @@ func (t *table) SetTemporary(v bool) Table {
 	return t
 }
 
+func (t *table) HasPartitioning() bool {
+	return t.partitioning != nil
+}
+
+func (t *table) Partitioning() Partitioning {
+	return t.partitioning
+}
+
+func (t *table) SetPartitioning(v Partitioning) Table {
+	t.partitioning = v
+	return t
+}
+
+func (t *table) Position() Position {
+	return t.pos
+}
+
+func (t *table) SetPosition(v Position) Table {
+	t.pos = v
+	return t
+}
+
 func (t *table) Columns() chan TableColumn {
 	ch := make(chan TableColumn, len(t.columns))
 	for _, col := range t.columns {
@@ -146,10 +173,27 @@ func (t *table) Options() chan TableOption {
 }
 
 func (t *table) Normalize() (Table, bool) {
+	return t.normalize(false)
+}
+
+func (t *table) NormalizeUpstream() (Table, bool) {
+	return t.normalize(true)
+}
+
+func (t *table) normalize(upstream bool) (Table, bool) {
 	var clone bool
 	var additionalIndexes []Index
 	var columns []TableColumn
+	primaryColumns := t.primaryKeyColumns()
 	for col := range t.Columns() {
+		// the columns of the primary key are NOT NULL, even if they are
+		// not declared so
+		if _, ok := primaryColumns[strings.ToLower(col.Name())]; ok && !upstream && col.NullState() != NullStateNotNull {
+			clone = true
+			col = col.Clone()
+			col.SetNullState(NullStateNotNull)
+		}
+
 		ncol, modified := col.Normalize()
 		if modified {
 			clone = true
@@ -158,11 +202,12 @@ func (t *table) Normalize() (Table, bool) {
 		// column_definition [UNIQUE [KEY] | [PRIMARY] KEY]
 		// they mean same as INDEX or CONSTRAINT
 		switch {
-		case ncol.IsPrimary():
+		case ncol.IsPrimary() || ncol.IsKey():
 			// we have to move off the index declaration from the
 			// primary key column to an index associated with the table
 			index := NewIndex(IndexKindPrimaryKey, t.ID())
 			index.SetType(IndexTypeNone)
+			index.SetPosition(ncol.Position())
 			idxCol := NewIndexColumn(ncol.Name())
 			index.AddColumns(idxCol)
 			additionalIndexes = append(additionalIndexes, index)
@@ -171,11 +216,13 @@ func (t *table) Normalize() (Table, bool) {
 			}
 			ncol = ncol.Clone()
 			ncol.SetPrimary(false)
+			ncol.SetKey(false)
 		case ncol.IsUnique():
 			index := NewIndex(IndexKindUnique, t.ID())
 			// if you do not assign a name, the index is assigned the same name as the first indexed column
 			index.SetName(ncol.Name())
 			index.SetType(IndexTypeNone)
+			index.SetPosition(ncol.Position())
 			idxCol := NewIndexColumn(ncol.Name())
 			index.AddColumns(idxCol)
 			additionalIndexes = append(additionalIndexes, index)
@@ -210,6 +257,7 @@ func (t *table) Normalize() (Table, bool) {
 				// add implicitly created INDEX
 				index := NewIndex(IndexKindNormal, t.ID())
 				index.SetName(nidx.Symbol())
+				index.SetPosition(nidx.Position())
 				if nidx.IsBtree() {
 					index.SetType(IndexTypeBtree)
 				} else if nidx.IsHash() {
@@ -227,6 +275,10 @@ func (t *table) Normalize() (Table, bool) {
 		seen[nidx.Name()] = struct{}{}
 	}
 
+	if !upstream && nameIndexes(additionalIndexes, indexes) {
+		clone = true
+	}
+
 	if !clone {
 		return t, false
 	}
@@ -234,6 +286,7 @@ func (t *table) Normalize() (Table, bool) {
 	tbl := NewTable(t.Name())
 	tbl.SetIfNotExists(t.IsIfNotExists())
 	tbl.SetTemporary(t.IsTemporary())
+	tbl.SetPosition(t.Position())
 
 	for _, index := range additionalIndexes {
 		tbl.AddIndex(index)
@@ -250,9 +303,82 @@ func (t *table) Normalize() (Table, bool) {
 	for opt := range t.Options() {
 		tbl.AddOption(opt)
 	}
+
+	if t.HasPartitioning() {
+		tbl.SetPartitioning(t.Partitioning())
+	}
 	return tbl, true
 }
 
+// nameIndexes names the indexes that are declared without a name, as
+// MySQL does: after their symbol if they have one, and otherwise after
+// their first column, with a suffix such as _2 if the name is already
+// taken. The primary key and the foreign keys are left alone. The
+// indexes of the table are replaced by the named ones, and true is
+// returned if any index was named.
+func nameIndexes(columnIndexes, indexes []Index) bool {
+	taken := map[string]struct{}{"primary": {}}
+	for _, list := range [][]Index{columnIndexes, indexes} {
+		for _, idx := range list {
+			if idx.HasName() && !idx.IsForeignKey() {
+				taken[strings.ToLower(idx.Name())] = struct{}{}
+			}
+		}
+	}
+
+	var named bool
+	for i, idx := range indexes {
+		if idx.HasName() || idx.IsPrimaryKey() || idx.IsForeignKey() {
+			continue
+		}
+
+		var name string
+		if idx.HasSymbol() {
+			name = idx.Symbol()
+		} else {
+			for col := range idx.Columns() {
+				if name == "" {
+					name = col.Name()
+				}
+			}
+			if name == "" {
+				continue
+			}
+			base := name
+			for n := 2; ; n++ {
+				if _, ok := taken[strings.ToLower(name)]; !ok {
+					break
+				}
+				name = base + "_" + strconv.Itoa(n)
+			}
+		}
+		taken[strings.ToLower(name)] = struct{}{}
+		indexes[i] = idx.Clone().SetName(name)
+		named = true
+	}
+	return named
+}
+
+// primaryKeyColumns returns the lower cased names of the columns of
+// the primary key, whether it is declared by a column or by the table
+func (t *table) primaryKeyColumns() map[string]struct{} {
+	names := make(map[string]struct{})
+	for col := range t.Columns() {
+		if col.IsPrimary() || col.IsKey() {
+			names[strings.ToLower(col.Name())] = struct{}{}
+		}
+	}
+	for idx := range t.Indexes() {
+		if !idx.IsPrimaryKey() {
+			continue
+		}
+		for col := range idx.Columns() {
+			names[strings.ToLower(col.Name())] = struct{}{}
+		}
+	}
+	return names
+}
+
 // NewTableOption creates a new table option with the given name, value, and a flag indicating if quoting is necessary
 func NewTableOption(k, v string, q bool) TableOption {
 	return &tableopt{
@@ -138,6 +138,13 @@ func (t *tablecol) Name() string {
 	return t.name
 }
 
+// SetName changes the name of the column. As the name is part of the
+// ID, the column must not be renamed after it has been added to a table
+func (t *tablecol) SetName(s string) TableColumn {
+	t.name = s
+	return t
+}
+
 func (t *tablecol) NullState() NullState {
 	return t.nullstate
 }
@@ -223,6 +230,34 @@ func (t *tablecol) AutoUpdate() string {
 	return t.autoUpdate.Value
 }
 
+func (t *tablecol) HasAutoRandom() bool {
+	return t.autoRandom.Valid
+}
+
+func (t *tablecol) SetAutoRandom(s string) TableColumn {
+	t.autoRandom.Value = s
+	t.autoRandom.Valid = true
+	return t
+}
+
+func (t *tablecol) AutoRandom() string {
+	return t.autoRandom.Value
+}
+
+func (t *tablecol) HasSRID() bool {
+	return t.srid.Valid
+}
+
+func (t *tablecol) SetSRID(s string) TableColumn {
+	t.srid.Value = s
+	t.srid.Valid = true
+	return t
+}
+
+func (t *tablecol) SRID() string {
+	return t.srid.Value
+}
+
 func (t *tablecol) HasEnumValues() bool {
 	return len(t.enumValues) != 0
 }
@@ -381,6 +416,15 @@ func (t *tablecol) Normalize() (TableColumn, bool) {
 	return col, true
 }
 
+func (t *tablecol) Position() Position {
+	return t.pos
+}
+
+func (t *tablecol) SetPosition(v Position) TableColumn {
+	t.pos = v
+	return t
+}
+
 func (t *tablecol) Clone() TableColumn {
 	col := &tablecol{}
 	*col = *t
@@ -0,0 +1,289 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a foreign key or an index that MySQL would
+// reject
+type ValidationError struct {
+	// Position is where the foreign key or the index was declared, if
+	// known
+	Position Position
+	// Table is the name of the table that declares the foreign key or
+	// the index
+	Table string
+	// ForeignKey is the name of the foreign key, which is empty if
+	// the foreign key is not named, or if the problem is of an index
+	ForeignKey string
+	// Index is the name of the index, which is empty if the index is
+	// not named, or if the problem is of a foreign key
+	Index   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if !e.Position.IsValid() {
+		return e.Message
+	}
+	return e.Position.String() + ": " + e.Message
+}
+
+// ValidationErrors lists the problems found by Validate, in schema order
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate checks that the foreign keys of the tables can be created:
+// each foreign key must have as many columns as the columns that it
+// references, the referenced table and columns must exist, the
+// referenced columns must be the leading columns of an index of the
+// referenced table, and the types of the columns must be compatible.
+// Spatial indexes must have exactly one column, which must be a NOT
+// NULL column of a spatial type. The problems found are returned as
+// ValidationErrors.
+//
+// Tables created with LIKE are not checked, and the columns of the
+// foreign keys that reference them are not either.
+func (s Stmts) Validate() error {
+	tables := make(map[string]Table)
+	for _, stmt := range s {
+		if table, ok := stmt.(Table); ok {
+			tables[table.Name()] = table
+		}
+	}
+
+	var errs ValidationErrors
+	for _, stmt := range s {
+		table, ok := stmt.(Table)
+		if !ok || table.HasLikeTable() {
+			continue
+		}
+		for idx := range table.Indexes() {
+			if idx.IsSpatial() {
+				for _, msg := range validateSpatialIndex(table, idx) {
+					errs = append(errs, &ValidationError{
+						Position: idx.Position(),
+						Table:    table.Name(),
+						Index:    idx.Name(),
+						Message:  msg,
+					})
+				}
+				continue
+			}
+			if !idx.IsForeignKey() || idx.Reference() == nil {
+				continue
+			}
+			for _, msg := range validateForeignKey(tables, table, idx) {
+				errs = append(errs, &ValidationError{
+					Position:   idx.Position(),
+					Table:      table.Name(),
+					ForeignKey: foreignKeyName(idx),
+					Message:    msg,
+				})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateForeignKey returns the messages describing the problems of
+// the foreign key idx of table
+func validateForeignKey(tables map[string]Table, table Table, idx Index) []string {
+	ref := idx.Reference()
+	subject := "foreign key"
+	if name := foreignKeyName(idx); name != "" {
+		subject += " `" + name + "`"
+	}
+	subject += " on table `" + table.Name() + "`"
+
+	columns := indexColumnNames(idx)
+	refColumns := indexColumnNames(ref)
+	if len(columns) != len(refColumns) {
+		return []string{fmt.Sprintf("%s has %d columns, but references %d columns", subject, len(columns), len(refColumns))}
+	}
+
+	var msgs []string
+	for _, name := range columns {
+		if _, ok := table.LookupColumn(NewTableColumn(name).ID()); !ok {
+			msgs = append(msgs, fmt.Sprintf("%s uses column `%s`, which does not exist", subject, name))
+		}
+	}
+
+	refTable, ok := tables[ref.TableName()]
+	if !ok {
+		return append(msgs, fmt.Sprintf("%s references table `%s`, which does not exist", subject, ref.TableName()))
+	}
+	if refTable.HasLikeTable() {
+		return msgs
+	}
+
+	var missing bool
+	for _, name := range refColumns {
+		if _, ok := refTable.LookupColumn(NewTableColumn(name).ID()); !ok {
+			msgs = append(msgs, fmt.Sprintf("%s references column `%s`.`%s`, which does not exist", subject, refTable.Name(), name))
+			missing = true
+		}
+	}
+	if missing {
+		return msgs
+	}
+
+	if !hasIndexPrefix(refTable, refColumns) {
+		msgs = append(msgs, fmt.Sprintf("%s references columns (%s) of table `%s`, which are not the leading columns of an index", subject, strings.Join(refColumns, ", "), refTable.Name()))
+	}
+
+	for i, name := range columns {
+		col, ok := table.LookupColumn(NewTableColumn(name).ID())
+		if !ok {
+			continue
+		}
+		refCol, _ := refTable.LookupColumn(NewTableColumn(refColumns[i]).ID())
+		if reason := incompatibleColumns(col, refCol); reason != "" {
+			msgs = append(msgs, fmt.Sprintf("%s uses column `%s`, which is incompatible with `%s`.`%s`: %s", subject, name, refTable.Name(), refCol.Name(), reason))
+		}
+	}
+	return msgs
+}
+
+// validateSpatialIndex returns the messages describing the problems
+// of the spatial index idx of table
+func validateSpatialIndex(table Table, idx Index) []string {
+	subject := "spatial index"
+	if idx.HasName() {
+		subject += " `" + idx.Name() + "`"
+	}
+	subject += " on table `" + table.Name() + "`"
+
+	columns := indexColumnNames(idx)
+	if len(columns) != 1 {
+		return []string{fmt.Sprintf("%s has %d columns, but must have exactly 1", subject, len(columns))}
+	}
+
+	col, ok := table.LookupColumn(NewTableColumn(columns[0]).ID())
+	if !ok {
+		return []string{fmt.Sprintf("%s uses column `%s`, which does not exist", subject, columns[0])}
+	}
+	var msgs []string
+	if !isSpatialType(col.Type()) {
+		msgs = append(msgs, fmt.Sprintf("%s uses column `%s`, whose type %s is not a spatial type", subject, col.Name(), col.Type()))
+	}
+	if col.NullState() != NullStateNotNull {
+		msgs = append(msgs, fmt.Sprintf("%s uses column `%s`, which is not NOT NULL", subject, col.Name()))
+	}
+	return msgs
+}
+
+// hasIndexPrefix returns true if the columns are the leading columns
+// of an index of the table, other than a foreign key
+func hasIndexPrefix(table Table, columns []string) bool {
+	for idx := range table.Indexes() {
+		if idx.IsForeignKey() || idx.IsFullText() || idx.IsSpatial() {
+			continue
+		}
+		names := indexColumnNames(idx)
+		if len(names) < len(columns) {
+			continue
+		}
+		match := true
+		for i, name := range columns {
+			if !strings.EqualFold(names[i], name) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// incompatibleColumns returns why a foreign key column can not
+// reference the other column, or an empty string if it can. As MySQL
+// requires, the types must be the same, except that string types may
+// have different lengths, and integer and decimal types must have the
+// same sign and precision. String columns must have the same
+// character set and collation, when both declare them.
+func incompatibleColumns(col, ref TableColumn) string {
+	typ, refTyp := col.Type().SynonymType(), ref.Type().SynonymType()
+	if typ != refTyp && !(isCharType(typ) && isCharType(refTyp)) {
+		return fmt.Sprintf("types %s and %s differ", col.Type(), ref.Type())
+	}
+
+	switch typ {
+	case ColumnTypeTinyInt, ColumnTypeSmallInt, ColumnTypeMediumInt, ColumnTypeInt, ColumnTypeBigInt,
+		ColumnTypeDecimal, ColumnTypeFloat, ColumnTypeDouble:
+		if col.IsUnsigned() != ref.IsUnsigned() {
+			return "signs differ"
+		}
+	}
+	if typ == ColumnTypeDecimal && decimalPrecision(col) != decimalPrecision(ref) {
+		return "precisions differ"
+	}
+
+	if isCharType(typ) {
+		if col.HasCharacterSet() && ref.HasCharacterSet() && !strings.EqualFold(col.CharacterSet(), ref.CharacterSet()) {
+			return fmt.Sprintf("character sets %s and %s differ", col.CharacterSet(), ref.CharacterSet())
+		}
+		if col.HasCollation() && ref.HasCollation() && !strings.EqualFold(col.Collation(), ref.Collation()) {
+			return fmt.Sprintf("collations %s and %s differ", col.Collation(), ref.Collation())
+		}
+	}
+	return ""
+}
+
+func isCharType(typ ColumnType) bool {
+	return typ == ColumnTypeChar || typ == ColumnTypeVarChar
+}
+
+func isSpatialType(typ ColumnType) bool {
+	switch typ {
+	case ColumnTypeGeometry, ColumnTypePoint, ColumnTypeLineString,
+		ColumnTypePolygon, ColumnTypeMultiPoint, ColumnTypeMultiLineString,
+		ColumnTypeMultiPolygon, ColumnTypeGeometryCollection, ColumnTypeGeomCollection:
+		return true
+	}
+	return false
+}
+
+// decimalPrecision returns the precision and scale of a DECIMAL column,
+// using the defaults of MySQL if they are not declared
+func decimalPrecision(col TableColumn) string {
+	if !col.HasLength() {
+		return "10,0"
+	}
+	l := col.Length()
+	if !l.HasDecimal() {
+		return l.Length() + ",0"
+	}
+	return l.Length() + "," + l.Decimal()
+}
+
+func indexColumnNames(c ColumnContainer) []string {
+	var names []string
+	for col := range c.Columns() {
+		names = append(names, col.Name())
+	}
+	return names
+}
+
+func foreignKeyName(idx Index) string {
+	if idx.HasSymbol() {
+		return idx.Symbol()
+	}
+	if idx.HasName() {
+		return idx.Name()
+	}
+	return ""
+}
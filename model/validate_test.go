@@ -0,0 +1,126 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	const parents = `CREATE TABLE parent (
+  id INT UNSIGNED NOT NULL,
+  code VARCHAR(10) CHARACTER SET utf8mb4,
+  amount DECIMAL(10,2),
+  a INT, b INT,
+  PRIMARY KEY (id),
+  KEY (a, b)
+);
+`
+	specs := []struct {
+		Input  string
+		Errors []string
+	}{
+		{
+			Input: parents + `CREATE TABLE child (
+  id INT UNSIGNED NOT NULL,
+  parent_id INTEGER UNSIGNED,
+  code CHAR(20),
+  amount NUMERIC(10,2),
+  a INT, b INT,
+  CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES parent (id),
+  FOREIGN KEY (a, b) REFERENCES parent (a, b),
+  FOREIGN KEY (a) REFERENCES parent (a),
+  FOREIGN KEY (id) REFERENCES child (id),
+  PRIMARY KEY (id)
+);`,
+		},
+		{
+			Input: parents + `CREATE TABLE child (
+  parent_id INT UNSIGNED,
+  a INT,
+  CONSTRAINT fk_count FOREIGN KEY (parent_id) REFERENCES parent (id, a),
+  CONSTRAINT fk_table FOREIGN KEY (parent_id) REFERENCES missing (id),
+  CONSTRAINT fk_column FOREIGN KEY (parent_id, missing) REFERENCES parent (id, missing)
+);`,
+			Errors: []string{
+				"12:2: foreign key `fk_count` on table `child` has 1 columns, but references 2 columns",
+				"13:2: foreign key `fk_table` on table `child` references table `missing`, which does not exist",
+				"14:2: foreign key `fk_column` on table `child` uses column `missing`, which does not exist",
+				"14:2: foreign key `fk_column` on table `child` references column `parent`.`missing`, which does not exist",
+			},
+		},
+		{
+			Input: parents + `CREATE TABLE child (
+  parent_id INT,
+  code VARCHAR(10) CHARACTER SET latin1,
+  amount DECIMAL(12,2),
+  b INT,
+  CONSTRAINT fk_sign FOREIGN KEY (parent_id) REFERENCES parent (id),
+  CONSTRAINT fk_charset FOREIGN KEY (code) REFERENCES parent (code),
+  CONSTRAINT fk_precision FOREIGN KEY (amount) REFERENCES parent (amount),
+  CONSTRAINT fk_index FOREIGN KEY (b) REFERENCES parent (b),
+  CONSTRAINT fk_type FOREIGN KEY (code) REFERENCES parent (id)
+);`,
+			Errors: []string{
+				"14:2: foreign key `fk_sign` on table `child` uses column `parent_id`, which is incompatible with `parent`.`id`: signs differ",
+				"15:2: foreign key `fk_charset` on table `child` references columns (code) of table `parent`, which are not the leading columns of an index",
+				"15:2: foreign key `fk_charset` on table `child` uses column `code`, which is incompatible with `parent`.`code`: character sets latin1 and utf8mb4 differ",
+				"16:2: foreign key `fk_precision` on table `child` references columns (amount) of table `parent`, which are not the leading columns of an index",
+				"16:2: foreign key `fk_precision` on table `child` uses column `amount`, which is incompatible with `parent`.`amount`: precisions differ",
+				"17:2: foreign key `fk_index` on table `child` references columns (b) of table `parent`, which are not the leading columns of an index",
+				"18:2: foreign key `fk_type` on table `child` uses column `code`, which is incompatible with `parent`.`id`: types VARCHAR and INT differ",
+			},
+		},
+		{
+			Input: `CREATE TABLE child (
+  id INT NOT NULL,
+  g GEOMETRY NOT NULL,
+  p POINT,
+  a INT NOT NULL,
+  SPATIAL INDEX sp_g (g),
+  SPATIAL KEY sp_p (p),
+  SPATIAL INDEX sp_a (a),
+  SPATIAL INDEX sp_missing (missing),
+  SPATIAL INDEX sp_two (g, p)
+);`,
+			Errors: []string{
+				"7:2: spatial index `sp_p` on table `child` uses column `p`, which is not NOT NULL",
+				"8:2: spatial index `sp_a` on table `child` uses column `a`, whose type INT is not a spatial type",
+				"9:2: spatial index `sp_missing` on table `child` uses column `missing`, which does not exist",
+				"10:2: spatial index `sp_two` on table `child` has 2 columns, but must have exactly 1",
+			},
+		},
+	}
+
+	for _, spec := range specs {
+		stmts, err := schemalex.New().ParseString(spec.Input)
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+
+		err = stmts.Validate()
+		if spec.Errors == nil {
+			if !assert.NoError(t, err, "validate should succeed") {
+				return
+			}
+			continue
+		}
+
+		errs, ok := err.(model.ValidationErrors)
+		if !assert.True(t, ok, "validate should return model.ValidationErrors") {
+			return
+		}
+		var msgs []string
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		if !assert.Equal(t, spec.Errors, msgs, "errors should match") {
+			return
+		}
+		if !assert.Equal(t, "child", errs[0].Table, "table should be set") {
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package model
+
+// NewView creates a new view with the given name, defined by the given
+// SELECT statement
+func NewView(name, definition string) View {
+	return &view{
+		name:       name,
+		definition: definition,
+	}
+}
+
+func (v *view) isView() bool {
+	return true
+}
+
+func (v *view) ID() string {
+	return "view#" + v.name
+}
+
+func (v *view) Name() string {
+	return v.name
+}
+
+func (v *view) Columns() []string {
+	return v.columns
+}
+
+func (v *view) SetColumns(columns []string) View {
+	v.columns = columns
+	return v
+}
+
+func (v *view) Algorithm() string {
+	return v.algorithm
+}
+
+func (v *view) SetAlgorithm(s string) View {
+	v.algorithm = s
+	return v
+}
+
+func (v *view) Definer() string {
+	return v.definer
+}
+
+func (v *view) SetDefiner(s string) View {
+	v.definer = s
+	return v
+}
+
+func (v *view) SQLSecurity() string {
+	return v.security
+}
+
+func (v *view) SetSQLSecurity(s string) View {
+	v.security = s
+	return v
+}
+
+func (v *view) Definition() string {
+	return v.definition
+}
+
+func (v *view) SetDefinition(s string) View {
+	v.definition = s
+	return v
+}
+
+func (v *view) CheckOption() string {
+	return v.checkOption
+}
+
+func (v *view) SetCheckOption(s string) View {
+	v.checkOption = s
+	return v
+}
+
+func (v *view) Position() Position {
+	return v.pos
+}
+
+func (v *view) SetPosition(pos Position) View {
+	v.pos = pos
+	return v
+}
+
+// NewDropView creates a new DROP VIEW statement for the given view
+func NewDropView(name string) DropView {
+	return &dropView{
+		name: name,
+	}
+}
+
+func (d *dropView) isDropView() bool {
+	return true
+}
+
+func (d *dropView) ID() string {
+	return "dropview#" + d.name
+}
+
+func (d *dropView) Name() string {
+	return d.name
+}
+
+func (d *dropView) IsIfExists() bool {
+	return d.ifexists
+}
+
+func (d *dropView) SetIfExists(v bool) DropView {
+	d.ifexists = v
+	return d
+}
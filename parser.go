@@ -1,14 +1,26 @@
 package schemalex
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/eihigh/schemalex/internal/errors"
 	"github.com/eihigh/schemalex/model"
 )
 
+// The column options may be given in any order, as MySQL accepts
+// them, except for the size or the values of the type, which must
+// directly follow the type. e.g. these are allowed
+// * INT(11) COMMENT 'foo' NOT NULL PRIMARY KEY AUTO_INCREMENT
+// * INT(11) ZEROFILL UNSIGNED DEFAULT 1 NOT NULL
+// But these need to be errors
+// * INT NOT NULL (11)
+// * INT DEFAULT 1 DEFAULT 2
 const (
 	coloptSize = 1 << iota
 	coloptDecimalSize
@@ -20,21 +32,14 @@ const (
 	coloptCollate
 	coloptEnumValues
 	coloptSetValues
-
-	// Everything else, meaning after this position, you can put anything
-	// you want. e.g. these are allowed
-	// * INT(11) COMMENT 'foo' NOT NULL PRIMARY KEY AUTO_INCREMENT
-	// * INT(11) AUTO_INCREMENT NOT NULL DEFAULT 1
-	// But this needs to be an error
-	// * COMMENT 'foo' INT(11) NOT NULL
-
-	coloptEverythingElse
-
-	coloptNull          = coloptEverythingElse
-	coloptDefault       = coloptEverythingElse
-	coloptAutoIncrement = coloptEverythingElse
-	coloptKey           = coloptEverythingElse
-	coloptComment       = coloptEverythingElse
+	coloptNull
+	coloptDefault
+	coloptOnUpdate
+	coloptAutoIncrement
+	coloptUnique
+	coloptKey
+	coloptComment
+	coloptSRID
 )
 
 const (
@@ -47,32 +52,124 @@ const (
 	coloptFlagBinary          = coloptSize
 	coloptFlagEnum            = coloptEnumValues
 	coloptFlagSet             = coloptSetValues
+	coloptFlagSpatial         = coloptSRID
 )
 
-// Parser is responsible to parse a set of SQL statements
-type Parser struct{}
+// Parser is responsible to parse a set of SQL statements.
+//
+// A Parser is not modified once it is created, and keeps no state from
+// one parse to the next, so a single Parser may be shared by goroutines
+// and used to parse any number of schemas concurrently, such as by the
+// handlers of a service. The warning handler given to NewParser may be
+// called from all of these goroutines.
+type Parser struct {
+	dialect  Dialect
+	version  MySQLVersion
+	tolerant bool
+	warn     func(Warning)
+	dupErrs  bool
+	sqlMode  string
+	upstream bool
+	options  []Option
+	// legacyTimestamps is true if the TIMESTAMP columns follow the
+	// rules of explicit_defaults_for_timestamp=OFF
+	legacyTimestamps bool
+}
 
 // New creates a new Parser
 func New() *Parser {
 	return &Parser{}
 }
 
+// NewParser creates a new Parser with options. WithMySQLVersion may be
+// specified to reject syntax that the target server does not support.
+func NewParser(options ...Option) *Parser {
+	p := Parser{options: options}
+	var ansiQuotes bool
+	var explicitDefaults *bool
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyDialect:
+			p.dialect = o.Value().(Dialect)
+		case optkeyMySQLVersion:
+			p.version = o.Value().(MySQLVersion)
+		case optkeyErrorTolerance:
+			p.tolerant = o.Value().(bool)
+		case optkeyWarningHandler:
+			p.warn = o.Value().(func(Warning))
+		case optkeyDuplicateErrors:
+			p.dupErrs = o.Value().(bool)
+		case optkeySQLMode:
+			p.sqlMode = o.Value().(string)
+		case optkeyUpstreamCompat:
+			p.upstream = o.Value().(bool)
+		case optkeyANSIQuotes:
+			ansiQuotes = o.Value().(bool)
+		case optkeyExplicitDefaults:
+			b := o.Value().(bool)
+			explicitDefaults = &b
+		}
+	}
+	p.legacyTimestamps = legacyTimestamps(p.dialect, p.version, explicitDefaults)
+	if ansiQuotes && !hasANSIQuotes(p.sqlMode) {
+		if p.sqlMode == "" {
+			p.sqlMode = "ANSI_QUOTES"
+		} else {
+			p.sqlMode += ",ANSI_QUOTES"
+		}
+	}
+	return &p
+}
+
 type parseCtx struct {
 	context.Context
+	dialect    Dialect
+	version    MySQLVersion
+	warn       func(Warning)
+	session    *session
+	defs       *definitions
+	dupErrs    bool
 	input      []byte
-	lexsrc     chan *Token
+	markers    []fileMarker
+	lexer      *lexer
 	peekCount  int
 	peekTokens [3]*Token
+	lex        lexer
+	// optional lists the optional tokens that the parser looked for in
+	// place of the token at optionalPos, which are expected along with
+	// the others if the token is not valid there
+	optional    []TokenType
+	optionalPos int
 }
 
-func newParseCtx(ctx context.Context) *parseCtx {
-	return &parseCtx{
-		Context:   ctx,
-		peekCount: -1,
-	}
+// parseCtxPool holds the contexts of the parses that succeeded, so
+// that their lexers and chunks of tokens are reused
+var parseCtxPool = sync.Pool{
+	New: func() interface{} {
+		return &parseCtx{}
+	},
 }
 
-var eofToken = Token{Type: EOF}
+// newParseCtx returns a context to parse src from the offset pos, which
+// is the beginning of the given line of the input
+func newParseCtx(ctx context.Context, src []byte, pos, line int) *parseCtx {
+	pctx := parseCtxPool.Get().(*parseCtx)
+	pctx.Context = ctx
+	pctx.peekCount = -1
+	pctx.lex.reset(src, pos, line)
+	pctx.lexer = &pctx.lex
+	return pctx
+}
+
+// release returns the context to the pool. Parse errors refer to the
+// tokens of the context, so it must only be released once parsing
+// succeeded.
+func (pctx *parseCtx) release() {
+	chunk := pctx.lex.chunk
+	*pctx = parseCtx{}
+	pctx.lex.chunk = chunk
+	parseCtxPool.Put(pctx)
+}
 
 // peek the next token. this operation fills the peekTokens
 // buffer. `next()` is a combination of peek+advance.
@@ -81,16 +178,8 @@ var eofToken = Token{Type: EOF}
 // if you do that, you're f*cked.
 func (pctx *parseCtx) peek() *Token {
 	if pctx.peekCount < 0 {
-		select {
-		case <-pctx.Context.Done():
-			return &eofToken
-		case t, ok := <-pctx.lexsrc:
-			if !ok {
-				return &eofToken
-			}
-			pctx.peekCount++
-			pctx.peekTokens[pctx.peekCount] = t
-		}
+		pctx.peekCount++
+		pctx.peekTokens[pctx.peekCount] = pctx.lexer.nextToken()
 	}
 	return pctx.peekTokens[pctx.peekCount]
 }
@@ -113,20 +202,55 @@ func (pctx *parseCtx) next() *Token {
 	return t
 }
 
+// expectOptional records that the optional tokens of the given types
+// were looked for in place of t, which was not one of them
+func (pctx *parseCtx) expectOptional(t *Token, types ...TokenType) {
+	if pctx.optionalPos != t.Pos {
+		pctx.optional = pctx.optional[:0]
+		pctx.optionalPos = t.Pos
+	}
+	for _, typ := range types {
+		var found bool
+		for _, v := range pctx.optional {
+			if v == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pctx.optional = append(pctx.optional, typ)
+		}
+	}
+}
+
 // ParseFile parses a file containing SQL statements and creates
 // a mode.Stmts structure.
 // See Parse for details.
 func (p *Parser) ParseFile(fn string) (model.Stmts, error) {
+	return p.ParseFileContext(context.Background(), fn)
+}
+
+// ParseFileContext is like ParseFile, but stops parsing once the
+// context is canceled
+func (p *Parser) ParseFileContext(ctx context.Context, fn string) (model.Stmts, error) {
 	src, err := ioutil.ReadFile(fn)
 	if err != nil {
 		return nil, errors.Wrapf(err, `failed to open file %s`, fn)
 	}
 
-	stmts, err := p.Parse(src)
+	stmts, err := p.ParseContext(ctx, src)
 	if err != nil {
-		if pe, ok := err.(*parseError); ok {
+		if pe, ok := err.(*parseError); ok && pe.file == "" {
 			pe.file = fn
 		}
+		if errs, ok := err.(ParseErrors); ok {
+			for _, pe := range errs {
+				if pe, ok := pe.(*parseError); ok && pe.file == "" {
+					pe.file = fn
+				}
+			}
+			return stmts, errs
+		}
 		return nil, err
 	}
 	return stmts, nil
@@ -136,25 +260,129 @@ func (p *Parser) ParseFile(fn string) (model.Stmts, error) {
 // a mode.Stmts structure.
 // See Parse for details.
 func (p *Parser) ParseString(src string) (model.Stmts, error) {
-	return p.Parse([]byte(src))
+	return p.ParseContext(context.Background(), []byte(src))
+}
+
+// ParseStringContext is like ParseString, but stops parsing once the
+// context is canceled
+func (p *Parser) ParseStringContext(ctx context.Context, src string) (model.Stmts, error) {
+	return p.ParseContext(ctx, []byte(src))
 }
 
 // Parse parses the given set of SQL statements and creates a
 // model.Stmts structure.
 // If it encounters errors while parsing, the returned error will be a
 // ParseError type.
+// Files written on Windows parse the same as on other platforms: a
+// leading byte order mark is skipped, CRLF line endings within quoted
+// strings read as LF, and the white spaces of Unicode, such as
+// non-breaking spaces, separate tokens as spaces do.
 func (p *Parser) Parse(src []byte) (model.Stmts, error) {
-	cctx, cancel := context.WithCancel(context.TODO())
-	defer cancel()
+	return p.ParseContext(context.Background(), src)
+}
 
-	ctx := newParseCtx(cctx)
-	ctx.input = src
-	ctx.lexsrc = lex(cctx, src)
+// ParseContext is like Parse, but stops parsing once the context is
+// canceled, in which case the error of the context is returned. The
+// context is checked before each statement.
+func (p *Parser) ParseContext(ctx context.Context, src []byte) (model.Stmts, error) {
+	src = bytes.TrimPrefix(src, byteOrderMark)
+	if !isBuiltinDialect(p.dialect) {
+		fn, ok := lookupDialect(p.dialect)
+		if !ok {
+			return nil, errors.Errorf(`unknown dialect %s`, p.dialect)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return fn(p.options...).Parse(src)
+	}
 
 	var stmts model.Stmts
+	err := p.parse(ctx, src, 1, findFileMarkers(src), newSession(p.sqlMode), func(stmt model.Stmt) error {
+		stmts = stmts.Apply(stmt)
+		return nil
+	})
+	if err != nil {
+		if errs, ok := err.(ParseErrors); ok {
+			return stmts, errs
+		}
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// parse parses the statements in src, which starts at the given line
+// of the input, and calls fn for each of them. The state left by the
+// previous parts of the input, such as the definitions used to find
+// duplicates, is given by sess.
+func (p *Parser) parse(cctx context.Context, src []byte, line int, markers []fileMarker, sess *session, fn func(model.Stmt) error) error {
+	return p.parseFrom(cctx, src, 0, line, markers, sess, fn, nil)
+}
+
+// parseFrom is like parse, but starts at the offset pos of src, which
+// is the beginning of the given line. If boundary is not nil, it is
+// called with the offset and the line of each line from which the rest
+// of src can be parsed on its own, before the statements that follow
+// are parsed, and parsing stops if it returns false.
+func (p *Parser) parseFrom(cctx context.Context, src []byte, pos, line int, markers []fileMarker, sess *session, fn func(model.Stmt) error, boundary func(pos, line int) bool) (err error) {
+	if p.dialect == DialectTiDB {
+		src = unwrapTiDBComments(src)
+	}
+
+	ctx := newParseCtx(cctx, src, pos, line)
+	defer func() {
+		if err == nil {
+			ctx.release()
+		}
+	}()
+	ctx.dialect = p.dialect
+	ctx.version = p.version
+	ctx.warn = p.warn
+	ctx.session = sess
+	ctx.defs = sess.defs
+	ctx.dupErrs = p.dupErrs
+	ctx.input = src
+	ctx.markers = markers
+	ctx.lexer.ansiQuotes = hasANSIQuotes(sess.sqlMode)
+	ctx.lexer.delimiter = sess.delimiter
+
+	// with WithErrorTolerance, the errors are collected, and the rest
+	// of the statement is skipped
+	var errs ParseErrors
+	tolerate := func(err error) error {
+		if !p.tolerant {
+			return err
+		}
+		pe, ok := err.(*parseError)
+		if !ok {
+			pe = newParseError(ctx, ctx.peek(), "%s", err).(*parseError)
+		}
+		errs = append(errs, pe)
+		if ctx.peek() == pe.token || pe.token.Type != SEMICOLON {
+			p.skipStatement(ctx)
+		}
+		return nil
+	}
+
+	last := pos
 LOOP:
 	for {
-		ctx.skipWhiteSpaces()
+		if err := cctx.Err(); err != nil {
+			return err
+		}
+
+		// the statements that follow a DELIMITER command can not be
+		// parsed on their own
+		if boundary != nil && ctx.lex.delimiter == "" {
+			if next, nextLine, ok := ctx.nextLine(); ok && next > last {
+				last = next
+				if !boundary(next, nextLine) {
+					break LOOP
+				}
+			}
+		}
+
+		ctx.skipToStatement()
 		switch t := ctx.peek(); t.Type {
 		case CREATE:
 			stmt, err := p.parseCreate(ctx)
@@ -163,16 +391,81 @@ LOOP:
 					// this is ignorable.
 					continue
 				}
-				if pe, ok := err.(ParseError); ok {
-					return nil, pe
+				if _, ok := err.(ParseError); !ok {
+					err = errors.Wrap(err, `failed to parse create`)
+				}
+				if err := tolerate(err); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := fn(stmt); err != nil {
+				return err
+			}
+		case ANALYZE:
+			list, err := p.parseAnalyze(ctx)
+			if err != nil {
+				if errors.IsIgnorable(err) {
+					continue
+				}
+				if _, ok := err.(ParseError); !ok {
+					err = errors.Wrap(err, `failed to parse analyze`)
+				}
+				if err := tolerate(err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, stmt := range list {
+				if err := fn(stmt); err != nil {
+					return err
 				}
-				return nil, errors.Wrap(err, `failed to parse create`)
 			}
-			stmts = append(stmts, stmt)
 		case COMMENT_IDENT:
 			ctx.advance()
-		case DROP, SET, USE:
+		case IDENT:
+			if isWord(ctx, t, "DELIMITER") {
+				if err := p.parseDelimiter(ctx); err != nil {
+					if err := tolerate(err); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			// the data and locks in dumps made by mysqldump
+			if !isDataStatement(t.value(ctx.input)) {
+				if err := tolerate(newExpectedError(ctx, t, statementStart...)); err != nil {
+					return err
+				}
+				continue
+			}
+			p.skipStatement(ctx)
+		case DROP:
+			list, err := p.parseDrop(ctx)
+			if err != nil {
+				if errors.IsIgnorable(err) {
+					continue
+				}
+				if _, ok := err.(ParseError); !ok {
+					err = errors.Wrap(err, `failed to parse drop`)
+				}
+				if err := tolerate(err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, stmt := range list {
+				if err := fn(stmt); err != nil {
+					return err
+				}
+			}
+		case SET:
+			if !p.parseSet(ctx) {
+				ctx.warnf(t, "%s statement is ignored", t.Type)
+			}
+		case USE:
 			// We don't do anything about these
+			ctx.warnf(t, "%s statement is ignored", t.Type)
 		S1:
 			for {
 				switch t := ctx.peek(); t.Type {
@@ -195,28 +488,730 @@ LOOP:
 			ctx.advance()
 			break LOOP
 		default:
-			return nil, newParseError(ctx, t, "expected CREATE, COMMENT_IDENT, SEMICOLON or EOF")
+			if err := tolerate(newExpectedError(ctx, t, statementStart...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// statementStart lists the tokens that may start a statement
+var statementStart = []TokenType{CREATE, ANALYZE, DROP, SET, USE, COMMENT_IDENT, SEMICOLON, EOF}
+
+// isDataStatement returns true for the first word of the statements
+// that do not change the schema, such as INSERT, which are skipped
+func isDataStatement(s string) bool {
+	switch strings.ToUpper(s) {
+	case "INSERT", "REPLACE", "LOCK", "UNLOCK":
+		return true
+	}
+	return false
+}
+
+// parseDelimiter reads the DELIMITER command of the mysql client,
+// which is not an SQL statement: the delimiter is the first word of the
+// rest of the line, and the statements that follow end with it instead
+// of a semicolon, so that the bodies of triggers and stored routines
+// may contain semicolons. A semicolon restores the default.
+// https://dev.mysql.com/doc/refman/8.0/en/stored-programs-defining.html
+func (p *Parser) parseDelimiter(ctx *parseCtx) error {
+	t := ctx.next()
+	begin := t.Pos + len(t.value(ctx.input))
+	rest := ctx.input[begin:]
+	next, line := len(ctx.input), t.Line
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+		next, line = begin+i+1, t.Line+1
+	}
+
+	words := strings.Fields(string(rest))
+	if len(words) == 0 || len(rest) == len(bytes.TrimLeft(rest, " \t")) {
+		return newParseError(ctx, t, "expected delimiter after DELIMITER")
+	}
+	delimiter := words[0]
+	if delimiter == ";" {
+		delimiter = ""
+	}
+
+	ctx.session.delimiter = delimiter
+	ctx.lex.delimiter = delimiter
+	ctx.lex.skipTo(next, line)
+	ctx.peekCount = -1
+	return nil
+}
+
+func (p *Parser) parseCreate(ctx *parseCtx) (model.Stmt, error) {
+	start := ctx.next()
+	if start.Type != CREATE {
+		return nil, errors.New(`expected CREATE`)
+	}
+	ctx.skipWhiteSpaces()
+	switch t := ctx.peek(); t.Type {
+	case DATABASE:
+		if _, err := p.parseCreateDatabase(ctx); err != nil {
+			return nil, err
+		}
+		ctx.warnf(start, "CREATE DATABASE statement is ignored")
+		return nil, errors.Ignorable(nil)
+	case TABLE, TEMPORARY:
+		table, err := p.parseCreateTable(ctx)
+		if err != nil {
+			return nil, err
+		}
+		table.SetPosition(ctx.position(start))
+		return table, nil
+	default:
+		// the DEFINER is common to views, triggers and stored routines
+		var definer string
+		if isWord(ctx, t, "DEFINER") {
+			v, err := p.parseDefiner(ctx)
+			if err != nil {
+				return nil, err
+			}
+			definer = v
+			t = ctx.peek()
+		}
+		if kind := routineKind(ctx, t); kind != model.RoutineKindInvalid {
+			routine, err := p.parseCreateRoutine(ctx, kind)
+			if err != nil {
+				return nil, err
+			}
+			routine.SetDefiner(definer)
+			routine.SetPosition(ctx.position(start))
+			return routine, nil
+		}
+		if !isViewStart(ctx, t) {
+			return nil, newExpectedError(ctx, t, DATABASE, TABLE)
+		}
+		view, err := p.parseCreateView(ctx, definer)
+		if err != nil {
+			return nil, err
+		}
+		view.SetPosition(ctx.position(start))
+		return view, nil
+	}
+}
+
+// isWord returns true if t is the identifier word, which is not a
+// keyword of the lexer, regardless of case
+func isWord(ctx *parseCtx, t *Token, word string) bool {
+	return t.Type == IDENT && strings.EqualFold(t.value(ctx.input), word)
+}
+
+// isViewStart returns true if t starts the rest of a CREATE VIEW
+// statement, after CREATE
+func isViewStart(ctx *parseCtx, t *Token) bool {
+	for _, word := range []string{"OR", "ALGORITHM", "SQL", "VIEW"} {
+		if isWord(ctx, t, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// routineKind returns the kind of routine that t starts the rest of
+// a CREATE or DROP statement of, or RoutineKindInvalid if it does not
+func routineKind(ctx *parseCtx, t *Token) model.RoutineKind {
+	switch {
+	case isWord(ctx, t, "TRIGGER"):
+		return model.RoutineKindTrigger
+	case isWord(ctx, t, "PROCEDURE"):
+		return model.RoutineKindProcedure
+	case isWord(ctx, t, "FUNCTION"):
+		return model.RoutineKindFunction
+	}
+	return model.RoutineKindInvalid
+}
+
+// parseDefiner parses the DEFINER of a view, a trigger or a stored
+// routine, and returns the user as written, such as `root`@`localhost`
+// or CURRENT_USER(), up to the word that follows it
+func (p *Parser) parseDefiner(ctx *parseCtx) (string, error) {
+	ctx.advance()
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type == EQUAL {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+	}
+	begin := ctx.peek()
+	for {
+		t := ctx.peek()
+		if t.Type == SEMICOLON || t.Type == EOF || isWord(ctx, t, "SQL") || isWord(ctx, t, "VIEW") || routineKind(ctx, t) != model.RoutineKindInvalid {
+			if definer := strings.TrimSpace(string(ctx.input[begin.Pos:t.Pos])); definer != "" {
+				return definer, nil
+			}
+			return "", newParseError(ctx, begin, "expected user after DEFINER")
+		}
+		ctx.advance()
+	}
+}
+
+// parseCreateRoutine parses the rest of a `CREATE TRIGGER`, `CREATE
+// PROCEDURE` or `CREATE FUNCTION` statement, after the DEFINER. Only
+// the name, and the table of a trigger, are read: what follows the name
+// is kept as written, up to the end of the statement. The body of a
+// compound statement contains semicolons, so that the statement must
+// end with the delimiter set by a DELIMITER command.
+// https://dev.mysql.com/doc/refman/8.0/en/create-trigger.html
+// https://dev.mysql.com/doc/refman/8.0/en/create-procedure.html
+func (p *Parser) parseCreateRoutine(ctx *parseCtx, kind model.RoutineKind) (model.Routine, error) {
+	ctx.advance()
+	ctx.skipWhiteSpaces()
+	// IF NOT EXISTS only matters to the server
+	if ctx.peek().Type == IF {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, NOT, EXISTS); err != nil {
+			return nil, err
+		}
+		ctx.skipWhiteSpaces()
+	}
+
+	name, err := p.parseQualifiedName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	routine := model.NewRoutine(kind, name, "")
+
+	ctx.skipWhiteSpaces()
+	begin := ctx.peek()
+	if kind == model.RoutineKindTrigger {
+		// { BEFORE | AFTER } { INSERT | UPDATE | DELETE } ON table
+		for _, words := range [][]string{{"BEFORE", "AFTER"}, {"INSERT", "UPDATE", "DELETE"}, {"ON"}} {
+			ctx.skipWhiteSpaces()
+			t := ctx.next()
+			if !isOneOf(ctx, t, words...) {
+				return nil, newParseError(ctx, t, "expected %s", strings.Join(words, ", "))
+			}
+		}
+		ctx.skipWhiteSpaces()
+		table, err := p.parseQualifiedName(ctx)
+		if err != nil {
+			return nil, err
+		}
+		routine.SetTable(table)
+	}
+
+	// the spaces and comments that follow the last word are left out
+	end := begin.Pos
+	var pending bool
+	var begins, ends int
+	var prev *Token
+	for {
+		t := ctx.peek()
+		if t.Type == EOF || ctx.isDelimiter(t) {
+			if pending {
+				end = t.Pos
+			}
+			break
+		}
+		ctx.advance()
+		switch t.Type {
+		case SPACE, COMMENT_IDENT:
+			if pending {
+				end = t.Pos
+				pending = false
+			}
+			continue
+		case IDENT:
+			// the words of a column such as NEW.begin are not counted
+			if prev == nil || prev.Type != DOT {
+				if isWord(ctx, t, "BEGIN") {
+					begins++
+				} else if isWord(ctx, t, "END") {
+					ends++
+				}
+			}
+		}
+		prev = t
+		pending = true
+	}
+
+	definition := strings.TrimSpace(string(ctx.input[begin.Pos:end]))
+	if definition == "" {
+		return nil, newParseError(ctx, ctx.peek(), "expected body of %s", kind)
+	}
+	if begins > ends {
+		return nil, newParseError(ctx, ctx.peek(), "expected END of compound statement: a DELIMITER command must precede a %s whose body contains semicolons", kind)
+	}
+	routine.SetDefinition(definition)
+
+	p.eol(ctx)
+	return routine, nil
+}
+
+// isOneOf returns true if the source text of t is one of the words,
+// regardless of case, whether the words are keywords of the lexer or
+// not
+func isOneOf(ctx *parseCtx, t *Token, words ...string) bool {
+	for _, word := range words {
+		if strings.EqualFold(t.value(ctx.input), word) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQualifiedName parses the name of a trigger, a stored routine or
+// a table, which may be qualified by the name of its database, and
+// returns it without the database
+func (p *Parser) parseQualifiedName(ctx *parseCtx) (string, error) {
+	var name string
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		name = t.value(ctx.input)
+	default:
+		return "", newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+	}
+	if ctx.peek().Type == DOT {
+		ctx.advance()
+		switch t := ctx.next(); t.Type {
+		case IDENT, BACKTICK_IDENT:
+			name = t.value(ctx.input)
+		default:
+			return "", newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+		}
+	}
+	return name, nil
+}
+
+// isDelimiter returns true if t ends a statement whose body may contain
+// semicolons, which is the delimiter set by a DELIMITER command, or a
+// semicolon if there is none
+func (pctx *parseCtx) isDelimiter(t *Token) bool {
+	return t.Type == SEMICOLON && (pctx.lex.delimiter == "" || t.value(pctx.input) != ";")
+}
+
+// parseCreateView parses the rest of a `CREATE VIEW` statement, after
+// CREATE and the DEFINER, if it is the first option. The SELECT
+// statement is not read: it is kept as written, up to the end of the
+// statement or to WITH CHECK OPTION.
+// https://dev.mysql.com/doc/refman/8.0/en/create-view.html
+func (p *Parser) parseCreateView(ctx *parseCtx, definer string) (model.View, error) {
+	// OR REPLACE only matters to the server
+	if t := ctx.peek(); isWord(ctx, t, "OR") {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		if t := ctx.next(); !isWord(ctx, t, "REPLACE") {
+			return nil, newParseError(ctx, t, "expected REPLACE")
+		}
+		ctx.skipWhiteSpaces()
+	}
+
+	var algorithm, security string
+	if t := ctx.peek(); definer == "" && isWord(ctx, t, "ALGORITHM") {
+		ctx.advance()
+		v, err := p.parseViewOptionValue(ctx, "UNDEFINED", "MERGE", "TEMPTABLE")
+		if err != nil {
+			return nil, err
+		}
+		algorithm = v
+	}
+	if t := ctx.peek(); definer == "" && isWord(ctx, t, "DEFINER") {
+		v, err := p.parseDefiner(ctx)
+		if err != nil {
+			return nil, err
+		}
+		definer = v
+	}
+	if t := ctx.peek(); isWord(ctx, t, "SQL") {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		if t := ctx.next(); !isWord(ctx, t, "SECURITY") {
+			return nil, newParseError(ctx, t, "expected SECURITY")
+		}
+		v, err := p.parseViewOptionValue(ctx, "DEFINER", "INVOKER")
+		if err != nil {
+			return nil, err
+		}
+		security = v
+	}
+
+	if t := ctx.next(); !isWord(ctx, t, "VIEW") {
+		return nil, newParseError(ctx, t, "expected VIEW")
+	}
+
+	ctx.skipWhiteSpaces()
+	var view model.View
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		view = model.NewView(t.value(ctx.input), "")
+	default:
+		return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+	}
+	view.SetAlgorithm(algorithm)
+	view.SetDefiner(definer)
+	view.SetSQLSecurity(security)
+
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type == LPAREN {
+		ctx.advance()
+		var columns []string
+		for {
+			ctx.skipWhiteSpaces()
+			switch t := ctx.next(); t.Type {
+			case IDENT, BACKTICK_IDENT:
+				columns = append(columns, t.value(ctx.input))
+			default:
+				return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+			}
+			ctx.skipWhiteSpaces()
+			t := ctx.next()
+			if t.Type == RPAREN {
+				break
+			}
+			if t.Type != COMMA {
+				return nil, newExpectedError(ctx, t, COMMA, RPAREN)
+			}
+		}
+		view.SetColumns(columns)
+		ctx.skipWhiteSpaces()
+	}
+
+	if t := ctx.next(); !isWord(ctx, t, "AS") {
+		return nil, newParseError(ctx, t, "expected AS")
+	}
+
+	// the words of the SELECT statement, to find WITH CHECK OPTION at
+	// its end. The spaces and comments that follow the last word are
+	// left out.
+	ctx.skipWhiteSpaces()
+	begin := ctx.peek()
+	var words []*Token
+	end := begin.Pos
+	var pending bool
+	for {
+		t := ctx.peek()
+		if t.Type == SEMICOLON || t.Type == EOF {
+			if pending {
+				end = t.Pos
+			}
+			break
+		}
+		ctx.advance()
+		switch t.Type {
+		case SPACE, COMMENT_IDENT:
+			if pending {
+				end = t.Pos
+				pending = false
+			}
+		default:
+			words = append(words, t)
+			pending = true
+		}
+	}
+	if n := len(words); n >= 3 && isWord(ctx, words[n-1], "OPTION") && words[n-2].Type == CHECK {
+		switch {
+		case words[n-3].Type == WITH:
+			view.SetCheckOption("CASCADED")
+			end = words[n-3].Pos
+		case n >= 4 && words[n-4].Type == WITH && (isWord(ctx, words[n-3], "CASCADED") || isWord(ctx, words[n-3], "LOCAL")):
+			view.SetCheckOption(strings.ToUpper(words[n-3].value(ctx.input)))
+			end = words[n-4].Pos
+		}
+	}
+
+	definition := strings.TrimSpace(string(ctx.input[begin.Pos:end]))
+	if definition == "" {
+		return nil, newParseError(ctx, ctx.peek(), "expected SELECT statement")
+	}
+	view.SetDefinition(definition)
+
+	p.eol(ctx)
+	return view, nil
+}
+
+// parseViewOptionValue parses the value of the ALGORITHM or SQL
+// SECURITY of a view, which is one of the given words, and returns it
+// in upper case
+func (p *Parser) parseViewOptionValue(ctx *parseCtx, words ...string) (string, error) {
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type == EQUAL {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+	}
+	t := ctx.next()
+	for _, word := range words {
+		if isWord(ctx, t, word) {
+			ctx.skipWhiteSpaces()
+			return word, nil
+		}
+	}
+	return "", newParseError(ctx, t, "expected %s", strings.Join(words, ", "))
+}
+
+// parseAnalyze parses `ANALYZE TABLE ... UPDATE HISTOGRAM` statements.
+// Other forms of ANALYZE TABLE only have an effect on the data, so
+// they are skipped.
+// https://dev.mysql.com/doc/refman/8.0/en/analyze-table.html
+func (p *Parser) parseAnalyze(ctx *parseCtx) (model.Stmts, error) {
+	start := ctx.next()
+	if start.Type != ANALYZE {
+		return nil, errors.New(`expected ANALYZE`)
+	}
+
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type != TABLE {
+		ctx.warnf(start, "ANALYZE statement is ignored")
+		return nil, p.skipStatement(ctx)
+	}
+	ctx.advance()
+
+	ctx.skipWhiteSpaces()
+	var table string
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		table = t.value(ctx.input)
+	default:
+		return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+	}
+
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type != UPDATE {
+		// ANALYZE TABLE a, b, ... or ANALYZE TABLE a DROP HISTOGRAM ON ...
+		ctx.warnf(start, "ANALYZE TABLE statement without UPDATE HISTOGRAM is ignored")
+		return nil, p.skipStatement(ctx)
+	}
+	ctx.advance()
+
+	ctx.skipWhiteSpaces()
+	if err := ctx.requireVersion(ctx.peek(), "histograms", 8, 0, 0); err != nil {
+		return nil, err
+	}
+	if _, err := p.parseIdents(ctx, HISTOGRAM, ON); err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for {
+		ctx.skipWhiteSpaces()
+		switch t := ctx.next(); t.Type {
+		case IDENT, BACKTICK_IDENT:
+			columns = append(columns, t.value(ctx.input))
+		default:
+			return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+		}
+
+		ctx.skipWhiteSpaces()
+		if ctx.peek().Type != COMMA {
+			break
+		}
+		ctx.advance()
+	}
+
+	var buckets int
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type == WITH {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		t := ctx.next()
+		if t.Type != NUMBER {
+			return nil, newExpectedError(ctx, t, NUMBER)
+		}
+		n, err := strconv.Atoi(t.value(ctx.input))
+		if err != nil || n <= 0 {
+			return nil, newParseError(ctx, t, "expected positive number of buckets")
+		}
+		buckets = n
+
+		if _, err := p.parseIdents(ctx, BUCKETS); err != nil {
+			return nil, err
+		}
+	}
+
+	var stmts model.Stmts
+	for _, column := range columns {
+		stmts = append(stmts, model.NewHistogram(table, column).SetBuckets(buckets))
+	}
+
+	p.eol(ctx)
+	return stmts, nil
+}
+
+// parseDrop parses `DROP TABLE`, `DROP VIEW`, `DROP TRIGGER`, `DROP
+// PROCEDURE`, `DROP FUNCTION` and `DROP DATABASE` statements, which the
+// callers apply to the statements parsed before. Other DROP statements,
+// such as DROP EVENT, are skipped.
+// https://dev.mysql.com/doc/refman/8.0/en/drop-table.html
+// https://dev.mysql.com/doc/refman/8.0/en/drop-view.html
+// https://dev.mysql.com/doc/refman/8.0/en/drop-trigger.html
+// https://dev.mysql.com/doc/refman/8.0/en/drop-procedure.html
+// https://dev.mysql.com/doc/refman/8.0/en/drop-database.html
+func (p *Parser) parseDrop(ctx *parseCtx) (model.Stmts, error) {
+	start := ctx.next()
+	if start.Type != DROP {
+		return nil, errors.New(`expected DROP`)
+	}
+
+	ctx.skipWhiteSpaces()
+	switch t := ctx.peek(); {
+	case t.Type == TABLE, t.Type == TEMPORARY:
+		return p.parseDropTable(ctx)
+	case t.Type == DATABASE, t.Type == IDENT && strings.EqualFold(t.value(ctx.input), "SCHEMA"):
+		ctx.advance()
+		return p.parseDropDatabase(ctx)
+	case isWord(ctx, t, "VIEW"):
+		ctx.advance()
+		return p.parseDropView(ctx)
+	case routineKind(ctx, t) != model.RoutineKindInvalid:
+		ctx.advance()
+		return p.parseDropRoutine(ctx, routineKind(ctx, t))
+	}
+	ctx.warnf(start, "%s statement is ignored", start.Type)
+	return nil, p.skipStatement(ctx)
+}
+
+func (p *Parser) parseDropTable(ctx *parseCtx) (model.Stmts, error) {
+	var temporary bool
+	if ctx.peek().Type == TEMPORARY {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		temporary = true
+	}
+	if t := ctx.next(); t.Type != TABLE {
+		return nil, newExpectedError(ctx, t, TABLE)
+	}
+
+	ctx.skipWhiteSpaces()
+	var ifexists bool
+	if ctx.peek().Type == IF {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, EXISTS); err != nil {
+			return nil, err
+		}
+		ifexists = true
+	}
+
+	var stmts model.Stmts
+	for {
+		ctx.skipWhiteSpaces()
+		switch t := ctx.next(); t.Type {
+		case IDENT, BACKTICK_IDENT:
+			stmts = append(stmts, model.NewDropTable(t.value(ctx.input)).SetTemporary(temporary).SetIfExists(ifexists))
+			// the table and its foreign keys can be defined again
+			ctx.defs.dropTable(t.value(ctx.input))
+		default:
+			return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+		}
+
+		ctx.skipWhiteSpaces()
+		if ctx.peek().Type != COMMA {
+			break
+		}
+		ctx.advance()
+	}
+
+	// RESTRICT and CASCADE do nothing
+	switch ctx.peek().Type {
+	case RESTRICT, CASCADE:
+		ctx.advance()
+	}
+
+	p.eol(ctx)
+	return stmts, nil
+}
+
+func (p *Parser) parseDropView(ctx *parseCtx) (model.Stmts, error) {
+	ctx.skipWhiteSpaces()
+	var ifexists bool
+	if ctx.peek().Type == IF {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, EXISTS); err != nil {
+			return nil, err
+		}
+		ifexists = true
+	}
+
+	var stmts model.Stmts
+	for {
+		ctx.skipWhiteSpaces()
+		switch t := ctx.next(); t.Type {
+		case IDENT, BACKTICK_IDENT:
+			stmts = append(stmts, model.NewDropView(t.value(ctx.input)).SetIfExists(ifexists))
+		default:
+			return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+		}
+
+		ctx.skipWhiteSpaces()
+		if ctx.peek().Type != COMMA {
+			break
 		}
+		ctx.advance()
+	}
+
+	// RESTRICT and CASCADE do nothing
+	switch ctx.peek().Type {
+	case RESTRICT, CASCADE:
+		ctx.advance()
 	}
 
+	p.eol(ctx)
 	return stmts, nil
 }
 
-func (p *Parser) parseCreate(ctx *parseCtx) (model.Stmt, error) {
-	if t := ctx.next(); t.Type != CREATE {
-		return nil, errors.New(`expected CREATE`)
+func (p *Parser) parseDropRoutine(ctx *parseCtx, kind model.RoutineKind) (model.Stmts, error) {
+	ctx.skipWhiteSpaces()
+	var ifexists bool
+	if ctx.peek().Type == IF {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, EXISTS); err != nil {
+			return nil, err
+		}
+		ifexists = true
 	}
+
 	ctx.skipWhiteSpaces()
-	switch t := ctx.peek(); t.Type {
-	case DATABASE:
-		if _, err := p.parseCreateDatabase(ctx); err != nil {
+	name, err := p.parseQualifiedName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.eol(ctx)
+	return model.Stmts{model.NewDropRoutine(kind, name).SetIfExists(ifexists)}, nil
+}
+
+func (p *Parser) parseDropDatabase(ctx *parseCtx) (model.Stmts, error) {
+	ctx.skipWhiteSpaces()
+	var ifexists bool
+	if ctx.peek().Type == IF {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, EXISTS); err != nil {
 			return nil, err
 		}
-		return nil, errors.Ignorable(nil)
-	case TABLE:
-		return p.parseCreateTable(ctx)
+		ifexists = true
+	}
+
+	ctx.skipWhiteSpaces()
+	var database model.DropDatabase
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		database = model.NewDropDatabase(t.value(ctx.input)).SetIfExists(ifexists)
 	default:
-		return nil, newParseError(ctx, t, "expected DATABASE or TABLE")
+		return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+	}
+	ctx.defs.dropDatabase()
+
+	p.eol(ctx)
+	return model.Stmts{database}, nil
+}
+
+// skipStatement skips all tokens until the end of the current statement.
+// It always returns an ignorable error
+func (p *Parser) skipStatement(ctx *parseCtx) error {
+	for {
+		switch t := ctx.peek(); t.Type {
+		case SEMICOLON:
+			ctx.advance()
+			return errors.Ignorable(nil)
+		case EOF:
+			return errors.Ignorable(nil)
+		default:
+			ctx.advance()
+		}
 	}
 }
 
@@ -243,9 +1238,9 @@ func (p *Parser) parseCreateDatabase(ctx *parseCtx) (model.Database, error) {
 	var database model.Database
 	switch t := ctx.next(); t.Type {
 	case IDENT, BACKTICK_IDENT:
-		database = model.NewDatabase(t.Value)
+		database = model.NewDatabase(t.value(ctx.input))
 	default:
-		return nil, newParseError(ctx, t, "expected IDENT, BACKTICK_IDENT")
+		return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
 	}
 
 	database.SetIfNotExists(notexists)
@@ -255,13 +1250,6 @@ func (p *Parser) parseCreateDatabase(ctx *parseCtx) (model.Database, error) {
 
 // http://dev.mysql.com/doc/refman/5.6/en/create-table.html
 func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
-	if t := ctx.next(); t.Type != TABLE {
-		return nil, errors.New(`expected TABLE`)
-	}
-
-	var table model.Table
-
-	ctx.skipWhiteSpaces()
 	var temporary bool
 	if t := ctx.peek(); t.Type == TEMPORARY {
 		ctx.advance()
@@ -269,9 +1257,19 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
 		temporary = true
 	}
 
+	if t := ctx.next(); t.Type != TABLE {
+		return nil, newExpectedError(ctx, t, TABLE)
+	}
+
+	var table model.Table
+
+	ctx.skipWhiteSpaces()
+
 	// IF NOT EXISTS
 	var notexists bool
-	if ctx.peek().Type == IF {
+	if t := ctx.peek(); t.Type != IF {
+		ctx.expectOptional(t, IF)
+	} else {
 		ctx.advance()
 		if _, err := p.parseIdents(ctx, NOT, EXISTS); err != nil {
 			return nil, err
@@ -280,14 +1278,18 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
 		notexists = true
 	}
 
-	switch t := ctx.next(); t.Type {
+	name := ctx.next()
+	switch name.Type {
 	case IDENT, BACKTICK_IDENT:
-		table = model.NewTable(t.Value)
+		table = model.NewTable(name.value(ctx.input))
 	default:
-		return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+		return nil, newExpectedError(ctx, name, IDENT, BACKTICK_IDENT)
 	}
 	table.SetTemporary(temporary)
 	table.SetIfNotExists(notexists)
+	if err := ctx.checkTable(name, table); err != nil {
+		return nil, err
+	}
 
 	ctx.skipWhiteSpaces()
 	switch t := ctx.peek(); t.Type {
@@ -297,7 +1299,7 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
 		ctx.skipWhiteSpaces()
 		switch t := ctx.next(); t.Type {
 		case IDENT, BACKTICK_IDENT:
-			table.SetLikeTable(t.Value)
+			table.SetLikeTable(t.value(ctx.input))
 		default:
 			return nil, newParseError(ctx, t, "expected table name after LIKE")
 		}
@@ -315,16 +1317,25 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
 		}
 		ctx.skipWhiteSpaces()
 		table.SetIfNotExists(true)
+	default:
+		ctx.expectOptional(t, LIKE, IF)
 	}
 
 	if t := ctx.next(); t.Type != LPAREN {
-		return nil, newParseError(ctx, t, "expected LPAREN")
+		return nil, newExpectedError(ctx, t, LPAREN)
 	}
 
 	if err := p.parseCreateTableFields(ctx, table); err != nil {
 		return nil, err
 	}
 
+	if p.legacyTimestamps {
+		resolveTimestamps(table)
+	}
+	if p.upstream {
+		table, _ = table.NormalizeUpstream()
+		return table, nil
+	}
 	table, _ = table.Normalize()
 	return table, nil
 }
@@ -333,7 +1344,8 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
 func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt model.Table) error {
 	for {
 		ctx.skipWhiteSpaces()
-		switch t := ctx.peek(); t.Type {
+		start := ctx.peek()
+		switch t := start; t.Type {
 		case CONSTRAINT:
 			if err := p.parseTableConstraint(ctx, stmt); err != nil {
 				return err
@@ -370,7 +1382,17 @@ func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt model.Table) error {
 				return err
 			}
 		default:
-			return newParseError(ctx, t, "unexpected create table field token: %s", t.Type)
+			return withExpected(ctx, newParseError(ctx, t, "unexpected create table field token: %s", t.Type), tableFieldStart...)
+		}
+
+		switch start.Type {
+		case IDENT, BACKTICK_IDENT:
+		default:
+			// the index has just been added by the case above
+			index := setLastIndexPosition(stmt, ctx.position(start))
+			if err := ctx.checkIndex(start, stmt, index); err != nil {
+				return err
+			}
 		}
 
 		ctx.skipWhiteSpaces()
@@ -389,14 +1411,18 @@ func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt model.Table) error {
 			ctx.advance()
 			// Expecting another table field, keep looping
 		default:
-			return newParseError(ctx, t, "expected RPAREN or COMMA")
+			return newExpectedError(ctx, t, RPAREN, COMMA)
 		}
 	}
 }
 
+// tableFieldStart lists the tokens that may start the definition of a
+// column or an index in CREATE TABLE
+var tableFieldStart = []TokenType{IDENT, BACKTICK_IDENT, CONSTRAINT, PRIMARY, UNIQUE, INDEX, KEY, FULLTEXT, SPATIAL, FOREIGN}
+
 func (p *Parser) parseTableConstraint(ctx *parseCtx, table model.Table) error {
 	if t := ctx.next(); t.Type != CONSTRAINT {
-		return newParseError(ctx, t, "expected CONSTRAINT")
+		return newExpectedError(ctx, t, CONSTRAINT)
 	}
 	ctx.skipWhiteSpaces()
 
@@ -405,9 +1431,11 @@ func (p *Parser) parseTableConstraint(ctx *parseCtx, table model.Table) error {
 	case IDENT, BACKTICK_IDENT:
 		// TODO: should be smarter
 		// (lestrrat): I don't understand. How?
-		sym = t.Value
+		sym = t.value(ctx.input)
 		ctx.advance()
 		ctx.skipWhiteSpaces()
+	default:
+		ctx.expectOptional(t, IDENT, BACKTICK_IDENT)
 	}
 
 	var index model.Index
@@ -428,7 +1456,7 @@ func (p *Parser) parseTableConstraint(ctx *parseCtx, table model.Table) error {
 			return err
 		}
 	default:
-		return newParseError(ctx, t, "not supported")
+		return withExpected(ctx, newParseError(ctx, t, "not supported"), PRIMARY, UNIQUE, FOREIGN)
 	}
 
 	if len(sym) > 0 {
@@ -493,6 +1521,19 @@ func (p *Parser) parseTableForeignKey(ctx *parseCtx, table model.Table) error {
 	return nil
 }
 
+// setLastIndexPosition sets the position of the index that was added
+// last to the table, and returns it
+func setLastIndexPosition(table model.Table, pos model.Position) model.Index {
+	var last model.Index
+	for idx := range table.Indexes() {
+		last = idx
+	}
+	if last != nil {
+		last.SetPosition(pos)
+	}
+	return last
+}
+
 func (p *Parser) parseTableColumn(ctx *parseCtx, table model.Table) error {
 	t := ctx.next()
 	switch t.Type {
@@ -501,14 +1542,40 @@ func (p *Parser) parseTableColumn(ctx *parseCtx, table model.Table) error {
 		return newParseError(ctx, t, "expcted IDENT or BACKTICK_IDENT")
 	}
 
-	col := model.NewTableColumn(t.Value)
+	col := model.NewTableColumn(t.value(ctx.input))
+	col.SetPosition(ctx.position(t))
 	if err := p.parseTableColumnSpec(ctx, col); err != nil {
 		return err
 	}
+	if err := ctx.checkColumn(t, table, col); err != nil {
+		return err
+	}
 	table.AddColumn(col)
 	return nil
 }
 
+// mariadbColumnTypes lists the column types that are only accepted by
+// the MariaDB dialect
+var mariadbColumnTypes = map[string]model.ColumnType{
+	"INET4": model.ColumnTypeInet4,
+	"INET6": model.ColumnTypeInet6,
+	"UUID":  model.ColumnTypeUUID,
+}
+
+// spatialColumnTypes lists the spatial column types, which are read
+// as identifiers so that they can still be used as column names
+var spatialColumnTypes = map[string]model.ColumnType{
+	"GEOMETRY":           model.ColumnTypeGeometry,
+	"POINT":              model.ColumnTypePoint,
+	"LINESTRING":         model.ColumnTypeLineString,
+	"POLYGON":            model.ColumnTypePolygon,
+	"MULTIPOINT":         model.ColumnTypeMultiPoint,
+	"MULTILINESTRING":    model.ColumnTypeMultiLineString,
+	"MULTIPOLYGON":       model.ColumnTypeMultiPolygon,
+	"GEOMETRYCOLLECTION": model.ColumnTypeGeometryCollection,
+	"GEOMCOLLECTION":     model.ColumnTypeGeomCollection,
+}
+
 func (p *Parser) parseTableColumnSpec(ctx *parseCtx, col model.TableColumn) error {
 	var coltyp model.ColumnType
 	var colopt int
@@ -615,24 +1682,68 @@ func (p *Parser) parseTableColumnSpec(ctx *parseCtx, col model.TableColumn) erro
 		coltyp = model.ColumnTypeBool
 		colopt = coloptFlagNone
 	case JSON:
+		if err := ctx.requireVersion(t, "JSON type", 5, 7, 8); err != nil {
+			return err
+		}
 		coltyp = model.ColumnTypeJSON
 		colopt = coloptFlagNone
+	case IDENT:
+		if typ, ok := spatialColumnTypes[strings.ToUpper(t.value(ctx.input))]; ok {
+			coltyp = typ
+			colopt = coloptFlagSpatial
+			break
+		}
+		typ, ok := mariadbColumnTypes[strings.ToUpper(t.value(ctx.input))]
+		if !ok || ctx.dialect != DialectMariaDB {
+			return columnTypeError(ctx, t)
+		}
+		coltyp = typ
+		colopt = coloptFlagNone
 	default:
-		return newParseError(ctx, t, "unsupported type in column specification")
+		return columnTypeError(ctx, t)
 	}
 
 	col.SetType(coltyp)
 	return p.parseColumnOption(ctx, col, colopt)
 }
 
+// columnTypeStart lists the keywords of the column types. The spatial
+// types, and the types of MariaDB, are identifiers, whose names are
+// given to the error by columnTypeError.
+var columnTypeStart = []TokenType{BIT, TINYINT, SMALLINT, MEDIUMINT, INT, INTEGER, BIGINT, REAL, DOUBLE, FLOAT, DECIMAL, NUMERIC, DATE, TIME, TIMESTAMP, DATETIME, YEAR, CHAR, VARCHAR, BINARY, VARBINARY, TINYBLOB, BLOB, MEDIUMBLOB, LONGBLOB, TINYTEXT, TEXT, MEDIUMTEXT, LONGTEXT, ENUM, SET, BOOLEAN, BOOL, JSON}
+
+// spatialColumnTypeNames and mariadbColumnTypeNames list the names of
+// spatialColumnTypes and mariadbColumnTypes in order
+var (
+	spatialColumnTypeNames = []string{"GEOMETRY", "POINT", "LINESTRING", "POLYGON", "MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON", "GEOMETRYCOLLECTION", "GEOMCOLLECTION"}
+	mariadbColumnTypeNames = []string{"INET4", "INET6", "UUID"}
+)
+
+// columnTypeError returns the error for a token that is not a column
+// type
+func columnTypeError(ctx *parseCtx, t *Token) error {
+	words := spatialColumnTypeNames
+	if ctx.dialect == DialectMariaDB {
+		words = append(words[:len(words):len(words)], mariadbColumnTypeNames...)
+	}
+	return withWords(withExpected(ctx, newParseError(ctx, t, "unsupported type in column specification"), columnTypeStart...), words...)
+}
+
 func (p *Parser) parseCreateTableOptionValue(ctx *parseCtx, table model.Table, name string, follow ...TokenType) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.peek(); t.Type == EQUAL {
 		ctx.advance()
 		ctx.skipWhiteSpaces()
+	} else {
+		ctx.expectOptional(t, EQUAL)
 	}
 
 	t := ctx.next()
+	if name == "DEFAULT COLLATE" {
+		if err := ctx.requireCollation(t); err != nil {
+			return err
+		}
+	}
 	for _, typ := range follow {
 		if typ != t.Type {
 			continue
@@ -642,10 +1753,66 @@ func (p *Parser) parseCreateTableOptionValue(ctx *parseCtx, table model.Table, n
 		case SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT:
 			quotes = true
 		}
-		table.AddOption(model.NewTableOption(name, t.Value, quotes))
+		table.AddOption(model.NewTableOption(name, t.value(ctx.input), quotes))
+		return nil
+	}
+	return newExpectedError(ctx, t, follow...)
+}
+
+// parseRowFormat parses the value of ROW_FORMAT, which is stored in
+// upper case, as the keywords are case insensitive. PAGE, the format of
+// the Aria engine, is only accepted by the MariaDB dialect.
+func (p *Parser) parseRowFormat(ctx *parseCtx, table model.Table) error {
+	ctx.skipWhiteSpaces()
+	if t := ctx.peek(); t.Type == EQUAL {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+	} else {
+		ctx.expectOptional(t, EQUAL)
+	}
+
+	switch t := ctx.next(); t.Type {
+	case DEFAULT, DYNAMIC, FIXED, COMPRESSED, REDUNDANT, COMPACT:
+		table.AddOption(model.NewTableOption("ROW_FORMAT", strings.ToUpper(t.value(ctx.input)), false))
 		return nil
+	case IDENT:
+		if ctx.dialect == DialectMariaDB && strings.EqualFold(t.value(ctx.input), "PAGE") {
+			table.AddOption(model.NewTableOption("ROW_FORMAT", "PAGE", false))
+			return nil
+		}
+		fallthrough
+	default:
+		return newExpectedError(ctx, t, DEFAULT, DYNAMIC, FIXED, COMPRESSED, REDUNDANT, COMPACT)
 	}
-	return newParseError(ctx, t, "expected %v", follow)
+}
+
+// mariadbTableOptions lists the table options that are only accepted
+// by the MariaDB dialect, along with the types of their values
+var mariadbTableOptions = map[string][]TokenType{
+	"ENCRYPTED":              {IDENT, SINGLE_QUOTE_IDENT},
+	"ENCRYPTION_KEY_ID":      {NUMBER},
+	"IETF_QUOTES":            {IDENT, SINGLE_QUOTE_IDENT},
+	"PAGE_CHECKSUM":          {NUMBER},
+	"PAGE_COMPRESSED":        {NUMBER},
+	"PAGE_COMPRESSION_LEVEL": {NUMBER},
+	"SEQUENCE":               {NUMBER},
+	"TRANSACTIONAL":          {NUMBER},
+}
+
+// tidbTableOptions lists the table options that are only accepted by
+// the TiDB dialect, along with the types of their values
+var tidbTableOptions = map[string][]TokenType{
+	"AUTO_ID_CACHE":     {NUMBER},
+	"AUTO_RANDOM_BASE":  {NUMBER},
+	"PRE_SPLIT_REGIONS": {NUMBER},
+	"SHARD_ROW_ID_BITS": {NUMBER},
+}
+
+// dialectTableOptions lists the table options that are specific to
+// a dialect
+var dialectTableOptions = map[Dialect]map[string][]TokenType{
+	DialectMariaDB: mariadbTableOptions,
+	DialectTiDB:    tidbTableOptions,
 }
 
 func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error {
@@ -659,6 +1826,7 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 		// no table options, end of statement
 		return nil
 	}
+	ctx.expectOptional(ctx.peek(), SEMICOLON)
 
 	for {
 		ctx.skipWhiteSpaces()
@@ -684,13 +1852,13 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 			case CHARACTER:
 				ctx.skipWhiteSpaces()
 				if t := ctx.next(); t.Type != SET {
-					return newParseError(ctx, t, "expected SET")
+					return newExpectedError(ctx, t, SET)
 				}
 				name = "DEFAULT CHARACTER SET"
 			case COLLATE:
 				name = "DEFAULT COLLATE"
 			default:
-				return newParseError(ctx, t, "expected CHARACTER or COLLATE")
+				return newExpectedError(ctx, t, CHARACTER, COLLATE)
 			}
 			if err := p.parseCreateTableOptionValue(ctx, table, name, IDENT, BACKTICK_IDENT); err != nil {
 				return err
@@ -698,7 +1866,7 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 		case CHARACTER:
 			ctx.skipWhiteSpaces()
 			if t := ctx.next(); t.Type != SET {
-				return newParseError(ctx, t, "expected SET")
+				return newExpectedError(ctx, t, SET)
 			}
 			if err := p.parseCreateTableOptionValue(ctx, table, "DEFAULT CHARACTER SET", IDENT, BACKTICK_IDENT); err != nil {
 				return err
@@ -722,7 +1890,7 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 		case DATA:
 			ctx.skipWhiteSpaces()
 			if t := ctx.next(); t.Type != DIRECTORY {
-				return newParseError(ctx, t, "expected DIRECTORY")
+				return newExpectedError(ctx, t, DIRECTORY)
 			}
 			if err := p.parseCreateTableOptionValue(ctx, table, "DATA DIRECTORY", SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT); err != nil {
 				return err
@@ -764,7 +1932,7 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 				return err
 			}
 		case ROW_FORMAT:
-			if err := p.parseCreateTableOptionValue(ctx, table, "ROW_FORMAT", DEFAULT, DYNAMIC, FIXED, COMPRESSED, REDUNDANT, COMPACT); err != nil {
+			if err := p.parseRowFormat(ctx, table); err != nil {
 				return err
 			}
 		case STATS_AUTO_RECALC:
@@ -775,32 +1943,319 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 			if err := p.parseCreateTableOptionValue(ctx, table, "STATS_PERSISTENT", NUMBER, DEFAULT); err != nil {
 				return err
 			}
-		case STATS_SAMPLE_PAGES:
-			if err := p.parseCreateTableOptionValue(ctx, table, "STATS_SAMPLE_PAGES", NUMBER); err != nil {
-				return err
+		case STATS_SAMPLE_PAGES:
+			if err := p.parseCreateTableOptionValue(ctx, table, "STATS_SAMPLE_PAGES", NUMBER); err != nil {
+				return err
+			}
+		case PARTITION:
+			if err := p.parsePartitionOptions(ctx, table); err != nil {
+				return err
+			}
+		case TABLESPACE:
+			return newParseError(ctx, t, "unsupported option TABLESPACE")
+		case UNION:
+			return newParseError(ctx, t, "unsupported option UNION")
+		case COMMA:
+			// no op, continue to next option
+			continue
+		case IDENT:
+			name, ok := dialectTableOptions[ctx.dialect][strings.ToUpper(t.value(ctx.input))]
+			if !ok {
+				return withExpected(ctx, newParseError(ctx, t, "unexpected token in table options: "+t.Type.String()), tableOptionStart...)
+			}
+			if err := p.parseCreateTableOptionValue(ctx, table, strings.ToUpper(t.value(ctx.input)), name...); err != nil {
+				return err
+			}
+		default:
+			return withExpected(ctx, newParseError(ctx, t, "unexpected token in table options: "+t.Type.String()), tableOptionStart...)
+		}
+
+		ctx.skipWhiteSpaces()
+		// except for the case where we continue to the next option (COMMA)
+		// we should expect the end of this statement
+		switch t := ctx.peek(); t.Type {
+		case EOF:
+			// end of table options, end of input
+			ctx.advance()
+			return nil
+		case SEMICOLON:
+			// end of table options, end of statement
+			return nil
+		default:
+			ctx.expectOptional(t, SEMICOLON)
+		}
+	}
+}
+
+// tableOptionStart lists the keywords that may start a table option
+var tableOptionStart = []TokenType{ENGINE, AUTO_INCREMENT, AVG_ROW_LENGTH, DEFAULT, CHARACTER, COLLATE, CHECKSUM, COMMENT, CONNECTION, DATA, DELAY_KEY_WRITE, INDEX, INSERT_METHOD, KEY_BLOCK_SIZE, MAX_ROWS, MIN_ROWS, PACK_KEYS, PASSWORD, ROW_FORMAT, STATS_AUTO_RECALC, STATS_PERSISTENT, STATS_SAMPLE_PAGES, PARTITION, COMMA}
+
+// https://dev.mysql.com/doc/refman/5.7/en/create-table.html#create-table-partitioning
+// Start parsing after `PARTITION`
+func (p *Parser) parsePartitionOptions(ctx *parseCtx, table model.Table) error {
+	ctx.skipWhiteSpaces()
+	if t := ctx.next(); t.Type != BY {
+		return newExpectedError(ctx, t, BY)
+	}
+
+	ctx.skipWhiteSpaces()
+	var linear bool
+	if t := ctx.peek(); t.Type == LINEAR {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		linear = true
+	}
+
+	var partitioning model.Partitioning
+	switch t := ctx.next(); t.Type {
+	case HASH:
+		partitioning = model.NewPartitioning(model.PartitionTypeHash)
+		expr, err := p.parseParenthesizedExpression(ctx)
+		if err != nil {
+			return err
+		}
+		partitioning.SetExpression(expr)
+	case KEY:
+		partitioning = model.NewPartitioning(model.PartitionTypeKey)
+		cols, err := p.parsePartitionColumns(ctx)
+		if err != nil {
+			return err
+		}
+		partitioning.SetColumns(cols)
+	case RANGE, LIST:
+		if linear {
+			return newParseError(ctx, t, "LINEAR is only allowed for HASH or KEY")
+		}
+
+		if t.Type == RANGE {
+			partitioning = model.NewPartitioning(model.PartitionTypeRange)
+		} else {
+			partitioning = model.NewPartitioning(model.PartitionTypeList)
+		}
+
+		ctx.skipWhiteSpaces()
+		if t := ctx.peek(); t.Type == COLUMNS {
+			ctx.advance()
+			cols, err := p.parsePartitionColumns(ctx)
+			if err != nil {
+				return err
+			}
+			if len(cols) == 0 {
+				return newParseError(ctx, t, "expected at least one column")
+			}
+			partitioning.SetColumns(cols)
+		} else {
+			expr, err := p.parseParenthesizedExpression(ctx)
+			if err != nil {
+				return err
+			}
+			partitioning.SetExpression(expr)
+		}
+	default:
+		return newExpectedError(ctx, t, HASH, KEY, RANGE, LIST)
+	}
+	partitioning.SetLinear(linear)
+
+	ctx.skipWhiteSpaces()
+	if t := ctx.peek(); t.Type == PARTITIONS {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		t := ctx.next()
+		if t.Type != NUMBER {
+			return newExpectedError(ctx, t, NUMBER)
+		}
+		partitioning.SetPartitionCount(t.value(ctx.input))
+	}
+
+	ctx.skipWhiteSpaces()
+	if t := ctx.peek(); t.Type == LPAREN {
+		ctx.advance()
+		if err := p.parsePartitionDefinitions(ctx, partitioning); err != nil {
+			return err
+		}
+	}
+
+	table.SetPartitioning(partitioning)
+	return nil
+}
+
+// Start parsing after `PARTITION BY ... (`
+func (p *Parser) parsePartitionDefinitions(ctx *parseCtx, partitioning model.Partitioning) error {
+	for {
+		ctx.skipWhiteSpaces()
+		if t := ctx.next(); t.Type != PARTITION {
+			return newExpectedError(ctx, t, PARTITION)
+		}
+
+		ctx.skipWhiteSpaces()
+		var def model.PartitionDefinition
+		switch t := ctx.next(); t.Type {
+		case IDENT, BACKTICK_IDENT:
+			def = model.NewPartitionDefinition(t.value(ctx.input))
+		default:
+			return newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+		}
+
+		ctx.skipWhiteSpaces()
+		if t := ctx.peek(); t.Type == VALUES {
+			ctx.advance()
+			ctx.skipWhiteSpaces()
+			switch t := ctx.next(); t.Type {
+			case LESS:
+				if partitioning.Type() != model.PartitionTypeRange {
+					return newParseError(ctx, t, "VALUES LESS THAN is only allowed for RANGE partitioning")
+				}
+				ctx.skipWhiteSpaces()
+				if t := ctx.next(); t.Type != THAN {
+					return newExpectedError(ctx, t, THAN)
+				}
+				ctx.skipWhiteSpaces()
+				if t := ctx.peek(); t.Type == MAXVALUE {
+					ctx.advance()
+					def.SetMaxValue(true)
+				} else {
+					values, err := p.parseParenthesizedExpression(ctx)
+					if err != nil {
+						return err
+					}
+					def.SetLessThan(values)
+				}
+			case IN:
+				if partitioning.Type() != model.PartitionTypeList {
+					return newParseError(ctx, t, "VALUES IN is only allowed for LIST partitioning")
+				}
+				values, err := p.parseParenthesizedExpression(ctx)
+				if err != nil {
+					return err
+				}
+				def.SetValuesIn(values)
+			default:
+				return newExpectedError(ctx, t, LESS, IN)
+			}
+		}
+
+	OPTIONS:
+		for {
+			ctx.skipWhiteSpaces()
+			switch t := ctx.peek(); t.Type {
+			case STORAGE, ENGINE:
+				ctx.advance()
+				if t.Type == STORAGE {
+					ctx.skipWhiteSpaces()
+					if t := ctx.next(); t.Type != ENGINE {
+						return newExpectedError(ctx, t, ENGINE)
+					}
+				}
+				v, err := p.parsePartitionOptionValue(ctx, IDENT, BACKTICK_IDENT)
+				if err != nil {
+					return err
+				}
+				def.SetEngine(v)
+			case COMMENT:
+				ctx.advance()
+				v, err := p.parsePartitionOptionValue(ctx, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT)
+				if err != nil {
+					return err
+				}
+				def.SetComment(v)
+			default:
+				break OPTIONS
 			}
-		case TABLESPACE:
-			return newParseError(ctx, t, "unsupported option TABLESPACE")
-		case UNION:
-			return newParseError(ctx, t, "unsupported option UNION")
+		}
+		partitioning.AddDefinition(def)
+
+		ctx.skipWhiteSpaces()
+		switch t := ctx.next(); t.Type {
+		case RPAREN:
+			return nil
 		case COMMA:
-			// no op, continue to next option
-			continue
+			// Expecting another partition definition, keep looping
+		default:
+			return newExpectedError(ctx, t, RPAREN, COMMA)
+		}
+	}
+}
+
+func (p *Parser) parsePartitionOptionValue(ctx *parseCtx, follow ...TokenType) (string, error) {
+	ctx.skipWhiteSpaces()
+	if t := ctx.peek(); t.Type == EQUAL {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+	} else {
+		ctx.expectOptional(t, EQUAL)
+	}
+
+	t := ctx.next()
+	for _, typ := range follow {
+		if typ == t.Type {
+			return t.value(ctx.input), nil
+		}
+	}
+	return "", newExpectedError(ctx, t, follow...)
+}
+
+// parsePartitionColumns parses a parenthesized, possibly empty, list
+// of column names
+func (p *Parser) parsePartitionColumns(ctx *parseCtx) ([]string, error) {
+	ctx.skipWhiteSpaces()
+	if t := ctx.next(); t.Type != LPAREN {
+		return nil, newExpectedError(ctx, t, LPAREN)
+	}
+
+	var cols []string
+	for {
+		ctx.skipWhiteSpaces()
+		t := ctx.next()
+		switch t.Type {
+		case RPAREN:
+			if len(cols) == 0 {
+				return cols, nil
+			}
+			return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+		case IDENT, BACKTICK_IDENT:
+			cols = append(cols, t.value(ctx.input))
 		default:
-			return newParseError(ctx, t, "unexpected token in table options: "+t.Type.String())
+			return nil, newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
 		}
 
 		ctx.skipWhiteSpaces()
-		// except for the case where we continue to the next option (COMMA)
-		// we should expect the end of this statement
-		switch t := ctx.peek(); t.Type {
+		switch t := ctx.next(); t.Type {
+		case RPAREN:
+			return cols, nil
+		case COMMA:
+			// Expecting another column, keep looping
+		default:
+			return nil, newExpectedError(ctx, t, COMMA, RPAREN)
+		}
+	}
+}
+
+// parseParenthesizedExpression parses a parenthesized expression, and
+// returns the text between the parentheses as-is. Expressions are not
+// interpreted, so that they can be written back exactly as they were given
+func (p *Parser) parseParenthesizedExpression(ctx *parseCtx) (string, error) {
+	ctx.skipWhiteSpaces()
+	lparen := ctx.next()
+	if lparen.Type != LPAREN {
+		return "", newExpectedError(ctx, lparen, LPAREN)
+	}
+
+	depth := 1
+	for {
+		switch t := ctx.next(); t.Type {
+		case LPAREN:
+			depth++
+		case RPAREN:
+			depth--
+			if depth == 0 {
+				expr := strings.TrimSpace(string(ctx.input[lparen.Pos+1 : t.Pos]))
+				if expr == "" {
+					return "", newParseError(ctx, t, "expected expression")
+				}
+				return expr, nil
+			}
 		case EOF:
-			// end of table options, end of input
-			ctx.advance()
-			return nil
-		case SEMICOLON:
-			// end of table options, end of statement
-			return nil
+			return "", newExpectedError(ctx, t, RPAREN)
 		}
 	}
 }
@@ -813,29 +2268,62 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 // seem to state otherwise.
 //
 func (p *Parser) parseColumnOption(ctx *parseCtx, col model.TableColumn, f int) error {
-	f = f | coloptNull | coloptDefault | coloptAutoIncrement | coloptKey | coloptComment
-	pos := 0
+	f = f | coloptCharacterSet | coloptCollate | coloptNull | coloptDefault | coloptOnUpdate | coloptAutoIncrement | coloptUnique | coloptKey | coloptComment
+	// seen records the options that were already given, which may
+	// not be given again
+	var seen int
 	check := func(_f int) bool {
-		if pos > _f {
-			return false
+		return f|_f == f
+	}
+	apply := func(t *Token, _f int, name string) error {
+		if !check(_f) {
+			return newParseError(ctx, t, "cannot apply %s", name)
 		}
-		if f|_f != f {
-			return false
+		if seen&_f != 0 {
+			return newParseError(ctx, t, "duplicate column option %s", name)
+		}
+		seen |= _f
+		return nil
+	}
+
+	// the character set and collation are applied once all options are
+	// read, as MariaDB dumps JSON columns as LONGTEXT columns with the
+	// utf8mb4_bin collation, followed by a json_valid() check
+	var charset, collation string
+	var hasCharset, hasCollation, jsonValid bool
+	finish := func() {
+		if jsonValid && col.Type() == model.ColumnTypeLongText {
+			col.SetType(model.ColumnTypeJSON)
+			if strings.EqualFold(charset, "utf8mb4") {
+				hasCharset = false
+			}
+			if strings.EqualFold(collation, "utf8mb4_bin") {
+				hasCollation = false
+			}
+		}
+		if hasCharset {
+			col.SetCharacterSet(charset)
+		}
+		if hasCollation {
+			col.SetCollation(collation)
 		}
-		pos = _f
-		return true
 	}
+
 	for {
 		ctx.skipWhiteSpaces()
 		switch t := ctx.next(); t.Type {
 		case LPAREN:
+			if seen != 0 {
+				return newParseError(ctx, t, "the size or the values of the column must directly follow its type")
+			}
+			seen |= coloptSize
 			if check(coloptSize) {
 				ctx.skipWhiteSpaces()
 				t := ctx.next()
 				if t.Type != NUMBER {
 					return newParseError(ctx, t, "expected NUMBER (column size)")
 				}
-				tlen := t.Value
+				tlen := t.value(ctx.input)
 
 				ctx.skipWhiteSpaces()
 				t = ctx.next()
@@ -857,7 +2345,7 @@ func (p *Parser) parseColumnOption(ctx *parseCtx, col model.TableColumn, f int)
 				if t.Type != NUMBER {
 					return newParseError(ctx, t, "expected NUMBER (decimal size `M`)")
 				}
-				tlen := t.Value
+				tlen := t.value(ctx.input)
 
 				ctx.skipWhiteSpaces()
 				t = ctx.next()
@@ -873,7 +2361,7 @@ func (p *Parser) parseColumnOption(ctx *parseCtx, col model.TableColumn, f int)
 				if t.Type != NUMBER {
 					return newParseError(ctx, t, "expected NUMBER (decimal size `D`)")
 				}
-				tscale := t.Value
+				tscale := t.value(ctx.input)
 
 				ctx.skipWhiteSpaces()
 				if t := ctx.next(); t.Type != RPAREN {
@@ -889,129 +2377,300 @@ func (p *Parser) parseColumnOption(ctx *parseCtx, col model.TableColumn, f int)
 			} else {
 				return newParseError(ctx, t, "cannot apply coloptSize, coloptDecimalSize, coloptDecimalOptionalSize, coloptEnumValues, coloptSetValues")
 			}
-		case CHARACTER:
-			ctx.skipWhiteSpaces()
-			if t := ctx.next(); t.Type != SET {
-				return newParseError(ctx, t, "expected SET")
-			}
-			ctx.skipWhiteSpaces()
-			v := ctx.next()
-			col.SetCharacterSet(v.Value)
-		case COLLATE:
-			ctx.skipWhiteSpaces()
-			v := ctx.next()
-			col.SetCollation(v.Value)
-		case UNSIGNED:
-			if !check(coloptUnsigned) {
-				return newParseError(ctx, t, "cannot apply UNSIGNED")
-			}
-			col.SetUnsigned(true)
-		case ZEROFILL:
-			if !check(coloptZerofill) {
-				return newParseError(ctx, t, "cannot apply ZEROFILL")
-			}
-			col.SetZeroFill(true)
-		case BINARY:
-			if !check(coloptBinary) {
-				return newParseError(ctx, t, "cannot apply BINARY")
-			}
-			col.SetBinary(true)
-		case NOT:
-			if !check(coloptNull) {
-				return newParseError(ctx, t, "cannot apply NOT NULL")
-			}
-			ctx.skipWhiteSpaces()
-			switch t := ctx.next(); t.Type {
-			case NULL:
-				col.SetNullState(model.NullStateNotNull)
-			default:
-				return newParseError(ctx, t, "expected NULL")
-			}
-		case NULL:
-			if !check(coloptNull) {
-				return newParseError(ctx, t, "cannot apply NULL")
-			}
-			col.SetNullState(model.NullStateNull)
-		case ON:
-			// for now, only applicable to ON UPDATE ...
-			ctx.skipWhiteSpaces()
-			if t := ctx.next(); t.Type != UPDATE {
-				return newParseError(ctx, t, "expected ON UPDATE")
-			}
-			ctx.skipWhiteSpaces()
-			v := ctx.next()
-			col.SetAutoUpdate(v.Value)
-		case DEFAULT:
-			if !check(coloptDefault) {
-				return newParseError(ctx, t, "cannot apply DEFAULT")
-			}
-			ctx.skipWhiteSpaces()
-			switch t := ctx.next(); t.Type {
-			case IDENT, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT:
-				col.SetDefault(t.Value, true)
-			case NUMBER, CURRENT_TIMESTAMP, NULL, TRUE, FALSE:
-				col.SetDefault(strings.ToUpper(t.Value), false)
-			case NOW:
-				now := t.Value
-				if t := ctx.next(); t.Type != LPAREN {
-					return newParseError(ctx, t, "expected LPAREN")
+		case IDENT:
+			if check(coloptSRID) && strings.EqualFold(t.value(ctx.input), "SRID") {
+				if err := apply(t, coloptSRID, "SRID"); err != nil {
+					return err
 				}
-				if t := ctx.next(); t.Type != RPAREN {
-					return newParseError(ctx, t, "expected RPAREN")
+				if err := ctx.requireVersion(t, "SRID", 8, 0, 3); err != nil {
+					return err
 				}
-				col.SetDefault(strings.ToUpper(now)+"()", false)
-			default:
-				return newParseError(ctx, t, "expected IDENT, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT, NUMBER, CURRENT_TIMESTAMP, NULL")
+				ctx.skipWhiteSpaces()
+				v := ctx.next()
+				if v.Type != NUMBER {
+					return newExpectedError(ctx, v, NUMBER)
+				}
+				col.SetSRID(v.value(ctx.input))
+				continue
 			}
-		case AUTO_INCREMENT:
-			if !check(coloptAutoIncrement) {
-				return newParseError(ctx, t, "cannot apply AUTO_INCREMENT")
+			if ctx.dialect != DialectTiDB || !strings.EqualFold(t.value(ctx.input), "AUTO_RANDOM") {
+				return withExpected(ctx, newParseError(ctx, t, "unexpected column option %s", t.Type), columnOptionStart(f, seen)...)
 			}
-			col.SetAutoIncrement(true)
-		case UNIQUE:
-			if !check(coloptKey) {
-				return newParseError(ctx, t, "cannot apply UNIQUE KEY")
+			if err := apply(t, coloptAutoIncrement, "AUTO_RANDOM"); err != nil {
+				return err
 			}
+			var args string
 			ctx.skipWhiteSpaces()
-			if t := ctx.peek(); t.Type == KEY {
-				ctx.advance()
-			}
-			col.SetUnique(true)
-		case KEY:
-			if !check(coloptKey) {
-				return newParseError(ctx, t, "cannot apply KEY")
-			}
-			col.SetKey(true)
-		case PRIMARY:
-			if !check(coloptKey) {
-				return newParseError(ctx, t, "cannot apply PRIMARY KEY")
+			if ctx.peek().Type == LPAREN {
+				expr, err := p.parseParenthesizedExpression(ctx)
+				if err != nil {
+					return err
+				}
+				// normalize the spaces around the arguments
+				list := strings.Split(expr, ",")
+				for i := range list {
+					list[i] = strings.TrimSpace(list[i])
+				}
+				args = strings.Join(list, ", ")
 			}
-			ctx.skipWhiteSpaces()
-			if t := ctx.next(); t.Type != KEY {
-				return newParseError(ctx, t, "expected PRIMARY KEY")
+			col.SetAutoRandom(args)
+		case CHECK:
+			if ctx.dialect != DialectMariaDB {
+				return withExpected(ctx, newParseError(ctx, t, "unexpected column option %s", t.Type), columnOptionStart(f, seen)...)
 			}
-			col.SetPrimary(true)
-		case COMMENT:
-			if !check(coloptComment) {
-				return newParseError(ctx, t, "cannot apply COMMENT")
+			expr, err := p.parseParenthesizedExpression(ctx)
+			if err != nil {
+				return err
 			}
-			ctx.skipWhiteSpaces()
-			switch t := ctx.next(); t.Type {
-			case SINGLE_QUOTE_IDENT:
-				col.SetComment(t.Value)
-			default:
-				return newParseError(ctx, t, "should SINGLE_QUOTE_IDENT")
+			if !isJSONValid(expr, col.Name()) {
+				return newParseError(ctx, t, "unsupported CHECK constraint: %s", expr)
 			}
+			jsonValid = true
 		case COMMA:
 			ctx.rewind()
+			finish()
 			return nil
 		case RPAREN:
 			ctx.rewind()
+			finish()
 			return nil
 		default:
-			return newParseError(ctx, t, "unexpected column option %s", t.Type)
+			attr, ok := columnAttributes[t.Type]
+			if !ok {
+				return withExpected(ctx, newParseError(ctx, t, "unexpected column option %s", t.Type), columnOptionStart(f, seen)...)
+			}
+			if err := apply(t, attr.flag, attr.name); err != nil {
+				return err
+			}
+			if err := ctx.parseKeywords(attr.follow, attr.optional); err != nil {
+				return err
+			}
+
+			switch t.Type {
+			case UNSIGNED:
+				col.SetUnsigned(true)
+			case ZEROFILL:
+				col.SetZeroFill(true)
+			case BINARY:
+				col.SetBinary(true)
+			case CHARACTER:
+				ctx.skipWhiteSpaces()
+				charset = ctx.next().value(ctx.input)
+				hasCharset = true
+			case COLLATE:
+				ctx.skipWhiteSpaces()
+				v := ctx.next()
+				if err := ctx.requireCollation(v); err != nil {
+					return err
+				}
+				collation = v.value(ctx.input)
+				hasCollation = true
+			case NOT:
+				col.SetNullState(model.NullStateNotNull)
+			case NULL:
+				col.SetNullState(model.NullStateNull)
+			case ON:
+				ctx.skipWhiteSpaces()
+				v := ctx.next()
+				value := v.value(ctx.input)
+				if ctx.isCurrentTimestamp(v) {
+					var err error
+					if value, err = ctx.parseCurrentTimestamp(v); err != nil {
+						return err
+					}
+				}
+				col.SetAutoUpdate(value)
+			case DEFAULT:
+				if err := ctx.parseColumnDefault(col); err != nil {
+					return err
+				}
+			case AUTO_INCREMENT:
+				col.SetAutoIncrement(true)
+			case UNIQUE:
+				col.SetUnique(true)
+			case KEY:
+				col.SetKey(true)
+			case PRIMARY:
+				col.SetPrimary(true)
+			case COMMENT:
+				ctx.skipWhiteSpaces()
+				// double quoted strings are identifiers with
+				// ANSI_QUOTES, and strings otherwise
+				switch t := ctx.next(); t.Type {
+				case SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT:
+					col.SetComment(t.value(ctx.input))
+				default:
+					return newExpectedError(ctx, t, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT)
+				}
+			}
+		}
+	}
+}
+
+// columnAttribute describes a column option that starts with a keyword
+type columnAttribute struct {
+	// flag tells the types that accept the option, and is used to
+	// find the options that are given twice
+	flag int
+	name string
+	// follow lists the keywords that must follow the first one, such
+	// as NULL after NOT
+	follow []TokenType
+	// optional is a keyword that may follow, such as KEY after UNIQUE
+	optional TokenType
+}
+
+// columnAttributes maps the first keyword of each column option to its
+// description. The options that are not listed here, such as the size
+// of the type, are parsed separately.
+var columnAttributes = map[TokenType]columnAttribute{
+	UNSIGNED:       {flag: coloptUnsigned, name: "UNSIGNED"},
+	ZEROFILL:       {flag: coloptZerofill, name: "ZEROFILL"},
+	BINARY:         {flag: coloptBinary, name: "BINARY"},
+	CHARACTER:      {flag: coloptCharacterSet, name: "CHARACTER SET", follow: []TokenType{SET}},
+	COLLATE:        {flag: coloptCollate, name: "COLLATE"},
+	NOT:            {flag: coloptNull, name: "NOT NULL", follow: []TokenType{NULL}},
+	NULL:           {flag: coloptNull, name: "NULL"},
+	ON:             {flag: coloptOnUpdate, name: "ON UPDATE", follow: []TokenType{UPDATE}},
+	DEFAULT:        {flag: coloptDefault, name: "DEFAULT"},
+	AUTO_INCREMENT: {flag: coloptAutoIncrement, name: "AUTO_INCREMENT"},
+	UNIQUE:         {flag: coloptUnique, name: "UNIQUE KEY", optional: KEY},
+	KEY:            {flag: coloptKey, name: "KEY"},
+	PRIMARY:        {flag: coloptKey, name: "PRIMARY KEY", follow: []TokenType{KEY}},
+	COMMENT:        {flag: coloptComment, name: "COMMENT"},
+}
+
+// columnOptionStart returns the tokens that may start the next column
+// option, given the options that the type accepts and those that were
+// already given, followed by the end of the column
+func columnOptionStart(f, seen int) []TokenType {
+	var list []TokenType
+	if seen == 0 && f&(coloptSize|coloptDecimalSize|coloptDecimalOptionalSize|coloptEnumValues|coloptSetValues) != 0 {
+		list = append(list, LPAREN)
+	}
+	for typ, attr := range columnAttributes {
+		if f|attr.flag == f && seen&attr.flag == 0 {
+			list = append(list, typ)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return append(list, COMMA, RPAREN)
+}
+
+// parseKeywords consumes the keywords that must follow, and then the
+// optional keyword, if it follows. Only the keywords are consumed, so
+// that the next token is the one after them.
+func (pctx *parseCtx) parseKeywords(follow []TokenType, optional TokenType) error {
+	for _, typ := range follow {
+		pctx.skipWhiteSpaces()
+		if t := pctx.next(); t.Type != typ {
+			return newExpectedError(pctx, t, typ)
+		}
+	}
+	if optional == ILLEGAL {
+		return nil
+	}
+	pctx.skipWhiteSpaces()
+	if t := pctx.peek(); t.Type == optional {
+		pctx.advance()
+	} else {
+		pctx.expectOptional(t, optional)
+	}
+	return nil
+}
+
+// parseColumnDefault parses the value that follows DEFAULT
+func (pctx *parseCtx) parseColumnDefault(col model.TableColumn) error {
+	pctx.skipWhiteSpaces()
+	t := pctx.next()
+	if pctx.isCurrentTimestamp(t) {
+		value, err := pctx.parseCurrentTimestamp(t)
+		if err != nil {
+			return err
+		}
+		col.SetDefault(value, false)
+		return nil
+	}
+
+	switch t.Type {
+	case IDENT, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT:
+		col.SetDefault(t.value(pctx.input), true)
+	case NUMBER, NULL, TRUE, FALSE:
+		col.SetDefault(strings.ToUpper(t.value(pctx.input)), false)
+	default:
+		return newExpectedError(pctx, t, IDENT, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT, NUMBER, CURRENT_TIMESTAMP, NULL)
+	}
+	return nil
+}
+
+// isJSONValid returns true if the expression is json_valid(column),
+// which MariaDB adds to the columns declared as JSON
+func isJSONValid(expr, column string) bool {
+	expr = strings.NewReplacer("`", "", " ", "").Replace(strings.ToLower(expr))
+	return expr == "json_valid("+strings.ToLower(column)+")"
+}
+
+// isCurrentTimestamp returns true if the token is CURRENT_TIMESTAMP or
+// one of its synonyms, NOW, LOCALTIME and LOCALTIMESTAMP
+func (pctx *parseCtx) isCurrentTimestamp(t *Token) bool {
+	switch t.Type {
+	case CURRENT_TIMESTAMP, NOW:
+		return true
+	case IDENT:
+		switch strings.ToUpper(t.value(pctx.input)) {
+		case "LOCALTIME", "LOCALTIMESTAMP":
+			return true
 		}
 	}
+	return false
+}
+
+// parseCurrentTimestamp parses the optional precision that follows
+// CURRENT_TIMESTAMP or one of its synonyms, whose token is t, and
+// returns its canonical spelling, such as CURRENT_TIMESTAMP(6), NOW()
+// or LOCALTIMESTAMP. NOW requires the parentheses, which the others do
+// not need.
+func (pctx *parseCtx) parseCurrentTimestamp(t *Token) (string, error) {
+	name := strings.ToUpper(t.value(pctx.input))
+	pctx.skipWhiteSpaces()
+	if t.Type == NOW && pctx.peek().Type != LPAREN {
+		return "", newExpectedError(pctx, pctx.peek(), LPAREN)
+	}
+	precision, err := pctx.parseTimestampPrecision()
+	if err != nil {
+		return "", err
+	}
+	if t.Type == NOW && precision == "" {
+		precision = "()"
+	}
+	return name + precision, nil
+}
+
+// parseTimestampPrecision parses the optional parentheses that follow
+// CURRENT_TIMESTAMP, such as in `current_timestamp()` written by MariaDB
+// or CURRENT_TIMESTAMP(6). Empty parentheses are dropped, and the
+// precision is returned as "(6)"
+func (pctx *parseCtx) parseTimestampPrecision() (string, error) {
+	if pctx.peek().Type != LPAREN {
+		return "", nil
+	}
+	pctx.advance()
+
+	pctx.skipWhiteSpaces()
+	t := pctx.next()
+	if t.Type == RPAREN {
+		return "", nil
+	}
+	if t.Type != NUMBER {
+		return "", newExpectedError(pctx, t, NUMBER, RPAREN)
+	}
+	precision := t.value(pctx.input)
+
+	pctx.skipWhiteSpaces()
+	if t := pctx.next(); t.Type != RPAREN {
+		return "", newExpectedError(pctx, t, RPAREN)
+	}
+	return "(" + precision + ")", nil
 }
 
 func (ctx *parseCtx) parseSetOrEnum(setter func([]string) model.TableColumn) error {
@@ -1021,7 +2680,7 @@ OUTER:
 		ctx.skipWhiteSpaces()
 		v := ctx.next()
 		if v.Type == SINGLE_QUOTE_IDENT || v.Type == DOUBLE_QUOTE_IDENT {
-			values = append(values, v.Value)
+			values = append(values, v.value(ctx.input))
 		} else {
 			return newParseError(ctx, v, "expected RPAREN, SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT(enum values): %s", v.Type)
 		}
@@ -1032,7 +2691,7 @@ OUTER:
 		case RPAREN:
 			break OUTER
 		default:
-			return newParseError(ctx, t, "expected COMMA")
+			return newExpectedError(ctx, t, COMMA)
 		}
 	}
 	setter(values)
@@ -1042,11 +2701,11 @@ OUTER:
 func (p *Parser) parseColumnIndexPrimaryKey(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != PRIMARY {
-		return newParseError(ctx, t, "expected PRIMARY")
+		return newExpectedError(ctx, t, PRIMARY)
 	}
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != KEY {
-		return newParseError(ctx, t, "expected KEY")
+		return newExpectedError(ctx, t, KEY)
 	}
 
 	return p.parseColumnIndexCommon(ctx, index)
@@ -1057,13 +2716,15 @@ func (p *Parser) parseColumnIndexUniqueKey(ctx *parseCtx, index model.Index) err
 	switch t := ctx.next(); t.Type {
 	case UNIQUE:
 	default:
-		return newParseError(ctx, t, "expected UNIQUE")
+		return newExpectedError(ctx, t, UNIQUE)
 	}
 
 	ctx.skipWhiteSpaces()
 	switch t := ctx.peek(); t.Type {
 	case KEY, INDEX:
 		ctx.advance()
+	default:
+		ctx.expectOptional(t, KEY, INDEX)
 	}
 
 	return p.parseColumnIndexCommon(ctx, index)
@@ -1088,15 +2749,36 @@ func (p *Parser) parseColumnIndexCommon(ctx *parseCtx, index model.Index) error
 		return err
 	}
 
+	if ctx.dialect == DialectTiDB && index.IsPrimaryKey() {
+		p.parseColumnIndexClustering(ctx, index)
+	}
+
 	return nil
 }
 
+// parseColumnIndexClustering parses the optional CLUSTERED or
+// NONCLUSTERED keyword that follows a TiDB primary key
+func (p *Parser) parseColumnIndexClustering(ctx *parseCtx, index model.Index) {
+	ctx.skipWhiteSpaces()
+	t := ctx.peek()
+	if t.Type != IDENT {
+		return
+	}
+	switch strings.ToUpper(t.value(ctx.input)) {
+	case "CLUSTERED":
+		ctx.advance()
+		index.SetClustering(model.IndexClusteringClustered)
+	case "NONCLUSTERED":
+		ctx.advance()
+		index.SetClustering(model.IndexClusteringNonClustered)
+	}
+}
+
 func (p *Parser) parseColumnIndexKey(ctx *parseCtx, index model.Index) error {
 	switch t := ctx.next(); t.Type {
 	case KEY, INDEX:
-		ctx.advance()
 	default:
-		return newParseError(ctx, t, "expected KEY or INDEX")
+		return newExpectedError(ctx, t, KEY, INDEX)
 	}
 
 	return p.parseColumnIndexCommon(ctx, index)
@@ -1105,13 +2787,16 @@ func (p *Parser) parseColumnIndexKey(ctx *parseCtx, index model.Index) error {
 func (p *Parser) parseColumnIndexFullTextKey(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != FULLTEXT {
-		return newParseError(ctx, t, "expected FULLTEXT")
+		return newExpectedError(ctx, t, FULLTEXT)
 	}
 
-	// optional INDEX
+	// optional INDEX or KEY
 	ctx.skipWhiteSpaces()
-	if t := ctx.peek(); t.Type == INDEX {
+	switch t := ctx.peek(); t.Type {
+	case KEY, INDEX:
 		ctx.advance()
+	default:
+		ctx.expectOptional(t, KEY, INDEX)
 	}
 
 	if err := p.parseColumnIndexName(ctx, index); err != nil {
@@ -1122,19 +2807,46 @@ func (p *Parser) parseColumnIndexFullTextKey(ctx *parseCtx, index model.Index) e
 		return err
 	}
 
+	return p.parseColumnIndexParser(ctx, index)
+}
+
+// parseColumnIndexParser parses the optional WITH PARSER clause that
+// follows the columns of a full-text index
+func (p *Parser) parseColumnIndexParser(ctx *parseCtx, index model.Index) error {
+	ctx.skipWhiteSpaces()
+	if t := ctx.peek(); t.Type != WITH {
+		return nil
+	}
+	ctx.advance()
+
+	ctx.skipWhiteSpaces()
+	if t := ctx.next(); t.Type != IDENT || !strings.EqualFold(t.value(ctx.input), "PARSER") {
+		return newParseError(ctx, t, "expected PARSER")
+	}
+
+	ctx.skipWhiteSpaces()
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		index.SetParser(t.value(ctx.input))
+	default:
+		return newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
+	}
 	return nil
 }
 
 func (p *Parser) parseColumnIndexSpatialKey(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != SPATIAL {
-		return newParseError(ctx, t, "expected SPATIAL")
+		return newExpectedError(ctx, t, SPATIAL)
 	}
 
-	// optional INDEX
+	// optional INDEX or KEY
 	ctx.skipWhiteSpaces()
-	if t := ctx.peek(); t.Type == INDEX {
+	switch t := ctx.peek(); t.Type {
+	case KEY, INDEX:
 		ctx.advance()
+	default:
+		ctx.expectOptional(t, KEY, INDEX)
 	}
 
 	if err := p.parseColumnIndexName(ctx, index); err != nil {
@@ -1151,12 +2863,12 @@ func (p *Parser) parseColumnIndexSpatialKey(ctx *parseCtx, index model.Index) er
 func (p *Parser) parseColumnIndexForeignKey(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != FOREIGN {
-		return newParseError(ctx, t, "expected FOREGIN")
+		return newExpectedError(ctx, t, FOREIGN)
 	}
 
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != KEY {
-		return newParseError(ctx, t, "expected KEY")
+		return newExpectedError(ctx, t, KEY)
 	}
 	if err := p.parseColumnIndexName(ctx, index); err != nil {
 		return err
@@ -1171,6 +2883,8 @@ func (p *Parser) parseColumnIndexForeignKey(ctx *parseCtx, index model.Index) er
 		if err := p.parseColumnReference(ctx, index); err != nil {
 			return err
 		}
+	} else {
+		ctx.expectOptional(t, REFERENCES)
 	}
 
 	return nil
@@ -1186,17 +2900,17 @@ func (p *Parser) parseReferenceOption(ctx *parseCtx, set func(model.ReferenceOpt
 	case SET:
 		ctx.skipWhiteSpaces()
 		if t := ctx.next(); t.Type != NULL {
-			return newParseError(ctx, t, "expected NULL")
+			return newExpectedError(ctx, t, NULL)
 		}
 		set(model.ReferenceOptionSetNull)
 	case NO:
 		ctx.skipWhiteSpaces()
 		if t := ctx.next(); t.Type != ACTION {
-			return newParseError(ctx, t, "expected ACTION")
+			return newExpectedError(ctx, t, ACTION)
 		}
 		set(model.ReferenceOptionNoAction)
 	default:
-		return newParseError(ctx, t, "expected RESTRICT, CASCADE, SET or NO")
+		return newExpectedError(ctx, t, RESTRICT, CASCADE, SET, NO)
 	}
 	return nil
 }
@@ -1204,7 +2918,7 @@ func (p *Parser) parseReferenceOption(ctx *parseCtx, set func(model.ReferenceOpt
 func (p *Parser) parseColumnReference(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != REFERENCES {
-		return newParseError(ctx, t, "expected REFERENCES")
+		return newExpectedError(ctx, t, REFERENCES)
 	}
 
 	r := model.NewReference()
@@ -1212,9 +2926,9 @@ func (p *Parser) parseColumnReference(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	switch t := ctx.next(); t.Type {
 	case BACKTICK_IDENT, IDENT:
-		r.SetTableName(t.Value)
+		r.SetTableName(t.value(ctx.input))
 	default:
-		return newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+		return newExpectedError(ctx, t, IDENT, BACKTICK_IDENT)
 	}
 
 	if err := p.parseColumnIndexColumns(ctx, r); err != nil {
@@ -1244,6 +2958,10 @@ OUTER:
 	for i := 0; i < 2; i++ {
 		ctx.skipWhiteSpaces()
 		if t := ctx.peek(); t.Type != ON {
+			if i == 0 && !r.MatchFull() && !r.MatchPartial() && !r.MatchSimple() {
+				ctx.expectOptional(t, MATCH)
+			}
+			ctx.expectOptional(t, ON)
 			break OUTER
 		}
 		ctx.advance()
@@ -1260,7 +2978,7 @@ OUTER:
 			}
 			break OUTER
 		default:
-			return newParseError(ctx, t, "expected DELETE or UPDATE")
+			return newExpectedError(ctx, t, DELETE, UPDATE)
 		}
 	}
 
@@ -1273,7 +2991,9 @@ func (p *Parser) parseColumnIndexName(ctx *parseCtx, index model.Index) error {
 	switch t := ctx.peek(); t.Type {
 	case BACKTICK_IDENT, IDENT:
 		ctx.advance()
-		index.SetName(t.Value)
+		index.SetName(t.value(ctx.input))
+	default:
+		ctx.expectOptional(t, IDENT, BACKTICK_IDENT)
 	}
 	return nil
 }
@@ -1281,6 +3001,7 @@ func (p *Parser) parseColumnIndexName(ctx *parseCtx, index model.Index) error {
 func (p *Parser) parseColumnIndexType(ctx *parseCtx, index model.Index) error {
 	ctx.skipWhiteSpaces()
 	if t := ctx.peek(); t.Type != USING {
+		ctx.expectOptional(t, USING)
 		return nil
 	}
 	ctx.advance()
@@ -1304,7 +3025,7 @@ func (p *Parser) parseColumnIndexType(ctx *parseCtx, index model.Index) error {
 	case HASH:
 		index.SetType(model.IndexTypeHash)
 	default:
-		return newParseError(ctx, t, "expected BTREE or HASH")
+		return newExpectedError(ctx, t, BTREE, HASH)
 	}
 	return nil
 }
@@ -1318,7 +3039,7 @@ func (p *Parser) parseColumnIndexColumns(ctx *parseCtx, container interface {
 
 	ctx.skipWhiteSpaces()
 	if t := ctx.next(); t.Type != LPAREN {
-		return newParseError(ctx, t, "expected LPAREN while parsing index column: %s", t.Type)
+		return withExpected(ctx, newParseError(ctx, t, "expected LPAREN while parsing index column: %s", t.Type), LPAREN)
 	}
 
 OUTER:
@@ -1326,10 +3047,10 @@ OUTER:
 		ctx.skipWhiteSpaces()
 		t := ctx.next()
 		if !(t.Type == IDENT || t.Type == BACKTICK_IDENT) {
-			return newParseError(ctx, t, "should IDENT or BACKTICK_IDENT")
+			return withExpected(ctx, newParseError(ctx, t, "should IDENT or BACKTICK_IDENT"), IDENT, BACKTICK_IDENT)
 		}
 
-		col := model.NewIndexColumn(t.Value)
+		col := model.NewIndexColumn(t.value(ctx.input))
 		cols = append(cols, col)
 
 		ctx.skipWhiteSpaces()
@@ -1337,16 +3058,17 @@ OUTER:
 		case LPAREN:
 			t := ctx.next()
 			if t.Type != NUMBER {
-				return newParseError(ctx, t, "expected NUMBER")
+				return newExpectedError(ctx, t, NUMBER)
 			}
-			tlen := t.Value
+			tlen := t.value(ctx.input)
 			ctx.skipWhiteSpaces()
 			if t = ctx.next(); t.Type != RPAREN {
-				return newParseError(ctx, t, "expected RPAREN")
+				return newExpectedError(ctx, t, RPAREN)
 			}
 			col.SetLength(tlen)
 		default:
 			ctx.rewind()
+			ctx.expectOptional(t, LPAREN)
 		}
 
 		// optional sort direction
@@ -1357,6 +3079,8 @@ OUTER:
 		case DESC:
 			ctx.advance()
 			col.SetSortDirection(model.SortDirectionDescending)
+		default:
+			ctx.expectOptional(t, ASC, DESC)
 		}
 
 		ctx.skipWhiteSpaces()
@@ -1367,7 +3091,7 @@ OUTER:
 		case RPAREN:
 			break OUTER
 		default:
-			return newParseError(ctx, t, "expected COMMA or RPAREN")
+			return newExpectedError(ctx, t, COMMA, RPAREN)
 		}
 	}
 
@@ -1375,6 +3099,25 @@ OUTER:
 	return nil
 }
 
+// requireVersion returns an error if the target MySQL version is
+// earlier than the one that introduced the feature. Versions are not
+// checked for the MariaDB and TiDB dialects.
+func (pctx *parseCtx) requireVersion(t *Token, feature string, major, minor, patch int) error {
+	if pctx.dialect == DialectMariaDB || pctx.dialect == DialectTiDB || pctx.version.AtLeast(major, minor, patch) {
+		return nil
+	}
+	return newParseError(pctx, t, "%s requires MySQL %d.%d.%d or later (targeting %s)", feature, major, minor, patch, pctx.version)
+}
+
+// requireCollation returns an error if the collation was introduced
+// after the target MySQL version, such as utf8mb4_0900_ai_ci
+func (pctx *parseCtx) requireCollation(t *Token) error {
+	if !strings.Contains(strings.ToLower(t.value(pctx.input)), "_0900_") {
+		return nil
+	}
+	return pctx.requireVersion(t, "collation "+t.value(pctx.input), 8, 0, 0)
+}
+
 // Skips over whitespaces. Once this method returns, you can be
 // certain that next call to ctx.next()/peek() will result in a
 // non-space token
@@ -1396,9 +3139,9 @@ func (p *Parser) parseIdents(ctx *parseCtx, idents ...TokenType) ([]string, erro
 		ctx.skipWhiteSpaces()
 		t := ctx.next()
 		if t.Type != ident {
-			return nil, newParseError(ctx, t, "expected %v", idents)
+			return nil, newExpectedError(ctx, t, ident)
 		}
-		strs = append(strs, t.Value)
+		strs = append(strs, t.value(ctx.input))
 	}
 	return strs, nil
 }
@@ -2,14 +2,19 @@ package schemalex_test
 
 import (
 	"bytes"
+	"context"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/pmezard/go-difflib/difflib"
 	"github.com/eihigh/schemalex"
 	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,7 +105,7 @@ primary key (id, c)
 	})
 	parse("WithKeyAndIndex", &Spec{
 		Input:  "create table hoge ( `id` bigint unsigned not null auto_increment,\n `c` varchar(20) not null,\nKEY (`id`), INDEX (`c`)\n)",
-		Expect: "CREATE TABLE `hoge` (\n`id` BIGINT (20) UNSIGNED NOT NULL AUTO_INCREMENT,\n`c` VARCHAR (20) NOT NULL,\nINDEX (`id`),\nINDEX (`c`)\n)",
+		Expect: "CREATE TABLE `hoge` (\n`id` BIGINT (20) UNSIGNED NOT NULL AUTO_INCREMENT,\n`c` VARCHAR (20) NOT NULL,\nINDEX `id` (`id`),\nINDEX `c` (`c`)\n)",
 	})
 	parse("WithUniqueKeyPrimaryKey", &Spec{
 		Input:  "create table hoge ( `id` bigint unsigned not null auto_increment,\n `c` varchar(20) not null,\nUNIQUE INDEX `uniq_id` (`id`, `c`),\n PRIMARY KEY (`id`)\n )",
@@ -114,6 +119,18 @@ primary key (id, c)
 		Input:  "create table hoge (txt TEXT, fulltext ft_idx(txt))",
 		Expect: "CREATE TABLE `hoge` (\n`txt` TEXT,\nFULLTEXT INDEX `ft_idx` (`txt`)\n)",
 	})
+	parse("WithFulltextIndexParser", &Spec{
+		Input:  "create table hoge (txt TEXT, fulltext key ft (txt) with parser ngram)",
+		Expect: "CREATE TABLE `hoge` (\n`txt` TEXT,\nFULLTEXT INDEX `ft` (`txt`) WITH PARSER ngram\n)",
+	})
+	parse("WithSpatialIndex", &Spec{
+		Input:  "create table hoge (g geometry not null, point point not null, spatial key sp_g (g), spatial index (point))",
+		Expect: "CREATE TABLE `hoge` (\n`g` GEOMETRY NOT NULL,\n`point` POINT NOT NULL,\nSPATIAL INDEX `sp_g` (`g`),\nSPATIAL INDEX `point` (`point`)\n)",
+	})
+	parse("WithSRID", &Spec{
+		Input:  "create table hoge (g point not null srid 4326, h geometry srid 0 not null, spatial key sp_g (g))",
+		Expect: "CREATE TABLE `hoge` (\n`g` POINT NOT NULL SRID 4326,\n`h` GEOMETRY NOT NULL SRID 0,\nSPATIAL INDEX `sp_g` (`g`)\n)",
+	})
 	parse("WithSimpleReferenceForeignKey", &Spec{
 		Input:  "create table hoge ( `id` bigint unsigned not null auto_increment,\n `c` varchar(20) not null,\nFOREIGN KEY `fk_c` (`c`) REFERENCES `fuga` (`id`) )",
 		Expect: "CREATE TABLE `hoge` (\n`id` BIGINT (20) UNSIGNED NOT NULL AUTO_INCREMENT,\n`c` VARCHAR (20) NOT NULL,\nFOREIGN KEY `fk_c` (`c`) REFERENCES `fuga` (`id`)\n)",
@@ -142,6 +159,22 @@ primary key (id, c)
 		Input: "create table hoge ( `id` bigint unsigned not null auto_increment,\n `c` varchar(20) not null,\nFOREIGN KEY `fk_c` (`c`) REFERENCES `fuga` (`id`) HOGE )",
 		Error: true,
 	})
+	parse("ColumnOptionsInAnyOrder", &Spec{
+		Input:  "create table hoge (`a` INT DEFAULT 0 NOT NULL, `b` INT ZEROFILL UNSIGNED COMMENT 'b', `c` VARCHAR(10) NOT NULL COLLATE utf8mb4_bin DEFAULT '')",
+		Expect: "CREATE TABLE `hoge` (\n`a` INT (11) NOT NULL DEFAULT 0,\n`b` INT (10) UNSIGNED ZEROFILL DEFAULT NULL COMMENT 'b',\n`c` VARCHAR (10) COLLATE `utf8mb4_bin` NOT NULL DEFAULT ''\n)",
+	})
+	parse("ColumnSizeAfterOptionsGotError", &Spec{
+		Input: "create table hoge (`a` INT NOT NULL (11))",
+		Error: true,
+	})
+	parse("DuplicateColumnOptionGotError", &Spec{
+		Input: "create table hoge (`a` INT DEFAULT 0 NOT NULL DEFAULT 1)",
+		Error: true,
+	})
+	parse("ConflictingNullGotError", &Spec{
+		Input: "create table hoge (`a` INT NULL NOT NULL)",
+		Error: true,
+	})
 	parse("DecimalNotDefault", &Spec{
 		Input:  "create table hoge (`foo` DECIMAL(32,30))",
 		Expect: "CREATE TABLE `hoge` (\n`foo` DECIMAL (32,30) DEFAULT NULL\n)",
@@ -173,7 +206,12 @@ primary key (id, c)
 	parse("ColumnOptionPrimaryKey", &Spec{
 		// see https://github.com/eihigh/schemalex/pull/40
 		Input:  "CREATE TABLE foo (id INTEGER PRIMARY KEY AUTO_INCREMENT)",
-		Expect: "CREATE TABLE `foo` (\n`id` INT (11) DEFAULT NULL AUTO_INCREMENT,\nPRIMARY KEY (`id`)\n)",
+		Expect: "CREATE TABLE `foo` (\n`id` INT (11) NOT NULL AUTO_INCREMENT,\nPRIMARY KEY (`id`)\n)",
+	})
+	parse("ColumnOptionKey", &Spec{
+		// KEY alone declares the primary key too
+		Input:  "CREATE TABLE foo (id INTEGER KEY, a INT)",
+		Expect: "CREATE TABLE `foo` (\n`id` INT (11) NOT NULL,\n`a` INT (11) DEFAULT NULL,\nPRIMARY KEY (`id`)\n)",
 	})
 	parse("ColumnOptionCommentPrimaryKey1", &Spec{
 		// see https://github.com/eihigh/schemalex/pull/40
@@ -268,6 +306,14 @@ primary key (id, c)
 		Input:  "create table `test_log` (`created_at` DATETIME default NOW())",
 		Expect: "CREATE TABLE `test_log` (\n`created_at` DATETIME DEFAULT NOW()\n)",
 	})
+	parse("DefaultCurrentTimestampSynonyms", &Spec{
+		Input:  "create table `test_log` (`a` DATETIME(3) default now(3) on update localtime (3), `b` DATETIME default localtimestamp on update now(), `c` TIMESTAMP(6) default current_timestamp (6), `d` DATETIME default localtime())",
+		Expect: "CREATE TABLE `test_log` (\n`a` DATETIME (3) ON UPDATE LOCALTIME(3) DEFAULT NOW(3),\n`b` DATETIME ON UPDATE NOW() DEFAULT LOCALTIMESTAMP,\n`c` TIMESTAMP (6) DEFAULT CURRENT_TIMESTAMP(6),\n`d` DATETIME DEFAULT LOCALTIME\n)",
+	})
+	parse("DefaultNowWithoutParenthesesGotError", &Spec{
+		Input: "create table `test_log` (`created_at` DATETIME default NOW)",
+		Error: true,
+	})
 
 	parse("GithubIssue79", &Spec{
 		Input: "CREATE TABLE `test_tb` (" +
@@ -319,6 +365,458 @@ c varchar(20) default 'hoge''s'
 		Input:  "CREATE TABLE `test` (\n`status` SET('foo\\'', 'bar''', 'baz') NOT NULL DEFAULT 'foo,baz'\n);",
 		Expect: "CREATE TABLE `test` (\n`status` SET ('foo\\'','bar\\'','baz') NOT NULL DEFAULT 'foo,baz'\n)",
 	})
+	parse("PartitionByRange", &Spec{
+		Input:  "CREATE TABLE `log` ( `id` INT NOT NULL, `created` DATE NOT NULL ) ENGINE=InnoDB PARTITION BY RANGE ( TO_DAYS(`created`) ) ( PARTITION p20200101 VALUES LESS THAN (TO_DAYS('2020-01-01')) COMMENT = 'first', PARTITION pmax VALUES LESS THAN MAXVALUE ENGINE = InnoDB )",
+		Expect: "CREATE TABLE `log` (\n`id` INT (11) NOT NULL,\n`created` DATE NOT NULL\n) ENGINE = InnoDB\nPARTITION BY RANGE (TO_DAYS(`created`)) (\nPARTITION `p20200101` VALUES LESS THAN (TO_DAYS('2020-01-01')) COMMENT = 'first',\nPARTITION `pmax` VALUES LESS THAN MAXVALUE ENGINE = InnoDB\n)",
+	})
+	parse("PartitionByRangeColumns", &Spec{
+		Input:  "CREATE TABLE `log` ( `a` INT NOT NULL, `b` INT NOT NULL ) PARTITION BY RANGE COLUMNS(a, `b`) ( PARTITION p0 VALUES LESS THAN (10, 20), PARTITION p1 VALUES LESS THAN (MAXVALUE, MAXVALUE) );",
+		Expect: "CREATE TABLE `log` (\n`a` INT (11) NOT NULL,\n`b` INT (11) NOT NULL\n)\nPARTITION BY RANGE COLUMNS (`a`, `b`) (\nPARTITION `p0` VALUES LESS THAN (10, 20),\nPARTITION `p1` VALUES LESS THAN (MAXVALUE, MAXVALUE)\n)",
+	})
+	parse("PartitionByList", &Spec{
+		Input:  "CREATE TABLE `log` ( `region` INT NOT NULL ) PARTITION BY LIST (`region`) ( PARTITION east VALUES IN (1, 2), PARTITION west VALUES IN (3) )",
+		Expect: "CREATE TABLE `log` (\n`region` INT (11) NOT NULL\n)\nPARTITION BY LIST (`region`) (\nPARTITION `east` VALUES IN (1, 2),\nPARTITION `west` VALUES IN (3)\n)",
+	})
+	parse("PartitionByLinearHash", &Spec{
+		Input:  "CREATE TABLE `log` ( `id` INT NOT NULL ) PARTITION BY LINEAR HASH (`id` DIV 2) PARTITIONS 4",
+		Expect: "CREATE TABLE `log` (\n`id` INT (11) NOT NULL\n)\nPARTITION BY LINEAR HASH (`id` DIV 2) PARTITIONS 4",
+	})
+	parse("PartitionByKey", &Spec{
+		Input:  "CREATE TABLE `log` ( `id` INT NOT NULL, PRIMARY KEY (`id`) ) PARTITION BY KEY () PARTITIONS 2",
+		Expect: "CREATE TABLE `log` (\n`id` INT (11) NOT NULL,\nPRIMARY KEY (`id`)\n)\nPARTITION BY KEY () PARTITIONS 2",
+	})
+	parse("PartitionByLinearRange", &Spec{
+		Input: "CREATE TABLE `log` ( `id` INT NOT NULL ) PARTITION BY LINEAR RANGE (`id`) ( PARTITION p0 VALUES LESS THAN (10) )",
+		Error: true,
+	})
+	parse("PartitionValuesInForRange", &Spec{
+		Input: "CREATE TABLE `log` ( `id` INT NOT NULL ) PARTITION BY RANGE (`id`) ( PARTITION p0 VALUES IN (10) )",
+		Error: true,
+	})
+	parse("PartitionUnterminatedExpression", &Spec{
+		Input: "CREATE TABLE `log` ( `id` INT NOT NULL ) PARTITION BY HASH (`id`",
+		Error: true,
+	})
+	parse("UpdateHistogram", &Spec{
+		Input:  "ANALYZE TABLE `log` UPDATE HISTOGRAM ON `created` WITH 32 BUCKETS;",
+		Expect: "ANALYZE TABLE `log` UPDATE HISTOGRAM ON `created` WITH 32 BUCKETS",
+	})
+	parse("UpdateHistogramWithoutBuckets", &Spec{
+		Input:  "ANALYZE TABLE log UPDATE HISTOGRAM ON created",
+		Expect: "ANALYZE TABLE `log` UPDATE HISTOGRAM ON `created`",
+	})
+	parse("AnalyzeTableIgnored", &Spec{
+		Input:  "ANALYZE TABLE `log`, `user`; ANALYZE TABLE `log` DROP HISTOGRAM ON `created`; ANALYZE NO_WRITE_TO_BINLOG TABLE `log`;",
+		Expect: "",
+	})
+	parse("UpdateHistogramMissingBuckets", &Spec{
+		Input: "ANALYZE TABLE `log` UPDATE HISTOGRAM ON `created` WITH 32",
+		Error: true,
+	})
+	parse("DoubleQuotedColumnComment", &Spec{
+		Input:  "CREATE TABLE `t` (`a` INT COMMENT \"it's \"\"a\"\"\", `b` INT COMMENT \"b\" NOT NULL)",
+		Expect: "CREATE TABLE `t` (\n`a` INT (11) DEFAULT NULL COMMENT 'it\\'s \"a\"',\n`b` INT (11) NOT NULL COMMENT 'b'\n)",
+	})
+	parse("CreateTemporaryTable", &Spec{
+		Input:  "CREATE TEMPORARY TABLE IF NOT EXISTS `tmp` (`a` INT)",
+		Expect: "CREATE TEMPORARY TABLE IF NOT EXISTS `tmp` (\n`a` INT (11) DEFAULT NULL\n)",
+	})
+	parse("CreateTableTemporaryGotError", &Spec{
+		Input: "CREATE TABLE TEMPORARY `tmp` (`a` INT)",
+		Error: true,
+	})
+	parse("RowFormatCaseInsensitive", &Spec{
+		Input:  "CREATE TABLE `t` (`a` INT) ENGINE=InnoDB ROW_FORMAT=compressed",
+		Expect: "CREATE TABLE `t` (\n`a` INT (11) DEFAULT NULL\n) ENGINE = InnoDB, ROW_FORMAT = COMPRESSED",
+	})
+	parse("RowFormatPageGotError", &Spec{
+		Input: "CREATE TABLE `t` (`a` INT) ENGINE=Aria ROW_FORMAT=PAGE",
+		Error: true,
+	})
+	parse("MultiByteCharacters", &Spec{
+		Input:  "/* 寿司 🍣 */ CREATE TABLE `寿司` (名前 VARCHAR (20) NOT NULL DEFAULT '🍣' COMMENT 'ネタ 🐟', `ü` INT, KEY `索引` (名前)) COMMENT 'お品書き 📜'",
+		Expect: "CREATE TABLE `寿司` (\n`名前` VARCHAR (20) NOT NULL DEFAULT '🍣' COMMENT 'ネタ 🐟',\n`ü` INT (11) DEFAULT NULL,\nINDEX `索引` (`名前`)\n) COMMENT = 'お品書き 📜'",
+	})
+	parse("CreateView", &Spec{
+		Input:  "create view v as select id, name from hoge where id > 10 /* big */ ;",
+		Expect: "CREATE VIEW `v` AS select id, name from hoge where id > 10",
+	})
+	parse("CreateViewWithOptions", &Spec{
+		Input:  "CREATE OR REPLACE ALGORITHM=merge DEFINER=`root`@`localhost` SQL SECURITY invoker VIEW `v` (a, `b`) AS SELECT id, 'x;y' FROM hoge WITH LOCAL CHECK OPTION",
+		Expect: "CREATE ALGORITHM = MERGE DEFINER = `root`@`localhost` SQL SECURITY INVOKER VIEW `v` (`a`, `b`) AS SELECT id, 'x;y' FROM hoge WITH LOCAL CHECK OPTION",
+	})
+	parse("CreateViewWithCheckOption", &Spec{
+		Input:  "CREATE VIEW v AS SELECT * FROM hoge WITH CHECK OPTION",
+		Expect: "CREATE VIEW `v` AS SELECT * FROM hoge WITH CASCADED CHECK OPTION",
+	})
+	parse("CreateViewWithoutSelectGotError", &Spec{
+		Input: "CREATE VIEW v AS;",
+		Error: true,
+	})
+	parse("CreateTrigger", &Spec{
+		Input:  "create definer = CURRENT_USER trigger `db`.trg before update on `hoge` for each row set NEW.updated = now() -- done\n;",
+		Expect: "CREATE DEFINER = CURRENT_USER TRIGGER `trg` before update on `hoge` for each row set NEW.updated = now()",
+	})
+	parse("CreateProcedureWithDelimiter", &Spec{
+		Input:  "DELIMITER //\nCREATE PROCEDURE IF NOT EXISTS p (IN a INT)\nBEGIN\n  IF a > 0 THEN SELECT ';'; END IF;\nEND //\nDELIMITER ;\n",
+		Expect: "CREATE PROCEDURE `p` (IN a INT)\nBEGIN\n  IF a > 0 THEN SELECT ';'; END IF;\nEND",
+	})
+	parse("CreateFunction", &Spec{
+		Input:  "CREATE FUNCTION hello (s CHAR(20)) RETURNS CHAR(50) DETERMINISTIC RETURN CONCAT('Hello, ', s, '!');",
+		Expect: "CREATE FUNCTION `hello` (s CHAR(20)) RETURNS CHAR(50) DETERMINISTIC RETURN CONCAT('Hello, ', s, '!')",
+	})
+	parse("CreateProcedureWithoutDelimiterGotError", &Spec{
+		Input: "CREATE PROCEDURE p () BEGIN SELECT 1; END;",
+		Error: true,
+	})
+	parse("CreateTriggerWithoutEventGotError", &Spec{
+		Input: "CREATE TRIGGER trg BEFORE ON hoge FOR EACH ROW SET NEW.a = 1;",
+		Error: true,
+	})
+	parse("DelimiterWithoutValueGotError", &Spec{
+		Input: "DELIMITER\nCREATE TABLE hoge (a INT);",
+		Error: true,
+	})
+	parse("CreateOrReplaceTableGotError", &Spec{
+		Input: "CREATE OR REPLACE TABLE t (a INT)",
+		Error: true,
+	})
+}
+
+func TestParseColumnAttributes(t *testing.T) {
+	specs := []struct {
+		Column string
+		Expect string
+		Index  string
+	}{
+		{Column: "INT UNSIGNED", Expect: "INT (10) UNSIGNED DEFAULT NULL"},
+		{Column: "INT ZEROFILL", Expect: "INT (11) ZEROFILL DEFAULT NULL"},
+		{Column: "VARCHAR(10) BINARY", Expect: "VARCHAR (10) BINARY DEFAULT NULL"},
+		{Column: "VARCHAR(10) CHARACTER SET utf8mb4", Expect: "VARCHAR (10) CHARACTER SET `utf8mb4` DEFAULT NULL"},
+		{Column: "VARCHAR(10) COLLATE utf8mb4_bin", Expect: "VARCHAR (10) COLLATE `utf8mb4_bin` DEFAULT NULL"},
+		{Column: "INT NOT NULL", Expect: "INT (11) NOT NULL"},
+		{Column: "INT NULL", Expect: "INT (11) DEFAULT NULL"},
+		{Column: "DATETIME ON UPDATE CURRENT_TIMESTAMP", Expect: "DATETIME ON UPDATE CURRENT_TIMESTAMP DEFAULT NULL"},
+		{Column: "INT DEFAULT 1", Expect: "INT (11) DEFAULT 1"},
+		{Column: "INT AUTO_INCREMENT", Expect: "INT (11) DEFAULT NULL AUTO_INCREMENT"},
+		{Column: "INT UNIQUE", Expect: "INT (11) DEFAULT NULL", Index: "UNIQUE INDEX `a` (`a`)"},
+		{Column: "INT UNIQUE KEY", Expect: "INT (11) DEFAULT NULL", Index: "UNIQUE INDEX `a` (`a`)"},
+		{Column: "INT UNIQUE NOT NULL", Expect: "INT (11) NOT NULL", Index: "UNIQUE INDEX `a` (`a`)"},
+		{Column: "INT UNIQUE KEY NOT NULL", Expect: "INT (11) NOT NULL", Index: "UNIQUE INDEX `a` (`a`)"},
+		{Column: "INT KEY", Expect: "INT (11) NOT NULL", Index: "PRIMARY KEY (`a`)"},
+		{Column: "INT PRIMARY KEY", Expect: "INT (11) NOT NULL", Index: "PRIMARY KEY (`a`)"},
+		{Column: "INT COMMENT 'c'", Expect: "INT (11) DEFAULT NULL COMMENT 'c'"},
+	}
+
+	// each attribute is followed either by another column, without a
+	// space, or by the end of the table, so that a token that is
+	// skipped by mistake is noticed
+	for _, spec := range specs {
+		for _, last := range []bool{false, true} {
+			input := "CREATE TABLE `hoge` (`a` " + spec.Column
+			expect := "CREATE TABLE `hoge` (\n`a` " + spec.Expect
+			if last {
+				input += ")"
+			} else {
+				input += ",`b` INT)"
+				expect += ",\n`b` INT (11) DEFAULT NULL"
+			}
+			if spec.Index != "" {
+				expect += ",\n" + spec.Index
+			}
+			expect += "\n)"
+			testParse(t, &Spec{Input: input, Expect: expect})
+		}
+	}
+
+	invalid := []string{
+		// the keywords that must follow are missing
+		"INT NOT",
+		"INT NOT DEFAULT 1",
+		"INT PRIMARY",
+		"INT PRIMARY NOT NULL",
+		"VARCHAR(10) CHARACTER utf8mb4",
+		"DATETIME ON CURRENT_TIMESTAMP",
+		// the attributes do not apply to the type
+		"VARCHAR(10) UNSIGNED",
+		"INT BINARY",
+		// the attributes are given twice
+		"INT UNSIGNED UNSIGNED",
+		"INT ZEROFILL ZEROFILL",
+		"VARCHAR(10) BINARY BINARY",
+		"VARCHAR(10) CHARACTER SET utf8mb4 CHARACTER SET utf8mb4",
+		"VARCHAR(10) COLLATE utf8mb4_bin COLLATE utf8mb4_bin",
+		"INT NOT NULL NOT NULL",
+		"INT NULL NULL",
+		"DATETIME ON UPDATE CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP",
+		"INT DEFAULT 1 DEFAULT 1",
+		"INT AUTO_INCREMENT AUTO_INCREMENT",
+		"INT UNIQUE UNIQUE",
+		"INT UNIQUE KEY UNIQUE KEY",
+		"INT KEY KEY",
+		"INT PRIMARY KEY KEY",
+		"INT PRIMARY KEY PRIMARY KEY",
+		"INT COMMENT 'c' COMMENT 'c'",
+	}
+	for _, column := range invalid {
+		testParse(t, &Spec{Input: "CREATE TABLE `hoge` (`a` " + column + ")", Error: true})
+	}
+
+	// the keyword of an index is not followed by a space
+	testParse(t, &Spec{
+		Input:  "CREATE TABLE `hoge` (`a` INT,KEY(`a`),INDEX`i`(`a`))",
+		Expect: "CREATE TABLE `hoge` (\n`a` INT (11) DEFAULT NULL,\nINDEX `a` (`a`),\nINDEX `i` (`a`)\n)",
+	})
+}
+
+func TestParseExplicitDefaultsForTimestamp(t *testing.T) {
+	const src = "CREATE TABLE `t` (`a` TIMESTAMP, `b` TIMESTAMP(3), `c` TIMESTAMP NULL, `d` TIMESTAMP DEFAULT '2000-01-01 00:00:00', `e` DATETIME)"
+	const explicit = "CREATE TABLE `t` (\n" +
+		"`a` TIMESTAMP DEFAULT NULL,\n" +
+		"`b` TIMESTAMP (3) DEFAULT NULL,\n" +
+		"`c` TIMESTAMP DEFAULT NULL,\n" +
+		"`d` TIMESTAMP DEFAULT '2000-01-01 00:00:00',\n" +
+		"`e` DATETIME DEFAULT NULL\n" +
+		")"
+	const legacy = "CREATE TABLE `t` (\n" +
+		"`a` TIMESTAMP ON UPDATE CURRENT_TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"`b` TIMESTAMP (3) NOT NULL DEFAULT '0000-00-00 00:00:00.000',\n" +
+		"`c` TIMESTAMP DEFAULT NULL,\n" +
+		"`d` TIMESTAMP NOT NULL DEFAULT '2000-01-01 00:00:00',\n" +
+		"`e` DATETIME DEFAULT NULL\n" +
+		")"
+
+	specs := []struct {
+		Options []schemalex.Option
+		Expect  string
+	}{
+		{Expect: explicit},
+		{Options: []schemalex.Option{schemalex.WithMySQLVersion(schemalex.MySQLVersion{Major: 8, Minor: 0, Patch: 2})}, Expect: explicit},
+		{Options: []schemalex.Option{schemalex.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 7, Patch: 44})}, Expect: legacy},
+		{Options: []schemalex.Option{schemalex.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 7, Patch: 44}), schemalex.WithExplicitDefaultsForTimestamp(true)}, Expect: explicit},
+		{Options: []schemalex.Option{schemalex.WithExplicitDefaultsForTimestamp(false)}, Expect: legacy},
+		{Options: []schemalex.Option{schemalex.WithDialect(schemalex.DialectMariaDB), schemalex.WithMySQLVersion(schemalex.MySQLVersion{Major: 5, Minor: 7})}, Expect: explicit},
+	}
+	for _, spec := range specs {
+		stmts, err := schemalex.NewParser(spec.Options...).ParseString(src)
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+		var buf bytes.Buffer
+		if !assert.NoError(t, format.SQL(&buf, stmts), "format should succeed") {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, buf.String(), "the columns should match") {
+			return
+		}
+	}
+
+	// the first TIMESTAMP column is not initialized if it declares ON
+	// UPDATE, and the others never are
+	p := schemalex.NewParser(schemalex.WithExplicitDefaultsForTimestamp(false))
+	stmts, err := p.ParseString("CREATE TABLE `t` (`a` TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, `b` TIMESTAMP)")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts), "format should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TABLE `t` (\n`a` TIMESTAMP ON UPDATE CURRENT_TIMESTAMP NOT NULL DEFAULT '0000-00-00 00:00:00',\n`b` TIMESTAMP NOT NULL DEFAULT '0000-00-00 00:00:00'\n)", buf.String(), "the columns should match") {
+		return
+	}
+}
+
+func TestParseHistogram(t *testing.T) {
+	p := schemalex.New()
+	stmts, err := p.ParseString("ANALYZE TABLE `log` UPDATE HISTOGRAM ON `a`, b WITH 16 BUCKETS;")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 2, "there should be one histogram per column") {
+		return
+	}
+
+	for i, name := range []string{"a", "b"} {
+		h, ok := stmts[i].(model.Histogram)
+		if !assert.True(t, ok, "statement should be a model.Histogram") {
+			return
+		}
+		if !assert.Equal(t, "log", h.TableName(), "table name should match") {
+			return
+		}
+		if !assert.Equal(t, name, h.ColumnName(), "column name should match") {
+			return
+		}
+		if !assert.Equal(t, 16, h.Buckets(), "buckets should match") {
+			return
+		}
+	}
+}
+
+func TestParseDrop(t *testing.T) {
+	const src = "CREATE TABLE foo (id INT, CONSTRAINT fk_bar FOREIGN KEY (id) REFERENCES bar (id));\n" +
+		"CREATE TABLE bar (id INT);\n" +
+		"ANALYZE TABLE foo UPDATE HISTOGRAM ON id;\n" +
+		"DROP TABLE IF EXISTS foo, baz CASCADE;\n" +
+		"CREATE TABLE foo (id BIGINT, CONSTRAINT fk_bar FOREIGN KEY (id) REFERENCES bar (id));\n" +
+		"DROP TEMPORARY TABLE bar;\n"
+
+	var warnings []string
+	p := schemalex.NewParser(schemalex.WithWarningHandler(func(w schemalex.Warning) {
+		warnings = append(warnings, w.String())
+	}))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Empty(t, warnings, "dropped tables should not be reported as duplicates") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts), "format.SQL should succeed") {
+		return
+	}
+	expect := "CREATE TABLE `bar` (\n`id` INT (11) DEFAULT NULL\n)" +
+		"CREATE TABLE `foo` (\n`id` BIGINT (20) DEFAULT NULL,\nINDEX `fk_bar` (`id`),\nCONSTRAINT `fk_bar` FOREIGN KEY (`id`) REFERENCES `bar` (`id`)\n)"
+	if !assert.Equal(t, expect, buf.String(), "the tables should be the recreated ones") {
+		return
+	}
+
+	stmts, err = p.ParseString(src + "DROP SCHEMA IF EXISTS app;\nCREATE TABLE baz (id INT);")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 1, "DROP DATABASE should drop all the tables") {
+		return
+	}
+	if !assert.Equal(t, "baz", stmts[0].(model.Table).Name(), "table name should match") {
+		return
+	}
+
+	stmts, err = p.ParseString("CREATE VIEW v AS SELECT 1;\n" +
+		"CREATE VIEW w AS SELECT 1;\n" +
+		"CREATE OR REPLACE VIEW v AS SELECT 2;\n" +
+		"DROP VIEW IF EXISTS w, x;\n")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 1, "DROP VIEW should drop the view") {
+		return
+	}
+	if !assert.Equal(t, "SELECT 2", stmts[0].(model.View).Definition(), "the view should be the replaced one") {
+		return
+	}
+
+	stmts, err = p.ParseString("CREATE TABLE foo (a INT);\n" +
+		"CREATE TRIGGER t1 BEFORE INSERT ON foo FOR EACH ROW SET NEW.a = 1;\n" +
+		"CREATE PROCEDURE t1 () SELECT 1;\n" +
+		"CREATE FUNCTION f () RETURNS INT RETURN 1;\n" +
+		"DROP FUNCTION IF EXISTS f;\n" +
+		"DROP TABLE foo;\n")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 1, "DROP FUNCTION and DROP TABLE should drop the function and the trigger") {
+		return
+	}
+	if !assert.Equal(t, model.RoutineKindProcedure, stmts[0].(model.Routine).Kind(), "the procedure should remain") {
+		return
+	}
+}
+
+func TestParseSQLMode(t *testing.T) {
+	const src = "/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO,ANSI_QUOTES' */;\n" +
+		"CREATE TABLE \"foo\" (\"id\" INT COMMENT 'a');\n" +
+		"/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n" +
+		"CREATE TABLE bar (id INT) COMMENT \"b\";\n" +
+		"SET SESSION sql_mode = 'ANSI', NAMES utf8mb4;\n" +
+		"CREATE TABLE \"baz\" (\"id\" INT);\n" +
+		"SET sql_mode = CONCAT(@@sql_mode, ',STRICT_ALL_TABLES');\n" +
+		"SET @@SESSION.sql_mode := DEFAULT;\n" +
+		"CREATE TABLE qux (id INT) COMMENT \"c\";\n"
+
+	var warnings []string
+	p := schemalex.NewParser(schemalex.WithWarningHandler(func(w schemalex.Warning) {
+		warnings = append(warnings, w.String())
+	}))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, []string{"warning: SET sql_mode to an expression is ignored at line 7 column 0"}, warnings, "warnings should match") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts), "format.SQL should succeed") {
+		return
+	}
+	expect := "CREATE TABLE `foo` (\n`id` INT (11) DEFAULT NULL COMMENT 'a'\n)" +
+		"CREATE TABLE `bar` (\n`id` INT (11) DEFAULT NULL\n) COMMENT = 'b'" +
+		"CREATE TABLE `baz` (\n`id` INT (11) DEFAULT NULL\n)" +
+		"CREATE TABLE `qux` (\n`id` INT (11) DEFAULT NULL\n) COMMENT = 'c'"
+	if !assert.Equal(t, expect, buf.String(), "double quotes should quote identifiers with ANSI_QUOTES") {
+		return
+	}
+
+	var streamed model.Stmts
+	err = p.ParseReader(context.Background(), strings.NewReader(src), func(stmt model.Stmt) error {
+		streamed = append(streamed, stmt)
+		return nil
+	})
+	if !assert.NoError(t, err, "streaming parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, stmts.String(), streamed.String(), "streamed statements should match") {
+		return
+	}
+
+	const ansi = "CREATE TABLE \"foo\" (\"id\" INT);"
+	if _, err := schemalex.New().ParseString(ansi); !assert.Error(t, err, "double quoted table names should be rejected by default") {
+		return
+	}
+	stmts, err = schemalex.NewParser(schemalex.WithSQLMode("ANSI")).ParseString(ansi)
+	if !assert.NoError(t, err, "parse should succeed with WithSQLMode") {
+		return
+	}
+	if !assert.Equal(t, "foo", stmts[0].(model.Table).Name(), "table name should match") {
+		return
+	}
+}
+
+func TestParseANSIQuotes(t *testing.T) {
+	const src = "CREATE TABLE \"users\" (\"id\" INT NOT NULL, \"say \"\"hi\"\"\" VARCHAR (10) COMMENT 'x', PRIMARY KEY (\"id\"));\n" +
+		"CREATE TABLE \"posts\" (\"user_id\" INT, CONSTRAINT \"fk\" FOREIGN KEY (\"user_id\") REFERENCES \"users\" (\"id\")) COMMENT 'p';"
+
+	p := schemalex.NewParser(schemalex.WithANSIQuotes(true))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts), "format.SQL should succeed") {
+		return
+	}
+	expect := "CREATE TABLE `users` (\n`id` INT (11) NOT NULL,\n`say \"hi\"` VARCHAR (10) DEFAULT NULL COMMENT 'x',\nPRIMARY KEY (`id`)\n)" +
+		"CREATE TABLE `posts` (\n`user_id` INT (11) DEFAULT NULL,\nINDEX `fk` (`user_id`),\nCONSTRAINT `fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)\n) COMMENT = 'p'"
+	if !assert.Equal(t, expect, buf.String(), "double quotes should quote identifiers") {
+		return
+	}
+
+	// SET sql_mode still applies
+	_, err = p.ParseString("SET sql_mode = '';\nCREATE TABLE \"foo\" (\"id\" INT);")
+	if !assert.Error(t, err, "double quotes should quote strings once sql_mode is reset") {
+		return
+	}
+
+	// ANSI_QUOTES is added to the SQL mode
+	p = schemalex.NewParser(schemalex.WithSQLMode("STRICT_TRANS_TABLES"), schemalex.WithANSIQuotes(true))
+	if _, err := p.ParseString("CREATE TABLE \"foo\" (\"id\" INT);"); !assert.NoError(t, err, "parse should succeed with both options") {
+		return
+	}
 }
 
 func testParse(t *testing.T, spec *Spec) {
@@ -404,6 +902,236 @@ func TestParseError2(t *testing.T) {
 	}
 }
 
+func TestParseErrorMultiByte(t *testing.T) {
+	const src = "CREATE TABLE `寿司` (`ネタ` VARCHAR (20) COMMENT '🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣' baz)"
+	p := schemalex.New()
+	_, err := p.ParseString(src)
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+
+	// the column and the context are counted in characters
+	expected := "parse error: unexpected column option IDENT at line 1 column 69\n    \"HAR (20) COMMENT '🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣🍣' \" <---- AROUND HERE"
+	if !assert.Equal(t, expected, err.Error(), "error matches") {
+		return
+	}
+}
+
+func TestParseErrorFileMarker(t *testing.T) {
+	const src = "-- schemalex:file foo.sql\nCREATE TABLE foo (id int PRIMARY KEY);\n\n-- schemalex:file bar.sql\n\nCREATE TABLE bar (id int PRIMARY KEY baz TEXT)"
+	p := schemalex.New()
+	_, err := p.ParseString(src)
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+
+	expected := "parse error: unexpected column option IDENT in file bar.sql at line 2 column 37\n    \"CREATE TABLE bar (id int PRIMARY KEY \" <---- AROUND HERE"
+	if !assert.Equal(t, expected, err.Error(), "error matches") {
+		return
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := schemalex.New().ParseStringContext(ctx, "CREATE TABLE foo (id int PRIMARY KEY);")
+	if !assert.Equal(t, context.Canceled, err, "parse should be canceled") {
+		return
+	}
+
+	stmts, err := schemalex.New().ParseContext(context.Background(), []byte("CREATE TABLE foo (id int PRIMARY KEY);"))
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 1, "there should be 1 statement") {
+		return
+	}
+}
+
+func TestParseErrorTolerance(t *testing.T) {
+	const src = "CREATE TABLE foo (id int PRIMARY KEY);\n" +
+		"CREATE TABLE bar (id int PRIMARY KEY baz TEXT);\n" +
+		"CREATE TABLE baz (id int PRIMARY KEY);\n" +
+		"FOO BAR;\n" +
+		"CREATE TABLE qux (id int PRIMARY KEY);\n"
+
+	p := schemalex.NewParser(schemalex.WithErrorTolerance(true))
+	stmts, err := p.ParseString(src)
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+	errs, ok := err.(schemalex.ParseErrors)
+	if !assert.True(t, ok, "err is ParseErrors") {
+		return
+	}
+	if !assert.Len(t, errs, 2, "there should be 2 errors") {
+		return
+	}
+	if !assert.Equal(t, 2, errs[0].Line(), "first error is on line 2") {
+		return
+	}
+	if !assert.Equal(t, 4, errs[1].Line(), "second error is on line 4") {
+		return
+	}
+
+	var names []string
+	for _, stmt := range stmts {
+		names = append(names, stmt.(model.Table).Name())
+	}
+	if !assert.Equal(t, []string{"foo", "baz", "qux"}, names, "the other statements should be parsed") {
+		return
+	}
+
+	_, err = schemalex.New().ParseString(src)
+	if _, ok := err.(schemalex.ParseError); !assert.True(t, ok, "parse should stop at the first error") {
+		return
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	const src = "SET NAMES utf8mb4;\n" +
+		"CREATE DATABASE foo;\n" +
+		"USE foo;\n" +
+		"DROP EVENT IF EXISTS bar;\n" +
+		"CREATE TABLE bar (id int PRIMARY KEY);\n" +
+		"INSERT INTO bar VALUES (1);\n"
+
+	var warnings []string
+	p := schemalex.NewParser(schemalex.WithWarningHandler(func(w schemalex.Warning) {
+		warnings = append(warnings, w.String())
+	}))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 1, "there should be 1 statement") {
+		return
+	}
+
+	expected := []string{
+		"warning: SET statement is ignored at line 1 column 1",
+		"warning: CREATE DATABASE statement is ignored at line 2 column 0",
+		"warning: USE statement is ignored at line 3 column 0",
+		"warning: DROP statement is ignored at line 4 column 0",
+	}
+	if !assert.Equal(t, expected, warnings, "warnings should match") {
+		return
+	}
+}
+
+func TestParseDuplicates(t *testing.T) {
+	const src = "CREATE TABLE foo (\n" +
+		"  id INT,\n" +
+		"  ID INT,\n" +
+		"  a INT,\n" +
+		"  PRIMARY KEY (id),\n" +
+		"  PRIMARY KEY (a),\n" +
+		"  KEY idx_a (a),\n" +
+		"  UNIQUE KEY idx_a (a),\n" +
+		"  CONSTRAINT fk_bar FOREIGN KEY (a) REFERENCES bar (id)\n" +
+		");\n" +
+		"CREATE TABLE bar (id INT, CONSTRAINT fk_bar FOREIGN KEY (id) REFERENCES foo (a));\n" +
+		"CREATE TABLE IF NOT EXISTS bar (id INT);\n" +
+		"CREATE TABLE foo (id INT);\n" +
+		"CREATE TABLE baz (id INT PRIMARY KEY, a INT KEY, PRIMARY KEY (id));\n"
+
+	var warnings []string
+	p := schemalex.NewParser(schemalex.WithWarningHandler(func(w schemalex.Warning) {
+		warnings = append(warnings, w.String())
+	}))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, 5, "there should be 5 statements") {
+		return
+	}
+
+	expected := []string{
+		"warning: duplicate column `ID` in table `foo` (first defined at line 2 column 2) at line 3 column 2",
+		"warning: duplicate primary key in table `foo` (first defined at line 5 column 2) at line 6 column 2",
+		"warning: duplicate index `idx_a` in table `foo` (first defined at line 7 column 2) at line 8 column 2",
+		"warning: duplicate foreign key constraint `fk_bar` (first defined at line 9 column 2) at line 11 column 26",
+		"warning: duplicate table `foo` (first defined at line 1 column 14) at line 13 column 13",
+		"warning: duplicate primary key in table `baz` (first defined at line 14 column 18) at line 14 column 38",
+		"warning: duplicate primary key in table `baz` (first defined at line 14 column 18) at line 14 column 49",
+	}
+	if !assert.Equal(t, expected, warnings, "warnings should match") {
+		return
+	}
+
+	p = schemalex.NewParser(schemalex.WithDuplicateErrors(true), schemalex.WithErrorTolerance(true))
+	stmts, err = p.ParseString(src)
+	errs, ok := err.(schemalex.ParseErrors)
+	if !assert.True(t, ok, "parse should return schemalex.ParseErrors") {
+		return
+	}
+	// the first table is skipped at the first duplicate
+	if !assert.Len(t, errs, 3, "there should be 3 errors") {
+		return
+	}
+	if !assert.Equal(t, "duplicate column `ID` in table `foo` (first defined at line 2 column 2)", errs[0].Message(), "message should match") {
+		return
+	}
+	if !assert.Equal(t, 3, errs[0].Line(), "line should match") {
+		return
+	}
+	if !assert.Equal(t, "duplicate table `foo` (first defined at line 1 column 14)", errs[1].Message(), "message should match") {
+		return
+	}
+	if !assert.Equal(t, "duplicate primary key in table `baz` (first defined at line 14 column 18)", errs[2].Message(), "message should match") {
+		return
+	}
+	if !assert.Len(t, stmts, 2, "only the statements without duplicates should be parsed") {
+		return
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	specs := []struct {
+		Input   string
+		Message string
+	}{
+		{
+			Input:   "CREATE TABLE foo (a TEXT DEFAULT 'unterminated",
+			Message: "unterminated quoted string",
+		},
+		{
+			Input:   "CREATE TABLE `foo",
+			Message: "unterminated quoted string",
+		},
+		{
+			Input:   "CREATE TABLE foo (id INT);\n/* CREATE TABLE bar (id INT);",
+			Message: "unterminated comment",
+		},
+		{
+			Input:   "CREATE TABLE foo (id INT);\x00CREATE TABLE bar (id INT);",
+			Message: `unexpected character "\x00"`,
+		},
+		{
+			Input:   "CREATE TABLE foo (\x01 INT)",
+			Message: `unexpected character "\x01"`,
+		},
+		{
+			Input:   "CREATE TABLE foo (a DECIMAL(10,",
+			Message: "expected NUMBER (decimal size `D`)",
+		},
+	}
+
+	p := schemalex.New()
+	for _, spec := range specs {
+		_, err := p.ParseString(spec.Input)
+		pe, ok := err.(schemalex.ParseError)
+		if !assert.True(t, ok, "err is a ParseError for %q", spec.Input) {
+			return
+		}
+		if !assert.Equal(t, spec.Message, pe.Message(), "message should match for %q", spec.Input) {
+			return
+		}
+	}
+}
+
 func TestParseFileError(t *testing.T) {
 	f, err := ioutil.TempFile("", "schemalex-file")
 	if !assert.NoError(t, err, "creating tempfile should succeed") {
@@ -435,3 +1163,195 @@ func TestParseFileError(t *testing.T) {
 		return
 	}
 }
+
+func TestParseErrorDetails(t *testing.T) {
+	_, err := schemalex.New().ParseString("CREATE TABLE foo (id INT);\nCREATE\n  SEQUENCE bar;")
+	pe, ok := err.(schemalex.ParseError)
+	if !assert.True(t, ok, "err is a ParseError") {
+		return
+	}
+	if !assert.Equal(t, "SEQUENCE", pe.Token().Value, "offending token should match") {
+		return
+	}
+	if !assert.Equal(t, []schemalex.TokenType{schemalex.DATABASE, schemalex.TABLE}, pe.Expected(), "expected tokens should match") {
+		return
+	}
+	if !assert.Equal(t, "expected DATABASE or TABLE", pe.Message(), "message should match") {
+		return
+	}
+	if !assert.Equal(t, "3 |   SEQUENCE bar;\n  |   ^", pe.Excerpt(), "excerpt should match") {
+		return
+	}
+}
+
+// largeSchema returns a schema with n tables, resembling the output of
+// mysqldump
+func largeSchema(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "DROP TABLE IF EXISTS `table_%d`;\n", i)
+		fmt.Fprintf(&buf, "/*!40101 SET @saved_cs_client = @@character_set_client */;\n")
+		fmt.Fprintf(&buf, "CREATE TABLE `table_%d` (\n", i)
+		buf.WriteString("  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\n")
+		buf.WriteString("  `parent_id` bigint(20) unsigned DEFAULT NULL,\n")
+		buf.WriteString("  `name` varchar(255) COLLATE utf8mb4_bin NOT NULL DEFAULT '',\n")
+		buf.WriteString("  `status` enum('active','inactive','deleted') NOT NULL DEFAULT 'active',\n")
+		buf.WriteString("  `score` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT 'the score',\n")
+		buf.WriteString("  `body` text,\n")
+		buf.WriteString("  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,\n")
+		buf.WriteString("  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n")
+		buf.WriteString("  PRIMARY KEY (`id`),\n")
+		buf.WriteString("  UNIQUE KEY `uniq_name` (`name`),\n")
+		buf.WriteString("  KEY `idx_status_created_at` (`status`,`created_at`),\n")
+		buf.WriteString("  KEY `idx_parent_id` (`parent_id`)\n")
+		buf.WriteString(") ENGINE=InnoDB AUTO_INCREMENT=1024 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin COMMENT='a table';\n\n")
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkParse(b *testing.B) {
+	src := largeSchema(1000)
+	p := schemalex.New()
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	// many small schemas, as parsed by a service
+	src := largeSchema(10)
+	p := schemalex.New()
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := p.Parse(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestParseConcurrent(t *testing.T) {
+	p := schemalex.NewParser(schemalex.WithMySQLVersion(schemalex.MySQLVersion{Major: 8}))
+	sources := [][]byte{
+		largeSchema(3),
+		largeSchema(5),
+		[]byte("CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY, `b` VARCHAR (20) DEFAULT 'x');"),
+		[]byte("CREATE TABLE `a` (`id` INT NOT NULL PRIMARY KEY) ENGINE = MyISAM; broken"),
+	}
+
+	expected := make([]string, len(sources))
+	for i, src := range sources {
+		stmts, err := p.Parse(src)
+		expected[i] = fmt.Sprintf("%v", err)
+		if err == nil {
+			var buf bytes.Buffer
+			if !assert.NoError(t, format.SQL(&buf, stmts), "format.SQL should succeed") {
+				return
+			}
+			expected[i] = buf.String()
+		}
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]string, 8)
+	for g := range results {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				i := (g + n) % len(sources)
+				stmts, err := p.Parse(sources[i])
+				result := fmt.Sprintf("%v", err)
+				if err == nil {
+					var buf bytes.Buffer
+					if err := format.SQL(&buf, stmts); err != nil {
+						result = err.Error()
+					} else {
+						result = buf.String()
+					}
+				}
+				if result != expected[i] {
+					results[g] = append(results[g], result)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g, mismatches := range results {
+		if !assert.Empty(t, mismatches, "goroutine %d should parse the same as the first parse", g) {
+			return
+		}
+	}
+}
+
+func TestParseWindowsText(t *testing.T) {
+	const unix = "-- users\n" +
+		"CREATE TABLE users (\n" +
+		"  id INT NOT NULL, -- the id\n" +
+		"  name VARCHAR (64) COMMENT 'first line\nsecond line',\n" +
+		"  PRIMARY KEY (id)\n" +
+		") COMMENT 'a\nb';\n" +
+		"CREATE TABLE posts (id INT NOT NULL PRIMARY KEY);\n"
+
+	// edited on Windows, with non-breaking and ideographic spaces pasted
+	// from GUI tools
+	windows := "\uFEFF" + strings.NewReplacer(
+		"\n", "\r\n",
+		"  id INT", "  id\u00A0INT",
+		"TABLE posts", "TABLE\u3000posts",
+	).Replace(unix)
+
+	p := schemalex.New()
+	expected, err := p.ParseString(unix)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	check := func(stmts model.Stmts) bool {
+		if !assert.Equal(t, expected.String(), stmts.String(), "statements should match") {
+			return false
+		}
+		for i := range expected {
+			want, got := expected[i].(model.Table), stmts[i].(model.Table)
+			if !assert.Equal(t, want.Position(), got.Position(), "position of table %s should match", want.Name()) {
+				return false
+			}
+			for col := range want.Columns() {
+				c, _ := got.LookupColumn(col.ID())
+				if !assert.Equal(t, col.Position(), c.Position(), "position of column %s should match", col.Name()) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	stmts, err := p.ParseString(windows)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !check(stmts) {
+		return
+	}
+
+	var streamed model.Stmts
+	err = p.ParseReader(context.Background(), strings.NewReader(windows), func(stmt model.Stmt) error {
+		streamed = append(streamed, stmt)
+		return nil
+	})
+	if !assert.NoError(t, err, "streaming parse should succeed") {
+		return
+	}
+	if !check(streamed) {
+		return
+	}
+}
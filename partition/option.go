@@ -0,0 +1,56 @@
+package partition
+
+import (
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyBound      = "bound"
+	optkeyInterval   = "interval"
+	optkeyNameFormat = "name-format"
+	optkeyPremake    = "premake"
+	optkeyRetention  = "retention"
+)
+
+// WithInterval specifies the period that each partition covers.
+// The default is IntervalDay
+func WithInterval(v Interval) Option {
+	return option.New(optkeyInterval, v)
+}
+
+// WithRetention specifies the number of past periods to keep,
+// not counting the current period. Partitions that are older are dropped.
+// The default is 0, which keeps all partitions
+func WithRetention(n int) Option {
+	return option.New(optkeyRetention, n)
+}
+
+// WithPremake specifies the number of future periods for which
+// partitions should be created in advance. The default is 3
+func WithPremake(n int) Option {
+	return option.New(optkeyPremake, n)
+}
+
+// WithNameFormat specifies the layout (as used by the time package)
+// that is used to name the partitions. The name of a partition is
+// derived from the beginning of the period that it covers.
+// Existing partitions whose names do not match this layout, such as
+// a catch-all `pmax` partition, are left untouched.
+// The default is "p20060102"
+func WithNameFormat(s string) Option {
+	return option.New(optkeyNameFormat, s)
+}
+
+// WithBound specifies the function that generates the expression for
+// `VALUES LESS THAN (...)` from the end of a period. If unspecified,
+// the expression is derived from the partitioning expression of the
+// table. `TO_DAYS(col)`, `UNIX_TIMESTAMP(col)`, `YEAR(col)` and
+// `RANGE COLUMNS(col)` are supported.
+func WithBound(f func(time.Time) string) Option {
+	return option.New(optkeyBound, f)
+}
@@ -0,0 +1,262 @@
+// Package partition computes the statements needed to maintain
+// a rolling window of time based RANGE partitions.
+//
+// Each partition covers a single period (such as a day or a month),
+// and is named after the beginning of that period. Given the current
+// time, Rotate generates the statements to create partitions for the
+// upcoming periods, and to drop the partitions that fall outside of
+// the retention period.
+package partition
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Interval describes the period covered by a single partition
+type Interval int
+
+// List of possible Interval values
+const (
+	IntervalDay Interval = iota
+	IntervalWeek
+	IntervalMonth
+	IntervalYear
+)
+
+// Truncate returns the beginning of the period that contains t.
+// Weeks begin on Monday
+func (i Interval) Truncate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch i {
+	case IntervalWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case IntervalMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case IntervalYear:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Add returns the beginning of the period that is n periods after t,
+// which must be the beginning of a period
+func (i Interval) Add(t time.Time, n int) time.Time {
+	switch i {
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	case IntervalMonth:
+		return t.AddDate(0, n, 0)
+	case IntervalYear:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+type rotateCtx struct {
+	bound      func(time.Time) string
+	interval   Interval
+	nameFormat string
+	premake    int
+	retention  int
+}
+
+type timePartition struct {
+	start time.Time
+	def   model.PartitionDefinition
+}
+
+var boundFuncRx = regexp.MustCompile(`(?i)^(TO_DAYS|UNIX_TIMESTAMP|YEAR)\s*\(`)
+
+// Bound returns the default function to generate the expression for
+// `VALUES LESS THAN (...)` for the given partitioning
+func Bound(partitioning model.Partitioning) (func(time.Time) string, error) {
+	if partitioning.HasColumns() {
+		var n int
+		for range partitioning.Columns() {
+			n++
+		}
+		if n != 1 {
+			return nil, errors.New(`RANGE COLUMNS partitioning must have exactly one column`)
+		}
+		return func(t time.Time) string {
+			return util.Singlequote(t.Format("2006-01-02 15:04:05"))
+		}, nil
+	}
+
+	m := boundFuncRx.FindStringSubmatch(partitioning.Expression())
+	if m == nil {
+		return nil, errors.Errorf(`unsupported partitioning expression %s`, partitioning.Expression())
+	}
+
+	switch fn := strings.ToUpper(m[1]); fn {
+	case "YEAR":
+		return func(t time.Time) string {
+			return t.Format("2006")
+		}, nil
+	case "TO_DAYS":
+		return func(t time.Time) string {
+			return fn + "(" + util.Singlequote(t.Format("2006-01-02")) + ")"
+		}, nil
+	default:
+		return func(t time.Time) string {
+			return fn + "(" + util.Singlequote(t.Format("2006-01-02 15:04:05")) + ")"
+		}, nil
+	}
+}
+
+// isMaxValue returns true for `VALUES LESS THAN MAXVALUE`, as well as
+// for `VALUES LESS THAN (MAXVALUE, ...)` used by RANGE COLUMNS
+func isMaxValue(def model.PartitionDefinition) bool {
+	if def.IsMaxValue() {
+		return true
+	}
+	if !def.HasLessThan() {
+		return false
+	}
+	for _, v := range strings.Split(def.LessThan(), ",") {
+		if !strings.EqualFold(strings.TrimSpace(v), "MAXVALUE") {
+			return false
+		}
+	}
+	return true
+}
+
+// Rotate generates the statements to maintain the rolling window of
+// partitions for the given table, as of `now`, and writes them to `dst`.
+// The table must be partitioned by RANGE.
+//
+// New partitions are appended after the newest existing partition.
+// If the table has a `VALUES LESS THAN MAXVALUE` partition, it is
+// reorganized so that it stays at the end.
+func Rotate(dst io.Writer, table model.Table, now time.Time, options ...Option) error {
+	ctx := &rotateCtx{
+		interval:   IntervalDay,
+		nameFormat: "p20060102",
+		premake:    3,
+	}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyBound:
+			ctx.bound = o.Value().(func(time.Time) string)
+		case optkeyInterval:
+			ctx.interval = o.Value().(Interval)
+		case optkeyNameFormat:
+			ctx.nameFormat = o.Value().(string)
+		case optkeyPremake:
+			ctx.premake = o.Value().(int)
+		case optkeyRetention:
+			ctx.retention = o.Value().(int)
+		}
+	}
+
+	if !table.HasPartitioning() || table.Partitioning().Type() != model.PartitionTypeRange {
+		return errors.Errorf(`table %s is not partitioned by RANGE`, table.Name())
+	}
+	partitioning := table.Partitioning()
+
+	if ctx.bound == nil {
+		f, err := Bound(partitioning)
+		if err != nil {
+			return errors.Wrapf(err, `failed to determine partition bound for table %s`, table.Name())
+		}
+		ctx.bound = f
+	}
+
+	var existing []timePartition
+	var maxValue model.PartitionDefinition
+	for def := range partitioning.Definitions() {
+		if isMaxValue(def) {
+			maxValue = def
+			continue
+		}
+		start, err := time.ParseInLocation(ctx.nameFormat, def.Name(), now.Location())
+		if err != nil {
+			continue
+		}
+		existing = append(existing, timePartition{start: start, def: def})
+	}
+
+	current := ctx.interval.Truncate(now)
+	last := ctx.interval.Add(current, ctx.premake)
+
+	// Partitions can only be added after the last one
+	next := current
+	if l := len(existing); l > 0 {
+		if n := ctx.interval.Add(existing[l-1].start, 1); n.After(next) {
+			next = n
+		}
+	}
+
+	var added []model.PartitionDefinition
+	for start := next; !start.After(last); start = ctx.interval.Add(start, 1) {
+		def := model.NewPartitionDefinition(start.Format(ctx.nameFormat))
+		def.SetLessThan(ctx.bound(ctx.interval.Add(start, 1)))
+		added = append(added, def)
+	}
+
+	var dropped []string
+	if ctx.retention > 0 {
+		oldest := ctx.interval.Add(current, -ctx.retention)
+		for _, p := range existing {
+			if p.start.Before(oldest) {
+				dropped = append(dropped, p.def.Name())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if len(added) > 0 {
+		buf.WriteString("ALTER TABLE ")
+		buf.WriteString(util.Backquote(table.Name()))
+		if maxValue != nil {
+			buf.WriteString(" REORGANIZE PARTITION ")
+			buf.WriteString(util.Backquote(maxValue.Name()))
+			buf.WriteString(" INTO (")
+			added = append(added, maxValue)
+		} else {
+			buf.WriteString(" ADD PARTITION (")
+		}
+		for i, def := range added {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := format.SQL(&buf, def); err != nil {
+				return errors.Wrap(err, `failed to format partition definition`)
+			}
+		}
+		buf.WriteString(");")
+	}
+
+	if len(dropped) > 0 {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("ALTER TABLE ")
+		buf.WriteString(util.Backquote(table.Name()))
+		buf.WriteString(" DROP PARTITION ")
+		for i, name := range dropped {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(util.Backquote(name))
+		}
+		buf.WriteByte(';')
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write statements`)
+	}
+	return nil
+}
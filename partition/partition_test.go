@@ -0,0 +1,103 @@
+package partition_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/model"
+	"github.com/eihigh/schemalex/partition"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotate(t *testing.T) {
+	type Spec struct {
+		Input   string
+		Options []partition.Option
+		Expect  string
+		Error   bool
+	}
+
+	now := time.Date(2020, time.January, 15, 12, 34, 56, 0, time.UTC)
+	specs := []Spec{
+		// nothing to do
+		{
+			Input:   "CREATE TABLE `log` ( `created` DATE NOT NULL ) PARTITION BY RANGE (TO_DAYS(`created`)) ( PARTITION p20200115 VALUES LESS THAN (TO_DAYS('2020-01-16')), PARTITION p20200116 VALUES LESS THAN (TO_DAYS('2020-01-17')) )",
+			Options: []partition.Option{partition.WithPremake(1)},
+			Expect:  "",
+		},
+		// add and drop daily partitions
+		{
+			Input: "CREATE TABLE `log` ( `created` DATE NOT NULL ) PARTITION BY RANGE (TO_DAYS(`created`)) ( PARTITION p20200113 VALUES LESS THAN (TO_DAYS('2020-01-14')), PARTITION p20200114 VALUES LESS THAN (TO_DAYS('2020-01-15')), PARTITION p20200115 VALUES LESS THAN (TO_DAYS('2020-01-16')) )",
+			Options: []partition.Option{
+				partition.WithPremake(2),
+				partition.WithRetention(1),
+			},
+			Expect: "ALTER TABLE `log` ADD PARTITION (PARTITION `p20200116` VALUES LESS THAN (TO_DAYS('2020-01-17')), PARTITION `p20200117` VALUES LESS THAN (TO_DAYS('2020-01-18')));\n" +
+				"ALTER TABLE `log` DROP PARTITION `p20200113`;",
+		},
+		// reorganize the MAXVALUE partition
+		{
+			Input: "CREATE TABLE `log` ( `created` DATETIME NOT NULL ) PARTITION BY RANGE (UNIX_TIMESTAMP(`created`)) ( PARTITION p202001 VALUES LESS THAN (UNIX_TIMESTAMP('2020-02-01 00:00:00')), PARTITION pmax VALUES LESS THAN MAXVALUE )",
+			Options: []partition.Option{
+				partition.WithInterval(partition.IntervalMonth),
+				partition.WithNameFormat("p200601"),
+				partition.WithPremake(1),
+			},
+			Expect: "ALTER TABLE `log` REORGANIZE PARTITION `pmax` INTO (PARTITION `p202002` VALUES LESS THAN (UNIX_TIMESTAMP('2020-03-01 00:00:00')), PARTITION `pmax` VALUES LESS THAN MAXVALUE);",
+		},
+		// RANGE COLUMNS, and no existing partitions matching the name format
+		{
+			Input: "CREATE TABLE `log` ( `created` DATE NOT NULL ) PARTITION BY RANGE COLUMNS (`created`) ( PARTITION pmax VALUES LESS THAN (MAXVALUE) )",
+			Options: []partition.Option{
+				partition.WithInterval(partition.IntervalWeek),
+				partition.WithPremake(0),
+			},
+			Expect: "ALTER TABLE `log` REORGANIZE PARTITION `pmax` INTO (PARTITION `p20200113` VALUES LESS THAN ('2020-01-20 00:00:00'), PARTITION `pmax` VALUES LESS THAN (MAXVALUE));",
+		},
+		// custom bound
+		{
+			Input: "CREATE TABLE `log` ( `created` INT NOT NULL ) PARTITION BY RANGE (`created`) ( PARTITION p2019 VALUES LESS THAN (2020) )",
+			Options: []partition.Option{
+				partition.WithInterval(partition.IntervalYear),
+				partition.WithNameFormat("p2006"),
+				partition.WithPremake(0),
+				partition.WithBound(func(t time.Time) string { return t.Format("2006") }),
+			},
+			Expect: "ALTER TABLE `log` ADD PARTITION (PARTITION `p2020` VALUES LESS THAN (2021));",
+		},
+		// unsupported expression
+		{
+			Input: "CREATE TABLE `log` ( `created` INT NOT NULL ) PARTITION BY RANGE (`created`) ( PARTITION p2019 VALUES LESS THAN (2020) )",
+			Error: true,
+		},
+		// not partitioned by RANGE
+		{
+			Input: "CREATE TABLE `log` ( `id` INT NOT NULL ) PARTITION BY HASH (`id`) PARTITIONS 4",
+			Error: true,
+		},
+	}
+
+	p := schemalex.New()
+	var buf bytes.Buffer
+	for _, spec := range specs {
+		stmts, err := p.ParseString(spec.Input)
+		if !assert.NoError(t, err, "parse should succeed") {
+			return
+		}
+
+		buf.Reset()
+		err = partition.Rotate(&buf, stmts[0].(model.Table), now, spec.Options...)
+		if spec.Error {
+			assert.Error(t, err, "partition.Rotate should fail for %s", spec.Input)
+			continue
+		}
+		if !assert.NoError(t, err, "partition.Rotate should succeed") {
+			return
+		}
+		if !assert.Equal(t, spec.Expect, buf.String(), "result SQL should match") {
+			return
+		}
+	}
+}
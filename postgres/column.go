@@ -0,0 +1,339 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/eihigh/schemalex/model"
+)
+
+type columnType struct {
+	typ model.ColumnType
+	// serial types are integers whose default is taken from a sequence
+	serial bool
+}
+
+// columnTypes maps the PostgreSQL types to the closest types of the
+// model. Types that take several words, such as "double precision",
+// are joined by a single space.
+var columnTypes = map[string]columnType{
+	"smallint":                    {typ: model.ColumnTypeSmallInt},
+	"int2":                        {typ: model.ColumnTypeSmallInt},
+	"integer":                     {typ: model.ColumnTypeInt},
+	"int":                         {typ: model.ColumnTypeInt},
+	"int4":                        {typ: model.ColumnTypeInt},
+	"bigint":                      {typ: model.ColumnTypeBigInt},
+	"int8":                        {typ: model.ColumnTypeBigInt},
+	"smallserial":                 {typ: model.ColumnTypeSmallInt, serial: true},
+	"serial2":                     {typ: model.ColumnTypeSmallInt, serial: true},
+	"serial":                      {typ: model.ColumnTypeInt, serial: true},
+	"serial4":                     {typ: model.ColumnTypeInt, serial: true},
+	"bigserial":                   {typ: model.ColumnTypeBigInt, serial: true},
+	"serial8":                     {typ: model.ColumnTypeBigInt, serial: true},
+	"real":                        {typ: model.ColumnTypeFloat},
+	"float4":                      {typ: model.ColumnTypeFloat},
+	"double precision":            {typ: model.ColumnTypeDouble},
+	"float8":                      {typ: model.ColumnTypeDouble},
+	"numeric":                     {typ: model.ColumnTypeDecimal},
+	"decimal":                     {typ: model.ColumnTypeDecimal},
+	"boolean":                     {typ: model.ColumnTypeBoolean},
+	"bool":                        {typ: model.ColumnTypeBoolean},
+	"text":                        {typ: model.ColumnTypeText},
+	"varchar":                     {typ: model.ColumnTypeVarChar},
+	"character varying":           {typ: model.ColumnTypeVarChar},
+	"char":                        {typ: model.ColumnTypeChar},
+	"character":                   {typ: model.ColumnTypeChar},
+	"bpchar":                      {typ: model.ColumnTypeChar},
+	"bytea":                       {typ: model.ColumnTypeLongBlob},
+	"date":                        {typ: model.ColumnTypeDate},
+	"time":                        {typ: model.ColumnTypeTime},
+	"time without time zone":      {typ: model.ColumnTypeTime},
+	"time with time zone":         {typ: model.ColumnTypeTime},
+	"timetz":                      {typ: model.ColumnTypeTime},
+	"timestamp":                   {typ: model.ColumnTypeDateTime},
+	"timestamp without time zone": {typ: model.ColumnTypeDateTime},
+	"timestamp with time zone":    {typ: model.ColumnTypeTimestamp},
+	"timestamptz":                 {typ: model.ColumnTypeTimestamp},
+	"json":                        {typ: model.ColumnTypeJSON},
+	"jsonb":                       {typ: model.ColumnTypeJSON},
+	"uuid":                        {typ: model.ColumnTypeUUID},
+}
+
+// multiWordTypes lists the words that may follow the first word of
+// a type name
+var multiWordTypes = map[string][]string{
+	"double":    {"precision"},
+	"character": {"varying"},
+	"time":      {"with", "without", "time", "zone"},
+	"timestamp": {"with", "without", "time", "zone"},
+}
+
+func (p *Parser) parseColumn(ctx *parseCtx, table model.Table) error {
+	t := ctx.peek()
+	name, err := p.parseIdent(ctx)
+	if err != nil {
+		return err
+	}
+
+	col := model.NewTableColumn(name)
+	col.SetPosition(ctx.position(t))
+
+	serial, err := p.parseColumnType(ctx, col)
+	if err != nil {
+		return err
+	}
+	if serial {
+		col.SetAutoIncrement(true)
+		col.SetNullState(model.NullStateNotNull)
+	}
+
+	if err := p.parseColumnConstraints(ctx, table, col); err != nil {
+		return err
+	}
+	table.AddColumn(col)
+	return nil
+}
+
+// parseColumnType parses the type of a column, and returns true if it
+// is a serial type
+func (p *Parser) parseColumnType(ctx *parseCtx, col model.TableColumn) (bool, error) {
+	start := ctx.next()
+	if start.kind != tokenIdent {
+		return false, newParseError(start, "expected type")
+	}
+
+	// the words that may follow (time zone ...) come after the
+	// precision, as in "timestamp(3) with time zone"
+	words := []string{start.value}
+	var length model.Length
+	for {
+		t := ctx.peek()
+		if t.isPunct("(") && length == nil {
+			l, err := p.parseTypeLength(ctx)
+			if err != nil {
+				return false, err
+			}
+			length = l
+			continue
+		}
+		if t.kind == tokenIdent && containsString(multiWordTypes[start.value], t.value) {
+			ctx.next()
+			words = append(words, t.value)
+			continue
+		}
+		break
+	}
+
+	name := strings.Join(words, " ")
+	typ, ok := columnTypes[name]
+	if !ok {
+		return false, newParseError(start, "unsupported type %s", name)
+	}
+	if t := ctx.peek(); t.isPunct("[") {
+		return false, newParseError(t, "unsupported array type")
+	}
+
+	col.SetType(typ.typ)
+	if length != nil {
+		switch typ.typ {
+		case model.ColumnTypeVarChar, model.ColumnTypeChar, model.ColumnTypeDecimal:
+			col.SetLength(length)
+		}
+	}
+	return typ.serial, nil
+}
+
+func (p *Parser) parseTypeLength(ctx *parseCtx) (model.Length, error) {
+	ctx.next()
+	t := ctx.next()
+	if t.kind != tokenNumber {
+		return nil, newParseError(t, "expected number")
+	}
+	length := model.NewLength(t.value)
+	if ctx.acceptPunct(",") {
+		t := ctx.next()
+		if t.kind != tokenNumber {
+			return nil, newParseError(t, "expected number")
+		}
+		length.SetDecimal(t.value)
+	}
+	if err := ctx.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return length, nil
+}
+
+func (p *Parser) parseColumnConstraints(ctx *parseCtx, table model.Table, col model.TableColumn) error {
+	for {
+		t := ctx.peek()
+		if t.isPunct(",") || t.isPunct(")") {
+			return nil
+		}
+
+		var symbol string
+		if ctx.accept("constraint") {
+			s, err := p.parseIdent(ctx)
+			if err != nil {
+				return err
+			}
+			symbol = s
+		}
+
+		t = ctx.next()
+		switch {
+		case t.is("not"):
+			if err := ctx.expect("null"); err != nil {
+				return err
+			}
+			col.SetNullState(model.NullStateNotNull)
+		case t.is("null"):
+			col.SetNullState(model.NullStateNull)
+		case t.is("default"):
+			if err := p.parseDefault(ctx, col); err != nil {
+				return err
+			}
+		case t.is("primary"):
+			if err := ctx.expect("key"); err != nil {
+				return err
+			}
+			col.SetPrimary(true)
+		case t.is("unique"):
+			col.SetUnique(true)
+		case t.is("collate"):
+			name, _, err := p.parseName(ctx)
+			if err != nil {
+				return err
+			}
+			col.SetCollation(name)
+		case t.is("references"):
+			ref, err := p.parseReference(ctx)
+			if err != nil {
+				return err
+			}
+			index := model.NewIndex(model.IndexKindForeignKey, table.ID())
+			if symbol != "" {
+				index.SetSymbol(symbol)
+			}
+			index.SetPosition(ctx.position(t))
+			index.AddColumns(model.NewIndexColumn(col.Name()))
+			index.SetReference(ref)
+			table.AddIndex(index)
+		case t.is("check"):
+			// CHECK constraints are not represented in the model
+			if _, err := p.parseExpression(ctx); err != nil {
+				return err
+			}
+		case t.is("generated"):
+			if err := p.parseIdentity(ctx, col); err != nil {
+				return err
+			}
+		case t.is("deferrable"), t.is("initially"), t.is("immediate"), t.is("deferred"):
+		default:
+			return newParseError(t, "unexpected column constraint %s", t.value)
+		}
+	}
+}
+
+// parseIdentity parses `GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY`,
+// which is treated as AUTO_INCREMENT. Generated columns are not
+// supported.
+func (p *Parser) parseIdentity(ctx *parseCtx, col model.TableColumn) error {
+	if !ctx.accept("always") {
+		if err := ctx.expect("by", "default"); err != nil {
+			return err
+		}
+	}
+	if err := ctx.expect("as"); err != nil {
+		return err
+	}
+	if t := ctx.next(); !t.is("identity") {
+		return newParseError(t, "unsupported generated column")
+	}
+	if ctx.peek().isPunct("(") {
+		// sequence options
+		if _, err := p.parseExpression(ctx); err != nil {
+			return err
+		}
+	}
+	col.SetAutoIncrement(true)
+	col.SetNullState(model.NullStateNotNull)
+	return nil
+}
+
+// parseDefault parses the default value of a column. String constants
+// are unquoted, casts such as 'a'::text are dropped, and defaults taken
+// from sequences with nextval() are treated as AUTO_INCREMENT.
+func (p *Parser) parseDefault(ctx *parseCtx, col model.TableColumn) error {
+	start := ctx.peek()
+	if start.kind == tokenEOF || start.isPunct(",") || start.isPunct(")") || start.isPunct(";") {
+		return newParseError(start, "expected default value")
+	}
+
+	// the first token is always part of the value, as in DEFAULT NULL
+	end := ctx.next()
+	if start.isPunct("(") {
+		ctx.pos--
+		end = nil
+	}
+	depth := 0
+LOOP:
+	for {
+		t := ctx.peek()
+		switch {
+		case t.kind == tokenEOF, t.isPunct(";"):
+			break LOOP
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			if depth == 0 {
+				break LOOP
+			}
+			depth--
+		case depth == 0 && (t.isPunct(",") || isConstraintKeyword(t)):
+			break LOOP
+		}
+		end = ctx.next()
+	}
+
+	expr := strings.TrimSpace(string(ctx.input[start.pos:end.end]))
+	if i := strings.LastIndex(expr, "::"); i > 0 && !strings.ContainsAny(expr[i:], "()'") {
+		expr = strings.TrimSpace(expr[:i])
+	}
+
+	switch {
+	case start.kind == tokenString && strings.HasPrefix(expr, "'") && strings.HasSuffix(expr, "'"):
+		col.SetDefault(start.value, true)
+	case start.is("nextval"):
+		col.SetAutoIncrement(true)
+	case start.is("now") && expr == "now()", start.is("current_timestamp") && (expr == "current_timestamp" || expr == "CURRENT_TIMESTAMP"):
+		col.SetDefault("CURRENT_TIMESTAMP", false)
+	case start.is("true"), start.is("false"), start.is("null"):
+		col.SetDefault(strings.ToUpper(expr), false)
+	default:
+		col.SetDefault(expr, false)
+	}
+	return nil
+}
+
+var constraintKeywords = []string{
+	"not", "null", "default", "primary", "unique", "collate",
+	"references", "check", "generated", "constraint",
+	"deferrable", "initially",
+}
+
+func isConstraintKeyword(t *token) bool {
+	for _, k := range constraintKeywords {
+		if t.is(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
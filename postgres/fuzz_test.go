@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex/postgres"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"CREATE TABLE foo (id serial PRIMARY KEY, name varchar(50) NOT NULL DEFAULT 'x');",
+		"ALTER TABLE ONLY public.foo ADD CONSTRAINT foo_pkey PRIMARY KEY (id);",
+		"CREATE UNIQUE INDEX foo_name ON public.foo USING btree (name DESC NULLS LAST);",
+		"CREATE TABLE foo (a text DEFAULT 'unterminated",
+		"CREATE TABLE \"foo",
+		"/* unterminated",
+		"CREATE TABLE foo (a numeric(10,",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		postgres.New().Parse(input)
+	})
+}
@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+	end   int
+	line  int
+	col   int
+}
+
+// is returns true if the token is the given keyword. Keywords are
+// unquoted identifiers, and are case insensitive
+func (t *token) is(keyword string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.value, keyword)
+}
+
+func (t *token) isPunct(s string) bool {
+	return t.kind == tokenPunct && t.value == s
+}
+
+type lexer struct {
+	input []byte
+	pos   int
+	line  int
+	col   int
+}
+
+// lex splits the input into tokens. Whitespaces and comments are
+// dropped. Unquoted identifiers are folded to lower case, as done
+// by PostgreSQL.
+func lex(input []byte) ([]*token, error) {
+	l := lexer{input: input, line: 1, col: 1}
+	var tokens []*token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		if t.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.pos+n >= len(l.input) {
+		return 0
+	}
+	return rune(l.input[l.pos+n])
+}
+
+func (l *lexer) advance() {
+	r, size := utf8.DecodeRune(l.input[l.pos:])
+	l.pos += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+}
+
+func (l *lexer) errorf(t *token, format string, args ...interface{}) error {
+	return newParseError(t, format, args...)
+}
+
+func (l *lexer) next() (*token, error) {
+	l.skip()
+
+	t := &token{pos: l.pos, line: l.line, col: l.col}
+	defer func() { t.end = l.pos }()
+
+	if l.pos >= len(l.input) {
+		t.kind = tokenEOF
+		t.value = "EOF"
+		return t, nil
+	}
+
+	r, _ := utf8.DecodeRune(l.input[l.pos:])
+	switch {
+	case (r == 'e' || r == 'E') && l.peek(1) == '\'':
+		l.advance()
+		return t, l.string(t, true)
+	case r == '\'':
+		return t, l.string(t, false)
+	case r == '"':
+		t.kind = tokenQuotedIdent
+		l.advance()
+		var buf strings.Builder
+		for {
+			if l.pos >= len(l.input) {
+				return nil, l.errorf(t, "unterminated quoted identifier")
+			}
+			c, _ := utf8.DecodeRune(l.input[l.pos:])
+			l.advance()
+			if c == '"' {
+				if l.peek(0) != '"' {
+					break
+				}
+				l.advance()
+			}
+			buf.WriteRune(c)
+		}
+		t.value = buf.String()
+	case r == '$' && (l.peek(1) == '$' || isIdentStart(l.peek(1))):
+		return t, l.dollarString(t)
+	case isIdentStart(r):
+		t.kind = tokenIdent
+		start := l.pos
+		for l.pos < len(l.input) {
+			r, _ := utf8.DecodeRune(l.input[l.pos:])
+			if !isIdentStart(r) && !unicode.IsDigit(r) && r != '$' {
+				break
+			}
+			l.advance()
+		}
+		t.value = strings.ToLower(string(l.input[start:l.pos]))
+	case unicode.IsDigit(r) || (r == '.' && unicode.IsDigit(l.peek(1))):
+		t.kind = tokenNumber
+		start := l.pos
+		for l.pos < len(l.input) {
+			c := l.peek(0)
+			if unicode.IsDigit(c) || c == '.' {
+				l.advance()
+				continue
+			}
+			if (c == 'e' || c == 'E') && (unicode.IsDigit(l.peek(1)) || ((l.peek(1) == '+' || l.peek(1) == '-') && unicode.IsDigit(l.peek(2)))) {
+				l.advance()
+				l.advance()
+				continue
+			}
+			break
+		}
+		t.value = string(l.input[start:l.pos])
+	case r == ':' && l.peek(1) == ':':
+		t.kind = tokenPunct
+		t.value = "::"
+		l.advance()
+		l.advance()
+	default:
+		t.kind = tokenPunct
+		l.advance()
+		t.value = string(l.input[t.pos:l.pos])
+	}
+	return t, nil
+}
+
+// skip skips whitespaces and comments
+func (l *lexer) skip() {
+	for l.pos < len(l.input) {
+		r, _ := utf8.DecodeRune(l.input[l.pos:])
+		switch {
+		case unicode.IsSpace(r):
+			l.advance()
+		case r == '-' && l.peek(1) == '-':
+			for l.pos < len(l.input) && l.peek(0) != '\n' {
+				l.advance()
+			}
+		case r == '/' && l.peek(1) == '*':
+			l.advance()
+			l.advance()
+			for l.pos < len(l.input) && !(l.peek(0) == '*' && l.peek(1) == '/') {
+				l.advance()
+			}
+			if l.pos < len(l.input) {
+				l.advance()
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// string reads a string constant. Quotes are escaped by doubling them,
+// and backslashes escape the next character in E'...' strings
+func (l *lexer) string(t *token, escapes bool) error {
+	t.kind = tokenString
+	l.advance()
+	var buf strings.Builder
+	for {
+		if l.pos >= len(l.input) {
+			return l.errorf(t, "unterminated string")
+		}
+		r, _ := utf8.DecodeRune(l.input[l.pos:])
+		l.advance()
+		switch {
+		case r == '\'':
+			if l.peek(0) != '\'' {
+				t.value = buf.String()
+				return nil
+			}
+			l.advance()
+		case r == '\\' && escapes && l.pos < len(l.input):
+			r, _ = utf8.DecodeRune(l.input[l.pos:])
+			l.advance()
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// dollarString reads a dollar-quoted string constant, such as the
+// body of a function: $$ ... $$ or $tag$ ... $tag$
+func (l *lexer) dollarString(t *token) error {
+	t.kind = tokenString
+	start := l.pos
+	l.advance()
+	for l.peek(0) != '$' {
+		if l.pos >= len(l.input) {
+			return l.errorf(t, "unterminated dollar-quoted string")
+		}
+		l.advance()
+	}
+	l.advance()
+	tag := string(l.input[start:l.pos])
+
+	end := strings.Index(string(l.input[l.pos:]), tag)
+	if end < 0 {
+		return l.errorf(t, "unterminated dollar-quoted string")
+	}
+	body := l.pos
+	for l.pos < body+end+len(tag) {
+		l.advance()
+	}
+	t.value = string(l.input[body : body+end])
+	return nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
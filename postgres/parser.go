@@ -0,0 +1,702 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// Parser parses PostgreSQL statements
+type Parser struct{}
+
+// New creates a new Parser
+func New(options ...schemalex.Option) *Parser {
+	return &Parser{}
+}
+
+type parseCtx struct {
+	input  []byte
+	tokens []*token
+	pos    int
+	stmts  model.Stmts
+}
+
+func (ctx *parseCtx) peek() *token {
+	return ctx.tokens[ctx.pos]
+}
+
+func (ctx *parseCtx) next() *token {
+	t := ctx.tokens[ctx.pos]
+	if t.kind != tokenEOF {
+		ctx.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the keywords
+func (ctx *parseCtx) accept(keywords ...string) bool {
+	for _, k := range keywords {
+		if ctx.peek().is(k) {
+			ctx.next()
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx *parseCtx) acceptPunct(s string) bool {
+	if ctx.peek().isPunct(s) {
+		ctx.next()
+		return true
+	}
+	return false
+}
+
+// expect consumes the given sequence of keywords
+func (ctx *parseCtx) expect(keywords ...string) error {
+	for _, k := range keywords {
+		if t := ctx.next(); !t.is(k) {
+			return newParseError(t, "expected %s", strings.ToUpper(k))
+		}
+	}
+	return nil
+}
+
+func (ctx *parseCtx) expectPunct(s string) error {
+	if t := ctx.next(); !t.isPunct(s) {
+		return newParseError(t, "expected %s", s)
+	}
+	return nil
+}
+
+// skipStatement skips all tokens until the end of the current statement
+func (ctx *parseCtx) skipStatement() {
+	for {
+		t := ctx.next()
+		if t.kind == tokenEOF || t.isPunct(";") {
+			return
+		}
+	}
+}
+
+func (ctx *parseCtx) position(t *token) model.Position {
+	return model.Position{Line: t.line, Col: t.col}
+}
+
+// ParseFile parses a file containing PostgreSQL statements
+func (p *Parser) ParseFile(fn string) (model.Stmts, error) {
+	src, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to open file %s`, fn)
+	}
+
+	stmts, err := p.Parse(src)
+	if err != nil {
+		if pe, ok := err.(*parseError); ok {
+			pe.file = fn
+		}
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// ParseString parses a string containing PostgreSQL statements
+func (p *Parser) ParseString(src string) (model.Stmts, error) {
+	return p.Parse([]byte(src))
+}
+
+// Parse parses CREATE TABLE, CREATE INDEX, and the constraints added
+// by ALTER TABLE ... ADD CONSTRAINT, which is how pg_dump declares
+// primary keys and foreign keys. Other statements, such as SET or
+// CREATE SEQUENCE, are skipped. If it encounters errors while parsing,
+// the returned error will be a schemalex.ParseError.
+func (p *Parser) Parse(src []byte) (model.Stmts, error) {
+	stmts, err := p.parse(src)
+	if err != nil {
+		if pe, ok := err.(*parseError); ok {
+			pe.input = src
+		}
+		return nil, err
+	}
+	return stmts, nil
+}
+
+func (p *Parser) parse(src []byte) (model.Stmts, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := &parseCtx{input: src, tokens: tokens}
+	for {
+		t := ctx.peek()
+		switch {
+		case t.kind == tokenEOF:
+			return normalize(ctx.stmts), nil
+		case t.isPunct(";"):
+			ctx.next()
+		case t.is("create"):
+			if err := p.parseCreate(ctx); err != nil {
+				return nil, err
+			}
+		case t.is("alter"):
+			if err := p.parseAlter(ctx); err != nil {
+				return nil, err
+			}
+		default:
+			ctx.skipStatement()
+		}
+	}
+}
+
+// normalize normalizes the tables once all the statements that modify
+// them have been read
+func normalize(stmts model.Stmts) model.Stmts {
+	for i, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			stmts[i], _ = table.Normalize()
+		}
+	}
+	return stmts
+}
+
+func (p *Parser) parseCreate(ctx *parseCtx) error {
+	create := ctx.next()
+	switch {
+	case ctx.accept("table"):
+		return p.parseCreateTable(ctx, create)
+	case ctx.accept("unique"):
+		if err := ctx.expect("index"); err != nil {
+			return err
+		}
+		return p.parseCreateIndex(ctx, create, model.IndexKindUnique)
+	case ctx.accept("index"):
+		return p.parseCreateIndex(ctx, create, model.IndexKindNormal)
+	case ctx.peek().is("temporary") || ctx.peek().is("temp") || ctx.peek().is("unlogged"):
+		ctx.next()
+		if err := ctx.expect("table"); err != nil {
+			return err
+		}
+		return p.parseCreateTable(ctx, create)
+	}
+	ctx.skipStatement()
+	return nil
+}
+
+// parseName parses a possibly schema qualified name. The default
+// schema, public, is dropped, and other schemas are kept as a prefix
+// such as "audit.events".
+func (p *Parser) parseName(ctx *parseCtx) (string, *token, error) {
+	t := ctx.next()
+	if t.kind != tokenIdent && t.kind != tokenQuotedIdent {
+		return "", t, newParseError(t, "expected identifier")
+	}
+	name := t.value
+	if ctx.acceptPunct(".") {
+		n := ctx.next()
+		if n.kind != tokenIdent && n.kind != tokenQuotedIdent {
+			return "", n, newParseError(n, "expected identifier")
+		}
+		if name == "public" {
+			name = n.value
+		} else {
+			name = name + "." + n.value
+		}
+	}
+	return name, t, nil
+}
+
+func (p *Parser) parseIdent(ctx *parseCtx) (string, error) {
+	t := ctx.next()
+	if t.kind != tokenIdent && t.kind != tokenQuotedIdent {
+		return "", newParseError(t, "expected identifier")
+	}
+	return t.value, nil
+}
+
+func (p *Parser) lookupTable(ctx *parseCtx, name string, t *token) (model.Table, error) {
+	stmt, ok := ctx.stmts.Lookup(model.NewTable(name).ID())
+	if !ok {
+		return nil, newParseError(t, "unknown table %s", name)
+	}
+	return stmt.(model.Table), nil
+}
+
+func (p *Parser) parseCreateTable(ctx *parseCtx, create *token) error {
+	var ifNotExists bool
+	if ctx.accept("if") {
+		if err := ctx.expect("not", "exists"); err != nil {
+			return err
+		}
+		ifNotExists = true
+	}
+
+	name, _, err := p.parseName(ctx)
+	if err != nil {
+		return err
+	}
+
+	table := model.NewTable(name)
+	table.SetIfNotExists(ifNotExists)
+	table.SetPosition(ctx.position(create))
+
+	if err := ctx.expectPunct("("); err != nil {
+		return err
+	}
+	if !ctx.acceptPunct(")") {
+		for {
+			if err := p.parseTableElement(ctx, table); err != nil {
+				return err
+			}
+			if ctx.acceptPunct(",") {
+				continue
+			}
+			if err := ctx.expectPunct(")"); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	// INHERITS, PARTITION BY, WITH (...), and TABLESPACE are not
+	// represented in the model
+	ctx.skipStatement()
+
+	ctx.stmts = append(ctx.stmts, table)
+	return nil
+}
+
+func (p *Parser) parseTableElement(ctx *parseCtx, table model.Table) error {
+	t := ctx.peek()
+	switch {
+	case t.is("constraint"), t.is("primary"), t.is("unique"), t.is("foreign"), t.is("check"), t.is("exclude"):
+		return p.parseTableConstraint(ctx, table)
+	case t.is("like"):
+		return newParseError(t, "unsupported LIKE in table definition")
+	}
+	return p.parseColumn(ctx, table)
+}
+
+// parseTableConstraint parses a table constraint. CHECK constraints
+// are not represented in the model, and are skipped.
+func (p *Parser) parseTableConstraint(ctx *parseCtx, table model.Table) error {
+	start := ctx.peek()
+
+	var symbol string
+	if ctx.accept("constraint") {
+		s, err := p.parseIdent(ctx)
+		if err != nil {
+			return err
+		}
+		symbol = s
+	}
+
+	var index model.Index
+	t := ctx.next()
+	switch {
+	case t.is("primary"):
+		if err := ctx.expect("key"); err != nil {
+			return err
+		}
+		index = model.NewIndex(model.IndexKindPrimaryKey, table.ID())
+	case t.is("unique"):
+		index = model.NewIndex(model.IndexKindUnique, table.ID())
+		if symbol != "" {
+			index.SetName(symbol)
+		}
+	case t.is("foreign"):
+		if err := ctx.expect("key"); err != nil {
+			return err
+		}
+		index = model.NewIndex(model.IndexKindForeignKey, table.ID())
+	case t.is("check"):
+		if _, err := p.parseExpression(ctx); err != nil {
+			return err
+		}
+		p.skipConstraintAttributes(ctx)
+		return nil
+	default:
+		return newParseError(t, "unsupported table constraint")
+	}
+
+	if symbol != "" {
+		index.SetSymbol(symbol)
+	}
+	index.SetPosition(ctx.position(start))
+
+	columns, err := p.parseColumnList(ctx)
+	if err != nil {
+		return err
+	}
+	index.AddColumns(columns...)
+
+	if index.IsForeignKey() {
+		if err := ctx.expect("references"); err != nil {
+			return err
+		}
+		ref, err := p.parseReference(ctx)
+		if err != nil {
+			return err
+		}
+		index.SetReference(ref)
+	}
+	p.skipConstraintAttributes(ctx)
+
+	table.AddIndex(index)
+	return nil
+}
+
+// skipConstraintAttributes skips DEFERRABLE, INITIALLY DEFERRED, and
+// the like, as well as index parameters such as USING INDEX TABLESPACE
+func (p *Parser) skipConstraintAttributes(ctx *parseCtx) {
+	depth := 0
+	for {
+		t := ctx.peek()
+		switch {
+		case t.kind == tokenEOF, t.isPunct(";"):
+			return
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case t.isPunct(","):
+			if depth == 0 {
+				return
+			}
+		}
+		ctx.next()
+	}
+}
+
+func (p *Parser) parseColumnList(ctx *parseCtx) ([]model.IndexColumn, error) {
+	if err := ctx.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var columns []model.IndexColumn
+	for {
+		t := ctx.peek()
+		if t.isPunct("(") {
+			return nil, newParseError(t, "unsupported expression in index")
+		}
+		name, err := p.parseIdent(ctx)
+		if err != nil {
+			return nil, err
+		}
+		col := model.NewIndexColumn(name)
+		if ctx.accept("collate") {
+			if _, _, err := p.parseName(ctx); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case ctx.accept("asc"):
+			col.SetSortDirection(model.SortDirectionAscending)
+		case ctx.accept("desc"):
+			col.SetSortDirection(model.SortDirectionDescending)
+		}
+		if ctx.accept("nulls") {
+			if !ctx.accept("first", "last") {
+				return nil, newParseError(ctx.peek(), "expected FIRST or LAST")
+			}
+		}
+		columns = append(columns, col)
+
+		if ctx.acceptPunct(",") {
+			continue
+		}
+		if err := ctx.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return columns, nil
+	}
+}
+
+var referenceOptions = map[string]model.ReferenceOption{
+	"cascade":  model.ReferenceOptionCascade,
+	"restrict": model.ReferenceOptionRestrict,
+}
+
+// parseReference parses the part of a foreign key that follows
+// REFERENCES
+func (p *Parser) parseReference(ctx *parseCtx) (model.Reference, error) {
+	name, _, err := p.parseName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ref := model.NewReference()
+	ref.SetTableName(name)
+
+	if ctx.peek().isPunct("(") {
+		columns, err := p.parseColumnList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ref.AddColumns(columns...)
+	}
+
+	if ctx.accept("match") {
+		switch t := ctx.next(); {
+		case t.is("full"):
+			ref.SetMatch(model.ReferenceMatchFull)
+		case t.is("partial"):
+			ref.SetMatch(model.ReferenceMatchPartial)
+		case t.is("simple"):
+			ref.SetMatch(model.ReferenceMatchSimple)
+		default:
+			return nil, newParseError(t, "expected FULL, PARTIAL, or SIMPLE")
+		}
+	}
+
+	for ctx.accept("on") {
+		var set func(model.ReferenceOption) model.Reference
+		switch t := ctx.next(); {
+		case t.is("delete"):
+			set = ref.SetOnDelete
+		case t.is("update"):
+			set = ref.SetOnUpdate
+		default:
+			return nil, newParseError(t, "expected DELETE or UPDATE")
+		}
+
+		t := ctx.next()
+		switch {
+		case t.is("cascade"), t.is("restrict"):
+			set(referenceOptions[t.value])
+		case t.is("no"):
+			if err := ctx.expect("action"); err != nil {
+				return nil, err
+			}
+			set(model.ReferenceOptionNoAction)
+		case t.is("set"):
+			if t := ctx.next(); !t.is("null") {
+				return nil, newParseError(t, "unsupported reference option SET %s", strings.ToUpper(t.value))
+			}
+			set(model.ReferenceOptionSetNull)
+		default:
+			return nil, newParseError(t, "expected CASCADE, RESTRICT, NO ACTION, or SET NULL")
+		}
+	}
+	return ref, nil
+}
+
+// parseExpression parses a parenthesized expression, and returns the
+// text between the parentheses
+func (p *Parser) parseExpression(ctx *parseCtx) (string, error) {
+	lparen := ctx.next()
+	if !lparen.isPunct("(") {
+		return "", newParseError(lparen, "expected (")
+	}
+	depth := 1
+	for {
+		t := ctx.next()
+		switch {
+		case t.kind == tokenEOF:
+			return "", newParseError(t, "expected )")
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(string(ctx.input[lparen.end:t.pos])), nil
+			}
+		}
+	}
+}
+
+func (p *Parser) parseCreateIndex(ctx *parseCtx, create *token, kind model.IndexKind) error {
+	ctx.accept("concurrently")
+	if ctx.accept("if") {
+		if err := ctx.expect("not", "exists"); err != nil {
+			return err
+		}
+	}
+
+	var name string
+	if !ctx.peek().is("on") {
+		n, err := p.parseIdent(ctx)
+		if err != nil {
+			return err
+		}
+		name = n
+	}
+	if err := ctx.expect("on"); err != nil {
+		return err
+	}
+	ctx.accept("only")
+
+	tableName, t, err := p.parseName(ctx)
+	if err != nil {
+		return err
+	}
+	table, err := p.lookupTable(ctx, tableName, t)
+	if err != nil {
+		return err
+	}
+
+	index := model.NewIndex(kind, table.ID())
+	if name != "" {
+		index.SetName(name)
+	}
+	index.SetPosition(ctx.position(create))
+
+	if ctx.accept("using") {
+		method := ctx.next()
+		switch {
+		case method.is("btree"):
+			index.SetType(model.IndexTypeBtree)
+		case method.is("hash"):
+			index.SetType(model.IndexTypeHash)
+		default:
+			return newParseError(method, "unsupported index method %s", method.value)
+		}
+	}
+
+	columns, err := p.parseColumnList(ctx)
+	if err != nil {
+		return err
+	}
+	index.AddColumns(columns...)
+
+	if t := ctx.peek(); t.is("where") {
+		return newParseError(t, "unsupported partial index")
+	}
+	ctx.skipStatement()
+
+	table.AddIndex(index)
+	return nil
+}
+
+// parseAlter parses `ALTER TABLE [ONLY] name ADD CONSTRAINT ...`.
+// Other ALTER statements, such as the ones that set the owner of a
+// table, are skipped.
+func (p *Parser) parseAlter(ctx *parseCtx) error {
+	ctx.next()
+	if !ctx.accept("table") {
+		ctx.skipStatement()
+		return nil
+	}
+	if ctx.accept("if") {
+		if err := ctx.expect("exists"); err != nil {
+			return err
+		}
+	}
+	ctx.accept("only")
+
+	tableName, t, err := p.parseName(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.peek().is("add") || !ctx.tokens[ctx.pos+1].is("constraint") {
+		ctx.skipStatement()
+		return nil
+	}
+	ctx.next()
+
+	table, err := p.lookupTable(ctx, tableName, t)
+	if err != nil {
+		return err
+	}
+	if err := p.parseTableConstraint(ctx, table); err != nil {
+		return err
+	}
+	ctx.skipStatement()
+	return nil
+}
+
+type parseError struct {
+	file    string
+	line    int
+	col     int
+	message string
+	eof     bool
+	token   *token
+	input   []byte
+}
+
+func newParseError(t *token, format string, args ...interface{}) error {
+	return &parseError{
+		line:    t.line,
+		col:     t.col,
+		message: fmt.Sprintf(format, args...),
+		eof:     t.kind == tokenEOF,
+		token:   t,
+	}
+}
+
+func (e *parseError) File() string    { return e.file }
+func (e *parseError) Line() int       { return e.line }
+func (e *parseError) Col() int        { return e.col }
+func (e *parseError) Message() string { return e.message }
+func (e *parseError) EOF() bool       { return e.eof }
+
+// Expected returns nil, as the expectations are only described by the
+// message
+func (e *parseError) Expected() []schemalex.TokenType { return nil }
+
+// Token returns the offending token, converted to the closest MySQL
+// token type
+func (e *parseError) Token() schemalex.Token {
+	t := e.token
+	var typ schemalex.TokenType
+	switch t.kind {
+	case tokenEOF:
+		typ = schemalex.EOF
+	case tokenIdent:
+		typ = schemalex.IDENT
+	case tokenQuotedIdent:
+		typ = schemalex.DOUBLE_QUOTE_IDENT
+	case tokenString:
+		typ = schemalex.SINGLE_QUOTE_IDENT
+	case tokenNumber:
+		typ = schemalex.NUMBER
+	default:
+		typ = punctTokens[t.value]
+	}
+	return schemalex.Token{
+		Type:  typ,
+		Value: t.value,
+		Pos:   t.pos,
+		Line:  t.line,
+		Col:   t.col,
+		EOF:   t.kind == tokenEOF,
+	}
+}
+
+var punctTokens = map[string]schemalex.TokenType{
+	"(": schemalex.LPAREN,
+	")": schemalex.RPAREN,
+	",": schemalex.COMMA,
+	";": schemalex.SEMICOLON,
+	".": schemalex.DOT,
+	"=": schemalex.EQUAL,
+}
+
+// Excerpt returns the line where the error was encountered, with a
+// caret pointing at the offending token
+func (e *parseError) Excerpt() string {
+	return util.Excerpt(e.input, e.token.pos, e.line)
+}
+
+func (e *parseError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("parse error: ")
+	buf.WriteString(e.message)
+	if e.file != "" {
+		buf.WriteString(" in file ")
+		buf.WriteString(e.file)
+	}
+	fmt.Fprintf(&buf, " at line %d column %d", e.line, e.col)
+	if e.eof {
+		buf.WriteString(" (at EOF)")
+	}
+	return buf.String()
+}
@@ -0,0 +1,125 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	const src = `-- dumped by pg_dump
+SET statement_timeout = 0;
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE public.users (
+    id bigserial PRIMARY KEY,
+    email character varying(255) NOT NULL,
+    name text DEFAULT 'anonymous'::text,
+    score numeric(10,2) DEFAULT 0 CHECK (score >= 0),
+    active boolean DEFAULT true NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE TABLE "Posts" (
+    id integer NOT NULL,
+    user_id bigint REFERENCES users (id) ON DELETE CASCADE,
+    body jsonb,
+    published_at timestamp(3) without time zone
+);
+
+CREATE SEQUENCE posts_id_seq START WITH 1;
+ALTER TABLE ONLY "Posts" ALTER COLUMN id SET DEFAULT nextval('posts_id_seq'::regclass);
+ALTER TABLE ONLY "Posts" ADD CONSTRAINT posts_pkey PRIMARY KEY (id);
+CREATE INDEX posts_published_at_idx ON public."Posts" USING btree (published_at DESC);
+
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+`
+
+	p := schemalex.NewParser(schemalex.WithDialect(postgres.Dialect))
+	stmts, err := p.ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	for i, stmt := range stmts {
+		if i > 0 {
+			buf.WriteString(";\n\n")
+		}
+		if err := format.SQL(&buf, stmt); !assert.NoError(t, err, "format should succeed") {
+			return
+		}
+	}
+	expected := "CREATE TABLE `users` (\n" +
+		"`id` BIGINT (20) NOT NULL AUTO_INCREMENT,\n" +
+		"`email` VARCHAR (255) NOT NULL,\n" +
+		"`name` TEXT DEFAULT 'anonymous',\n" +
+		"`score` DECIMAL (10,2) DEFAULT 0,\n" +
+		"`active` TINYINT (1) NOT NULL DEFAULT 1,\n" +
+		"`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"PRIMARY KEY (`id`),\n" +
+		"CONSTRAINT `users_email_key` UNIQUE INDEX `users_email_key` (`email`)\n" +
+		");\n\n" +
+		"CREATE TABLE `Posts` (\n" +
+		"`id` INT (11) NOT NULL,\n" +
+		"`user_id` BIGINT (20) DEFAULT NULL,\n" +
+		"`body` JSON DEFAULT NULL,\n" +
+		"`published_at` DATETIME DEFAULT NULL,\n" +
+		"FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,\n" +
+		"CONSTRAINT `posts_pkey` PRIMARY KEY (`id`),\n" +
+		"INDEX `posts_published_at_idx` USING BTREE (`published_at` DESC)\n" +
+		")"
+	if !assert.Equal(t, expected, buf.String(), "formatted statements should match") {
+		return
+	}
+}
+
+func TestParseError(t *testing.T) {
+	p := postgres.New()
+	_, err := p.ParseString("CREATE TABLE foo (\n  tags text[]\n);")
+	if !assert.Error(t, err, "parse should fail") {
+		return
+	}
+	pe, ok := err.(schemalex.ParseError)
+	if !assert.True(t, ok, "error should be a schemalex.ParseError") {
+		return
+	}
+	if !assert.Equal(t, 2, pe.Line(), "line should match") {
+		return
+	}
+	if !assert.Equal(t, "unsupported array type", pe.Message(), "message should match") {
+		return
+	}
+	if !assert.Equal(t, "2 |   tags text[]\n  |            ^", pe.Excerpt(), "excerpt should match") {
+		return
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var buf bytes.Buffer
+	err := diff.Strings(&buf,
+		"CREATE TABLE items (id serial PRIMARY KEY, name varchar(50));",
+		"CREATE TABLE items (id serial PRIMARY KEY, name varchar(100) NOT NULL, price numeric(8,2));",
+		diff.WithDialect(postgres.Dialect),
+		diff.WithTransaction(false),
+	)
+	if !assert.NoError(t, err, "diff should succeed") {
+		return
+	}
+	expected := "ALTER TABLE `items` ADD COLUMN `price` DECIMAL (8,2) DEFAULT NULL AFTER `name`;\n" +
+		"ALTER TABLE `items` CHANGE COLUMN `name` `name` VARCHAR (100) NOT NULL;"
+	if !assert.Equal(t, expected, buf.String(), "diff should match") {
+		return
+	}
+}
@@ -0,0 +1,31 @@
+// Package postgres provides an experimental parser for PostgreSQL
+// schemas, which reads CREATE TABLE and CREATE INDEX statements into
+// the same model as the MySQL parser, so that the diff engine can be
+// reused.
+//
+// Importing the package registers the "postgres" dialect, which can be
+// selected using schemalex.WithDialect:
+//
+//	import _ "github.com/eihigh/schemalex/postgres"
+//
+//	p := schemalex.NewParser(schemalex.WithDialect(postgres.Dialect))
+//
+// Types are mapped to the closest MySQL types, serial and identity
+// columns are read as AUTO_INCREMENT columns, and tables in the public
+// schema are named without the schema. CHECK constraints, which the
+// model does not represent, are skipped. Note that the statements
+// generated by the diff package are still written in MySQL syntax.
+package postgres
+
+import (
+	"github.com/eihigh/schemalex"
+)
+
+// Dialect is the name of the PostgreSQL dialect
+const Dialect schemalex.Dialect = "postgres"
+
+func init() {
+	schemalex.RegisterDialect(Dialect, func(options ...schemalex.Option) schemalex.DialectParser {
+		return New(options...)
+	})
+}
@@ -0,0 +1,329 @@
+package schemalex
+
+import (
+	"strings"
+)
+
+var (
+	mysql57 = MySQLVersion{Major: 5, Minor: 7}
+	mysql80 = MySQLVersion{Major: 8}
+)
+
+// reservedWords maps the reserved words of MySQL to the version that
+// reserved them. The words reserved by MySQL 5.7 are listed as such,
+// even if older versions reserved them as well.
+var reservedWords = map[string]MySQLVersion{
+	"ACCESSIBLE":                    mysql57,
+	"ADD":                           mysql57,
+	"ALL":                           mysql57,
+	"ALTER":                         mysql57,
+	"ANALYZE":                       mysql57,
+	"AND":                           mysql57,
+	"ARRAY":                         {Major: 8, Patch: 17},
+	"AS":                            mysql57,
+	"ASC":                           mysql57,
+	"ASENSITIVE":                    mysql57,
+	"BEFORE":                        mysql57,
+	"BETWEEN":                       mysql57,
+	"BIGINT":                        mysql57,
+	"BINARY":                        mysql57,
+	"BLOB":                          mysql57,
+	"BOTH":                          mysql57,
+	"BY":                            mysql57,
+	"CALL":                          mysql57,
+	"CASCADE":                       mysql57,
+	"CASE":                          mysql57,
+	"CHANGE":                        mysql57,
+	"CHAR":                          mysql57,
+	"CHARACTER":                     mysql57,
+	"CHECK":                         mysql57,
+	"COLLATE":                       mysql57,
+	"COLUMN":                        mysql57,
+	"CONDITION":                     mysql57,
+	"CONSTRAINT":                    mysql57,
+	"CONTINUE":                      mysql57,
+	"CONVERT":                       mysql57,
+	"CREATE":                        mysql57,
+	"CROSS":                         mysql57,
+	"CUBE":                          mysql80,
+	"CUME_DIST":                     mysql80,
+	"CURRENT_DATE":                  mysql57,
+	"CURRENT_TIME":                  mysql57,
+	"CURRENT_TIMESTAMP":             mysql57,
+	"CURRENT_USER":                  mysql57,
+	"CURSOR":                        mysql57,
+	"DATABASE":                      mysql57,
+	"DATABASES":                     mysql57,
+	"DAY_HOUR":                      mysql57,
+	"DAY_MICROSECOND":               mysql57,
+	"DAY_MINUTE":                    mysql57,
+	"DAY_SECOND":                    mysql57,
+	"DEC":                           mysql57,
+	"DECIMAL":                       mysql57,
+	"DECLARE":                       mysql57,
+	"DEFAULT":                       mysql57,
+	"DELAYED":                       mysql57,
+	"DELETE":                        mysql57,
+	"DENSE_RANK":                    mysql80,
+	"DESC":                          mysql57,
+	"DESCRIBE":                      mysql57,
+	"DETERMINISTIC":                 mysql57,
+	"DISTINCT":                      mysql57,
+	"DISTINCTROW":                   mysql57,
+	"DIV":                           mysql57,
+	"DOUBLE":                        mysql57,
+	"DROP":                          mysql57,
+	"DUAL":                          mysql57,
+	"EACH":                          mysql57,
+	"ELSE":                          mysql57,
+	"ELSEIF":                        mysql57,
+	"EMPTY":                         mysql80,
+	"ENCLOSED":                      mysql57,
+	"ESCAPED":                       mysql57,
+	"EXCEPT":                        mysql80,
+	"EXISTS":                        mysql57,
+	"EXIT":                          mysql57,
+	"EXPLAIN":                       mysql57,
+	"FALSE":                         mysql57,
+	"FETCH":                         mysql57,
+	"FIRST_VALUE":                   mysql80,
+	"FLOAT":                         mysql57,
+	"FLOAT4":                        mysql57,
+	"FLOAT8":                        mysql57,
+	"FOR":                           mysql57,
+	"FORCE":                         mysql57,
+	"FOREIGN":                       mysql57,
+	"FROM":                          mysql57,
+	"FULLTEXT":                      mysql57,
+	"FUNCTION":                      mysql80,
+	"GENERATED":                     mysql57,
+	"GET":                           mysql57,
+	"GRANT":                         mysql57,
+	"GROUP":                         mysql57,
+	"GROUPING":                      mysql80,
+	"GROUPS":                        mysql80,
+	"HAVING":                        mysql57,
+	"HIGH_PRIORITY":                 mysql57,
+	"HOUR_MICROSECOND":              mysql57,
+	"HOUR_MINUTE":                   mysql57,
+	"HOUR_SECOND":                   mysql57,
+	"IF":                            mysql57,
+	"IGNORE":                        mysql57,
+	"IN":                            mysql57,
+	"INDEX":                         mysql57,
+	"INFILE":                        mysql57,
+	"INNER":                         mysql57,
+	"INOUT":                         mysql57,
+	"INSENSITIVE":                   mysql57,
+	"INSERT":                        mysql57,
+	"INT":                           mysql57,
+	"INT1":                          mysql57,
+	"INT2":                          mysql57,
+	"INT3":                          mysql57,
+	"INT4":                          mysql57,
+	"INT8":                          mysql57,
+	"INTEGER":                       mysql57,
+	"INTERSECT":                     {Major: 8, Patch: 31},
+	"INTERVAL":                      mysql57,
+	"INTO":                          mysql57,
+	"IO_AFTER_GTIDS":                mysql57,
+	"IO_BEFORE_GTIDS":               mysql57,
+	"IS":                            mysql57,
+	"ITERATE":                       mysql57,
+	"JOIN":                          mysql57,
+	"JSON_TABLE":                    mysql80,
+	"KEY":                           mysql57,
+	"KEYS":                          mysql57,
+	"KILL":                          mysql57,
+	"LAG":                           mysql80,
+	"LAST_VALUE":                    mysql80,
+	"LATERAL":                       {Major: 8, Patch: 14},
+	"LEAD":                          mysql80,
+	"LEADING":                       mysql57,
+	"LEAVE":                         mysql57,
+	"LEFT":                          mysql57,
+	"LIKE":                          mysql57,
+	"LIMIT":                         mysql57,
+	"LINEAR":                        mysql57,
+	"LINES":                         mysql57,
+	"LOAD":                          mysql57,
+	"LOCALTIME":                     mysql57,
+	"LOCALTIMESTAMP":                mysql57,
+	"LOCK":                          mysql57,
+	"LONG":                          mysql57,
+	"LONGBLOB":                      mysql57,
+	"LONGTEXT":                      mysql57,
+	"LOOP":                          mysql57,
+	"LOW_PRIORITY":                  mysql57,
+	"MASTER_BIND":                   mysql57,
+	"MASTER_SSL_VERIFY_SERVER_CERT": mysql57,
+	"MATCH":                         mysql57,
+	"MAXVALUE":                      mysql57,
+	"MEDIUMBLOB":                    mysql57,
+	"MEDIUMINT":                     mysql57,
+	"MEDIUMTEXT":                    mysql57,
+	"MEMBER":                        {Major: 8, Patch: 17},
+	"MIDDLEINT":                     mysql57,
+	"MINUTE_MICROSECOND":            mysql57,
+	"MINUTE_SECOND":                 mysql57,
+	"MOD":                           mysql57,
+	"MODIFIES":                      mysql57,
+	"NATURAL":                       mysql57,
+	"NOT":                           mysql57,
+	"NO_WRITE_TO_BINLOG":            mysql57,
+	"NTH_VALUE":                     mysql80,
+	"NTILE":                         mysql80,
+	"NULL":                          mysql57,
+	"NUMERIC":                       mysql57,
+	"OF":                            mysql80,
+	"ON":                            mysql57,
+	"OPTIMIZE":                      mysql57,
+	"OPTIMIZER_COSTS":               mysql57,
+	"OPTION":                        mysql57,
+	"OPTIONALLY":                    mysql57,
+	"OR":                            mysql57,
+	"ORDER":                         mysql57,
+	"OUT":                           mysql57,
+	"OUTER":                         mysql57,
+	"OUTFILE":                       mysql57,
+	"OVER":                          mysql80,
+	"PARTITION":                     mysql57,
+	"PERCENT_RANK":                  mysql80,
+	"PRECISION":                     mysql57,
+	"PRIMARY":                       mysql57,
+	"PROCEDURE":                     mysql57,
+	"PURGE":                         mysql57,
+	"RANGE":                         mysql57,
+	"RANK":                          mysql80,
+	"READ":                          mysql57,
+	"READS":                         mysql57,
+	"READ_WRITE":                    mysql57,
+	"REAL":                          mysql57,
+	"RECURSIVE":                     mysql80,
+	"REFERENCES":                    mysql57,
+	"REGEXP":                        mysql57,
+	"RELEASE":                       mysql57,
+	"RENAME":                        mysql57,
+	"REPEAT":                        mysql57,
+	"REPLACE":                       mysql57,
+	"REQUIRE":                       mysql57,
+	"RESIGNAL":                      mysql57,
+	"RESTRICT":                      mysql57,
+	"RETURN":                        mysql57,
+	"REVOKE":                        mysql57,
+	"RIGHT":                         mysql57,
+	"RLIKE":                         mysql57,
+	"ROW":                           mysql80,
+	"ROWS":                          mysql80,
+	"ROW_NUMBER":                    mysql80,
+	"SCHEMA":                        mysql57,
+	"SCHEMAS":                       mysql57,
+	"SECOND_MICROSECOND":            mysql57,
+	"SELECT":                        mysql57,
+	"SENSITIVE":                     mysql57,
+	"SEPARATOR":                     mysql57,
+	"SET":                           mysql57,
+	"SHOW":                          mysql57,
+	"SIGNAL":                        mysql57,
+	"SMALLINT":                      mysql57,
+	"SPATIAL":                       mysql57,
+	"SPECIFIC":                      mysql57,
+	"SQL":                           mysql57,
+	"SQLEXCEPTION":                  mysql57,
+	"SQLSTATE":                      mysql57,
+	"SQLWARNING":                    mysql57,
+	"SQL_BIG_RESULT":                mysql57,
+	"SQL_CALC_FOUND_ROWS":           mysql57,
+	"SQL_SMALL_RESULT":              mysql57,
+	"SSL":                           mysql57,
+	"STARTING":                      mysql57,
+	"STORED":                        mysql57,
+	"STRAIGHT_JOIN":                 mysql57,
+	"SYSTEM":                        mysql80,
+	"TABLE":                         mysql57,
+	"TERMINATED":                    mysql57,
+	"THEN":                          mysql57,
+	"TINYBLOB":                      mysql57,
+	"TINYINT":                       mysql57,
+	"TINYTEXT":                      mysql57,
+	"TO":                            mysql57,
+	"TRAILING":                      mysql57,
+	"TRIGGER":                       mysql57,
+	"TRUE":                          mysql57,
+	"UNDO":                          mysql57,
+	"UNION":                         mysql57,
+	"UNIQUE":                        mysql57,
+	"UNLOCK":                        mysql57,
+	"UNSIGNED":                      mysql57,
+	"UPDATE":                        mysql57,
+	"USAGE":                         mysql57,
+	"USE":                           mysql57,
+	"USING":                         mysql57,
+	"UTC_DATE":                      mysql57,
+	"UTC_TIME":                      mysql57,
+	"UTC_TIMESTAMP":                 mysql57,
+	"VALUES":                        mysql57,
+	"VARBINARY":                     mysql57,
+	"VARCHAR":                       mysql57,
+	"VARCHARACTER":                  mysql57,
+	"VARYING":                       mysql57,
+	"VIRTUAL":                       mysql57,
+	"WHEN":                          mysql57,
+	"WHERE":                         mysql57,
+	"WHILE":                         mysql57,
+	"WINDOW":                        mysql80,
+	"WITH":                          mysql57,
+	"WRITE":                         mysql57,
+	"XOR":                           mysql57,
+	"YEAR_MONTH":                    mysql57,
+	"ZEROFILL":                      mysql57,
+}
+
+// unreservedWords maps the words that MySQL 5.7 reserved to the
+// version that does not reserve them anymore
+var unreservedWords = map[string]MySQLVersion{
+	"ANALYSE":         mysql80,
+	"PARSE_GCOL_EXPR": mysql80,
+}
+
+// IsReservedWord returns true if the word, such as "rank", is reserved
+// by the given version of MySQL, so that it can not be used as an
+// identifier without quotes. With the zero version, the words reserved
+// by the latest version are considered.
+func IsReservedWord(word string, v MySQLVersion) bool {
+	word = strings.ToUpper(word)
+	if since, ok := reservedWords[word]; ok {
+		return v.AtLeast(since.Major, since.Minor, since.Patch)
+	}
+	if until, ok := unreservedWords[word]; ok {
+		return !v.AtLeast(until.Major, until.Minor, until.Patch)
+	}
+	return false
+}
+
+// NeedsQuotes returns true if the identifier must be quoted with
+// backticks to be used in statements for the given version of MySQL:
+// it is a reserved word, it contains characters other than letters,
+// digits, underscores and dollar signs, or it starts with a digit.
+// Identifiers that are keywords of the parser, such as COMMENT, also
+// need quotes so that the statements can be parsed again.
+func NeedsQuotes(ident string, v MySQLVersion) bool {
+	if ident == "" || IsReservedWord(ident, v) {
+		return true
+	}
+	if _, ok := keywordIdentMap[strings.ToUpper(ident)]; ok {
+		return true
+	}
+	for i, r := range ident {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == '$':
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package schemalex_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReservedWord(t *testing.T) {
+	mysql57 := schemalex.MySQLVersion{Major: 5, Minor: 7}
+	mysql8013 := schemalex.MySQLVersion{Major: 8, Patch: 13}
+	mysql8014 := schemalex.MySQLVersion{Major: 8, Patch: 14}
+
+	specs := []struct {
+		Word     string
+		Version  schemalex.MySQLVersion
+		Reserved bool
+	}{
+		{Word: "select", Version: mysql57, Reserved: true},
+		{Word: "Key", Reserved: true},
+		{Word: "rank", Version: mysql57, Reserved: false},
+		{Word: "rank", Version: mysql8013, Reserved: true},
+		{Word: "lateral", Version: mysql8013, Reserved: false},
+		{Word: "lateral", Version: mysql8014, Reserved: true},
+		{Word: "analyse", Version: mysql57, Reserved: true},
+		{Word: "analyse", Version: mysql8013, Reserved: false},
+		{Word: "analyse", Reserved: false},
+		{Word: "comment", Reserved: false},
+		{Word: "users", Reserved: false},
+	}
+	for _, spec := range specs {
+		if !assert.Equal(t, spec.Reserved, schemalex.IsReservedWord(spec.Word, spec.Version), "%s in %s", spec.Word, spec.Version) {
+			return
+		}
+	}
+}
+
+func TestNeedsQuotes(t *testing.T) {
+	for ident, expected := range map[string]bool{
+		"users":      false,
+		"user_id":    false,
+		"price$":     false,
+		"t1":         false,
+		"":           true,
+		"1t":         true,
+		"first name": true,
+		"ünicode":    true,
+		"order":      true,
+		"comment":    true,
+	} {
+		if !assert.Equal(t, expected, schemalex.NeedsQuotes(ident, schemalex.MySQLVersion{}), "%q", ident) {
+			return
+		}
+	}
+}
@@ -1,6 +1,16 @@
 //go:generate go run internal/cmd/gentokens/main.go
 //go:generate go run internal/cmd/gencoltypes/main.go
+//go:generate go run internal/cmd/gencorpusdoc/main.go
 
+// Package schemalex parses MySQL CREATE TABLE statements into the
+// statements of the model package, and reads schemas from sources such
+// as files, git repositories, and running databases.
+//
+// The exported API of this package, and of the model, format, diff,
+// schemasource, lint, and apply packages, follows semantic versioning:
+// new behaviors are added as new functions, types, or options, and the
+// existing signatures do not change within a major version. Packages
+// under internal are not part of the API.
 package schemalex
 
 // Version contains the version number. Note that this does
@@ -0,0 +1,140 @@
+package schemasource
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/eihigh/schemalex/internal/errors"
+)
+
+// FileMarker is the prefix of the comment line written by Directory
+// before the contents of each file. schemalex.Parser uses it to report
+// errors with the position within the original file.
+const FileMarker = "-- schemalex:file "
+
+// Directory reads the schema from the files in a directory, such as
+// a directory that holds one CREATE TABLE statement per file
+type Directory struct {
+	dir     string
+	include []string
+	exclude []string
+}
+
+// NewDirectory creates a source that concatenates the files in the
+// directory and its subdirectories, in lexical order of their paths.
+// Only the files matching WithInclude are read, which defaults to
+// "*.sql", and the files matching WithExclude are skipped.
+func NewDirectory(dir string, options ...Option) *Directory {
+	s := &Directory{dir: dir}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyInclude:
+			s.include = append(s.include, o.Value().([]string)...)
+		case optkeyExclude:
+			s.exclude = append(s.exclude, o.Value().([]string)...)
+		}
+	}
+	if len(s.include) == 0 {
+		s.include = []string{"*.sql"}
+	}
+	return s
+}
+
+// String returns the path of the directory
+func (s *Directory) String() string {
+	return s.dir
+}
+
+// Files returns the paths of the files that are read, in order
+func (s *Directory) Files() ([]string, error) {
+	for _, pattern := range append(s.include, s.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, `invalid pattern %s`, pattern)
+		}
+	}
+
+	var files []string
+	err := filepath.Walk(s.dir, func(fn string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, fn)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAny(s.include, rel) && !matchAny(s.exclude, rel) {
+			files = append(files, fn)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to list files in %s`, s.dir)
+	}
+	return files, nil
+}
+
+// Open concatenates the files. The contents of each file are preceded
+// by a FileMarker line, and followed by a semicolon if the file does
+// not end with one.
+func (s *Directory) Open(ctx context.Context) (io.ReadCloser, error) {
+	files, err := s.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, fn := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		src, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to read file %s`, fn)
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(FileMarker)
+		buf.WriteString(fn)
+		buf.WriteByte('\n')
+		buf.Write(src)
+		if trimmed := bytes.TrimSpace(src); len(trimmed) > 0 && trimmed[len(trimmed)-1] != ';' {
+			buf.WriteByte(';')
+		}
+		if len(src) > 0 && src[len(src)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return ioutil.NopCloser(&buf), nil
+}
+
+// WriteSchema writes the concatenated files to dst
+func (s *Directory) WriteSchema(dst io.Writer) error {
+	return Copy(context.Background(), dst, s)
+}
+
+// matchAny returns true if the slash separated path, or its base name,
+// matches any of the patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
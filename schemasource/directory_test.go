@@ -0,0 +1,63 @@
+package schemasource_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eihigh/schemalex/schemasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemalex-dir-")
+	if !assert.NoError(t, err, "creating temporary directory should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"b.sql":           "CREATE TABLE `b` ( `id` INTEGER );\n",
+		"a.sql":           "CREATE TABLE `a` ( `id` INTEGER )",
+		"sub/c.sql":       "CREATE TABLE `c` ( `id` INTEGER );\n",
+		"tmp_d.sql":       "CREATE TABLE `d` ( `id` INTEGER );\n",
+		"README.md":       "# schema\n",
+		"sub/e_draft.sql": "CREATE TABLE `e` ( `id` INTEGER );\n",
+	}
+	for name, content := range files {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(fn), 0755), "creating directory should succeed") {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644), "writing file should succeed") {
+			return
+		}
+	}
+
+	src := schemasource.NewDirectory(dir, schemasource.WithExclude("tmp_*", "sub/*_draft.sql"))
+	list, err := src.Files()
+	if !assert.NoError(t, err, "listing files should succeed") {
+		return
+	}
+	expect := []string{
+		filepath.Join(dir, "a.sql"),
+		filepath.Join(dir, "b.sql"),
+		filepath.Join(dir, "sub", "c.sql"),
+	}
+	if !assert.Equal(t, expect, list, "files should match") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, src.WriteSchema(&buf), "writing schema should succeed") {
+		return
+	}
+	expectSchema := schemasource.FileMarker + expect[0] + "\nCREATE TABLE `a` ( `id` INTEGER );\n" +
+		"\n" + schemasource.FileMarker + expect[1] + "\nCREATE TABLE `b` ( `id` INTEGER );\n" +
+		"\n" + schemasource.FileMarker + expect[2] + "\nCREATE TABLE `c` ( `id` INTEGER );\n"
+	if !assert.Equal(t, expectSchema, buf.String(), "schema should match") {
+		return
+	}
+}
@@ -0,0 +1,73 @@
+package schemasource
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eihigh/schemalex/internal/errors"
+)
+
+// DefaultHTTPTimeout is the time limit of the requests made by the HTTP
+// source, unless another client is given with WithHTTPClient
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTP reads the schema from a file published at an http(s) URL
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTP creates a source that fetches the schema from the given URL.
+// The request times out after DefaultHTTPTimeout. Use WithHTTPClient to
+// specify the client used for the request, for example to set another
+// timeout.
+func NewHTTP(url string, options ...Option) *HTTP {
+	s := &HTTP{url: url, client: &http.Client{Timeout: DefaultHTTPTimeout}}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyHTTPClient:
+			s.client = o.Value().(*http.Client)
+		}
+	}
+	return s
+}
+
+// String returns the URL, without the password
+func (s *HTTP) String() string {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return "http"
+	}
+	if u.User != nil {
+		u.User = url.User(u.User.Username())
+	}
+	return u.String()
+}
+
+// Open fetches the schema. Responses with a status other than 200 OK
+// result in an error.
+func (s *HTTP) Open(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to create request for %s`, s)
+	}
+
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to fetch %s`, s)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.Errorf(`failed to fetch %s: %s`, s, res.Status)
+	}
+	return res.Body, nil
+}
+
+// WriteSchema fetches the schema and writes it to dst
+func (s *HTTP) WriteSchema(dst io.Writer) error {
+	return Copy(context.Background(), dst, s)
+}
@@ -0,0 +1,421 @@
+package schemasource
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+)
+
+// isTable is a base table as described by information_schema, from
+// which the CREATE TABLE statement is built
+type isTable struct {
+	name        string
+	engine      string
+	charset     string
+	collation   string
+	comment     string
+	columns     []isColumn
+	indexes     []*isIndex
+	foreignKeys []*isForeignKey
+}
+
+type isColumn struct {
+	name       string
+	typ        string
+	nullable   bool
+	def        sql.NullString
+	extra      string
+	charset    string
+	collation  string
+	comment    string
+	generation string
+}
+
+type isIndex struct {
+	name    string
+	unique  bool
+	typ     string
+	columns []isIndexColumn
+}
+
+type isIndexColumn struct {
+	name       string
+	subPart    string
+	descending bool
+}
+
+type isForeignKey struct {
+	name       string
+	columns    []string
+	refTable   string
+	refColumns []string
+	onUpdate   string
+	onDelete   string
+}
+
+// WriteInformationSchema writes the CREATE TABLE statements of the base
+// tables in the current database of db, built from the tables, columns,
+// indexes, and constraints listed by information_schema, rather than
+// from SHOW CREATE TABLE. This works with users that may not run SHOW
+// CREATE TABLE, and the statements do not depend on how the server
+// formats them. The tables are written in the order of their names, and
+// their AUTO_INCREMENT counters are left out. MySQL 5.7 or later is
+// required.
+func WriteInformationSchema(ctx context.Context, dst io.Writer, db *sql.DB) error {
+	tables, err := readInformationSchema(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, table := range tables {
+		writeStmt(&buf, table.String())
+	}
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+func readInformationSchema(ctx context.Context, db *sql.DB) ([]*isTable, error) {
+	var tables []*isTable
+	lookup := make(map[string]*isTable)
+
+	err := queryRows(ctx, db, `SELECT t.TABLE_NAME, t.ENGINE, t.TABLE_COLLATION, c.CHARACTER_SET_NAME, t.TABLE_COMMENT
+FROM information_schema.TABLES t
+LEFT JOIN information_schema.COLLATIONS c ON c.COLLATION_NAME = t.TABLE_COLLATION
+WHERE t.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY t.TABLE_NAME`, func(rows *sql.Rows) error {
+		var name, comment string
+		var engine, collation, charset sql.NullString
+		if err := rows.Scan(&name, &engine, &collation, &charset, &comment); err != nil {
+			return err
+		}
+		table := &isTable{
+			name:      name,
+			engine:    engine.String,
+			charset:   charset.String,
+			collation: collation.String,
+			comment:   comment,
+		}
+		tables = append(tables, table)
+		lookup[name] = table
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read information_schema.TABLES`)
+	}
+
+	err = queryRows(ctx, db, `SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA,
+CHARACTER_SET_NAME, COLLATION_NAME, COLUMN_COMMENT, GENERATION_EXPRESSION
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, ORDINAL_POSITION`, func(rows *sql.Rows) error {
+		var tableName, nullable string
+		var charset, collation sql.NullString
+		var col isColumn
+		if err := rows.Scan(&tableName, &col.name, &col.typ, &nullable, &col.def, &col.extra, &charset, &collation, &col.comment, &col.generation); err != nil {
+			return err
+		}
+		col.nullable = nullable == "YES"
+		col.charset = charset.String
+		col.collation = collation.String
+		if table, ok := lookup[tableName]; ok {
+			table.columns = append(table.columns, col)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read information_schema.COLUMNS`)
+	}
+
+	// the primary key comes first, as in SHOW CREATE TABLE
+	err = queryRows(ctx, db, `SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, COLUMN_NAME, SUB_PART, COLLATION, INDEX_TYPE
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, INDEX_NAME <> 'PRIMARY', INDEX_NAME, SEQ_IN_INDEX`, func(rows *sql.Rows) error {
+		var tableName, indexName, typ string
+		var nonUnique int
+		var columnName, subPart, collation sql.NullString
+		if err := rows.Scan(&tableName, &indexName, &nonUnique, &columnName, &subPart, &collation, &typ); err != nil {
+			return err
+		}
+		table, ok := lookup[tableName]
+		if !ok {
+			return nil
+		}
+		if !columnName.Valid {
+			return errors.Errorf(`index %s of table %s has functional key parts, which are not supported`, indexName, tableName)
+		}
+		n := len(table.indexes)
+		if n == 0 || table.indexes[n-1].name != indexName {
+			table.indexes = append(table.indexes, &isIndex{name: indexName, unique: nonUnique == 0, typ: typ})
+			n++
+		}
+		idx := table.indexes[n-1]
+		idx.columns = append(idx.columns, isIndexColumn{
+			name:       columnName.String,
+			subPart:    subPart.String,
+			descending: collation.String == "D",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read information_schema.STATISTICS`)
+	}
+
+	err = queryRows(ctx, db, `SELECT k.TABLE_NAME, k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE
+FROM information_schema.TABLE_CONSTRAINTS c
+JOIN information_schema.KEY_COLUMN_USAGE k ON k.CONSTRAINT_SCHEMA = c.CONSTRAINT_SCHEMA AND k.TABLE_NAME = c.TABLE_NAME AND k.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = c.CONSTRAINT_SCHEMA AND r.TABLE_NAME = c.TABLE_NAME AND r.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+WHERE c.TABLE_SCHEMA = DATABASE() AND c.CONSTRAINT_TYPE = 'FOREIGN KEY'
+ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`, func(rows *sql.Rows) error {
+		var tableName, name, column, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&tableName, &name, &column, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return err
+		}
+		table, ok := lookup[tableName]
+		if !ok {
+			return nil
+		}
+		n := len(table.foreignKeys)
+		if n == 0 || table.foreignKeys[n-1].name != name {
+			table.foreignKeys = append(table.foreignKeys, &isForeignKey{name: name, refTable: refTable, onUpdate: onUpdate, onDelete: onDelete})
+			n++
+		}
+		fk := table.foreignKeys[n-1]
+		fk.columns = append(fk.columns, column)
+		fk.refColumns = append(fk.refColumns, refColumn)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read information_schema.KEY_COLUMN_USAGE`)
+	}
+
+	return tables, nil
+}
+
+func queryRows(ctx context.Context, db *sql.DB, query string, fn func(*sql.Rows) error) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// String returns the CREATE TABLE statement of the table, without a
+// terminating semicolon. Character sets and collations of the columns
+// are only written if they differ from those of the table, and the
+// default RESTRICT and NO ACTION rules of the foreign keys are left
+// out, as in SHOW CREATE TABLE.
+func (t *isTable) String() string {
+	var lines []string
+	for _, col := range t.columns {
+		lines = append(lines, col.definition(t))
+	}
+	for _, idx := range t.indexes {
+		lines = append(lines, idx.definition())
+	}
+	for _, fk := range t.foreignKeys {
+		lines = append(lines, fk.definition())
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("CREATE TABLE ")
+	buf.WriteString(util.Backquote(t.name))
+	buf.WriteString(" (\n  ")
+	buf.WriteString(strings.Join(lines, ",\n  "))
+	buf.WriteString("\n)")
+	if t.engine != "" {
+		buf.WriteString(" ENGINE=")
+		buf.WriteString(t.engine)
+	}
+	if t.charset != "" {
+		buf.WriteString(" DEFAULT CHARSET=")
+		buf.WriteString(t.charset)
+	}
+	if t.collation != "" {
+		buf.WriteString(" COLLATE=")
+		buf.WriteString(t.collation)
+	}
+	if t.comment != "" {
+		buf.WriteString(" COMMENT=")
+		buf.WriteString(util.Singlequote(t.comment))
+	}
+	return buf.String()
+}
+
+func (c isColumn) definition(t *isTable) string {
+	var buf bytes.Buffer
+	buf.WriteString(util.Backquote(c.name))
+	buf.WriteByte(' ')
+	buf.WriteString(c.typ)
+	if c.charset != "" && c.charset != t.charset {
+		buf.WriteString(" CHARACTER SET ")
+		buf.WriteString(c.charset)
+	}
+	if c.collation != "" && c.collation != t.collation {
+		buf.WriteString(" COLLATE ")
+		buf.WriteString(c.collation)
+	}
+
+	extra := strings.ToLower(c.extra)
+	if c.generation != "" {
+		buf.WriteString(" GENERATED ALWAYS AS (")
+		buf.WriteString(c.generation)
+		buf.WriteByte(')')
+		if strings.Contains(extra, "stored generated") {
+			buf.WriteString(" STORED")
+		} else {
+			buf.WriteString(" VIRTUAL")
+		}
+	}
+
+	if c.nullable {
+		buf.WriteString(" NULL")
+	} else {
+		buf.WriteString(" NOT NULL")
+	}
+	if c.def.Valid && c.generation == "" {
+		buf.WriteString(" DEFAULT ")
+		buf.WriteString(c.defaultValue())
+	}
+	if strings.Contains(extra, "auto_increment") {
+		buf.WriteString(" AUTO_INCREMENT")
+	}
+	if i := strings.Index(extra, "on update "); i >= 0 {
+		buf.WriteString(" ON UPDATE ")
+		buf.WriteString(strings.ToUpper(strings.Fields(c.extra[i+len("on update "):])[0]))
+	}
+	if c.comment != "" {
+		buf.WriteString(" COMMENT ")
+		buf.WriteString(util.Singlequote(c.comment))
+	}
+	return buf.String()
+}
+
+// defaultValue returns the default of the column as it is written in
+// the column definition. information_schema does not quote the
+// defaults, so numbers, bit values, and the current time are written
+// as is, expressions (marked DEFAULT_GENERATED by MySQL 8.0) are
+// written in parentheses, and other values are quoted.
+func (c isColumn) defaultValue() string {
+	v := c.def.String
+	if isCurrentTimestamp(v) {
+		return strings.ToUpper(v)
+	}
+	if strings.Contains(strings.ToLower(c.extra), "default_generated") {
+		return "(" + v + ")"
+	}
+	if strings.HasPrefix(v, "b'") {
+		return v
+	}
+	if isNumericType(c.typ) {
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return v
+		}
+	}
+	return util.Singlequote(v)
+}
+
+func isCurrentTimestamp(v string) bool {
+	v = strings.ToLower(v)
+	return v == "now()" || v == "current_timestamp" || strings.HasPrefix(v, "current_timestamp(")
+}
+
+func isNumericType(typ string) bool {
+	typ = strings.ToLower(typ)
+	if i := strings.IndexAny(typ, "( "); i >= 0 {
+		typ = typ[:i]
+	}
+	switch typ {
+	case "bit", "tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+		"real", "double", "float", "decimal", "numeric":
+		return true
+	}
+	return false
+}
+
+func (idx *isIndex) definition() string {
+	var buf bytes.Buffer
+	switch {
+	case idx.name == "PRIMARY":
+		buf.WriteString("PRIMARY KEY")
+	case idx.typ == "FULLTEXT":
+		buf.WriteString("FULLTEXT KEY ")
+		buf.WriteString(util.Backquote(idx.name))
+	case idx.typ == "SPATIAL":
+		buf.WriteString("SPATIAL KEY ")
+		buf.WriteString(util.Backquote(idx.name))
+	case idx.unique:
+		buf.WriteString("UNIQUE KEY ")
+		buf.WriteString(util.Backquote(idx.name))
+	default:
+		buf.WriteString("KEY ")
+		buf.WriteString(util.Backquote(idx.name))
+	}
+
+	buf.WriteString(" (")
+	for i, col := range idx.columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(util.Backquote(col.name))
+		if col.subPart != "" {
+			buf.WriteByte('(')
+			buf.WriteString(col.subPart)
+			buf.WriteByte(')')
+		}
+		if col.descending {
+			buf.WriteString(" DESC")
+		}
+	}
+	buf.WriteByte(')')
+	if idx.typ == "HASH" {
+		buf.WriteString(" USING HASH")
+	}
+	return buf.String()
+}
+
+func (fk *isForeignKey) definition() string {
+	quote := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = util.Backquote(name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("CONSTRAINT ")
+	buf.WriteString(util.Backquote(fk.name))
+	buf.WriteString(" FOREIGN KEY (")
+	buf.WriteString(quote(fk.columns))
+	buf.WriteString(") REFERENCES ")
+	buf.WriteString(util.Backquote(fk.refTable))
+	buf.WriteString(" (")
+	buf.WriteString(quote(fk.refColumns))
+	buf.WriteByte(')')
+	if fk.onDelete != "" && fk.onDelete != "RESTRICT" && fk.onDelete != "NO ACTION" {
+		buf.WriteString(" ON DELETE ")
+		buf.WriteString(fk.onDelete)
+	}
+	if fk.onUpdate != "" && fk.onUpdate != "RESTRICT" && fk.onUpdate != "NO ACTION" {
+		buf.WriteString(" ON UPDATE ")
+		buf.WriteString(fk.onUpdate)
+	}
+	return buf.String()
+}
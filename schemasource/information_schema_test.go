@@ -0,0 +1,54 @@
+package schemasource
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInformationSchemaTable(t *testing.T) {
+	table := &isTable{
+		name:      "posts",
+		engine:    "InnoDB",
+		charset:   "utf8mb4",
+		collation: "utf8mb4_0900_ai_ci",
+		comment:   "blog posts",
+		columns: []isColumn{
+			{name: "id", typ: "bigint unsigned", extra: "auto_increment"},
+			{name: "user_id", typ: "int", charset: "", nullable: true},
+			{name: "title", typ: "varchar(255)", charset: "utf8mb4", collation: "utf8mb4_bin", def: sql.NullString{String: "untitled", Valid: true}},
+			{name: "score", typ: "decimal(5,2)", def: sql.NullString{String: "0.00", Valid: true}},
+			{name: "status", typ: "enum('draft','published')", charset: "utf8mb4", collation: "utf8mb4_0900_ai_ci", def: sql.NullString{String: "draft", Valid: true}, comment: "it's the status"},
+			{name: "created_at", typ: "datetime(3)", def: sql.NullString{String: "CURRENT_TIMESTAMP(3)", Valid: true}, extra: "DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)"},
+			{name: "uuid", typ: "binary(16)", def: sql.NullString{String: "uuid_to_bin(uuid())", Valid: true}, extra: "DEFAULT_GENERATED"},
+			{name: "title_length", typ: "int", nullable: true, extra: "VIRTUAL GENERATED", generation: "char_length(`title`)"},
+		},
+		indexes: []*isIndex{
+			{name: "PRIMARY", unique: true, typ: "BTREE", columns: []isIndexColumn{{name: "id"}}},
+			{name: "title", unique: true, typ: "BTREE", columns: []isIndexColumn{{name: "title", subPart: "64"}, {name: "created_at", descending: true}}},
+			{name: "user_id", typ: "BTREE", columns: []isIndexColumn{{name: "user_id"}}},
+		},
+		foreignKeys: []*isForeignKey{
+			{name: "posts_ibfk_1", columns: []string{"user_id"}, refTable: "users", refColumns: []string{"id"}, onUpdate: "RESTRICT", onDelete: "CASCADE"},
+		},
+	}
+
+	expected := "CREATE TABLE `posts` (\n" +
+		"  `id` bigint unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `user_id` int NULL,\n" +
+		"  `title` varchar(255) COLLATE utf8mb4_bin NOT NULL DEFAULT 'untitled',\n" +
+		"  `score` decimal(5,2) NOT NULL DEFAULT 0.00,\n" +
+		"  `status` enum('draft','published') NOT NULL DEFAULT 'draft' COMMENT 'it\\'s the status',\n" +
+		"  `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),\n" +
+		"  `uuid` binary(16) NOT NULL DEFAULT (uuid_to_bin(uuid())),\n" +
+		"  `title_length` int GENERATED ALWAYS AS (char_length(`title`)) VIRTUAL NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `title` (`title`(64), `created_at` DESC),\n" +
+		"  KEY `user_id` (`user_id`),\n" +
+		"  CONSTRAINT `posts_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='blog posts'"
+	if !assert.Equal(t, expected, table.String(), "statements should match") {
+		return
+	}
+}
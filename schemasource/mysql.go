@@ -0,0 +1,242 @@
+// Package schemasource provides schema sources that read the schema
+// from somewhere other than a local file, such as a running database
+// or a URL.
+package schemasource
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL reads the schema from a running MySQL database, using
+// SHOW CREATE TABLE for each table in the database, or the contents of
+// information_schema with WithInformationSchema
+type MySQL struct {
+	dsn               string
+	views             bool
+	routines          bool
+	informationSchema bool
+}
+
+// NewMySQL creates a source that reads the schema of the database
+// specified by the DSN, such as "user:pass@tcp(host:3306)/dbname".
+// See ParseDSN for the extra parameters that the DSN accepts.
+func NewMySQL(dsn string, options ...Option) *MySQL {
+	s := &MySQL{dsn: dsn, views: true, routines: true}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyViews:
+			s.views = o.Value().(bool)
+		case optkeyRoutines:
+			s.routines = o.Value().(bool)
+		case optkeyIntrospect:
+			s.informationSchema = o.Value().(bool)
+		}
+	}
+	return s
+}
+
+// ParseDSN creates a *mysql.Config struct from the given DSN.
+//
+// The extra parameters "ssl-ca", "ssl-cert", and "ssl-secret" (which
+// all should point to local file names) are respected when the "tls"
+// parameter is set to some boolean true value. In this case, the tls
+// configuration using those values is registered automatically.
+//
+// The extra parameter "introspect" is removed from the parameters sent
+// to the server. With the value "information_schema", the MySQL source
+// reads the schema as with WithInformationSchema.
+//
+// Please note that the "tls" parameter MUST BE A BOOLEAN. Otherwise
+// we expect that you have already registered your tls configuration
+// manually, and that you gave us the name of that configuration
+func ParseDSN(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse DSN`)
+	}
+
+	// tls=true&ssl-ca=file=...&ssl-cert=...&ssql-secret=...
+	if v, err := strconv.ParseBool(cfg.TLSConfig); err == nil && v {
+		sslCa := cfg.Params["ssl-ca"]
+		sslCert := cfg.Params["ssl-cert"]
+		sslSecret := cfg.Params["ssl-secret"]
+		if sslCa == "" || sslCert == "" || sslSecret == "" {
+			return nil, errors.New(`to enable tls, you must provide ssl-ca, ssl-cert, and ssl-secret parameters to the DSN`)
+		}
+
+		// When comparing two mysql schemas against eachother, we will have
+		// multiple calls to RegisterTLSConfig, and in that case we need
+		// unique names for both.
+		//
+		// Here, we do the poor man's UUID, and create a unique name
+		b := make([]byte, 16)
+		rand.Reader.Read(b)
+		b[6] = (b[6] & 0x0F) | 0x40
+		b[8] = (b[8] &^ 0x40) | 0x80
+		tlsName := fmt.Sprintf("custom-tls-%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+
+		rootCertPool := x509.NewCertPool()
+		pem, err := ioutil.ReadFile(sslCa)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to read ssl-ca file`)
+		}
+
+		if ok := rootCertPool.AppendCertsFromPEM(pem); !ok {
+			return nil, errors.New(`failed to append ssl-ca PEM to cert pool`)
+		}
+		certs, err := tls.LoadX509KeyPair(sslCert, sslSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to load X509 key pair`)
+		}
+		mysql.RegisterTLSConfig(tlsName, &tls.Config{
+			RootCAs:      rootCertPool,
+			Certificates: []tls.Certificate{certs},
+		})
+		cfg.TLSConfig = tlsName
+	}
+	return cfg, nil
+}
+
+// open connects to the database, and reports if the schema should be
+// read from information_schema
+func (s *MySQL) open() (*sql.DB, bool, error) {
+	cfg, err := ParseDSN(s.dsn)
+	if err != nil {
+		return nil, false, errors.Wrap(err, `failed to create MySQL config from source spec`)
+	}
+
+	informationSchema := s.informationSchema
+	switch v := cfg.Params["introspect"]; v {
+	case "":
+	case "information_schema":
+		informationSchema = true
+	case "show_create":
+		informationSchema = false
+	default:
+		return nil, false, errors.Errorf(`invalid introspect parameter %s: expected information_schema or show_create`, v)
+	}
+	delete(cfg.Params, "introspect")
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	return db, informationSchema, err
+}
+
+// String returns the DSN, without the password
+func (s *MySQL) String() string {
+	cfg, err := mysql.ParseDSN(s.dsn)
+	if err != nil {
+		return "mysql"
+	}
+	cfg.Passwd = ""
+	return "mysql://" + cfg.FormatDSN()
+}
+
+// Open reads the CREATE TABLE statements of all tables in the
+// database, followed by the CREATE VIEW statements unless WithViews is
+// false, and the statements that create its stored functions,
+// procedures, and triggers unless WithRoutines is false. Views are
+// written after the tables, as they may select from any of them, and
+// the routines are written last, in a DELIMITER block, as their bodies
+// may contain semicolons. With WithInformationSchema, the CREATE TABLE
+// statements are built by WriteInformationSchema.
+func (s *MySQL) Open(ctx context.Context) (io.ReadCloser, error) {
+	db, informationSchema, err := s.open()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to open connection to database`)
+	}
+	defer db.Close()
+
+	tables, views, err := listTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var name, stmt string
+	if informationSchema {
+		if err := WriteInformationSchema(ctx, &buf, db); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, table := range tables {
+			if err := db.QueryRowContext(ctx, "SHOW CREATE TABLE "+util.Backquote(table)).Scan(&name, &stmt); err != nil {
+				return nil, errors.Wrapf(err, `failed to execute 'SHOW CREATE TABLE "%s"'`, table)
+			}
+			writeStmt(&buf, stmt)
+		}
+	}
+
+	if s.views {
+		var charset, collation string
+		for _, view := range views {
+			if err := db.QueryRowContext(ctx, "SHOW CREATE VIEW "+util.Backquote(view)).Scan(&name, &stmt, &charset, &collation); err != nil {
+				return nil, errors.Wrapf(err, `failed to execute 'SHOW CREATE VIEW "%s"'`, view)
+			}
+			writeStmt(&buf, stmt)
+		}
+	}
+
+	if s.routines {
+		stmts, err := readRoutines(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		writeBlock(&buf, stmts)
+	}
+
+	return ioutil.NopCloser(&buf), nil
+}
+
+// WriteSchema writes the schema read by Open to dst
+func (s *MySQL) WriteSchema(dst io.Writer) error {
+	return Copy(context.Background(), dst, s)
+}
+
+// listTables returns the names of the base tables and the views in
+// the database, in the order returned by the server
+func listTables(ctx context.Context, db *sql.DB) ([]string, []string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW FULL TABLES")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, `failed to execute 'SHOW FULL TABLES'`)
+	}
+	defer rows.Close()
+
+	var tables, views []string
+	var name, typ string
+	for rows.Next() {
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, nil, errors.Wrap(err, `failed to scan tables`)
+		}
+		if typ == "VIEW" {
+			views = append(views, name)
+		} else {
+			tables = append(tables, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, `failed to scan tables`)
+	}
+	return tables, views, nil
+}
+
+func writeStmt(buf *bytes.Buffer, stmt string) {
+	if buf.Len() > 0 {
+		buf.WriteString("\n\n")
+	}
+	// TODO remove dynamic info. ex) AUTO_INCREMENT,PARTITION
+	buf.WriteString(stmt)
+	buf.WriteByte(';')
+}
@@ -0,0 +1,26 @@
+package schemasource_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex/schemasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDSN(t *testing.T) {
+	cfg, err := schemasource.ParseDSN("user:pass@tcp(1.2.3.4:9999)/dbname")
+	if !assert.NoError(t, err, "should be able to parse DSN") {
+		return
+	}
+	if !assert.Equal(t, "dbname", cfg.DBName, "database names should match") {
+		return
+	}
+	if !assert.Equal(t, "1.2.3.4:9999", cfg.Addr, "addresses should match") {
+		return
+	}
+
+	_, err = schemasource.ParseDSN("user:pass@tcp(1.2.3.4:9999)/dbname?tls=true")
+	if !assert.Error(t, err, "should error, because no tls configuration is provided") {
+		return
+	}
+}
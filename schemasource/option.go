@@ -0,0 +1,70 @@
+package schemasource
+
+import (
+	"net/http"
+
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+// Option is the same as schemalex.Option. It is declared here because
+// package schemalex depends on this package
+type Option = option.Option
+
+const (
+	optkeyExclude    = "exclude"
+	optkeyHTTPClient = "http-client"
+	optkeyInclude    = "include"
+	optkeyIntrospect = "introspect"
+	optkeyRoutines   = "routines"
+	optkeyViews      = "views"
+)
+
+// WithViews specifies if the CREATE VIEW statements of the views in
+// the database should be written after the tables, so that they are
+// compared as well. The default is true, as with
+// schemalex.NewMySQLSource. The server rewrites the SELECT statements
+// of views, so they only compare equal to views written as the server
+// prints them.
+func WithViews(b bool) Option {
+	return option.New(optkeyViews, b)
+}
+
+// WithRoutines specifies if the statements that create the stored
+// functions, procedures, and triggers of the database should be written
+// after the tables and views, so that they are compared as well. The
+// default is true, as with schemalex.NewMySQLSource. They are read from
+// information_schema and SHOW CREATE, which only shows the bodies to
+// their definer and to users with the privileges to read them.
+func WithRoutines(b bool) Option {
+	return option.New(optkeyRoutines, b)
+}
+
+// WithInformationSchema specifies if the MySQL source builds the CREATE
+// TABLE statements from information_schema, as WriteInformationSchema
+// does, instead of using SHOW CREATE TABLE. The default is false. It
+// can also be enabled with the "introspect=information_schema"
+// parameter of the DSN
+func WithInformationSchema(b bool) Option {
+	return option.New(optkeyIntrospect, b)
+}
+
+// WithHTTPClient specifies the client used by the HTTP source. The
+// default is a client whose requests time out after
+// DefaultHTTPTimeout
+func WithHTTPClient(c *http.Client) Option {
+	return option.New(optkeyHTTPClient, c)
+}
+
+// WithInclude specifies the glob patterns of the files read by the
+// Directory source. Patterns are matched against both the path relative
+// to the directory, using slashes, and the base name of the file. The
+// default is "*.sql"
+func WithInclude(patterns ...string) Option {
+	return option.New(optkeyInclude, patterns)
+}
+
+// WithExclude specifies the glob patterns of the files skipped by the
+// Directory source, even if they match WithInclude
+func WithExclude(patterns ...string) Option {
+	return option.New(optkeyExclude, patterns)
+}
@@ -0,0 +1,128 @@
+package schemasource
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+)
+
+// readRoutines returns the statements that create the stored functions,
+// procedures, and triggers of the database, in that order, as functions
+// may be called by procedures, and both by triggers
+func readRoutines(ctx context.Context, db *sql.DB) ([]string, error) {
+	var queries []string
+	err := queryRows(ctx, db, `SELECT ROUTINE_TYPE, ROUTINE_NAME
+FROM information_schema.ROUTINES
+WHERE ROUTINE_SCHEMA = DATABASE() AND ROUTINE_TYPE IN ('FUNCTION', 'PROCEDURE')
+ORDER BY ROUTINE_TYPE, ROUTINE_NAME`, func(rows *sql.Rows) error {
+		var typ, name string
+		if err := rows.Scan(&typ, &name); err != nil {
+			return err
+		}
+		queries = append(queries, "SHOW CREATE "+typ+" "+util.Backquote(name))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read information_schema.ROUTINES`)
+	}
+
+	err = queryRows(ctx, db, `SELECT TRIGGER_NAME
+FROM information_schema.TRIGGERS
+WHERE TRIGGER_SCHEMA = DATABASE()
+ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER`, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		queries = append(queries, "SHOW CREATE TRIGGER "+util.Backquote(name))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read information_schema.TRIGGERS`)
+	}
+
+	var stmts []string
+	for _, query := range queries {
+		stmt, err := showCreate(ctx, db, query)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// showCreate runs a SHOW CREATE FUNCTION, PROCEDURE, or TRIGGER
+// statement, and returns the statement in its result. The columns of
+// the result differ between statements and servers, so the statement
+// is looked up by the name of its column.
+func showCreate(ctx context.Context, db *sql.DB, query string) (string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", errors.Wrapf(err, `failed to execute '%s'`, query)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", errors.Wrapf(err, `failed to execute '%s'`, query)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", errors.Wrapf(err, `failed to execute '%s'`, query)
+		}
+		return "", errors.Errorf(`'%s' returned no rows`, query)
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", errors.Wrapf(err, `failed to scan the result of '%s'`, query)
+	}
+
+	for i, column := range columns {
+		switch column {
+		case "Create Function", "Create Procedure", "SQL Original Statement":
+			if !values[i].Valid {
+				// the body is only shown to the definer and to users
+				// with the privileges to read it
+				return "", errors.Errorf(`'%s' returned no statement: the user may not read its body`, query)
+			}
+			return values[i].String, nil
+		}
+	}
+	return "", errors.Errorf(`'%s' returned no statement`, query)
+}
+
+// writeBlock writes the statements in a DELIMITER block, with a
+// delimiter that none of them contains
+func writeBlock(buf *bytes.Buffer, stmts []string) {
+	if len(stmts) == 0 {
+		return
+	}
+
+	d := "$$"
+	for _, stmt := range stmts {
+		for strings.Contains(stmt, d) {
+			d += "$"
+		}
+	}
+
+	if buf.Len() > 0 {
+		buf.WriteString("\n\n")
+	}
+	buf.WriteString("DELIMITER " + d)
+	for _, stmt := range stmts {
+		buf.WriteByte('\n')
+		buf.WriteString(stmt)
+		buf.WriteString(d)
+	}
+	buf.WriteString("\nDELIMITER ;")
+}
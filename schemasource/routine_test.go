@@ -0,0 +1,26 @@
+package schemasource
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBlock(t *testing.T) {
+	var buf bytes.Buffer
+	writeBlock(&buf, nil)
+	if !assert.Equal(t, "", buf.String(), "no block should be written without statements") {
+		return
+	}
+
+	buf.WriteString("CREATE TABLE `t` (`id` int);")
+	writeBlock(&buf, []string{
+		"CREATE FUNCTION `f`() RETURNS int RETURN 1",
+		"CREATE TRIGGER `t_bi` BEFORE INSERT ON `t` FOR EACH ROW BEGIN\n  SET @s = '$$';\nEND",
+	})
+	expect := "CREATE TABLE `t` (`id` int);\n\nDELIMITER $$$\nCREATE FUNCTION `f`() RETURNS int RETURN 1$$$\nCREATE TRIGGER `t_bi` BEFORE INSERT ON `t` FOR EACH ROW BEGIN\n  SET @s = '$$';\nEND$$$\nDELIMITER ;"
+	if !assert.Equal(t, expect, buf.String(), "statements should be written in a DELIMITER block") {
+		return
+	}
+}
@@ -0,0 +1,94 @@
+package schemasource
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eihigh/schemalex/internal/errors"
+)
+
+// Source is the interface for objects that provide a schema from
+// some location. Third party sources can be made available to
+// schemalex.NewSchemaSource, and therefore to the command line tools,
+// using Register.
+type Source interface {
+	// Open returns a reader for the schema. The caller must close it
+	// after reading.
+	Open(ctx context.Context) (io.ReadCloser, error)
+	// String returns a description of the location of the schema,
+	// which is used in error messages. It should not include secrets
+	// such as passwords.
+	String() string
+}
+
+// OpenFunc creates a Source from a URI, such as "s3://bucket/schema.sql"
+type OpenFunc func(uri string) (Source, error)
+
+var registry = struct {
+	sync.RWMutex
+	schemes map[string]OpenFunc
+}{
+	schemes: make(map[string]OpenFunc),
+}
+
+// Register makes a source available for URIs with the given scheme,
+// such as "s3". Schemes are case insensitive. Registered schemes take
+// precedence over the ones built into schemalex.NewSchemaSource.
+// Register panics if the scheme is already registered.
+func Register(scheme string, fn OpenFunc) {
+	scheme = normalizeScheme(scheme)
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if fn == nil {
+		panic("schemasource: Register open func is nil")
+	}
+	if _, ok := registry.schemes[scheme]; ok {
+		panic("schemasource: Register called twice for scheme " + scheme)
+	}
+	registry.schemes[scheme] = fn
+}
+
+// Lookup returns the OpenFunc registered for the scheme
+func Lookup(scheme string) (OpenFunc, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	fn, ok := registry.schemes[normalizeScheme(scheme)]
+	return fn, ok
+}
+
+// Schemes returns the sorted list of registered schemes
+func Schemes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	schemes := make([]string, 0, len(registry.schemes))
+	for scheme := range registry.schemes {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Copy opens the source and writes the schema to dst
+func Copy(ctx context.Context, dst io.Writer, src Source) error {
+	rc, err := src.Open(ctx)
+	if err != nil {
+		return errors.Wrapf(err, `failed to open source %s`, src)
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(dst, rc); err != nil {
+		return errors.Wrapf(err, `failed to read schema from %s`, src)
+	}
+	return nil
+}
+
+func normalizeScheme(scheme string) string {
+	return strings.ToLower(scheme)
+}
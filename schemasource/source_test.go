@@ -0,0 +1,53 @@
+package schemasource_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/eihigh/schemalex/schemasource"
+	"github.com/stretchr/testify/assert"
+)
+
+type memorySource string
+
+func (s memorySource) Open(ctx context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(string(s))), nil
+}
+
+func (s memorySource) String() string {
+	return "memory"
+}
+
+func TestRegister(t *testing.T) {
+	schemasource.Register("Memory-Test", func(uri string) (schemasource.Source, error) {
+		return memorySource(strings.TrimPrefix(uri, "memory-test://")), nil
+	})
+
+	fn, ok := schemasource.Lookup("memory-test")
+	if !assert.True(t, ok, "scheme should be registered") {
+		return
+	}
+	if !assert.Contains(t, schemasource.Schemes(), "memory-test", "scheme should be listed") {
+		return
+	}
+	if !assert.Panics(t, func() { schemasource.Register("memory-test", fn) }, "registering twice should panic") {
+		return
+	}
+
+	src, err := fn("memory-test://CREATE TABLE `hoge` ( `id` INTEGER );")
+	if !assert.NoError(t, err, "creating source should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, schemasource.Copy(context.Background(), &buf, src), "copying schema should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TABLE `hoge` ( `id` INTEGER );", buf.String(), "schema should match") {
+		return
+	}
+}
@@ -2,21 +2,18 @@ package schemalex
 
 import (
 	"bytes"
-	"crypto/rand"
-	"crypto/tls"
-	"crypto/x509"
-	"database/sql"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"os/exec"
-	"strconv"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/schemasource"
 )
 
 // SchemaSource is the interface used for objects that provide us with
@@ -31,6 +28,10 @@ type readerSource struct {
 	src io.Reader
 }
 
+type sourceAdapter struct {
+	src schemasource.Source
+}
+
 type mysqlSource string
 
 type localFileSource string
@@ -42,15 +43,43 @@ type localGitSource struct {
 }
 
 // NewSchemaSource creates a SchemaSource based on the given URI.
-// Currently "-" (for stdin), "local-git://...", "mysql://...", and
-// "file://..." are supported. A string that does not match any of
-// the above patterns and has no scheme part is treated as a local file.
+// Currently "-" (for stdin), "local-git://...", "mysql://...",
+// "http(s)://...", and "file://..." are supported, as well as the
+// schemes registered using schemasource.Register. A string that does
+// not match any of the above patterns and has no scheme part is
+// treated as a local file.
+//
+// If a local path is a directory, the *.sql files in it are read in
+// lexical order. Other files can be selected using comma separated glob
+// patterns, as in "file:///path/to/dir?include=*.sql,*.ddl&exclude=tmp_*".
+//
+// A file in a git repository can be specified either as
+// "local-git:///path/to/repo?file=foo.sql&commitish=bar", or as
+// "local-git:///path/to/repo/foo.sql@bar". In the latter form, the
+// commit defaults to HEAD, and relative paths such as
+// "local-git:foo.sql@HEAD~1" are allowed.
+//
+// Schemas published at http(s) URLs are fetched with a timeout of
+// schemasource.DefaultHTTPTimeout. To use another client, create the
+// source with FromSource(schemasource.NewHTTP(url,
+// schemasource.WithHTTPClient(client))) instead.
 func NewSchemaSource(uri string) (SchemaSource, error) {
 	// "-" is a special source, denoting stdin.
 	if uri == "-" {
 		return NewReaderSource(os.Stdin), nil
 	}
 
+	// sources registered by third parties
+	if i := strings.Index(uri, "://"); i > 0 {
+		if fn, ok := schemasource.Lookup(uri[:i]); ok {
+			src, err := fn(uri)
+			if err != nil {
+				return nil, errors.Wrapf(err, `failed to create %s source`, uri[:i])
+			}
+			return FromSource(src), nil
+		}
+	}
+
 	if strings.HasPrefix(uri, "mysql://") {
 		// Treat the argument as a DSN for mysql.
 		// DSN is everything after "mysql://", so let's be lazy
@@ -67,18 +96,48 @@ func NewSchemaSource(uri string) (SchemaSource, error) {
 	case "local-git":
 		// local-git:///path/to/dir?file=foo&commitish=bar
 		q := u.Query()
-		return NewLocalGitSource(u.Path, q.Get("file"), q.Get("commitish")), nil
+		if q.Get("file") != "" {
+			return NewLocalGitSource(u.Path, q.Get("file"), q.Get("commitish")), nil
+		}
+
+		// local-git:///path/to/dir/foo@bar, or local-git:foo@bar for
+		// a path relative to the current directory
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		return newLocalGitFileSource(path), nil
+	case "http", "https":
+		return schemasource.NewHTTP(uri), nil
 	case "file", "":
 		// Eh, no remote host, please
 		if u.Host != "" && u.Host != "localhost" {
 			return nil, errors.Wrap(err, `remote hosts for file:// sources are not supported`)
 		}
+		if fi, err := os.Stat(u.Path); err == nil && fi.IsDir() {
+			// file:///path/to/dir?include=*.sql&exclude=*_test.sql
+			q := u.Query()
+			var options []schemasource.Option
+			if v := q.Get("include"); v != "" {
+				options = append(options, schemasource.WithInclude(strings.Split(v, ",")...))
+			}
+			if v := q.Get("exclude"); v != "" {
+				options = append(options, schemasource.WithExclude(strings.Split(v, ",")...))
+			}
+			return FromSource(schemasource.NewDirectory(u.Path, options...)), nil
+		}
 		return NewLocalFileSource(u.Path), nil
 	}
 
 	return nil, errors.New("invalid source")
 }
 
+// FromSource creates a SchemaSource that reads the schema from the
+// given schemasource.Source
+func FromSource(src schemasource.Source) SchemaSource {
+	return &sourceAdapter{src: src}
+}
+
 // NewReaderSource creates a SchemaSource whose contents are read from the
 // given io.Reader.
 func NewReaderSource(src io.Reader) SchemaSource {
@@ -96,7 +155,12 @@ func NewReaderSource(src io.Reader) SchemaSource {
 //
 // Please note that the "tls" parameter MUST BE A BOOLEAN. Otherwise
 // we expect that you have already registered your tls configuration
-// manually, and that you gave us the name of that configuration
+// manually, and that you gave us the name of that configuration.
+//
+// The views of the database are read after its tables, followed by its
+// stored functions, procedures, and triggers. Use schemasource.NewMySQL
+// with schemasource.WithViews(false) or schemasource.WithRoutines(false)
+// to skip them.
 func NewMySQLSource(s string) SchemaSource {
 	return mysqlSource(s)
 }
@@ -117,6 +181,29 @@ func NewLocalGitSource(gitDir, file, commitish string) SchemaSource {
 	}
 }
 
+// newLocalGitFileSource creates a SchemaSource from a path to a file
+// in a git repository, followed by "@" and the commit ID. If the
+// commit ID is omitted, HEAD is used.
+func newLocalGitFileSource(path string) SchemaSource {
+	commitish := "HEAD"
+	if i := strings.LastIndexByte(path, '@'); i >= 0 {
+		path, commitish = path[:i], path[i+1:]
+	}
+
+	// "./" makes git resolve the file relative to the directory it
+	// runs in, instead of the top level of the repository
+	return NewLocalGitSource(filepath.Dir(path), "./"+filepath.Base(path), commitish)
+}
+
+func (s *sourceAdapter) WriteSchema(dst io.Writer) error {
+	return schemasource.Copy(context.Background(), dst, s.src)
+}
+
+// String returns the name of the source, as given by the source
+func (s *sourceAdapter) String() string {
+	return s.src.String()
+}
+
 func (s *readerSource) WriteSchema(dst io.Writer) error {
 	if _, err := io.Copy(dst, s.src); err != nil {
 		return errors.Wrap(err, `failed to write schema to dst`)
@@ -126,63 +213,7 @@ func (s *readerSource) WriteSchema(dst io.Writer) error {
 
 // MySQLConfig creates a *mysql.Config struct from the given DSN.
 func (s mysqlSource) MySQLConfig() (*mysql.Config, error) {
-	cfg, err := mysql.ParseDSN(string(s))
-	if err != nil {
-		return nil, errors.Wrap(err, `failed to parse DSN`)
-	}
-
-	// because _I_ need support for tls, I'm going to handle setting up
-	// the tls stuff, by using
-	// tls=true&ssl-ca=file=...&ssl-cert=...&ssql-secret=...
-	if v, err := strconv.ParseBool(cfg.TLSConfig); err == nil && v {
-		sslCa := cfg.Params["ssl-ca"]
-		sslCert := cfg.Params["ssl-cert"]
-		sslSecret := cfg.Params["ssl-secret"]
-		if sslCa == "" || sslCert == "" || sslSecret == "" {
-			return nil, errors.New(`to enable tls, you must provide ssl-ca, ssl-cert, and ssl-secret parameters to the DSN`)
-		}
-
-		// When comparing two mysql schemas against eachother, we will have
-		// multiple calls to RegisterTLSConfig, and in that case we need
-		// unique names for both.
-		//
-		// Here, we do the poor man's UUID, and create a unique name
-		b := make([]byte, 16)
-		rand.Reader.Read(b)
-		b[6] = (b[6] & 0x0F) | 0x40
-		b[8] = (b[8] &^ 0x40) | 0x80
-		tlsName := fmt.Sprintf("custom-tls-%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
-
-		rootCertPool := x509.NewCertPool()
-		pem, err := ioutil.ReadFile(sslCa)
-		if err != nil {
-			return nil, errors.Wrap(err, `failed to read ssl-ca file`)
-		}
-
-		if ok := rootCertPool.AppendCertsFromPEM(pem); !ok {
-			return nil, errors.New(`failed to append ssl-ca PEM to cert pool`)
-		}
-		certs, err := tls.LoadX509KeyPair(sslCert, sslSecret)
-		if err != nil {
-			return nil, errors.Wrap(err, `failed to load X509 key pair`)
-		}
-		mysql.RegisterTLSConfig(tlsName, &tls.Config{
-			RootCAs:      rootCertPool,
-			Certificates: []tls.Certificate{certs},
-		})
-		cfg.TLSConfig = tlsName
-	}
-	return cfg, nil
-}
-
-func (s mysqlSource) open() (*sql.DB, error) {
-	// attempt to open connection to mysql
-	cfg, err := s.MySQLConfig()
-	if err != nil {
-		return nil, errors.Wrap(err, `failed to create MySQL config from source spec`)
-	}
-
-	return sql.Open("mysql", cfg.FormatDSN())
+	return schemasource.ParseDSN(string(s))
 }
 
 func (s localFileSource) WriteSchema(dst io.Writer) error {
@@ -199,38 +230,12 @@ func (s localFileSource) WriteSchema(dst io.Writer) error {
 }
 
 func (s mysqlSource) WriteSchema(dst io.Writer) error {
-	db, err := s.open()
-	if err != nil {
-		return errors.Wrap(err, `failed to open connection to database`)
-	}
-	defer db.Close()
-
-	tableRows, err := db.Query("SHOW TABLES")
-	if err != nil {
-		return errors.Wrap(err, `failed to execute 'SHOW TABLES'`)
-	}
-	defer tableRows.Close()
-
-	var table string
-	var tableSchema string
-	var buf bytes.Buffer
-	for tableRows.Next() {
-		if err = tableRows.Scan(&table); err != nil {
-			return errors.Wrap(err, `failed to scan tables`)
-		}
-
-		if err = db.QueryRow("SHOW CREATE TABLE `"+table+"`").Scan(&table, &tableSchema); err != nil {
-			return errors.Wrapf(err, `failed to execute 'SHOW CREATE TABLE "%s"'`, table)
-		}
-		if buf.Len() > 0 {
-			buf.WriteString("\n\n")
-		}
-		// TODO remove dynamic info. ex) AUTO_INCREMENT,PARTITION
-		buf.WriteString(tableSchema)
-		buf.WriteByte(';')
-	}
+	return schemasource.NewMySQL(string(s)).WriteSchema(dst)
+}
 
-	return NewReaderSource(&buf).WriteSchema(dst)
+// String returns the DSN, without the password
+func (s mysqlSource) String() string {
+	return schemasource.NewMySQL(string(s)).String()
 }
 
 func (s localGitSource) WriteSchema(dst io.Writer) error {
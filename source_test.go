@@ -18,6 +18,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eihigh/schemalex/schemasource"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -230,7 +231,63 @@ func TestSchemaSource(t *testing.T) {
 				},
 			},
 		},
-		{Input: "https://github.com/eihigh/schemalex", Error: true},
+		{
+			Input: "local-git:///path/to/dir/foo.sql@HEAD~1",
+			Check: []checker{
+				func(s SchemaSource) bool {
+					lgs, ok := s.(*localGitSource)
+					if !assert.True(t, ok, `expected source to be local git source, got %T`, s) {
+						return false
+					}
+
+					if !assert.Equal(t, "/path/to/dir", lgs.dir, "directory should match") {
+						return false
+					}
+					if !assert.Equal(t, "./foo.sql", lgs.file, "file should match") {
+						return false
+					}
+					if !assert.Equal(t, "HEAD~1", lgs.commitish, "commit ID should match") {
+						return false
+					}
+					return true
+				},
+			},
+		},
+		{
+			Input: "local-git:foo.sql",
+			Check: []checker{
+				func(s SchemaSource) bool {
+					lgs, ok := s.(*localGitSource)
+					if !assert.True(t, ok, `expected source to be local git source, got %T`, s) {
+						return false
+					}
+
+					if !assert.Equal(t, ".", lgs.dir, "directory should match") {
+						return false
+					}
+					if !assert.Equal(t, "./foo.sql", lgs.file, "file should match") {
+						return false
+					}
+					if !assert.Equal(t, "HEAD", lgs.commitish, "commit ID should match") {
+						return false
+					}
+					return true
+				},
+			},
+		},
+		{
+			Input: "https://example.com/schema.sql",
+			Check: []checker{
+				func(s SchemaSource) bool {
+					_, ok := s.(*schemasource.HTTP)
+					if !assert.True(t, ok, `expected source to be http source, got %T`, s) {
+						return false
+					}
+					return true
+				},
+			},
+		},
+		{Input: "ftp://example.com/schema.sql", Error: true},
 	}
 
 	for _, c := range testcases {
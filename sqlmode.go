@@ -0,0 +1,287 @@
+package schemalex
+
+import (
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+const (
+	optkeySQLMode    = "sql-mode"
+	optkeyANSIQuotes = "ansi-quotes"
+)
+
+// WithSQLMode specifies the SQL mode that the input is parsed with, as
+// if it started with `SET sql_mode = ...`, for use with NewParser. The
+// SQL mode only matters with ANSI_QUOTES, or a combination mode such
+// as ANSI that includes it, with which the strings quoted by double
+// quotes are identifiers.
+//
+// The SET sql_mode statements of the input change the SQL mode of the
+// statements that follow them, including those of the executable
+// comments written by mysqldump, such as
+// `/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='ANSI_QUOTES' */`.
+func WithSQLMode(mode string) Option {
+	return option.New(optkeySQLMode, mode)
+}
+
+// WithANSIQuotes specifies if the strings quoted by double quotes are
+// identifiers, as with the ANSI_QUOTES SQL mode, for use with NewParser.
+// It adds ANSI_QUOTES to the SQL mode given by WithSQLMode. The SET
+// sql_mode statements of the input still change the SQL mode.
+func WithANSIQuotes(b bool) Option {
+	return option.New(optkeyANSIQuotes, b)
+}
+
+// session holds the state of the parser that lasts from one statement
+// of the input to the next
+type session struct {
+	defs    *definitions
+	sqlMode string
+	vars    map[string]string // user variables that hold SQL modes, by lower case name
+	// delimiter is the statement delimiter set by a DELIMITER command,
+	// or an empty string if it is the semicolon
+	delimiter string
+	// duplicated is set once a duplicate definition is reported
+	duplicated bool
+}
+
+func newSession(sqlMode string) *session {
+	return &session{
+		defs:    newDefinitions(),
+		sqlMode: sqlMode,
+		vars:    make(map[string]string),
+	}
+}
+
+// hasANSIQuotes returns true if the SQL mode includes ANSI_QUOTES
+func hasANSIQuotes(mode string) bool {
+	for _, m := range strings.Split(mode, ",") {
+		switch strings.ToUpper(strings.TrimSpace(m)) {
+		case "ANSI_QUOTES", "ANSI", "DB2", "MAXDB", "MSSQL", "ORACLE", "POSTGRESQL":
+			return true
+		}
+	}
+	return false
+}
+
+func (pctx *parseCtx) setSQLMode(mode string) {
+	pctx.session.sqlMode = mode
+	pctx.lexer.ansiQuotes = hasANSIQuotes(mode)
+}
+
+// parseSet parses a SET statement, and applies the assignments of the
+// SQL mode. It returns false if the statement assigns other variables
+// only, such as SET NAMES.
+// https://dev.mysql.com/doc/refman/8.0/en/set-variable.html
+func (p *Parser) parseSet(ctx *parseCtx) bool {
+	start := ctx.next()
+
+	var tokens []*Token
+LOOP:
+	for {
+		switch t := ctx.next(); t.Type {
+		case SEMICOLON, EOF:
+			break LOOP
+		case SPACE, COMMENT_IDENT:
+		default:
+			tokens = append(tokens, t)
+		}
+	}
+	return ctx.applySet(start, tokens)
+}
+
+// skipToStatement is like skipWhiteSpaces, but also applies the SET
+// statements of the executable comments found between statements
+func (pctx *parseCtx) skipToStatement() {
+	for {
+		switch t := pctx.peek(); t.Type {
+		case SPACE:
+			pctx.advance()
+		case COMMENT_IDENT:
+			pctx.advance()
+			pctx.applyExecutableComment(t)
+		default:
+			return
+		}
+	}
+}
+
+// applyExecutableComment applies the SET statement of an executable
+// comment, such as `/*!40101 SET SQL_MODE='ANSI_QUOTES' */`
+func (pctx *parseCtx) applyExecutableComment(t *Token) {
+	if !strings.HasPrefix(t.value(pctx.input), "/*!") || !strings.HasSuffix(t.value(pctx.input), "*/") {
+		return
+	}
+	body := strings.TrimLeft(t.value(pctx.input)[3:len(t.value(pctx.input))-2], "0123456789")
+
+	l := newLexer([]byte(body))
+	l.ansiQuotes = pctx.lexer.ansiQuotes
+	var tokens []*Token
+	for {
+		t := l.nextToken()
+		if t.Type == EOF || t.Type == SEMICOLON {
+			break
+		}
+		if t.Type != SPACE && t.Type != COMMENT_IDENT {
+			// the value is read now, as it is not in the input of
+			// the parser
+			t.value(l.input)
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == 0 || tokens[0].Type != SET {
+		return
+	}
+	pctx.applySet(t, tokens[1:])
+}
+
+// applySet applies the assignments of a SET statement, given as its
+// tokens after SET, without spaces and comments. Only the SQL mode of
+// the session, and the user variables that the SQL mode is saved to,
+// are tracked. It returns true if the SQL mode was assigned. The values
+// of the tokens are read before the assignments are evaluated.
+func (pctx *parseCtx) applySet(start *Token, tokens []*Token) bool {
+	for _, t := range tokens {
+		t.value(pctx.input)
+	}
+
+	var assigned bool
+	for _, a := range splitAssignments(tokens) {
+		target, value := a[0], a[1]
+
+		name, user := assignmentTarget(target)
+		mode, ok := pctx.evalSQLMode(value)
+		switch {
+		case name == "":
+		case user:
+			if ok {
+				pctx.session.vars[name] = mode
+			} else {
+				delete(pctx.session.vars, name)
+			}
+		case name == "sql_mode":
+			assigned = true
+			if !ok {
+				pctx.warnf(start, "SET sql_mode to an expression is ignored")
+				continue
+			}
+			pctx.setSQLMode(mode)
+		}
+	}
+	return assigned
+}
+
+// splitAssignments splits the tokens of a SET statement into the
+// targets and the values of its assignments. Statements such as SET
+// NAMES, which have no assignment, are ignored.
+func splitAssignments(tokens []*Token) [][2][]*Token {
+	var list [][2][]*Token
+	var depth, start, eq int
+	eq = -1
+	add := func(end int) {
+		if eq > start {
+			list = append(list, [2][]*Token{tokens[start:eq], tokens[eq+1 : end]})
+		}
+	}
+	for i, t := range tokens {
+		switch t.Type {
+		case LPAREN:
+			depth++
+		case RPAREN:
+			depth--
+		case EQUAL:
+			if depth == 0 && eq < start {
+				eq = i
+			}
+		case COMMA:
+			if depth == 0 {
+				add(i)
+				start = i + 1
+			}
+		}
+	}
+	add(len(tokens))
+	return list
+}
+
+// assignmentTarget returns the lower case name of the variable assigned
+// by the target of an assignment, and whether it is a user variable.
+// It returns an empty name for the variables of other scopes than the
+// session, such as GLOBAL variables.
+func assignmentTarget(target []*Token) (string, bool) {
+	// the colon of :=
+	if n := len(target); n > 0 && target[n-1].Value == ":" {
+		target = target[:n-1]
+	}
+
+	var ats int
+	for ats < len(target) && target[ats].Value == "@" {
+		ats++
+	}
+	target = target[ats:]
+	switch ats {
+	case 1:
+		if len(target) != 1 {
+			return "", false
+		}
+		return strings.ToLower(target[0].Value), true
+	case 2:
+		// @@SESSION.sql_mode
+		if len(target) == 3 && target[1].Type == DOT {
+			if !isSessionScope(target[0].Value) {
+				return "", false
+			}
+			target = target[2:]
+		}
+	default:
+		// SESSION sql_mode
+		if len(target) == 2 {
+			if !isSessionScope(target[0].Value) {
+				return "", false
+			}
+			target = target[1:]
+		}
+	}
+	if len(target) != 1 {
+		return "", false
+	}
+	return strings.ToLower(target[0].Value), false
+}
+
+func isSessionScope(s string) bool {
+	switch strings.ToUpper(s) {
+	case "SESSION", "LOCAL":
+		return true
+	}
+	return false
+}
+
+// evalSQLMode returns the SQL mode given by the value of an assignment,
+// which may be a string, DEFAULT, the current SQL mode, or a user
+// variable that holds an SQL mode
+func (pctx *parseCtx) evalSQLMode(value []*Token) (string, bool) {
+	switch len(value) {
+	case 1:
+		switch t := value[0]; t.Type {
+		case SINGLE_QUOTE_IDENT, DOUBLE_QUOTE_IDENT, BACKTICK_IDENT, IDENT:
+			return t.Value, true
+		case DEFAULT:
+			return "", true
+		}
+	case 2:
+		// @var
+		if value[0].Value == "@" {
+			mode, ok := pctx.session.vars[strings.ToLower(value[1].Value)]
+			return mode, ok
+		}
+	}
+
+	// @@sql_mode, or @@SESSION.sql_mode
+	if len(value) > 2 && value[0].Value == "@" && value[1].Value == "@" {
+		if name, _ := assignmentTarget(value); name == "sql_mode" {
+			return pctx.session.sqlMode, true
+		}
+	}
+	return "", false
+}
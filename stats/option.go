@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/internal/option"
+	"github.com/eihigh/schemalex/lint"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyJSON = "json"
+	optkeyTop  = "top"
+)
+
+// WithDefaultCharset specifies the character set of the columns whose
+// tables do not declare one, which Analyze uses to compute the size of
+// the rows. The default is utf8mb4
+func WithDefaultCharset(name string) Option {
+	return lint.WithDefaultCharset(name)
+}
+
+// WithCharsetWidths specifies the maximum number of bytes per character
+// of character sets, which Analyze uses to compute the size of the
+// rows. See lint.WithCharsetWidths
+func WithCharsetWidths(widths map[string]int) Option {
+	return lint.WithCharsetWidths(widths)
+}
+
+// WithJSON specifies if Write should write the report as JSON instead
+// of text
+func WithJSON(b bool) Option {
+	return option.New(optkeyJSON, b)
+}
+
+// WithTop specifies the number of tables listed by Write as having the
+// widest rows. The default is 10, and 0 lists all the tables
+func WithTop(n int) Option {
+	return option.New(optkeyTop, n)
+}
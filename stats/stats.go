@@ -0,0 +1,188 @@
+// Package stats reports the size and the complexity of a schema: the
+// number of tables, columns, indexes and foreign keys, the foreign keys
+// between the tables, the tables with the widest rows, and the tables
+// without a primary key. This helps to audit large legacy schemas.
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/lint"
+	"github.com/eihigh/schemalex/model"
+)
+
+// TableStats describes a single table
+type TableStats struct {
+	Name string `json:"name"`
+	// Columns is the number of columns of the table
+	Columns int `json:"columns"`
+	// Indexes is the number of indexes declared by the table, including
+	// the primary key. The foreign keys, and the indexes that are
+	// created implicitly for them, are not counted
+	Indexes int `json:"indexes"`
+	// ForeignKeys is the number of foreign keys of the table
+	ForeignKeys int `json:"foreign_keys"`
+	// FanOut is the number of other tables of the schema that the
+	// table references
+	FanOut int `json:"fan_out"`
+	// FanIn is the number of other tables of the schema that reference
+	// the table
+	FanIn int `json:"fan_in"`
+	// RowSize is the maximum size of a row in bytes, as counted for the
+	// row size limit of MySQL. See lint.RowSize
+	RowSize int `json:"row_size"`
+	// PrimaryKey is true if the table has a primary key
+	PrimaryKey bool `json:"primary_key"`
+}
+
+// Report holds the statistics of a schema
+type Report struct {
+	Tables      int `json:"tables"`
+	Columns     int `json:"columns"`
+	Indexes     int `json:"indexes"`
+	ForeignKeys int `json:"foreign_keys"`
+	// TableStats lists the statistics of each table, in schema order
+	TableStats []TableStats `json:"table_stats"`
+}
+
+// Analyze computes the statistics of the tables declared by the
+// statements. Statements other than tables are ignored, and so are the
+// tables that are declared again.
+func Analyze(stmts model.Stmts, options ...Option) *Report {
+	graph := stmts.Dependencies()
+	r := &Report{TableStats: []TableStats{}}
+	for _, table := range graph.Tables() {
+		ts := TableStats{
+			Name:    table.Name(),
+			FanOut:  len(graph.DependsOn(table.Name())),
+			FanIn:   len(graph.Dependents(table.Name())),
+			RowSize: lint.RowSize(table, options...),
+		}
+		for range table.Columns() {
+			ts.Columns++
+		}
+		fks := make(map[model.Position]struct{})
+		for idx := range table.Indexes() {
+			if idx.IsForeignKey() {
+				ts.ForeignKeys++
+				fks[idx.Position()] = struct{}{}
+			}
+		}
+		for idx := range table.Indexes() {
+			if idx.IsForeignKey() {
+				continue
+			}
+			// implicit indexes share the position of the foreign key
+			if _, ok := fks[idx.Position()]; ok && idx.Position().IsValid() {
+				continue
+			}
+			if idx.IsPrimaryKey() {
+				ts.PrimaryKey = true
+			}
+			ts.Indexes++
+		}
+
+		r.Tables++
+		r.Columns += ts.Columns
+		r.Indexes += ts.Indexes
+		r.ForeignKeys += ts.ForeignKeys
+		r.TableStats = append(r.TableStats, ts)
+	}
+	return r
+}
+
+// WidestRows returns the n tables with the widest rows, from the widest
+// to the narrowest. Tables with rows of the same size are returned in
+// schema order. All the tables are returned if n is 0.
+func (r *Report) WidestRows(n int) []TableStats {
+	list := append([]TableStats(nil), r.TableStats...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].RowSize > list[j].RowSize
+	})
+	if n > 0 && n < len(list) {
+		list = list[:n]
+	}
+	return list
+}
+
+// WithoutPrimaryKey returns the names of the tables that have no
+// primary key, in schema order
+func (r *Report) WithoutPrimaryKey() []string {
+	var names []string
+	for _, ts := range r.TableStats {
+		if !ts.PrimaryKey {
+			names = append(names, ts.Name)
+		}
+	}
+	return names
+}
+
+// Write writes the report as text, or as JSON if WithJSON(true) is
+// specified. The text report starts with the totals, followed by the
+// statistics of each table, the tables with the widest rows, and the
+// tables without a primary key.
+func (r *Report) Write(w io.Writer, options ...Option) error {
+	asJSON := false
+	top := 10
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyJSON:
+			asJSON = o.Value().(bool)
+		case optkeyTop:
+			top = o.Value().(int)
+		}
+	}
+
+	if asJSON {
+		buf, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, `failed to encode report`)
+		}
+		buf = append(buf, '\n')
+		if _, err := w.Write(buf); err != nil {
+			return errors.Wrap(err, `failed to write report`)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "tables:\t%d\n", r.Tables)
+	fmt.Fprintf(tw, "columns:\t%d\n", r.Columns)
+	fmt.Fprintf(tw, "indexes:\t%d\n", r.Indexes)
+	fmt.Fprintf(tw, "foreign keys:\t%d\n", r.ForeignKeys)
+	tw.Flush()
+
+	if r.Tables > 0 {
+		buf.WriteByte('\n')
+		tw = tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+		fmt.Fprint(tw, "TABLE\tCOLUMNS\tINDEXES\tFOREIGN KEYS\tFAN-IN\tFAN-OUT\tROW SIZE\n")
+		for _, ts := range r.TableStats {
+			fmt.Fprintf(tw, "`%s`\t%d\t%d\t%d\t%d\t%d\t%d\n", ts.Name, ts.Columns, ts.Indexes, ts.ForeignKeys, ts.FanIn, ts.FanOut, ts.RowSize)
+		}
+		tw.Flush()
+
+		buf.WriteString("\nwidest rows:\n")
+		for _, ts := range r.WidestRows(top) {
+			fmt.Fprintf(&buf, "  `%s` (%d bytes)\n", ts.Name, ts.RowSize)
+		}
+	}
+
+	if names := r.WithoutPrimaryKey(); len(names) > 0 {
+		buf.WriteString("\ntables without a primary key:\n")
+		for _, name := range names {
+			fmt.Fprintf(&buf, "  `%s`\n", name)
+		}
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return errors.Wrap(err, `failed to write report`)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package stats_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	const src = `CREATE TABLE users (
+  id INT NOT NULL,
+  name VARCHAR (100) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY by_name (name)
+);
+CREATE TABLE orders (
+  id INT NOT NULL,
+  user_id INT NOT NULL,
+  parent_id INT,
+  note TEXT,
+  PRIMARY KEY (id),
+  KEY by_user (user_id),
+  CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id),
+  CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES orders (id)
+);
+CREATE TABLE logs (
+  user_id INT,
+  message VARCHAR (20),
+  CONSTRAINT fk_log_user FOREIGN KEY (user_id) REFERENCES users (id)
+) DEFAULT CHARACTER SET latin1;`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	r := stats.Analyze(stmts)
+	expect := []stats.TableStats{
+		{Name: "users", Columns: 2, Indexes: 2, FanIn: 2, RowSize: 4 + 400 + 2, PrimaryKey: true},
+		{Name: "orders", Columns: 4, Indexes: 2, ForeignKeys: 2, FanOut: 1, RowSize: 4 + 4 + 4 + 10 + 1, PrimaryKey: true},
+		{Name: "logs", Columns: 2, ForeignKeys: 1, FanOut: 1, RowSize: 4 + 20 + 1 + 1},
+	}
+	if !assert.Equal(t, expect, r.TableStats, "table statistics should match") {
+		return
+	}
+	if !assert.Equal(t, []int{3, 8, 4, 3}, []int{r.Tables, r.Columns, r.Indexes, r.ForeignKeys}, "totals should match") {
+		return
+	}
+	if !assert.Equal(t, []string{"logs"}, r.WithoutPrimaryKey(), "tables without a primary key should match") {
+		return
+	}
+
+	widest := r.WidestRows(2)
+	if !assert.Len(t, widest, 2, "WidestRows should return 2 tables") {
+		return
+	}
+	if !assert.Equal(t, []string{"users", "logs"}, []string{widest[0].Name, widest[1].Name}, "widest rows should match") {
+		return
+	}
+
+	if !assert.Equal(t, 4+300+2, stats.Analyze(stmts, stats.WithDefaultCharset("utf8mb3")).TableStats[0].RowSize, "row size should use the default charset") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, r.Write(&buf, stats.WithTop(2)), "Write should succeed") {
+		return
+	}
+	const text = "tables:        3\n" +
+		"columns:       8\n" +
+		"indexes:       4\n" +
+		"foreign keys:  3\n" +
+		"\n" +
+		"TABLE     COLUMNS  INDEXES  FOREIGN KEYS  FAN-IN  FAN-OUT  ROW SIZE\n" +
+		"`users`   2        2        0             2       0        406\n" +
+		"`orders`  4        2        2             0       1        23\n" +
+		"`logs`    2        0        1             0       1        26\n" +
+		"\n" +
+		"widest rows:\n" +
+		"  `users` (406 bytes)\n" +
+		"  `logs` (26 bytes)\n" +
+		"\n" +
+		"tables without a primary key:\n" +
+		"  `logs`\n"
+	if !assert.Equal(t, text, buf.String(), "text report should match") {
+		return
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	stmts, err := schemalex.New().ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, PRIMARY KEY (`id`) );")
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, stats.Analyze(stmts).Write(&buf, stats.WithJSON(true)), "Write should succeed") {
+		return
+	}
+	expect := `{
+  "tables": 1,
+  "columns": 1,
+  "indexes": 1,
+  "foreign_keys": 0,
+  "table_stats": [
+    {
+      "name": "hoge",
+      "columns": 1,
+      "indexes": 1,
+      "foreign_keys": 0,
+      "fan_out": 0,
+      "fan_in": 0,
+      "row_size": 4,
+      "primary_key": true
+    }
+  ]
+}
+`
+	if !assert.Equal(t, expect, buf.String(), "JSON report should match") {
+		return
+	}
+}
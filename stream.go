@@ -0,0 +1,322 @@
+package schemalex
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/model"
+)
+
+// ParseReader parses the statements read from src, and calls fn for each
+// of them, in order. Unlike Parse, the input is read a few statements at
+// a time, so that large dumps can be processed with bounded memory. The
+// data of INSERT and REPLACE statements is skipped without being kept in
+// memory.
+//
+// As the statements that were already given to fn can not be taken
+// back, DROP TABLE, DROP VIEW, DROP TRIGGER, DROP PROCEDURE, DROP
+// FUNCTION and DROP DATABASE statements are given to fn as
+// model.DropTable, model.DropView, model.DropRoutine and
+// model.DropDatabase when they drop what was given before, and can be
+// applied using model.Stmts.Apply.
+//
+// Parsing stops at the first error, or if fn returns an error, which is
+// returned as is. With WithErrorTolerance, the parse errors of all the
+// statements are returned as ParseErrors once the input is exhausted.
+// Positions and parse errors refer to the lines of the whole input, as
+// with Parse. Dialects made available by RegisterDialect read the whole
+// input before parsing it.
+func (p *Parser) ParseReader(ctx context.Context, src io.Reader, fn func(model.Stmt) error) error {
+	if !isBuiltinDialect(p.dialect) {
+		buf, err := ioutil.ReadAll(src)
+		if err != nil {
+			return errors.Wrap(err, `failed to read source`)
+		}
+		stmts, err := p.ParseContext(ctx, buf)
+		if _, ok := err.(ParseErrors); err != nil && !ok {
+			return err
+		}
+		for _, stmt := range stmts {
+			if err := fn(stmt); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	// the names of the tables, views and routines given to fn, to only
+	// give it the DROP statements that apply to them
+	tables := make(map[string]struct{})
+	views := make(map[string]struct{})
+	routines := make(map[string]struct{})
+	emit := func(stmt model.Stmt) error {
+		switch stmt := stmt.(type) {
+		case model.Table:
+			tables[stmt.Name()] = struct{}{}
+		case model.DropTable:
+			if _, ok := tables[stmt.Name()]; !ok {
+				return nil
+			}
+			if !stmt.IsTemporary() {
+				delete(tables, stmt.Name())
+			}
+		case model.View:
+			views[stmt.Name()] = struct{}{}
+		case model.DropView:
+			if _, ok := views[stmt.Name()]; !ok {
+				return nil
+			}
+			delete(views, stmt.Name())
+		case model.Routine:
+			routines[stmt.ID()] = struct{}{}
+		case model.DropRoutine:
+			id := model.NewRoutine(stmt.Kind(), stmt.Name(), "").ID()
+			if _, ok := routines[id]; !ok {
+				return nil
+			}
+			delete(routines, id)
+		case model.DropDatabase:
+			if len(tables) == 0 && len(views) == 0 && len(routines) == 0 {
+				return nil
+			}
+			tables = make(map[string]struct{})
+			views = make(map[string]struct{})
+			routines = make(map[string]struct{})
+		}
+		return fn(stmt)
+	}
+
+	r := newStatementReader(src)
+	var last *fileMarker
+	var errs ParseErrors
+	sess := newSession(p.sqlMode)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		chunk, line, err := r.next()
+		if err == io.EOF {
+			if len(errs) > 0 {
+				return errs
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, `failed to read source`)
+		}
+
+		// the file markers found in the previous chunks still apply
+		var markers []fileMarker
+		if last != nil {
+			markers = append(markers, fileMarker{line: last.line, file: last.file})
+		}
+		for _, m := range findFileMarkers(chunk) {
+			m.line += line - 1
+			markers = append(markers, m)
+		}
+		if len(markers) > 0 {
+			last = &markers[len(markers)-1]
+		}
+
+		if err := p.parse(ctx, chunk, line, markers, sess, emit); err != nil {
+			chunkErrs, ok := err.(ParseErrors)
+			if !ok {
+				return err
+			}
+			errs = append(errs, chunkErrs...)
+		}
+	}
+}
+
+// statementReader splits its input into chunks of whole lines that
+// contain complete statements, keeping track of quotes and comments
+type statementReader struct {
+	src  *bufio.Reader
+	line int // line number where the next chunk starts
+	err  error
+	// delimiter is the statement delimiter set by the DELIMITER command
+	// of the mysql client, or an empty string if it is the semicolon
+	delimiter string
+}
+
+func newStatementReader(src io.Reader) *statementReader {
+	br := bufio.NewReader(src)
+	// the columns of the first line are counted after the byte order mark
+	if b, err := br.Peek(len(byteOrderMark)); err == nil && bytes.Equal(b, byteOrderMark) {
+		br.Discard(len(byteOrderMark))
+	}
+	return &statementReader{
+		src:  br,
+		line: 1,
+	}
+}
+
+// next returns the next chunk, along with the line number where it
+// starts. The statements whose data is skipped are replaced by blanks,
+// so that the positions in the rest of the chunk do not change.
+func (r *statementReader) next() ([]byte, int, error) {
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+
+	var buf bytes.Buffer
+	var quote byte       // the quote character, within a quoted string
+	var escaped bool     // the previous character was a backslash, within a quoted string
+	var lineComment bool // within a comment that lasts until the end of the line
+	var blockComment bool
+	var pending bool    // the current statement is not terminated yet
+	var complete bool   // at least one statement has been terminated
+	var word []byte     // the first word of the current statement
+	var inWord bool     // the first word is being read
+	var skipping bool   // the current statement is being skipped
+	var skippedCols int // the characters skipped since the last new line
+	var prev byte
+	var closed bool      // a comment was just closed
+	var command bool     // the current statement is a DELIMITER command, which ends with the line
+	var commandStart int // the offset in buf of what follows DELIMITER
+
+	start := r.line
+	for {
+		c, err := r.src.ReadByte()
+		if err != nil {
+			r.err = err
+			if err == io.EOF && buf.Len() > 0 {
+				return buf.Bytes(), start, nil
+			}
+			return nil, 0, err
+		}
+
+		if inWord {
+			if isLetter(rune(c)) || c == '_' {
+				word = append(word, c)
+			} else {
+				inWord = false
+				if strings.EqualFold(string(word), "DELIMITER") {
+					command = true
+					commandStart = buf.Len()
+				} else if r.delimiter == "" && isDataStatement(string(word)) {
+					// drop the word that has already been written. With a
+					// DELIMITER, such words may be within the bodies of
+					// triggers and stored routines, which are kept.
+					buf.Truncate(buf.Len() - len(word))
+					skipping = true
+					skippedCols = len(word)
+				}
+			}
+		}
+
+		switch {
+		case command:
+			if c == '\n' {
+				if words := strings.Fields(string(buf.Bytes()[commandStart:])); len(words) > 0 {
+					r.delimiter = words[0]
+					if r.delimiter == ";" {
+						r.delimiter = ""
+					}
+				}
+				command = false
+				pending = false
+				complete = true
+			}
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quote != '`':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+		case lineComment:
+			if c == '\n' {
+				lineComment = false
+			}
+		case blockComment:
+			if prev == '*' && c == '/' {
+				blockComment = false
+				closed = true
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			pending = true
+		case c == '#':
+			lineComment = true
+		case c == '-' && r.peekDashComment(prev):
+			lineComment = true
+		case prev == '/' && c == '*':
+			blockComment = true
+		case r.delimiter != "" && c == r.delimiter[len(r.delimiter)-1] && bytes.HasSuffix(buf.Bytes(), []byte(r.delimiter[:len(r.delimiter)-1])):
+			// with a DELIMITER, semicolons do not end the statements
+			pending = false
+			complete = true
+		case c == ';' && r.delimiter == "":
+			if skipping {
+				// keep the columns of what follows on the same line
+				buf.WriteString(strings.Repeat(" ", skippedCols+1))
+				skipping = false
+				pending = false
+				complete = true
+				prev = c
+				continue
+			}
+			pending = false
+			complete = true
+		case !isSpace(rune(c)):
+			if !pending && c != '/' && c != '-' {
+				pending = true
+				word = word[:0]
+				inWord = isLetter(rune(c))
+				if inWord {
+					word = append(word, c)
+				}
+			}
+		}
+
+		if c == '\n' {
+			r.line++
+		}
+		if skipping {
+			if c == '\n' {
+				buf.WriteByte('\n')
+				skippedCols = 0
+			} else if !isUTF8Continuation(c) {
+				skippedCols++
+			}
+		} else {
+			buf.WriteByte(c)
+		}
+		prev = c
+		if closed {
+			// the slash does not start another comment
+			prev = 0
+			closed = false
+		}
+
+		if c == '\n' && complete && !pending && quote == 0 && !blockComment {
+			return buf.Bytes(), start, nil
+		}
+	}
+}
+
+// peekDashComment returns true if the dash that was just read starts a
+// comment, that is, it follows another dash and is followed by a space
+func (r *statementReader) peekDashComment(prev byte) bool {
+	if prev != '-' {
+		return false
+	}
+	b, err := r.src.Peek(1)
+	if err != nil {
+		return err == io.EOF
+	}
+	return isSpace(rune(b[0]))
+}
+
+func isUTF8Continuation(c byte) bool {
+	return c&0xC0 == 0x80
+}
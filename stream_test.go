@@ -0,0 +1,193 @@
+package schemalex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/format"
+	"github.com/eihigh/schemalex/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const dump = `-- MySQL dump 10.13
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+-- schemalex:file users.sql
+DROP TABLE IF EXISTS ` + "`users`" + `;
+CREATE TABLE ` + "`users`" + ` (
+  ` + "`id`" + ` int(11) NOT NULL AUTO_INCREMENT,
+  ` + "`name`" + ` varchar(64) DEFAULT 'a;b',
+  PRIMARY KEY (` + "`id`" + `)
+) ENGINE=InnoDB;
+
+LOCK TABLES ` + "`users`" + ` WRITE;
+/*!40000 ALTER TABLE ` + "`users`" + ` DISABLE KEYS */;
+INSERT INTO ` + "`users`" + ` VALUES (1,'it''s; \'quoted\''),(2,'-- not a comment
+/* nor this */');
+UNLOCK TABLES;
+-- schemalex:file posts.sql
+CREATE TABLE posts (id INT NOT NULL PRIMARY KEY); INSERT INTO posts VALUES (1); CREATE TABLE tags (
+  id INT NOT NULL, /* a ; comment */
+  name TEXT # another ; comment
+);
+ANALYZE TABLE posts UPDATE HISTOGRAM ON id WITH 8 BUCKETS;
+`
+
+func TestParseReader(t *testing.T) {
+	p := schemalex.New()
+	expected, err := p.ParseString(dump)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, expected, 4, "there should be 4 statements") {
+		return
+	}
+
+	var stmts model.Stmts
+	err = p.ParseReader(context.Background(), iotest.OneByteReader(strings.NewReader(dump)), func(stmt model.Stmt) error {
+		stmts = append(stmts, stmt)
+		return nil
+	})
+	if !assert.NoError(t, err, "streaming parse should succeed") {
+		return
+	}
+	if !assert.Len(t, stmts, len(expected), "the statements should match") {
+		return
+	}
+
+	for i := range expected {
+		var want, got bytes.Buffer
+		if !assert.NoError(t, format.SQL(&want, expected[i]), "format should succeed") {
+			return
+		}
+		if !assert.NoError(t, format.SQL(&got, stmts[i]), "format should succeed") {
+			return
+		}
+		if !assert.Equal(t, want.String(), got.String(), "statement %d should match", i) {
+			return
+		}
+
+		table, ok := expected[i].(model.Table)
+		if !ok {
+			continue
+		}
+		if !assert.Equal(t, table.Position(), stmts[i].(model.Table).Position(), "position of statement %d should match", i) {
+			return
+		}
+		for col := range table.Columns() {
+			c, _ := stmts[i].(model.Table).LookupColumn(col.ID())
+			if !assert.Equal(t, col.Position(), c.Position(), "position of column %s should match", col.Name()) {
+				return
+			}
+		}
+	}
+	if !assert.Equal(t, model.Position{File: "posts.sql", Line: 1, Col: 80}, stmts[2].(model.Table).Position(), "position should be within the file") {
+		return
+	}
+}
+
+func TestParseReaderDrop(t *testing.T) {
+	const src = "DROP TABLE IF EXISTS foo;\n" +
+		"CREATE TABLE foo (id INT);\n" +
+		"DROP TABLE foo;\n" +
+		"CREATE TABLE foo (id BIGINT);\n" +
+		"DROP DATABASE app;\n" +
+		"CREATE TABLE bar (id INT);\n"
+
+	var stmts model.Stmts
+	var drops []string
+	err := schemalex.New().ParseReader(context.Background(), strings.NewReader(src), func(stmt model.Stmt) error {
+		switch stmt.(type) {
+		case model.DropTable, model.DropDatabase:
+			drops = append(drops, stmt.(fmt.Stringer).String())
+		}
+		stmts = stmts.Apply(stmt)
+		return nil
+	})
+	if !assert.NoError(t, err, "streaming parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, []string{"DROP TABLE `foo`", "DROP DATABASE `app`"}, drops, "only the DROP statements that apply to previous tables should be given") {
+		return
+	}
+
+	expected, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, expected.String(), stmts.String(), "the applied statements should match") {
+		return
+	}
+}
+
+func TestParseReaderDelimiter(t *testing.T) {
+	const src = "CREATE TABLE foo (id INT, n INT);\n" +
+		"DELIMITER $$\n" +
+		"CREATE TRIGGER foo_ins AFTER INSERT ON foo FOR EACH ROW\n" +
+		"BEGIN\n" +
+		"  INSERT INTO log VALUES (NEW.id, 'a;b');\n" +
+		"END$$\n" +
+		"CREATE PROCEDURE reset() BEGIN UPDATE foo SET n = 0; END $$\n" +
+		"DELIMITER ;\n" +
+		"INSERT INTO foo VALUES (1, 2);\n" +
+		"CREATE TABLE bar (id INT);\n"
+
+	expected, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+	if !assert.Len(t, expected, 4, "there should be 4 statements") {
+		return
+	}
+	if !assert.Equal(t, "AFTER INSERT ON foo FOR EACH ROW\nBEGIN\n  INSERT INTO log VALUES (NEW.id, 'a;b');\nEND", expected[1].(model.Routine).Definition(), "the body of the trigger should be kept") {
+		return
+	}
+
+	var stmts model.Stmts
+	err = schemalex.New().ParseReader(context.Background(), iotest.OneByteReader(strings.NewReader(src)), func(stmt model.Stmt) error {
+		stmts = append(stmts, stmt)
+		return nil
+	})
+	if !assert.NoError(t, err, "streaming parse should succeed") {
+		return
+	}
+	if !assert.Equal(t, expected.String(), stmts.String(), "the statements should match") {
+		return
+	}
+}
+
+func TestParseReaderError(t *testing.T) {
+	const src = "-- schemalex:file foo.sql\nCREATE TABLE foo (id int PRIMARY KEY);\n\n-- schemalex:file bar.sql\n\nCREATE TABLE bar (id int PRIMARY KEY baz TEXT)"
+
+	p := schemalex.New()
+	_, expected := p.ParseString(src)
+	if !assert.Error(t, expected, "parse should fail") {
+		return
+	}
+
+	err := p.ParseReader(context.Background(), strings.NewReader(src), func(model.Stmt) error { return nil })
+	if !assert.Error(t, err, "streaming parse should fail") {
+		return
+	}
+	if !assert.Equal(t, expected.Error(), err.Error(), "errors should match") {
+		return
+	}
+
+	stop := errors.New("stop")
+	var count int
+	err = p.ParseReader(context.Background(), strings.NewReader(dump), func(model.Stmt) error {
+		count++
+		return stop
+	})
+	if !assert.Equal(t, stop, err, "the error of the callback should be returned") {
+		return
+	}
+	if !assert.Equal(t, 1, count, "parse should stop at the first error") {
+		return
+	}
+}
@@ -0,0 +1,458 @@
+// Package structs builds tables from Go structs, so that the models
+// defined in code can be compared against a database or a schema file
+// with the diff package, or written as CREATE TABLE statements.
+//
+// Each exported field of a struct is a column. Columns are named after
+// the fields in snake case, such as user_id for UserID, and their types
+// follow the types of the fields: integers, floats, bool, string,
+// []byte, time.Time, and the types of database/sql such as
+// sql.NullString are supported. Fields of embedded structs are columns
+// of the table too. Pointers and the sql.Null types are nullable, and
+// other columns are NOT NULL.
+//
+// The columns are customized with the schemalex tag, which holds a list
+// of settings separated by semicolons:
+//
+//	type User struct {
+//		ID        uint64    `schemalex:"primary_key;auto_increment"`
+//		Email     string    `schemalex:"size:191;unique"`
+//		Name      string    `schemalex:"column:display_name;index"`
+//		Balance   float64   `schemalex:"type:DECIMAL(10,2);default:0"`
+//		CreatedAt time.Time `schemalex:"default:CURRENT_TIMESTAMP"`
+//		Ignored   string    `schemalex:"-"`
+//	}
+//
+// The settings are:
+//
+//	column:name      the name of the column
+//	type:TYPE        the SQL type of the column, such as DECIMAL(10,2)
+//	size:n           the length of strings and []byte, as VARCHAR(n) or
+//	                 VARBINARY(n) instead of VARCHAR(255) and BLOB
+//	primary_key      the column is part of the primary key. If no field
+//	                 has it, the column named id is the primary key
+//	auto_increment   the column is AUTO_INCREMENT
+//	null, not null   the column is nullable or NOT NULL, regardless of the
+//	                 type of the field
+//	default:value    the default value, as an SQL expression, such as 0,
+//	                 'none', or CURRENT_TIMESTAMP
+//	unique[:name]    the column is part of a unique index. Fields with the
+//	                 same name form a single index, in field order
+//	index[:name]     the column is part of an index, as with unique
+//	comment:text     the comment of the column
+//
+// Tables are named after the structs in snake case, unless the structs
+// have a TableName() string method.
+package structs
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/eihigh/schemalex"
+	_ "github.com/eihigh/schemalex/format" // for Stmts.WriteTo
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/util"
+	"github.com/eihigh/schemalex/model"
+)
+
+// types of the fields that are not mapped by their kind
+var (
+	typeTime        = reflect.TypeOf(time.Time{})
+	typeBytes       = reflect.TypeOf([]byte(nil))
+	typeNullString  = reflect.TypeOf(sql.NullString{})
+	typeNullInt64   = reflect.TypeOf(sql.NullInt64{})
+	typeNullInt32   = reflect.TypeOf(sql.NullInt32{})
+	typeNullFloat64 = reflect.TypeOf(sql.NullFloat64{})
+	typeNullBool    = reflect.TypeOf(sql.NullBool{})
+	typeNullTime    = reflect.TypeOf(sql.NullTime{})
+)
+
+// tableNamer is implemented by the structs that name their tables
+type tableNamer interface {
+	TableName() string
+}
+
+type column struct {
+	name          string
+	typ           string
+	nullable      bool
+	primary       bool
+	autoIncrement bool
+	defaultValue  string
+	hasDefault    bool
+	comment       string
+}
+
+type index struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+// definition holds the columns and indexes of a table, as read from the
+// fields of a struct
+type definition struct {
+	name    string
+	columns []*column
+	indexes []*index
+}
+
+// Table builds the table described by v, which is a struct, or a
+// pointer to a struct
+func Table(v interface{}) (model.Table, error) {
+	def, err := define(v)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, err := schemalex.New().ParseString(def.sql())
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to build table %s`, def.name)
+	}
+	if len(stmts) != 1 {
+		return nil, errors.Errorf(`failed to build table %s`, def.name)
+	}
+	table, ok := stmts[0].(model.Table)
+	if !ok {
+		return nil, errors.Errorf(`failed to build table %s`, def.name)
+	}
+	return table, nil
+}
+
+// Stmts builds the tables described by the values, in order. See Table
+func Stmts(values ...interface{}) (model.Stmts, error) {
+	stmts := make(model.Stmts, 0, len(values))
+	for _, v := range values {
+		table, err := Table(v)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, table)
+	}
+	return stmts, nil
+}
+
+// Source returns a schemalex.SchemaSource that writes the CREATE TABLE
+// statements of the tables described by the values, so that they can
+// be compared against another schema:
+//
+//	diff.Sources(os.Stdout, schemalex.NewMySQLSource(dsn), structs.Source(User{}, Post{}))
+func Source(values ...interface{}) schemalex.SchemaSource {
+	return &source{values: values}
+}
+
+type source struct {
+	values []interface{}
+}
+
+func (s *source) String() string {
+	return "go structs"
+}
+
+func (s *source) WriteSchema(dst io.Writer) error {
+	stmts, err := Stmts(s.values...)
+	if err != nil {
+		return err
+	}
+	if _, err := stmts.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+func define(v interface{}) (*definition, error) {
+	if v == nil {
+		return nil, errors.New(`a struct is required`)
+	}
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, errors.Errorf(`a struct is required, got %s`, rv.Type())
+	}
+
+	def := &definition{name: snakeCase(rt.Name())}
+	if namer, ok := v.(tableNamer); ok {
+		def.name = namer.TableName()
+	} else if namer, ok := reflect.New(rt).Interface().(tableNamer); ok {
+		def.name = namer.TableName()
+	}
+	if def.name == "" {
+		return nil, errors.Errorf(`the table of %s has no name`, rv.Type())
+	}
+
+	if err := def.addFields(rt); err != nil {
+		return nil, errors.Wrapf(err, `failed to build table %s`, def.name)
+	}
+	if len(def.columns) == 0 {
+		return nil, errors.Errorf(`table %s has no columns`, def.name)
+	}
+
+	hasPrimary := false
+	for _, col := range def.columns {
+		hasPrimary = hasPrimary || col.primary
+	}
+	if !hasPrimary {
+		for _, col := range def.columns {
+			if col.name == "id" {
+				col.primary = true
+			}
+		}
+	}
+	return def, nil
+}
+
+func (def *definition) addFields(rt reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, hasTag := field.Tag.Lookup("schemalex")
+		if tag == "-" {
+			continue
+		}
+
+		ft := field.Type
+		if field.Anonymous && !hasTag {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != typeTime {
+				if err := def.addFields(ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		if err := def.addField(field, tag); err != nil {
+			return errors.Wrapf(err, `invalid field %s`, field.Name)
+		}
+	}
+	return nil
+}
+
+func (def *definition) addField(field reflect.StructField, tag string) error {
+	col := &column{name: snakeCase(field.Name)}
+
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+		col.nullable = true
+	}
+
+	var size int
+	var typ string
+	for _, setting := range strings.Split(tag, ";") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		key, value := setting, ""
+		if i := strings.IndexByte(setting, ':'); i >= 0 {
+			key, value = strings.TrimSpace(setting[:i]), strings.TrimSpace(setting[i+1:])
+		}
+
+		switch strings.ToLower(key) {
+		case "column":
+			col.name = value
+		case "type":
+			typ = value
+		case "size":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return errors.Errorf(`invalid size %s`, value)
+			}
+			size = n
+		case "primary_key":
+			col.primary = true
+		case "auto_increment":
+			col.autoIncrement = true
+		case "null":
+			col.nullable = true
+		case "not null", "not_null":
+			col.nullable = false
+		case "default":
+			col.defaultValue = value
+			col.hasDefault = true
+		case "comment":
+			col.comment = value
+		case "unique", "index":
+			def.addIndex(value, key == "unique", col)
+		default:
+			return errors.Errorf(`unknown setting %s`, key)
+		}
+	}
+
+	if typ == "" {
+		var nullable bool
+		var err error
+		typ, nullable, err = columnType(ft, size)
+		if err != nil {
+			return err
+		}
+		col.nullable = col.nullable || nullable
+	}
+	col.typ = typ
+
+	def.columns = append(def.columns, col)
+	return nil
+}
+
+// addIndex adds the column to the index of the given name, which is
+// named after the column if the name is empty
+func (def *definition) addIndex(name string, unique bool, col *column) {
+	if name == "" {
+		name = col.name
+	}
+	for _, idx := range def.indexes {
+		if idx.name == name && idx.unique == unique {
+			idx.columns = append(idx.columns, col.name)
+			return
+		}
+	}
+	def.indexes = append(def.indexes, &index{name: name, unique: unique, columns: []string{col.name}})
+}
+
+// columnType returns the SQL type of the values of type t, and true if
+// they may be NULL
+func columnType(t reflect.Type, size int) (string, bool, error) {
+	switch t {
+	case typeTime:
+		return "DATETIME", false, nil
+	case typeBytes:
+		if size > 0 {
+			return "VARBINARY(" + strconv.Itoa(size) + ")", false, nil
+		}
+		return "BLOB", false, nil
+	case typeNullString:
+		return stringType(size), true, nil
+	case typeNullInt64:
+		return "BIGINT", true, nil
+	case typeNullInt32:
+		return "INT", true, nil
+	case typeNullFloat64:
+		return "DOUBLE", true, nil
+	case typeNullBool:
+		return "TINYINT(1)", true, nil
+	case typeNullTime:
+		return "DATETIME", true, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "TINYINT(1)", false, nil
+	case reflect.Int8:
+		return "TINYINT", false, nil
+	case reflect.Int16:
+		return "SMALLINT", false, nil
+	case reflect.Int32:
+		return "INT", false, nil
+	case reflect.Int, reflect.Int64:
+		return "BIGINT", false, nil
+	case reflect.Uint8:
+		return "TINYINT UNSIGNED", false, nil
+	case reflect.Uint16:
+		return "SMALLINT UNSIGNED", false, nil
+	case reflect.Uint32:
+		return "INT UNSIGNED", false, nil
+	case reflect.Uint, reflect.Uint64:
+		return "BIGINT UNSIGNED", false, nil
+	case reflect.Float32:
+		return "FLOAT", false, nil
+	case reflect.Float64:
+		return "DOUBLE", false, nil
+	case reflect.String:
+		return stringType(size), false, nil
+	}
+	return "", false, errors.Errorf(`unsupported type %s, which requires a type setting`, t)
+}
+
+func stringType(size int) string {
+	if size <= 0 {
+		size = 255
+	}
+	return "VARCHAR(" + strconv.Itoa(size) + ")"
+}
+
+// sql returns the CREATE TABLE statement of the table
+func (def *definition) sql() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "CREATE TABLE %s (", util.Backquote(def.name))
+
+	var primary []string
+	for i, col := range def.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "\n%s %s", util.Backquote(col.name), col.typ)
+		if col.nullable && !col.primary {
+			buf.WriteString(" NULL")
+		} else {
+			buf.WriteString(" NOT NULL")
+		}
+		if col.autoIncrement {
+			buf.WriteString(" AUTO_INCREMENT")
+		}
+		if col.hasDefault {
+			buf.WriteString(" DEFAULT ")
+			buf.WriteString(col.defaultValue)
+		}
+		if col.comment != "" {
+			buf.WriteString(" COMMENT ")
+			buf.WriteString(util.Singlequote(col.comment))
+		}
+		if col.primary {
+			primary = append(primary, util.Backquote(col.name))
+		}
+	}
+
+	if len(primary) > 0 {
+		fmt.Fprintf(&buf, ",\nPRIMARY KEY (%s)", strings.Join(primary, ", "))
+	}
+	for _, idx := range def.indexes {
+		columns := make([]string, len(idx.columns))
+		for i, name := range idx.columns {
+			columns[i] = util.Backquote(name)
+		}
+		kind := "INDEX"
+		if idx.unique {
+			kind = "UNIQUE INDEX"
+		}
+		fmt.Fprintf(&buf, ",\n%s %s (%s)", kind, util.Backquote(idx.name), strings.Join(columns, ", "))
+	}
+	buf.WriteString("\n)")
+	return buf.String()
+}
+
+// snakeCase converts a Go name to snake case, such as user_id for
+// UserID, or http_server for HTTPServer
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var buf strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					buf.WriteByte('_')
+				}
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
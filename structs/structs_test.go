@@ -0,0 +1,101 @@
+package structs_test
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eihigh/schemalex"
+	"github.com/eihigh/schemalex/diff"
+	"github.com/eihigh/schemalex/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+type Timestamps struct {
+	CreatedAt time.Time `schemalex:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt *time.Time
+}
+
+type UserAccount struct {
+	ID       uint64 `schemalex:"primary_key;auto_increment"`
+	Email    string `schemalex:"size:191;unique"`
+	Name     string `schemalex:"column:display_name;index:idx_name"`
+	Nickname sql.NullString
+	Balance  float64 `schemalex:"type:DECIMAL(10,2);default:0"`
+	Active   bool    `schemalex:"comment:can log in"`
+	Avatar   []byte
+	Ignored  string `schemalex:"-"`
+	internal string
+	Timestamps
+}
+
+type post struct {
+	ID     int64
+	UserID uint64 `schemalex:"index:idx_user_title"`
+	Title  string `schemalex:"index:idx_user_title"`
+}
+
+func (post) TableName() string { return "blog_posts" }
+
+func TestTable(t *testing.T) {
+	stmts, err := structs.Stmts(UserAccount{}, &post{})
+	if !assert.NoError(t, err, "structs.Stmts should succeed") {
+		return
+	}
+
+	expect := "CREATE TABLE `user_account` (\n" +
+		"`id` BIGINT (20) UNSIGNED NOT NULL AUTO_INCREMENT,\n" +
+		"`email` VARCHAR (191) NOT NULL,\n" +
+		"`display_name` VARCHAR (255) NOT NULL,\n" +
+		"`nickname` VARCHAR (255) DEFAULT NULL,\n" +
+		"`balance` DECIMAL (10,2) NOT NULL DEFAULT 0,\n" +
+		"`active` TINYINT (1) NOT NULL COMMENT 'can log in',\n" +
+		"`avatar` BLOB NOT NULL,\n" +
+		"`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"`updated_at` DATETIME DEFAULT NULL,\n" +
+		"PRIMARY KEY (`id`),\n" +
+		"UNIQUE INDEX `email` (`email`),\n" +
+		"INDEX `idx_name` (`display_name`)\n" +
+		");\n\n" +
+		"CREATE TABLE `blog_posts` (\n" +
+		"`id` BIGINT (20) NOT NULL,\n" +
+		"`user_id` BIGINT (20) UNSIGNED NOT NULL,\n" +
+		"`title` VARCHAR (255) NOT NULL,\n" +
+		"PRIMARY KEY (`id`),\n" +
+		"INDEX `idx_user_title` (`user_id`, `title`)\n" +
+		");\n"
+	if !assert.Equal(t, expect, stmts.String(), "the tables should follow the structs") {
+		return
+	}
+}
+
+func TestSource(t *testing.T) {
+	live := schemalex.NewReaderSource(strings.NewReader("CREATE TABLE `blog_posts` (`id` BIGINT NOT NULL, `title` VARCHAR(255) NOT NULL, PRIMARY KEY (`id`));"))
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Sources(&buf, live, structs.Source(post{}), diff.WithTransaction(false)), "diff.Sources should succeed") {
+		return
+	}
+	expect := "ALTER TABLE `blog_posts` ADD COLUMN `user_id` BIGINT (20) UNSIGNED NOT NULL AFTER `id`;\nALTER TABLE `blog_posts` ADD INDEX `idx_user_title` (`user_id`, `title`);"
+	if !assert.Equal(t, expect, buf.String(), "the structs should be compared against the schema") {
+		return
+	}
+}
+
+func TestTableErrors(t *testing.T) {
+	type unsupported struct {
+		ID    int
+		Point struct{ X, Y int }
+	}
+	type unknown struct {
+		ID int `schemalex:"primary"`
+	}
+	for _, v := range []interface{}{nil, 1, unsupported{}, unknown{}} {
+		_, err := structs.Table(v)
+		if !assert.Error(t, err, "structs.Table(%#v) should fail", v) {
+			return
+		}
+	}
+}
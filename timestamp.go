@@ -0,0 +1,81 @@
+package schemalex
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/option"
+	"github.com/eihigh/schemalex/model"
+)
+
+const optkeyExplicitDefaults = "explicit-defaults-for-timestamp"
+
+// WithExplicitDefaultsForTimestamp specifies the
+// explicit_defaults_for_timestamp setting of the server, for use with
+// NewParser. When it is false, the TIMESTAMP columns that are not
+// declared as NULL are NOT NULL, the first one of each table defaults
+// to CURRENT_TIMESTAMP and is updated to it, unless it declares DEFAULT
+// or ON UPDATE, and the others default to the zero timestamp, as the
+// server does.
+//
+// By default, the setting is false only when WithMySQLVersion targets
+// MySQL earlier than 8.0.2, whose servers disable it by default.
+func WithExplicitDefaultsForTimestamp(b bool) Option {
+	return option.New(optkeyExplicitDefaults, b)
+}
+
+// legacyTimestamps returns true if the TIMESTAMP columns follow the
+// rules of explicit_defaults_for_timestamp=OFF, given the options of
+// the parser
+func legacyTimestamps(dialect Dialect, version MySQLVersion, explicit *bool) bool {
+	if explicit != nil {
+		return !*explicit
+	}
+	if dialect != "" && dialect != DialectMySQL {
+		return false
+	}
+	return !version.AtLeast(8, 0, 2)
+}
+
+// resolveTimestamps declares the nullability and the defaults that the
+// server gives to the TIMESTAMP columns of the table, when
+// explicit_defaults_for_timestamp is OFF. It must be called before the
+// table is normalized, which forgets the columns declared as NULL.
+func resolveTimestamps(table model.Table) {
+	first := true
+	for col := range table.Columns() {
+		if col.Type() != model.ColumnTypeTimestamp {
+			continue
+		}
+		isFirst := first
+		first = false
+
+		if col.NullState() == model.NullStateNull {
+			continue
+		}
+		col.SetNullState(model.NullStateNotNull)
+		if col.HasDefault() {
+			continue
+		}
+
+		var fsp string
+		if col.HasLength() {
+			fsp = col.Length().Length()
+		}
+		if isFirst && !col.HasAutoUpdate() {
+			now := "CURRENT_TIMESTAMP"
+			if fsp != "" && fsp != "0" {
+				now += "(" + fsp + ")"
+			}
+			col.SetDefault(now, false)
+			col.SetAutoUpdate(now)
+			continue
+		}
+
+		zero := "0000-00-00 00:00:00"
+		if n, err := strconv.Atoi(fsp); err == nil && n > 0 {
+			zero += "." + strings.Repeat("0", n)
+		}
+		col.SetDefault(zero, true)
+	}
+}
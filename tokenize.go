@@ -0,0 +1,65 @@
+package schemalex
+
+// Tokenizer splits MySQL statements into tokens, using the same lexer
+// as Parser. Unlike Parser, it reports every token of the input,
+// including spaces and comments, so that the input can be rebuilt by
+// concatenating the source text of the tokens.
+//
+//	tz := schemalex.Tokenize(src)
+//	for tz.Next() {
+//	    t := tz.Token()
+//	    ...
+//	}
+//
+// Quotes and comments that are not terminated are reported as a single
+// ILLEGAL token, after which Next returns false.
+type Tokenizer struct {
+	lexer *lexer
+	token *Token
+	text  []byte
+}
+
+// Tokenize creates a Tokenizer for src
+func Tokenize(src []byte) *Tokenizer {
+	return &Tokenizer{lexer: newLexer(src)}
+}
+
+// Next reads the next token, and returns false once the input is
+// exhausted
+func (tz *Tokenizer) Next() bool {
+	if tz.lexer.done {
+		tz.token, tz.text = nil, nil
+		return false
+	}
+
+	start := tz.lexer.start.pos
+	t := tz.lexer.nextToken()
+	if t.Type == EOF {
+		tz.token, tz.text = nil, nil
+		return false
+	}
+	if t.Line > 1 {
+		// the lexer counts the columns from 0 after the first line
+		t.Col++
+	}
+	tz.token = t
+	tz.text = tz.lexer.input[start:tz.lexer.start.pos]
+	return true
+}
+
+// Token returns the token read by the last call to Next. Lines and
+// columns are counted from 1, and the value of quoted identifiers and
+// strings is unquoted.
+func (tz *Tokenizer) Token() Token {
+	if tz.token == nil {
+		return Token{Type: EOF, EOF: true}
+	}
+	tz.token.value(tz.lexer.input)
+	return *tz.token
+}
+
+// Text returns the source text of the token read by the last call to
+// Next, including the quotes and the comment markers
+func (tz *Tokenizer) Text() []byte {
+	return tz.text
+}
@@ -0,0 +1,49 @@
+package schemalex_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	const src = "CREATE TABLE `foo` (\n  id INT -- the id\n);\n/* done */"
+
+	var buf bytes.Buffer
+	var tokens []schemalex.Token
+	tz := schemalex.Tokenize([]byte(src))
+	for tz.Next() {
+		buf.Write(tz.Text())
+		tokens = append(tokens, tz.Token())
+	}
+	if !assert.Equal(t, src, buf.String(), "the text of the tokens should make up the input") {
+		return
+	}
+
+	if !assert.Equal(t, schemalex.Token{Type: schemalex.BACKTICK_IDENT, Value: "foo", Pos: 13, Line: 1, Col: 14}, tokens[4], "backtick ident should match") {
+		return
+	}
+	if !assert.Equal(t, schemalex.Token{Type: schemalex.IDENT, Value: "id", Pos: 23, Line: 2, Col: 3}, tokens[8], "ident on the second line should match") {
+		return
+	}
+	last := tokens[len(tokens)-1]
+	if !assert.Equal(t, schemalex.COMMENT_IDENT, last.Type, "last token should be a comment") {
+		return
+	}
+	if !assert.False(t, tz.Next(), "Next should keep returning false") {
+		return
+	}
+}
+
+func TestTokenizeUnterminated(t *testing.T) {
+	tz := schemalex.Tokenize([]byte("SELECT 'abc"))
+	var types []schemalex.TokenType
+	for tz.Next() {
+		types = append(types, tz.Token().Type)
+	}
+	if !assert.Equal(t, []schemalex.TokenType{schemalex.IDENT, schemalex.SPACE, schemalex.ILLEGAL}, types, "unterminated quote should be ILLEGAL") {
+		return
+	}
+}
@@ -13,6 +13,12 @@ type Token struct {
 	Line  int
 	Col   int
 	EOF   bool
+
+	// size is the length of the source text of the token, from
+	// which the lexer reads Value on first use unless it is 0. It
+	// fits in the padding after EOF, so that tokens are no larger
+	// than their exported fields.
+	size int32
 }
 
 // NewToken creates a new token of type `t`, with value `v`
@@ -155,6 +161,22 @@ const (
 	ASC
 	DESC
 	NOW
+	PARTITION
+	PARTITIONS
+	BY
+	RANGE
+	LIST
+	LINEAR
+	COLUMNS
+	VALUES
+	LESS
+	THAN
+	MAXVALUE
+	IN
+	ANALYZE
+	HISTOGRAM
+	BUCKETS
+	WITH
 )
 
 var keywordIdentMap = map[string]TokenType{
@@ -270,6 +292,22 @@ var keywordIdentMap = map[string]TokenType{
 	"ASC":                ASC,
 	"DESC":               DESC,
 	"NOW":                NOW,
+	"PARTITION":          PARTITION,
+	"PARTITIONS":         PARTITIONS,
+	"BY":                 BY,
+	"RANGE":              RANGE,
+	"LIST":               LIST,
+	"LINEAR":             LINEAR,
+	"COLUMNS":            COLUMNS,
+	"VALUES":             VALUES,
+	"LESS":               LESS,
+	"THAN":               THAN,
+	"MAXVALUE":           MAXVALUE,
+	"IN":                 IN,
+	"ANALYZE":            ANALYZE,
+	"HISTOGRAM":          HISTOGRAM,
+	"BUCKETS":            BUCKETS,
+	"WITH":               WITH,
 }
 
 func (t TokenType) String() string {
@@ -540,6 +578,38 @@ func (t TokenType) String() string {
 		return "DESC"
 	case NOW:
 		return "NOW"
+	case PARTITION:
+		return "PARTITION"
+	case PARTITIONS:
+		return "PARTITIONS"
+	case BY:
+		return "BY"
+	case RANGE:
+		return "RANGE"
+	case LIST:
+		return "LIST"
+	case LINEAR:
+		return "LINEAR"
+	case COLUMNS:
+		return "COLUMNS"
+	case VALUES:
+		return "VALUES"
+	case LESS:
+		return "LESS"
+	case THAN:
+		return "THAN"
+	case MAXVALUE:
+		return "MAXVALUE"
+	case IN:
+		return "IN"
+	case ANALYZE:
+		return "ANALYZE"
+	case HISTOGRAM:
+		return "HISTOGRAM"
+	case BUCKETS:
+		return "BUCKETS"
+	case WITH:
+		return "WITH"
 	}
 	return "(invalid)"
 }
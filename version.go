@@ -0,0 +1,77 @@
+package schemalex
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/eihigh/schemalex/internal/errors"
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+// MySQLVersion is the version of the MySQL server that a schema targets,
+// such as 8.0.16. The zero value targets no specific version, in which
+// case all supported syntax is accepted.
+type MySQLVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseMySQLVersion parses versions such as "5.7", "8.0.16", or
+// "8.0.21-log". Missing components are treated as 0.
+func ParseMySQLVersion(s string) (MySQLVersion, error) {
+	var v MySQLVersion
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, errors.Errorf(`invalid MySQL version %s`, s)
+	}
+	dst := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, errors.Errorf(`invalid MySQL version %s`, s)
+		}
+		*dst[i] = n
+	}
+	if v.IsZero() {
+		return v, errors.Errorf(`invalid MySQL version %s`, s)
+	}
+	return v, nil
+}
+
+// IsZero returns true if no version is specified
+func (v MySQLVersion) IsZero() bool {
+	return v == MySQLVersion{}
+}
+
+// AtLeast returns true if v is the same as or later than the given
+// version. The zero value is considered to be later than any version.
+func (v MySQLVersion) AtLeast(major, minor, patch int) bool {
+	if v.IsZero() {
+		return true
+	}
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+func (v MySQLVersion) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+}
+
+const optkeyMySQLVersion = "mysql-version"
+
+// WithMySQLVersion specifies the version of the MySQL server that the
+// schema targets, for use with NewParser. The parser rejects syntax that the version does not
+// support, such as the JSON type before 5.7.8, or histograms and
+// utf8mb4_0900_* collations before 8.0.
+func WithMySQLVersion(v MySQLVersion) Option {
+	return option.New(optkeyMySQLVersion, v)
+}
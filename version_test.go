@@ -0,0 +1,99 @@
+package schemalex_test
+
+import (
+	"testing"
+
+	"github.com/eihigh/schemalex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMySQLVersion(t *testing.T) {
+	for in, expected := range map[string]schemalex.MySQLVersion{
+		"5.7":        {Major: 5, Minor: 7},
+		"8.0.16":     {Major: 8, Minor: 0, Patch: 16},
+		"8.0.21-log": {Major: 8, Minor: 0, Patch: 21},
+	} {
+		v, err := schemalex.ParseMySQLVersion(in)
+		if !assert.NoError(t, err, "parse %s should succeed", in) {
+			return
+		}
+		if !assert.Equal(t, expected, v, "version should match") {
+			return
+		}
+	}
+
+	for _, in := range []string{"", "8.x", "1.2.3.4", "0.0"} {
+		_, err := schemalex.ParseMySQLVersion(in)
+		if !assert.Error(t, err, "parse %s should fail", in) {
+			return
+		}
+	}
+}
+
+func TestMySQLVersion(t *testing.T) {
+	v57 := schemalex.MySQLVersion{Major: 5, Minor: 7}
+	v80 := schemalex.MySQLVersion{Major: 8, Minor: 0}
+
+	specs := []struct {
+		Input   string
+		Version schemalex.MySQLVersion
+		Error   string
+	}{
+		{
+			Input:   "CREATE TABLE foo (a JSON);",
+			Version: schemalex.MySQLVersion{Major: 5, Minor: 6, Patch: 40},
+			Error:   "JSON type requires MySQL 5.7.8 or later (targeting 5.6.40)",
+		},
+		{
+			Input:   "CREATE TABLE foo (a JSON);",
+			Version: v57,
+			Error:   "JSON type requires MySQL 5.7.8 or later (targeting 5.7.0)",
+		},
+		{
+			Input:   "CREATE TABLE foo (a JSON);",
+			Version: schemalex.MySQLVersion{Major: 5, Minor: 7, Patch: 8},
+		},
+		{
+			Input:   "CREATE TABLE foo (a INT);\nANALYZE TABLE foo UPDATE HISTOGRAM ON a;",
+			Version: v57,
+			Error:   "histograms requires MySQL 8.0.0 or later (targeting 5.7.0)",
+		},
+		{
+			Input:   "CREATE TABLE foo (a INT);\nANALYZE TABLE foo UPDATE HISTOGRAM ON a;",
+			Version: v80,
+		},
+		{
+			Input:   "CREATE TABLE foo (a VARCHAR (10) COLLATE utf8mb4_0900_ai_ci);",
+			Version: v57,
+			Error:   "collation utf8mb4_0900_ai_ci requires MySQL 8.0.0 or later (targeting 5.7.0)",
+		},
+		{
+			Input:   "CREATE TABLE foo (a INT) DEFAULT COLLATE = utf8mb4_0900_ai_ci;",
+			Version: v57,
+			Error:   "collation utf8mb4_0900_ai_ci requires MySQL 8.0.0 or later (targeting 5.7.0)",
+		},
+		{
+			Input: "CREATE TABLE foo (a JSON) DEFAULT COLLATE = utf8mb4_0900_ai_ci;",
+		},
+	}
+
+	for _, spec := range specs {
+		_, err := schemalex.NewParser(schemalex.WithMySQLVersion(spec.Version)).ParseString(spec.Input)
+		if spec.Error == "" {
+			if !assert.NoError(t, err, "parse should succeed for %s", spec.Input) {
+				return
+			}
+			continue
+		}
+		if !assert.Error(t, err, "parse should fail for %s", spec.Input) {
+			return
+		}
+		pe, ok := err.(schemalex.ParseError)
+		if !assert.True(t, ok, "error should be a ParseError") {
+			return
+		}
+		if !assert.Equal(t, spec.Error, pe.Message(), "message should match") {
+			return
+		}
+	}
+}
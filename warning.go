@@ -0,0 +1,70 @@
+package schemalex
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/eihigh/schemalex/internal/option"
+)
+
+// Warning describes a construct that was skipped while parsing, so that
+// the parsed statements do not capture it, or a definition that
+// duplicates a previous one. Warnings about the statements generated by
+// the diff package do not refer to a position, and their Line is zero.
+type Warning struct {
+	File    string
+	Line    int
+	Col     int
+	Message string
+}
+
+// String returns the formatted string representation of this warning
+func (w Warning) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("warning: ")
+	buf.WriteString(w.Message)
+	if len(w.File) > 0 {
+		buf.WriteString(" in file ")
+		buf.WriteString(w.File)
+	}
+	if w.Line == 0 {
+		return buf.String()
+	}
+	buf.WriteString(" at line ")
+	buf.WriteString(strconv.Itoa(w.Line))
+	buf.WriteString(" column ")
+	buf.WriteString(strconv.Itoa(w.Col))
+	return buf.String()
+}
+
+const optkeyWarningHandler = "warning-handler"
+
+// WithWarningHandler specifies a function that is called for each
+// statement that is skipped, such as CREATE DATABASE, DROP EVENT, SET
+// and USE, and for each duplicate definition (see WithDuplicateErrors), for
+// use with NewParser. The data statements found in dumps, such as
+// INSERT, are skipped without warnings. Dialects made available by
+// RegisterDialect may not support it.
+//
+// When given to the diff package, the function is also called for each
+// table that is created or altered with indexes that its storage
+// engine does not support on the target server, such as foreign keys
+// of MyISAM tables.
+func WithWarningHandler(fn func(Warning)) Option {
+	return option.New(optkeyWarningHandler, fn)
+}
+
+// warnf reports a warning about the construct starting at t
+func (pctx *parseCtx) warnf(t *Token, format string, args ...interface{}) {
+	if pctx.warn == nil {
+		return
+	}
+	file, line := locate(pctx.markers, t.Pos, t.Line)
+	pctx.warn(Warning{
+		File:    file,
+		Line:    line,
+		Col:     t.Col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}